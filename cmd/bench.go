@@ -1,14 +1,16 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"net"
 	"os"
 	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/drsoft-oss/proxybench/internal/bench"
-	"github.com/drsoft-oss/proxybench/internal/geo"
+	"github.com/drsoft-oss/proxybench/internal/checker"
 	"github.com/drsoft-oss/proxybench/internal/output"
 )
 
@@ -20,7 +22,15 @@ var benchCmd = &cobra.Command{
 Examples:
   proxybench bench http://1.2.3.4:8080
   proxybench bench socks5://10.0.0.1:1080 --samples 10 --format json
-  cat proxies.txt | proxybench bench --payload-url http://speed.example.com/10mb`,
+  cat proxies.txt | proxybench bench --payload-url http://speed.example.com/10mb
+  proxybench bench http://1.2.3.4:8080 --samples 10 --reuse-connections
+  proxybench bench http://1.2.3.4:8080 --payload-url http://speed.example.com/10mb --ceiling
+  proxybench bench http://1.2.3.4:8080 --payload-url http://speed.example.com/100mb --sustain 60s
+  proxybench bench http://1.2.3.4:8080 --test-url https://example.com --churn
+  proxybench bench http://1.2.3.4:8080 --conn-limit --conn-limit-max 512
+  proxybench bench socks5://10.0.0.1:1080 --udp-dns 1.1.1.1:53
+  proxybench bench --nearest-target < proxies.txt
+  proxybench bench --target-pool europe=http://fra.example.com/ --target-pool north-america=http://nyc.example.com/ < proxies.txt`,
 	RunE: runBench,
 }
 
@@ -33,59 +43,292 @@ var (
 	benchConcurrency int
 	benchGeo         bool
 	benchDBPath      string
+	benchReuse       bool
+	benchCeiling     bool
+	benchCeilingMax  int
+	benchCeilingWin  time.Duration
+	benchSustain     time.Duration
+	benchThrottleAt  time.Duration
+	benchThrottleX   float64
+	benchMinOK       int
+	benchInterleave  bool
+	benchWarmPath    bool
+	benchTimeoutsIn  string
+	benchTimeoutX    float64
+	benchPercentile  string
+	benchUDPDNS      string
+	benchPaths       bool
+	benchChurn       bool
+	benchChurnMax    int
+	benchChurnWin    time.Duration
+	benchConnLimit   bool
+	benchConnMax     int
+	benchNearest     bool
+	benchTargetPool  []string
+	benchH3          bool
 )
 
 func init() {
-	benchCmd.Flags().StringVarP(&benchFormat, "format", "f", "table", "output format: table|json|csv")
+	benchCmd.Flags().StringVarP(&benchFormat, "format", "f", "table", "output format: table|json|ndjson|csv")
 	benchCmd.Flags().IntVarP(&benchTimeout, "timeout", "t", 15, "per-request timeout in seconds")
+	benchCmd.Flags().StringVar(&benchTimeoutsIn, "timeouts-from", "", "JSON/NDJSON check or bench output; time out each listed proxy at --timeout-factor × its observed latency")
+	benchCmd.Flags().Float64Var(&benchTimeoutX, "timeout-factor", bench.DefaultTimeoutFactor, "multiple of observed latency used by --timeouts-from")
 	benchCmd.Flags().IntVarP(&benchSamples, "samples", "n", 5, "number of requests per proxy")
+	benchCmd.Flags().StringVar(&benchPercentile, "percentile", string(bench.Nearest), "percentile method: nearest (a real sample) or linear (interpolated, as most tools report)")
 	benchCmd.Flags().StringVar(&benchTestURL, "test-url", "http://www.google.com", "URL to hit for latency measurement")
 	benchCmd.Flags().StringVar(&benchPayloadURL, "payload-url", "", "URL of a large file for throughput measurement (optional)")
 	benchCmd.Flags().IntVarP(&benchConcurrency, "concurrency", "c", 5, "max parallel proxies under test")
 	benchCmd.Flags().BoolVar(&benchGeo, "geo", false, "append country info (requires IP database)")
 	benchCmd.Flags().StringVar(&benchDBPath, "db", "", "path to ip2country.csv (default: auto-detect)")
+	benchCmd.Flags().BoolVar(&benchReuse, "reuse-connections", false, "keep the proxy connection open between samples and report cold vs warm latency")
+	benchCmd.Flags().BoolVar(&benchWarmPath, "warm", false, "measure steady-state latency: open the connection with an unmeasured request, then sample over it")
+	benchCmd.Flags().BoolVar(&benchInterleave, "interleave", false, "spread each proxy's samples across the run instead of taking them back to back")
+	benchCmd.Flags().IntVar(&benchMinOK, "min-successful", 1, "samples that must succeed before latency stats are reported; fewer counts as failed")
+	benchCmd.Flags().DurationVar(&benchSustain, "sustain", 0, "keep the payload download going this long, repeating it as needed, to detect throttling after a burst (needs --payload-url)")
+	benchCmd.Flags().DurationVar(&benchThrottleAt, "throttle-after", bench.DefaultThrottleAfter, "length of the initial burst --sustain compares the rest of the download with")
+	benchCmd.Flags().Float64Var(&benchThrottleX, "throttle-drop", bench.DefaultThrottleDrop, "fraction by which sustained speed must fall below the initial burst to report throttled")
+	benchCmd.Flags().BoolVar(&benchCeiling, "ceiling", false, "find each proxy's throughput ceiling with parallel payload downloads (needs --payload-url)")
+	benchCmd.Flags().IntVar(&benchCeilingMax, "ceiling-max", bench.DefaultCeilingMax, "most parallel downloads tried by --ceiling")
+	benchCmd.Flags().BoolVar(&benchPaths, "compare-connect", false, "also time HTTP proxies' absolute-URI GET and CONNECT tunnel paths separately, over plain http to the test URL's host")
+	benchCmd.Flags().StringVar(&benchUDPDNS, "udp-dns", "", "also time DNS queries to this resolver (ip:port) through SOCKS5 proxies' UDP ASSOCIATE relay")
+	benchCmd.Flags().DurationVar(&benchCeilingWin, "ceiling-window", bench.DefaultCeilingWindow, "how long each --ceiling level downloads")
+	benchCmd.Flags().BoolVar(&benchChurn, "churn", false, "measure how many new tunnels per second each proxy accepts, ramping concurrent connection attempts")
+	benchCmd.Flags().IntVar(&benchChurnMax, "churn-max", bench.DefaultChurnMax, "most concurrent connection attempts tried by --churn")
+	benchCmd.Flags().DurationVar(&benchChurnWin, "churn-window", bench.DefaultChurnWindow, "how long each --churn level runs")
+	benchCmd.Flags().BoolVar(&benchConnLimit, "conn-limit", false, "find how many connections each proxy keeps open at once, ramping simultaneous held connections")
+	benchCmd.Flags().IntVar(&benchConnMax, "conn-limit-max", bench.DefaultConnLimitMax, "most simultaneous connections tried by --conn-limit")
+	benchCmd.Flags().BoolVar(&benchH3, "h3", false, "fetch the test URL and payloads over HTTP/3 (QUIC) through SOCKS5 proxies' UDP relay")
+	benchCmd.Flags().BoolVar(&benchNearest, "nearest-target", false, "sample each proxy against the built-in target nearest its country instead of --test-url")
+	benchCmd.Flags().StringSliceVar(&benchTargetPool, "target-pool", nil, "region=url test targets for --nearest-target, where region is a country code, a continent or default (repeatable; replaces the built-in pool)")
 }
 
 func runBench(cmd *cobra.Command, args []string) error {
-	addresses := collectAddresses(args)
-	if len(addresses) == 0 {
-		return fmt.Errorf("no proxy addresses provided")
+	if benchCeiling && benchPayloadURL == "" {
+		return fmt.Errorf("--ceiling needs --payload-url")
+	}
+	if benchSustain > 0 && benchPayloadURL == "" {
+		return fmt.Errorf("--sustain needs --payload-url")
+	}
+	if benchSustain > 0 && benchSustain <= benchThrottleAt {
+		return fmt.Errorf("--sustain (%s) must be longer than --throttle-after (%s)", benchSustain, benchThrottleAt)
+	}
+	if benchMinOK > benchSamples {
+		return fmt.Errorf("--min-successful (%d) exceeds --samples (%d)", benchMinOK, benchSamples)
+	}
+	method, err := bench.ParsePercentileMethod(benchPercentile)
+	if err != nil {
+		return err
+	}
+	targets := bench.BuiltinTargets
+	if len(benchTargetPool) > 0 {
+		benchNearest = true
+		if targets, err = bench.ParseTargets(benchTargetPool); err != nil {
+			return fmt.Errorf("--target-pool: %w", err)
+		}
+	}
+	if benchUDPDNS != "" {
+		host, _, err := net.SplitHostPort(benchUDPDNS)
+		if err != nil || net.ParseIP(host) == nil {
+			return fmt.Errorf("--udp-dns: want a resolver ip:port like 1.1.1.1:53, got %q", benchUDPDNS)
+		}
+	}
+
+	out, finishUpload, err := resultWriter("bench", benchFormat)
+	if err != nil {
+		return err
 	}
 
+	if err := setupResolver(); err != nil {
+		return err
+	}
+	if err := loadLimits(); err != nil {
+		return err
+	}
+	if args, err = withPAC(args); err != nil {
+		return err
+	}
+
+	stopTracing, err := startTracing()
+	if err != nil {
+		return err
+	}
+	defer stopTracing()
+
+	classes, err := newClassifier(true)
+	if err != nil {
+		return err
+	}
+
+	sd, err := openStatsD()
+	if err != nil {
+		return err
+	}
+	defer closeStatsD(sd)
+
 	opts := bench.Options{
 		Samples:     benchSamples,
 		Timeout:     time.Duration(benchTimeout) * time.Second,
 		TestURL:     benchTestURL,
 		PayloadURL:  benchPayloadURL,
 		Concurrency: benchConcurrency,
+		OnSample:    benchSampleHook(sd),
+		Percentiles: method,
+
+		ReuseConnections: benchReuse,
+		MinSuccessful:    benchMinOK,
+		Interleave:       benchInterleave,
+		WarmPath:         benchWarmPath,
+		Ceiling:          benchCeiling,
+		CeilingMax:       benchCeilingMax,
+		CeilingWindow:    benchCeilingWin,
+		Sustain:          benchSustain,
+		ThrottleAfter:    benchThrottleAt,
+		ThrottleDrop:     benchThrottleX,
+		UDPTarget:        benchUDPDNS,
+		ComparePaths:     benchPaths,
+		Churn:            benchChurn,
+		ChurnMax:         benchChurnMax,
+		ChurnWindow:      benchChurnWin,
+		ConnLimit:        benchConnLimit,
+		ConnLimitMax:     benchConnMax,
+		H3:               benchH3,
+	}
+	if opts.ConnectTimeout, opts.HandshakeTimeout, opts.RequestTimeout, opts.OverallBudget, err = phaseTimeouts(); err != nil {
+		return err
+	}
+	if opts.ProxyProtocol, _, err = parseProxyProto(benchProxyProto, false); err != nil {
+		return err
+	}
+	if opts.Credentials, err = loadCredentials(); err != nil {
+		return err
+	}
+	if opts.Overrides, err = loadOverrides(); err != nil {
+		return err
+	}
+	if opts.RootCAs, opts.InsecureTLS, err = loadTLS(); err != nil {
+		return err
+	}
+	opts.ProxyInsecure = proxyInsecure
+	if opts.TLSFingerprint, err = loadFingerprint(); err != nil {
+		return err
+	}
+	if opts.SSHKey, err = loadSSHKey(); err != nil {
+		return err
+	}
+	opts.SSHHostKeys = sshHostKeys()
+	if err := loadShard(); err != nil {
+		return err
+	}
+	if opts.Request, err = testRequest(benchTestURL); err != nil {
+		return err
+	}
+	dialect, err := csvDialect()
+	if err != nil {
+		return err
+	}
+	gate, err := parseHealthGate()
+	if err != nil {
+		return err
+	}
+	if benchTimeoutsIn != "" {
+		if benchTimeoutX <= 0 {
+			return fmt.Errorf("--timeout-factor must be positive")
+		}
+		observed, err := output.ReadLatenciesFile(benchTimeoutsIn)
+		if err != nil {
+			return fmt.Errorf("--timeouts-from: %w", err)
+		}
+		opts.TimeoutFor = func(address string) (time.Duration, bool) {
+			d, ok := observed[address]
+			return bench.AdaptiveTimeout(d, benchTimeoutX), ok
+		}
 	}
 
-	fmt.Fprintf(os.Stderr, "Benchmarking %d proxies (%d samples each)…\n", len(addresses), benchSamples)
-	results := bench.RunMany(addresses, opts)
+	locate := geoLookup(benchGeo || summaryByCountry || benchNearest, benchDBPath)
+	if benchNearest {
+		opts.TestURLFor = func(address string) string { return targets.Nearest(locate(address).CountryCode) }
+	}
+	w := output.NewBenchWriter(out, output.Format(benchFormat), benchGeo)
+	summary := output.NewSummary()
+	w.Warm = benchReuse || benchWarmPath
+	w.UDP = benchUDPDNS != ""
+	w.Paths = benchPaths
+	w.CSV = dialect
+	w.Meta = runMeta(cmd, output.RunOptions{
+		TestURL:     benchTestURL,
+		Concurrency: benchConcurrency,
+		Samples:     benchSamples,
+		PayloadURL:  benchPayloadURL,
+		Percentile:  string(method),
+		TLSPolicy:   checker.TLSPolicy(opts.RootCAs, opts.InsecureTLS),
+		Shard:       shard.String(),
+	}, opts.Request, opts.Timeout, benchGeo || summaryByCountry, benchDBPath)
+	hooks := newHook(execOnResult)
+	var recorded, held []bench.Stats
+	var writeErr error
+	total, reachable := 0, 0
 
-	var countries []string
-	if benchGeo {
-		db := geo.DefaultDB
-		if benchDBPath != "" {
-			if err := db.LoadFile(benchDBPath); err != nil {
-				fmt.Fprintf(os.Stderr, "warn: geo DB load failed: %v\n", err)
-			}
-		} else {
-			if err := db.Load(); err != nil {
-				fmt.Fprintf(os.Stderr, "warn: geo DB not found at %s\n  run `proxybench db update` to download it\n", geo.DefaultDBPath())
-			}
+	live := newLiveStatus(benchFormat, "Benchmarked", "reachable")
+	opts.AnyOrder = live != nil && !stableSort
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	fmt.Fprintf(os.Stderr, "Benchmarking proxies (%d samples each, %s percentiles)…\n", benchSamples, method)
+	started := time.Now()
+	addresses, counts := uniqueAddresses(ctx, shardAddresses(ctx, hashInput(ctx, streamAddresses(ctx, args))))
+	bench.RunStream(throttleAddresses(ctx, addresses), opts, func(s bench.Stats) {
+		s.Count = counts.of(s.Address)
+		total++
+		keep := classes.bench(&s)
+		if s.OK() {
+			reachable++
+		}
+		if benchHistory {
+			recorded = append(recorded, s)
 		}
-		countries = make([]string, len(results))
-		for i, r := range results {
-			host := extractHost(r.Address)
-			if host != "" {
-				cc, cn := db.Lookup(host)
-				if cc != "--" {
-					countries[i] = cc + " " + cn
-				}
+		if keep {
+			runHook(hooks, s)
+		}
+		if keep && summaryByCountry {
+			summary.AddBench(s, locate(s.Address))
+		} else if keep && stableSort {
+			held = append(held, s)
+		} else if keep && writeErr == nil {
+			if writeErr = live.write(func() error { return w.WriteGeo(s, locate(s.Address)) }); writeErr != nil {
+				stop()
 			}
 		}
+		live.count(s.OK())
+	})
+	live.finish()
+	waitHook(hooks, "--exec-on-result")
+	for _, s := range stableOrder(held, func(s bench.Stats) string { return s.Address }) {
+		if writeErr == nil {
+			writeErr = w.WriteGeo(s, locate(s.Address))
+		}
+	}
+	if writeErr != nil {
+		return writeErr
+	}
+	if total == 0 {
+		return fmt.Errorf("no proxy addresses provided")
+	}
+	if benchHistory {
+		recordBenchRun(started, recorded)
 	}
 
-	return output.WriteBenchResults(os.Stdout, results, countries, output.Format(benchFormat))
+	w.Meta.InputSHA256 = inputDigest()
+	if summaryByCountry {
+		err = output.WriteSummary(out, summary.Countries(), output.Format(benchFormat), dialect, w.Meta)
+	} else {
+		err = w.Close()
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Benchmarked %d proxies in %s: %d reachable, %d unreachable\n",
+		total, time.Since(started).Round(time.Millisecond), reachable, total-reachable)
+	if err := finishUpload(); err != nil {
+		return err
+	}
+	return gate.check(cmd, total, reachable)
 }
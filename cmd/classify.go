@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/drsoft-oss/proxybench/internal/bench"
+	"github.com/drsoft-oss/proxybench/internal/checker"
+	"github.com/drsoft-oss/proxybench/internal/classify"
+)
+
+var (
+	latencyClasses string
+	speedClasses   string
+	onlyClasses    string
+	onlySpeeds     string
+	usableSpec     string
+)
+
+func init() {
+	for _, c := range []*cobra.Command{checkCmd, benchCmd} {
+		c.Flags().StringVar(&latencyClasses, "latency-classes", classify.DefaultLatency, "latency buckets in ms, fastest first (name:limit,...,name)")
+		c.Flags().StringVar(&onlyClasses, "class", "", "only output results in these latency classes (e.g. fast,medium)")
+	}
+	benchCmd.Flags().StringVar(&speedClasses, "speed-classes", classify.DefaultSpeed, "throughput buckets in bytes/sec, fastest first (name:limit,...,name)")
+	benchCmd.Flags().StringVar(&onlySpeeds, "speed-class", "", "only output results in these speed classes")
+	benchCmd.Flags().StringVar(&usableSpec, "usable", classify.DefaultThresholds, "limits for the usable verdict and A–F grade (loss:ratio,latency:ms,speed:bytes/sec)")
+}
+
+// classifier labels results from the --*-classes flags and applies the
+// --class / --speed-class filters.
+type classifier struct {
+	latency, speed classify.Scale
+	only, speeds   classify.Filter
+	usable         classify.Thresholds
+}
+
+func newClassifier(withSpeed bool) (*classifier, error) {
+	c := &classifier{only: classify.ParseFilter(onlyClasses)}
+	var err error
+	if c.latency, err = classify.ParseLatency(latencyClasses); err != nil {
+		return nil, fmt.Errorf("--latency-classes: %w", err)
+	}
+	if withSpeed {
+		if c.speed, err = classify.ParseSpeed(speedClasses); err != nil {
+			return nil, fmt.Errorf("--speed-classes: %w", err)
+		}
+		c.speeds = classify.ParseFilter(onlySpeeds)
+		if c.usable, err = classify.ParseThresholds(usableSpec); err != nil {
+			return nil, fmt.Errorf("--usable: %w", err)
+		}
+	}
+	return c, nil
+}
+
+// check labels an alive result and reports whether it passes the filter.
+func (c *classifier) check(r *checker.Result) bool {
+	if r.Alive {
+		r.Class = c.latency.Classify(float64(r.LatencyMS()))
+	}
+	return c.only.Match(r.Class)
+}
+
+// bench labels and grades stats and reports whether they pass both filters.
+func (c *classifier) bench(s *bench.Stats) bool {
+	if s.OK() {
+		s.Grade, s.Usable = c.usable.Grade(s.LossRate, float64(s.P50MS), float64(s.SpeedBps))
+	} else {
+		s.Grade = "F"
+	}
+	if s.OK() {
+		s.LatencyClass = c.latency.Classify(float64(s.P50MS))
+	}
+	if s.SpeedBps > 0 {
+		s.SpeedClass = c.speed.Classify(float64(s.SpeedBps))
+	}
+	return c.only.Match(s.LatencyClass) && c.speeds.Match(s.SpeedClass)
+}
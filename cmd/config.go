@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"github.com/drsoft-oss/proxybench/internal/config"
+	"github.com/drsoft-oss/proxybench/internal/proxylist"
+)
+
+var (
+	configPath    string
+	configProfile string
+
+	// configProxies are the proxies from the config's monitor targets or
+	// sources, used when none are given as arguments or on stdin.
+	configProxies []string
+	// configFile is the config file loaded, "" if none was; configJobs
+	// are its schedule jobs.
+	configFile string
+	configJobs []config.Job
+)
+
+func init() {
+	f := rootCmd.PersistentFlags()
+	f.StringVar(&configPath, "config", "", "config file (default: ~/.config/proxybench/config.yaml or the OS equivalent)")
+	f.StringVar(&configProfile, "profile", "", "config profile to apply on top of the defaults (or set PROXYBENCH_PROFILE)")
+	rootCmd.PersistentPreRunE = applyConfig
+}
+
+// applyConfig sets every flag the config gives a value for and the command
+// line does not, and loads the config's proxies for the commands that
+// check proxies. A missing default config file is not an error.
+func applyConfig(cmd *cobra.Command, _ []string) error {
+	profile := configProfile
+	if profile == "" {
+		profile = os.Getenv("PROXYBENCH_PROFILE")
+	}
+	path := configPath
+	if path == "" {
+		path = config.DefaultPath()
+	}
+	if path == "" || cmd.Name() == "help" {
+		return nil
+	}
+	cfg, err := config.Load(path)
+	if errors.Is(err, fs.ErrNotExist) && configPath == "" {
+		if profile != "" {
+			return fmt.Errorf("--profile %s: no config file at %s", profile, path)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	section, err := cfg.Profile(profile)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	if err := validateConfig(section); err != nil {
+		return fmt.Errorf("config %s: %w", path, err)
+	}
+	configFile, configJobs = path, cfg.Jobs
+
+	values := section.For(cmd.Name())
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	flags := cmd.Flags()
+	for _, k := range keys {
+		fl := flags.Lookup(k)
+		if fl == nil || fl.Changed {
+			continue
+		}
+		if err := flags.Set(k, values[k]); err != nil {
+			return fmt.Errorf("config %s: %s: %w", path, k, err)
+		}
+	}
+
+	switch cmd {
+	case checkCmd, benchCmd, monitorCmd, topCmd:
+		sources := section.Sources
+		if cmd == monitorCmd && section.Targets != nil {
+			configProxies = section.Targets
+			sources = nil
+		}
+		for _, src := range sources {
+			data, err := os.ReadFile(src)
+			if err != nil {
+				return fmt.Errorf("config source: %w", err)
+			}
+			proxies, _, err := proxylist.Read(data, "")
+			if err != nil {
+				return fmt.Errorf("config source %s: %w", src, err)
+			}
+			for _, p := range proxies {
+				configProxies = append(configProxies, p.Address)
+			}
+		}
+	}
+	return nil
+}
+
+// validateConfig rejects settings no command would use, so a typo in the
+// config does not go unnoticed.
+func validateConfig(s config.Section) error {
+	commands := map[string]*cobra.Command{}
+	for _, c := range rootCmd.Commands() {
+		commands[c.Name()] = c
+	}
+	anyHas := func(flag string) bool {
+		for _, c := range commands {
+			if c.Flags().Lookup(flag) != nil {
+				return true
+			}
+		}
+		return rootCmd.PersistentFlags().Lookup(flag) != nil
+	}
+	for _, m := range []map[string]string{s.Flags, s.Notify} {
+		for k := range m {
+			if !anyHas(k) {
+				return fmt.Errorf("no command has a --%s flag", k)
+			}
+		}
+	}
+	for name, m := range s.Commands {
+		c, ok := commands[name]
+		if !ok {
+			return fmt.Errorf("unknown command %q", name)
+		}
+		for k := range m {
+			if c.Flags().Lookup(k) == nil {
+				return fmt.Errorf("%s has no --%s flag", name, k)
+			}
+		}
+	}
+	return nil
+}
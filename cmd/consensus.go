@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/drsoft-oss/proxybench/internal/geo"
+)
+
+var geoConsensus bool
+
+func init() {
+	for _, c := range []*cobra.Command{checkCmd, benchCmd, topCmd, rpcCmd} {
+		c.Flags().BoolVar(&geoConsensus, "geo-consensus", false, "take the country most geo databases agree on (the main one and those added with 'db attach country') and flag disagreements")
+	}
+}
+
+// setConsensus turns on consensus lookups in layered if --geo-consensus
+// is set, warning when there is nothing to vote among.
+func setConsensus(layered *geo.Layered) {
+	if !geoConsensus {
+		return
+	}
+	layered.Consensus = true
+	if n := layered.CountrySources(); n < 2 {
+		fmt.Fprintf(os.Stderr, "warn: --geo-consensus: %d country database(s) loaded; add more with `proxybench db attach country <csv>`\n", n)
+	}
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/drsoft-oss/proxybench/internal/proxylist"
+)
+
+var convertCmd = &cobra.Command{
+	Use:   "convert [file]",
+	Short: "Convert a proxy list between formats without checking it",
+	Long: `Convert reads a proxy list or earlier results (from file, or stdin) and
+writes the proxies in another format. Nothing is checked, so it works
+offline. Formats: txt, csv, json, clash, subscription (base64 of URIs),
+pac (read: every proxy the script can return; written: a failover chain).
+
+Results read as csv or json give their addresses; to change the format of
+results themselves, use 'proxybench filter -f <format>'.
+
+Examples:
+  proxybench convert --to clash proxies.txt > clash.yaml
+  proxybench convert --from subscription --to txt < sub.txt
+  proxybench convert --to txt wpad.dat
+  curl -s https://example.com/sub | proxybench convert --to txt | proxybench check`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runConvert,
+}
+
+var (
+	convertFrom string
+	convertTo   string
+)
+
+func init() {
+	convertCmd.Flags().StringVar(&convertFrom, "from", "auto", "input format: auto|txt|csv|json|clash|subscription|pac")
+	convertCmd.Flags().StringVar(&convertTo, "to", "txt", "output format: txt|csv|json|clash|subscription|pac")
+}
+
+func runConvert(cmd *cobra.Command, args []string) error {
+	from, err := proxylist.ParseFormat(convertFrom)
+	if err != nil {
+		return fmt.Errorf("--from: %w", err)
+	}
+	to, err := proxylist.ParseFormat(convertTo)
+	if err != nil {
+		return fmt.Errorf("--to: %w", err)
+	}
+	if to == "" {
+		return fmt.Errorf("--to needs a format, not auto")
+	}
+
+	var data []byte
+	if len(args) == 1 && args[0] != "-" {
+		data, err = os.ReadFile(args[0])
+	} else {
+		data, err = io.ReadAll(os.Stdin)
+	}
+	if err != nil {
+		return err
+	}
+
+	proxies, skipped, err := proxylist.Read(data, from)
+	if err != nil {
+		return err
+	}
+	writeSkipped, err := proxylist.Write(os.Stdout, proxies, to)
+	if err != nil {
+		return err
+	}
+	skipped = append(skipped, writeSkipped...)
+	for _, s := range skipped {
+		fmt.Fprintf(os.Stderr, "skip: %s\n", s)
+	}
+	fmt.Fprintf(os.Stderr, "Converted %d proxies to %s (%d skipped)\n", len(proxies)-len(writeSkipped), to, len(skipped))
+	return nil
+}
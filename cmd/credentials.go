@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+
+	"github.com/drsoft-oss/proxybench/internal/creds"
+	"github.com/drsoft-oss/proxybench/internal/tui"
+)
+
+var (
+	credentialsPath   string
+	credentialList    string
+	promptCredentials bool
+)
+
+func init() {
+	for _, c := range []*cobra.Command{checkCmd, benchCmd, monitorCmd} {
+		c.Flags().StringVar(&credentialsPath, "credentials", "", "file mapping host[:port] to user:pass (\"*\" for a default pair) for proxies listed without credentials")
+	}
+	for _, c := range []*cobra.Command{checkCmd, monitorCmd} {
+		c.Flags().StringVar(&credentialList, "try-credentials", "", "file of user:pass pairs tried in order on proxies listed without credentials that ask for them; the pair that worked is reported")
+	}
+	checkCmd.Flags().BoolVar(&promptCredentials, "prompt-credentials", false, "ask on the terminal for user:pass when a proxy listed without credentials requires them, once per host, and re-check it")
+}
+
+// loadCredentials reads --credentials and returns the lookup to put in
+// checker/bench options, or nil when the flag is unset.
+func loadCredentials() (func(hostPort string) *url.Userinfo, error) {
+	if credentialsPath == "" {
+		return nil, nil
+	}
+	m, err := creds.Load(credentialsPath)
+	if err != nil {
+		return nil, fmt.Errorf("--credentials: %w", err)
+	}
+	return m.Lookup, nil
+}
+
+// loadCredentialList reads --try-credentials, or returns nil when the flag
+// is unset.
+func loadCredentialList() ([]*url.Userinfo, error) {
+	if credentialList == "" {
+		return nil, nil
+	}
+	list, err := creds.LoadList(credentialList)
+	if err != nil {
+		return nil, fmt.Errorf("--try-credentials: %w", err)
+	}
+	if len(list) == 0 {
+		return nil, fmt.Errorf("--try-credentials: %s holds no pairs", credentialList)
+	}
+	return list, nil
+}
+
+// credentialPrompt asks on the terminal for the credentials of proxies
+// that require them, once per host: the answer, or a skip, is reused for
+// every other proxy on that host for the rest of the run.
+type credentialPrompt struct {
+	base func(hostPort string) *url.Userinfo
+
+	mu     sync.Mutex
+	p      *tui.Prompt
+	byHost map[string]*url.Userinfo
+}
+
+// withPrompt wraps the lookup from loadCredentials for --prompt-credentials
+// and returns the lookup and ask funcs to put in checker options. Without
+// a terminal it warns and leaves the lookup as it is.
+func withPrompt(base func(hostPort string) *url.Userinfo) (lookup, ask func(hostPort string) *url.Userinfo, closer func()) {
+	if !promptCredentials {
+		return base, nil, func() {}
+	}
+	p, err := tui.OpenPrompt()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warn: --prompt-credentials: %v; not prompting\n", err)
+		return base, nil, func() {}
+	}
+	cp := &credentialPrompt{base: base, p: p, byHost: map[string]*url.Userinfo{}}
+	return cp.lookup, cp.ask, func() { p.Close() }
+}
+
+func promptHost(hostPort string) string {
+	host := hostPort
+	if h, _, err := net.SplitHostPort(hostPort); err == nil {
+		host = h
+	}
+	return strings.ToLower(host)
+}
+
+// lookup prefers --credentials, then an answer given for the host.
+func (c *credentialPrompt) lookup(hostPort string) *url.Userinfo {
+	if c.base != nil {
+		if info := c.base(hostPort); info != nil {
+			return info
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.byHost[promptHost(hostPort)]
+}
+
+// ask prompts for the host of hostPort unless it was asked about already.
+// Prompts are serialized, so a second proxy on the host waits for the
+// first answer instead of asking again.
+func (c *credentialPrompt) ask(hostPort string) *url.Userinfo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	host := promptHost(hostPort)
+	if info, asked := c.byHost[host]; asked {
+		return info
+	}
+	c.byHost[host] = nil
+	c.p.Say("\nProxy %s requires credentials (empty user skips this host).", hostPort)
+	user, err := c.p.Line("user: ")
+	if err != nil || user == "" {
+		return nil
+	}
+	pass, err := c.p.Secret("password: ")
+	if err != nil {
+		return nil
+	}
+	c.byHost[host] = url.UserPassword(user, pass)
+	return c.byHost[host]
+}
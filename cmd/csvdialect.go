@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/drsoft-oss/proxybench/internal/output"
+)
+
+var (
+	csvDelimiter string
+	csvCRLF      bool
+	csvNoHeader  bool
+	csvDecimal   string
+)
+
+func init() {
+	for _, c := range []*cobra.Command{checkCmd, benchCmd} {
+		c.Flags().StringVar(&csvDelimiter, "csv-delimiter", "comma", "CSV field delimiter: comma|semicolon|tab")
+		c.Flags().BoolVar(&csvCRLF, "csv-crlf", false, "end CSV lines with CRLF (Windows)")
+		c.Flags().BoolVar(&csvNoHeader, "csv-no-header", false, "omit the CSV header row")
+		c.Flags().StringVar(&csvDecimal, "csv-decimal", "point", "CSV decimal separator: point|comma (e.g. for Excel in German or French locales)")
+	}
+}
+
+// csvDialect builds the CSV dialect from the --csv-* flags.
+func csvDialect() (output.CSVDialect, error) {
+	d := output.CSVDialect{CRLF: csvCRLF, NoHeader: csvNoHeader}
+	var err error
+	if d.Comma, err = output.ParseDelimiter(csvDelimiter); err != nil {
+		return d, err
+	}
+	d.DecimalComma, err = output.ParseDecimal(csvDecimal)
+	return d, err
+}
@@ -2,7 +2,12 @@ package cmd
 
 import (
 	"fmt"
+	"net/url"
 	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -24,30 +29,139 @@ and saves it to the proxybench data directory.
 The database is used by the 'check' command to resolve proxy IP addresses to
 country codes. It is updated monthly by the upstream provider.
 
+With --url, the database is fetched from another location instead, such as an
+internal mirror or a licensed download. A .gz URL is decompressed, and
+{YYYY-MM} is replaced with the current month. --format makes the update fail,
+keeping the installed database, if the download is in another layout.
+
 Examples:
   proxybench db update
   proxybench db update --dest /etc/proxybench/ip2country.csv
-  proxybench db update --timeout 120`,
+  proxybench db update --timeout 120
+  proxybench db update --url https://mirror.example.com/ip2location-lite-db1.csv.gz --format ip2location
+  proxybench db update --delta https://mirror.example.com/ip2country-{YYYY-MM}.delta.gz`,
 	RunE: runDBUpdate,
 }
 
+var dbDiffCmd = &cobra.Command{
+	Use:   "diff <old.csv> <new.csv>",
+	Short: "Write the delta that patches one database into another",
+	Long: `Diff compares two IP-to-country CSVs and writes the ranges removed and
+added between them to stdout. Publish the delta on a mirror, and machines
+holding the old database can move to the new one with 'db update --delta'
+instead of downloading it in full. Checksums in the delta make sure it is
+only applied to the database it was made from.
+
+Example:
+  proxybench db diff 2026-09.csv 2026-10.csv | gzip > ip2country-2026-10.delta.gz`,
+	Args: cobra.ExactArgs(2),
+	RunE: runDBDiff,
+}
+
+var dbAttachCmd = &cobra.Command{
+	Use:   "attach <kind> <csv>",
+	Short: "Add an ASN, city or country database as a lookup layer",
+	Long: `Attach adds a database to the layers consulted next to the main
+country database, so lookups in 'check' and 'bench' --geo also report the
+ASN, region and city. Kind is asn, city or country; ip2location LITE
+(ASN, DB3 and up) and DB-IP lite CSVs are read. Each field comes from the
+first layer that has it, the main database first.
+
+Layers are recorded, one "kind path" line each, in layers.conf next to the
+main database, which can also be edited by hand.
+
+Examples:
+  proxybench db attach asn ~/geo/IP2LOCATION-LITE-ASN.CSV
+  proxybench db attach city ~/geo/dbip-city-lite-2026-10.csv`,
+	Args: cobra.ExactArgs(2),
+	RunE: runDBAttach,
+}
+
+var dbDetachCmd = &cobra.Command{
+	Use:   "detach <csv>",
+	Short: "Remove a lookup layer added with 'db attach'",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runDBDetach,
+}
+
+var dbVerifyCmd = &cobra.Command{
+	Use:   "verify [csv]",
+	Short: "Check a database for corruption",
+	Long: `Verify parses an IP-to-country CSV and checks that it has ranges, that no
+range is inverted or overlaps the previous one, and that its binary index (if
+fresh) resolves every range like the CSV. It prints the database checksum,
+which matches the result checksum of the delta it was last patched with.
+Exits non-zero when a problem is found.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runDBVerify,
+}
+
+var dbStatsCmd = &cobra.Command{
+	Use:   "stats [csv]",
+	Short: "Show what a database covers, per country and overall",
+	Long: `Stats reports the ranges and addresses of an IP-to-country CSV per country,
+the share of public IPv4 space it places, its IPv4/IPv6 split and the largest
+public spans it leaves unplaced, to sanity-check a newly installed database.
+Special-use space (private, loopback, multicast, ...) is left out of the
+coverage figure and the gaps.
+
+Examples:
+  proxybench db stats
+  proxybench db stats --top 0 --gaps 20 ip2location-lite-db1.csv`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runDBStats,
+}
+
 var dbInfoCmd = &cobra.Command{
 	Use:   "info",
 	Short: "Show information about the currently loaded database",
 	RunE:  runDBInfo,
 }
 
+var dbIndexCmd = &cobra.Command{
+	Use:   "index [csv]",
+	Short: "Compile a CSV database into a memory-mapped binary index",
+	Long: `Index compiles an IP-to-country CSV into a binary index stored next to it
+(ip2country.csv → ip2country.idx). Lookups then memory-map the index instead
+of loading every range into memory, which keeps large databases usable on
+small machines. 'db update' rebuilds the index automatically; run this after
+replacing the CSV by hand.
+
+Examples:
+  proxybench db index
+  proxybench db index /etc/proxybench/ip2country.csv`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runDBIndex,
+}
+
 var (
 	dbUpdateDest    string
 	dbUpdateTimeout int
+	dbUpdateURL     string
+	dbUpdateFormat  string
+	dbUpdateDelta   string
+	dbStatsTop      int
+	dbStatsGaps     int
 )
 
 func init() {
 	dbCmd.AddCommand(dbUpdateCmd)
 	dbCmd.AddCommand(dbInfoCmd)
+	dbCmd.AddCommand(dbIndexCmd)
+	dbCmd.AddCommand(dbDiffCmd)
+	dbCmd.AddCommand(dbVerifyCmd)
+	dbCmd.AddCommand(dbAttachCmd)
+	dbCmd.AddCommand(dbDetachCmd)
+	dbCmd.AddCommand(dbStatsCmd)
 
 	dbUpdateCmd.Flags().StringVarP(&dbUpdateDest, "dest", "d", "", "destination path (default: auto-detect)")
 	dbUpdateCmd.Flags().IntVarP(&dbUpdateTimeout, "timeout", "t", 120, "download timeout in seconds")
+	dbUpdateCmd.Flags().StringVar(&dbUpdateURL, "url", "", "download from this URL instead of db-ip.com (.gz is decompressed)")
+	dbUpdateCmd.Flags().StringVar(&dbUpdateDelta, "delta", "", "patch the installed database with a delta from 'db diff' (file or URL) instead of a full download")
+	dbUpdateCmd.Flags().StringVar(&dbUpdateFormat, "format", "", "required database layout: dbip|ip2location (default: any for --url)")
+
+	dbStatsCmd.Flags().IntVar(&dbStatsTop, "top", 20, "countries to list, most addresses first (0 = all)")
+	dbStatsCmd.Flags().IntVar(&dbStatsGaps, "gaps", 10, "largest unplaced spans to list")
 }
 
 func runDBUpdate(cmd *cobra.Command, args []string) error {
@@ -58,22 +172,212 @@ func runDBUpdate(cmd *cobra.Command, args []string) error {
 			fmt.Fprintln(os.Stderr, msg)
 		},
 	}
-
-	if err := geo.Update(opts); err != nil {
-		return fmt.Errorf("db update failed: %w", err)
-	}
-
-	// Verify the file loads cleanly.
 	dest := dbUpdateDest
 	if dest == "" {
 		dest = geo.DefaultDBPath()
 	}
+
+	if dbUpdateDelta != "" {
+		if dbUpdateURL != "" || dbUpdateFormat != "" {
+			return fmt.Errorf("--delta cannot be combined with --url or --format")
+		}
+		fmt.Fprintf(os.Stderr, "Applying delta %s …\n", dbUpdateDelta)
+		removed, added, err := geo.ApplyDelta(dest, dbUpdateDelta, opts.Timeout)
+		if err != nil {
+			return fmt.Errorf("db update failed: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Patched %s: %d ranges removed, %d added\n", dest, removed, added)
+	} else {
+		if dbUpdateURL != "" || dbUpdateFormat != "" {
+			src, err := updateSource()
+			if err != nil {
+				return err
+			}
+			opts.Source = src
+		}
+		if err := geo.Update(opts); err != nil {
+			return fmt.Errorf("db update failed: %w", err)
+		}
+	}
+
+	// Verify the file loads cleanly.
 	fmt.Fprintf(os.Stderr, "Verifying database…\n")
 	db := &geo.DB{}
 	if err := db.LoadFile(dest); err != nil {
 		return fmt.Errorf("verification failed: %w", err)
 	}
 	fmt.Fprintf(os.Stderr, "✓ Database loaded successfully (%d entries)\n", db.Count())
+	return db.Close()
+}
+
+// updateSource returns the source for --url and --format: the builtin
+// source unless --url is set, with Format replaced when --format is.
+func updateSource() (*geo.Source, error) {
+	src := geo.BuiltinSources[0]
+	if dbUpdateURL != "" {
+		u, err := url.Parse(dbUpdateURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return nil, fmt.Errorf("--url must be an http(s) URL")
+		}
+		src = geo.Source{
+			Name:    path.Base(u.Path),
+			URL:     dbUpdateURL,
+			Gzipped: strings.HasSuffix(u.Path, ".gz"),
+		}
+	}
+	if dbUpdateFormat != "" {
+		f, err := geo.ParseFormat(dbUpdateFormat)
+		if err != nil {
+			return nil, err
+		}
+		src.Format = f
+	}
+	return &src, nil
+}
+
+func runDBDiff(cmd *cobra.Command, args []string) error {
+	removed, added, err := geo.Diff(args[0], args[1], os.Stdout)
+	if err != nil {
+		return fmt.Errorf("db diff failed: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "%d ranges removed, %d added\n", removed, added)
+	return nil
+}
+
+func runDBVerify(cmd *cobra.Command, args []string) error {
+	path := geo.DefaultDBPath()
+	if len(args) == 1 {
+		path = args[0]
+	}
+	rep, err := geo.Verify(path)
+	if err != nil {
+		return fmt.Errorf("db verify failed: %w", err)
+	}
+	fmt.Printf("Path:     %s\n", path)
+	fmt.Printf("Format:   %s\n", rep.Format)
+	fmt.Printf("Ranges:   %d\n", rep.Ranges)
+	fmt.Printf("SHA-256:  %s\n", rep.Checksum)
+	if rep.Inverted > 0 {
+		fmt.Printf("Inverted: %d ranges end before they start\n", rep.Inverted)
+	}
+	if rep.Overlaps > 0 {
+		fmt.Printf("Overlaps: %d ranges overlap the previous one\n", rep.Overlaps)
+	}
+	if rep.Indexed {
+		fmt.Printf("Index:    %d ranges resolve differently from the CSV\n", rep.IndexMismatches)
+	}
+	if !rep.OK() {
+		return fmt.Errorf("database %s failed verification", path)
+	}
+	fmt.Printf("Status:   OK\n")
+	return nil
+}
+
+func runDBStats(cmd *cobra.Command, args []string) error {
+	path := geo.DefaultDBPath()
+	if len(args) == 1 {
+		path = args[0]
+	}
+	st, err := geo.ComputeStats(path, dbStatsGaps)
+	if err != nil {
+		return fmt.Errorf("db stats failed: %w", err)
+	}
+	fmt.Printf("Path:      %s\n", path)
+	fmt.Printf("Format:    %s\n", st.Format)
+	fmt.Printf("Ranges:    %d IPv4, %d IPv6 (IPv6 rows are not used for lookups)\n", st.IPv4Ranges, st.IPv6Ranges)
+	fmt.Printf("Countries: %d\n", len(st.Countries))
+	fmt.Printf("Coverage:  %d of %d public IPv4 addresses (%.1f%%)\n", st.Addresses, st.Public, 100*st.Coverage())
+
+	countries := st.Countries
+	if dbStatsTop > 0 && len(countries) > dbStatsTop {
+		countries = countries[:dbStatsTop]
+	}
+	if len(countries) > 0 {
+		fmt.Printf("\n%-4s %-30s %8s %14s %7s\n", "CC", "COUNTRY", "RANGES", "ADDRESSES", "SHARE")
+		for _, c := range countries {
+			fmt.Printf("%-4s %-30s %8d %14d %6.2f%%\n", c.Code, truncateName(c.Name, 30), c.Ranges, c.Addresses, 100*float64(c.Addresses)/float64(st.Public))
+		}
+		if n := len(st.Countries) - len(countries); n > 0 {
+			fmt.Printf("... %d more (--top 0 lists all)\n", n)
+		}
+	}
+	if len(st.Gaps) > 0 {
+		fmt.Printf("\nLargest gaps:\n")
+		for _, g := range st.Gaps {
+			fmt.Printf("  %-33s %12d addresses\n", g, g.Size())
+		}
+	}
+	return nil
+}
+
+// truncateName shortens s to n runes for a fixed-width column.
+func truncateName(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n-1]) + "…"
+	}
+	return s
+}
+
+func runDBAttach(cmd *cobra.Command, args []string) error {
+	kind, err := geo.ParseKind(args[0])
+	if err != nil {
+		return err
+	}
+	abs, err := filepath.Abs(args[1])
+	if err != nil {
+		return err
+	}
+	layer := geo.Layer{Kind: kind, Path: abs}
+	ld := &geo.Layered{}
+	if err := ld.Attach(layer); err != nil {
+		return fmt.Errorf("db attach failed: %w", err)
+	}
+
+	conf := geo.LayersPath()
+	layers, err := geo.ReadLayers(conf)
+	if err != nil {
+		return err
+	}
+	layers = slices.DeleteFunc(layers, func(l geo.Layer) bool { return l.Path == abs })
+	if err := geo.WriteLayers(conf, append(layers, layer)); err != nil {
+		return fmt.Errorf("db attach failed: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "✓ Attached %s layer %s\n", kind, abs)
+	return nil
+}
+
+func runDBDetach(cmd *cobra.Command, args []string) error {
+	abs, err := filepath.Abs(args[0])
+	if err != nil {
+		return err
+	}
+	conf := geo.LayersPath()
+	layers, err := geo.ReadLayers(conf)
+	if err != nil {
+		return err
+	}
+	kept := slices.DeleteFunc(slices.Clone(layers), func(l geo.Layer) bool { return l.Path == abs })
+	if len(kept) == len(layers) {
+		return fmt.Errorf("%s is not attached", abs)
+	}
+	if err := geo.WriteLayers(conf, kept); err != nil {
+		return fmt.Errorf("db detach failed: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "✓ Detached %s\n", abs)
+	return nil
+}
+
+func runDBIndex(cmd *cobra.Command, args []string) error {
+	path := geo.DefaultDBPath()
+	if len(args) == 1 {
+		path = args[0]
+	}
+	idxPath := geo.IndexPath(path)
+	n, err := geo.BuildIndex(path, idxPath)
+	if err != nil {
+		return fmt.Errorf("db index failed: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "✓ Indexed %d ranges → %s\n", n, idxPath)
 	return nil
 }
 
@@ -93,12 +397,28 @@ func runDBInfo(cmd *cobra.Command, args []string) error {
 	fmt.Printf("Size:     %.1f MB\n", float64(info.Size())/(1<<20))
 	fmt.Printf("Modified: %s\n", info.ModTime().Format("2006-01-02 15:04:05"))
 
+	if format, err := geo.DetectFormat(path); err == nil {
+		fmt.Printf("Format:   %s\n", format)
+	}
+
 	db := &geo.DB{}
 	if err := db.LoadFile(path); err != nil {
 		fmt.Printf("Status:   ERROR - %v\n", err)
 	} else {
 		fmt.Printf("Entries:  %d\n", db.Count())
+		if db.Indexed() {
+			fmt.Printf("Index:    %s (memory-mapped)\n", geo.IndexPath(path))
+		} else {
+			fmt.Printf("Index:    none (run `proxybench db index`)\n")
+		}
 		fmt.Printf("Status:   OK\n")
 	}
-	return nil
+	if layers, err := geo.ReadLayers(geo.LayersPath()); err != nil {
+		fmt.Printf("Layers:   ERROR - %v\n", err)
+	} else {
+		for _, l := range layers {
+			fmt.Printf("Layer:    %-7s %s\n", l.Kind, l.Path)
+		}
+	}
+	return db.Close()
 }
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"context"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/drsoft-oss/proxybench/internal/checker"
+)
+
+var (
+	dedupInput      bool
+	countDuplicates bool
+)
+
+func init() {
+	for _, c := range []*cobra.Command{checkCmd, benchCmd} {
+		c.Flags().BoolVar(&dedupInput, "dedup", true, "skip addresses that normalize to one already read (scheme/host case, default port, credential escaping)")
+		c.Flags().BoolVar(&countDuplicates, "count-duplicates", false, "read the whole input first and report how many times each proxy was listed in a count column; implies --dedup")
+	}
+}
+
+// inputCounts maps normalized addresses to the number of input lines that
+// named them.
+type inputCounts map[string]int
+
+// of returns how often address was listed, or 0 when counting is off. The
+// check may have given a bare host:port a scheme, so that form is tried
+// too.
+func (c inputCounts) of(address string) int {
+	if c == nil {
+		return 0
+	}
+	if n, ok := c[checker.Normalize(address)]; ok {
+		return n
+	}
+	if _, rest, ok := strings.Cut(address, "://"); ok {
+		return c[checker.Normalize(rest)]
+	}
+	return 0
+}
+
+// uniqueAddresses drops repeats of an address from in, keeping the first
+// form seen. With --count-duplicates the input is read in full before
+// anything is passed on, so the counts are complete by the first result.
+func uniqueAddresses(ctx context.Context, in <-chan string) (<-chan string, inputCounts) {
+	if !dedupInput && !countDuplicates {
+		return in, nil
+	}
+	var counts inputCounts
+	var unique []string
+	if countDuplicates {
+		counts = inputCounts{}
+		for a := range in {
+			key := checker.Normalize(a)
+			if counts[key] == 0 {
+				unique = append(unique, a)
+			}
+			counts[key]++
+		}
+	}
+
+	out := make(chan string)
+	send := func(s string) bool {
+		select {
+		case out <- s:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	go func() {
+		defer close(out)
+		if counts != nil {
+			for _, a := range unique {
+				if !send(a) {
+					return
+				}
+			}
+			return
+		}
+		seen := map[string]bool{}
+		for a := range in {
+			key := checker.Normalize(a)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			if !send(a) {
+				return
+			}
+		}
+	}()
+	return out, counts
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Exit codes for a run that completed but missed a --require-alive or
+// --fail-if-dead-over threshold; 1 stays the code for errors.
+const (
+	exitTooFewAlive = 2
+	exitTooManyDead = 3
+)
+
+var (
+	requireAlive   int
+	failIfDeadOver string
+)
+
+func init() {
+	for _, c := range []*cobra.Command{checkCmd, benchCmd} {
+		c.Flags().IntVar(&requireAlive, "require-alive", 0, "exit with code 2 unless at least this many proxies are alive (bench: reachable)")
+		c.Flags().StringVar(&failIfDeadOver, "fail-if-dead-over", "", "exit with code 3 if more proxies than this are dead: a count, or a share like 20%")
+	}
+}
+
+// exitError ends the process with code after the error is printed.
+type exitError struct {
+	code int
+	msg  string
+}
+
+func (e *exitError) Error() string { return e.msg }
+
+// healthGate holds the run-outcome thresholds; the zero value passes
+// every run.
+type healthGate struct {
+	minAlive int
+	maxDead  float64 // a count, or a fraction of the total with share
+	share    bool
+}
+
+func parseHealthGate() (healthGate, error) {
+	g := healthGate{minAlive: requireAlive}
+	if requireAlive < 0 {
+		return g, fmt.Errorf("--require-alive must not be negative")
+	}
+	if failIfDeadOver == "" {
+		g.maxDead = -1
+		return g, nil
+	}
+	s := strings.TrimSpace(failIfDeadOver)
+	pct, isShare := strings.CutSuffix(s, "%")
+	v, err := strconv.ParseFloat(strings.TrimSpace(pct), 64)
+	if err != nil || v < 0 || isShare && v > 100 {
+		return g, fmt.Errorf("--fail-if-dead-over: want a count or a percentage like 20%%, got %q", failIfDeadOver)
+	}
+	if isShare {
+		g.maxDead, g.share = v/100, true
+	} else {
+		g.maxDead = v
+	}
+	return g, nil
+}
+
+// check returns an *exitError if the run missed a threshold, silencing
+// cobra's usage text since the command line was fine.
+func (g healthGate) check(cmd *cobra.Command, total, alive int) error {
+	dead := total - alive
+	var err *exitError
+	switch {
+	case alive < g.minAlive:
+		err = &exitError{exitTooFewAlive, fmt.Sprintf("only %d of %d proxies alive, want at least %d", alive, total, g.minAlive)}
+	case g.share && total > 0 && float64(dead)/float64(total) > g.maxDead:
+		err = &exitError{exitTooManyDead, fmt.Sprintf("%d of %d proxies dead (%.1f%%), over --fail-if-dead-over %s",
+			dead, total, 100*float64(dead)/float64(total), failIfDeadOver)}
+	case !g.share && g.maxDead >= 0 && float64(dead) > g.maxDead:
+		err = &exitError{exitTooManyDead, fmt.Sprintf("%d of %d proxies dead, over --fail-if-dead-over %s", dead, total, failIfDeadOver)}
+	}
+	if err == nil {
+		return nil
+	}
+	cmd.SilenceUsage = true
+	return err
+}
@@ -0,0 +1,221 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/drsoft-oss/proxybench/internal/checker"
+	"github.com/drsoft-oss/proxybench/internal/classify"
+	"github.com/drsoft-oss/proxybench/internal/output"
+)
+
+var filterCmd = &cobra.Command{
+	Use:   "filter [results-file...]",
+	Short: "Select rows from earlier check or bench results",
+	Long: `Filter reads results written by 'check' or 'bench' in JSON, NDJSON or CSV
+(from the files given, or stdin) and writes the rows matching every
+condition in any output format.
+
+Examples:
+  proxybench filter --alive --country US,DE results.json
+  proxybench filter --protocol socks5 --max-latency 500 -f csv results.csv
+  proxybench filter --min-speed 1MB --format json bench.ndjson
+  proxybench check -f ndjson < proxies.txt | proxybench filter --error-kind timeout,refused`,
+	RunE: runFilter,
+}
+
+var (
+	filterFormat     string
+	filterAlive      bool
+	filterDead       bool
+	filterProtocols  string
+	filterCountries  string
+	filterMaxLatency int64
+	filterMinSpeed   string
+	filterErrorKinds string
+	filterClasses    string
+	filterChain      string
+)
+
+func init() {
+	f := filterCmd.Flags()
+	f.StringVarP(&filterFormat, "format", "f", "table", "output format: table|json|ndjson|csv|proxychains")
+	f.BoolVar(&filterAlive, "alive", false, "only alive proxies (bench: reachable)")
+	f.BoolVar(&filterDead, "dead", false, "only dead proxies (bench: unreachable)")
+	f.StringVar(&filterProtocols, "protocol", "", "only these protocols, e.g. socks5,http")
+	f.StringVar(&filterCountries, "country", "", "only these country codes, e.g. US,DE")
+	f.Int64Var(&filterMaxLatency, "max-latency", 0, "only proxies measured at or under this latency in ms (bench: p50)")
+	f.StringVar(&filterMinSpeed, "min-speed", "", "only bench rows with at least this speed in bytes/sec, e.g. 512KB")
+	f.StringVar(&filterErrorKinds, "error-kind", "", "only check rows that failed with these kinds, e.g. timeout,refused")
+	f.StringVar(&filterClasses, "class", "", "only these latency classes, e.g. fast,medium")
+	f.StringVar(&filterChain, "chain", "dynamic", "chain type written by --format proxychains: dynamic|strict")
+}
+
+// rowFilter holds the parsed conditions; a zero field matches everything.
+type rowFilter struct {
+	alive, dead bool
+	protocols   classify.Filter
+	countries   classify.Filter
+	maxLatency  int64
+	minSpeed    float64
+	errorKinds  classify.Filter
+	classes     classify.Filter
+}
+
+func newRowFilter() (rowFilter, error) {
+	rf := rowFilter{
+		alive:      filterAlive,
+		dead:       filterDead,
+		protocols:  classify.ParseFilter(strings.ToLower(filterProtocols)),
+		countries:  classify.ParseFilter(strings.ToUpper(filterCountries)),
+		maxLatency: filterMaxLatency,
+		errorKinds: classify.ParseFilter(strings.ToLower(filterErrorKinds)),
+		classes:    classify.ParseFilter(filterClasses),
+	}
+	if rf.alive && rf.dead {
+		return rf, fmt.Errorf("--alive and --dead exclude each other")
+	}
+	if filterMinSpeed != "" {
+		v, err := classify.ParseBytes(filterMinSpeed)
+		if err != nil {
+			return rf, fmt.Errorf("--min-speed: %w", err)
+		}
+		rf.minSpeed = v
+	}
+	return rf, nil
+}
+
+// common applies the conditions check and bench rows share.
+func (rf rowFilter) common(ok bool, proto checker.Protocol, country, class string, latencyMS int64) bool {
+	switch {
+	case rf.alive && !ok, rf.dead && ok:
+		return false
+	case rf.protocols != nil && !rf.protocols.Match(string(proto)):
+		return false
+	case rf.countries != nil && !rf.countries.Match(country):
+		return false
+	case rf.classes != nil && !rf.classes.Match(class):
+		return false
+	case rf.maxLatency > 0 && (latencyMS <= 0 || latencyMS > rf.maxLatency):
+		return false
+	}
+	return true
+}
+
+func (rf rowFilter) check(rec output.CheckRecord) bool {
+	r := rec.Result
+	proto := r.Protocol
+	if r.DetectedProtocol != "" {
+		proto = r.DetectedProtocol
+	}
+	var lat int64
+	if r.Alive {
+		lat = r.LatencyMS()
+	}
+	if !rf.common(r.Alive, proto, rec.Geo.CountryCode, r.Class, lat) || rf.minSpeed > 0 {
+		return false
+	}
+	if rf.errorKinds != nil {
+		return r.Failure != nil && rf.errorKinds.Match(string(r.Failure.Kind))
+	}
+	return true
+}
+
+func (rf rowFilter) bench(rec output.BenchRecord) bool {
+	s := rec.Stats
+	if !rf.common(s.OK(), checker.DetectProtocol(s.Address), rec.Geo.CountryCode, s.LatencyClass, s.P50MS) {
+		return false
+	}
+	if rf.minSpeed > 0 && float64(s.SpeedBps) < rf.minSpeed {
+		return false
+	}
+	return rf.errorKinds == nil
+}
+
+func runFilter(cmd *cobra.Command, args []string) error {
+	rf, err := newRowFilter()
+	if err != nil {
+		return err
+	}
+	chain, err := output.ParseChain(filterChain)
+	if err != nil {
+		return err
+	}
+
+	var results output.Results
+	if len(args) == 0 {
+		args = []string{"-"}
+	}
+	for _, path := range args {
+		if err := readResultsFile(&results, path); err != nil {
+			return err
+		}
+	}
+
+	kept, err := writeResults(&results, output.Format(filterFormat), chain, rf)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Kept %d of %d results\n", kept, results.Len())
+	return nil
+}
+
+// writeResults writes the rows of results that rf keeps to stdout in
+// format, with the results' run metadata, and returns how many it wrote.
+func writeResults(results *output.Results, format output.Format, chain output.Chain, rf rowFilter) (int, error) {
+	var err error
+	kept := 0
+	if len(results.Benches) > 0 {
+		withGeo := false
+		for _, rec := range results.Benches {
+			withGeo = withGeo || rec.Geo.CountryCode != ""
+		}
+		w := output.NewBenchWriter(os.Stdout, format, withGeo)
+		w.Meta = results.Meta
+		for _, rec := range results.Benches {
+			if rf.bench(rec) {
+				kept++
+				if err := w.WriteGeo(rec.Stats, rec.Geo); err != nil {
+					return kept, err
+				}
+			}
+		}
+		err = w.Close()
+	} else {
+		w := output.NewCheckWriter(os.Stdout, format)
+		w.Meta = results.Meta
+		w.Chain = chain
+		for _, rec := range results.Checks {
+			if rf.check(rec) {
+				kept++
+				if err := w.WriteGeo(rec.Result, rec.Geo); err != nil {
+					return kept, err
+				}
+			}
+		}
+		err = w.Close()
+	}
+	return kept, err
+}
+
+// readResultsFile appends the results in path ("-" for stdin) to rs.
+func readResultsFile(rs *output.Results, path string) error {
+	if path == "-" {
+		if err := rs.ReadResults(os.Stdin); err != nil {
+			return fmt.Errorf("stdin: %w", err)
+		}
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := rs.ReadResults(f); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/drsoft-oss/proxybench/internal/checker"
+	"github.com/drsoft-oss/proxybench/internal/history"
+	"github.com/drsoft-oss/proxybench/internal/monitor"
+)
+
+var (
+	skipIfChecked time.Duration
+	unlessFailed  bool
+)
+
+func init() {
+	for _, c := range []*cobra.Command{checkCmd, monitorCmd} {
+		c.Flags().DurationVar(&skipIfChecked, "skip-if-checked", 0, "skip proxies already checked within this long, e.g. 30m (check: by the --history database, which it then records to; monitor: by its state)")
+		c.Flags().BoolVar(&unlessFailed, "unless-failed", false, "with --skip-if-checked, still re-check proxies whose last check failed")
+	}
+}
+
+// freshness holds when proxies were last checked, by normalized address,
+// and skips those --skip-if-checked says need no check yet.
+type freshness struct {
+	last           map[string]history.Latest
+	now            time.Time
+	skipped, alive atomic.Int64
+}
+
+// validateFreshness checks the --skip-if-checked flags.
+func validateFreshness() error {
+	if skipIfChecked < 0 {
+		return fmt.Errorf("--skip-if-checked must not be negative")
+	}
+	if unlessFailed && skipIfChecked == 0 {
+		return fmt.Errorf("--unless-failed needs --skip-if-checked")
+	}
+	return nil
+}
+
+// loadFreshness reads the check runs of the last --skip-if-checked from
+// the history database, or returns nil without the flag.
+func loadFreshness() (*freshness, error) {
+	if err := validateFreshness(); err != nil || skipIfChecked == 0 {
+		return nil, err
+	}
+	store, err := history.Open(historyPath)
+	if err != nil {
+		return nil, fmt.Errorf("--skip-if-checked: %w", err)
+	}
+	defer store.Close()
+	now := time.Now()
+	latest, err := store.LatestChecks(now.Add(-skipIfChecked))
+	if err != nil {
+		return nil, fmt.Errorf("--skip-if-checked: %w", err)
+	}
+	return newFreshness(now, latest), nil
+}
+
+// monitorFreshness is loadFreshness for a monitor round: it reads the
+// tracker's state of addresses.
+func monitorFreshness(tracker *monitor.Tracker, addresses []string) (*freshness, error) {
+	if skipIfChecked == 0 {
+		return nil, nil
+	}
+	var keys []string
+	for _, a := range addresses {
+		keys = append(keys, resultAddresses(a)...)
+	}
+	states, err := tracker.States(keys)
+	if err != nil {
+		return nil, err
+	}
+	latest := make(map[string]history.Latest, len(states))
+	for a, s := range states {
+		latest[a] = history.Latest{At: s.LastCheck, Alive: s.Alive}
+	}
+	return newFreshness(time.Now(), latest), nil
+}
+
+func newFreshness(now time.Time, latest map[string]history.Latest) *freshness {
+	f := &freshness{last: make(map[string]history.Latest, len(latest)), now: now}
+	for a, l := range latest {
+		key := checker.Normalize(a)
+		if prev, ok := f.last[key]; !ok || l.At.After(prev.At) {
+			f.last[key] = l
+		}
+	}
+	return f
+}
+
+// resultAddresses returns the addresses a check of a may be recorded
+// under: a itself, and for a bare host:port the addresses protocol
+// detection gives it.
+func resultAddresses(a string) []string {
+	if strings.Contains(a, "://") {
+		return []string{a}
+	}
+	return []string{a, "socks5://" + a, "http://" + a}
+}
+
+// fresh reports whether a was checked recently enough to skip, and
+// whether it was alive then.
+func (f *freshness) fresh(a string) (skip, alive bool) {
+	var last history.Latest
+	for _, r := range resultAddresses(a) {
+		if l, ok := f.last[checker.Normalize(r)]; ok && l.At.After(last.At) {
+			last = l
+		}
+	}
+	if last.At.IsZero() || f.now.Sub(last.At) >= skipIfChecked || (unlessFailed && !last.Alive) {
+		return false, false
+	}
+	return true, last.Alive
+}
+
+// skip passes on the addresses from in that need checking and counts the
+// others. A nil freshness passes everything.
+func (f *freshness) skip(ctx context.Context, in <-chan string) <-chan string {
+	if f == nil {
+		return in
+	}
+	out := make(chan string)
+	go func() {
+		defer close(out)
+		for a := range in {
+			if skip, alive := f.fresh(a); skip {
+				f.skipped.Add(1)
+				if alive {
+					f.alive.Add(1)
+				}
+				continue
+			}
+			select {
+			case out <- a:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// counts returns how many proxies were skipped and how many of those were
+// alive when last checked.
+func (f *freshness) counts() (skipped, alive int) {
+	if f == nil {
+		return 0, 0
+	}
+	return int(f.skipped.Load()), int(f.alive.Load())
+}
+
+// report notes the skipped proxies on stderr.
+func (f *freshness) report() {
+	if n, alive := f.counts(); n > 0 {
+		fmt.Fprintf(os.Stderr, "Skipped %d proxies checked within %s (%d alive then; --skip-if-checked)\n", n, skipIfChecked, alive)
+	}
+}
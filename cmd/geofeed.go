@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/drsoft-oss/proxybench/internal/geo"
+)
+
+var geofeeds []string
+
+func init() {
+	for _, c := range []*cobra.Command{checkCmd, benchCmd} {
+		c.Flags().StringArrayVar(&geofeeds, "geofeed", nil, "RFC 8805 geofeed (file or URL) whose prefixes override the geo DB; repeatable")
+	}
+}
+
+// loadGeofeeds overlays every --geofeed on db. A feed that cannot be read
+// is reported and skipped, like a missing geo DB.
+func loadGeofeeds(db *geo.DB) {
+	for _, path := range geofeeds {
+		if _, err := db.LoadGeofeed(path); err != nil {
+			fmt.Fprintf(os.Stderr, "warn: geofeed %s: %v\n", path, err)
+		}
+	}
+}
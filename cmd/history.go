@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/drsoft-oss/proxybench/internal/bench"
+	"github.com/drsoft-oss/proxybench/internal/checker"
+	"github.com/drsoft-oss/proxybench/internal/history"
+	"github.com/drsoft-oss/proxybench/internal/output"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Browse previously recorded check and bench runs",
+	Long: `History reads the local run database written by 'check --history' and
+'bench --history'.
+
+Examples:
+  proxybench history list
+  proxybench history show 12 --format json
+  proxybench history prune --older-than 30d
+  proxybench history prune --keep 100`,
+}
+
+var historyListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List recorded runs, newest first",
+	Args:  cobra.NoArgs,
+	RunE:  runHistoryList,
+}
+
+var historyShowCmd = &cobra.Command{
+	Use:   "show <run-id>",
+	Short: "Print the results of a recorded run",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runHistoryShow,
+}
+
+var historyPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Delete old runs",
+	Args:  cobra.NoArgs,
+	RunE:  runHistoryPrune,
+}
+
+var (
+	historyPath      string
+	historyLimit     int
+	historyFormat    string
+	historyOlderThan string
+	historyKeep      int
+
+	checkHistory bool
+	benchHistory bool
+)
+
+func init() {
+	historyCmd.AddCommand(historyListCmd)
+	historyCmd.AddCommand(historyShowCmd)
+	historyCmd.AddCommand(historyPruneCmd)
+
+	historyCmd.PersistentFlags().StringVar(&historyPath, "history-db", "", "path to history database (default: auto-detect)")
+	historyListCmd.Flags().IntVarP(&historyLimit, "limit", "n", 20, "max runs to list (0 = all)")
+	historyShowCmd.Flags().StringVarP(&historyFormat, "format", "f", "table", "output format: table|json|ndjson|csv")
+	historyPruneCmd.Flags().StringVar(&historyOlderThan, "older-than", "", "delete runs older than this age (e.g. 30d, 12h)")
+	historyPruneCmd.Flags().IntVar(&historyKeep, "keep", 0, "keep only the newest N runs")
+
+	// check and bench opt in to recording with --history.
+	checkCmd.Flags().BoolVar(&checkHistory, "history", false, "record this run in the local history database")
+	checkCmd.Flags().StringVar(&historyPath, "history-db", "", "path to history database (default: auto-detect)")
+	benchCmd.Flags().BoolVar(&benchHistory, "history", false, "record this run in the local history database")
+	benchCmd.Flags().StringVar(&historyPath, "history-db", "", "path to history database (default: auto-detect)")
+}
+
+func runHistoryList(cmd *cobra.Command, args []string) error {
+	store, err := history.Open(historyPath)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	runs, err := store.List(historyLimit)
+	if err != nil {
+		return err
+	}
+	if len(runs) == 0 {
+		fmt.Fprintln(os.Stderr, "No runs recorded yet. Use --history with check or bench.")
+		return nil
+	}
+	fmt.Printf("%6s  %-6s  %-19s  %9s  %6s  %6s\n", "ID", "KIND", "STARTED", "DURATION", "TOTAL", "OK")
+	for _, r := range runs {
+		fmt.Printf("%6d  %-6s  %-19s  %9s  %6d  %6d\n",
+			r.ID, r.Kind, r.StartedAt.Format("2006-01-02 15:04:05"),
+			r.Duration.Round(time.Second/10), r.Total, r.OK)
+	}
+	return nil
+}
+
+func runHistoryShow(cmd *cobra.Command, args []string) error {
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid run id %q", args[0])
+	}
+	store, err := history.Open(historyPath)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	run, err := store.Get(id)
+	if errors.Is(err, history.ErrNotFound) {
+		return fmt.Errorf("run %d not found", id)
+	} else if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Run %d: %s at %s (%d proxies)\n",
+		run.ID, run.Kind, run.StartedAt.Format("2006-01-02 15:04:05"), run.Total)
+
+	switch run.Kind {
+	case history.KindBench:
+		results, err := store.BenchResults(id)
+		if err != nil {
+			return err
+		}
+		return output.WriteBenchResults(os.Stdout, results, nil, output.Format(historyFormat))
+	default:
+		results, err := store.CheckResults(id)
+		if err != nil {
+			return err
+		}
+		return output.WriteCheckResults(os.Stdout, results, nil, output.Format(historyFormat))
+	}
+}
+
+func runHistoryPrune(cmd *cobra.Command, args []string) error {
+	if historyOlderThan == "" && historyKeep <= 0 {
+		return fmt.Errorf("specify --older-than and/or --keep")
+	}
+	var cutoff time.Time
+	if historyOlderThan != "" {
+		age, err := parseAge(historyOlderThan)
+		if err != nil {
+			return fmt.Errorf("--older-than: %w", err)
+		}
+		cutoff = time.Now().Add(-age)
+	}
+
+	store, err := history.Open(historyPath)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	n, err := store.Prune(cutoff, historyKeep)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Pruned %d runs\n", n)
+	return nil
+}
+
+// parseAge accepts Go durations plus a "d" (days) suffix, e.g. "30d".
+func parseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid age %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// recordCheckRun saves a check run for --history; failures only warn.
+func recordCheckRun(started time.Time, results []checker.Result) {
+	recordRun(func(store *history.Store) (int64, error) {
+		return store.SaveCheck(started, time.Since(started), results)
+	})
+}
+
+// recordBenchRun saves a bench run for --history; failures only warn.
+func recordBenchRun(started time.Time, results []bench.Stats) {
+	recordRun(func(store *history.Store) (int64, error) {
+		return store.SaveBench(started, time.Since(started), results)
+	})
+}
+
+func recordRun(save func(*history.Store) (int64, error)) {
+	store, err := history.Open(historyPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warn: history: %v\n", err)
+		return
+	}
+	defer store.Close()
+
+	id, err := save(store)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warn: history: %v\n", err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Recorded as run %d\n", id)
+}
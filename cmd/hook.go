@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/drsoft-oss/proxybench/internal/hook"
+	"github.com/drsoft-oss/proxybench/internal/notify"
+)
+
+var (
+	execOnResult string
+	execOnChange string
+	execTimeout  time.Duration
+)
+
+func init() {
+	for _, c := range []*cobra.Command{checkCmd, benchCmd} {
+		c.Flags().StringVar(&execOnResult, "exec-on-result", "", "shell command run for every result written, with the result as JSON on stdin and in place of {json}")
+	}
+	monitorCmd.Flags().StringVar(&execOnChange, "exec-on-change", "", "shell command run for every proxy that goes down, recovers or regresses, with the event as JSON on stdin and in place of {json}")
+	for _, c := range []*cobra.Command{checkCmd, benchCmd, monitorCmd} {
+		c.Flags().DurationVar(&execTimeout, "exec-timeout", hook.DefaultTimeout, "kill an --exec-on-result or --exec-on-change command after this long")
+	}
+}
+
+// newHook returns the runner for an --exec-on-* command, or nil when it is
+// unset. Its output goes to stderr, keeping stdout for results.
+func newHook(command string) *hook.Runner {
+	if command == "" {
+		return nil
+	}
+	return &hook.Runner{Command: command, Timeout: execTimeout, Concurrency: hook.DefaultConcurrency, Output: os.Stderr}
+}
+
+// runHook passes v to h, if there is one.
+func runHook(h *hook.Runner, v any) {
+	if h != nil {
+		h.Run(context.Background(), v)
+	}
+}
+
+// waitHook waits for h's commands to finish and warns about failed ones.
+func waitHook(h *hook.Runner, flag string) {
+	if h == nil {
+		return
+	}
+	if n, err := h.Wait(); n > 0 {
+		fmt.Fprintf(os.Stderr, "warn: %s failed %d times; first: %v\n", flag, n, err)
+	}
+}
+
+// changeEvent is what --exec-on-change receives for one proxy.
+type changeEvent struct {
+	Time       time.Time `json:"time"`
+	Event      string    `json:"event"` // down, recovered or regression
+	Address    string    `json:"address"`
+	Error      string    `json:"error,omitempty"`
+	BaselineMS int64     `json:"baseline_ms,omitempty"`
+	LatencyMS  int64     `json:"latency_ms,omitempty"`
+}
+
+// changeEvents lists the state changes of one monitor round.
+func changeEvents(sum notify.Summary) []changeEvent {
+	var events []changeEvent
+	for _, d := range sum.Down {
+		events = append(events, changeEvent{Time: sum.Time, Event: "down", Address: d.Address, Error: d.Error})
+	}
+	for _, a := range sum.Recovered {
+		events = append(events, changeEvent{Time: sum.Time, Event: "recovered", Address: a})
+	}
+	for _, r := range sum.Regressions {
+		events = append(events, changeEvent{Time: sum.Time, Event: "regression", Address: r.Address,
+			BaselineMS: r.Baseline.Milliseconds(), LatencyMS: r.Latency.Milliseconds()})
+	}
+	return events
+}
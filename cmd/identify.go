@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/drsoft-oss/proxybench/internal/checker"
+	"github.com/drsoft-oss/proxybench/internal/pool"
+)
+
+var identifyCmd = &cobra.Command{
+	Use:   "identify [host:port...]",
+	Short: "Fingerprint what listens at bare host:port entries and print them with a scheme",
+	Long: `Identify sends a SOCKS5 greeting, a SOCKS4 request and an HTTP request in
+the clear and over TLS to each host:port at once, and reports what answered:
+a proxy (with every protocol it speaks), a TLS service, some other service,
+a silent listener or nothing. Entries come from arguments, stdin or the
+config; a scheme or credentials on an entry are ignored.
+
+The default txt output lists one scheme-qualified address per protocol
+found, ready for 'proxybench check'. Any HTTP reply counts, so plain web
+servers come out as http:// too; checking them tells them apart.
+Shadowsocks answers nothing without its key and shows up as silent.
+
+Examples:
+  proxybench identify 1.2.3.4:1080 5.6.7.8:8080
+  proxybench identify < scanned.txt | proxybench check
+  proxybench identify -f table < scanned.txt`,
+	RunE: runIdentify,
+}
+
+var (
+	identifyFormat      string
+	identifyTimeout     int
+	identifyConcurrency int
+)
+
+func init() {
+	identifyCmd.Flags().StringVarP(&identifyFormat, "format", "f", "txt", "output format: txt|table|json|ndjson")
+	identifyCmd.Flags().IntVarP(&identifyTimeout, "timeout", "t", 5, "per-probe timeout in seconds")
+	identifyCmd.Flags().IntVarP(&identifyConcurrency, "concurrency", "c", 20, "max endpoints probed at once (each gets four connections)")
+}
+
+func runIdentify(cmd *cobra.Command, args []string) error {
+	switch identifyFormat {
+	case "txt", "table", "json", "ndjson":
+	default:
+		return fmt.Errorf("--format: want txt, table, json or ndjson, got %q", identifyFormat)
+	}
+	if err := setupResolver(); err != nil {
+		return err
+	}
+	stopTracing, err := startTracing()
+	if err != nil {
+		return err
+	}
+	defer stopTracing()
+
+	timeout := time.Duration(identifyTimeout) * time.Second
+	out := os.Stdout
+	if identifyFormat == "table" {
+		writeIdentityHeader(out)
+	}
+	var ids []checker.Identity
+	var writeErr error
+	started := time.Now()
+	total, proxies := 0, 0
+	pool.Ordered(streamAddresses(context.Background(), args), identifyConcurrency, func(entry string) checker.Identity {
+		hostPort, ok := bareHostPort(entry)
+		if !ok {
+			return checker.Identity{Address: entry}
+		}
+		return checker.Identify(context.Background(), hostPort, timeout)
+	}, func(id checker.Identity) {
+		if id.Kind == "" {
+			fmt.Fprintf(os.Stderr, "skip: %s: want host:port\n", checker.Redact(id.Address))
+			return
+		}
+		total++
+		if id.Kind == checker.KindProxy {
+			proxies++
+		}
+		if identifyFormat == "json" {
+			ids = append(ids, id)
+		} else if writeErr == nil {
+			writeErr = writeIdentity(out, identifyFormat, id)
+		}
+	})
+	if identifyFormat == "json" {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		if ids == nil {
+			ids = []checker.Identity{}
+		}
+		writeErr = enc.Encode(ids)
+	}
+	if writeErr != nil {
+		return writeErr
+	}
+	if total == 0 {
+		return fmt.Errorf("no addresses provided; pass them as arguments, via stdin or as config sources")
+	}
+	fmt.Fprintf(os.Stderr, "Identified %d endpoints in %s: %d proxies, %d other\n",
+		total, time.Since(started).Round(time.Millisecond), proxies, total-proxies)
+	return nil
+}
+
+// writeIdentityHeader writes the header of the table format.
+func writeIdentityHeader(w io.Writer) {
+	fmt.Fprintf(w, "%-45s %-8s %s\n", "ADDRESS", "KIND", "PROTOCOLS / BANNER")
+	fmt.Fprintln(w, strings.Repeat("─", 90))
+}
+
+// writeIdentity writes one result in the txt, table or ndjson format.
+func writeIdentity(w io.Writer, format string, id checker.Identity) error {
+	var err error
+	switch format {
+	case "txt":
+		for _, p := range id.Proxies {
+			if _, err = fmt.Fprintln(w, p); err != nil {
+				break
+			}
+		}
+	case "ndjson":
+		err = json.NewEncoder(w).Encode(id)
+	default: // table
+		detail := id.Banner
+		if len(id.Protocols) > 0 {
+			names := make([]string, len(id.Protocols))
+			for i, p := range id.Protocols {
+				names[i] = string(p)
+			}
+			detail = strings.Join(names, ", ")
+		}
+		_, err = fmt.Fprintf(w, "%-45s %-8s %s\n", id.Address, id.Kind, detail)
+	}
+	return err
+}
+
+// bareHostPort returns the host:port of an entry, dropping any scheme and
+// credentials: identify probes the listener, not an account on it.
+func bareHostPort(entry string) (string, bool) {
+	if _, rest, ok := strings.Cut(entry, "://"); ok {
+		entry = rest
+	}
+	if i := strings.LastIndex(entry, "@"); i >= 0 {
+		entry = entry[i+1:]
+	}
+	entry = strings.TrimSuffix(entry, "/")
+	host, port, err := net.SplitHostPort(entry)
+	if err != nil || host == "" || port == "" {
+		return "", false
+	}
+	return entry, true
+}
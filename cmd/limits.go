@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/drsoft-oss/proxybench/internal/memlimit"
+	"github.com/drsoft-oss/proxybench/internal/resolver"
+	"github.com/drsoft-oss/proxybench/internal/target"
+)
+
+var (
+	maxMemory string
+
+	// memGuard is the guard loadLimits made for --max-memory, or nil.
+	memGuard *memlimit.Guard
+)
+
+func init() {
+	for _, c := range []*cobra.Command{checkCmd, benchCmd, monitorCmd} {
+		c.Flags().StringVar(&maxMemory, "max-memory", "", "memory to stay under, e.g. 512M or 2G: collect harder as it nears and start no new proxies above 90% of it")
+	}
+}
+
+// loadLimits applies --max-memory.
+func loadLimits() error {
+	if maxMemory == "" {
+		return nil
+	}
+	n, err := target.ParseSize(maxMemory)
+	if err != nil || n == 0 {
+		return fmt.Errorf("--max-memory: want a size like 512M or 2G, got %q", maxMemory)
+	}
+	memGuard = memlimit.New(uint64(n))
+	return nil
+}
+
+// socketPoll is how often throttleAddresses re-checks open sockets.
+const socketPoll = 100 * time.Millisecond
+
+// throttleAddresses passes addresses on, holding each back while memory is
+// near --max-memory or open sockets near --max-sockets, so fewer proxies
+// are worked on at once until the process has room again. It says on
+// stderr the first time it holds back for each reason.
+func throttleAddresses(ctx context.Context, in <-chan string) <-chan string {
+	out := make(chan string)
+	var memWarn, sockWarn sync.Once
+	go func() {
+		defer close(out)
+		for a := range in {
+			if memGuard.Over() {
+				memWarn.Do(func() {
+					fmt.Fprintf(os.Stderr, "warn: memory near --max-memory %s; starting fewer proxies at once\n", maxMemory)
+				})
+			}
+			if memGuard.Wait(ctx) != nil {
+				return
+			}
+			for socketsNearCap() {
+				sockWarn.Do(func() {
+					fmt.Fprintln(os.Stderr, "warn: open sockets near --max-sockets; starting fewer proxies at once")
+				})
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(socketPoll):
+				}
+			}
+			select {
+			case out <- a:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// socketsNearCap reports whether the process-wide socket budget is busy.
+func socketsNearCap() bool {
+	b := resolver.Default().Sockets
+	return b != nil && b.Busy()
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// liveStatus keeps a progress line ("Checked 37 · 12 alive · 4s") at the
+// bottom of the terminal while table rows scroll past above it. It only
+// runs when both the table (stdout) and the status line (stderr) go to a
+// terminal; otherwise every method is a plain pass-through.
+type liveStatus struct {
+	mu      sync.Mutex
+	w       io.Writer
+	verb    string // "Checked", "Benchmarked"
+	good    string // "alive", "reachable"
+	started time.Time
+	done    int
+	ok      int
+	stop    chan struct{}
+}
+
+// newLiveStatus returns a liveStatus for a run writing format, or nil
+// when live rendering does not apply.
+func newLiveStatus(format, verb, good string) *liveStatus {
+	if format != "table" || !isTerminal(os.Stdout) || !isTerminal(os.Stderr) {
+		return nil
+	}
+	l := &liveStatus{w: os.Stderr, verb: verb, good: good, started: time.Now(), stop: make(chan struct{})}
+	go func() {
+		// Keep the elapsed time moving while slow proxies are pending.
+		t := time.NewTicker(time.Second)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				l.mu.Lock()
+				l.draw()
+				l.mu.Unlock()
+			case <-l.stop:
+				return
+			}
+		}
+	}()
+	return l
+}
+
+// write runs write (which prints table rows) with the status line
+// cleared, then redraws it below the new rows.
+func (l *liveStatus) write(write func() error) error {
+	if l == nil {
+		return write()
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.clear()
+	defer l.draw()
+	return write()
+}
+
+// count records one finished proxy, as good if ok.
+func (l *liveStatus) count(ok bool) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.done++
+	if ok {
+		l.ok++
+	}
+	l.draw()
+}
+
+// finish removes the status line for good.
+func (l *liveStatus) finish() {
+	if l == nil {
+		return
+	}
+	close(l.stop)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.clear()
+}
+
+func (l *liveStatus) clear() {
+	fmt.Fprint(l.w, "\r\033[K")
+}
+
+func (l *liveStatus) draw() {
+	fmt.Fprintf(l.w, "\r\033[K%s %d · %d %s · %s", l.verb, l.done, l.ok, l.good,
+		time.Since(l.started).Truncate(time.Second))
+}
+
+// isTerminal reports whether f is a character device such as a terminal.
+func isTerminal(f *os.File) bool {
+	stat, err := f.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice != 0
+}
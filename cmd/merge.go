@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/drsoft-oss/proxybench/internal/output"
+)
+
+var mergeCmd = &cobra.Command{
+	Use:   "merge <results-file>...",
+	Short: "Combine results of sharded or repeated runs into one row per proxy",
+	Long: `Merge reads results written by 'check' or 'bench' in JSON, NDJSON or CSV
+(from the files given; "-" is stdin) and writes one row per proxy in any
+output format. Where several files have a row for the same proxy, compared
+after normalizing its address, --prefer picks one: newest takes the row
+from the most recent run, best the alive (bench: reachable) one with the
+lowest latency (bench: loss, then p50). A run's time is its JSON envelope's
+timestamp, or else the file's modification time.
+
+Examples:
+  proxybench merge part1.json part2.json part3.json -f json > results.json
+  proxybench merge --prefer best monday.ndjson tuesday.ndjson -f csv`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runMerge,
+}
+
+var (
+	mergeFormat string
+	mergePrefer string
+	mergeChain  string
+)
+
+func init() {
+	mergeCmd.Flags().StringVarP(&mergeFormat, "format", "f", "table", "output format: table|json|ndjson|csv|proxychains")
+	mergeCmd.Flags().StringVar(&mergePrefer, "prefer", string(output.MergeNewest), "row kept for a proxy found in several files: newest|best")
+	mergeCmd.Flags().StringVar(&mergeChain, "chain", "dynamic", "chain type written by --format proxychains: dynamic|strict")
+}
+
+func runMerge(cmd *cobra.Command, args []string) error {
+	rule, err := output.ParseMergeRule(mergePrefer)
+	if err != nil {
+		return fmt.Errorf("--prefer: %w", err)
+	}
+	chain, err := output.ParseChain(mergeChain)
+	if err != nil {
+		return err
+	}
+
+	runs := make([]output.Run, 0, len(args))
+	read := 0
+	for _, path := range args {
+		var run output.Run
+		if err := readResultsFile(&run.Results, path); err != nil {
+			return err
+		}
+		if run.At, err = runTime(path, run.Meta); err != nil {
+			return err
+		}
+		read += run.Len()
+		runs = append(runs, run)
+	}
+	merged, err := output.Merge(runs, rule)
+	if err != nil {
+		return err
+	}
+	if _, err := writeResults(&merged, output.Format(mergeFormat), chain, rowFilter{}); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Merged %d results from %d files into %d proxies\n", read, len(args), merged.Len())
+	return nil
+}
+
+// runTime returns when the results in path were made: the envelope's
+// timestamp, the file's modification time, or now for stdin.
+func runTime(path string, meta *output.Meta) (time.Time, error) {
+	if meta != nil && !meta.Timestamp.IsZero() {
+		return meta.Timestamp, nil
+	}
+	if path == "-" {
+		return time.Now(), nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/drsoft-oss/proxybench/internal/checker"
+	"github.com/drsoft-oss/proxybench/internal/geo"
+	"github.com/drsoft-oss/proxybench/internal/output"
+)
+
+// runMeta describes a check or bench run for the JSON envelope: the
+// version, the options that shape the numbers, the flags that replay it
+// and, with geo lookup on, which database located the proxies.
+func runMeta(cmd *cobra.Command, opts output.RunOptions, rq checker.Request, timeout time.Duration, withGeo bool, dbPath string) *output.Meta {
+	opts.TimeoutMS = timeout.Milliseconds()
+	if req, err := rq.New(context.Background(), opts.TestURL); err == nil {
+		opts.Method = req.Method
+	}
+	m := output.NewMeta(version, cmd.Name(), opts)
+	m.Args = replayArgs(cmd)
+	if withGeo {
+		if dbPath == "" {
+			dbPath = geo.DefaultDBPath()
+		}
+		if fi, err := os.Stat(dbPath); err == nil {
+			m.GeoDB = &output.GeoDBInfo{Path: dbPath, Modified: fi.ModTime().UTC().Truncate(time.Second)}
+		}
+	}
+	return m
+}
+
+// notReplayed are flags whose effect lies outside the results (history,
+// uploads, metrics, traces, the terminal) and that 'proxybench rerun' must
+// not repeat, and --skip-if-checked, which would skip what a rerun means
+// to re-test. --config and --profile are left out because the values they
+// applied are listed as flags.
+var notReplayed = map[string]bool{
+	"config": true, "profile": true,
+	"history": true, "history-db": true, "upload": true,
+	"skip-if-checked": true, "unless-failed": true,
+	"statsd": true, "statsd-prefix": true, "statsd-flavor": true,
+	"otlp-endpoint": true, "otlp-insecure": true,
+	"prompt-credentials": true,
+	"exec-on-result":     true, "exec-timeout": true,
+}
+
+// replayArgs lists the flags set for cmd, from the command line or the
+// config file, as --name=value arguments in name order. A repeatable flag
+// is listed once per value.
+func replayArgs(cmd *cobra.Command) []string {
+	var args []string
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		if notReplayed[f.Name] {
+			return
+		}
+		sv, isSlice := f.Value.(pflag.SliceValue)
+		switch {
+		case isSlice && f.Value.Type() == "stringArray":
+			for _, v := range sv.GetSlice() {
+				args = append(args, fmt.Sprintf("--%s=%s", f.Name, v))
+			}
+		case isSlice:
+			args = append(args, fmt.Sprintf("--%s=%s", f.Name, strings.Join(sv.GetSlice(), ",")))
+		default:
+			args = append(args, fmt.Sprintf("--%s=%s", f.Name, f.Value))
+		}
+	})
+	return args
+}
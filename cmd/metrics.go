@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/drsoft-oss/proxybench/internal/checker"
+	"github.com/drsoft-oss/proxybench/internal/metrics"
+)
+
+var (
+	statsdAddr   string
+	statsdPrefix string
+	statsdFlavor string
+)
+
+func init() {
+	for _, c := range []*cobra.Command{checkCmd, benchCmd, monitorCmd} {
+		c.Flags().StringVar(&statsdAddr, "statsd", "", "emit metrics to a StatsD/DogStatsD endpoint (host:port)")
+		c.Flags().StringVar(&statsdPrefix, "statsd-prefix", "proxybench.", "prefix for emitted metric names")
+		c.Flags().StringVar(&statsdFlavor, "statsd-flavor", "dogstatsd", "metric dialect: dogstatsd (tagged) | statsd (tags folded into names)")
+	}
+}
+
+// openStatsD returns a metrics client for --statsd, or nil when disabled.
+func openStatsD() (*metrics.Client, error) {
+	if statsdAddr == "" {
+		return nil, nil
+	}
+	c, err := metrics.New(statsdAddr, statsdPrefix, metrics.Flavor(statsdFlavor))
+	if err != nil {
+		return nil, fmt.Errorf("--statsd: %w", err)
+	}
+	return c, nil
+}
+
+// closeStatsD reports dropped packets, if any, and closes the client.
+func closeStatsD(c *metrics.Client) {
+	if n := c.Errors(); n > 0 {
+		fmt.Fprintf(os.Stderr, "warn: %d metric packets could not be sent to %s\n", n, statsdAddr)
+	}
+	c.Close() //nolint:errcheck
+}
+
+func proxyTags(address string) metrics.Tags {
+	return metrics.Tags{"proxy": address, "protocol": string(checker.DetectProtocol(address))}
+}
+
+// emitCheckMetrics records one latency timer and a success/failure counter per result.
+func emitCheckMetrics(c *metrics.Client, results []checker.Result) {
+	for _, r := range results {
+		emitCheckMetric(c, r)
+	}
+}
+
+func emitCheckMetric(c *metrics.Client, r checker.Result) {
+	tags := metrics.Tags{"proxy": r.Address, "protocol": string(r.Protocol)}
+	if r.Alive {
+		c.Timing("check.latency", r.Latency, tags)
+		c.Incr("check.success", tags)
+	} else {
+		c.Incr("check.failure", tags)
+	}
+}
+
+// benchSampleHook returns a bench.Options.OnSample callback emitting per-sample metrics.
+func benchSampleHook(c *metrics.Client) func(string, time.Duration, error) {
+	if c == nil {
+		return nil
+	}
+	return func(address string, latency time.Duration, err error) {
+		tags := proxyTags(address)
+		if err != nil {
+			c.Incr("bench.sample.failure", tags)
+			return
+		}
+		c.Timing("bench.sample.latency", latency, tags)
+		c.Incr("bench.sample.success", tags)
+	}
+}
@@ -0,0 +1,277 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/drsoft-oss/proxybench/internal/checker"
+	"github.com/drsoft-oss/proxybench/internal/geo"
+	"github.com/drsoft-oss/proxybench/internal/health"
+	"github.com/drsoft-oss/proxybench/internal/monitor"
+	"github.com/drsoft-oss/proxybench/internal/notify"
+	"github.com/drsoft-oss/proxybench/internal/pool"
+	"github.com/drsoft-oss/proxybench/internal/tracing"
+)
+
+var monitorCmd = &cobra.Command{
+	Use:   "monitor [proxy...]",
+	Short: "Continuously check proxies and alert on failures",
+	Long: `Monitor re-checks the given proxies on a fixed interval and sends alerts
+to Slack and/or Telegram when proxies go down, recover, or their latency
+regresses well beyond its running baseline.
+
+With --redis, health state is kept in a Redis hash instead of process memory,
+so several monitor instances share one view of which proxies are healthy and
+a failure already reported by one instance is not re-alerted by the others.
+
+With --listen, liveness (/healthz), readiness (/readyz) and build-info
+(/version, including the geo database age) endpoints are served for
+Kubernetes probes. Readiness requires a completed round within the last
+three intervals.
+
+Alerts are graded by severity: critical (proxies down), warning (latency
+regressions) and info (recoveries only). Each channel only receives alerts
+at or above its configured severity.
+
+The Slack webhook and Telegram token can also be supplied through the
+PROXYBENCH_SLACK_WEBHOOK and PROXYBENCH_TELEGRAM_TOKEN environment variables.
+
+Examples:
+  proxybench monitor http://1.2.3.4:8080 --slack-webhook https://hooks.slack.com/services/...
+  cat proxies.txt | proxybench monitor --interval 300 \
+    --telegram-token 123:ABC --telegram-chat -100123 --telegram-severity critical`,
+	RunE: runMonitor,
+}
+
+var (
+	monitorInterval         int
+	monitorTimeout          int
+	monitorTestURL          string
+	monitorConcurrency      int
+	monitorRegressionFactor float64
+	monitorTemplatePath     string
+	monitorSlackWebhook     string
+	monitorSlackSeverity    string
+	monitorTelegramToken    string
+	monitorTelegramChat     string
+	monitorTelegramSeverity string
+	monitorRedisURL         string
+	monitorRedisKey         string
+	monitorListen           string
+)
+
+func init() {
+	monitorCmd.Flags().IntVarP(&monitorInterval, "interval", "i", 60, "seconds between check rounds")
+	monitorCmd.Flags().IntVarP(&monitorTimeout, "timeout", "t", 10, "per-proxy timeout in seconds")
+	monitorCmd.Flags().StringVar(&monitorTestURL, "test-url", "http://www.google.com", "URL to use for HTTP/SOCKS5 forward checks")
+	monitorCmd.Flags().IntVarP(&monitorConcurrency, "concurrency", "c", 10, "max parallel checks")
+	monitorCmd.Flags().Float64Var(&monitorRegressionFactor, "regression-factor", monitor.DefaultRegressionFactor, "latency/baseline ratio reported as a regression")
+	monitorCmd.Flags().StringVar(&monitorTemplatePath, "template", "", "path to a Go text/template file for alert messages")
+	monitorCmd.Flags().StringVar(&monitorSlackWebhook, "slack-webhook", "", "Slack incoming webhook URL")
+	monitorCmd.Flags().StringVar(&monitorSlackSeverity, "slack-severity", "warning", "minimum severity sent to Slack: info|warning|critical")
+	monitorCmd.Flags().StringVar(&monitorTelegramToken, "telegram-token", "", "Telegram bot token")
+	monitorCmd.Flags().StringVar(&monitorTelegramChat, "telegram-chat", "", "Telegram chat ID to post to")
+	monitorCmd.Flags().StringVar(&monitorTelegramSeverity, "telegram-severity", "warning", "minimum severity sent to Telegram: info|warning|critical")
+	monitorCmd.Flags().StringVar(&monitorRedisURL, "redis", "", "share proxy health state via Redis (redis://[user:pass@]host:6379/db)")
+	monitorCmd.Flags().StringVar(&monitorRedisKey, "redis-key", monitor.DefaultRedisKey, "Redis hash holding the shared state")
+	monitorCmd.Flags().StringVar(&monitorListen, "listen", "", "serve /healthz, /readyz and /version on this address (e.g. :8080)")
+}
+
+func runMonitor(cmd *cobra.Command, args []string) error {
+	addresses := collectAddresses(args)
+	if len(addresses) == 0 {
+		return fmt.Errorf("no proxy addresses provided; pass them as arguments, via stdin or as config sources")
+	}
+	if monitorInterval <= 0 {
+		return fmt.Errorf("--interval must be positive")
+	}
+	if err := validateFreshness(); err != nil {
+		return err
+	}
+
+	dispatcher, err := buildDispatcher()
+	if err != nil {
+		return err
+	}
+	if len(dispatcher.Channels) == 0 {
+		fmt.Fprintln(os.Stderr, "warn: no alert channels configured; state changes are only logged")
+	}
+
+	if err := setupResolver(); err != nil {
+		return err
+	}
+	if err := loadLimits(); err != nil {
+		return err
+	}
+
+	stopTracing, err := startTracing()
+	if err != nil {
+		return err
+	}
+	defer stopTracing()
+
+	sd, err := openStatsD()
+	if err != nil {
+		return err
+	}
+	defer closeStatsD(sd)
+
+	opts := checker.Options{
+		Timeout:     time.Duration(monitorTimeout) * time.Second,
+		TestURL:     monitorTestURL,
+		Concurrency: monitorConcurrency,
+	}
+	if opts.Credentials, err = loadCredentials(); err != nil {
+		return err
+	}
+	if opts.Overrides, err = loadOverrides(); err != nil {
+		return err
+	}
+	if opts.RootCAs, opts.InsecureTLS, err = loadTLS(); err != nil {
+		return err
+	}
+	opts.ProxyInsecure = proxyInsecure
+	if opts.TLSFingerprint, err = loadFingerprint(); err != nil {
+		return err
+	}
+	if opts.SSHKey, err = loadSSHKey(); err != nil {
+		return err
+	}
+	opts.SSHHostKeys = sshHostKeys()
+	if opts.CredentialList, err = loadCredentialList(); err != nil {
+		return err
+	}
+	var store monitor.Store
+	if monitorRedisURL != "" {
+		rs, err := monitor.NewRedisStore(monitorRedisURL, monitorRedisKey)
+		if err != nil {
+			return err
+		}
+		defer rs.Close()
+		store = rs
+	}
+	tracker := monitor.NewTracker(monitorRegressionFactor, store)
+
+	var probes *health.Server
+	if monitorListen != "" {
+		// Not ready until the first round completes; unready again if rounds stall.
+		probes = health.New(version, geo.DefaultDBPath(), 3*time.Duration(monitorInterval)*time.Second)
+		ln, err := net.Listen("tcp", monitorListen)
+		if err != nil {
+			return fmt.Errorf("--listen: %w", err)
+		}
+		srv := &http.Server{Handler: tracing.Middleware(probes.Handler()), ReadHeaderTimeout: 5 * time.Second}
+		go srv.Serve(ln) //nolint:errcheck
+		defer srv.Close()
+		fmt.Fprintf(os.Stderr, "Serving health endpoints on %s\n", ln.Addr())
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	ticker := time.NewTicker(time.Duration(monitorInterval) * time.Second)
+	defer ticker.Stop()
+
+	fmt.Fprintf(os.Stderr, "Monitoring %d proxies every %ds (Ctrl-C to stop)…\n", len(addresses), monitorInterval)
+	for {
+		fresh, err := monitorFreshness(tracker, addresses)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warn: --skip-if-checked: %v\n", err)
+		}
+		var results []checker.Result
+		checker.CheckStream(throttleAddresses(context.Background(), fresh.skip(context.Background(), pool.FromSlice(addresses))), opts, func(r checker.Result) {
+			results = append(results, r)
+		})
+		emitCheckMetrics(sd, results)
+		sum, err := tracker.Observe(results, time.Now())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warn: %v\n", err)
+		}
+		skipped := ""
+		if n, _ := fresh.counts(); n > 0 {
+			skipped = fmt.Sprintf(", %d skipped", n)
+		}
+		fmt.Fprintf(os.Stderr, "[%s] %d/%d alive, %d down, %d recovered, %d regressions%s\n",
+			sum.Time.Format("15:04:05"), sum.Alive, sum.Total,
+			len(sum.Down), len(sum.Recovered), len(sum.Regressions), skipped)
+		if err := dispatcher.Dispatch(sum); err != nil {
+			fmt.Fprintf(os.Stderr, "warn: alert delivery failed: %v\n", err)
+		}
+		if hooks := newHook(execOnChange); hooks != nil {
+			for _, e := range changeEvents(sum) {
+				hooks.Run(context.Background(), e)
+			}
+			waitHook(hooks, "--exec-on-change")
+		}
+		if probes != nil {
+			probes.MarkRound(sum.Time)
+		}
+
+		select {
+		case <-stop:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// buildDispatcher assembles alert channels from the monitor flags.
+func buildDispatcher() (*notify.Dispatcher, error) {
+	var tmpl string
+	if monitorTemplatePath != "" {
+		b, err := os.ReadFile(monitorTemplatePath)
+		if err != nil {
+			return nil, fmt.Errorf("read template: %w", err)
+		}
+		tmpl = string(b)
+	}
+	t, err := notify.ParseTemplate(tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("parse template: %w", err)
+	}
+
+	d := &notify.Dispatcher{}
+
+	webhook := monitorSlackWebhook
+	if webhook == "" {
+		webhook = os.Getenv("PROXYBENCH_SLACK_WEBHOOK")
+	}
+	if webhook != "" {
+		sev, err := notify.ParseSeverity(monitorSlackSeverity)
+		if err != nil {
+			return nil, fmt.Errorf("--slack-severity: %w", err)
+		}
+		d.Channels = append(d.Channels, notify.Channel{
+			Notifier:    &notify.Slack{WebhookURL: webhook},
+			MinSeverity: sev,
+			Template:    t,
+		})
+	}
+
+	token := monitorTelegramToken
+	if token == "" {
+		token = os.Getenv("PROXYBENCH_TELEGRAM_TOKEN")
+	}
+	if token != "" {
+		if monitorTelegramChat == "" {
+			return nil, fmt.Errorf("--telegram-chat is required with a Telegram token")
+		}
+		sev, err := notify.ParseSeverity(monitorTelegramSeverity)
+		if err != nil {
+			return nil, fmt.Errorf("--telegram-severity: %w", err)
+		}
+		d.Channels = append(d.Channels, notify.Channel{
+			Notifier:    &notify.Telegram{Token: token, ChatID: monitorTelegramChat},
+			MinSeverity: sev,
+			Template:    t,
+		})
+	}
+	return d, nil
+}
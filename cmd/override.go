@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/drsoft-oss/proxybench/internal/checker"
+)
+
+var overridesPath string
+
+func init() {
+	for _, c := range []*cobra.Command{checkCmd, benchCmd, monitorCmd} {
+		c.Flags().StringVar(&overridesPath, "overrides", "", "file mapping host[:port] to sni=name&host=name (\"*\" for a default) for proxies that only answer to a given TLS server name or Host header; an address's own #sni=…&host=… fragment wins")
+	}
+}
+
+// loadOverrides reads --overrides and returns the lookup to put in
+// checker/bench options, or nil when the flag is unset.
+func loadOverrides() (func(hostPort string) checker.Override, error) {
+	if overridesPath == "" {
+		return nil, nil
+	}
+	lookup, err := checker.LoadOverrides(overridesPath)
+	if err != nil {
+		return nil, fmt.Errorf("--overrides: %w", err)
+	}
+	return lookup, nil
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/drsoft-oss/proxybench/internal/checker"
+	"github.com/drsoft-oss/proxybench/internal/proxylist"
+	"github.com/drsoft-oss/proxybench/internal/resolver"
+)
+
+// pacFetchTimeout bounds downloading a --pac URL.
+const pacFetchTimeout = 30 * time.Second
+
+var pacSource string
+
+func init() {
+	for _, c := range []*cobra.Command{checkCmd, benchCmd} {
+		c.Flags().StringVar(&pacSource, "pac", "", "proxy auto-config file or URL (e.g. http://wpad/wpad.dat); every proxy it can return is added to the input")
+	}
+}
+
+// withPAC appends the proxies of --pac to args.
+func withPAC(args []string) ([]string, error) {
+	if pacSource == "" {
+		return args, nil
+	}
+	addrs, err := pacProxies(pacSource)
+	if err != nil {
+		return nil, fmt.Errorf("--pac: %w", err)
+	}
+	return append(args, addrs...), nil
+}
+
+// pacProxies returns the addresses of the proxies a PAC file or URL can
+// return, reporting skipped entries on stderr.
+func pacProxies(src string) ([]string, error) {
+	data, err := readPAC(src)
+	if err != nil {
+		return nil, err
+	}
+	proxies, skipped, err := proxylist.Read(data, proxylist.FormatPAC)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range skipped {
+		fmt.Fprintf(os.Stderr, "skip: %s\n", s)
+	}
+	fmt.Fprintf(os.Stderr, "Found %d proxies in %s\n", len(proxies), src)
+	addrs := make([]string, len(proxies))
+	for i, p := range proxies {
+		addrs[i] = p.Address
+	}
+	return addrs, nil
+}
+
+// readPAC reads a PAC file from disk (a path or file:// URL) or, for an
+// http(s) URL, directly from the server.
+func readPAC(src string) ([]byte, error) {
+	if !strings.HasPrefix(src, "http://") && !strings.HasPrefix(src, "https://") {
+		return os.ReadFile(strings.TrimPrefix(src, "file://"))
+	}
+	client := &http.Client{
+		Timeout: pacFetchTimeout,
+		Transport: &http.Transport{
+			DialContext:     resolver.Default().DialContext,
+			TLSClientConfig: checker.ClientTLS(caRoots, insecureTLS),
+		},
+	}
+	resp, err := client.Get(src)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", src, resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+}
@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/drsoft-oss/proxybench/internal/proxyproto"
+)
+
+var (
+	checkProxyProto string
+	benchProxyProto string
+)
+
+func init() {
+	checkCmd.Flags().StringVar(&checkProxyProto, "proxy-protocol", "off", "send a PROXY protocol header to proxies: off|v1|v2|auto (retry failures with each)")
+	benchCmd.Flags().StringVar(&benchProxyProto, "proxy-protocol", "off", "send a PROXY protocol header to proxies: off|v1|v2")
+}
+
+// parseProxyProto maps a --proxy-protocol value to a header version and
+// whether to detect it per proxy ("auto", where allowed).
+func parseProxyProto(s string, allowAuto bool) (proxyproto.Version, bool, error) {
+	if s == "auto" && allowAuto {
+		return proxyproto.None, true, nil
+	}
+	v, err := proxyproto.ParseVersion(s)
+	if err != nil {
+		return proxyproto.None, false, fmt.Errorf("--proxy-protocol: %w", err)
+	}
+	return v, false, nil
+}
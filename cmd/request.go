@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/drsoft-oss/proxybench/internal/checker"
+)
+
+var (
+	requestMethod      string
+	requestBody        string
+	requestContentType string
+)
+
+func init() {
+	for _, c := range []*cobra.Command{checkCmd, benchCmd} {
+		c.Flags().StringVar(&requestMethod, "method", "", "HTTP method for the test URL request (default GET, or POST with --body)")
+		c.Flags().StringVar(&requestBody, "body", "", "request body for the test URL; @file reads it from a file")
+		c.Flags().StringVar(&requestContentType, "content-type", "", "Content-Type header sent with --body")
+	}
+}
+
+// testRequest builds the checker.Request from --method, --body and
+// --content-type, and makes sure it can be sent to testURL.
+func testRequest(testURL string) (checker.Request, error) {
+	rq := checker.Request{
+		Method:      strings.ToUpper(requestMethod),
+		ContentType: requestContentType,
+	}
+	if path, ok := strings.CutPrefix(requestBody, "@"); ok {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return rq, fmt.Errorf("--body: %w", err)
+		}
+		rq.Body = b
+	} else if requestBody != "" {
+		rq.Body = []byte(requestBody)
+	}
+	if rq.ContentType != "" && rq.Body == nil {
+		return rq, fmt.Errorf("--content-type needs --body")
+	}
+	if _, err := rq.New(context.Background(), testURL); err != nil {
+		return rq, fmt.Errorf("test request: %w", err)
+	}
+	return rq, nil
+}
@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+
+	"github.com/drsoft-oss/proxybench/internal/output"
+)
+
+var rerunCmd = &cobra.Command{
+	Use:   "rerun <results.json> [addresses...]",
+	Short: "Repeat the check or bench run that wrote a JSON results file",
+	Long: `Rerun reads the envelope of results written by 'check' or 'bench' with
+--format json and runs the same command again with the flags recorded in
+it, whether they came from the command line or the config file. The
+current config file is not applied, so the run is the one recorded.
+
+The addresses to test come from arguments or stdin, like for the original
+command; with neither, the proxies listed in the results are used. When
+done, rerun reports whether the input given matches the original run's by
+its SHA-256. Flags that only reach outside the results (--history, --upload,
+--statsd, --otlp-endpoint, --exec-on-result, --prompt-credentials) are
+never recorded and so never repeated.
+
+Examples:
+  proxybench rerun results.json < proxies.txt > again.json
+  proxybench rerun results.json`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runRerun,
+}
+
+func runRerun(cmd *cobra.Command, args []string) error {
+	var rs output.Results
+	if err := readResultsFile(&rs, args[0]); err != nil {
+		return err
+	}
+	meta := rs.Meta
+	if meta == nil {
+		return fmt.Errorf("%s: no run envelope; only results written with --format json can be rerun", args[0])
+	}
+	target, run := checkCmd, runCheck
+	switch meta.Command {
+	case "check":
+	case "bench":
+		target, run = benchCmd, runBench
+	default:
+		return fmt.Errorf("%s: cannot rerun %q results", args[0], meta.Command)
+	}
+	if meta.Version != version {
+		fmt.Fprintf(os.Stderr, "warning: results were written by proxybench %s, this is %s; defaults may differ\n", meta.Version, version)
+	}
+	if err := target.Flags().Parse(meta.Args); err != nil {
+		return fmt.Errorf("%s: recorded flags: %w", args[0], err)
+	}
+
+	addresses := args[1:]
+	fromResults := false
+	if stat, _ := os.Stdin.Stat(); len(addresses) == 0 && stat.Mode()&os.ModeCharDevice != 0 {
+		fromResults = true
+		for _, r := range rs.Checks {
+			addresses = append(addresses, r.Result.Address)
+		}
+		for _, s := range rs.Benches {
+			addresses = append(addresses, s.Stats.Address)
+		}
+		fmt.Fprintf(os.Stderr, "No input given; re-testing the %d proxies in %s\n", len(addresses), args[0])
+	}
+	fmt.Fprintf(os.Stderr, "Rerunning: proxybench %s %s\n", meta.Command, joinArgs(meta.Args))
+	runErr := run(target, addresses)
+
+	switch digest := inputDigest(); {
+	case meta.InputSHA256 == "":
+		fmt.Fprintln(os.Stderr, "Input not verified: the results do not record its SHA-256")
+	case fromResults:
+		fmt.Fprintln(os.Stderr, "Input not verified: the proxies were taken from the results, not the original input")
+	case digest == meta.InputSHA256:
+		fmt.Fprintf(os.Stderr, "Input matches the original run (sha256 %s)\n", digest)
+	default:
+		fmt.Fprintf(os.Stderr, "Input differs from the original run (sha256 %s, was %s)\n", digest, meta.InputSHA256)
+	}
+	return runErr
+}
+
+// joinArgs quotes args that a shell would split.
+func joinArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		if strings.ContainsAny(a, " \t\"'$&|;<>*?") {
+			a = strconv.Quote(a)
+		}
+		quoted[i] = a
+	}
+	return strings.Join(quoted, " ")
+}
+
+// input hashes the addresses a check or bench run read, one per line in
+// the order read, for the envelope's input_sha256.
+var input struct {
+	sync.Mutex
+	hash.Hash
+}
+
+// hashInput passes addresses through, adding each to the input digest,
+// until in closes or ctx is cancelled.
+func hashInput(ctx context.Context, in <-chan string) <-chan string {
+	input.Lock()
+	input.Hash = sha256.New()
+	input.Unlock()
+	out := make(chan string)
+	go func() {
+		defer close(out)
+		for a := range in {
+			input.Lock()
+			input.Write([]byte(a + "\n"))
+			input.Unlock()
+			select {
+			case out <- a:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// inputDigest returns the hex SHA-256 of the addresses hashInput has
+// passed so far, or "" if it was not used.
+func inputDigest() string {
+	input.Lock()
+	defer input.Unlock()
+	if input.Hash == nil {
+		return ""
+	}
+	return hex.EncodeToString(input.Sum(nil))
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/drsoft-oss/proxybench/internal/fdlimit"
+	"github.com/drsoft-oss/proxybench/internal/resolver"
+)
+
+var (
+	resolverSpec string
+	resolverTTL  time.Duration
+	maxSockets   int
+)
+
+func init() {
+	for _, c := range []*cobra.Command{checkCmd, benchCmd, monitorCmd} {
+		c.Flags().StringVar(&resolverSpec, "resolver", "", "DNS resolver: system | host[:port] | tcp://host | tls://host (DoT) | https://host/dns-query (DoH)")
+		c.Flags().DurationVar(&resolverTTL, "dns-ttl", resolver.DefaultTTL, "how long resolved proxy hosts are cached (negative disables caching)")
+		c.Flags().IntVar(&maxSockets, "max-sockets", 0, "max proxy sockets open at once; further dials queue (0 = derive from the open-file limit)")
+	}
+}
+
+// setupResolver installs the process-wide dialer from --resolver, --dns-ttl
+// and --max-sockets.
+func setupResolver() error {
+	r, err := resolver.New(resolverSpec, resolverTTL)
+	if err != nil {
+		return fmt.Errorf("--resolver: %w", err)
+	}
+	if maxSockets < 0 {
+		return fmt.Errorf("--max-sockets must not be negative")
+	}
+	if maxSockets > 0 {
+		r.Sockets = fdlimit.New(maxSockets)
+	}
+	resolver.SetDefault(r)
+	return nil
+}
@@ -2,6 +2,7 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
@@ -21,6 +22,7 @@ Features:
   • Speed benchmarks with latency percentiles
   • Geo-location lookup via local IP database
   • JSON and CSV output for pipeline integration
+  • Monitor mode with Slack and Telegram alerts
 `,
 	Version: version,
 }
@@ -29,6 +31,10 @@ Features:
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
+		var ee *exitError
+		if errors.As(err, &ee) {
+			os.Exit(ee.code)
+		}
 		os.Exit(1)
 	}
 }
@@ -38,4 +44,18 @@ func init() {
 	rootCmd.AddCommand(checkCmd)
 	rootCmd.AddCommand(benchCmd)
 	rootCmd.AddCommand(dbCmd)
+	rootCmd.AddCommand(monitorCmd)
+	rootCmd.AddCommand(historyCmd)
+	rootCmd.AddCommand(filterCmd)
+	rootCmd.AddCommand(mergeCmd)
+	rootCmd.AddCommand(rerunCmd)
+	rootCmd.AddCommand(rpcCmd)
+	rootCmd.AddCommand(useCmd)
+	rootCmd.AddCommand(convertCmd)
+	rootCmd.AddCommand(topCmd)
+	rootCmd.AddCommand(validateCmd)
+	rootCmd.AddCommand(identifyCmd)
+	rootCmd.AddCommand(scanCmd)
+	rootCmd.AddCommand(targetCmd)
+	rootCmd.AddCommand(scheduleCmd)
 }
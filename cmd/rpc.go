@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/drsoft-oss/proxybench/internal/bench"
+	"github.com/drsoft-oss/proxybench/internal/checker"
+	"github.com/drsoft-oss/proxybench/internal/geo"
+	"github.com/drsoft-oss/proxybench/internal/output"
+	"github.com/drsoft-oss/proxybench/internal/pool"
+	"github.com/drsoft-oss/proxybench/internal/rpc"
+)
+
+var rpcCmd = &cobra.Command{
+	Use:   "rpc",
+	Short: "Serve JSON-RPC 2.0 on stdin/stdout for driving proxybench from other programs",
+	Long: `Rpc reads JSON-RPC 2.0 requests from stdin, one per line, and writes one
+response per line to stdout until stdin is closed. Requests run
+concurrently and are answered as they finish; match responses by id.
+
+Methods:
+  check       {"addresses": [...], "test_url", "timeout_ms", "concurrency"}
+              → [check result, ...]; each result is also sent as it
+              completes in a "check.result" notification {"id", "result"}
+  bench       {"addresses": [...], "test_url", "payload_url", "samples",
+               "timeout_ms", "concurrency"}
+              → [bench stats, ...], with "bench.result" notifications
+  geo.lookup  {"addresses": [...]} → [{"address", "country_code", ...}, ...]
+  cancel      {"id": <request id>} → {"cancelled": true|false}; the
+              cancelled request fails with code -32800
+
+Results have the fields of 'check -f ndjson' and 'bench -f ndjson' rows.
+Omitted params take the check and bench defaults; --resolver, --ca-cert
+and the other flags of those commands do not apply.
+
+Example:
+  echo '{"jsonrpc":"2.0","id":1,"method":"check","params":{"addresses":["socks5://1.2.3.4:1080"]}}' | proxybench rpc`,
+	Args: cobra.NoArgs,
+	RunE: runRPC,
+}
+
+var rpcDBPath string
+
+func init() {
+	rpcCmd.Flags().StringVar(&rpcDBPath, "db", "", "path to ip2country.csv for geo.lookup (default: auto-detect)")
+}
+
+// rpcParams are the params of the check, bench and geo.lookup methods.
+type rpcParams struct {
+	Addresses   []string `json:"addresses"`
+	TestURL     string   `json:"test_url"`
+	PayloadURL  string   `json:"payload_url"`
+	Samples     int      `json:"samples"`
+	TimeoutMS   int64    `json:"timeout_ms"`
+	Concurrency int      `json:"concurrency"`
+}
+
+func parseRPCParams(raw json.RawMessage) (rpcParams, error) {
+	var p rpcParams
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return p, rpc.InvalidParams(err)
+	}
+	if len(p.Addresses) == 0 {
+		return p, rpc.InvalidParams(errors.New("addresses: want at least one"))
+	}
+	if p.TimeoutMS < 0 || p.Samples < 0 || p.Concurrency < 0 {
+		return p, rpc.InvalidParams(errors.New("timeout_ms, samples and concurrency must not be negative"))
+	}
+	return p, nil
+}
+
+func runRPC(cmd *cobra.Command, _ []string) error {
+	s := rpc.NewServer()
+	s.Handle("check", rpcCheck)
+	s.Handle("bench", rpcBench)
+	locate := geoLookup(true, rpcDBPath)
+	s.Handle("geo.lookup", func(_ context.Context, raw json.RawMessage) (any, error) {
+		p, err := parseRPCParams(raw)
+		if err != nil {
+			return nil, err
+		}
+		type located struct {
+			Address string `json:"address"`
+			geo.Record
+		}
+		out := make([]located, len(p.Addresses))
+		for i, a := range p.Addresses {
+			out[i] = located{a, locate(a)}
+		}
+		return out, nil
+	})
+	return s.Serve(cmd.Context(), os.Stdin, os.Stdout)
+}
+
+func rpcCheck(ctx context.Context, raw json.RawMessage) (any, error) {
+	p, err := parseRPCParams(raw)
+	if err != nil {
+		return nil, err
+	}
+	opts := checker.DefaultOptions()
+	if p.TestURL != "" {
+		opts.TestURL = p.TestURL
+	}
+	if p.TimeoutMS > 0 {
+		opts.Timeout = time.Duration(p.TimeoutMS) * time.Millisecond
+	}
+	if p.Concurrency > 0 {
+		opts.Concurrency = p.Concurrency
+	}
+	results := make([]json.RawMessage, 0, len(p.Addresses))
+	checker.CheckStreamContext(ctx, pool.FromSlice(p.Addresses), opts, func(r checker.Result) {
+		row := ndjsonRow(func(w io.Writer) error { return output.NewCheckWriter(w, output.FormatNDJSON).Write(r, "") })
+		rpc.Notify(ctx, "check.result", row)
+		results = append(results, row)
+	})
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func rpcBench(ctx context.Context, raw json.RawMessage) (any, error) {
+	p, err := parseRPCParams(raw)
+	if err != nil {
+		return nil, err
+	}
+	opts := bench.DefaultOptions()
+	if p.TestURL != "" {
+		opts.TestURL = p.TestURL
+	}
+	if p.TimeoutMS > 0 {
+		opts.Timeout = time.Duration(p.TimeoutMS) * time.Millisecond
+	}
+	if p.Samples > 0 {
+		opts.Samples = p.Samples
+	}
+	opts.PayloadURL = p.PayloadURL
+	opts.Concurrency = p.Concurrency
+	opts.Context = ctx
+	results := make([]json.RawMessage, 0, len(p.Addresses))
+	bench.RunStream(pool.FromSlice(p.Addresses), opts, func(s bench.Stats) {
+		row := ndjsonRow(func(w io.Writer) error { return output.NewBenchWriter(w, output.FormatNDJSON, false).Write(s, "") })
+		rpc.Notify(ctx, "bench.result", row)
+		results = append(results, row)
+	})
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// ndjsonRow returns the row write writes in the ndjson format, so RPC
+// results match the commands' output.
+func ndjsonRow(write func(io.Writer) error) json.RawMessage {
+	var buf bytes.Buffer
+	if err := write(&buf); err != nil {
+		return json.RawMessage("null")
+	}
+	return bytes.TrimSpace(buf.Bytes())
+}
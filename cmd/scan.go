@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/drsoft-oss/proxybench/internal/checker"
+	"github.com/drsoft-oss/proxybench/internal/pool"
+	"github.com/drsoft-oss/proxybench/internal/scan"
+)
+
+var scanCmd = &cobra.Command{
+	Use:   "scan <host|CIDR>...",
+	Short: "Find proxies on hosts you own by probing common proxy ports",
+	Long: `Scan tries a TCP connection to each --ports port of every host and network
+given, then fingerprints each open port as 'proxybench identify' does and
+prints what it found in the same formats. The default txt output lists one
+scheme-qualified proxy per line, ready for 'proxybench check'.
+
+Only scan hosts you own or are authorised to test, and say so with
+--authorized. Connection attempts are capped at --rate per second across
+the whole scan, and networks may hold at most --max-hosts hosts in total.
+
+Examples:
+  proxybench scan 10.0.0.0/24 --authorized
+  proxybench scan proxy1.corp.example 10.0.0.0/28 --ports 1080,3128,8000-8010 --authorized
+  proxybench scan 10.0.0.0/24 --authorized | proxybench check`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runScan,
+}
+
+var (
+	scanPorts       string
+	scanAuthorized  bool
+	scanRate        float64
+	scanConcurrency int
+	scanMaxHosts    int
+	scanTimeout     int
+	scanFormat      string
+)
+
+func init() {
+	scanCmd.Flags().StringVar(&scanPorts, "ports", "1080,3128,8080,8888", "ports to try, comma-separated, with lo-hi ranges")
+	scanCmd.Flags().BoolVar(&scanAuthorized, "authorized", false, "confirm that you own or are authorised to scan every host given (required)")
+	scanCmd.Flags().Float64Var(&scanRate, "rate", scan.DefaultRate, fmt.Sprintf("most connection attempts per second (at most %d)", scan.MaxRate))
+	scanCmd.Flags().IntVarP(&scanConcurrency, "concurrency", "c", scan.DefaultConcurrency, "max connection attempts and fingerprints in flight")
+	scanCmd.Flags().IntVar(&scanMaxHosts, "max-hosts", scan.DefaultMaxHosts, "refuse to scan more hosts than this")
+	scanCmd.Flags().IntVarP(&scanTimeout, "timeout", "t", 2, "per-connection timeout in seconds")
+	scanCmd.Flags().StringVarP(&scanFormat, "format", "f", "txt", "output format: txt|table|json|ndjson")
+}
+
+func runScan(cmd *cobra.Command, args []string) error {
+	if !scanAuthorized {
+		return fmt.Errorf("scan only hosts you own or are authorised to test, and confirm it with --authorized")
+	}
+	switch scanFormat {
+	case "txt", "table", "json", "ndjson":
+	default:
+		return fmt.Errorf("--format: want txt, table, json or ndjson, got %q", scanFormat)
+	}
+	if scanRate <= 0 || scanRate > scan.MaxRate {
+		return fmt.Errorf("--rate: want more than 0 and at most %d, got %g", scan.MaxRate, scanRate)
+	}
+	ports, err := scan.ParsePorts(scanPorts)
+	if err != nil {
+		return fmt.Errorf("--ports: %w", err)
+	}
+	hosts, err := scan.Hosts(args, scanMaxHosts)
+	if err != nil {
+		return err
+	}
+	if err := setupResolver(); err != nil {
+		return err
+	}
+	stopTracing, err := startTracing()
+	if err != nil {
+		return err
+	}
+	defer stopTracing()
+
+	ctx := context.Background()
+	timeout := time.Duration(scanTimeout) * time.Second
+	started := time.Now()
+	fmt.Fprintf(os.Stderr, "Scanning %d ports on %d hosts at up to %g attempts/s…\n", len(ports), len(hosts), scanRate)
+	var open []string
+	tried := scan.Open(ctx, hosts, ports, scan.Options{Rate: scanRate, Concurrency: scanConcurrency, Timeout: timeout}, func(hostPort string) {
+		open = append(open, hostPort)
+	})
+
+	out := os.Stdout
+	if scanFormat == "table" {
+		writeIdentityHeader(out)
+	}
+	var ids []checker.Identity
+	var writeErr error
+	proxies := 0
+	pool.Ordered(pool.FromSlice(open), scanConcurrency, func(hostPort string) checker.Identity {
+		return checker.Identify(ctx, hostPort, timeout)
+	}, func(id checker.Identity) {
+		if id.Kind == checker.KindProxy {
+			proxies++
+		}
+		if scanFormat == "json" {
+			ids = append(ids, id)
+		} else if writeErr == nil {
+			writeErr = writeIdentity(out, scanFormat, id)
+		}
+	})
+	if scanFormat == "json" {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		if ids == nil {
+			ids = []checker.Identity{}
+		}
+		writeErr = enc.Encode(ids)
+	}
+	if writeErr != nil {
+		return writeErr
+	}
+	fmt.Fprintf(os.Stderr, "Scanned %d endpoints in %s: %d open, %d proxies\n",
+		tried, time.Since(started).Round(time.Millisecond), len(open), proxies)
+	return nil
+}
@@ -0,0 +1,418 @@
+package cmd
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/drsoft-oss/proxybench/internal/config"
+	"github.com/drsoft-oss/proxybench/internal/notify"
+	"github.com/drsoft-oss/proxybench/internal/output"
+	"github.com/drsoft-oss/proxybench/internal/proxylist"
+	"github.com/drsoft-oss/proxybench/internal/schedule"
+	"github.com/drsoft-oss/proxybench/internal/upload"
+)
+
+var scheduleCmd = &cobra.Command{
+	Use:   "schedule",
+	Short: "Run the config's pipelines (fetch, check, bench, export, notify) on cron expressions",
+	Long: `Schedule runs the jobs defined under "jobs:" in the config file, each on
+its own cron expression, in one long-running process. A job is a pipeline
+of stages, run in this order when present:
+
+  fetch   proxy lists (files or http(s) URLs) to check; without it the
+          check reads the config's sources
+  check   'proxybench check' with the given flags
+  bench   'proxybench bench' with the given flags, on the proxies the
+          check found alive
+  export  a file, or an s3://, gs:// or az:// upload target, the results
+          of the last stage are written to
+  notify  "always" or "failure": when the Slack and Telegram channels of
+          the config's notify section hear of a run
+
+  jobs:
+    refresh:
+      cron: "*/30 * * * *"     # minute hour day-of-month month day-of-week
+      fetch: [https://example.com/proxies.txt, vendor.txt]
+      check: --timeout 5 --concurrency 50
+      bench: --samples 3
+      format: json             # of the export: json|ndjson|csv
+      export: results/latest.json
+      notify: failure
+
+A job whose previous run is still going when it comes due again is
+skipped for that time. Each job appends its stages' output to its own log,
+<log-dir>/<job>.log. Stages run as subprocesses of this binary with the
+same --config and --profile.
+
+Examples:
+  proxybench schedule --config sched.yaml
+  proxybench schedule --config sched.yaml --once --job refresh`,
+	Args: cobra.NoArgs,
+	RunE: runSchedule,
+}
+
+var (
+	scheduleLogDir        string
+	scheduleOnce          bool
+	scheduleJobNames      []string
+	scheduleSlackWebhook  string
+	scheduleTelegramToken string
+	scheduleTelegramChat  string
+)
+
+func init() {
+	f := scheduleCmd.Flags()
+	f.StringVar(&scheduleLogDir, "log-dir", "", "directory of the per-job logs (default: logs/ next to the config file)")
+	f.BoolVar(&scheduleOnce, "once", false, "run the jobs once now, one after another, and exit")
+	f.StringSliceVar(&scheduleJobNames, "job", nil, "run only these jobs")
+	f.StringVar(&scheduleSlackWebhook, "slack-webhook", "", "Slack incoming webhook URL for job notifications")
+	f.StringVar(&scheduleTelegramToken, "telegram-token", "", "Telegram bot token for job notifications")
+	f.StringVar(&scheduleTelegramChat, "telegram-chat", "", "Telegram chat ID to post job notifications to")
+}
+
+func runSchedule(cmd *cobra.Command, args []string) error {
+	jobs, err := scheduledJobs()
+	if err != nil {
+		return err
+	}
+	notifiers, err := jobNotifiers()
+	if err != nil {
+		return err
+	}
+	logDir := scheduleLogDir
+	if logDir == "" {
+		logDir = filepath.Join(filepath.Dir(configFile), "logs")
+	}
+	if err := os.MkdirAll(logDir, 0o755); err != nil {
+		return fmt.Errorf("--log-dir: %w", err)
+	}
+
+	var sched schedule.Scheduler
+	for _, j := range jobs {
+		cron, err := schedule.ParseCron(j.Cron)
+		if err != nil {
+			return fmt.Errorf("job %s: %w", j.Name, err)
+		}
+		if j.Export != "" && strings.Contains(j.Export, "://") {
+			if _, err := upload.ParseTarget(j.Export); err != nil {
+				return fmt.Errorf("job %s: export: %w", j.Name, err)
+			}
+		}
+		p := &pipeline{job: j, logDir: logDir, notifiers: notifiers}
+		sched.Jobs = append(sched.Jobs, schedule.Job{Name: j.Name, Cron: cron, Run: p.run})
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if scheduleOnce {
+		var failed []string
+		for _, j := range sched.Jobs {
+			fmt.Fprintf(os.Stderr, "[%s] %s: started\n", time.Now().Format("15:04:05"), j.Name)
+			start := time.Now()
+			err := j.Run(ctx)
+			logJobDone(j.Name, time.Since(start), err)
+			if err != nil {
+				failed = append(failed, j.Name)
+			}
+		}
+		if len(failed) > 0 {
+			return fmt.Errorf("failed jobs: %s (see their logs in %s)", strings.Join(failed, ", "), logDir)
+		}
+		return nil
+	}
+
+	now := time.Now()
+	for _, j := range sched.Jobs {
+		fmt.Fprintf(os.Stderr, "%s: %q, next run %s\n", j.Name, j.Cron.String(), j.Cron.Next(now).Format("2006-01-02 15:04"))
+	}
+	fmt.Fprintf(os.Stderr, "Scheduling %d jobs, logs in %s (Ctrl-C to stop)…\n", len(sched.Jobs), logDir)
+	sched.Events = func(e schedule.Event) {
+		switch e.Kind {
+		case schedule.EventStart:
+			fmt.Fprintf(os.Stderr, "[%s] %s: started\n", e.At.Format("15:04:05"), e.Job)
+		case schedule.EventDone:
+			logJobDone(e.Job, e.Took, e.Err)
+		case schedule.EventSkipped:
+			fmt.Fprintf(os.Stderr, "[%s] %s: skipped, the previous run is still going\n", e.At.Format("15:04:05"), e.Job)
+			appendJobLog(logDir, e.Job, fmt.Sprintf("%s run skipped: the previous run is still going\n", e.At.Format(time.RFC3339)))
+		}
+	}
+	if err := sched.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+	return nil
+}
+
+// scheduledJobs returns the config's jobs, only those named by --job if
+// it is set.
+func scheduledJobs() ([]config.Job, error) {
+	if len(configJobs) == 0 {
+		return nil, fmt.Errorf(`no jobs to schedule: define them under "jobs:" in the config file (--config)`)
+	}
+	if len(scheduleJobNames) == 0 {
+		return configJobs, nil
+	}
+	var jobs []config.Job
+	for _, name := range scheduleJobNames {
+		i := slices.IndexFunc(configJobs, func(j config.Job) bool { return j.Name == name })
+		if i < 0 {
+			return nil, fmt.Errorf("--job: no job %q in %s", name, configFile)
+		}
+		jobs = append(jobs, configJobs[i])
+	}
+	return jobs, nil
+}
+
+// jobNotifiers returns the alert channels set up by the notify flags, or
+// their environment variables.
+func jobNotifiers() ([]notify.Notifier, error) {
+	var out []notify.Notifier
+	if webhook := cmp.Or(scheduleSlackWebhook, os.Getenv("PROXYBENCH_SLACK_WEBHOOK")); webhook != "" {
+		out = append(out, &notify.Slack{WebhookURL: webhook})
+	}
+	if token := cmp.Or(scheduleTelegramToken, os.Getenv("PROXYBENCH_TELEGRAM_TOKEN")); token != "" {
+		if scheduleTelegramChat == "" {
+			return nil, fmt.Errorf("--telegram-chat is required with a Telegram token")
+		}
+		out = append(out, &notify.Telegram{Token: token, ChatID: scheduleTelegramChat})
+	}
+	return out, nil
+}
+
+func logJobDone(name string, took time.Duration, err error) {
+	now := time.Now().Format("15:04:05")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[%s] %s: failed after %s: %v\n", now, name, took.Round(time.Second), err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "[%s] %s: done in %s\n", now, name, took.Round(time.Second))
+}
+
+// appendJobLog appends text to a job's log, reporting a failure on stderr.
+func appendJobLog(dir, job, text string) {
+	f, err := openJobLog(dir, job)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warn: %v\n", err)
+		return
+	}
+	defer f.Close()
+	io.WriteString(f, text) //nolint:errcheck
+}
+
+func openJobLog(dir, job string) (*os.File, error) {
+	return os.OpenFile(filepath.Join(dir, job+".log"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+}
+
+// pipeline runs one job's stages.
+type pipeline struct {
+	job       config.Job
+	logDir    string
+	notifiers []notify.Notifier
+}
+
+// run runs the stages once, logging to the job's log, and notifies as
+// the job asks.
+func (p *pipeline) run(ctx context.Context) error {
+	log, err := openJobLog(p.logDir, p.job.Name)
+	if err != nil {
+		return err
+	}
+	defer log.Close()
+	start := time.Now()
+	fmt.Fprintf(log, "%s run started\n", start.Format(time.RFC3339))
+	summary, err := p.stages(ctx, log, start)
+	took := time.Since(start).Round(time.Second)
+	var text string
+	if err != nil {
+		fmt.Fprintf(log, "%s run failed after %s: %v\n", time.Now().Format(time.RFC3339), took, err)
+		text = fmt.Sprintf("[critical] proxybench job %s failed after %s: %v", p.job.Name, took, err)
+	} else {
+		fmt.Fprintf(log, "%s run done in %s: %s\n", time.Now().Format(time.RFC3339), took, summary)
+		text = fmt.Sprintf("[info] proxybench job %s: %s (took %s)", p.job.Name, summary, took)
+	}
+	if p.job.Notify == "always" || p.job.Notify == "failure" && err != nil {
+		for _, n := range p.notifiers {
+			if nerr := n.Send(text); nerr != nil {
+				fmt.Fprintf(log, "notify %s: %v\n", n.Name(), nerr)
+			}
+		}
+	}
+	return err
+}
+
+// stages runs fetch, check, bench and export in turn and returns a
+// one-line summary of the outcome.
+func (p *pipeline) stages(ctx context.Context, log io.Writer, start time.Time) (string, error) {
+	j := p.job
+	var input []byte
+	if len(j.Fetch) > 0 {
+		addrs, err := fetchLists(ctx, j.Fetch)
+		if err != nil {
+			return "", fmt.Errorf("fetch: %w", err)
+		}
+		fmt.Fprintf(log, "fetch: %d proxies from %d lists\n", len(addrs), len(j.Fetch))
+		input = []byte(strings.Join(addrs, "\n") + "\n")
+	}
+
+	var out []byte
+	var summary []string
+	command := "check"
+	if j.Check != nil {
+		format := j.Format
+		if j.Bench != nil {
+			format = "json"
+		}
+		var rs output.Results
+		var err error
+		if out, err = runStage(ctx, log, "check", j.Check, format, input, &rs); err != nil {
+			return "", err
+		}
+		var alive []string
+		for _, r := range rs.Checks {
+			if r.Result.Alive {
+				alive = append(alive, r.Result.Address)
+			}
+		}
+		summary = append(summary, fmt.Sprintf("%d/%d proxies alive", len(alive), len(rs.Checks)))
+		if j.Bench != nil && len(alive) == 0 {
+			return summary[0] + ", nothing to bench", nil
+		}
+		input = []byte(strings.Join(alive, "\n") + "\n")
+	}
+	if j.Bench != nil {
+		command = "bench"
+		var rs output.Results
+		var err error
+		if out, err = runStage(ctx, log, "bench", j.Bench, j.Format, input, &rs); err != nil {
+			return "", err
+		}
+		ok := 0
+		for _, b := range rs.Benches {
+			if b.Stats.OK() {
+				ok++
+			}
+		}
+		summary = append(summary, fmt.Sprintf("%d/%d benchmarked", ok, len(rs.Benches)))
+	}
+
+	if j.Export != "" {
+		loc, err := exportResults(ctx, j, command, out, start)
+		if err != nil {
+			return "", fmt.Errorf("export: %w", err)
+		}
+		fmt.Fprintf(log, "export: wrote %s\n", loc)
+		summary = append(summary, "exported to "+loc)
+	}
+	return strings.Join(summary, ", "), nil
+}
+
+// runStage runs 'proxybench <stage> <args> --format <format>' with input on
+// stdin (none if nil), its stderr going to log, and reads its output into
+// rs.
+func runStage(ctx context.Context, log io.Writer, stage string, args []string, format string, input []byte, rs *output.Results) ([]byte, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	argv := append([]string{stage}, args...)
+	if configFile != "" {
+		path, err := filepath.Abs(configFile)
+		if err != nil {
+			return nil, err
+		}
+		argv = append(argv, "--config", path)
+	}
+	if configProfile != "" {
+		argv = append(argv, "--profile", configProfile)
+	}
+	argv = append(argv, "--format", format)
+	fmt.Fprintf(log, "$ proxybench %s\n", strings.Join(argv, " "))
+
+	c := exec.CommandContext(ctx, exe, argv...)
+	if input != nil {
+		c.Stdin = bytes.NewReader(input)
+	}
+	var out bytes.Buffer
+	c.Stdout, c.Stderr = &out, log
+	if err := c.Run(); err != nil {
+		return nil, fmt.Errorf("%s: %w", stage, err)
+	}
+	if err := rs.ReadResults(bytes.NewReader(out.Bytes())); err != nil {
+		return nil, fmt.Errorf("%s: read results: %w", stage, err)
+	}
+	return out.Bytes(), nil
+}
+
+// fetchLists reads proxy lists from files or http(s) URLs and returns
+// their addresses.
+func fetchLists(ctx context.Context, sources []string) ([]string, error) {
+	var addrs []string
+	for _, src := range sources {
+		data, err := fetchList(ctx, src)
+		if err != nil {
+			return nil, err
+		}
+		proxies, _, err := proxylist.Read(data, "")
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", src, err)
+		}
+		for _, p := range proxies {
+			addrs = append(addrs, p.Address)
+		}
+	}
+	return addrs, nil
+}
+
+func fetchList(ctx context.Context, src string) ([]byte, error) {
+	if !strings.HasPrefix(src, "http://") && !strings.HasPrefix(src, "https://") {
+		return os.ReadFile(src)
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", src, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// exportResults writes a run's results to the job's export file, replaced
+// whole, or uploads them under a timestamped name, and returns where.
+func exportResults(ctx context.Context, j config.Job, command string, data []byte, start time.Time) (string, error) {
+	if strings.Contains(j.Export, "://") {
+		ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+		defer cancel()
+		return upload.Upload(ctx, j.Export, upload.FileName(command, j.Format, start), upload.ContentType(j.Format), data)
+	}
+	if err := os.MkdirAll(filepath.Dir(j.Export), 0o755); err != nil {
+		return "", err
+	}
+	tmp := j.Export + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return "", err
+	}
+	return j.Export, os.Rename(tmp, j.Export)
+}
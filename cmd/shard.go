@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/drsoft-oss/proxybench/internal/proxylist"
+)
+
+var (
+	shardSpec string
+
+	// shard is the part of the input this run covers, parsed from --shard
+	// by loadShard; the zero Shard covers all of it.
+	shard proxylist.Shard
+)
+
+func init() {
+	for _, c := range []*cobra.Command{checkCmd, benchCmd} {
+		c.Flags().StringVar(&shardSpec, "shard", "", "only take the i-th of N parts of the input, e.g. 2/5, to split one list across machines; merge the results with 'proxybench merge'")
+	}
+}
+
+// loadShard parses --shard into shard.
+func loadShard() error {
+	if shardSpec == "" {
+		return nil
+	}
+	var err error
+	if shard, err = proxylist.ParseShard(shardSpec); err != nil {
+		return fmt.Errorf("--shard: %w", err)
+	}
+	return nil
+}
+
+// shardAddresses passes on the addresses from in that belong to shard.
+func shardAddresses(ctx context.Context, in <-chan string) <-chan string {
+	if shard.Count <= 1 {
+		return in
+	}
+	out := make(chan string)
+	go func() {
+		defer close(out)
+		for a := range in {
+			if !shard.Has(a) {
+				continue
+			}
+			select {
+			case out <- a:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
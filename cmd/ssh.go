@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/drsoft-oss/proxybench/internal/checker"
+)
+
+var (
+	sshKeyPath  string
+	sshInsecure bool
+)
+
+func init() {
+	for _, c := range []*cobra.Command{checkCmd, benchCmd, monitorCmd} {
+		c.Flags().StringVar(&sshKeyPath, "ssh-key", "", "private key file to log in to ssh:// proxies with, besides any password in the address")
+		c.Flags().BoolVar(&sshInsecure, "ssh-insecure", false, "do not check ssh:// proxies' host keys against ~/.ssh/known_hosts before sending credentials")
+	}
+}
+
+// loadSSHKey reads --ssh-key for checker/bench options; nil if unset.
+func loadSSHKey() (ssh.Signer, error) {
+	if sshKeyPath == "" {
+		return nil, nil
+	}
+	key, err := checker.LoadSSHKey(sshKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("--ssh-key: %w", err)
+	}
+	return key, nil
+}
+
+// sshHostKeys is the host key check --ssh-insecure selects; nil uses
+// ~/.ssh/known_hosts.
+func sshHostKeys() ssh.HostKeyCallback {
+	if sshInsecure {
+		return ssh.InsecureIgnoreHostKey() //nolint:gosec // asked for with --ssh-insecure
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/drsoft-oss/proxybench/internal/checker"
+)
+
+var stableSort bool
+
+func init() {
+	for _, c := range []*cobra.Command{checkCmd, benchCmd} {
+		c.Flags().BoolVar(&stableSort, "stable-sort", false, "write results sorted by normalized address, once per proxy, after the run (diffable output)")
+	}
+}
+
+// stableOrder sorts rows by the normalized form of their address and
+// keeps only the first row for each, so two runs over the same proxies
+// produce the same sequence whatever the input order or duplicates.
+func stableOrder[T any](rows []T, address func(T) string) []T {
+	type keyedRow struct {
+		key string
+		row T
+	}
+	keyed := make([]keyedRow, len(rows))
+	for i, r := range rows {
+		keyed[i].key, keyed[i].row = checker.Normalize(address(r)), r
+	}
+	slices.SortStableFunc(keyed, func(a, b keyedRow) int {
+		return strings.Compare(a.key, b.key)
+	})
+	out := rows[:0]
+	for i, k := range keyed {
+		if i == 0 || k.key != keyed[i-1].key {
+			out = append(out, k.row)
+		}
+	}
+	return out
+}
@@ -0,0 +1,13 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var summaryByCountry bool
+
+func init() {
+	for _, c := range []*cobra.Command{checkCmd, benchCmd} {
+		c.Flags().BoolVar(&summaryByCountry, "summary", false, "write one row per exit country (count, alive %, median latency, best proxy) instead of one per proxy; implies --geo")
+	}
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/drsoft-oss/proxybench/internal/checker"
+	"github.com/drsoft-oss/proxybench/internal/sysproxy"
+)
+
+var checkSystem bool
+
+func init() {
+	checkCmd.Flags().BoolVar(&checkSystem, "system", false, "check the proxies this machine is configured to use (proxy environment variables, macOS, Windows or GNOME proxy settings)")
+}
+
+// withSystem appends the system proxy settings to args, expanding a
+// configured auto-config URL into the proxies it can return. An
+// auto-config URL that cannot be read is reported and skipped.
+func withSystem(args []string) []string {
+	if !checkSystem {
+		return args
+	}
+	entries := sysproxy.Read(context.Background())
+	if len(entries) == 0 {
+		fmt.Fprintln(os.Stderr, "warn: --system: no proxy configured on this machine")
+	}
+	for _, e := range entries {
+		if !e.PAC {
+			fmt.Fprintf(os.Stderr, "System proxy %s (%s)\n", checker.Redact(e.Address), e.Source)
+			args = append(args, e.Address)
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "System auto-config %s (%s)\n", e.Address, e.Source)
+		addrs, err := pacProxies(e.Address)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warn: --system: %s: %v\n", e.Address, err)
+			continue
+		}
+		args = append(args, addrs...)
+	}
+	return args
+}
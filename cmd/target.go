@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/drsoft-oss/proxybench/internal/target"
+	"github.com/drsoft-oss/proxybench/internal/tracing"
+)
+
+var targetCmd = &cobra.Command{
+	Use:   "target",
+	Short: "Serve a test target to check and benchmark proxies against",
+	Long: `Target runs an HTTP(S) server to point --test-url, --payload-url, --mtu-url
+and --exit-ip-url at, so benchmarks hit infrastructure you control instead
+of a public site. Run it on a host the proxies can reach.
+
+Endpoints (all accept ?delay=250ms to inject latency):
+  /            "ok"
+  /bytes/N     N bytes of incompressible data (k, M, G suffixes)
+  /echo        the request as received, as JSON
+  /ip          the client's (proxy's exit) address
+  /status/N    an empty response with status N
+
+Examples:
+  proxybench target --listen :8080
+  proxybench bench --test-url http://203.0.113.5:8080/ --payload-url http://203.0.113.5:8080/bytes/10M
+  proxybench target --listen :8443 --tls --cert cert.pem --key key.pem`,
+	Args: cobra.NoArgs,
+	RunE: runTarget,
+}
+
+var (
+	targetListen   string
+	targetTLS      bool
+	targetCert     string
+	targetKey      string
+	targetDelay    time.Duration
+	targetMaxBytes string
+)
+
+func init() {
+	targetCmd.Flags().StringVar(&targetListen, "listen", ":8080", "address to serve on")
+	targetCmd.Flags().BoolVar(&targetTLS, "tls", false, "serve https, with --cert/--key or a self-signed certificate")
+	targetCmd.Flags().StringVar(&targetCert, "cert", "", "PEM certificate for --tls")
+	targetCmd.Flags().StringVar(&targetKey, "key", "", "PEM private key for --tls")
+	targetCmd.Flags().DurationVar(&targetDelay, "delay", 0, "latency added to every response")
+	targetCmd.Flags().StringVar(&targetMaxBytes, "max-bytes", "1G", "largest /bytes/N response")
+}
+
+func runTarget(cmd *cobra.Command, args []string) error {
+	if (targetCert == "") != (targetKey == "") {
+		return fmt.Errorf("--cert and --key go together")
+	}
+	if targetCert != "" && !targetTLS {
+		return fmt.Errorf("--cert and --key need --tls")
+	}
+	if targetDelay < 0 {
+		return fmt.Errorf("--delay must not be negative")
+	}
+	maxBytes, err := target.ParseSize(targetMaxBytes)
+	if err != nil {
+		return fmt.Errorf("--max-bytes: %w", err)
+	}
+	stopTracing, err := startTracing()
+	if err != nil {
+		return err
+	}
+	defer stopTracing()
+
+	ln, err := net.Listen("tcp", targetListen)
+	if err != nil {
+		return fmt.Errorf("--listen: %w", err)
+	}
+	scheme := "http"
+	if targetTLS {
+		cert, err := targetCertificate(ln.Addr())
+		if err != nil {
+			ln.Close()
+			return err
+		}
+		ln = tls.NewListener(ln, &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12})
+		scheme = "https"
+	}
+
+	s := &target.Server{Delay: targetDelay, MaxBytes: maxBytes}
+	srv := &http.Server{Handler: tracing.Middleware(s.Handler()), ReadHeaderTimeout: 5 * time.Second}
+	go srv.Serve(ln) //nolint:errcheck
+	defer srv.Close()
+	fmt.Fprintf(os.Stderr, "Serving test target on %s://%s (Ctrl-C to stop)…\n", scheme, ln.Addr())
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	<-stop
+	return nil
+}
+
+// targetCertificate loads --cert/--key, or makes a self-signed certificate
+// for the listening address and localhost.
+func targetCertificate(addr net.Addr) (tls.Certificate, error) {
+	if targetCert != "" {
+		cert, err := tls.LoadX509KeyPair(targetCert, targetKey)
+		if err != nil {
+			return cert, fmt.Errorf("--cert/--key: %w", err)
+		}
+		return cert, nil
+	}
+	hosts := []string{"localhost", "127.0.0.1", "::1"}
+	if host, _, err := net.SplitHostPort(addr.String()); err == nil {
+		hosts = append(hosts, host)
+	}
+	if name, err := os.Hostname(); err == nil {
+		hosts = append(hosts, name)
+	}
+	fmt.Fprintln(os.Stderr, "warn: serving a self-signed certificate; check and bench reject it unless run with --insecure, so use --cert/--key for https tests")
+	return target.SelfSigned(hosts)
+}
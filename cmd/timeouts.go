@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Per-phase limits shared by check and bench; zero falls back to --timeout.
+var (
+	connectTimeout   time.Duration
+	handshakeTimeout time.Duration
+	requestTimeout   time.Duration
+	overallBudget    time.Duration
+)
+
+func init() {
+	for _, c := range []*cobra.Command{checkCmd, benchCmd} {
+		c.Flags().DurationVar(&connectTimeout, "connect-timeout", 0, "limit for the TCP connection to a proxy (default --timeout)")
+		c.Flags().DurationVar(&handshakeTimeout, "handshake-timeout", 0, "limit for the SOCKS5 handshake or TLS with an https proxy (default --timeout)")
+		c.Flags().DurationVar(&requestTimeout, "request-timeout", 0, "limit for a whole request through a proxy, handshakes included (default --timeout)")
+		c.Flags().DurationVar(&overallBudget, "budget", 0, "limit for everything done with one proxy, optional probes included (default none)")
+	}
+}
+
+// phaseTimeouts returns the per-phase limits, rejecting negative ones.
+func phaseTimeouts() (connect, handshake, request, budget time.Duration, err error) {
+	for _, d := range []time.Duration{connectTimeout, handshakeTimeout, requestTimeout, overallBudget} {
+		if d < 0 {
+			return 0, 0, 0, 0, fmt.Errorf("--connect-timeout, --handshake-timeout, --request-timeout and --budget must not be negative")
+		}
+	}
+	return connectTimeout, handshakeTimeout, requestTimeout, overallBudget, nil
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/drsoft-oss/proxybench/internal/checker"
+)
+
+var (
+	caCertPath     string
+	insecureTLS    bool
+	proxyInsecure  bool
+	tlsFingerprint string
+
+	// caRoots is the pool loadTLS read from --ca-cert, for the command's
+	// own https fetches such as --pac.
+	caRoots *x509.CertPool
+)
+
+func init() {
+	for _, c := range []*cobra.Command{checkCmd, benchCmd, monitorCmd} {
+		c.Flags().StringVar(&caCertPath, "ca-cert", "", "PEM bundle of extra CAs to trust for https proxies and targets, e.g. a corporate TLS-interception CA")
+		c.Flags().BoolVar(&insecureTLS, "insecure", false, "do not verify certificates of https proxies and targets; recorded as tls_policy in results")
+		c.Flags().BoolVar(&proxyInsecure, "proxy-insecure", false, "do not verify the certificates of https:// and hy2:// proxies themselves; targets are still verified")
+		c.Flags().StringVar(&tlsFingerprint, "tls-fingerprint", "go", "ClientHello sent to https targets and proxies: go|"+strings.Join(checker.TLSFingerprints, "|"))
+	}
+}
+
+// loadTLS reads --ca-cert and --insecure into the roots and flag of
+// checker/bench options, and says on stderr that checks are relaxed.
+func loadTLS() (*x509.CertPool, bool, error) {
+	if caCertPath != "" {
+		var err error
+		if caRoots, err = checker.LoadCACert(caCertPath); err != nil {
+			return nil, false, fmt.Errorf("--ca-cert: %w", err)
+		}
+	}
+	if insecureTLS {
+		fmt.Fprintln(os.Stderr, "warn: --insecure: TLS certificates are not verified")
+	}
+	return caRoots, insecureTLS, nil
+}
+
+// loadFingerprint parses --tls-fingerprint.
+func loadFingerprint() (string, error) {
+	f, err := checker.ParseTLSFingerprint(tlsFingerprint)
+	if err != nil {
+		return "", fmt.Errorf("--tls-fingerprint: %w", err)
+	}
+	return f, nil
+}
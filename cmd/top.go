@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/drsoft-oss/proxybench/internal/bench"
+	"github.com/drsoft-oss/proxybench/internal/checker"
+	"github.com/drsoft-oss/proxybench/internal/output"
+	"github.com/drsoft-oss/proxybench/internal/pool"
+	"github.com/drsoft-oss/proxybench/internal/tui"
+)
+
+var topCmd = &cobra.Command{
+	Use:   "top [proxy...]",
+	Short: "Interactive dashboard that keeps re-checking proxies",
+	Long: `Top shows the given proxies in a full-screen table that is refreshed as
+they are re-checked in rounds, like monitor without the alerts. Columns can
+be sorted, a detail pane shows the selected proxy's last check and an
+on-demand benchmark, and proxies can be re-checked or removed by key.
+
+Keys: ↑/↓ (j/k) select, ←/→ (s) sort column, S reverse, enter details,
+r re-check, R start a round, b bench, d remove, q quit.
+
+The proxy list may be piped in; keys are read from the terminal.
+
+Examples:
+  proxybench top http://1.2.3.4:8080 socks5://5.6.7.8:1080
+  proxybench top --interval 15 < proxies.txt`,
+	RunE: runTop,
+}
+
+var (
+	topInterval    int
+	topTimeout     int
+	topTestURL     string
+	topConcurrency int
+	topSamples     int
+	topGeo         bool
+	topDBPath      string
+)
+
+func init() {
+	topCmd.Flags().IntVarP(&topInterval, "interval", "i", 30, "seconds between check rounds")
+	topCmd.Flags().IntVarP(&topTimeout, "timeout", "t", 10, "per-proxy timeout in seconds")
+	topCmd.Flags().StringVar(&topTestURL, "test-url", "http://www.google.com", "URL to use for HTTP/SOCKS5 forward checks")
+	topCmd.Flags().IntVarP(&topConcurrency, "concurrency", "c", 10, "max parallel checks")
+	topCmd.Flags().IntVar(&topSamples, "samples", 5, "requests per on-demand benchmark")
+	topCmd.Flags().BoolVar(&topGeo, "geo", true, "show country info (requires IP database)")
+	topCmd.Flags().StringVar(&topDBPath, "db", "", "path to ip2country.csv (default: auto-detect)")
+}
+
+// topCheck is a finished check of an address as listed.
+type topCheck struct {
+	address string
+	result  checker.Result
+}
+
+// topBench is a finished benchmark of an address as listed.
+type topBench struct {
+	address string
+	stats   bench.Stats
+}
+
+func runTop(cmd *cobra.Command, args []string) error {
+	addresses := collectAddresses(args)
+	if len(addresses) == 0 {
+		return fmt.Errorf("no proxy addresses provided; pass them as arguments, via stdin or as config sources")
+	}
+	if topInterval <= 0 {
+		return fmt.Errorf("--interval must be positive")
+	}
+	if err := setupResolver(); err != nil {
+		return err
+	}
+	opts := checker.Options{
+		Timeout:     time.Duration(topTimeout) * time.Second,
+		TestURL:     topTestURL,
+		Concurrency: topConcurrency,
+	}
+	var err error
+	if opts.Credentials, err = loadCredentials(); err != nil {
+		return err
+	}
+	benchOpts := bench.DefaultOptions()
+	benchOpts.Samples = topSamples
+	benchOpts.Timeout = opts.Timeout
+	benchOpts.TestURL = topTestURL
+	benchOpts.Credentials = opts.Credentials
+
+	// Load the geo database now, so its warnings go to the normal screen.
+	locate := geoLookup(topGeo, topDBPath)
+	locate("")
+
+	term, err := tui.Open()
+	if err != nil {
+		return err
+	}
+	defer term.Close()
+
+	d := tui.New(addresses)
+	interval := time.Duration(topInterval) * time.Second
+	keys := make(chan tui.Key)
+	checks := make(chan topCheck)
+	benches := make(chan topBench)
+	countries := make(chan [2]string)
+	roundDone := make(chan struct{})
+
+	go func() {
+		buf := make([]byte, 64)
+		for {
+			n, err := term.Read(buf)
+			if err != nil {
+				close(keys)
+				return
+			}
+			for _, k := range tui.ParseKeys(buf[:n]) {
+				keys <- k
+			}
+		}
+	}()
+	go func() {
+		for _, a := range addresses {
+			country, _ := output.Locate(locate(a))
+			countries <- [2]string{a, country}
+		}
+	}()
+
+	running := false
+	startRound := func() {
+		if running {
+			return
+		}
+		running = true
+		list := d.Addresses()
+		for _, a := range list {
+			d.Checking(a)
+		}
+		d.NextRound = time.Now().Add(interval)
+		go func() {
+			// Results come in input order, so the i-th is list[i]'s even
+			// when the checker rewrote the address.
+			i := 0
+			checker.CheckStream(pool.FromSlice(list), opts, func(r checker.Result) {
+				checks <- topCheck{list[i], r}
+				i++
+			})
+			roundDone <- struct{}{}
+		}()
+	}
+
+	rounds := time.NewTicker(interval)
+	defer rounds.Stop()
+	redraw := time.NewTicker(time.Second)
+	defer redraw.Stop()
+	startRound()
+	for {
+		w, h := term.Size()
+		if err := term.Draw(d.Render(w, h, time.Now())); err != nil {
+			return err
+		}
+		select {
+		case k, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			switch a := d.Handle(k); a.Kind {
+			case tui.ActionQuit:
+				return nil
+			case tui.ActionRecheckAll:
+				if running {
+					d.Message("a round is already running")
+				}
+				startRound()
+				rounds.Reset(interval)
+			case tui.ActionRecheck:
+				go func() { checks <- topCheck{a.Address, checker.Check(a.Address, opts)} }()
+			case tui.ActionBench:
+				go func() { benches <- topBench{a.Address, bench.Run(a.Address, benchOpts)} }()
+			}
+		case c := <-checks:
+			d.Update(c.address, c.result, time.Now())
+		case b := <-benches:
+			d.UpdateBench(b.address, b.stats)
+		case c := <-countries:
+			d.SetCountry(c[0], c[1])
+		case <-roundDone:
+			running = false
+		case <-rounds.C:
+			startRound()
+		case <-term.Resized():
+		case <-redraw.C:
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/drsoft-oss/proxybench/internal/tracing"
+)
+
+var (
+	otlpEndpoint string
+	otlpInsecure bool
+)
+
+func init() {
+	for _, c := range []*cobra.Command{checkCmd, benchCmd, monitorCmd} {
+		c.Flags().StringVar(&otlpEndpoint, "otlp-endpoint", "", "export OpenTelemetry traces over OTLP/HTTP (host:port or URL; OTEL_EXPORTER_OTLP_* also honoured)")
+		c.Flags().BoolVar(&otlpInsecure, "otlp-insecure", false, "send OTLP traces over plain HTTP")
+	}
+}
+
+// startTracing installs the OTLP exporter when --otlp-endpoint or the
+// standard OTEL environment is set, and returns a func that flushes it.
+func startTracing() (func(), error) {
+	if otlpEndpoint == "" && !tracing.EnvConfigured() {
+		return func() {}, nil
+	}
+	shutdown, err := tracing.Setup(context.Background(), otlpEndpoint, otlpInsecure, version)
+	if err != nil {
+		return nil, fmt.Errorf("--otlp-endpoint: %w", err)
+	}
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdown(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "warn: flushing traces: %v\n", err)
+		}
+	}, nil
+}
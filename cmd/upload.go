@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/drsoft-oss/proxybench/internal/upload"
+)
+
+var uploadTarget string
+
+func init() {
+	checkCmd.Flags().StringVar(&uploadTarget, "upload", "", "also upload the output to s3://bucket/path, gs://bucket/path or az://account/container/path")
+	benchCmd.Flags().StringVar(&uploadTarget, "upload", "", "also upload the output to s3://bucket/path, gs://bucket/path or az://account/container/path")
+}
+
+// resultWriter returns stdout, teed into a buffer when --upload is set, and
+// a finish func that uploads the buffered output (a no-op without --upload).
+// The target is validated up front so a typo fails before any network work.
+func resultWriter(command, format string) (io.Writer, func() error, error) {
+	if uploadTarget == "" {
+		return os.Stdout, func() error { return nil }, nil
+	}
+	if _, err := upload.ParseTarget(uploadTarget); err != nil {
+		return nil, nil, fmt.Errorf("--upload: %w", err)
+	}
+	var buf bytes.Buffer
+	started := time.Now()
+	return io.MultiWriter(os.Stdout, &buf), func() error {
+		ext := format
+		if ext != "json" && ext != "ndjson" && ext != "csv" {
+			ext = "txt"
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		defer cancel()
+		loc, err := upload.Upload(ctx, uploadTarget, upload.FileName(command, ext, started), upload.ContentType(format), buf.Bytes())
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Uploaded results to %s\n", loc)
+		return nil
+	}, nil
+}
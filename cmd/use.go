@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/spf13/cobra"
+
+	"github.com/drsoft-oss/proxybench/internal/checker"
+	"github.com/drsoft-oss/proxybench/internal/output"
+	"github.com/drsoft-oss/proxybench/internal/sysproxy"
+)
+
+var useCmd = &cobra.Command{
+	Use:   "use <address|best>",
+	Short: "Make a proxy, or the best one in a results file, the system proxy",
+	Long: `Use prints the commands that make a proxy this machine's system proxy:
+networksetup on macOS, the WinINET registry values on Windows and
+gsettings on Linux (GNOME). With --apply it runs them instead.
+
+"best" picks the proxy from earlier check or bench results (--from, in
+JSON, NDJSON or CSV; "-" is stdin) the way 'merge --prefer best' does:
+alive (bench: reachable) first, then the lowest latency (bench: loss,
+then p50). An http:// proxy is set for http and https URLs, a socks5://
+proxy as the SOCKS proxy. System settings hold neither credentials nor
+proxies reached over TLS.
+
+--off prints (or with --apply runs) the commands that turn the system
+proxy off again. Windows applications read the new settings when they
+next start; the environment variables --system also reads are not
+changed.
+
+Examples:
+  proxybench use socks5://127.0.0.1:1080
+  proxybench check -f json < proxies.txt > results.json
+  proxybench use best --from results.json --apply
+  proxybench use --off --apply`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if useOff {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.ExactArgs(1)(cmd, args)
+	},
+	RunE: runUse,
+}
+
+var (
+	useFrom    string
+	useApply   bool
+	useOff     bool
+	useService string
+)
+
+func init() {
+	useCmd.Flags().StringVar(&useFrom, "from", "", `results file "best" picks from ("-" for stdin)`)
+	useCmd.Flags().BoolVar(&useApply, "apply", false, "change the system settings instead of printing the commands")
+	useCmd.Flags().BoolVar(&useOff, "off", false, "turn the system proxy off")
+	useCmd.Flags().StringVar(&useService, "service", sysproxy.DefaultService, "macOS network service to configure (see networksetup -listallnetworkservices)")
+}
+
+func runUse(cmd *cobra.Command, args []string) error {
+	var cmds [][]string
+	var address string
+	var err error
+	if useOff {
+		cmds, err = sysproxy.OffCommands(runtime.GOOS, useService)
+	} else {
+		if address, err = useAddress(args[0]); err != nil {
+			return err
+		}
+		cmds, err = sysproxy.Commands(runtime.GOOS, address, useService)
+		if err != nil {
+			err = fmt.Errorf("%s: %w", checker.Redact(address), err)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if !useApply {
+		for _, argv := range cmds {
+			fmt.Println(sysproxy.Quote(argv))
+		}
+		return nil
+	}
+	if err := sysproxy.Run(context.Background(), cmds); err != nil {
+		return err
+	}
+	if useOff {
+		fmt.Fprintln(os.Stderr, "System proxy turned off")
+	} else {
+		fmt.Fprintf(os.Stderr, "System proxy set to %s\n", address)
+	}
+	return nil
+}
+
+// useAddress resolves the use argument: an address as given, or "best"
+// picked from the --from results.
+func useAddress(arg string) (string, error) {
+	if arg != "best" {
+		return arg, nil
+	}
+	if useFrom == "" {
+		return "", fmt.Errorf(`"best" needs the results to pick from: pass --from results.json`)
+	}
+	var rs output.Results
+	if err := readResultsFile(&rs, useFrom); err != nil {
+		return "", err
+	}
+	address, ok := output.Best(&rs)
+	if !ok {
+		return "", fmt.Errorf("%s: no alive proxy in %d results", useFrom, rs.Len())
+	}
+	fmt.Fprintf(os.Stderr, "Best proxy: %s\n", checker.Redact(address))
+	return address, nil
+}
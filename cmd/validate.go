@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/drsoft-oss/proxybench/internal/checker"
+	"github.com/drsoft-oss/proxybench/internal/proxylist"
+)
+
+var validateCmd = &cobra.Command{
+	Use:   "validate [file]",
+	Short: "Lint a proxy list and write it back cleaned, without checking it",
+	Long: `Validate reads a text list of proxies (from file, or stdin), reports each
+malformed line with its line number and reason on stderr, and writes the
+remaining entries cleaned: whitespace trimmed, scheme and host lower-cased,
+the scheme added where missing and host:port:user:pass rewritten as
+user:pass@host:port. Nothing is dialled, so it works offline.
+
+Examples:
+  proxybench validate proxies.txt -o clean.txt
+  proxybench validate --scheme socks5 < vendor.txt | proxybench check
+  proxybench validate --strict --scheme "" proxies.txt > /dev/null   # lint only`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runValidate,
+}
+
+var (
+	validateScheme string
+	validateOutput string
+	validateDedup  bool
+	validateStrict bool
+)
+
+func init() {
+	f := validateCmd.Flags()
+	f.StringVar(&validateScheme, "scheme", "http", "scheme added to bare host:port entries: http|https|socks5|socks5h (\"\" leaves them bare for auto-detection)")
+	f.StringVarP(&validateOutput, "output", "o", "", "write the cleaned list to this file instead of stdout")
+	f.BoolVar(&validateDedup, "dedup", true, "drop entries that normalize to one already listed")
+	f.BoolVar(&validateStrict, "strict", false, "exit with an error if any line is malformed")
+}
+
+func runValidate(cmd *cobra.Command, args []string) error {
+	switch validateScheme {
+	case "", "http", "https", "socks5", "socks5h":
+	default:
+		return fmt.Errorf("--scheme: want http, https, socks5, socks5h or \"\", got %q", validateScheme)
+	}
+
+	in := io.Reader(os.Stdin)
+	if len(args) == 1 && args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	entries, problems, err := proxylist.Lint(in, validateScheme)
+	if err != nil {
+		return err
+	}
+	for _, p := range problems {
+		fmt.Fprintln(os.Stderr, p)
+	}
+
+	var b strings.Builder
+	kept, dups := 0, 0
+	seen := map[string]int{}
+	for _, e := range entries {
+		key := checker.Normalize(e.Address)
+		if first, ok := seen[key]; ok && validateDedup {
+			fmt.Fprintf(os.Stderr, "line %d: %s: duplicate of line %d\n", e.Line, checker.Redact(e.Address), first)
+			dups++
+			continue
+		}
+		seen[key] = e.Line
+		b.WriteString(e.Address + "\n")
+		kept++
+	}
+
+	out := io.Writer(os.Stdout)
+	if validateOutput != "" {
+		f, err := os.Create(validateOutput)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	if _, err := io.WriteString(out, b.String()); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Kept %d of %d entries (%d malformed, %d duplicates)\n",
+		kept, len(entries)+len(problems), len(problems), dups)
+	if validateStrict && len(problems) > 0 {
+		return fmt.Errorf("%d malformed entries", len(problems))
+	}
+	return nil
+}
@@ -2,14 +2,26 @@
 package bench
 
 import (
+	"cmp"
+	"context"
+	"crypto/x509"
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
 	"sort"
 	"time"
 
-	"golang.org/x/net/proxy"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/drsoft-oss/proxybench/internal/checker"
+	"github.com/drsoft-oss/proxybench/internal/pool"
+	"github.com/drsoft-oss/proxybench/internal/proxyproto"
+	"github.com/drsoft-oss/proxybench/internal/resolver"
+	"github.com/drsoft-oss/proxybench/internal/tracing"
 )
 
 // Stats holds benchmark statistics for a single proxy.
@@ -24,6 +36,99 @@ type Stats struct {
 	P95MS      int64   `json:"p95_ms"`
 	LossRate   float64 `json:"loss_rate"`   // 0.0 – 1.0
 	SpeedBps   int64   `json:"speed_bps"`   // bytes/sec of payload download, 0 if not measured
+
+	// PercentileMethod records how P50MS and P95MS were computed, so
+	// results can be compared with other tools' percentiles.
+	PercentileMethod PercentileMethod `json:"percentile_method"`
+
+	// StatusCodes counts successful samples by the HTTP status the test
+	// URL answered with, and StatusClasses by class ("2xx" … "5xx"). A
+	// proxy answering 403 or 429 to every sample has no loss but is of no
+	// use.
+	StatusCodes   map[int]int    `json:"status_codes,omitempty"`
+	StatusClasses map[string]int `json:"status_classes,omitempty"`
+
+	// Error explains why a proxy with some successful samples still
+	// failed, e.g. fewer than Options.MinSuccessful; its stats are zero.
+	Error string `json:"error,omitempty"`
+
+	// Set with PayloadURL: bytes/sec in each second of the download, the
+	// best second, and how long the download took to first reach 90% of
+	// it. A series that falls away after the peak shows a proxy that
+	// throttles once the first megabytes are through.
+	SpeedSeries []int64 `json:"speed_series,omitempty"`
+	PeakBps     int64   `json:"peak_bps,omitempty"`
+	RampUpMS    int64   `json:"ramp_up_ms,omitempty"`
+
+	// Set with Options.Sustain: average bytes/sec over the first
+	// Options.ThrottleAfter of the download and over the rest, and whether
+	// the rest fell short of the start by more than Options.ThrottleDrop,
+	// as with providers that shape traffic after a free burst.
+	InitialBps   int64 `json:"initial_bps,omitempty"`
+	SustainedBps int64 `json:"sustained_bps,omitempty"`
+	Throttled    bool  `json:"throttled,omitempty"`
+
+	// Set with Options.Ceiling: the highest aggregate bytes/sec reached
+	// with parallel downloads, and how many connections it took.
+	CapacityBps     int64 `json:"capacity_bps,omitempty"`
+	SaturationConns int   `json:"saturation_conns,omitempty"`
+
+	// Set with Options.Churn: the most new tunnels per second the proxy
+	// accepted, at how many concurrent attempts, and the concurrency at
+	// which 5% or more of attempts started failing (0 if none did).
+	HandshakeRate   float64 `json:"handshake_rate,omitempty"`
+	HandshakeConns  int     `json:"handshake_conns,omitempty"`
+	ErrorOnsetConns int     `json:"error_onset_conns,omitempty"`
+
+	// Set with Options.ConnLimit: the most connections through the proxy
+	// that were open and answered at once, and whether a higher number
+	// failed (if not, ConnLimit is only a lower bound at ConnLimitMax).
+	ConnLimit    int  `json:"conn_limit,omitempty"`
+	ConnLimitHit bool `json:"conn_limit_hit,omitempty"`
+
+	// Set only with Options.ReuseConnections: average latency of samples
+	// that opened a new connection (TCP + proxy handshake) and of samples
+	// served over an already-open one.
+	ColdMS int64 `json:"cold_ms,omitempty"`
+	WarmMS int64 `json:"warm_ms,omitempty"`
+	// Reconnects counts samples, after the first connection was open,
+	// that had to open a new one because the proxy dropped it.
+	Reconnects int `json:"reconnects,omitempty"`
+
+	// Set with Options.UDPTarget for SOCKS5 proxies: whether the proxy
+	// grants UDP ASSOCIATE (nil when it could not be asked), and the
+	// round-trip time and loss of DNS queries sent through its relay.
+	UDPSupported *bool   `json:"udp_supported,omitempty"`
+	UDPP50MS     int64   `json:"udp_p50_ms,omitempty"`
+	UDPP95MS     int64   `json:"udp_p95_ms,omitempty"`
+	UDPLossRate  float64 `json:"udp_loss_rate,omitempty"`
+
+	// Labels such as "fast" assigned by the caller (see package classify)
+	// from P50MS and SpeedBps; empty when there was nothing to measure.
+	LatencyClass string `json:"latency_class,omitempty"`
+	SpeedClass   string `json:"speed_class,omitempty"`
+
+	// Verdict assigned by the caller from loss, latency and speed
+	// thresholds (see classify.Thresholds): Grade is A–F, and Usable is
+	// true exactly when Grade is not F.
+	Usable bool   `json:"usable"`
+	Grade  string `json:"grade,omitempty"`
+
+	// TestURL is the URL the latency samples went to when
+	// Options.TestURLFor chose one for this proxy.
+	TestURL string `json:"test_url,omitempty"`
+
+	// Paths compares the absolute-URI GET and CONNECT paths of an HTTP
+	// proxy; set with Options.ComparePaths, nil for other protocols.
+	Paths *PathStats `json:"paths,omitempty"`
+
+	// TLSPolicy records how certificate checks were relaxed (see
+	// checker.Result.TLSPolicy).
+	TLSPolicy string `json:"tls_policy,omitempty"`
+
+	// Count is how many input lines named this proxy, set by the caller
+	// when it collapses duplicates; 0 when it does not.
+	Count int `json:"count,omitempty"`
 }
 
 // Options configures a benchmark run.
@@ -33,6 +138,141 @@ type Options struct {
 	TestURL     string
 	PayloadURL  string // optional large URL for throughput measurement
 	Concurrency int
+
+	// Per-phase limits for latency samples, each defaulting to Timeout
+	// when zero (see the checker.Options fields of the same names).
+	// OverallBudget, if set, bounds everything done for one proxy,
+	// including the throughput tests.
+	ConnectTimeout   time.Duration
+	HandshakeTimeout time.Duration
+	RequestTimeout   time.Duration
+	OverallBudget    time.Duration
+
+	// Sustain, if set, makes the PayloadURL download last this long,
+	// repeating it as needed, to catch throttling that starts after a
+	// burst: the rate after the first ThrottleAfter is compared with the
+	// rate before it (see Stats.Throttled).
+	Sustain       time.Duration
+	ThrottleAfter time.Duration
+	ThrottleDrop  float64
+
+	// Ceiling adds a capacity test on PayloadURL: parallel downloads are
+	// doubled, up to CeilingMax connections for CeilingWindow each, until
+	// aggregate throughput stops rising (see Stats.CapacityBps).
+	Ceiling       bool
+	CeilingMax    int
+	CeilingWindow time.Duration
+
+	// Churn adds a connection rate test on TestURL: every request opens a
+	// new connection, with concurrent attempts doubled up to ChurnMax for
+	// ChurnWindow each, until errors set in (see Stats.HandshakeRate).
+	Churn       bool
+	ChurnMax    int
+	ChurnWindow time.Duration
+
+	// ConnLimit adds a concurrency test on TestURL: 1, 2, 4, ... up to
+	// ConnLimitMax connections are held open through the proxy at once,
+	// each with a request on it, until some fail (see Stats.ConnLimit).
+	ConnLimit    bool
+	ConnLimitMax int
+
+	// ReuseConnections keeps the proxy connection open between samples, so
+	// only the first sample pays for the TCP and proxy handshake. Cold and
+	// warm latencies are then reported separately in Stats.
+	ReuseConnections bool
+
+	// TimeoutFor, if set, overrides Timeout for a proxy's latency samples
+	// when it reports ok, e.g. from AdaptiveTimeout over earlier results.
+	// Payload downloads keep Timeout.
+	TimeoutFor func(address string) (time.Duration, bool)
+
+	// TestURLFor, if set, picks the test URL for a proxy, e.g. the one
+	// nearest to it from a set of Targets; "" keeps TestURL. Latency and
+	// churn samples go to it, payload downloads do not.
+	TestURLFor func(address string) string
+
+	// WarmPath measures steady-state latency: an unmeasured warm-up request
+	// opens the connection (and any CONNECT tunnel) first, and every sample
+	// then goes over it. ColdMS is the warm-up's latency. Implies
+	// ReuseConnections.
+	WarmPath bool
+
+	// Interleave spreads each proxy's samples across the run instead of
+	// taking them back to back: workers take one sample at a time,
+	// round-robin over a window of proxies. Measurements are less biased
+	// by momentary network conditions and slow proxies no longer hold a
+	// worker for all their samples.
+	Interleave bool
+
+	// AnyOrder makes RunStream emit stats as each proxy finishes instead
+	// of in input order. Interleaved runs always finish proxies in order.
+	AnyOrder bool
+
+	// Context, if set, aborts benchmarks in flight when it is done; their
+	// remaining samples fail at once.
+	Context context.Context
+
+	// MinSuccessful is how many samples must succeed for latency stats
+	// to be computed; below it the proxy is reported as failed. Zero
+	// means one.
+	MinSuccessful int
+
+	// ProxyProtocol starts every connection to the proxy with a PROXY
+	// protocol header (see checker.Options.ProxyProtocol).
+	ProxyProtocol proxyproto.Version
+
+	// RootCAs and InsecureTLS relax certificate checks on https proxies
+	// and targets (see checker.Options.RootCAs).
+	RootCAs     *x509.CertPool
+	InsecureTLS bool
+	// ProxyInsecure skips verification of https:// and hy2:// proxies' own
+	// certificates only (see checker.Options.ProxyInsecure).
+	ProxyInsecure bool
+	// TLSFingerprint is the ClientHello sent to https targets and
+	// proxies (see checker.Options.TLSFingerprint).
+	TLSFingerprint string
+
+	// H3 fetches the test URL and payloads through SOCKS5 proxies over
+	// HTTP/3, i.e. QUIC through their UDP relay (see checker.H3Transport),
+	// instead of over TCP. Per-proxy overrides do not apply to it.
+	H3 bool
+
+	// SSHKey is offered to ssh:// proxies (see checker.Options.SSHKey).
+	SSHKey ssh.Signer
+	// SSHHostKeys checks ssh:// proxies' host keys (see
+	// checker.Options.SSHHostKeys).
+	SSHHostKeys ssh.HostKeyCallback
+
+	// Credentials supplies user:pass for proxies whose address has none
+	// (see checker.Options.Credentials).
+	Credentials func(hostPort string) *url.Userinfo
+
+	// Overrides supplies the SNI and Host override for proxies whose
+	// address does not set them (see checker.Override).
+	Overrides func(hostPort string) checker.Override
+
+	// Request sets the method, body and content type of every sample
+	// (see checker.Request); the zero value is a GET.
+	Request checker.Request
+
+	// Percentiles selects how P50MS and P95MS are computed; empty means
+	// Nearest.
+	Percentiles PercentileMethod
+
+	// UDPTarget, if set, is a DNS resolver ("ip:port") that SOCKS5 proxies
+	// are also benchmarked against over UDP ASSOCIATE, one query per
+	// sample. TCP latency says little about how a relay handles UDP.
+	UDPTarget string
+
+	// ComparePaths also times HTTP and HTTPS proxies along both of their
+	// paths, absolute-URI GET and CONNECT tunnel, to the test URL over
+	// plain http (see Stats.Paths). Many proxies serve one well and the
+	// other badly.
+	ComparePaths bool
+
+	// OnSample, if set, is called after every latency sample (err is nil on
+	// success). It may be called concurrently for different proxies.
+	OnSample func(address string, latency time.Duration, err error)
 }
 
 // DefaultOptions returns sensible benchmark defaults.
@@ -46,39 +286,180 @@ func DefaultOptions() Options {
 
 // Run executes a benchmark against a single proxy and returns aggregate stats.
 func Run(address string, opts Options) Stats {
-	stats := Stats{Address: address, Samples: opts.Samples}
+	r := newRunner(address, opts)
+	for i := 0; i < r.opts.Samples && r.ready(); i++ {
+		r.sample(i)
+	}
+	return r.finish()
+}
+
+// runner benchmarks one proxy a sample at a time, so samples can be run
+// back to back (Run) or spread across a run (Options.Interleave). Its
+// methods must not be called concurrently.
+type runner struct {
+	opts    Options
+	ctx     context.Context    // set by begin, like span and cancel
+	span    trace.Span         // nil until begin
+	cancel  context.CancelFunc // ends the OverallBudget
+	attrs   []attribute.KeyValue
+	err     error        // why the proxy address is unusable
+	client  *http.Client // nil if the proxy address is unusable
+	testURL string
+	stats   Stats
+
+	latencies  []int64
+	cold, warm []int64
+	warmedUp   bool // WarmPath: the warm-up request has been made
+}
+
+func newRunner(address string, opts Options) *runner {
+	if opts.Percentiles == "" {
+		opts.Percentiles = Nearest
+	}
+	r := &runner{stats: Stats{Address: address, Samples: opts.Samples, PercentileMethod: opts.Percentiles}}
 	if opts.Samples <= 0 {
 		opts.Samples = 5
 	}
+	if opts.WarmPath {
+		opts.ReuseConnections = true
+	}
+	r.opts = opts
+	r.attrs = []attribute.KeyValue{attribute.String("proxy.address", checker.Redact(address))}
 
-	client, err := buildClient(address, opts.Timeout)
+	client, err := buildClient(address, opts)
 	if err != nil {
-		return stats
+		r.err = err
+		return r
+	}
+	if opts.TimeoutFor != nil {
+		if d, ok := opts.TimeoutFor(address); ok {
+			client.Timeout = d
+			r.attrs = append(r.attrs, attribute.Int64("bench.timeout_ms", d.Milliseconds()))
+		}
 	}
+	r.client = client
 
-	testURL := opts.TestURL
-	if testURL == "" {
-		testURL = "http://www.google.com"
+	r.stats.TLSPolicy = checker.TLSPolicy(opts.RootCAs, opts.InsecureTLS)
+	r.testURL = opts.TestURL
+	if opts.TestURLFor != nil {
+		if u := opts.TestURLFor(address); u != "" {
+			r.testURL, r.stats.TestURL = u, u
+			r.attrs = append(r.attrs, attribute.String("bench.test_url", u))
+		}
+	}
+	if r.testURL == "" {
+		r.testURL = "http://www.google.com"
 	}
+	r.latencies = make([]int64, 0, opts.Samples)
+	return r
+}
 
-	latencies := make([]int64, 0, opts.Samples)
+// ready reports whether the proxy address could be used, so samples can
+// be taken.
+func (r *runner) ready() bool {
+	return r.client != nil
+}
 
-	for i := 0; i < opts.Samples; i++ {
-		start := time.Now()
-		resp, err := client.Get(testURL)
-		elapsed := time.Since(start).Milliseconds()
-		if err != nil {
-			continue
+// begin starts the OverallBudget and the proxy's span. It runs at the
+// first sample rather than in newRunner, since Interleave opens runners
+// well before their turn comes.
+func (r *runner) begin() {
+	if r.span != nil {
+		return
+	}
+	ctx, cancel := context.Background(), context.CancelFunc(func() {})
+	if r.opts.Context != nil {
+		ctx = r.opts.Context
+	}
+	if r.opts.OverallBudget > 0 {
+		ctx, cancel = context.WithTimeout(ctx, r.opts.OverallBudget)
+	}
+	r.cancel = cancel
+	r.ctx, r.span = tracing.Start(ctx, "bench", trace.WithAttributes(r.attrs...))
+	if r.err != nil {
+		tracing.Fail(r.span, r.err)
+	}
+}
+
+// sample takes latency sample i.
+func (r *runner) sample(i int) {
+	r.begin()
+	if r.opts.WarmPath && !r.warmedUp {
+		r.warmUp()
+	}
+	start := time.Now()
+	resp, reused, err := sample(r.ctx, r.client, r.opts.Request, r.testURL, i)
+	took := time.Since(start)
+	elapsed := took.Milliseconds()
+	if r.opts.OnSample != nil {
+		r.opts.OnSample(r.stats.Address, took, err)
+	}
+	if err != nil {
+		return
+	}
+	io.Copy(io.Discard, resp.Body) //nolint:errcheck
+	resp.Body.Close()
+	r.stats.countStatus(resp.StatusCode)
+	r.latencies = append(r.latencies, elapsed)
+	r.stats.Successful++
+	if reused {
+		r.warm = append(r.warm, elapsed)
+	} else {
+		if r.opts.ReuseConnections && len(r.cold)+len(r.warm) > 0 {
+			r.stats.Reconnects++
 		}
-		io.Copy(io.Discard, resp.Body) //nolint:errcheck
-		resp.Body.Close()
-		latencies = append(latencies, elapsed)
-		stats.Successful++
+		r.cold = append(r.cold, elapsed)
 	}
+}
+
+// warmUp makes the unmeasured request that opens the connection for
+// WarmPath samples, recording its latency as the cold one.
+func (r *runner) warmUp() {
+	r.warmedUp = true
+	start := time.Now()
+	resp, _, err := sample(r.ctx, r.client, r.opts.Request, r.testURL, -1)
+	if err != nil {
+		return // the first sample will connect instead
+	}
+	io.Copy(io.Discard, resp.Body) //nolint:errcheck
+	resp.Body.Close()
+	r.cold = append(r.cold, time.Since(start).Milliseconds())
+}
+
+// finish computes the stats, runs the optional throughput tests and ends
+// the proxy's span.
+func (r *runner) finish() Stats {
+	r.begin()
+	stats, opts := &r.stats, r.opts
+	defer func() {
+		r.span.SetAttributes(
+			attribute.Int("bench.samples", opts.Samples),
+			attribute.Int("bench.successful", stats.Successful),
+			attribute.Int64("bench.p50_ms", stats.P50MS),
+		)
+		r.span.End()
+		r.cancel()
+		if r.client != nil {
+			r.client.CloseIdleConnections()
+		}
+	}()
 
+	if opts.UDPTarget != "" {
+		measureUDP(r.ctx, stats.Address, opts, stats)
+	}
+	if opts.ComparePaths {
+		measurePaths(r.ctx, stats.Address, r.testURL, opts, stats)
+	}
+
+	latencies := r.latencies
 	if len(latencies) == 0 {
 		stats.LossRate = 1.0
-		return stats
+		return *stats
+	}
+	stats.LossRate = float64(opts.Samples-stats.Successful) / float64(opts.Samples)
+	if stats.Successful < opts.MinSuccessful {
+		stats.Error = fmt.Sprintf("only %d of %d samples succeeded (need %d)", stats.Successful, opts.Samples, opts.MinSuccessful)
+		return *stats
 	}
 
 	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
@@ -86,91 +467,274 @@ func Run(address string, opts Options) Stats {
 	stats.MinMS = latencies[0]
 	stats.MaxMS = latencies[len(latencies)-1]
 	stats.AvgMS = avg(latencies)
-	stats.P50MS = percentile(latencies, 50)
-	stats.P95MS = percentile(latencies, 95)
-	stats.LossRate = float64(opts.Samples-stats.Successful) / float64(opts.Samples)
+	stats.P50MS = opts.Percentiles.of(latencies, 50)
+	stats.P95MS = opts.Percentiles.of(latencies, 95)
+	if opts.ReuseConnections {
+		stats.ColdMS = avg(r.cold)
+		stats.WarmMS = avg(r.warm)
+	}
 
 	// Optional throughput measurement.
-	if opts.PayloadURL != "" {
-		stats.SpeedBps = measureSpeed(client, opts.PayloadURL, opts.Timeout)
+	if opts.PayloadURL != "" && r.client != nil {
+		r.client.Timeout = opts.Timeout + opts.Sustain
+		tp := measureSpeed(r.ctx, r.client, opts.PayloadURL, opts.Sustain)
+		stats.SpeedBps = tp.bps
+		stats.SpeedSeries = tp.series
+		stats.PeakBps, stats.RampUpMS = peak(tp.series)
+		if opts.Sustain > 0 {
+			stats.InitialBps, stats.SustainedBps, stats.Throttled = throttling(tp.series, opts.ThrottleAfter, opts.ThrottleDrop)
+		}
+		if opts.Ceiling {
+			stats.CapacityBps, stats.SaturationConns = measureCeiling(r.ctx, r.client, opts.PayloadURL, opts.CeilingMax, opts.CeilingWindow)
+		}
 	}
 
-	return stats
+	if opts.Churn {
+		fresh := opts
+		fresh.ReuseConnections = false
+		if client, err := buildClient(stats.Address, fresh); err == nil {
+			c := measureChurn(r.ctx, client, r.testURL, opts.ChurnMax, opts.ChurnWindow)
+			stats.HandshakeRate, stats.HandshakeConns, stats.ErrorOnsetConns = c.rate, c.conns, c.errorConns
+		}
+	}
+
+	if opts.ConnLimit && r.client != nil {
+		stats.ConnLimit, stats.ConnLimitHit = measureConnLimit(r.ctx, stats.Address, r.testURL, opts.ConnLimitMax, opts)
+	}
+
+	return *stats
+}
+
+// OK reports whether the proxy produced stats: at least one sample (and
+// Options.MinSuccessful) succeeded.
+func (s Stats) OK() bool {
+	return s.Successful > 0 && s.Error == ""
 }
 
-// RunMany benchmarks multiple proxies concurrently.
+// RunMany benchmarks multiple proxies concurrently and returns stats in input order.
 func RunMany(addresses []string, opts Options) []Stats {
+	results := make([]Stats, 0, len(addresses))
+	RunStream(pool.FromSlice(addresses), opts, func(s Stats) {
+		results = append(results, s)
+	})
+	return results
+}
+
+// RunStream benchmarks every address received from in on opts.Concurrency
+// workers and passes each result to emit in input order (or as each
+// finishes, with opts.AnyOrder), holding only a bounded window of proxies
+// in memory.
+func RunStream(in <-chan string, opts Options, emit func(Stats)) {
 	if opts.Concurrency <= 0 {
 		opts.Concurrency = 5
 	}
-	sem := make(chan struct{}, opts.Concurrency)
-	results := make([]Stats, len(addresses))
-	done := make(chan struct{}, len(addresses))
-
-	for i, addr := range addresses {
-		go func(idx int, address string) {
-			sem <- struct{}{}
-			results[idx] = Run(address, opts)
-			<-sem
-			done <- struct{}{}
-		}(i, addr)
+	if opts.Interleave {
+		runInterleaved(in, opts, emit)
+		return
 	}
-	for range addresses {
-		<-done
+	run := pool.Ordered[string, Stats]
+	if opts.AnyOrder {
+		run = pool.Unordered[string, Stats]
 	}
-	return results
+	run(in, opts.Concurrency, func(address string) Stats {
+		return Run(address, opts)
+	}, emit)
+}
+
+// sample performs one traced latency request and reports whether it was
+// served over a reused connection; the caller drains the body.
+func sample(ctx context.Context, client *http.Client, rq checker.Request, testURL string, i int) (*http.Response, bool, error) {
+	ctx, span := tracing.Start(ctx, "bench.sample", trace.WithAttributes(attribute.Int("bench.sample", i)))
+	var reused bool
+	ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) { reused = info.Reused },
+	})
+	req, err := rq.New(ctx, testURL)
+	if err != nil {
+		tracing.End(span, err)
+		return nil, false, err
+	}
+	resp, err := client.Do(tracing.WithClientTrace(req))
+	span.SetAttributes(attribute.Bool("net.conn.reused", reused))
+	tracing.End(span, err)
+	return resp, reused, err
 }
 
 // buildClient returns an http.Client routed through the proxy at address.
-func buildClient(address string, timeout time.Duration) (*http.Client, error) {
+// Unless opts.ReuseConnections is set, every request opens a fresh connection.
+func buildClient(address string, opts Options) (*http.Client, error) {
+	if checker.DetectProtocol(address) == checker.ProtocolHysteria2 {
+		return buildHysteria2Client(address, opts)
+	}
 	u, err := url.Parse(address)
 	if err != nil {
 		return nil, fmt.Errorf("parse proxy URL: %w", err)
 	}
+	if u.User == nil && opts.Credentials != nil {
+		u.User = opts.Credentials(u.Host)
+	}
+	override, err := checker.OverrideFor(u, opts.Overrides)
+	if err != nil {
+		return nil, fmt.Errorf("proxy override: %w", err)
+	}
 
 	var transport *http.Transport
+	reuse := opts.ReuseConnections
+	forward := checker.ConnectDialer{
+		Forward: proxyproto.Dialer{Forward: resolver.Default(), Version: opts.ProxyProtocol},
+		Timeout: opts.connectTimeout(),
+	}
 
 	switch u.Scheme {
-	case "socks5":
-		dialer, err := proxy.FromURL(u, proxy.Direct)
+	case "socks5", "socks5h":
+		if opts.H3 {
+			host := u.Host
+			if u.Port() == "" {
+				host += ":1080"
+			}
+			return &http.Client{
+				Transport: checker.H3Transport(host, u.User, checker.ClientTLS(opts.RootCAs, opts.InsecureTLS), reuse),
+				Timeout:   opts.requestTimeout(),
+				CheckRedirect: func(*http.Request, []*http.Request) error {
+					return http.ErrUseLastResponse
+				},
+			}, nil
+		}
+		dialer, err := checker.SOCKS5Dialer(u, forward)
 		if err != nil {
 			return nil, fmt.Errorf("socks5 dialer: %w", err)
 		}
-		transport = &http.Transport{Dial: dialer.Dial, DisableKeepAlives: true}
+		transport = &http.Transport{DialContext: checker.HandshakeDialer{Dialer: dialer, Timeout: opts.handshakeTimeout()}.DialContext, DisableKeepAlives: !reuse, TLSClientConfig: checker.ClientTLS(opts.RootCAs, opts.InsecureTLS)}
+	case "ssh":
+		dialer, err := checker.SSHDialer(u, opts.SSHKey, opts.SSHHostKeys, forward)
+		if err != nil {
+			return nil, fmt.Errorf("ssh dialer: %w", err)
+		}
+		transport = &http.Transport{DialContext: checker.HandshakeDialer{Dialer: dialer, Timeout: opts.handshakeTimeout()}.DialContext, DisableKeepAlives: !reuse, TLSClientConfig: checker.ClientTLS(opts.RootCAs, opts.InsecureTLS)}
 	default:
 		// http / https proxy
 		transport = &http.Transport{
-			Proxy:             http.ProxyURL(u),
-			DisableKeepAlives: true,
+			Proxy:               http.ProxyURL(u),
+			DialContext:         forward.DialContext,
+			TLSHandshakeTimeout: opts.handshakeTimeout(),
+			TLSClientConfig:     checker.ClientTLS(opts.RootCAs, opts.InsecureTLS),
+			DisableKeepAlives:   !reuse,
 		}
+		checker.ProxyTLS{Config: checker.ClientTLS(opts.RootCAs, opts.InsecureTLS || opts.ProxyInsecure), ServerName: override.SNI, Fingerprint: opts.TLSFingerprint}.Apply(transport, u)
+		override.SNI = "" // applied with the proxy's TLS
 	}
+	checker.ClientHello{Fingerprint: opts.TLSFingerprint}.Apply(transport, u)
 
 	return &http.Client{
-		Transport: transport,
-		Timeout:   timeout,
+		Transport: override.Apply(transport, u),
+		Timeout:   opts.requestTimeout(),
 		CheckRedirect: func(*http.Request, []*http.Request) error {
 			return http.ErrUseLastResponse
 		},
 	}, nil
 }
 
-// measureSpeed downloads a URL through the client and returns bytes/sec.
-func measureSpeed(client *http.Client, payloadURL string, timeout time.Duration) int64 {
-	resp, err := client.Get(payloadURL)
-	if err != nil {
-		return 0
+// connectTimeout, handshakeTimeout and requestTimeout return the limit
+// for each phase of a latency sample.
+func (o Options) connectTimeout() time.Duration   { return cmp.Or(o.ConnectTimeout, o.Timeout) }
+func (o Options) handshakeTimeout() time.Duration { return cmp.Or(o.HandshakeTimeout, o.Timeout) }
+func (o Options) requestTimeout() time.Duration   { return cmp.Or(o.RequestTimeout, o.Timeout) }
+
+// speedInterval is the width of one SpeedSeries bucket (a var for tests).
+var speedInterval = time.Second
+
+// throughput is the outcome of measureSpeed.
+type throughput struct {
+	bps    int64   // average over the whole download
+	series []int64 // bytes/sec per speedInterval
+}
+
+// measureSpeed downloads a URL through the client and returns the average
+// bytes/sec along with the rate in each speedInterval. A trailing partial
+// interval is scaled up when it lasted at least a tenth of an interval and
+// dropped otherwise, as a few stray bytes would skew it. With sustain set,
+// the download runs for exactly that long instead, fetching the URL again
+// whenever it ends early.
+func measureSpeed(ctx context.Context, client *http.Client, payloadURL string, sustain time.Duration) throughput {
+	ctx, span := tracing.Start(ctx, "bench.throughput")
+	defer span.End()
+
+	var tp throughput
+	var total, bucket int64
+	var start, next, now time.Time
+	buf := make([]byte, 32<<10)
+	for done := false; !done; {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, payloadURL, nil)
+		if err != nil {
+			tracing.Fail(span, err)
+			return throughput{}
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			if start.IsZero() {
+				tracing.Fail(span, err)
+				return throughput{}
+			}
+			break
+		}
+		if start.IsZero() {
+			start = time.Now()
+			next = start.Add(speedInterval)
+		}
+		for {
+			n, err := resp.Body.Read(buf)
+			now = time.Now()
+			for !now.Before(next) {
+				tp.series = append(tp.series, int64(float64(bucket)/speedInterval.Seconds()))
+				bucket = 0
+				next = next.Add(speedInterval)
+			}
+			total += int64(n)
+			bucket += int64(n)
+			if sustain > 0 && now.Sub(start) >= sustain {
+				done = true
+				break
+			}
+			if err != nil {
+				done = sustain <= 0 || ctx.Err() != nil
+				break
+			}
+		}
+		resp.Body.Close()
 	}
-	defer resp.Body.Close()
-	start := time.Now()
-	n, _ := io.Copy(io.Discard, resp.Body)
-	elapsed := time.Since(start).Seconds()
-	if elapsed == 0 {
-		return 0
+	if rest := speedInterval - next.Sub(now); rest >= speedInterval/10 {
+		tp.series = append(tp.series, int64(float64(bucket)/rest.Seconds()))
+	}
+	elapsed := now.Sub(start).Seconds()
+	if elapsed <= 0 {
+		return throughput{}
 	}
-	return int64(float64(n) / elapsed)
+	tp.bps = int64(float64(total) / elapsed)
+	span.SetAttributes(attribute.Int64("bench.speed_bps", tp.bps))
+	return tp
+}
+
+// peak returns the highest rate in series and the time, in ms from the
+// start of the download, at which the rate first reached 90% of it.
+func peak(series []int64) (peakBps, rampUpMS int64) {
+	for _, v := range series {
+		peakBps = max(peakBps, v)
+	}
+	if peakBps == 0 {
+		return 0, 0
+	}
+	for i, v := range series {
+		if v*10 >= peakBps*9 {
+			return peakBps, int64(i+1) * speedInterval.Milliseconds()
+		}
+	}
+	return peakBps, 0
 }
 
 func avg(vals []int64) int64 {
+	if len(vals) == 0 {
+		return 0
+	}
 	var sum int64
 	for _, v := range vals {
 		sum += v
@@ -1,7 +1,15 @@
 package bench
 
 import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/drsoft-oss/proxybench/internal/checker"
 )
 
 func TestAvg(t *testing.T) {
@@ -12,6 +20,7 @@ func TestAvg(t *testing.T) {
 		{[]int64{10, 20, 30}, 20},
 		{[]int64{100}, 100},
 		{[]int64{1, 2, 3, 4, 5}, 3},
+		{nil, 0},
 	}
 	for _, c := range cases {
 		got := avg(c.vals)
@@ -47,6 +56,36 @@ func TestPercentile_empty(t *testing.T) {
 	}
 }
 
+func TestPercentileMethod(t *testing.T) {
+	five := []int64{100, 110, 120, 130, 400}
+	cases := []struct {
+		m      PercentileMethod
+		sorted []int64
+		p      int
+		want   int64
+	}{
+		{Nearest, five, 95, 400},
+		{Linear, five, 95, 346}, // 130 + 0.8 × 270
+		{Linear, five, 50, 120},
+		{Linear, []int64{10, 20}, 50, 15},
+		{Linear, five, 100, 400},
+		{Linear, []int64{7}, 95, 7},
+		{Linear, nil, 50, 0},
+	}
+	for _, c := range cases {
+		if got := c.m.of(c.sorted, c.p); got != c.want {
+			t.Errorf("%s p%d of %v = %d, want %d", c.m, c.p, c.sorted, got, c.want)
+		}
+	}
+
+	if m, err := ParsePercentileMethod(""); err != nil || m != Nearest {
+		t.Errorf(`ParsePercentileMethod("") = %q, %v`, m, err)
+	}
+	if _, err := ParsePercentileMethod("median"); err == nil {
+		t.Error("ParsePercentileMethod accepted an unknown method")
+	}
+}
+
 func TestRunMany_emptyInput(t *testing.T) {
 	results := RunMany(nil, DefaultOptions())
 	if len(results) != 0 {
@@ -71,3 +110,280 @@ func TestRun_zeroSamples(t *testing.T) {
 		t.Errorf("address not preserved")
 	}
 }
+
+func TestRun_onSample(t *testing.T) {
+	// The test server plays the HTTP proxy: it answers every forwarded request.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	var calls, failures int
+	var mu sync.Mutex
+	opts := DefaultOptions()
+	opts.Samples = 3
+	opts.OnSample = func(address string, latency time.Duration, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if err != nil {
+			failures++
+		}
+	}
+	stats := Run(srv.URL, opts)
+	if calls != 3 || failures != 0 {
+		t.Errorf("OnSample calls/failures = %d/%d, want 3/0", calls, failures)
+	}
+	if stats.Successful != 3 {
+		t.Errorf("successful = %d, want 3", stats.Successful)
+	}
+}
+
+func TestRun_reuseConnections(t *testing.T) {
+	var mu sync.Mutex
+	conns := 0
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.Config.ConnState = func(_ net.Conn, s http.ConnState) {
+		if s == http.StateNew {
+			mu.Lock()
+			conns++
+			mu.Unlock()
+		}
+	}
+	srv.Start()
+	defer srv.Close()
+
+	opts := DefaultOptions()
+	opts.Samples = 4
+	opts.ReuseConnections = true
+	stats := Run(srv.URL, opts)
+	if stats.Successful != 4 {
+		t.Fatalf("successful = %d, want 4", stats.Successful)
+	}
+	mu.Lock()
+	if conns != 1 {
+		t.Errorf("opened %d connections, want 1 with reuse", conns)
+	}
+	mu.Unlock()
+
+	stats = Run(srv.URL, DefaultOptions())
+	if stats.ColdMS != 0 || stats.WarmMS != 0 {
+		t.Errorf("cold/warm should be unset without reuse, got %d/%d", stats.ColdMS, stats.WarmMS)
+	}
+}
+
+func TestPeak(t *testing.T) {
+	defer func(d time.Duration) { speedInterval = d }(speedInterval)
+	speedInterval = time.Second
+
+	cases := []struct {
+		series     []int64
+		peak, ramp int64
+	}{
+		{nil, 0, 0},
+		{[]int64{0, 0}, 0, 0},
+		{[]int64{100, 500, 1000, 950, 200}, 1000, 3000},
+		{[]int64{900, 1000, 200}, 1000, 1000}, // 90% of peak counts as ramped up
+	}
+	for _, tc := range cases {
+		gotPeak, gotRamp := peak(tc.series)
+		if gotPeak != tc.peak || gotRamp != tc.ramp {
+			t.Errorf("peak(%v) = %d, %d; want %d, %d", tc.series, gotPeak, gotRamp, tc.peak, tc.ramp)
+		}
+	}
+}
+
+func TestMeasureSpeed_series(t *testing.T) {
+	defer func(d time.Duration) { speedInterval = d }(speedInterval)
+	speedInterval = 50 * time.Millisecond
+
+	// Fast for two intervals, then throttled to a trickle.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		chunk := make([]byte, 64<<10)
+		flush := w.(http.Flusher)
+		for i := 0; i < 4; i++ {
+			w.Write(chunk) //nolint:errcheck
+			flush.Flush()
+			time.Sleep(25 * time.Millisecond)
+		}
+		for i := 0; i < 4; i++ {
+			w.Write(chunk[:1<<10]) //nolint:errcheck
+			flush.Flush()
+			time.Sleep(50 * time.Millisecond)
+		}
+	}))
+	defer srv.Close()
+
+	tp := measureSpeed(t.Context(), srv.Client(), srv.URL, 0)
+	if tp.bps <= 0 || len(tp.series) < 4 {
+		t.Fatalf("bps=%d series=%v", tp.bps, tp.series)
+	}
+	peakBps, ramp := peak(tp.series)
+	if last := tp.series[len(tp.series)-1]; last*10 > peakBps {
+		t.Errorf("throttled tail %d B/s not well below peak %d B/s (series %v)", last, peakBps, tp.series)
+	}
+	if ramp <= 0 || ramp > 4*speedInterval.Milliseconds() {
+		t.Errorf("ramp-up = %dms, want within the fast phase (series %v)", ramp, tp.series)
+	}
+}
+
+func TestRun_phaseTimeouts(t *testing.T) {
+	// A proxy that accepts connections and never answers.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	opts := DefaultOptions()
+	opts.Timeout = 30 * time.Second
+	opts.Samples = 3
+	opts.TestURL = "http://example.invalid/"
+	for _, scheme := range []string{"http", "socks5"} {
+		o := opts
+		if scheme == "http" {
+			o.RequestTimeout = 100 * time.Millisecond
+		} else {
+			o.HandshakeTimeout = 100 * time.Millisecond
+		}
+		start := time.Now()
+		if stats := Run(scheme+"://"+ln.Addr().String(), o); stats.Successful != 0 {
+			t.Fatalf("%s: %d samples succeeded through a silent proxy", scheme, stats.Successful)
+		}
+		if d := time.Since(start); d > 5*time.Second {
+			t.Errorf("%s: Run took %s", scheme, d)
+		}
+	}
+
+	opts.OverallBudget = 200 * time.Millisecond
+	start := time.Now()
+	Run("http://"+ln.Addr().String(), opts)
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("Run took %s with a 200ms budget", d)
+	}
+}
+
+func TestRun_minSuccessful(t *testing.T) {
+	// Only the first request gets an answer; later connections are dropped.
+	var mu sync.Mutex
+	served := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		served++
+		first := served == 1
+		mu.Unlock()
+		if !first {
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+		}
+	}))
+	defer srv.Close()
+
+	opts := DefaultOptions()
+	opts.Samples = 4
+	opts.MinSuccessful = 2
+	stats := Run(srv.URL, opts)
+	if stats.Successful != 1 || stats.OK() {
+		t.Fatalf("successful=%d ok=%v, want 1 and not OK", stats.Successful, stats.OK())
+	}
+	if stats.Error == "" || stats.P50MS != 0 || stats.AvgMS != 0 {
+		t.Errorf("stats of a failed proxy: error=%q p50=%d avg=%d", stats.Error, stats.P50MS, stats.AvgMS)
+	}
+	if stats.LossRate != 0.75 {
+		t.Errorf("loss rate = %v, want 0.75", stats.LossRate)
+	}
+}
+
+func TestRun_warmPath(t *testing.T) {
+	var mu sync.Mutex
+	conns, requests := 0, 0
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+	}))
+	srv.Config.ConnState = func(_ net.Conn, s http.ConnState) {
+		if s == http.StateNew {
+			mu.Lock()
+			conns++
+			mu.Unlock()
+		}
+	}
+	srv.Start()
+	defer srv.Close()
+
+	var measured int
+	opts := DefaultOptions()
+	opts.Samples = 3
+	opts.WarmPath = true
+	opts.OnSample = func(string, time.Duration, error) { measured++ }
+	stats := Run(srv.URL, opts)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if conns != 1 || requests != 4 {
+		t.Errorf("connections/requests = %d/%d, want 1/4 (warm-up + 3 samples)", conns, requests)
+	}
+	if measured != 3 || stats.Successful != 3 {
+		t.Errorf("measured %d samples, %d successful; the warm-up must not count", measured, stats.Successful)
+	}
+	if stats.Reconnects != 0 {
+		t.Errorf("reconnects = %d, want 0", stats.Reconnects)
+	}
+}
+
+func TestRun_request(t *testing.T) {
+	var mu sync.Mutex
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		got = append(got, r.Method+" "+r.Header.Get("Content-Type")+" "+string(body))
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	opts := DefaultOptions()
+	opts.Samples = 2
+	opts.Request = checker.Request{Body: []byte(`{"q":1}`), ContentType: "application/json"}
+	if s := Run(srv.URL, opts); s.Successful != 2 {
+		t.Fatalf("successful = %d, want 2", s.Successful)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for _, g := range got {
+		if g != `POST application/json {"q":1}` {
+			t.Errorf("sample request = %q", g)
+		}
+	}
+}
+
+func TestRun_override(t *testing.T) {
+	// An https proxy behind a front that routes on SNI and Host.
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS.ServerName != "cdn.example" || r.Host != "app.example" {
+			w.WriteHeader(http.StatusMisdirectedRequest)
+		}
+	}))
+	srv.StartTLS()
+	defer srv.Close()
+	proxy := "https://" + srv.Listener.Addr().String()
+
+	opts := DefaultOptions()
+	opts.Samples = 2
+	opts.InsecureTLS = true
+	if stats := Run(proxy, opts); stats.StatusCodes[http.StatusMisdirectedRequest] != 2 {
+		t.Errorf("no override: status codes = %v", stats.StatusCodes)
+	}
+	opts.Overrides = func(string) checker.Override { return checker.Override{SNI: "cdn.example", Host: "app.example"} }
+	if stats := Run(proxy, opts); stats.StatusCodes[http.StatusOK] != 2 {
+		t.Errorf("override: status codes = %v", stats.StatusCodes)
+	}
+}
@@ -0,0 +1,116 @@
+package bench
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/drsoft-oss/proxybench/internal/tracing"
+)
+
+// Defaults for the throughput ceiling test.
+const (
+	DefaultCeilingMax    = 16
+	DefaultCeilingWindow = 3 * time.Second
+)
+
+// ceilingGain is the smallest improvement in aggregate throughput that
+// counts as not yet saturated when the number of connections doubles.
+const ceilingGain = 1.10
+
+// measureCeiling downloads payloadURL over 1, 2, 4, ... parallel
+// connections, each level for window, until doubling the connections adds
+// less than 10% aggregate throughput or maxConns is reached. It returns the
+// best aggregate bytes/sec and the last connection count that still raised
+// it meaningfully.
+func measureCeiling(ctx context.Context, client *http.Client, payloadURL string, maxConns int, window time.Duration) (int64, int) {
+	ctx, span := tracing.Start(ctx, "bench.ceiling")
+	defer span.End()
+	if maxConns <= 0 {
+		maxConns = DefaultCeilingMax
+	}
+	if window <= 0 {
+		window = DefaultCeilingWindow
+	}
+
+	var best int64
+	bestConns := 0
+	for conns := 1; conns <= maxConns; conns *= 2 {
+		bps := aggregateRate(ctx, client, payloadURL, conns, window)
+		if bps == 0 && conns == 1 {
+			break // the payload cannot be downloaded at all
+		}
+		if float64(bps) < float64(best)*ceilingGain {
+			// Saturated at the previous level; keep the higher reading.
+			best = max(best, bps)
+			break
+		}
+		best, bestConns = bps, conns
+	}
+	span.SetAttributes(
+		attribute.Int64("bench.capacity_bps", best),
+		attribute.Int("bench.saturation_conns", bestConns),
+	)
+	return best, bestConns
+}
+
+// aggregateRate runs conns downloaders for window, each restarting the
+// download when it completes, and returns the combined bytes/sec.
+func aggregateRate(ctx context.Context, client *http.Client, payloadURL string, conns int, window time.Duration) int64 {
+	ctx, span := tracing.Start(ctx, "bench.ceiling.level", trace.WithAttributes(attribute.Int("bench.conns", conns)))
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, window)
+	defer cancel()
+
+	var total atomic.Int64
+	var wg sync.WaitGroup
+	start := time.Now()
+	for range conns {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				if !download(ctx, client, payloadURL, &total) {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start).Seconds()
+	if elapsed == 0 {
+		return 0
+	}
+	return int64(float64(total.Load()) / elapsed)
+}
+
+// download fetches payloadURL once, adding every byte read to total. It
+// reports false if the request failed before any body was received.
+func download(ctx context.Context, client *http.Client, payloadURL string, total *atomic.Int64) bool {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, payloadURL, nil)
+	if err != nil {
+		return false
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	buf := make([]byte, 32<<10)
+	for {
+		n, err := resp.Body.Read(buf)
+		total.Add(int64(n))
+		if err == io.EOF {
+			return true
+		}
+		if err != nil {
+			return false
+		}
+	}
+}
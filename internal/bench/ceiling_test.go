@@ -0,0 +1,45 @@
+package bench
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMeasureCeiling(t *testing.T) {
+	// Each connection is paced, and at most two are served at a time, so
+	// throughput doubles from one to two connections and then stops.
+	slots := make(chan struct{}, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		slots <- struct{}{}
+		defer func() { <-slots }()
+		chunk := make([]byte, 8<<10)
+		for i := 0; i < 8; i++ {
+			if _, err := w.Write(chunk); err != nil {
+				return
+			}
+			w.(http.Flusher).Flush()
+			time.Sleep(5 * time.Millisecond)
+		}
+	}))
+	defer srv.Close()
+
+	capacity, conns := measureCeiling(t.Context(), srv.Client(), srv.URL, 16, 300*time.Millisecond)
+	if conns != 2 {
+		t.Errorf("saturated at %d connections, want 2 (capacity %d B/s)", conns, capacity)
+	}
+	if capacity <= 0 {
+		t.Errorf("capacity = %d", capacity)
+	}
+}
+
+func TestMeasureCeiling_unreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+
+	if capacity, conns := measureCeiling(t.Context(), http.DefaultClient, url, 4, 50*time.Millisecond); capacity != 0 || conns != 0 {
+		t.Errorf("measureCeiling on a closed server = %d, %d; want 0, 0", capacity, conns)
+	}
+}
@@ -0,0 +1,115 @@
+package bench
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/drsoft-oss/proxybench/internal/tracing"
+)
+
+// Defaults for the connection churn test.
+const (
+	DefaultChurnMax    = 64
+	DefaultChurnWindow = 3 * time.Second
+)
+
+// churnErrorRate is the share of failed attempts at one concurrency level
+// that counts as the onset of errors and ends the ramp.
+const churnErrorRate = 0.05
+
+// churn is the outcome of measureChurn.
+type churn struct {
+	rate       float64 // best successful tunnels/sec
+	conns      int     // concurrency that reached rate
+	errorConns int     // first concurrency with churnErrorRate failures; 0 if none
+}
+
+// measureChurn opens a fresh connection through the proxy for every request
+// to testURL (client must not keep connections alive), with 1, 2, 4, ...
+// attempts in flight for window each, until at least 5% of a level's
+// attempts fail or maxConns is reached. For an https testURL every attempt
+// is a new CONNECT tunnel and TLS handshake.
+func measureChurn(ctx context.Context, client *http.Client, testURL string, maxConns int, window time.Duration) churn {
+	ctx, span := tracing.Start(ctx, "bench.churn")
+	defer span.End()
+	if maxConns <= 0 {
+		maxConns = DefaultChurnMax
+	}
+	if window <= 0 {
+		window = DefaultChurnWindow
+	}
+
+	var c churn
+	for conns := 1; conns <= maxConns; conns *= 2 {
+		rate, failRate := handshakeRate(ctx, client, testURL, conns, window)
+		if rate > c.rate {
+			c.rate, c.conns = rate, conns
+		}
+		if failRate >= churnErrorRate {
+			c.errorConns = conns
+			break
+		}
+	}
+	span.SetAttributes(
+		attribute.Float64("bench.handshake_rate", c.rate),
+		attribute.Int("bench.error_onset_conns", c.errorConns),
+	)
+	return c
+}
+
+// handshakeRate keeps conns requests in flight for window, each on a new
+// connection, and returns the successful ones per second and the share of
+// attempts that failed.
+func handshakeRate(ctx context.Context, client *http.Client, testURL string, conns int, window time.Duration) (float64, float64) {
+	ctx, span := tracing.Start(ctx, "bench.churn.level", trace.WithAttributes(attribute.Int("bench.conns", conns)))
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, window)
+	defer cancel()
+
+	var ok, failed atomic.Int64
+	var wg sync.WaitGroup
+	start := time.Now()
+	for range conns {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				if handshake(ctx, client, testURL) {
+					ok.Add(1)
+				} else if ctx.Err() == nil {
+					failed.Add(1) // attempts cut off by the window end do not count
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start).Seconds()
+	attempts := ok.Load() + failed.Load()
+	if elapsed == 0 || attempts == 0 {
+		return 0, 0
+	}
+	return float64(ok.Load()) / elapsed, float64(failed.Load()) / float64(attempts)
+}
+
+// handshake requests testURL once and reports whether any response came
+// back. The connection is dropped afterwards, so little of the body is read.
+func handshake(ctx context.Context, client *http.Client, testURL string) bool {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, testURL, nil)
+	if err != nil {
+		return false
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10)) //nolint:errcheck
+	resp.Body.Close()
+	return true
+}
@@ -0,0 +1,48 @@
+package bench
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMeasureChurn(t *testing.T) {
+	// At most three requests are served at a time; any more are dropped
+	// without a response, so errors set in at four attempts in flight. The
+	// spare slot absorbs a request cut off by the end of the previous level.
+	slots := make(chan struct{}, 3)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case slots <- struct{}{}:
+			time.Sleep(5 * time.Millisecond)
+			<-slots // before the response is sent, so the client cannot race it
+		default:
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err == nil {
+				conn.Close()
+			}
+		}
+	}))
+	defer srv.Close()
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+
+	c := measureChurn(t.Context(), client, srv.URL, 64, 200*time.Millisecond)
+	if c.errorConns != 4 {
+		t.Errorf("errors set in at %d attempts, want 4", c.errorConns)
+	}
+	if c.rate <= 0 || c.conns == 0 {
+		t.Errorf("rate = %.1f/s at %d attempts", c.rate, c.conns)
+	}
+}
+
+func TestMeasureChurn_noErrors(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+
+	c := measureChurn(t.Context(), client, srv.URL, 4, 50*time.Millisecond)
+	if c.errorConns != 0 || c.rate <= 0 {
+		t.Errorf("measureChurn = %+v; want a rate and no error onset", c)
+	}
+}
@@ -0,0 +1,123 @@
+package bench
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/drsoft-oss/proxybench/internal/tracing"
+)
+
+// DefaultConnLimitMax is the most simultaneous connections the connection
+// limit test opens through one proxy.
+const DefaultConnLimitMax = 256
+
+// connLimitSettle is how long the connection limit test waits between
+// levels for the proxy to notice the previous level's connections closing
+// (a var for tests).
+var connLimitSettle = 200 * time.Millisecond
+
+// measureConnLimit finds how many connections the proxy keeps open at once:
+// 1, 2, 4, ... connections each carry a request to testURL and stay open
+// until the whole level has been answered, doubling up to maxConns until a
+// level has a failure, after which the gap to the last full level is
+// bisected. It returns the largest level that fully succeeded and whether
+// a failing level was found below maxConns.
+func measureConnLimit(ctx context.Context, address, testURL string, maxConns int, opts Options) (limit int, hit bool) {
+	ctx, span := tracing.Start(ctx, "bench.conn_limit")
+	defer span.End()
+	if maxConns <= 0 {
+		maxConns = DefaultConnLimitMax
+	}
+
+	fail := 0
+	for conns := 1; ; conns *= 2 {
+		conns = min(conns, maxConns)
+		if !holdConns(ctx, address, testURL, conns, opts) {
+			fail = conns
+			break
+		}
+		limit = conns
+		if conns == maxConns {
+			break
+		}
+	}
+	if fail != 0 {
+		for fail-limit > 1 && ctx.Err() == nil {
+			mid := (limit + fail) / 2
+			if holdConns(ctx, address, testURL, mid, opts) {
+				limit = mid
+			} else {
+				fail = mid
+			}
+		}
+		hit = true
+	}
+	span.SetAttributes(
+		attribute.Int("bench.conn_limit", limit),
+		attribute.Bool("bench.conn_limit_hit", hit),
+	)
+	return limit, hit
+}
+
+// holdConns sends conns requests to testURL at once, each over its own new
+// connection through the proxy, and reports whether all were answered
+// while the connections of those already answered stayed open. A 429 or
+// 5xx answer counts as a failure, as that is how many proxies turn away a
+// connection over their limit.
+func holdConns(ctx context.Context, address, testURL string, conns int, opts Options) bool {
+	o := opts
+	o.ReuseConnections = true
+	clients := make([]*http.Client, conns)
+	defer func() {
+		for _, c := range clients {
+			if c != nil {
+				c.CloseIdleConnections()
+			}
+		}
+		time.Sleep(connLimitSettle)
+	}()
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		failed bool
+	)
+	for i := range clients {
+		client, err := buildClient(address, o)
+		if err != nil {
+			return false
+		}
+		clients[i] = client
+	}
+	for i, client := range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, _, err := sample(ctx, client, opts.Request, testURL, i)
+			ok := err == nil
+			if ok {
+				ok = !refused(resp.StatusCode)
+				// Drained so the connection goes back to the client's
+				// pool, where it is held until the level is over.
+				io.Copy(io.Discard, resp.Body) //nolint:errcheck
+				resp.Body.Close()
+			}
+			mu.Lock()
+			failed = failed || !ok
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return !failed && ctx.Err() == nil
+}
+
+// refused reports whether status is one a proxy sends when it is over a
+// limit rather than the target's own answer.
+func refused(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
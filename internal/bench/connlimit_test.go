@@ -0,0 +1,55 @@
+package bench
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeCappedProxy is an HTTP proxy that answers absolute-URI GETs but keeps
+// at most limit connections open, closing any more without a response.
+func fakeCappedProxy(t *testing.T, limit int32) string {
+	t.Helper()
+	var open atomic.Int32
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok")) //nolint:errcheck
+	}))
+	srv.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		switch state {
+		case http.StateNew:
+			if open.Add(1) > limit {
+				conn.Close()
+			}
+		case http.StateClosed, http.StateHijacked:
+			open.Add(-1)
+		}
+	}
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func TestRun_connLimit(t *testing.T) {
+	defer func(d time.Duration) { connLimitSettle = d }(connLimitSettle)
+	connLimitSettle = 50 * time.Millisecond
+
+	opts := DefaultOptions()
+	opts.Samples = 1
+	opts.Timeout = 2 * time.Second
+	opts.TestURL = "http://example.invalid/"
+	opts.ConnLimit = true
+	opts.ConnLimitMax = 16
+
+	proxy := fakeCappedProxy(t, 5)
+	if stats := Run(proxy, opts); stats.ConnLimit != 5 || !stats.ConnLimitHit {
+		t.Errorf("ConnLimit = %d (hit %v), want 5 (hit)", stats.ConnLimit, stats.ConnLimitHit)
+	}
+
+	opts.ConnLimitMax = 4
+	if stats := Run(proxy, opts); stats.ConnLimit != 4 || stats.ConnLimitHit {
+		t.Errorf("ConnLimit = %d (hit %v), want 4 (not hit)", stats.ConnLimit, stats.ConnLimitHit)
+	}
+}
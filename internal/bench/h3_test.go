@@ -0,0 +1,47 @@
+package bench
+
+import (
+	"crypto/x509"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/drsoft-oss/proxybench/internal/testproxy"
+)
+
+func TestRun_h3(t *testing.T) {
+	addr, cert := testproxy.HTTP3(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 3 {
+			http.Error(w, r.Proto, http.StatusHTTPVersionNotSupported)
+			return
+		}
+		if r.URL.Path == "/payload" {
+			w.Write(make([]byte, 256<<10)) //nolint:errcheck
+		}
+	}))
+	opts := DefaultOptions()
+	opts.Samples = 3
+	opts.Timeout = 2 * time.Second
+	opts.TestURL = "https://" + addr + "/"
+	opts.PayloadURL = "https://" + addr + "/payload"
+	opts.RootCAs = x509.NewCertPool()
+	opts.RootCAs.AddCert(cert)
+	opts.H3 = true
+
+	for _, reuse := range []bool{false, true} {
+		opts.ReuseConnections = reuse
+		stats := Run("socks5://"+(&testproxy.SOCKS5{RelayUDP: true}).Start(t), opts)
+		if stats.Successful != 3 || !stats.OK() {
+			t.Fatalf("reuse %v: Successful = %d (%s), want 3", reuse, stats.Successful, stats.Error)
+		}
+		if stats.SpeedBps == 0 {
+			t.Errorf("reuse %v: SpeedBps = 0, want the payload's rate over HTTP/3", reuse)
+		}
+	}
+
+	// An echoing relay carries no QUIC.
+	opts.PayloadURL = ""
+	if stats := Run("socks5://"+(&testproxy.SOCKS5{}).Start(t), opts); stats.Successful != 0 {
+		t.Errorf("echoing relay: Successful = %d, want 0", stats.Successful)
+	}
+}
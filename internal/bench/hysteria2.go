@@ -0,0 +1,34 @@
+package bench
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/drsoft-oss/proxybench/internal/checker"
+	"github.com/drsoft-oss/proxybench/internal/resolver"
+)
+
+// buildHysteria2Client returns an http.Client whose connections are TCP
+// streams through the Hysteria2 server of a hy2:// link, each opened over
+// its own authenticated QUIC connection.
+func buildHysteria2Client(address string, opts Options) (*http.Client, error) {
+	cfg, err := checker.ParseHysteria2URL(address)
+	if err != nil {
+		return nil, fmt.Errorf("parse hy2 link: %w", err)
+	}
+	forward := checker.ConnectDialer{Forward: resolver.Default(), Timeout: opts.connectTimeout()}
+	dialer := checker.Hysteria2Dialer(cfg, opts.RootCAs, opts.ProxyInsecure, forward)
+	transport := &http.Transport{
+		DialContext:       checker.HandshakeDialer{Dialer: dialer, Timeout: opts.handshakeTimeout()}.DialContext,
+		DisableKeepAlives: !opts.ReuseConnections,
+		TLSClientConfig:   checker.ClientTLS(opts.RootCAs, opts.InsecureTLS),
+	}
+	checker.ClientHello{Fingerprint: opts.TLSFingerprint}.Apply(transport, nil)
+	return &http.Client{
+		Transport: transport,
+		Timeout:   opts.requestTimeout(),
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}, nil
+}
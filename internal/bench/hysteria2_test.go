@@ -0,0 +1,42 @@
+package bench
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/drsoft-oss/proxybench/internal/testproxy"
+)
+
+func TestRun_hysteria2(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/payload" {
+			w.Write(make([]byte, 256<<10)) //nolint:errcheck
+		}
+	}))
+	defer target.Close()
+	srv := &testproxy.Hysteria2{Auth: "secret", Obfs: "s3cret"}
+	srv.Start(t)
+
+	opts := DefaultOptions()
+	opts.Samples = 3
+	opts.Timeout = 2 * time.Second
+	opts.TestURL = target.URL
+	opts.PayloadURL = target.URL + "/payload"
+	stats := Run(srv.Link("secret"), opts)
+	if stats.Successful != 3 || !stats.OK() {
+		t.Fatalf("Successful = %d (%s), want 3", stats.Successful, stats.Error)
+	}
+	if stats.SpeedBps == 0 {
+		t.Error("SpeedBps = 0, want the payload's rate through the tunnel")
+	}
+
+	opts.PayloadURL = ""
+	if stats := Run(srv.Link("guess"), opts); stats.Successful != 0 {
+		t.Errorf("wrong auth: Successful = %d, want 0", stats.Successful)
+	}
+	if stats := Run("hy2://auth@127.0.0.1:0", opts); stats.Successful != 0 || stats.LossRate != 1 {
+		t.Errorf("unparseable link: Successful = %d, LossRate = %v; want 0, 1", stats.Successful, stats.LossRate)
+	}
+}
@@ -0,0 +1,130 @@
+package bench
+
+import "sync"
+
+// interleaveWindow is how many proxies per worker runInterleaved keeps
+// open at once; their samples are what gets spread out.
+const interleaveWindow = 4
+
+// job is one proxy in runInterleaved's window. All fields but stats are
+// only touched by the coordinating goroutine.
+type job struct {
+	r         *runner
+	next      int  // next sample to hand out
+	busy      bool // a sample or finish is in flight
+	finishing bool // the in-flight task is finish
+	finished  bool
+	stats     Stats // written by the finish task
+}
+
+// runInterleaved is RunStream with Options.Interleave. It keeps a window
+// of proxies open and hands out their samples one at a time, round-robin,
+// so each proxy's samples are spread over the run and a slow proxy holds a
+// worker for one sample rather than all of them. A proxy never has two
+// samples in flight, and results are still emitted in input order.
+func runInterleaved(in <-chan string, opts Options, emit func(Stats)) {
+	workers := opts.Concurrency
+	tasks := make(chan func())
+	done := make(chan *job)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for task := range tasks {
+				task()
+			}
+		}()
+	}
+
+	var active []*job
+	add := func(address string, ok bool) {
+		if !ok {
+			in = nil
+			return
+		}
+		active = append(active, &job{r: newRunner(address, opts)})
+	}
+	window := interleaveWindow * workers
+
+	cursor, idle := 0, workers
+	for in != nil || len(active) > 0 {
+		// Take in whatever input is ready first, so there are as many
+		// proxies as possible to spread samples over.
+	fill:
+		for in != nil && len(active) < window {
+			select {
+			case address, ok := <-in:
+				add(address, ok)
+			default:
+				break fill
+			}
+		}
+
+		for len(active) > 0 && active[0].finished {
+			emit(active[0].stats)
+			active = active[1:]
+			cursor = max(cursor-1, 0)
+		}
+
+		for idle > 0 {
+			j := nextJob(active, &cursor)
+			if j == nil {
+				break
+			}
+			j.busy = true
+			idle--
+			tasks <- taskFor(j, done)
+		}
+
+		if in == nil && len(active) == 0 {
+			break
+		}
+		var feed <-chan string
+		if in != nil && len(active) < window {
+			feed = in
+		}
+		select {
+		case address, ok := <-feed:
+			add(address, ok)
+		case j := <-done:
+			j.busy = false
+			j.finished = j.finishing
+			idle++
+		}
+	}
+	close(tasks)
+	wg.Wait()
+}
+
+// nextJob returns the first job from cursor onwards (wrapping) with work
+// to hand out and advances cursor past it, or nil if every job is busy or
+// finished.
+func nextJob(active []*job, cursor *int) *job {
+	for k := range active {
+		i := (*cursor + k) % len(active)
+		if j := active[i]; !j.busy && !j.finished {
+			*cursor = (i + 1) % len(active)
+			return j
+		}
+	}
+	return nil
+}
+
+// taskFor returns j's next unit of work: its next sample, or the final
+// stats and throughput tests once every sample has been taken.
+func taskFor(j *job, done chan<- *job) func() {
+	if j.next < j.r.opts.Samples && j.r.ready() {
+		i := j.next
+		j.next++
+		return func() {
+			j.r.sample(i)
+			done <- j
+		}
+	}
+	j.finishing = true
+	return func() {
+		j.stats = j.r.finish()
+		done <- j
+	}
+}
@@ -0,0 +1,87 @@
+package bench
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRunStream_interleave(t *testing.T) {
+	// Each test server plays a proxy and logs which proxy was sampled, so
+	// the order of samples across proxies can be inspected.
+	var mu sync.Mutex
+	var order []string
+	proxy := func(name string, delay time.Duration) string {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			time.Sleep(delay)
+		}))
+		t.Cleanup(srv.Close)
+		return srv.URL
+	}
+	addrs := []string{proxy("a", 0), proxy("b", 0), proxy("c", 0), "http://[::1]:bad"}
+
+	opts := DefaultOptions()
+	opts.Samples = 3
+	opts.Concurrency = 1
+	opts.Interleave = true
+	var got []Stats
+	RunStream(fromSlice(addrs), opts, func(s Stats) { got = append(got, s) })
+
+	if len(got) != len(addrs) {
+		t.Fatalf("got %d results, want %d", len(got), len(addrs))
+	}
+	for i, s := range got {
+		if s.Address != addrs[i] {
+			t.Errorf("result %d is %s, want input order (%s)", i, s.Address, addrs[i])
+		}
+	}
+	for _, s := range got[:3] {
+		if s.Successful != 3 {
+			t.Errorf("%s: successful = %d, want 3", s.Address, s.Successful)
+		}
+	}
+	if got[3].OK() {
+		t.Errorf("unusable address reported OK: %+v", got[3])
+	}
+	// One worker, round-robin: samples alternate between proxies.
+	if seq := strings.Join(order, ""); seq != "abcabcabc" {
+		t.Errorf("sample order = %s, want abcabcabc", seq)
+	}
+}
+
+func TestRunStream_interleaveBudget(t *testing.T) {
+	// One worker takes the window's samples one after another, so the last
+	// proxy's turn comes well after the budget would have run out had it
+	// started when the proxy was opened.
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+	}))
+	t.Cleanup(srv.Close)
+	addrs := []string{srv.URL, srv.URL + "/", srv.URL + "/b", srv.URL + "/c"}
+
+	opts := DefaultOptions()
+	opts.Samples = 1
+	opts.Concurrency = 1
+	opts.Interleave = true
+	opts.OverallBudget = 250 * time.Millisecond
+	RunStream(fromSlice(addrs), opts, func(s Stats) {
+		if s.Successful != 1 {
+			t.Errorf("%s: successful = %d, want 1 within its own budget", s.Address, s.Successful)
+		}
+	})
+}
+
+func fromSlice(s []string) <-chan string {
+	ch := make(chan string, len(s))
+	for _, v := range s {
+		ch <- v
+	}
+	close(ch)
+	return ch
+}
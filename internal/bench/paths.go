@@ -0,0 +1,153 @@
+package bench
+
+import (
+	"cmp"
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/drsoft-oss/proxybench/internal/checker"
+	"github.com/drsoft-oss/proxybench/internal/proxyproto"
+	"github.com/drsoft-oss/proxybench/internal/resolver"
+	"github.com/drsoft-oss/proxybench/internal/tracing"
+)
+
+// PathStats compares the two ways an HTTP proxy carries a plain-http
+// request (see Options.ComparePaths): forwarding an absolute-URI GET, and
+// relaying it through a CONNECT tunnel. Latencies include connecting to
+// the proxy, since every request opens a new connection.
+type PathStats struct {
+	GetP50MS        int64   `json:"get_p50_ms"`
+	GetP95MS        int64   `json:"get_p95_ms"`
+	GetLossRate     float64 `json:"get_loss_rate"`
+	ConnectP50MS    int64   `json:"connect_p50_ms"`
+	ConnectP95MS    int64   `json:"connect_p95_ms"`
+	ConnectLossRate float64 `json:"connect_loss_rate"`
+}
+
+// measurePaths times opts.Samples requests along each path of an HTTP or
+// HTTPS proxy to testURL, fetched over plain http whatever its scheme, so
+// that the only difference is the proxy's. Other protocols are skipped.
+func measurePaths(ctx context.Context, address, testURL string, opts Options, stats *Stats) {
+	switch checker.DetectProtocol(address) {
+	case checker.ProtocolHTTP, checker.ProtocolHTTPS:
+	default:
+		return
+	}
+	target, err := url.Parse(testURL)
+	if err != nil || target.Host == "" {
+		return
+	}
+	target.Scheme = "http"
+	o := opts
+	o.ReuseConnections = false
+	get, err := buildClient(address, o)
+	if err != nil {
+		return
+	}
+	proxyURL, err := url.Parse(address)
+	if err != nil {
+		return
+	}
+	if proxyURL.User == nil && opts.Credentials != nil {
+		proxyURL.User = opts.Credentials(proxyURL.Host)
+	}
+	override, err := checker.OverrideFor(proxyURL, opts.Overrides)
+	if err != nil {
+		return
+	}
+	tunnel := *get
+	tunnel.Transport = checker.Override{Host: override.Host}.Apply(&http.Transport{
+		DialContext:       tunnelDialer{proxy: proxyURL, sni: override.SNI, opts: opts}.DialContext,
+		DisableKeepAlives: true,
+	}, target)
+
+	ctx, span := tracing.Start(ctx, "bench.paths")
+	defer span.End()
+	p := &PathStats{}
+	p.GetP50MS, p.GetP95MS, p.GetLossRate = samplePath(ctx, get, target.String(), opts)
+	p.ConnectP50MS, p.ConnectP95MS, p.ConnectLossRate = samplePath(ctx, &tunnel, target.String(), opts)
+	stats.Paths = p
+	span.SetAttributes(
+		attribute.Int64("bench.get_p50_ms", p.GetP50MS),
+		attribute.Int64("bench.connect_p50_ms", p.ConnectP50MS),
+	)
+}
+
+// samplePath times opts.Samples requests to target with client and returns
+// their P50, P95 and loss rate. Any response counts, as for the latency
+// samples.
+func samplePath(ctx context.Context, client *http.Client, target string, opts Options) (p50, p95 int64, loss float64) {
+	var rtts []int64
+	for i := range opts.Samples {
+		start := time.Now()
+		resp, _, err := sample(ctx, client, opts.Request, target, i)
+		if err != nil {
+			continue
+		}
+		io.Copy(io.Discard, resp.Body) //nolint:errcheck
+		resp.Body.Close()
+		rtts = append(rtts, time.Since(start).Milliseconds())
+	}
+	loss = float64(opts.Samples-len(rtts)) / float64(opts.Samples)
+	if len(rtts) == 0 {
+		return 0, 0, loss
+	}
+	sort.Slice(rtts, func(i, j int) bool { return rtts[i] < rtts[j] })
+	return opts.Percentiles.of(rtts, 50), opts.Percentiles.of(rtts, 95), loss
+}
+
+// tunnelDialer opens a CONNECT tunnel through an HTTP or HTTPS proxy to
+// every address it is asked to dial, greeting an HTTPS proxy with sni
+// when set.
+type tunnelDialer struct {
+	proxy *url.URL
+	sni   string
+	opts  Options
+}
+
+func (d tunnelDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	forward := checker.ConnectDialer{
+		Forward: proxyproto.Dialer{Forward: resolver.Default(), Version: d.opts.ProxyProtocol},
+		Timeout: d.opts.connectTimeout(),
+	}
+	hostPort := d.proxy.Host
+	if d.proxy.Port() == "" {
+		port := "80"
+		if d.proxy.Scheme == "https" {
+			port = "443"
+		}
+		hostPort = net.JoinHostPort(d.proxy.Hostname(), port)
+	}
+	conn, err := forward.DialContext(ctx, network, hostPort)
+	if err != nil {
+		return nil, err
+	}
+	hsCtx, cancel := context.WithTimeout(ctx, d.opts.handshakeTimeout())
+	defer cancel()
+	if d.proxy.Scheme == "https" {
+		cfg := checker.ClientTLS(d.opts.RootCAs, d.opts.InsecureTLS)
+		if cfg == nil {
+			cfg = &tls.Config{}
+		}
+		cfg.ServerName = cmp.Or(d.sni, d.proxy.Hostname())
+		tc, _, err := checker.ClientHandshake(hsCtx, conn, cfg, d.opts.TLSFingerprint)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tc
+	}
+	if _, err := checker.ConnectTunnel(hsCtx, conn, addr, d.proxy.User, nil); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
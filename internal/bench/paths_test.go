@@ -0,0 +1,61 @@
+package bench
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// fakeSlowTunnelProxy is an HTTP proxy that answers absolute-URI GETs at
+// once but opens CONNECT tunnels only after connectDelay, then serves one
+// request inside.
+func fakeSlowTunnelProxy(t *testing.T, connectDelay time.Duration) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			io.WriteString(w, "ok") //nolint:errcheck
+			return
+		}
+		time.Sleep(connectDelay)
+		conn, buf, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n") //nolint:errcheck
+		if _, err := http.ReadRequest(bufio.NewReader(buf)); err != nil {
+			return
+		}
+		io.WriteString(conn, "HTTP/1.1 200 OK\r\nContent-Length: 2\r\nConnection: close\r\n\r\nok") //nolint:errcheck
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func TestRun_comparePaths(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Samples = 3
+	opts.Timeout = 2 * time.Second
+	opts.TestURL = "https://example.invalid/" // compared over plain http
+	opts.ComparePaths = true
+
+	stats := Run(fakeSlowTunnelProxy(t, 100*time.Millisecond), opts)
+	p := stats.Paths
+	if p == nil {
+		t.Fatal("Paths = nil, want both paths measured")
+	}
+	if p.GetLossRate != 0 || p.ConnectLossRate != 0 {
+		t.Errorf("loss = %v GET, %v CONNECT; want none", p.GetLossRate, p.ConnectLossRate)
+	}
+	if p.ConnectP50MS < 100 || p.GetP50MS >= 100 {
+		t.Errorf("P50 = %dms GET, %dms CONNECT; want only CONNECT slowed", p.GetP50MS, p.ConnectP50MS)
+	}
+
+	opts.Samples = 1
+	if stats := Run("socks5://127.0.0.1:1", opts); stats.Paths != nil {
+		t.Errorf("Paths = %+v for a SOCKS5 proxy, want nil", stats.Paths)
+	}
+}
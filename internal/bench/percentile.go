@@ -0,0 +1,48 @@
+package bench
+
+import (
+	"fmt"
+	"math"
+)
+
+// PercentileMethod selects how P50MS and P95MS are computed from the
+// sorted latencies.
+type PercentileMethod string
+
+const (
+	// Nearest picks the sample at the rounded rank p/100 × (n-1). Every
+	// reported value is a real sample, but with few samples it jumps
+	// between them: p95 of five samples is simply the maximum.
+	Nearest PercentileMethod = "nearest"
+	// Linear interpolates between the two samples around that rank, as
+	// numpy and most spreadsheets do.
+	Linear PercentileMethod = "linear"
+)
+
+// ParsePercentileMethod accepts "nearest" or "linear"; "" means Nearest.
+func ParsePercentileMethod(s string) (PercentileMethod, error) {
+	switch m := PercentileMethod(s); m {
+	case "":
+		return Nearest, nil
+	case Nearest, Linear:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown percentile method %q (want nearest or linear)", s)
+}
+
+// of returns the p-th percentile of sorted by method m.
+func (m PercentileMethod) of(sorted []int64, p int) int64 {
+	if m != Linear {
+		return percentile(sorted, p)
+	}
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := float64(p) / 100 * float64(len(sorted)-1)
+	lo := int(rank)
+	if lo >= len(sorted)-1 {
+		return sorted[len(sorted)-1]
+	}
+	frac := rank - float64(lo)
+	return sorted[lo] + int64(math.Round(frac*float64(sorted[lo+1]-sorted[lo])))
+}
@@ -0,0 +1,25 @@
+package bench
+
+import "fmt"
+
+// countStatus records one sample answered with HTTP status code.
+func (s *Stats) countStatus(code int) {
+	if s.StatusCodes == nil {
+		s.StatusCodes = map[int]int{}
+		s.StatusClasses = map[string]int{}
+	}
+	s.StatusCodes[code]++
+	s.StatusClasses[fmt.Sprintf("%dxx", code/100)]++
+}
+
+// TopStatus returns the status code most samples answered with, the lowest
+// on a tie, or 0 when no sample succeeded.
+func (s Stats) TopStatus() int {
+	top, n := 0, 0
+	for code, c := range s.StatusCodes {
+		if c > n || c == n && code < top {
+			top, n = code, c
+		}
+	}
+	return top
+}
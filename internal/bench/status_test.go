@@ -0,0 +1,50 @@
+package bench
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestRun_statusCodes(t *testing.T) {
+	// Answers 200 twice, then rate-limits.
+	var mu sync.Mutex
+	served := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		served++
+		n := served
+		mu.Unlock()
+		if n > 2 {
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer srv.Close()
+
+	opts := DefaultOptions()
+	opts.Samples = 5
+	stats := Run(srv.URL, opts)
+	if stats.Successful != 5 {
+		t.Fatalf("successful = %d, want 5", stats.Successful)
+	}
+	if stats.StatusCodes[200] != 2 || stats.StatusCodes[429] != 3 {
+		t.Errorf("status codes = %v, want 200:2 429:3", stats.StatusCodes)
+	}
+	if stats.StatusClasses["2xx"] != 2 || stats.StatusClasses["4xx"] != 3 {
+		t.Errorf("status classes = %v", stats.StatusClasses)
+	}
+	if got := stats.TopStatus(); got != 429 {
+		t.Errorf("TopStatus = %d, want 429", got)
+	}
+}
+
+func TestTopStatus_tie(t *testing.T) {
+	s := Stats{StatusCodes: map[int]int{503: 2, 200: 2}}
+	if got := s.TopStatus(); got != 200 {
+		t.Errorf("TopStatus = %d, want the lower code 200 on a tie", got)
+	}
+	if got := (Stats{}).TopStatus(); got != 0 {
+		t.Errorf("TopStatus with no samples = %d, want 0", got)
+	}
+}
@@ -0,0 +1,72 @@
+package bench
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/drsoft-oss/proxybench/internal/geo"
+)
+
+// Targets are test URLs by region: a country code ("DE"), a continent
+// (see geo.Continent) or "default". Benching every proxy against a target
+// near it keeps cross-continent round trips out of the comparison.
+type Targets map[string]string
+
+// BuiltinTargets are the AWS EC2 regional health endpoints, which answer
+// a plain "healthy" from a known region: one per continent, plus a few
+// countries far from their continent's.
+var BuiltinTargets = Targets{
+	geo.NorthAmerica: "http://ec2.us-east-1.amazonaws.com/ping",
+	geo.SouthAmerica: "http://ec2.sa-east-1.amazonaws.com/ping",
+	geo.Europe:       "http://ec2.eu-central-1.amazonaws.com/ping",
+	geo.Africa:       "http://ec2.af-south-1.amazonaws.com/ping",
+	geo.Asia:         "http://ec2.ap-southeast-1.amazonaws.com/ping",
+	geo.Oceania:      "http://ec2.ap-southeast-2.amazonaws.com/ping",
+	"jp":             "http://ec2.ap-northeast-1.amazonaws.com/ping",
+	"kr":             "http://ec2.ap-northeast-2.amazonaws.com/ping",
+	"in":             "http://ec2.ap-south-1.amazonaws.com/ping",
+	"ae":             "http://ec2.me-central-1.amazonaws.com/ping",
+}
+
+// ParseTargets reads "region=url" pairs, e.g. "europe=http://eu.example/"
+// or "DE=http://fra.example/".
+func ParseTargets(specs []string) (Targets, error) {
+	t := Targets{}
+	for _, spec := range specs {
+		region, target, ok := strings.Cut(spec, "=")
+		region = strings.ToLower(strings.TrimSpace(region))
+		if !ok || region == "" {
+			return nil, fmt.Errorf("%q: want region=url", spec)
+		}
+		if !validRegion(region) {
+			return nil, fmt.Errorf("%q: region must be a country code, a continent (%s) or default",
+				spec, strings.Join(continentNames, ", "))
+		}
+		if u, err := url.Parse(target); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("%q: want an http(s) URL", spec)
+		}
+		t[region] = target
+	}
+	return t, nil
+}
+
+var continentNames = []string{geo.Africa, geo.Antarctica, geo.Asia, geo.Europe, geo.NorthAmerica, geo.Oceania, geo.SouthAmerica}
+
+func validRegion(region string) bool {
+	return region == "default" || len(region) == 2 && geo.Continent(region) != "" || slices.Contains(continentNames, region)
+}
+
+// Nearest returns the target for a proxy in country (a country code):
+// the country's own, else its continent's, else the default; "" if none
+// applies or country is unknown and there is no default.
+func (t Targets) Nearest(country string) string {
+	if u, ok := t[strings.ToLower(country)]; ok && country != "" {
+		return u
+	}
+	if u, ok := t[geo.Continent(country)]; ok {
+		return u
+	}
+	return t["default"]
+}
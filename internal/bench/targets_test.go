@@ -0,0 +1,58 @@
+package bench
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestTargets_nearest(t *testing.T) {
+	targets, err := ParseTargets([]string{"europe=http://eu.test/", "DE=http://fra.test/", "default=http://any.test/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for country, want := range map[string]string{
+		"DE": "http://fra.test/",
+		"fr": "http://eu.test/",
+		"US": "http://any.test/",
+		"":   "http://any.test/",
+	} {
+		if got := targets.Nearest(country); got != want {
+			t.Errorf("Nearest(%q) = %q, want %q", country, got, want)
+		}
+	}
+	if got := (Targets{"asia": "http://sg.test/"}).Nearest("BR"); got != "" {
+		t.Errorf("Nearest without a match = %q, want \"\"", got)
+	}
+	if got := BuiltinTargets.Nearest("JP"); got != BuiltinTargets["jp"] {
+		t.Errorf("builtin Nearest(JP) = %q", got)
+	}
+}
+
+func TestParseTargets_invalid(t *testing.T) {
+	for _, spec := range []string{"europe", "=http://x.test/", "atlantis=http://x.test/", "QQ=http://x.test/", "europe=ftp://x.test/", "europe=eu.test"} {
+		if _, err := ParseTargets([]string{spec}); err == nil {
+			t.Errorf("ParseTargets(%q) succeeded", spec)
+		}
+	}
+}
+
+func TestRun_testURLFor(t *testing.T) {
+	var wrong atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.String() != "http://near.test/" {
+			wrong.Add(1)
+		}
+	}))
+	defer srv.Close()
+
+	opts := DefaultOptions()
+	opts.Samples = 2
+	opts.TestURL = "http://far.test/"
+	opts.TestURLFor = func(string) string { return "http://near.test/" }
+	s := Run(srv.URL, opts)
+	if s.Successful != 2 || s.TestURL != "http://near.test/" || wrong.Load() != 0 {
+		t.Errorf("successful = %d, test URL = %q, %d samples elsewhere", s.Successful, s.TestURL, wrong.Load())
+	}
+}
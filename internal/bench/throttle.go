@@ -0,0 +1,28 @@
+package bench
+
+import "time"
+
+// Defaults for throttling detection over a sustained download.
+const (
+	DefaultThrottleAfter = 5 * time.Second
+	DefaultThrottleDrop  = 0.3
+)
+
+// throttling splits a speed series after the first after of the download
+// and returns the average bytes/sec on either side, and whether the later
+// one is more than drop (a fraction) below the earlier. A series that does
+// not reach past after yields zeros.
+func throttling(series []int64, after time.Duration, drop float64) (initial, sustained int64, throttled bool) {
+	if after <= 0 {
+		after = DefaultThrottleAfter
+	}
+	if drop <= 0 {
+		drop = DefaultThrottleDrop
+	}
+	n := max(int(after/speedInterval), 1)
+	if len(series) <= n {
+		return 0, 0, false
+	}
+	initial, sustained = avg(series[:n]), avg(series[n:])
+	return initial, sustained, float64(sustained) < float64(initial)*(1-drop)
+}
@@ -0,0 +1,55 @@
+package bench
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestThrottling(t *testing.T) {
+	defer func(d time.Duration) { speedInterval = d }(speedInterval)
+	speedInterval = time.Second
+
+	tests := []struct {
+		name      string
+		series    []int64
+		initial   int64
+		sustained int64
+		throttled bool
+	}{
+		{"steady", []int64{100, 100, 90, 100, 95}, 100, 95, false},
+		{"shaped", []int64{100, 100, 20, 20, 20}, 100, 20, true},
+		{"too short", []int64{100, 100}, 0, 0, false},
+	}
+	for _, tt := range tests {
+		initial, sustained, throttled := throttling(tt.series, 2*time.Second, 0.3)
+		if initial != tt.initial || sustained != tt.sustained || throttled != tt.throttled {
+			t.Errorf("%s: throttling = %d, %d, %v; want %d, %d, %v", tt.name, initial, sustained, throttled, tt.initial, tt.sustained, tt.throttled)
+		}
+	}
+}
+
+func TestMeasureSpeed_sustain(t *testing.T) {
+	defer func(d time.Duration) { speedInterval = d }(speedInterval)
+	speedInterval = 50 * time.Millisecond
+
+	// Each download is short, so a sustained one has to repeat it.
+	var downloads atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads.Add(1)
+		w.Write(make([]byte, 16<<10)) //nolint:errcheck
+		time.Sleep(20 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	start := time.Now()
+	tp := measureSpeed(t.Context(), srv.Client(), srv.URL, 300*time.Millisecond)
+	if d := time.Since(start); d < 300*time.Millisecond || d > 2*time.Second {
+		t.Errorf("sustained download took %s, want about 300ms", d)
+	}
+	if downloads.Load() < 2 || tp.bps <= 0 || len(tp.series) < 5 {
+		t.Errorf("%d downloads, bps=%d, series=%v", downloads.Load(), tp.bps, tp.series)
+	}
+}
@@ -0,0 +1,23 @@
+package bench
+
+import "time"
+
+// Bounds and default factor for AdaptiveTimeout.
+const (
+	DefaultTimeoutFactor = 5
+	MinAdaptiveTimeout   = time.Second
+	MaxAdaptiveTimeout   = time.Minute
+)
+
+// AdaptiveTimeout derives a proxy's sample timeout from its previously
+// observed latency (p95 from bench, or a check's latency): factor times
+// the observation, kept within [MinAdaptiveTimeout, MaxAdaptiveTimeout].
+// Fast proxies then fail fast, and slow ones get more than one global
+// timeout would allow.
+func AdaptiveTimeout(observed time.Duration, factor float64) time.Duration {
+	if factor <= 0 {
+		factor = DefaultTimeoutFactor
+	}
+	d := time.Duration(float64(observed) * factor)
+	return min(max(d, MinAdaptiveTimeout), MaxAdaptiveTimeout)
+}
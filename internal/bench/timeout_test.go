@@ -0,0 +1,48 @@
+package bench
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAdaptiveTimeout(t *testing.T) {
+	cases := []struct {
+		observed time.Duration
+		factor   float64
+		want     time.Duration
+	}{
+		{400 * time.Millisecond, 5, 2 * time.Second},
+		{50 * time.Millisecond, 5, MinAdaptiveTimeout},
+		{30 * time.Second, 5, MaxAdaptiveTimeout},
+		{time.Second, 0, DefaultTimeoutFactor * time.Second},
+	}
+	for _, c := range cases {
+		if got := AdaptiveTimeout(c.observed, c.factor); got != c.want {
+			t.Errorf("AdaptiveTimeout(%v, %v) = %v, want %v", c.observed, c.factor, got, c.want)
+		}
+	}
+}
+
+func TestRun_timeoutFor(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	opts := DefaultOptions()
+	opts.Samples = 2
+	opts.Timeout = 5 * time.Second
+	opts.TimeoutFor = func(address string) (time.Duration, bool) {
+		return 50 * time.Millisecond, address == srv.URL
+	}
+	if s := Run(srv.URL, opts); s.Successful != 0 {
+		t.Errorf("successful = %d under a 50ms override, want 0", s.Successful)
+	}
+
+	opts.TimeoutFor = func(string) (time.Duration, bool) { return 50 * time.Millisecond, false }
+	if s := Run(srv.URL, opts); s.Successful != 2 {
+		t.Errorf("successful = %d without an override, want 2", s.Successful)
+	}
+}
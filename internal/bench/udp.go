@@ -0,0 +1,91 @@
+package bench
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"sort"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/drsoft-oss/proxybench/internal/checker"
+	"github.com/drsoft-oss/proxybench/internal/tracing"
+)
+
+// measureUDP times opts.Samples DNS queries to opts.UDPTarget sent through
+// the SOCKS5 proxy's UDP relay, one after another over one association.
+// Other protocols are skipped. A proxy that refuses UDP ASSOCIATE gets
+// UDPSupported false; one that could not be asked at all is left unknown.
+func measureUDP(ctx context.Context, address string, opts Options, stats *Stats) {
+	if checker.DetectProtocol(address) != checker.ProtocolSOCKS5 {
+		return
+	}
+	u, err := url.Parse(address)
+	if err != nil || u.Host == "" {
+		return
+	}
+	hostPort := u.Host
+	if _, _, err := net.SplitHostPort(hostPort); err != nil {
+		hostPort = net.JoinHostPort(u.Host, "1080")
+	}
+	user := u.User
+	if user == nil && opts.Credentials != nil {
+		user = opts.Credentials(u.Host)
+	}
+
+	ctx, span := tracing.Start(ctx, "bench.udp", trace.WithAttributes(attribute.String("net.target", opts.UDPTarget)))
+	defer span.End()
+	hsCtx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	relay, err := checker.AssociateUDP(hsCtx, hostPort, user)
+	cancel()
+	if err != nil {
+		if errors.Is(err, checker.ErrUDPUnsupported) {
+			stats.UDPSupported = new(bool)
+		}
+		tracing.Fail(span, err)
+		return
+	}
+	defer relay.Close()
+	supported := true
+	stats.UDPSupported = &supported
+
+	var rtts []int64
+	for i := range opts.Samples {
+		query := dnsQuery(uint16(i + 1))
+		start := time.Now()
+		reply, err := relay.Exchange(opts.UDPTarget, query, start.Add(opts.Timeout))
+		if err != nil || !dnsAnswers(reply, uint16(i+1)) {
+			continue
+		}
+		rtts = append(rtts, time.Since(start).Milliseconds())
+	}
+	stats.UDPLossRate = float64(opts.Samples-len(rtts)) / float64(opts.Samples)
+	if len(rtts) > 0 {
+		sort.Slice(rtts, func(i, j int) bool { return rtts[i] < rtts[j] })
+		stats.UDPP50MS = opts.Percentiles.of(rtts, 50)
+		stats.UDPP95MS = opts.Percentiles.of(rtts, 95)
+	}
+	span.SetAttributes(attribute.Int64("bench.udp_p50_ms", stats.UDPP50MS))
+}
+
+// dnsQuery asks for the root NS records: the smallest query every resolver
+// answers from cache.
+func dnsQuery(id uint16) []byte {
+	msg := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: dnsmessage.MustNewName("."), Type: dnsmessage.TypeNS, Class: dnsmessage.ClassINET}},
+	}
+	b, _ := msg.Pack()
+	return b
+}
+
+// dnsAnswers reports whether reply is a DNS response to query id.
+func dnsAnswers(reply []byte, id uint16) bool {
+	var p dnsmessage.Parser
+	h, err := p.Start(reply)
+	return err == nil && h.Response && h.ID == id
+}
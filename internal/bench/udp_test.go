@@ -0,0 +1,62 @@
+package bench
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/drsoft-oss/proxybench/internal/testproxy"
+)
+
+// fakeSOCKS5UDP grants UDP ASSOCIATE when udp is set and refuses every
+// other command. Its relay answers DNS queries itself, except query ID 2.
+func fakeSOCKS5UDP(t *testing.T, udp bool) string {
+	t.Helper()
+	srv := &testproxy.SOCKS5{
+		Reply: func(r testproxy.Request) byte {
+			if r.Cmd != testproxy.CmdUDPAssociate || !udp {
+				return 0x07 // command not supported
+			}
+			return 0
+		},
+		UDP: func(msg []byte) []byte {
+			if binary.BigEndian.Uint16(msg) == 2 {
+				return nil
+			}
+			msg[2] |= 0x80 // QR: response
+			return msg
+		},
+	}
+	return "socks5://" + srv.Start(t)
+}
+
+func TestRun_udp(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Samples = 4
+	opts.Timeout = 300 * time.Millisecond
+	opts.TestURL = "http://example.invalid/"
+	opts.UDPTarget = "9.9.9.9:53"
+
+	stats := Run(fakeSOCKS5UDP(t, true), opts)
+	if stats.UDPSupported == nil || !*stats.UDPSupported {
+		t.Fatalf("UDPSupported = %v, want true", stats.UDPSupported)
+	}
+	if stats.UDPLossRate != 0.25 {
+		t.Errorf("UDPLossRate = %v, want 0.25 (query 2 unanswered)", stats.UDPLossRate)
+	}
+
+	stats = Run(fakeSOCKS5UDP(t, false), opts)
+	if stats.UDPSupported == nil || *stats.UDPSupported {
+		t.Errorf("UDPSupported = %v, want false", stats.UDPSupported)
+	}
+}
+
+func TestRun_udpSkipsOtherProtocols(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Samples = 1
+	opts.Timeout = 300 * time.Millisecond
+	opts.UDPTarget = "9.9.9.9:53"
+	if stats := Run("http://127.0.0.1:1", opts); stats.UDPSupported != nil {
+		t.Errorf("UDPSupported = %v for an HTTP proxy, want nil", *stats.UDPSupported)
+	}
+}
@@ -0,0 +1,225 @@
+package checker
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/drsoft-oss/proxybench/internal/resolver"
+	"github.com/drsoft-oss/proxybench/internal/tracing"
+)
+
+// DefaultJudgeURLs are proxy judges: pages that echo the request they
+// received, in the azenv.php "KEY = value" layout or as JSON with
+// "headers" and "origin" (httpbin, proxybench target's /echo).
+var DefaultJudgeURLs = []string{
+	"http://azenv.net/",
+	"http://httpbin.org/get",
+}
+
+// Anonymity levels in Result.Anonymity.
+const (
+	AnonymityTransparent = "transparent" // the judge saw the client's own address
+	AnonymityAnonymous   = "anonymous"   // the client is hidden, but the proxy announces itself
+	AnonymityElite       = "elite"       // the request looks like it came from the proxy alone
+)
+
+// proxyHeaders are request headers, in CGI form, that reveal a proxy.
+var proxyHeaders = []string{
+	"HTTP_VIA",
+	"HTTP_FORWARDED",
+	"HTTP_FORWARDED_FOR",
+	"HTTP_X_FORWARDED",
+	"HTTP_X_FORWARDED_FOR",
+	"HTTP_X_REAL_IP",
+	"HTTP_X_PROXY_ID",
+	"HTTP_CLIENT_IP",
+	"HTTP_PROXY_CONNECTION",
+	"HTTP_X_BLUECOAT_VIA",
+}
+
+// Judges are proxy judges to ask, nearest first, and the client's own
+// addresses as they saw them when asked directly (see RankJudges).
+type Judges struct {
+	URLs []string
+	Own  []netip.Addr
+}
+
+// RankJudges asks every judge in urls directly, without a proxy, and
+// returns the ones that answered in order of latency, along with the
+// addresses they saw the request come from. It fails when no judge
+// answered or none reported the client's address.
+func RankJudges(ctx context.Context, urls []string, timeout time.Duration) (*Judges, error) {
+	ctx, span := tracing.Start(ctx, "judges.rank")
+	defer span.End()
+	client := &http.Client{
+		Timeout:   timeout,
+		Transport: &http.Transport{DialContext: resolver.Default().DialContext},
+	}
+	type answer struct {
+		url  string
+		took time.Duration
+		env  map[string]string
+	}
+	var answers []answer
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, u := range urls {
+		wg.Go(func() {
+			start := time.Now()
+			env, err := askJudge(ctx, client, u)
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			answers = append(answers, answer{u, time.Since(start), env})
+			mu.Unlock()
+		})
+	}
+	wg.Wait()
+	if len(answers) == 0 {
+		err := errors.New("no proxy judge answered")
+		tracing.Fail(span, err)
+		return nil, err
+	}
+	slices.SortFunc(answers, func(a, b answer) int { return cmp.Compare(a.took, b.took) })
+	j := &Judges{}
+	for _, a := range answers {
+		j.URLs = append(j.URLs, a.url)
+		for _, ip := range addrsIn(a.env["REMOTE_ADDR"]) {
+			if !slices.Contains(j.Own, ip) {
+				j.Own = append(j.Own, ip)
+			}
+		}
+	}
+	if len(j.Own) == 0 {
+		return nil, errors.New("no proxy judge reported the client's address")
+	}
+	span.SetAttributes(attribute.String("judge.url", j.URLs[0]))
+	return j, nil
+}
+
+// probeAnonymity asks the judges, nearest first, what a request through
+// client looks like and grades the proxy by what it gave away. Judges
+// must be plain http: a CONNECT tunnel hides the request from the proxy,
+// so it could add nothing. "" means no judge answered.
+func probeAnonymity(ctx context.Context, client *http.Client, j *Judges) string {
+	ctx, span := tracing.Start(ctx, "anonymity")
+	defer span.End()
+	for _, u := range j.URLs {
+		env, err := askJudge(ctx, client, u)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ""
+			}
+			continue // fall back to the next judge
+		}
+		level := grade(env, j.Own)
+		span.SetAttributes(attribute.String("judge.url", u), attribute.String("anonymity", level))
+		return level
+	}
+	return ""
+}
+
+// grade rates what a judge saw: any of own anywhere means transparent,
+// a proxy header without it anonymous, neither elite.
+func grade(env map[string]string, own []netip.Addr) string {
+	level := AnonymityElite
+	for k, v := range env {
+		for _, ip := range addrsIn(v) {
+			if slices.Contains(own, ip) {
+				return AnonymityTransparent
+			}
+		}
+		if slices.Contains(proxyHeaders, k) {
+			level = AnonymityAnonymous
+		}
+	}
+	return level
+}
+
+func askJudge(ctx context.Context, client *http.Client, judge string) (map[string]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, judge, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(tracing.WithClientTrace(req))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("judge answered %s", resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return nil, err
+	}
+	env := parseJudge(body)
+	if env == nil {
+		return nil, errors.New("judge page not understood")
+	}
+	return env, nil
+}
+
+// azenvLine matches a "KEY = value" line of an azenv.php page.
+var azenvLine = regexp.MustCompile(`(?m)^\s*([A-Z][A-Z0-9_]*)\s*=\s*(.*?)\s*$`)
+
+// parseJudge reads a judge page into CGI-style variables (REMOTE_ADDR,
+// HTTP_VIA, ...), or returns nil if it is in neither known layout.
+func parseJudge(body []byte) map[string]string {
+	var obj struct {
+		Headers map[string]json.RawMessage `json:"headers"`
+		Origin  string                     `json:"origin"`
+	}
+	if json.Unmarshal(body, &obj) == nil && obj.Headers != nil {
+		env := map[string]string{"REMOTE_ADDR": obj.Origin}
+		for k, raw := range obj.Headers {
+			var one string
+			var many []string
+			if json.Unmarshal(raw, &one) != nil && json.Unmarshal(raw, &many) == nil {
+				one = strings.Join(many, ", ")
+			}
+			env["HTTP_"+strings.ToUpper(strings.ReplaceAll(k, "-", "_"))] = one
+		}
+		return env
+	}
+	matches := azenvLine.FindAllSubmatch(body, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+	env := make(map[string]string, len(matches))
+	for _, m := range matches {
+		env[string(m[1])] = string(m[2])
+	}
+	if _, ok := env["REMOTE_ADDR"]; !ok {
+		return nil
+	}
+	return env
+}
+
+// addrsIn returns the IP addresses in a header value such as
+// "for=192.0.2.1:4711, 1.1 proxy" or "203.0.113.5, 198.51.100.7".
+func addrsIn(v string) []netip.Addr {
+	var addrs []netip.Addr
+	for _, f := range strings.FieldsFunc(v, func(r rune) bool { return strings.ContainsRune(" ,;=\"", r) }) {
+		if ap, err := netip.ParseAddrPort(f); err == nil {
+			addrs = append(addrs, ap.Addr().Unmap())
+		} else if ip, err := netip.ParseAddr(strings.Trim(f, "[]")); err == nil {
+			addrs = append(addrs, ip.Unmap())
+		}
+	}
+	return addrs
+}
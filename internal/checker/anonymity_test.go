@@ -0,0 +1,131 @@
+package checker
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeJudge serves an azenv.php-style page after delay.
+func fakeJudge(t *testing.T, delay time.Duration) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(delay)
+		host, _, _ := net.SplitHostPort(r.RemoteAddr)
+		fmt.Fprintf(w, "<html><body><pre>\nREMOTE_ADDR = %s\nREQUEST_METHOD = %s\n", host, r.Method)
+		for k, v := range r.Header {
+			fmt.Fprintf(w, "HTTP_%s = %s\n", strings.ToUpper(strings.ReplaceAll(k, "-", "_")), strings.Join(v, ", "))
+		}
+		io.WriteString(w, "</pre></body></html>\n") //nolint:errcheck
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL + "/azenv.php"
+}
+
+// fakeForwardingProxy is an HTTP proxy that forwards requests with extra
+// headers added.
+func fakeForwardingProxy(t *testing.T, extra http.Header) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		out, err := http.NewRequestWithContext(r.Context(), r.Method, r.URL.String(), nil)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for k, v := range extra {
+			out.Header[k] = v
+		}
+		resp, err := http.DefaultTransport.RoundTrip(out)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+		w.WriteHeader(resp.StatusCode)
+		io.Copy(w, resp.Body) //nolint:errcheck
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func TestCheck_anonymity(t *testing.T) {
+	judge := fakeJudge(t, 0)
+	own := netip.MustParseAddr("192.0.2.1")
+	cases := []struct {
+		name  string
+		extra http.Header
+		want  string
+	}{
+		{"transparent", http.Header{"X-Forwarded-For": {"192.0.2.1"}}, AnonymityTransparent},
+		{"forwarded with port", http.Header{"Forwarded": {`for="192.0.2.1:4711"`}}, AnonymityTransparent},
+		{"anonymous", http.Header{"Via": {"1.1 squid"}}, AnonymityAnonymous},
+		{"elite", nil, AnonymityElite},
+	}
+	for _, c := range cases {
+		opts := Options{
+			Timeout:    2 * time.Second,
+			TestURL:    judge,
+			ConnectURL: judge,
+			// The dead judge first: the next one is the fallback.
+			Judges: &Judges{URLs: []string{"http://127.0.0.1:1/azenv.php", judge}, Own: []netip.Addr{own}},
+		}
+		r := Check(fakeForwardingProxy(t, c.extra), opts)
+		if r.Anonymity != c.want {
+			t.Errorf("%s: Anonymity = %q, want %q", c.name, r.Anonymity, c.want)
+		}
+		if hasWarning := strings.Contains(r.Warning, "transparent"); hasWarning != (c.want == AnonymityTransparent) {
+			t.Errorf("%s: Warning = %q", c.name, r.Warning)
+		}
+	}
+}
+
+func TestRankJudges(t *testing.T) {
+	slow, fast := fakeJudge(t, 200*time.Millisecond), fakeJudge(t, 0)
+	j, err := RankJudges(t.Context(), []string{slow, "http://127.0.0.1:1/", fast}, 2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(j.URLs, []string{fast, slow}) {
+		t.Errorf("URLs = %v, want fast then slow", j.URLs)
+	}
+	if !slices.Contains(j.Own, netip.MustParseAddr("127.0.0.1")) {
+		t.Errorf("Own = %v, want 127.0.0.1", j.Own)
+	}
+	if _, err := RankJudges(t.Context(), []string{"http://127.0.0.1:1/"}, time.Second); err == nil {
+		t.Error("RankJudges with no live judge succeeded")
+	}
+}
+
+func TestParseJudge(t *testing.T) {
+	cases := []struct {
+		name, body string
+		want       map[string]string
+	}{
+		{"azenv", "<pre>\nREMOTE_ADDR = 203.0.113.5\nHTTP_VIA = 1.1 proxy\n</pre>",
+			map[string]string{"REMOTE_ADDR": "203.0.113.5", "HTTP_VIA": "1.1 proxy"}},
+		{"httpbin", `{"headers": {"Via": "1.1 proxy"}, "origin": "203.0.113.5"}`,
+			map[string]string{"REMOTE_ADDR": "203.0.113.5", "HTTP_VIA": "1.1 proxy"}},
+		{"target echo", `{"headers": {"X-Forwarded-For": ["192.0.2.1", "198.51.100.7"]}, "origin": "203.0.113.5"}`,
+			map[string]string{"REMOTE_ADDR": "203.0.113.5", "HTTP_X_FORWARDED_FOR": "192.0.2.1, 198.51.100.7"}},
+		{"other page", "<html>Welcome</html>", nil},
+	}
+	for _, c := range cases {
+		got := parseJudge([]byte(c.body))
+		if len(got) != len(c.want) {
+			t.Errorf("%s: parseJudge = %v, want %v", c.name, got, c.want)
+			continue
+		}
+		for k, v := range c.want {
+			if got[k] != v {
+				t.Errorf("%s: %s = %q, want %q", c.name, k, got[k], v)
+			}
+		}
+	}
+}
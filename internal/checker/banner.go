@@ -0,0 +1,154 @@
+package checker
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+	"unicode"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/drsoft-oss/proxybench/internal/resolver"
+	"github.com/drsoft-oss/proxybench/internal/tracing"
+)
+
+// bannerWait caps how long grabBanner waits for a server that speaks first
+// (SSH, SMTP, FTP, ...) before it starts probing.
+const bannerWait = 2 * time.Second
+
+// maxBanner is the longest banner kept, in bytes.
+const maxBanner = 120
+
+// grabBanner describes whatever listens at hostPort: the greeting of a
+// server that speaks first, else the negotiated TLS version, ALPN protocol
+// and certificate subject, else the status line and Server header of an
+// HTTP reply. TLS goes first because many TLS servers answer plain HTTP
+// with an error page. It returns "" when nothing answers.
+func grabBanner(ctx context.Context, hostPort string, timeout time.Duration) string {
+	ctx, span := tracing.Start(ctx, "banner", trace.WithAttributes(attribute.String("net.peer", hostPort)))
+	defer span.End()
+
+	banner := ""
+	for _, grab := range []func(context.Context, string, time.Duration) string{passiveBanner, tlsBanner, httpBanner} {
+		if banner = grab(ctx, hostPort, timeout); banner != "" {
+			break
+		}
+	}
+	span.SetAttributes(attribute.String("net.banner", banner))
+	return banner
+}
+
+// passiveBanner connects and returns whatever the server sends unprompted.
+func passiveBanner(ctx context.Context, hostPort string, timeout time.Duration) string {
+	conn, err := dialBanner(ctx, hostPort, timeout)
+	if err != nil {
+		return ""
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(min(timeout, bannerWait))) //nolint:errcheck
+	buf := make([]byte, maxBanner)
+	n, _ := conn.Read(buf)
+	return printable(buf[:n])
+}
+
+// httpBanner sends a HEAD request and returns the status line, plus the
+// Server header when present.
+func httpBanner(ctx context.Context, hostPort string, timeout time.Duration) string {
+	conn, err := dialBanner(ctx, hostPort, timeout)
+	if err != nil {
+		return ""
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout)) //nolint:errcheck
+	fmt.Fprintf(conn, "HEAD / HTTP/1.0\r\nHost: %s\r\n\r\n", hostPort)
+
+	br := bufio.NewReader(io.LimitReader(conn, 4096))
+	status, _ := br.ReadString('\n')
+	if !strings.HasPrefix(status, "HTTP/") {
+		return printable([]byte(status))
+	}
+	banner := strings.TrimSpace(status)
+	for {
+		line, err := br.ReadString('\n')
+		line = strings.TrimSpace(line)
+		if line == "" || err != nil {
+			break
+		}
+		if name, value, ok := strings.Cut(line, ":"); ok && strings.EqualFold(name, "Server") {
+			banner += "; Server: " + strings.TrimSpace(value)
+			break
+		}
+	}
+	return printable([]byte(banner))
+}
+
+// tlsBanner completes a TLS handshake, offering h2 and http/1.1.
+func tlsBanner(ctx context.Context, hostPort string, timeout time.Duration) string {
+	conn, err := dialBanner(ctx, hostPort, timeout)
+	if err != nil {
+		return ""
+	}
+	host, _, _ := net.SplitHostPort(hostPort)
+	tc := tls.Client(conn, &tls.Config{
+		ServerName:         host,
+		NextProtos:         []string{"h2", "http/1.1"},
+		InsecureSkipVerify: true, //nolint:gosec // identifying the server, not trusting it
+	})
+	defer tc.Close()
+	hctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if err := tc.HandshakeContext(hctx); err != nil {
+		return ""
+	}
+	st := tc.ConnectionState()
+	banner := "TLS " + tls.VersionName(st.Version)
+	if st.NegotiatedProtocol != "" {
+		banner += " ALPN=" + st.NegotiatedProtocol
+	}
+	if len(st.PeerCertificates) > 0 {
+		banner += " CN=" + st.PeerCertificates[0].Subject.CommonName
+	}
+	return printable([]byte(banner))
+}
+
+func dialBanner(ctx context.Context, hostPort string, timeout time.Duration) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return resolver.Default().DialContext(ctx, "tcp", hostPort)
+}
+
+// printable renders raw bytes for a report: the first line of mostly-text
+// replies with control characters escaped, or a hex dump of binary ones.
+func printable(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	if len(b) > maxBanner {
+		b = b[:maxBanner]
+	}
+	text := 0
+	for _, c := range b {
+		if c == '\r' || c == '\n' || c == '\t' || (c >= 0x20 && c < 0x7f) {
+			text++
+		}
+	}
+	if text*10 < len(b)*9 {
+		if len(b) > 16 {
+			b = b[:16]
+		}
+		return fmt.Sprintf("hex:% x", b)
+	}
+	line, _, _ := strings.Cut(string(b), "\n")
+	return strings.Map(func(r rune) rune {
+		if unicode.IsPrint(r) {
+			return r
+		}
+		return -1
+	}, strings.TrimSpace(line))
+}
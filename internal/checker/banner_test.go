@@ -0,0 +1,71 @@
+package checker
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGrabBanner(t *testing.T) {
+	// fakeListener only replies after a read; a server-first protocol
+	// needs its own listener.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			c.Write([]byte("SSH-2.0-OpenSSH_9.6\r\n")) //nolint:errcheck
+			c.Close()
+		}
+	}()
+	ssh := ln.Addr().String()
+
+	web := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "nginx/1.25")
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer web.Close()
+
+	tlsSrv := httptest.NewUnstartedServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	tlsSrv.EnableHTTP2 = true
+	tlsSrv.StartTLS()
+	defer tlsSrv.Close()
+
+	cases := []struct {
+		name, addr, want string
+	}{
+		{"server first", ssh, "SSH-2.0-OpenSSH_9.6"},
+		{"http", strings.TrimPrefix(web.URL, "http://"), "HTTP/1.0 404 Not Found; Server: nginx/1.25"},
+		{"tls", strings.TrimPrefix(tlsSrv.URL, "https://"), "TLS TLS 1.3 ALPN=h2"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := grabBanner(t.Context(), tc.addr, time.Second)
+			if !strings.HasPrefix(got, tc.want) {
+				t.Errorf("grabBanner = %q, want prefix %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPrintable(t *testing.T) {
+	cases := map[string]string{
+		"":                             "",
+		"220 ftp ready\r\nmore":        "220 ftp ready",
+		"\x16\x03\x01\x00\xa5\x01\x00": "hex:16 03 01 00 a5 01 00",
+	}
+	for in, want := range cases {
+		if got := printable([]byte(in)); got != want {
+			t.Errorf("printable(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package checker
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/drsoft-oss/proxybench/internal/tracing"
+)
+
+// DefaultConnectURL is the https target used to test CONNECT tunnelling.
+const DefaultConnectURL = "https://www.google.com"
+
+// probeConnect tests whether an HTTP proxy, proxyURL, tunnels to an https
+// target: the transport issues CONNECT and a TLS handshake, sending
+// opts.TLSFingerprint, runs through the tunnel, and either failing is
+// returned as the error.
+// The target's certificate and TLS version do not fail the probe, since
+// only the tunnel is under test; they are reported in the returned session,
+// verified against opts.RootCAs.
+func probeConnect(ctx context.Context, transport *http.Transport, proxyURL *url.URL, client *http.Client, target string, opts Options) (*TLSInfo, error) {
+	ctx, span := tracing.Start(ctx, "http.connect")
+	t := transport.Clone()
+	t.TLSClientConfig = &tls.Config{ //nolint:gosec // probing the tunnel, not the target
+		InsecureSkipVerify: true,
+		MinVersion:         tls.VersionTLS10, // so a downgrade can be seen
+	}
+	var session atomic.Pointer[tls.ConnectionState]
+	ClientHello{
+		Fingerprint: opts.TLSFingerprint,
+		OnHandshake: func(cs tls.ConnectionState) { session.Store(&cs) },
+	}.Apply(t, proxyURL)
+	c := *client
+	c.Transport = t
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, target, nil)
+	if err != nil {
+		tracing.End(span, err)
+		return nil, err
+	}
+	resp, err := c.Do(tracing.WithClientTrace(req))
+	tracing.End(span, err)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return sessionInfo(cmp.Or(resp.TLS, session.Load()), target, opts.RootCAs), nil
+}
+
+// connectTarget returns the https URL to probe CONNECT with.
+func connectTarget(opts Options) string {
+	if opts.ConnectURL != "" {
+		return opts.ConnectURL
+	}
+	return DefaultConnectURL
+}
+
+// tunnelsTestURL reports whether the forward check itself went through a
+// CONNECT tunnel, making a separate probe redundant.
+func tunnelsTestURL(testURL string) bool {
+	return strings.HasPrefix(testURL, "https://")
+}
+
+// ConnectTunnel asks the HTTP proxy on conn to CONNECT to addr, sending
+// user's credentials and header, and returns its reply, with the body
+// closed, along with an error for any but 200. ctx bounds the exchange.
+func ConnectTunnel(ctx context.Context, conn net.Conn, addr string, user *url.Userinfo, header http.Header) (*http.Response, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)          //nolint:errcheck
+		defer conn.SetDeadline(time.Time{}) //nolint:errcheck
+	}
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Unix(1, 0)) }) //nolint:errcheck
+	defer stop()
+
+	req := &http.Request{Method: http.MethodConnect, URL: &url.URL{Opaque: addr}, Host: addr, Header: header.Clone()}
+	if req.Header == nil {
+		req.Header = http.Header{}
+	}
+	if user != nil {
+		pass, _ := user.Password()
+		req.Header.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(user.Username()+":"+pass)))
+	}
+	if err := req.Write(conn); err != nil {
+		return nil, err
+	}
+	// The proxy sends nothing after its reply until the tunnelled request,
+	// so the buffered reader cannot swallow any of the tunnel's bytes.
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return resp, fmt.Errorf("CONNECT %s: %s", addr, resp.Status)
+	}
+	return resp, nil
+}
@@ -0,0 +1,131 @@
+package checker
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeHTTPProxy answers GETs itself and, when allowConnect is set, tunnels
+// CONNECT requests to their target; otherwise CONNECT gets a 405.
+func fakeHTTPProxy(t *testing.T, allowConnect bool) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				req, err := http.ReadRequest(bufio.NewReader(c))
+				if err != nil {
+					return
+				}
+				if req.Method != http.MethodConnect {
+					io.WriteString(c, "HTTP/1.1 200 OK\r\nContent-Length: 0\r\n\r\n") //nolint:errcheck
+					return
+				}
+				if !allowConnect {
+					io.WriteString(c, "HTTP/1.1 405 Method Not Allowed\r\nContent-Length: 0\r\n\r\n") //nolint:errcheck
+					return
+				}
+				up, err := net.Dial("tcp", req.Host)
+				if err != nil {
+					return
+				}
+				defer up.Close()
+				io.WriteString(c, "HTTP/1.1 200 Connection established\r\n\r\n") //nolint:errcheck
+				go io.Copy(up, c)                                                //nolint:errcheck
+				io.Copy(c, up)                                                   //nolint:errcheck
+			}()
+		}
+	}()
+	return "http://" + ln.Addr().String()
+}
+
+func TestCheckHTTP_connectSupported(t *testing.T) {
+	target := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer target.Close()
+
+	for _, allow := range []bool{true, false} {
+		opts := Options{Timeout: 2 * time.Second, TestURL: "http://example.invalid/", ConnectURL: target.URL}
+		r := CheckHTTP(fakeHTTPProxy(t, allow), opts)
+		if !r.Alive {
+			t.Fatalf("allowConnect=%v: GET forwarding failed: %s", allow, r.Error)
+		}
+		if r.ConnectSupported == nil || *r.ConnectSupported != allow {
+			t.Errorf("allowConnect=%v: ConnectSupported = %v", allow, r.ConnectSupported)
+		}
+		if r.HTTPOnly == allow {
+			t.Errorf("allowConnect=%v: HTTPOnly = %v", allow, r.HTTPOnly)
+		}
+	}
+}
+
+func TestCheckHTTP_requireConnect(t *testing.T) {
+	target := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer target.Close()
+
+	opts := Options{Timeout: 2 * time.Second, TestURL: "http://example.invalid/", ConnectURL: target.URL, RequireConnect: true}
+	if r := CheckHTTP(fakeHTTPProxy(t, true), opts); !r.Alive || r.HTTPOnly {
+		t.Errorf("tunnelling proxy: alive=%v http-only=%v (%s), want alive", r.Alive, r.HTTPOnly, r.Error)
+	}
+	r := CheckHTTP(fakeHTTPProxy(t, false), opts)
+	if r.Alive || !r.HTTPOnly {
+		t.Fatalf("http-only proxy: alive=%v http-only=%v, want dead and http-only", r.Alive, r.HTTPOnly)
+	}
+	if r.Failure == nil || r.Failure.Kind != ErrProtocol || !strings.Contains(r.Error, "Method Not Allowed") {
+		t.Errorf("failure = %+v, want a protocol error carrying the proxy's refusal", r.Failure)
+	}
+}
+
+func TestCheck_httpOnlyWarning(t *testing.T) {
+	r := Check(fakeHTTPProxy(t, false), Options{Timeout: 2 * time.Second, TestURL: "http://example.invalid/", ConnectURL: "https://example.invalid/"})
+	if !r.Alive || !strings.Contains(r.Warning, "http-only") {
+		t.Errorf("alive=%v warning=%q, want alive with an http-only warning", r.Alive, r.Warning)
+	}
+}
+
+func TestCheckHTTP_connectUnreachable(t *testing.T) {
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	addr := ln.Addr().String()
+	ln.Close()
+	r := CheckHTTP("http://"+addr, Options{Timeout: time.Second, TestURL: "http://example.invalid/"})
+	if r.ConnectSupported != nil {
+		t.Errorf("ConnectSupported = %v for an unreachable proxy, want nil", *r.ConnectSupported)
+	}
+}
+
+func TestCheckHTTP_credentials(t *testing.T) {
+	var gotAuth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Proxy-Authorization")
+	}))
+	defer srv.Close()
+
+	opts := Options{
+		Timeout:     2 * time.Second,
+		TestURL:     "http://example.invalid/",
+		ConnectURL:  "https://example.invalid/",
+		Credentials: func(string) *url.Userinfo { return url.UserPassword("alice", "pw") },
+	}
+	r := CheckHTTP(srv.URL, opts)
+	if want := "Basic YWxpY2U6cHc="; gotAuth != want {
+		t.Errorf("Proxy-Authorization = %q, want %q", gotAuth, want)
+	}
+	if r.Address != srv.URL {
+		t.Errorf("Address = %q, want the credential-free %q", r.Address, srv.URL)
+	}
+}
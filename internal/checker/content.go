@@ -0,0 +1,203 @@
+package checker
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+
+	"github.com/drsoft-oss/proxybench/internal/resolver"
+	"github.com/drsoft-oss/proxybench/internal/tracing"
+)
+
+// DefaultContentURL is a small, static plain-http page: a proxy can only
+// rewrite pages it sees in the clear.
+const DefaultContentURL = "http://example.com/"
+
+// maxContentDiff caps the differences kept in Result.ContentDiff.
+const maxContentDiff = 5
+
+// Reference is a page's structure fetched directly, for comparison with
+// the same page fetched through each proxy (see FetchReference).
+type Reference struct {
+	URL string
+	// elements counts the structural elements of the page, as described
+	// by pageElements; unstable are the kinds of element (see
+	// elementKind) that changed between two direct fetches and are not
+	// compared.
+	elements map[string]int
+	unstable map[string]bool
+}
+
+// FetchReference fetches pageURL directly, twice, and records its
+// structure: the scripts, frames, links, forms and embeds it contains.
+// Kinds of element that differ between the two fetches, such as inline
+// scripts carrying a nonce or rotating ad links, are left out of the
+// comparison.
+func FetchReference(ctx context.Context, pageURL string, timeout time.Duration) (*Reference, error) {
+	client := &http.Client{
+		Timeout:   timeout,
+		Transport: &http.Transport{DialContext: resolver.Default().DialContext},
+	}
+	first, err := fetchElements(ctx, client, pageURL)
+	if err != nil {
+		return nil, err
+	}
+	second, err := fetchElements(ctx, client, pageURL)
+	if err != nil {
+		return nil, err
+	}
+	ref := &Reference{URL: pageURL, elements: first, unstable: map[string]bool{}}
+	for _, m := range []map[string]int{first, second} {
+		for el := range m {
+			if first[el] != second[el] {
+				ref.unstable[elementKind(el)] = true
+			}
+		}
+	}
+	return ref, nil
+}
+
+// probeContent fetches the reference page through the proxy and reports
+// whether its structure differs, with the differences: elements added
+// (an injected script) or removed (a link replaced by another). nil means
+// the page could not be fetched intact enough to compare.
+func probeContent(ctx context.Context, client *http.Client, ref *Reference) (*bool, []string) {
+	ctx, span := tracing.Start(ctx, "content_check")
+	defer span.End()
+
+	got, err := fetchElements(ctx, client, ref.URL)
+	if err != nil {
+		return nil, nil
+	}
+	diff := diffElements(ref, got)
+	modified := len(diff) > 0
+	if len(diff) > maxContentDiff {
+		diff = append(diff[:maxContentDiff], fmt.Sprintf("%d more", len(diff)-maxContentDiff))
+	}
+	return &modified, diff
+}
+
+// diffElements lists the elements of got not in the reference ("added
+// ...") and then those of the reference missing from got ("removed
+// ..."), each sorted, skipping unstable ones.
+func diffElements(ref *Reference, got map[string]int) []string {
+	var added, removed []string
+	for _, el := range sortedElements(got) {
+		if got[el] > ref.elements[el] && !ref.unstable[elementKind(el)] {
+			added = append(added, "added "+el)
+		}
+	}
+	for _, el := range sortedElements(ref.elements) {
+		if ref.elements[el] > got[el] && !ref.unstable[elementKind(el)] {
+			removed = append(removed, "removed "+el)
+		}
+	}
+	return append(added, removed...)
+}
+
+func fetchElements(ctx context.Context, client *http.Client, pageURL string) (map[string]int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", pageURL, resp.Status)
+	}
+	return pageElements(io.LimitReader(resp.Body, 1<<20))
+}
+
+// pageElements counts what a proxy rewriting pages to monetize them
+// changes: external scripts by src, inline scripts by a hash of their
+// code, and the targets of frames, links, forms and embeds. Text and
+// styling are ignored.
+func pageElements(r io.Reader) (map[string]int, error) {
+	elements := map[string]int{}
+	z := html.NewTokenizer(r)
+	inScript := false
+	var script strings.Builder
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if z.Err() == io.EOF {
+				return elements, nil
+			}
+			return nil, z.Err()
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tok := z.Token()
+			attr := elementAttr[tok.Data]
+			if attr == "" {
+				continue
+			}
+			if v := attrValue(tok, attr); v != "" {
+				elements[fmt.Sprintf("%s %s=%s", tok.Data, attr, v)]++
+			} else if tok.Data == "script" {
+				inScript = true
+				script.Reset()
+			}
+		case html.TextToken:
+			if inScript {
+				script.Write(z.Text())
+			}
+		case html.EndTagToken:
+			if inScript {
+				sum := sha256.Sum256([]byte(strings.TrimSpace(script.String())))
+				elements["inline script "+hex.EncodeToString(sum[:4])]++
+				inScript = false
+			}
+		}
+	}
+}
+
+// elementKind returns what an element from pageElements is without where
+// it points: "inline script" or e.g. "script src".
+func elementKind(el string) string {
+	if strings.HasPrefix(el, "inline script ") {
+		return "inline script"
+	}
+	kind, _, _ := strings.Cut(el, "=")
+	return kind
+}
+
+// elementAttr is the attribute that says where each compared element
+// points.
+var elementAttr = map[string]string{
+	"script": "src",
+	"iframe": "src",
+	"frame":  "src",
+	"embed":  "src",
+	"object": "data",
+	"a":      "href",
+	"link":   "href",
+	"form":   "action",
+}
+
+func attrValue(tok html.Token, name string) string {
+	for _, a := range tok.Attr {
+		if a.Key == name {
+			return strings.TrimSpace(a.Val)
+		}
+	}
+	return ""
+}
+
+func sortedElements(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
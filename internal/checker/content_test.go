@@ -0,0 +1,90 @@
+package checker
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+const contentPage = `<!doctype html>
+<html><head><title>Example</title><script>var x = 1;</script></head>
+<body><h1>Example Domain</h1>
+<p><a href="https://www.iana.org/domains/example">More information...</a></p>
+</body></html>
+`
+
+// fakeRewritingProxy is an HTTP proxy that serves contentPage for every
+// request, passed through rewrite.
+func fakeRewritingProxy(t *testing.T, rewrite func(string) string) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, rewrite(contentPage)) //nolint:errcheck
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func TestCheck_content(t *testing.T) {
+	page := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, contentPage) //nolint:errcheck
+	}))
+	t.Cleanup(page.Close)
+	ref, err := FetchReference(t.Context(), page.URL, 2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name    string
+		rewrite func(string) string
+		want    []string
+	}{
+		{"clean", func(s string) string { return s }, nil},
+		{"script injected", func(s string) string {
+			return strings.Replace(s, "</body>", `<script src="http://ads.example/inject.js"></script></body>`, 1)
+		}, []string{"added script src=http://ads.example/inject.js"}},
+		{"link altered", func(s string) string {
+			return strings.Replace(s, "https://www.iana.org/domains/example", "http://aff.example/?to=iana", 1)
+		}, []string{"added a href=http://aff.example/?to=iana", "removed a href=https://www.iana.org/domains/example"}},
+		{"text only", func(s string) string { return strings.Replace(s, "More information", "Learn more", 1) }, nil},
+	}
+	for _, c := range cases {
+		opts := Options{Timeout: 2 * time.Second, TestURL: page.URL, ConnectURL: page.URL, ContentRef: ref}
+		r := Check(fakeRewritingProxy(t, c.rewrite), opts)
+		if r.ContentModified == nil || *r.ContentModified != (c.want != nil) || !slices.Equal(r.ContentDiff, c.want) {
+			t.Errorf("%s: ContentModified = %v, ContentDiff = %q, want %q", c.name, r.ContentModified, r.ContentDiff, c.want)
+		}
+		if hasWarning := strings.Contains(r.Warning, "content modified"); hasWarning != (c.want != nil) {
+			t.Errorf("%s: Warning = %q", c.name, r.Warning)
+		}
+	}
+}
+
+func TestFetchReference_unstable(t *testing.T) {
+	n := 0
+	page := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		io.WriteString(w, strings.Replace(contentPage, "var x = 1;", "var nonce = "+strings.Repeat("7", n)+";", 1)) //nolint:errcheck
+	}))
+	t.Cleanup(page.Close)
+	ref, err := FetchReference(t.Context(), page.URL, 2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Inline scripts change on every load, so a third nonce is not
+	// reported; an injected external script still is.
+	page2 := strings.Replace(contentPage, "var x = 1;", "var nonce = 0;", 1)
+	page2 = strings.Replace(page2, "</body>", `<script src="http://ads.example/inject.js"></script></body>`, 1)
+	got, err := pageElements(strings.NewReader(page2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"added script src=http://ads.example/inject.js"}
+	if diff := diffElements(ref, got); !slices.Equal(diff, want) {
+		t.Errorf("diff = %q, want %q", diff, want)
+	}
+}
@@ -0,0 +1,82 @@
+package checker
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/drsoft-oss/proxybench/internal/tracing"
+)
+
+// Defaults for the DNS canary: a page whose content is known, reached by
+// a name the proxy has to resolve itself.
+const (
+	DefaultDNSCanaryURL    = "http://example.com/"
+	DefaultDNSCanaryExpect = "Example Domain"
+)
+
+// Outcomes of the DNS canary in Result.DNSCanary.
+const (
+	DNSClean      = "ok"
+	DNSHijacked   = "hijacked"    // the canary name led to other content
+	DNSNXHijacked = "nx_hijacked" // a name that does not exist got an answer
+	DNSBlocked    = "blocked"     // the canary name did not resolve
+)
+
+// probeDNS checks the DNS resolution the proxy does on our behalf (HTTP
+// proxies and SOCKS5 with remote resolution both get the name, not an IP).
+// A name under .invalid, which cannot exist, must fail: an answer means
+// the upstream resolver rewrites NXDOMAIN. The canary URL must then serve
+// a page containing expect: other content means its name was hijacked,
+// a resolution failure that it was censored. "" means inconclusive.
+func probeDNS(ctx context.Context, client *http.Client, canaryURL, expect string) string {
+	ctx, span := tracing.Start(ctx, "dns_canary")
+	defer span.End()
+
+	label := make([]byte, 8)
+	rand.Read(label) //nolint:errcheck // never fails
+	if status, _, err := fetchCanary(ctx, client, "http://proxybench-"+hex.EncodeToString(label)+".invalid/"); err == nil && status < 400 {
+		return DNSNXHijacked
+	}
+
+	status, body, err := fetchCanary(ctx, client, canaryURL)
+	switch {
+	case err != nil && unresolved(err):
+		return DNSBlocked
+	case err != nil:
+		return ""
+	case status >= 500:
+		// The proxy's own error page: it could not resolve (or reach) a
+		// host that is always up.
+		return DNSBlocked
+	case status >= 400:
+		return "" // refused by policy, not a DNS matter
+	case !strings.Contains(body, expect):
+		return DNSHijacked
+	}
+	return DNSClean
+}
+
+func fetchCanary(ctx context.Context, client *http.Client, target string) (int, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return 0, "", err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	return resp.StatusCode, string(body), nil
+}
+
+// unresolved reports whether err says the proxy could not resolve the
+// target: a SOCKS5 "host unreachable" reply, or a DNS error.
+func unresolved(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "host unreachable") || strings.Contains(msg, "no such host")
+}
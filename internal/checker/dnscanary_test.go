@@ -0,0 +1,60 @@
+package checker
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeResolvingProxy is an HTTP proxy whose upstream DNS is simulated:
+// canary is what it serves for the canary host ("" for a resolution
+// failure), and nxAnswer makes nonexistent names answer too.
+func fakeResolvingProxy(t *testing.T, canary string, nxAnswer bool) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Hostname(), ".invalid") && !nxAnswer,
+			r.URL.Hostname() == "canary.test" && canary == "":
+			http.Error(w, "Unable to determine IP address", http.StatusBadGateway)
+		case r.URL.Hostname() == "canary.test":
+			io.WriteString(w, canary) //nolint:errcheck
+		default:
+			io.WriteString(w, "<html>Search results for your query</html>") //nolint:errcheck
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func TestCheck_dnsCanary(t *testing.T) {
+	cases := []struct {
+		name     string
+		canary   string
+		nxAnswer bool
+		want     string
+	}{
+		{"clean", "<h1>Canary Page</h1>", false, DNSClean},
+		{"hijacked", "<html>Blocked by order of the court</html>", false, DNSHijacked},
+		{"nx hijacked", "<h1>Canary Page</h1>", true, DNSNXHijacked},
+		{"blocked", "", false, DNSBlocked},
+	}
+	for _, c := range cases {
+		opts := Options{
+			Timeout:         2 * time.Second,
+			TestURL:         "http://example.invalid/",
+			ConnectURL:      "http://example.invalid/",
+			DNSCanaryURL:    "http://canary.test/",
+			DNSCanaryExpect: "Canary Page",
+		}
+		r := Check(fakeResolvingProxy(t, c.canary, c.nxAnswer), opts)
+		if r.DNSCanary != c.want {
+			t.Errorf("%s: DNSCanary = %q, want %q", c.name, r.DNSCanary, c.want)
+		}
+		if hasWarning := strings.Contains(r.Warning, "DNS"); hasWarning != (c.want != DNSClean) {
+			t.Errorf("%s: Warning = %q", c.name, r.Warning)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package checker
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/drsoft-oss/proxybench/internal/tracing"
+)
+
+// DefaultExitIPURL answers with the caller's IP address as plain text.
+const DefaultExitIPURL = "https://api.ipify.org"
+
+// probeExitIP asks target, an IP echo service, which address the request
+// came from through client: the proxy's exit IP, or "" if the service
+// did not say. Plain-text answers and JSON with an "ip" or "origin" field
+// (httpbin) are understood.
+func probeExitIP(ctx context.Context, client *http.Client, target string) string {
+	ctx, span := tracing.Start(ctx, "exit_ip")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		tracing.End(span, err)
+		return ""
+	}
+	resp, err := client.Do(tracing.WithClientTrace(req))
+	tracing.End(span, err)
+	if err != nil {
+		return ""
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return ""
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	return parseExitIP(body)
+}
+
+func parseExitIP(body []byte) string {
+	s := strings.TrimSpace(string(body))
+	var obj struct {
+		IP     string `json:"ip"`
+		Origin string `json:"origin"`
+	}
+	if json.Unmarshal(body, &obj) == nil {
+		s = obj.IP
+		if s == "" {
+			// httpbin lists every hop: "client, proxy".
+			s, _, _ = strings.Cut(obj.Origin, ",")
+		}
+	}
+	ip, err := netip.ParseAddr(strings.TrimSpace(s))
+	if err != nil {
+		return ""
+	}
+	return ip.Unmap().String()
+}
+
+// SetExitCountry records the country of ExitIP and flags a proxy that
+// exits somewhere other than ingress, the country of its own address,
+// e.g. a "US" proxy from a resold pool that exits in Germany. Either
+// country may be empty when unknown, which never counts as a mismatch.
+func (r *Result) SetExitCountry(ingress, exit string) {
+	r.ExitCountry = exit
+	if ingress != "" && exit != "" && !strings.EqualFold(ingress, exit) {
+		r.GeoMismatch = true
+		r.warn("exits in " + exit + ", not " + ingress)
+	}
+}
@@ -0,0 +1,52 @@
+package checker
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseExitIP(t *testing.T) {
+	cases := map[string]string{
+		"203.0.113.9\n":                          "203.0.113.9",
+		`{"ip":"2001:db8::1"}`:                   "2001:db8::1",
+		`{"origin":"203.0.113.9, 198.51.100.1"}`: "203.0.113.9",
+		"<html>blocked</html>":                   "",
+		`{"error":"rate limited"}`:               "",
+	}
+	for in, want := range cases {
+		if got := parseExitIP([]byte(in)); got != want {
+			t.Errorf("parseExitIP(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestCheck_exitIP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ip" {
+			io.WriteString(w, "203.0.113.9\n") //nolint:errcheck
+		}
+	}))
+	defer srv.Close()
+
+	opts := Options{Timeout: 2 * time.Second, TestURL: "http://example.invalid/", ConnectURL: "http://example.invalid/", ExitIPURL: "http://echo.invalid/ip"}
+	if r := Check(srv.URL, opts); r.ExitIP != "203.0.113.9" {
+		t.Errorf("ExitIP = %q", r.ExitIP)
+	}
+}
+
+func TestSetExitCountry(t *testing.T) {
+	var r Result
+	r.SetExitCountry("US", "DE")
+	if !r.GeoMismatch || r.ExitCountry != "DE" || r.Warning != "exits in DE, not US" {
+		t.Errorf("US→DE: %+v", r)
+	}
+	for _, c := range [][2]string{{"US", "US"}, {"", "DE"}, {"US", ""}} {
+		var r Result
+		if r.SetExitCountry(c[0], c[1]); r.GeoMismatch || r.Warning != "" {
+			t.Errorf("%v: flagged a mismatch", c)
+		}
+	}
+}
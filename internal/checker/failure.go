@@ -0,0 +1,110 @@
+package checker
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// ErrorKind classifies why a check failed.
+type ErrorKind string
+
+const (
+	ErrTimeout  ErrorKind = "timeout"  // no answer within the timeout
+	ErrRefused  ErrorKind = "refused"  // nothing listening on the port
+	ErrDNS      ErrorKind = "dns"      // the proxy host name did not resolve
+	ErrAuth     ErrorKind = "auth"     // the proxy rejected the credentials
+	ErrTLS      ErrorKind = "tls"      // handshake, certificate or SSH host key failure
+	ErrProtocol ErrorKind = "protocol" // the peer does not speak the expected protocol
+	ErrNetwork  ErrorKind = "network"  // reset, unreachable or closed mid-way
+	ErrConfig   ErrorKind = "config"   // the address or test URL is invalid
+	ErrUnknown  ErrorKind = "unknown"
+)
+
+// ErrorPhase is the step of a check that failed.
+type ErrorPhase string
+
+const (
+	PhaseParse     ErrorPhase = "parse"     // reading the address and test URL
+	PhaseConnect   ErrorPhase = "connect"   // reaching the proxy
+	PhaseHandshake ErrorPhase = "handshake" // proxy protocol negotiation and auth
+	PhaseRequest   ErrorPhase = "request"   // the request through the proxy
+)
+
+// Failure describes a failed check for automation: Kind and Phase are
+// stable identifiers, Message is the same text as Result.Error.
+type Failure struct {
+	Kind      ErrorKind  `json:"kind"`
+	Message   string     `json:"message"`
+	Phase     ErrorPhase `json:"phase,omitempty"`
+	Retriable bool       `json:"retriable"`
+}
+
+// fail records err, prefixed with context like "tcp probe", as the
+// result's error, classified as failing in phase. A parse failure is
+// always a config error; auth and protocol errors seen while sending the
+// request are put down to the handshake that precedes it.
+func (r *Result) fail(phase ErrorPhase, prefix string, err error) {
+	r.Error = err.Error()
+	if prefix != "" {
+		r.Error = prefix + ": " + r.Error
+	}
+	f := classify(err)
+	f.Phase, f.Message = phase, r.Error
+	switch {
+	case phase == PhaseParse:
+		f.Kind, f.Retriable = ErrConfig, false
+	case phase == PhaseRequest && (f.Kind == ErrAuth || f.Kind == ErrProtocol):
+		f.Phase = PhaseHandshake
+	}
+	r.Failure = &f
+}
+
+// classify derives the kind of err from its type where the standard
+// library gives one, and from the message for the errors of proxy
+// libraries that do not.
+func classify(err error) Failure {
+	var dnsErr *net.DNSError
+	var certErr *tls.CertificateVerificationError
+	var recordErr tls.RecordHeaderError
+	var authorityErr x509.UnknownAuthorityError
+	var hostErr x509.HostnameError
+	var netErr net.Error
+	var hostKeyErr *knownhosts.KeyError
+	msg := strings.ToLower(err.Error())
+	switch {
+	case errors.As(err, &dnsErr):
+		return Failure{Kind: ErrDNS, Retriable: dnsErr.IsTimeout || dnsErr.IsTemporary}
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, os.ErrDeadlineExceeded),
+		errors.As(err, &netErr) && netErr.Timeout():
+		return Failure{Kind: ErrTimeout, Retriable: true}
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return Failure{Kind: ErrRefused}
+	case errors.As(err, &certErr), errors.As(err, &recordErr), errors.As(err, &authorityErr),
+		errors.As(err, &hostErr), strings.Contains(msg, "tls: "),
+		errors.As(err, &hostKeyErr), strings.Contains(msg, "known hosts: "), strings.Contains(msg, "host key mismatch"): // an SSH host key
+
+		return Failure{Kind: ErrTLS}
+	case strings.Contains(msg, "407 "), strings.Contains(msg, "authentication"),
+		strings.Contains(msg, "username/password"), strings.Contains(msg, "not allowed by ruleset"),
+		strings.Contains(msg, "unable to authenticate"): // an SSH login
+		return Failure{Kind: ErrAuth}
+	case strings.Contains(msg, "malformed http"), strings.Contains(msg, "unexpected protocol version"),
+		strings.Contains(msg, "server gave http response"):
+		return Failure{Kind: ErrProtocol}
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE),
+		errors.Is(err, syscall.ENETUNREACH), errors.Is(err, syscall.EHOSTUNREACH),
+		errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF),
+		strings.Contains(msg, "unknown error"): // a SOCKS5 reply: the proxy could not reach the target
+		return Failure{Kind: ErrNetwork, Retriable: true}
+	}
+	return Failure{Kind: ErrUnknown}
+}
@@ -0,0 +1,68 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestClassify(t *testing.T) {
+	cases := []struct {
+		err       error
+		kind      ErrorKind
+		retriable bool
+	}{
+		{&net.DNSError{Err: "no such host", Name: "proxy.invalid", IsNotFound: true}, ErrDNS, false},
+		{&net.DNSError{Err: "i/o timeout", Name: "proxy.example", IsTimeout: true}, ErrDNS, true},
+		{fmt.Errorf("tcp dial: %w", context.DeadlineExceeded), ErrTimeout, true},
+		{errors.New("username/password authentication failed"), ErrAuth, false},
+		{errors.New("proxyconnect tcp: 407 Proxy Authentication Required"), ErrAuth, false},
+		{errors.New("socks connect tcp 1.2.3.4:1080->example.com:80: unexpected protocol version 72"), ErrProtocol, false},
+		{errors.New("socks connect tcp 1.2.3.4:1080->example.com:80: unknown error host unreachable"), ErrNetwork, true},
+		{fmt.Errorf("read: %w", io.ErrUnexpectedEOF), ErrNetwork, true},
+		{errors.New("something else"), ErrUnknown, false},
+	}
+	for _, c := range cases {
+		if f := classify(c.err); f.Kind != c.kind || f.Retriable != c.retriable {
+			t.Errorf("classify(%q) = %s retriable=%v, want %s retriable=%v", c.err, f.Kind, f.Retriable, c.kind, c.retriable)
+		}
+	}
+}
+
+func TestClassify_refused(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	_, err = net.DialTimeout("tcp", addr, time.Second)
+	if err == nil {
+		t.Skip("closed port accepted a connection")
+	}
+	if f := classify(err); f.Kind != ErrRefused {
+		t.Errorf("classify(%v) = %s, want refused", err, f.Kind)
+	}
+}
+
+func TestResultFail(t *testing.T) {
+	var r Result
+	r.fail(PhaseRequest, "forward check", errors.New("no acceptable authentication methods"))
+	if r.Error != "forward check: no acceptable authentication methods" {
+		t.Errorf("Error = %q", r.Error)
+	}
+	want := Failure{Kind: ErrAuth, Message: r.Error, Phase: PhaseHandshake}
+	if r.Failure == nil || *r.Failure != want {
+		t.Errorf("Failure = %+v, want %+v", r.Failure, want)
+	}
+
+	r = Result{}
+	r.fail(PhaseParse, "invalid proxy URL", fmt.Errorf("read: %w", io.EOF))
+	if r.Failure.Kind != ErrConfig || r.Failure.Retriable {
+		t.Errorf("parse failure = %+v, want non-retriable config", r.Failure)
+	}
+}
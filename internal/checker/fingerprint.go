@@ -0,0 +1,132 @@
+package checker
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/url"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/drsoft-oss/proxybench/internal/resolver"
+	"github.com/drsoft-oss/proxybench/internal/tracing"
+)
+
+// Probes used to fingerprint a listener. The SOCKS5 greeting offers only
+// "no auth"; a SOCKS5 server answers with version 5 whatever it picks. The
+// HTTP request is answered by the proxy itself and never forwarded.
+var (
+	socks5Greeting = []byte{socks5Version, 1, socks5AuthNone}
+	httpProbe      = []byte("OPTIONS * HTTP/1.0\r\n\r\n")
+)
+
+// detectMismatch fingerprints a proxy that accepted a connection but failed
+// the check for its declared protocol. If it turns out to speak the other
+// protocol, DetectedProtocol is set; with opts.FixProtocol the result is
+// replaced by a check of the address under the detected scheme.
+func detectMismatch(ctx context.Context, r Result, opts Options) Result {
+	if r.Alive || r.Family == "" || ctx.Err() != nil {
+		return r // working, nothing was reachable to fingerprint, or cancelled
+	}
+	hostPort, ok := proxyHostPort(r.Address, r.Protocol)
+	if !ok {
+		return r
+	}
+	got := fingerprint(ctx, hostPort, opts.handshakeTimeout())
+	if got == ProtocolUnknown || got == r.Protocol {
+		return r
+	}
+	r.DetectedProtocol = got
+	if !opts.FixProtocol {
+		return r
+	}
+
+	fixed := string(got) + "://" + StripScheme(r.Address, r.Protocol)
+	var res Result
+	if got == ProtocolSOCKS5 {
+		res = checkSOCKS5(ctx, fixed, opts)
+	} else {
+		res = checkHTTP(ctx, fixed, opts)
+	}
+	res.DetectedProtocol = got
+	return res
+}
+
+// fingerprint reports which proxy protocol the server at hostPort speaks,
+// trying a SOCKS5 greeting and then an HTTP request on fresh connections.
+// It returns ProtocolUnknown when neither gets a recognisable reply.
+func fingerprint(ctx context.Context, hostPort string, timeout time.Duration) Protocol {
+	ctx, span := tracing.Start(ctx, "fingerprint", trace.WithAttributes(attribute.String("net.peer", hostPort)))
+	defer span.End()
+
+	proto := ProtocolUnknown
+	for _, probe := range [][]byte{socks5Greeting, httpProbe} {
+		reply, err := exchange(ctx, hostPort, probe, timeout)
+		if err != nil {
+			break // the proxy is no longer reachable
+		}
+		switch {
+		case len(reply) == 0:
+			continue // closed or silent: not this protocol
+		case reply[0] == socks5Version:
+			proto = ProtocolSOCKS5
+		case bytes.HasPrefix(reply, []byte("HTTP/")):
+			proto = ProtocolHTTP
+		}
+		if proto != ProtocolUnknown {
+			break
+		}
+	}
+	span.SetAttributes(attribute.String("proxy.detected_protocol", string(proto)))
+	return proto
+}
+
+// exchange dials hostPort, writes probe and returns up to the first five
+// bytes of the reply, enough to tell "HTTP/" from a SOCKS5 version byte.
+// Only a failed dial is an error; a reply cut short is returned as is.
+// Cancelling ctx ends the exchange early.
+func exchange(ctx context.Context, hostPort string, probe []byte, timeout time.Duration) ([]byte, error) {
+	ctx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+	conn, err := resolver.Default().DialContext(ctx, "tcp", hostPort)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) }) //nolint:errcheck
+	defer stop()
+	if _, err := conn.Write(probe); err != nil {
+		return nil, nil
+	}
+	buf := make([]byte, 5)
+	n, _ := io.ReadAtLeast(conn, buf, 1)
+	if n > 0 && buf[0] == 'H' {
+		// A SOCKS5 reply is two bytes; only wait for more if it looks like HTTP.
+		m, _ := io.ReadFull(conn, buf[n:])
+		n += m
+	}
+	return buf[:n], nil
+}
+
+// proxyHostPort extracts host:port from a proxy address, defaulting the
+// port as the declared protocol's checker does.
+func proxyHostPort(address string, proto Protocol) (string, bool) {
+	u, err := url.Parse(address)
+	if err != nil || u.Host == "" {
+		return "", false
+	}
+	if u.Port() != "" {
+		return u.Host, true
+	}
+	port := "80"
+	switch proto {
+	case ProtocolSOCKS5:
+		port = "1080"
+	case ProtocolSSH:
+		port = "22"
+	}
+	return net.JoinHostPort(u.Hostname(), port), true
+}
@@ -0,0 +1,111 @@
+package checker
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+// fakeListener answers every connection's first read with reply (or closes
+// it without a word when reply is nil).
+func fakeListener(t *testing.T, reply func(first []byte) []byte) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				buf := make([]byte, 64)
+				n, _ := c.Read(buf)
+				if out := reply(buf[:n]); out != nil {
+					c.Write(out) //nolint:errcheck
+				}
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestFingerprint(t *testing.T) {
+	httpProxy := fakeListener(t, func(first []byte) []byte {
+		// Like most HTTP proxies: garbage gets a 400, not silence.
+		return []byte("HTTP/1.1 400 Bad Request\r\n\r\n")
+	})
+	socksProxy := fakeListener(t, func(first []byte) []byte {
+		if first[0] != socks5Version {
+			return nil
+		}
+		return []byte{socks5Version, socks5AuthNone}
+	})
+	silent := fakeListener(t, func([]byte) []byte { return nil })
+
+	cases := []struct {
+		name string
+		addr string
+		want Protocol
+	}{
+		{"http", httpProxy, ProtocolHTTP},
+		{"socks5", socksProxy, ProtocolSOCKS5},
+		{"silent", silent, ProtocolUnknown},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := fingerprint(t.Context(), tc.addr, 2*time.Second); got != tc.want {
+				t.Errorf("fingerprint = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDetectMismatch(t *testing.T) {
+	httpProxy := fakeListener(t, func([]byte) []byte {
+		return []byte("HTTP/1.1 400 Bad Request\r\n\r\n")
+	})
+	r := Result{Address: "socks5://u:p@" + httpProxy, Protocol: ProtocolSOCKS5, Family: "ipv4"}
+	got := detectMismatch(t.Context(), r, Options{Timeout: 2 * time.Second})
+	if got.DetectedProtocol != ProtocolHTTP {
+		t.Fatalf("DetectedProtocol = %q, want http", got.DetectedProtocol)
+	}
+	if got.Address != r.Address || got.Protocol != ProtocolSOCKS5 {
+		t.Errorf("without FixProtocol the declared address must be kept, got %s (%s)", got.Address, got.Protocol)
+	}
+
+	got = detectMismatch(t.Context(), r, Options{Timeout: 2 * time.Second, FixProtocol: true})
+	if want := "http://u:p@" + httpProxy; got.Address != want || got.Protocol != ProtocolHTTP {
+		t.Errorf("with FixProtocol got %s (%s), want %s (http)", got.Address, got.Protocol, want)
+	}
+	if got.DetectedProtocol != ProtocolHTTP {
+		t.Errorf("DetectedProtocol = %q after fix, want http", got.DetectedProtocol)
+	}
+
+	// Unreachable proxies are not fingerprinted.
+	dead := Result{Address: "socks5://" + httpProxy, Protocol: ProtocolSOCKS5}
+	if got := detectMismatch(t.Context(), dead, Options{Timeout: time.Second}); got.DetectedProtocol != "" {
+		t.Errorf("DetectedProtocol = %q for a result without a connection", got.DetectedProtocol)
+	}
+}
+
+func TestProxyHostPort(t *testing.T) {
+	cases := []struct {
+		addr  string
+		proto Protocol
+		want  string
+	}{
+		{"socks5://1.2.3.4:9050", ProtocolSOCKS5, "1.2.3.4:9050"},
+		{"socks5://user:pw@host", ProtocolSOCKS5, "host:1080"},
+		{"http://[::1]", ProtocolHTTP, "[::1]:80"},
+	}
+	for _, tc := range cases {
+		if got, ok := proxyHostPort(tc.addr, tc.proto); !ok || got != tc.want {
+			t.Errorf("proxyHostPort(%q) = %q, %v; want %q", tc.addr, got, ok, tc.want)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package checker
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	utls "github.com/refraction-networking/utls"
+)
+
+// TLS ClientHello fingerprints for Options.TLSFingerprint: a browser's,
+// sent with uTLS, or "" for Go's own. Some targets and proxy frontends
+// treat Go's ClientHello differently from a browser's.
+const (
+	HelloGo         = ""
+	HelloChrome     = "chrome"
+	HelloFirefox    = "firefox"
+	HelloSafari     = "safari"
+	HelloEdge       = "edge"
+	HelloIOS        = "ios"
+	HelloRandomized = "randomized" // a new random ClientHello per connection
+)
+
+// helloIDs maps the fingerprints to uTLS's parrots.
+var helloIDs = map[string]utls.ClientHelloID{
+	HelloChrome:     utls.HelloChrome_Auto,
+	HelloFirefox:    utls.HelloFirefox_Auto,
+	HelloSafari:     utls.HelloSafari_Auto,
+	HelloEdge:       utls.HelloEdge_Auto,
+	HelloIOS:        utls.HelloIOS_Auto,
+	HelloRandomized: utls.HelloRandomizedNoALPN,
+}
+
+// TLSFingerprints lists the fingerprints ParseTLSFingerprint accepts
+// besides "go".
+var TLSFingerprints = []string{HelloChrome, HelloFirefox, HelloSafari, HelloEdge, HelloIOS, HelloRandomized}
+
+// ParseTLSFingerprint accepts one of TLSFingerprints, and "" or "go" for
+// HelloGo.
+func ParseTLSFingerprint(s string) (string, error) {
+	s = strings.ToLower(s)
+	switch {
+	case s == "" || s == "go":
+		return HelloGo, nil
+	case slices.Contains(TLSFingerprints, s):
+		return s, nil
+	}
+	return "", fmt.Errorf("unknown TLS fingerprint %q (want go|%s)", s, strings.Join(TLSFingerprints, "|"))
+}
+
+// ClientHandshake runs a client TLS handshake on conn with cfg, sending
+// fingerprint's ClientHello, and returns the TLS connection and its
+// state. A browser's ClientHello offers only HTTP/1.1 by ALPN, since
+// http.Transport speaks HTTP/2 only over its own connections. Failed
+// certificate checks are returned as *tls.CertificateVerificationError
+// whichever fingerprint was sent.
+func ClientHandshake(ctx context.Context, conn net.Conn, cfg *tls.Config, fingerprint string) (net.Conn, tls.ConnectionState, error) {
+	if cfg == nil {
+		cfg = &tls.Config{}
+	}
+	id, ok := helloIDs[fingerprint]
+	if !ok {
+		tc := tls.Client(conn, cfg)
+		if err := tc.HandshakeContext(ctx); err != nil {
+			return nil, tls.ConnectionState{}, err
+		}
+		return tc, tc.ConnectionState(), nil
+	}
+
+	ucfg := &utls.Config{
+		ServerName:         cfg.ServerName,
+		RootCAs:            cfg.RootCAs,
+		InsecureSkipVerify: cfg.InsecureSkipVerify, //nolint:gosec // as cfg asks
+		MinVersion:         cfg.MinVersion,
+	}
+	uc := utls.UClient(conn, ucfg, id)
+	if id == utls.HelloRandomizedNoALPN {
+		if err := uc.BuildHandshakeStateWithoutSession(); err != nil {
+			return nil, tls.ConnectionState{}, err
+		}
+		dropUnsharedMLKEM(uc.Extensions)
+	} else {
+		spec, err := utls.UTLSIdToSpec(id)
+		if err != nil {
+			return nil, tls.ConnectionState{}, err
+		}
+		for _, ext := range spec.Extensions {
+			switch e := ext.(type) {
+			case *utls.ALPNExtension:
+				e.AlpnProtocols = []string{"http/1.1"}
+			case *utls.ApplicationSettingsExtension:
+				e.SupportedProtocols = []string{"http/1.1"}
+			case *utls.ApplicationSettingsExtensionNew:
+				e.SupportedProtocols = []string{"http/1.1"}
+			}
+		}
+		uc = utls.UClient(conn, ucfg, utls.HelloCustom)
+		if err := uc.ApplyPreset(&spec); err != nil {
+			return nil, tls.ConnectionState{}, err
+		}
+	}
+	if err := uc.HandshakeContext(ctx); err != nil {
+		var certErr *utls.CertificateVerificationError
+		if errors.As(err, &certErr) {
+			err = &tls.CertificateVerificationError{UnverifiedCertificates: certErr.UnverifiedCertificates, Err: certErr.Err}
+		}
+		return nil, tls.ConnectionState{}, err
+	}
+	s := uc.ConnectionState()
+	return uc, tls.ConnectionState{
+		Version:                     s.Version,
+		HandshakeComplete:           s.HandshakeComplete,
+		DidResume:                   s.DidResume,
+		CipherSuite:                 s.CipherSuite,
+		NegotiatedProtocol:          s.NegotiatedProtocol,
+		ServerName:                  s.ServerName,
+		PeerCertificates:            s.PeerCertificates,
+		VerifiedChains:              s.VerifiedChains,
+		SignedCertificateTimestamps: s.SignedCertificateTimestamps,
+		OCSPResponse:                s.OCSPResponse,
+	}, nil
+}
+
+// dropUnsharedMLKEM takes X25519MLKEM768 out of a built randomized
+// ClientHello's supported groups unless it also sends a key share for
+// it: uTLS cannot answer a HelloRetryRequest for that group, which is
+// what a server preferring it sends otherwise. The handshake rebuilds
+// the hello from the same extensions, so the key shares are cleared for
+// it to generate them again along with their private keys.
+func dropUnsharedMLKEM(exts []utls.TLSExtension) {
+	var curves *utls.SupportedCurvesExtension
+	shared := false
+	for _, ext := range exts {
+		switch e := ext.(type) {
+		case *utls.SupportedCurvesExtension:
+			curves = e
+		case *utls.KeyShareExtension:
+			for i := range e.KeyShares {
+				shared = shared || e.KeyShares[i].Group == utls.X25519MLKEM768
+				e.KeyShares[i].Data = nil
+			}
+		}
+	}
+	if curves != nil && !shared {
+		curves.Curves = slices.DeleteFunc(slices.Clone(curves.Curves), func(c utls.CurveID) bool { return c == utls.X25519MLKEM768 })
+	}
+}
+
+// ClientHello makes the TLS handshakes an http.Transport runs with https
+// targets send a fingerprint's ClientHello.
+type ClientHello struct {
+	// Fingerprint is one of TLSFingerprints; HelloGo leaves the
+	// transport alone.
+	Fingerprint string
+	// OnHandshake, if set, is called with each session established with
+	// a target; responses carry none, since the transport did not make
+	// the connection.
+	OnHandshake func(tls.ConnectionState)
+}
+
+// Apply makes t run the handshakes with https targets itself, with t's
+// TLSClientConfig: over the connections t.DialContext returns when t has
+// no proxy (a SOCKS5 or SSH tunnel), or over a CONNECT tunnel it opens
+// through proxyURL, an HTTP or https:// proxy. Call it after ProxyTLS.
+func (h ClientHello) Apply(t *http.Transport, proxyURL *url.URL) {
+	if h.Fingerprint == HelloGo {
+		return
+	}
+	dial := t.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	cfg := t.TLSClientConfig
+	handshake := func(ctx context.Context, conn net.Conn, addr string) (net.Conn, error) {
+		c := &tls.Config{}
+		if cfg != nil {
+			c = cfg.Clone()
+		}
+		if c.ServerName == "" {
+			c.ServerName, _, _ = net.SplitHostPort(addr)
+		}
+		hctx, cancel := withTimeout(ctx, t.TLSHandshakeTimeout)
+		defer cancel()
+		tc, state, err := ClientHandshake(hctx, conn, c, h.Fingerprint)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		if h.OnHandshake != nil {
+			h.OnHandshake(state)
+		}
+		return tc, nil
+	}
+
+	if t.Proxy == nil {
+		t.DialTLSContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := dial(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			return handshake(ctx, conn, addr)
+		}
+		return
+	}
+
+	// http.Transport tunnels to https targets and shakes hands with them
+	// itself, so requests to them skip its proxy and come to
+	// DialTLSContext, which does both. It is also how the transport
+	// reaches an https:// proxy for plain-http targets.
+	proxyFor := t.Proxy
+	t.Proxy = func(r *http.Request) (*url.URL, error) {
+		if r.URL.Scheme == "https" {
+			return nil, nil
+		}
+		return proxyFor(r)
+	}
+	proxyAddr := transportProxyAddr(proxyURL)
+	dialProxy := dial
+	if proxyTLS := t.DialTLSContext; proxyTLS != nil {
+		dialProxy = proxyTLS
+	}
+	t.DialTLSContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if addr == proxyAddr {
+			return dialProxy(ctx, network, addr)
+		}
+		conn, err := dialProxy(ctx, network, proxyAddr)
+		if err != nil {
+			return nil, err
+		}
+		hctx, cancel := withTimeout(ctx, t.TLSHandshakeTimeout)
+		resp, err := ConnectTunnel(hctx, conn, addr, proxyURL.User, t.ProxyConnectHeader)
+		cancel()
+		if resp != nil && t.OnProxyConnectResponse != nil {
+			if herr := t.OnProxyConnectResponse(ctx, proxyURL, resp.Request, resp); err == nil {
+				err = herr
+			}
+		}
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return handshake(ctx, conn, addr)
+	}
+}
+
+// transportProxyAddr is the address http.Transport dials for proxyURL.
+func transportProxyAddr(proxyURL *url.URL) string {
+	port := proxyURL.Port()
+	if port == "" {
+		port = "80"
+		if proxyURL.Scheme == "https" {
+			port = "443"
+		}
+	}
+	return net.JoinHostPort(proxyURL.Hostname(), port)
+}
@@ -0,0 +1,160 @@
+package checker
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/drsoft-oss/proxybench/internal/testproxy"
+)
+
+// helloServer is an https server that records the ClientHellos it gets.
+type helloServer struct {
+	*httptest.Server
+	mu     sync.Mutex
+	hellos []*tls.ClientHelloInfo
+}
+
+func newHelloServer(t *testing.T) *helloServer {
+	t.Helper()
+	s := &helloServer{}
+	s.Server = httptest.NewUnstartedServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	s.TLS = &tls.Config{GetConfigForClient: func(chi *tls.ClientHelloInfo) (*tls.Config, error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.hellos = append(s.hellos, chi)
+		return nil, nil
+	}}
+	s.StartTLS()
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *helloServer) last() *tls.ClientHelloInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.hellos) == 0 {
+		return nil
+	}
+	return s.hellos[len(s.hellos)-1]
+}
+
+func (s *helloServer) roots() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(s.Certificate())
+	return pool
+}
+
+// greased reports whether a ClientHello offered GREASE cipher suites, as
+// Chrome's does and Go's and Firefox's do not.
+func greased(chi *tls.ClientHelloInfo) bool {
+	return slices.ContainsFunc(chi.CipherSuites, func(c uint16) bool { return c&0x0f0f == 0x0a0a })
+}
+
+// recordSizeLimit is the extension Firefox sends and Go does not.
+const recordSizeLimit = 28
+
+func TestClientHandshake(t *testing.T) {
+	srv := newHelloServer(t)
+	cases := []struct {
+		fingerprint string
+		grease      bool
+		sizeLimit   bool
+	}{
+		{HelloGo, false, false},
+		{HelloChrome, true, false},
+		{HelloFirefox, false, true},
+		{HelloRandomized, false, false},
+	}
+	for _, c := range cases {
+		conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		tc, state, err := ClientHandshake(ctx, conn, &tls.Config{ServerName: "example.com", RootCAs: srv.roots()}, c.fingerprint)
+		cancel()
+		if err != nil {
+			t.Errorf("%q: %v", c.fingerprint, err)
+			conn.Close()
+			continue
+		}
+		tc.Close()
+		if !state.HandshakeComplete || len(state.PeerCertificates) == 0 || len(state.VerifiedChains) == 0 {
+			t.Errorf("%q: state = %+v", c.fingerprint, state)
+		}
+		if state.NegotiatedProtocol == "h2" {
+			t.Errorf("%q: negotiated h2, which the transport cannot speak over it", c.fingerprint)
+		}
+		chi := srv.last()
+		if c.fingerprint == HelloRandomized {
+			continue // anything goes
+		}
+		if greased(chi) != c.grease || slices.Contains(chi.Extensions, recordSizeLimit) != c.sizeLimit {
+			t.Errorf("%q: GREASE %v, record_size_limit %v; want %v, %v", c.fingerprint, greased(chi), slices.Contains(chi.Extensions, recordSizeLimit), c.grease, c.sizeLimit)
+		}
+	}
+
+	// An untrusted certificate fails the same way whatever was sent.
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_, _, err = ClientHandshake(context.Background(), conn, &tls.Config{ServerName: "example.com"}, HelloChrome)
+	var certErr *tls.CertificateVerificationError
+	if !errors.As(err, &certErr) || len(certErr.UnverifiedCertificates) == 0 || classify(err).Kind != ErrTLS {
+		t.Errorf("untrusted certificate: err = %v", err)
+	}
+}
+
+func TestCheck_tlsFingerprint(t *testing.T) {
+	srv := newHelloServer(t)
+	proxies := map[string]string{
+		"http":   fakeHTTPProxy(t, true),
+		"socks5": "socks5://" + (&testproxy.SOCKS5{}).Start(t),
+	}
+	for name, proxy := range proxies {
+		opts := Options{Timeout: 2 * time.Second, TestURL: srv.URL, RootCAs: srv.roots(), TLSFingerprint: HelloChrome}
+		r := Check(proxy, opts)
+		if !r.Alive {
+			t.Errorf("%s: not alive: %s", name, r.Error)
+			continue
+		}
+		if chi := srv.last(); chi == nil || !greased(chi) {
+			t.Errorf("%s: the target did not get Chrome's ClientHello", name)
+		}
+		if r.TLS == nil || !r.TLS.Verified {
+			t.Errorf("%s: TLS = %+v, want the verified session", name, r.TLS)
+		}
+	}
+
+	// The CONNECT probe of a plain-http test URL sends it too.
+	opts := Options{Timeout: 2 * time.Second, TestURL: "http://example.invalid/", ConnectURL: srv.URL, TLSFingerprint: HelloFirefox}
+	r := CheckHTTP(fakeHTTPProxy(t, true), opts)
+	if r.ConnectSupported == nil || !*r.ConnectSupported || r.TLS == nil {
+		t.Fatalf("ConnectSupported = %v, TLS = %+v", r.ConnectSupported, r.TLS)
+	}
+	if chi := srv.last(); !slices.Contains(chi.Extensions, recordSizeLimit) {
+		t.Error("the CONNECT probe did not send Firefox's ClientHello")
+	}
+}
+
+func TestParseTLSFingerprint(t *testing.T) {
+	for in, want := range map[string]string{"": HelloGo, "go": HelloGo, "Chrome": HelloChrome, "ios": HelloIOS} {
+		if got, err := ParseTLSFingerprint(in); err != nil || got != want {
+			t.Errorf("ParseTLSFingerprint(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseTLSFingerprint("netscape"); err == nil {
+		t.Error("ParseTLSFingerprint(netscape): want error")
+	}
+}
@@ -0,0 +1,98 @@
+package checker
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// hopTimer wraps the dialer used to reach a proxy and remembers how long
+// the most recent dial took (DNS, TCP and any PROXY protocol header), so a
+// request's latency can be split into the proxy hop and the rest. A
+// non-zero timeout bounds each dial.
+type hopTimer struct {
+	forward proxy.ContextDialer
+	timeout time.Duration
+	took    time.Duration
+}
+
+func (h *hopTimer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	start := time.Now()
+	conn, err := ConnectDialer{Forward: h.forward, Timeout: h.timeout}.DialContext(ctx, network, addr)
+	h.took = time.Since(start)
+	return conn, err
+}
+
+func (h *hopTimer) Dial(network, addr string) (net.Conn, error) {
+	return h.DialContext(context.Background(), network, addr)
+}
+
+// handshakeClock is the context key under which HandshakeDialer leaves
+// the func that starts a dial's handshake clock.
+type handshakeClock struct{}
+
+// ConnectDialer bounds each dial of Forward by Timeout (none if zero). It
+// is the dialer a HandshakeDialer reaches its proxy with: once connected,
+// it starts the handshake clock.
+type ConnectDialer struct {
+	Forward proxy.ContextDialer
+	Timeout time.Duration
+}
+
+func (d ConnectDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	dialCtx, cancel := withTimeout(ctx, d.Timeout)
+	defer cancel()
+	conn, err := d.Forward.DialContext(dialCtx, network, addr)
+	if start, ok := ctx.Value(handshakeClock{}).(func()); ok && err == nil {
+		start()
+	}
+	return conn, err
+}
+
+func (d ConnectDialer) Dial(network, addr string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, addr)
+}
+
+// HandshakeDialer bounds the proxy handshake of each dial through Dialer
+// (a SOCKS5 dialer built on a ConnectDialer) by Timeout, counted from
+// when the connection to the proxy opened, so a slow connect does not eat
+// into it. Zero means no limit.
+type HandshakeDialer struct {
+	Dialer  proxy.Dialer
+	Timeout time.Duration
+}
+
+func (d HandshakeDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	cd, ok := d.Dialer.(proxy.ContextDialer)
+	if !ok {
+		return d.Dialer.Dial(network, addr)
+	}
+	if d.Timeout <= 0 {
+		return cd.DialContext(ctx, network, addr)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var clock *time.Timer
+	ctx = context.WithValue(ctx, handshakeClock{}, func() { clock = time.AfterFunc(d.Timeout, cancel) })
+	conn, err := cd.DialContext(ctx, network, addr)
+	if clock != nil {
+		clock.Stop()
+	}
+	return conn, err
+}
+
+func (d HandshakeDialer) Dial(network, addr string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, addr)
+}
+
+// splitLatency fills in the hop and target latencies of an alive result
+// from the dial time to the proxy.
+func splitLatency(r *Result, hop time.Duration) {
+	if !r.Alive || hop <= 0 || hop > r.Latency {
+		return
+	}
+	r.HopLatency = hop
+	r.TargetLatency = r.Latency - hop
+}
@@ -0,0 +1,37 @@
+package checker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCheckHTTP_splitsLatency(t *testing.T) {
+	r := CheckHTTP(fakeHTTPProxy(t, false), Options{Timeout: 2 * time.Second, TestURL: "http://example.invalid/", ConnectURL: "https://example.invalid/"})
+	if !r.Alive {
+		t.Fatalf("check failed: %s", r.Error)
+	}
+	if r.HopLatency <= 0 || r.HopLatency+r.TargetLatency != r.Latency {
+		t.Errorf("hop %v + target %v != latency %v", r.HopLatency, r.TargetLatency, r.Latency)
+	}
+}
+
+func TestSplitLatency(t *testing.T) {
+	cases := []struct {
+		name       string
+		r          Result
+		hop        time.Duration
+		wantHop    time.Duration
+		wantTarget time.Duration
+	}{
+		{"alive", Result{Alive: true, Latency: 300 * time.Millisecond}, 40 * time.Millisecond, 40 * time.Millisecond, 260 * time.Millisecond},
+		{"dead", Result{Latency: 300 * time.Millisecond}, 40 * time.Millisecond, 0, 0},
+		{"no dial", Result{Alive: true, Latency: 300 * time.Millisecond}, 0, 0, 0},
+		{"hop longer than request", Result{Alive: true, Latency: 10 * time.Millisecond}, 40 * time.Millisecond, 0, 0},
+	}
+	for _, tc := range cases {
+		splitLatency(&tc.r, tc.hop)
+		if tc.r.HopLatency != tc.wantHop || tc.r.TargetLatency != tc.wantTarget {
+			t.Errorf("%s: hop=%v target=%v, want %v/%v", tc.name, tc.r.HopLatency, tc.r.TargetLatency, tc.wantHop, tc.wantTarget)
+		}
+	}
+}
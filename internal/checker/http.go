@@ -1,14 +1,28 @@
 package checker
 
 import (
-	"fmt"
+	"cmp"
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
+	"sync/atomic"
 	"time"
+
+	"github.com/drsoft-oss/proxybench/internal/proxyproto"
+	"github.com/drsoft-oss/proxybench/internal/resolver"
+	"github.com/drsoft-oss/proxybench/internal/tracing"
 )
 
 // CheckHTTP validates an HTTP/HTTPS proxy by sending a real request through it.
 func CheckHTTP(address string, opts Options) Result {
+	return checkHTTP(context.Background(), address, opts)
+}
+
+func checkHTTP(ctx context.Context, address string, opts Options) Result {
 	result := Result{Address: address, Protocol: ProtocolHTTP}
 	if DetectProtocol(address) == ProtocolHTTPS {
 		result.Protocol = ProtocolHTTPS
@@ -16,18 +30,54 @@ func CheckHTTP(address string, opts Options) Result {
 
 	proxyURL, err := url.Parse(address)
 	if err != nil {
-		result.Error = fmt.Sprintf("invalid proxy URL: %v", err)
+		result.fail(PhaseParse, "invalid proxy URL", err)
+		return result
+	}
+	withCredentials(proxyURL, opts)
+	override, err := OverrideFor(proxyURL, opts.Overrides)
+	if err != nil {
+		result.fail(PhaseParse, "invalid proxy override", err)
 		return result
 	}
 
+	hop := &hopTimer{forward: proxyproto.Dialer{Forward: resolver.Default(), Version: opts.ProxyProtocol}, timeout: opts.connectTimeout()}
 	transport := &http.Transport{
 		Proxy:               http.ProxyURL(proxyURL),
+		DialContext:         hop.DialContext,
 		DisableKeepAlives:   true,
-		TLSHandshakeTimeout: opts.Timeout,
+		TLSHandshakeTimeout: opts.handshakeTimeout(),
+		TLSClientConfig:     ClientTLS(opts.RootCAs, opts.InsecureTLS),
+		// CONNECT replies (Proxy-Agent, error pages) always come from the proxy.
+		OnProxyConnectResponse: func(_ context.Context, _ *url.URL, _ *http.Request, res *http.Response) error {
+			if s := identifySoftware(res.Header, nil, true); s != "" {
+				result.Software = s
+			}
+			return nil
+		},
 	}
+	// The proxy's own certificate is verified, and its failures reported,
+	// apart from the target's.
+	var proxySession atomic.Pointer[tls.ConnectionState]
+	proxySNI := override.SNI
+	if result.Protocol == ProtocolHTTPS {
+		ProxyTLS{
+			Config:      ClientTLS(opts.RootCAs, opts.InsecureTLS || opts.ProxyInsecure),
+			ServerName:  proxySNI,
+			Fingerprint: opts.TLSFingerprint,
+			OnHandshake: func(cs tls.ConnectionState) { proxySession.CompareAndSwap(nil, &cs) },
+		}.Apply(transport, proxyURL)
+		override.SNI = "" // applied above
+	}
+	// probeConnect applies the fingerprint over TLS settings of its own.
+	plain := transport.Clone()
+	var targetSession atomic.Pointer[tls.ConnectionState]
+	ClientHello{
+		Fingerprint: opts.TLSFingerprint,
+		OnHandshake: func(cs tls.ConnectionState) { targetSession.CompareAndSwap(nil, &cs) },
+	}.Apply(transport, proxyURL)
 	client := &http.Client{
-		Transport: transport,
-		Timeout:   opts.Timeout,
+		Transport: override.Apply(transport, proxyURL),
+		Timeout:   opts.requestTimeout(),
 		// Do not follow redirects — we only care about initial response.
 		CheckRedirect: func(*http.Request, []*http.Request) error {
 			return http.ErrUseLastResponse
@@ -39,17 +89,91 @@ func CheckHTTP(address string, opts Options) Result {
 		testURL = "http://www.google.com"
 	}
 
+	req, err := opts.Request.New(ctx, testURL)
+	if err != nil {
+		result.fail(PhaseParse, "invalid test URL", err)
+		return result
+	}
+
+	// The transport dials the proxy itself; note which family it reached.
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			result.Family = resolver.Family(info.Conn.RemoteAddr())
+		},
+	}))
+
 	start := time.Now()
-	resp, err := client.Get(testURL)
+	resp, err := client.Do(tracing.WithClientTrace(req))
 	elapsed := time.Since(start)
+	result.ProxyTLS = sessionInfo(proxySession.Load(), proxyTarget(proxyURL, proxySNI), opts.RootCAs)
 
-	if err != nil {
-		result.Error = err.Error()
-		return result
+	var proxyErr *ProxyTLSError
+	if errors.As(err, &proxyErr) {
+		result.fail(PhaseHandshake, "proxy tls", proxyErr.Err)
+		result.ProxyTLS = failedSessionInfo(proxyErr.Err, proxyTarget(proxyURL, proxySNI), opts.RootCAs)
+	} else if err != nil {
+		phase := PhaseRequest
+		if result.Family == "" {
+			phase = PhaseConnect // never reached the proxy
+		}
+		result.fail(phase, "", err)
+		result.TLS = failedSessionInfo(err, testURL, opts.RootCAs)
+	} else {
+		var body []byte
+		own := proxyGenerated(resp.StatusCode)
+		if own {
+			body, _ = io.ReadAll(io.LimitReader(resp.Body, 4096))
+		}
+		resp.Body.Close()
+		if s := identifySoftware(resp.Header, body, own); s != "" {
+			result.Software = s
+		}
+		result.Alive = true
+		result.TLS = sessionInfo(cmp.Or(resp.TLS, targetSession.Load()), testURL, opts.RootCAs)
+		result.StatusCode = resp.StatusCode
+		result.Latency = elapsed
+		splitLatency(&result, hop.took)
+		if opts.MTUURL != "" {
+			result.PMTUBlackhole = probeMTU(ctx, client, opts.MTUURL, opts.Timeout)
+		}
+		if opts.ExitIPURL != "" {
+			result.ExitIP = probeExitIP(ctx, client, opts.ExitIPURL)
+		}
+		if opts.RotationURL != "" {
+			result.ExitIPs, result.Rotating = probeRotation(ctx, client, opts.RotationURL, opts.RotationSamples, opts.RotationInterval)
+		}
+		if opts.DNSCanaryURL != "" {
+			result.DNSCanary = probeDNS(ctx, client, opts.DNSCanaryURL, opts.DNSCanaryExpect)
+		}
+		if opts.Judges != nil {
+			result.Anonymity = probeAnonymity(ctx, client, opts.Judges)
+		}
+		if opts.ContentRef != nil {
+			result.ContentModified, result.ContentDiff = probeContent(ctx, client, opts.ContentRef)
+		}
 	}
-	resp.Body.Close()
 
-	result.Alive = true
-	result.Latency = elapsed
+	// Many proxies forward plain GETs but refuse CONNECT (or the reverse),
+	// so tunnelling is reported on its own whenever the proxy was reached.
+	if result.Family != "" {
+		ok := result.Alive
+		if !tunnelsTestURL(testURL) {
+			var err error
+			target := connectTarget(opts)
+			result.TLS, err = probeConnect(ctx, plain, proxyURL, client, target, opts)
+			ok = err == nil
+			if !ok && result.Alive {
+				result.HTTPOnly = true
+				if opts.RequireConnect {
+					result.Alive = false
+					result.fail(PhaseRequest, "http-only: CONNECT to "+target, err)
+					if result.Failure.Kind == ErrUnknown { // the proxy's refusal, e.g. "Method Not Allowed"
+						result.Failure.Kind, result.Failure.Phase = ErrProtocol, PhaseHandshake
+					}
+				}
+			}
+		}
+		result.ConnectSupported = &ok
+	}
 	return result
 }
@@ -0,0 +1,467 @@
+package checker
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	mrand "math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+	"github.com/quic-go/quic-go/quicvarint"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/net/proxy"
+
+	"github.com/drsoft-oss/proxybench/internal/resolver"
+	"github.com/drsoft-oss/proxybench/internal/tracing"
+)
+
+// Hysteria2Config holds parsed Hysteria2 connection parameters.
+type Hysteria2Config struct {
+	Host string
+	Port string // the first port of a port-hopping range
+	Auth string // "password" or "user:password"
+	// Obfs is "" or "salamander", which needs ObfsPassword.
+	Obfs         string
+	ObfsPassword string
+	SNI          string
+	Insecure     bool
+	PinSHA256    string
+}
+
+// ParseHysteria2URL parses a hy2:// or hysteria2:// link:
+//
+//	hy2://auth@host:port/?obfs=salamander&obfs-password=...&sni=...&insecure=1
+//
+// The port defaults to 443. A port-hopping list such as "443,5000-6000"
+// is accepted and its first port used, as every port in it reaches the
+// same server.
+func ParseHysteria2URL(rawURL string) (Hysteria2Config, error) {
+	var cfg Hysteria2Config
+	scheme, rest, ok := strings.Cut(strings.TrimSpace(rawURL), "://")
+	if !ok || (!strings.EqualFold(scheme, "hy2") && !strings.EqualFold(scheme, "hysteria2")) {
+		return cfg, fmt.Errorf("not a hy2:// link")
+	}
+	rest, _, _ = strings.Cut(rest, "#")
+	rest, query, _ := strings.Cut(rest, "?")
+	rest = strings.TrimSuffix(rest, "/")
+	if at := strings.LastIndex(rest, "@"); at != -1 {
+		auth, err := url.PathUnescape(rest[:at])
+		if err != nil {
+			return cfg, fmt.Errorf("auth: %w", err)
+		}
+		cfg.Auth, rest = auth, rest[at+1:]
+	}
+
+	cfg.Host, cfg.Port = rest, "443"
+	if h, ports, err := net.SplitHostPort(rest); err == nil {
+		first, _, _ := strings.Cut(ports, ",")
+		first, _, _ = strings.Cut(first, "-")
+		cfg.Host, cfg.Port = h, first
+	}
+	cfg.Host = strings.Trim(cfg.Host, "[]")
+	if cfg.Host == "" {
+		return cfg, fmt.Errorf("missing host")
+	}
+	if p, err := strconv.Atoi(cfg.Port); err != nil || p < 1 || p > 65535 {
+		return cfg, fmt.Errorf("invalid port %q", cfg.Port)
+	}
+
+	params, err := url.ParseQuery(query)
+	if err != nil {
+		return cfg, fmt.Errorf("parameters: %w", err)
+	}
+	cfg.Obfs, cfg.ObfsPassword = params.Get("obfs"), params.Get("obfs-password")
+	cfg.SNI, cfg.PinSHA256 = params.Get("sni"), params.Get("pinSHA256")
+	cfg.Insecure = params.Get("insecure") == "1" || params.Get("insecure") == "true"
+	switch cfg.Obfs {
+	case "", "plain":
+		cfg.Obfs = ""
+	case "salamander":
+		if cfg.ObfsPassword == "" {
+			return cfg, fmt.Errorf("salamander obfs without obfs-password")
+		}
+	default:
+		return cfg, fmt.Errorf("unsupported obfs %q", cfg.Obfs)
+	}
+	return cfg, nil
+}
+
+// salamanderSaltLen is the length of the random salt Salamander puts in
+// front of every datagram.
+const salamanderSaltLen = 8
+
+// salamander obfuscates a datagram the way Hysteria2's Salamander obfs
+// does: a random salt, then p XORed with BLAKE2b-256(password || salt).
+func salamander(password string, p []byte) []byte {
+	out := make([]byte, salamanderSaltLen, salamanderSaltLen+len(p))
+	rand.Read(out) //nolint:errcheck // never fails
+	key := blake2b.Sum256(append([]byte(password), out...))
+	for i, b := range p {
+		out = append(out, b^key[i%len(key)])
+	}
+	return out
+}
+
+// unsalamander reverses salamander, returning nil for a datagram too short
+// to carry a salt.
+func unsalamander(password string, p []byte) []byte {
+	if len(p) <= salamanderSaltLen {
+		return nil
+	}
+	key := blake2b.Sum256(append([]byte(password), p[:salamanderSaltLen]...))
+	out := make([]byte, len(p)-salamanderSaltLen)
+	for i, b := range p[salamanderSaltLen:] {
+		out[i] = b ^ key[i%len(key)]
+	}
+	return out
+}
+
+// The Hysteria2 protocol: a client authenticates with an HTTP/3 POST the
+// server answers with a status of its own, then opens each TCP connection
+// as a QUIC stream that starts with a TCP request frame.
+const (
+	hy2AuthURL         = "https://hysteria/auth"
+	hy2StatusAuthOK    = 233
+	hy2FrameTCPRequest = 0x401
+	hy2MaxMessageLen   = 2048
+	hy2MaxPaddingLen   = 4096
+)
+
+// Hysteria2Dialer returns a dialer that opens TCP connections through the
+// Hysteria2 server of cfg, each over its own authenticated QUIC connection.
+// The server's certificate is checked against roots unless the link says
+// insecure=1 or insecure is set; a pinSHA256 in the link replaces the
+// check. forward dials the UDP socket to the server.
+func Hysteria2Dialer(cfg Hysteria2Config, roots *x509.CertPool, insecure bool, forward proxy.ContextDialer) proxy.Dialer {
+	tlsConf := &tls.Config{
+		ServerName:         cmp.Or(cfg.SNI, cfg.Host),
+		RootCAs:            roots,
+		InsecureSkipVerify: cfg.Insecure || insecure, //nolint:gosec // as the link or --proxy-insecure asks
+		NextProtos:         []string{http3.NextProtoH3},
+	}
+	if pin := strings.NewReplacer(":", "", "-", "").Replace(strings.ToLower(cfg.PinSHA256)); pin != "" {
+		tlsConf.InsecureSkipVerify = true //nolint:gosec // the pin is checked instead
+		tlsConf.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			for _, der := range rawCerts {
+				if sum := sha256.Sum256(der); hex.EncodeToString(sum[:]) == pin {
+					return nil
+				}
+			}
+			return errors.New("tls: no certificate matches pinSHA256")
+		}
+	}
+	obfs := ""
+	if cfg.Obfs == "salamander" {
+		obfs = cfg.ObfsPassword
+	}
+	return hy2Dialer{
+		addr:    net.JoinHostPort(cfg.Host, cfg.Port),
+		auth:    cfg.Auth,
+		obfs:    obfs,
+		tls:     tlsConf,
+		forward: forward,
+	}
+}
+
+type hy2Dialer struct {
+	addr    string
+	auth    string
+	obfs    string // the Salamander password, if any
+	tls     *tls.Config
+	forward proxy.ContextDialer
+}
+
+func (d hy2Dialer) Dial(network, addr string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, addr)
+}
+
+func (d hy2Dialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	conn, err := d.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	str, err := conn.OpenStreamSync(ctx)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	c := &hy2Conn{Stream: str, quic: conn}
+	stop := context.AfterFunc(ctx, func() { str.SetDeadline(time.Now()) })
+	err = hy2TCPRequest(str, addr)
+	if !stop() {
+		err = ctx.Err()
+	}
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// connect opens a QUIC connection to the server and authenticates on it.
+func (d hy2Dialer) connect(ctx context.Context) (*hy2QUIC, error) {
+	udp, err := d.forward.DialContext(ctx, "udp", d.addr)
+	if err != nil {
+		return nil, err
+	}
+	qc, err := quic.Dial(ctx, &hy2PacketConn{Conn: udp, obfs: d.obfs}, udp.RemoteAddr(), d.tls, nil)
+	if err != nil {
+		udp.Close()
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			err = fmt.Errorf("no reply, so a wrong obfs password or not a Hysteria2 server: %w", err)
+		}
+		return nil, fmt.Errorf("quic: %w", err)
+	}
+	conn := &hy2QUIC{Conn: qc, udp: udp}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hy2AuthURL, nil)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	req.Header.Set("Hysteria-Auth", d.auth)
+	req.Header.Set("Hysteria-CC-RX", "0") // the receive rate is unknown
+	req.Header.Set("Hysteria-Padding", hy2Padding(256, 2048))
+	resp, err := (&http3.Transport{}).NewClientConn(qc).RoundTrip(req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("hysteria2 auth: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != hy2StatusAuthOK {
+		conn.Close()
+		return nil, fmt.Errorf("hysteria2 authentication rejected (HTTP %d)", resp.StatusCode)
+	}
+	return conn, nil
+}
+
+// hy2TCPRequest asks the server for a TCP connection to addr on str and
+// reads its answer.
+func hy2TCPRequest(str io.ReadWriter, addr string) error {
+	padding := hy2Padding(64, 512)
+	req := quicvarint.Append(nil, hy2FrameTCPRequest)
+	req = quicvarint.Append(req, uint64(len(addr)))
+	req = append(req, addr...)
+	req = quicvarint.Append(req, uint64(len(padding)))
+	req = append(req, padding...)
+	if _, err := str.Write(req); err != nil {
+		return err
+	}
+
+	r := quicvarint.NewReader(str)
+	status, err := r.ReadByte()
+	if err != nil {
+		return err
+	}
+	msgLen, err := quicvarint.Read(r)
+	if err != nil {
+		return err
+	}
+	if msgLen > hy2MaxMessageLen {
+		return fmt.Errorf("hysteria2: malformed TCP response (message of %d bytes)", msgLen)
+	}
+	msg := make([]byte, msgLen)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return err
+	}
+	padLen, err := quicvarint.Read(r)
+	if err != nil {
+		return err
+	}
+	if padLen > hy2MaxPaddingLen {
+		return fmt.Errorf("hysteria2: malformed TCP response (padding of %d bytes)", padLen)
+	}
+	if _, err := io.CopyN(io.Discard, r, int64(padLen)); err != nil {
+		return err
+	}
+	if status != 0 {
+		return fmt.Errorf("hysteria2 server could not connect to %s: %s", addr, msg)
+	}
+	return nil
+}
+
+// hy2Padding returns between min and max-1 random letters, which Hysteria2
+// puts in its requests so their sizes vary.
+func hy2Padding(min, max int) string {
+	const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	b := make([]byte, min+mrand.IntN(max-min))
+	for i := range b {
+		b[i] = letters[mrand.IntN(len(letters))]
+	}
+	return string(b)
+}
+
+// hy2QUIC is a QUIC connection to a Hysteria2 server that closes its UDP
+// socket with it.
+type hy2QUIC struct {
+	*quic.Conn
+	udp net.Conn
+}
+
+func (c *hy2QUIC) Close() error {
+	err := c.CloseWithError(0, "")
+	c.udp.Close()
+	return err
+}
+
+// hy2Conn is a TCP stream through a Hysteria2 server that closes its QUIC
+// connection with it.
+type hy2Conn struct {
+	*quic.Stream
+	quic *hy2QUIC
+}
+
+func (c *hy2Conn) LocalAddr() net.Addr  { return c.quic.LocalAddr() }
+func (c *hy2Conn) RemoteAddr() net.Addr { return c.quic.RemoteAddr() }
+
+func (c *hy2Conn) Close() error {
+	c.CancelRead(0)
+	c.Stream.Close()
+	return c.quic.Close()
+}
+
+// hy2PacketConn is the connected UDP socket to a Hysteria2 server as the
+// net.PacketConn quic-go reads and writes, Salamander-obfuscating each
+// datagram when obfs is set.
+type hy2PacketConn struct {
+	net.Conn
+	obfs string
+	buf  []byte
+}
+
+func (c *hy2PacketConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	if c.obfs == "" {
+		n, err := c.Read(p)
+		return n, c.RemoteAddr(), err
+	}
+	if c.buf == nil {
+		c.buf = make([]byte, 64<<10)
+	}
+	for {
+		n, err := c.Read(c.buf)
+		if err != nil {
+			return 0, nil, err
+		}
+		if p2 := unsalamander(c.obfs, c.buf[:n]); p2 != nil {
+			return copy(p, p2), c.RemoteAddr(), nil
+		}
+	}
+}
+
+// SetReadBuffer and SetWriteBuffer let quic-go size the UDP socket's
+// buffers as it does its own.
+func (c *hy2PacketConn) SetReadBuffer(bytes int) error {
+	if udp, ok := c.Conn.(*net.UDPConn); ok {
+		return udp.SetReadBuffer(bytes)
+	}
+	return errors.ErrUnsupported
+}
+
+func (c *hy2PacketConn) SetWriteBuffer(bytes int) error {
+	if udp, ok := c.Conn.(*net.UDPConn); ok {
+		return udp.SetWriteBuffer(bytes)
+	}
+	return errors.ErrUnsupported
+}
+
+func (c *hy2PacketConn) WriteTo(p []byte, _ net.Addr) (int, error) {
+	if c.obfs == "" {
+		return c.Write(p)
+	}
+	if _, err := c.Write(salamander(c.obfs, p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// CheckHysteria2 validates a Hysteria2 server: it authenticates with the
+// link's auth string and fetches the test URL through the tunnel.
+func CheckHysteria2(address string, opts Options) Result {
+	return checkHysteria2(context.Background(), address, opts)
+}
+
+func checkHysteria2(ctx context.Context, address string, opts Options) Result {
+	result := Result{Address: address, Protocol: ProtocolHysteria2}
+
+	cfg, err := ParseHysteria2URL(address)
+	if err != nil {
+		result.fail(PhaseParse, "parse", err)
+		return result
+	}
+
+	dialer := Hysteria2Dialer(cfg, opts.RootCAs, opts.ProxyInsecure, ConnectDialer{Forward: resolver.Default(), Timeout: opts.connectTimeout()})
+	transport := &http.Transport{
+		DialContext:       tracedDial(HandshakeDialer{Dialer: dialer, Timeout: opts.handshakeTimeout()}),
+		DisableKeepAlives: true,
+		TLSClientConfig:   ClientTLS(opts.RootCAs, opts.InsecureTLS),
+	}
+	var targetSession atomic.Pointer[tls.ConnectionState]
+	ClientHello{
+		Fingerprint: opts.TLSFingerprint,
+		OnHandshake: func(cs tls.ConnectionState) { targetSession.CompareAndSwap(nil, &cs) },
+	}.Apply(transport, nil)
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   opts.requestTimeout(),
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	testURL := opts.TestURL
+	if testURL == "" {
+		testURL = "http://www.google.com"
+	}
+	req, err := opts.Request.New(ctx, testURL)
+	if err != nil {
+		result.fail(PhaseParse, "invalid test URL", err)
+		return result
+	}
+
+	start := time.Now()
+	resp, err := client.Do(tracing.WithClientTrace(req))
+	elapsed := time.Since(start)
+	if err != nil {
+		result.fail(PhaseRequest, "forward check", err)
+		result.TLS = failedSessionInfo(err, testURL, opts.RootCAs)
+		return result
+	}
+	resp.Body.Close()
+	result.TLS = sessionInfo(cmp.Or(resp.TLS, targetSession.Load()), testURL, opts.RootCAs)
+
+	result.Alive = true
+	result.StatusCode = resp.StatusCode
+	result.Latency = elapsed
+	if net.ParseIP(req.URL.Hostname()) == nil {
+		result.DNSResolution = ResolveRemote // the TCP request carries the hostname
+	}
+	if opts.ExitIPURL != "" {
+		result.ExitIP = probeExitIP(ctx, client, opts.ExitIPURL)
+	}
+	if opts.RotationURL != "" {
+		result.ExitIPs, result.Rotating = probeRotation(ctx, client, opts.RotationURL, opts.RotationSamples, opts.RotationInterval)
+	}
+	if opts.Judges != nil {
+		result.Anonymity = probeAnonymity(ctx, client, opts.Judges)
+	}
+	if opts.ContentRef != nil {
+		result.ContentModified, result.ContentDiff = probeContent(ctx, client, opts.ContentRef)
+	}
+	return result
+}
@@ -0,0 +1,104 @@
+package checker
+
+import (
+	"bytes"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/drsoft-oss/proxybench/internal/testproxy"
+)
+
+func TestParseHysteria2URL(t *testing.T) {
+	cfg, err := ParseHysteria2URL("hysteria2://us%3Aer:pa@[2001:db8::1]:443,5000-6000/?obfs=salamander&obfs-password=CaseKept&sni=cdn.example&insecure=1#Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Hysteria2Config{
+		Host: "2001:db8::1", Port: "443", Auth: "us:er:pa",
+		Obfs: "salamander", ObfsPassword: "CaseKept", SNI: "cdn.example", Insecure: true,
+	}
+	if cfg != want {
+		t.Errorf("got %+v, want %+v", cfg, want)
+	}
+
+	cfg, err = ParseHysteria2URL("hy2://secret@h.example")
+	if err != nil || cfg.Port != "443" || cfg.Auth != "secret" || cfg.Obfs != "" {
+		t.Errorf("got %+v, %v; want port 443, auth secret, no obfs", cfg, err)
+	}
+
+	for _, bad := range []string{
+		"ss://h.example:443",
+		"hy2://",
+		"hy2://h.example:0",
+		"hy2://h.example:443?obfs=salamander",
+		"hy2://h.example:443?obfs=xor&obfs-password=x",
+	} {
+		if _, err := ParseHysteria2URL(bad); err == nil {
+			t.Errorf("ParseHysteria2URL(%q): want error", bad)
+		}
+	}
+}
+
+func TestSalamander(t *testing.T) {
+	p := bytes.Repeat([]byte("quic"), 300)
+	obfs := salamander("pw", p)
+	if len(obfs) != salamanderSaltLen+len(p) || bytes.Contains(obfs, []byte("quicquic")) {
+		t.Fatalf("salamander left the payload readable: % x…", obfs[:16])
+	}
+	if got := unsalamander("pw", obfs); !bytes.Equal(got, p) {
+		t.Error("unsalamander did not restore the payload")
+	}
+	if got := unsalamander("other", obfs); bytes.Equal(got, p) {
+		t.Error("unsalamander with the wrong password restored the payload")
+	}
+}
+
+func TestCheckHysteria2(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	defer target.Close()
+	plain := &testproxy.Hysteria2{Auth: "secret"}
+	plain.Start(t)
+	obfs := &testproxy.Hysteria2{Obfs: "s3cret"}
+	obfs.Start(t)
+	roots := x509.NewCertPool()
+	roots.AddCert(plain.Certificate())
+
+	for _, tt := range []struct {
+		name, addr string
+		roots      *x509.CertPool
+		kind       ErrorKind // "" for alive
+	}{
+		{name: "pinned", addr: plain.Link("secret")},
+		{name: "trusted", addr: strings.Split(plain.Link("secret"), "?")[0], roots: roots},
+		{name: "salamander", addr: obfs.Link("any")},
+		{name: "wrong auth", addr: plain.Link("guess"), kind: ErrAuth},
+		{name: "untrusted", addr: strings.Split(plain.Link("secret"), "?")[0], kind: ErrTLS},
+		{name: "wrong obfs password", addr: strings.Replace(obfs.Link("any"), "s3cret", "nope", 1), kind: ErrTimeout},
+		{name: "missing obfs", addr: strings.Split(obfs.Link("any"), "&")[0], kind: ErrTimeout},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultOptions()
+			opts.TestURL, opts.RootCAs = target.URL, tt.roots
+			opts.Timeout = 2 * time.Second
+			if tt.kind == ErrTimeout {
+				opts.Timeout = 300 * time.Millisecond
+			}
+			r := CheckHysteria2(tt.addr, opts)
+			if r.Protocol != ProtocolHysteria2 || r.Alive != (tt.kind == "") {
+				t.Fatalf("CheckHysteria2 = %s alive=%v (%s), want hy2 alive=%v", r.Protocol, r.Alive, r.Error, tt.kind == "")
+			}
+			if r.Alive && r.StatusCode != http.StatusTeapot {
+				t.Errorf("StatusCode = %d, want %d from the test URL", r.StatusCode, http.StatusTeapot)
+			}
+			if !r.Alive && r.Failure.Kind != tt.kind {
+				t.Errorf("failure kind = %s (%s), want %s", r.Failure.Kind, r.Error, tt.kind)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package checker
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/drsoft-oss/proxybench/internal/resolver"
+	"github.com/drsoft-oss/proxybench/internal/tracing"
+)
+
+// ProtocolSOCKS4 is reported by Identify only; SOCKS4 proxies are not
+// checked.
+const ProtocolSOCKS4 Protocol = "socks4"
+
+// What Identify found at an address, in Identity.Kind.
+const (
+	KindProxy   = "proxy"   // speaks at least one proxy protocol
+	KindTLS     = "tls"     // a TLS service that does not speak HTTP inside
+	KindService = "service" // answers, but not as a proxy
+	KindSilent  = "silent"  // accepts connections but answers no probe
+	KindClosed  = "closed"  // refuses connections or does not respond
+)
+
+// Identity is the outcome of Identify for one host:port.
+type Identity struct {
+	Address string `json:"address"`
+	Kind    string `json:"kind"`
+	// Protocols lists every proxy protocol the listener answered, most
+	// capable first: some servers accept SOCKS5 and HTTP on one port.
+	Protocols []Protocol `json:"protocols,omitempty"`
+	// Proxies is Address qualified with each of Protocols' schemes.
+	Proxies []string `json:"proxies,omitempty"`
+	// Banner describes a listener that is not a proxy (see grabBanner).
+	Banner string `json:"banner,omitempty"`
+}
+
+// socks4Probe is a SOCKS4 CONNECT to 0.0.0.0:0, which a SOCKS4 server
+// rejects (reply 0x5b) without connecting anywhere.
+var socks4Probe = []byte{4, 1, 0, 0, 0, 0, 0, 0, 0}
+
+// identifyProbes are tried in parallel, each on its own connection, in
+// the order Identity.Protocols reports them.
+var identifyProbes = []struct {
+	proto  Protocol
+	speaks func(ctx context.Context, hostPort string, timeout time.Duration) (ok, reached bool)
+}{
+	{ProtocolSOCKS5, func(ctx context.Context, hostPort string, timeout time.Duration) (bool, bool) {
+		reply, err := exchange(ctx, hostPort, socks5Greeting, timeout)
+		return len(reply) > 0 && reply[0] == socks5Version, err == nil
+	}},
+	{ProtocolSOCKS4, func(ctx context.Context, hostPort string, timeout time.Duration) (bool, bool) {
+		reply, err := exchange(ctx, hostPort, socks4Probe, timeout)
+		return len(reply) >= 2 && reply[0] == 0 && reply[1] >= 0x5a && reply[1] <= 0x5d, err == nil
+	}},
+	{ProtocolHTTPS, func(ctx context.Context, hostPort string, timeout time.Duration) (bool, bool) {
+		reply, err := tlsExchange(ctx, hostPort, httpProbe, timeout)
+		return bytes.HasPrefix(reply, []byte("HTTP/")), err == nil
+	}},
+	{ProtocolHTTP, func(ctx context.Context, hostPort string, timeout time.Duration) (bool, bool) {
+		reply, err := exchange(ctx, hostPort, httpProbe, timeout)
+		return bytes.HasPrefix(reply, []byte("HTTP/")), err == nil
+	}},
+}
+
+// Identify fingerprints whatever listens at hostPort by sending a SOCKS5
+// greeting, a SOCKS4 request, and an HTTP request in the clear and over
+// TLS, all at once on separate connections. Any HTTP reply counts, so web
+// servers are reported as HTTP proxies too; a check tells them apart.
+// Shadowsocks cannot be recognised without its key: such a server
+// answers nothing and is reported as KindSilent.
+func Identify(ctx context.Context, hostPort string, timeout time.Duration) Identity {
+	ctx, span := tracing.Start(ctx, "identify", trace.WithAttributes(attribute.String("net.peer", hostPort)))
+	defer span.End()
+
+	id := Identity{Address: hostPort}
+	speaks := make([]bool, len(identifyProbes))
+	var reached bool
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i, p := range identifyProbes {
+		wg.Go(func() {
+			ok, dialed := p.speaks(ctx, hostPort, timeout)
+			mu.Lock()
+			speaks[i], reached = ok, reached || dialed
+			mu.Unlock()
+		})
+	}
+	wg.Wait()
+	for i, p := range identifyProbes {
+		if speaks[i] {
+			id.Protocols = append(id.Protocols, p.proto)
+		}
+	}
+	if slices.Contains(id.Protocols, ProtocolHTTPS) {
+		// TLS servers answer plain HTTP with an error page of their own.
+		id.Protocols = slices.DeleteFunc(id.Protocols, func(p Protocol) bool { return p == ProtocolHTTP })
+	}
+	for _, p := range id.Protocols {
+		id.Proxies = append(id.Proxies, string(p)+"://"+hostPort)
+	}
+
+	switch {
+	case len(id.Protocols) > 0:
+		id.Kind = KindProxy
+	case !reached || ctx.Err() != nil:
+		id.Kind = KindClosed
+	default:
+		id.Banner = grabBanner(ctx, hostPort, timeout)
+		switch {
+		case strings.HasPrefix(id.Banner, "TLS "):
+			id.Kind = KindTLS
+		case id.Banner != "":
+			id.Kind = KindService
+		default:
+			id.Kind = KindSilent
+		}
+	}
+	span.SetAttributes(attribute.String("identify.kind", id.Kind))
+	return id
+}
+
+// tlsExchange is exchange inside a TLS session. The certificate is not
+// verified: the point is what the server speaks, not whether to trust it.
+// Only a failed dial or handshake is an error.
+func tlsExchange(ctx context.Context, hostPort string, probe []byte, timeout time.Duration) ([]byte, error) {
+	ctx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+	conn, err := resolver.Default().DialContext(ctx, "tcp", hostPort)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) }) //nolint:errcheck
+	defer stop()
+	host, _, _ := net.SplitHostPort(hostPort)
+	tc := tls.Client(conn, &tls.Config{
+		ServerName:         host,
+		NextProtos:         []string{"http/1.1"},
+		InsecureSkipVerify: true, //nolint:gosec // identifying the server, not trusting it
+	})
+	if err := tc.Handshake(); err != nil {
+		return nil, err
+	}
+	if _, err := tc.Write(probe); err != nil {
+		return nil, nil
+	}
+	buf := make([]byte, 5)
+	n, _ := io.ReadFull(tc, buf)
+	return buf[:n], nil
+}
@@ -0,0 +1,105 @@
+package checker
+
+import (
+	"crypto/tls"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serve accepts connections on a loopback port until the test ends and
+// hands each to handle.
+func serve(t *testing.T, handle func(net.Conn)) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				handle(c)
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestIdentify(t *testing.T) {
+	// A port that speaks SOCKS5 and HTTP, as "mixed" listeners do.
+	mixed := serve(t, func(c net.Conn) {
+		b := make([]byte, 3)
+		io.ReadFull(c, b) //nolint:errcheck
+		if b[0] == socks5Version {
+			c.Write([]byte{5, socks5AuthNone}) //nolint:errcheck
+		} else {
+			c.Write([]byte("HTTP/1.0 400 Bad Request\r\n\r\n")) //nolint:errcheck
+		}
+	})
+	socks4 := serve(t, func(c net.Conn) {
+		b := make([]byte, 9)
+		io.ReadFull(c, b) //nolint:errcheck
+		if b[0] == 4 {
+			c.Write([]byte{0, 0x5b, 0, 0, 0, 0, 0, 0}) //nolint:errcheck
+		}
+	})
+	web := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(web.Close)
+	secure := httptest.NewUnstartedServer(http.NotFoundHandler())
+	secure.Config.ErrorLog = log.New(io.Discard, "", 0)
+	secure.StartTLS()
+	t.Cleanup(secure.Close)
+	// TLS around something that is not HTTP.
+	tlsOnly := serve(t, func(c net.Conn) {
+		tc := tls.Server(c, secure.TLS)
+		io.Copy(io.Discard, tc) //nolint:errcheck
+	})
+	ssh := serve(t, func(c net.Conn) {
+		c.Write([]byte("SSH-2.0-OpenSSH_9.6\r\n")) //nolint:errcheck
+	})
+	silent := serve(t, func(c net.Conn) {
+		io.Copy(io.Discard, c) //nolint:errcheck
+	})
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	closed := ln.Addr().String()
+	ln.Close()
+
+	cases := []struct {
+		name, addr string
+		kind       string
+		protocols  []Protocol
+	}{
+		{"socks5 and http", mixed, KindProxy, []Protocol{ProtocolSOCKS5, ProtocolHTTP}},
+		{"socks4", socks4, KindProxy, []Protocol{ProtocolSOCKS4}},
+		{"http", strings.TrimPrefix(web.URL, "http://"), KindProxy, []Protocol{ProtocolHTTP}},
+		{"https", strings.TrimPrefix(secure.URL, "https://"), KindProxy, []Protocol{ProtocolHTTPS}},
+		{"tls", tlsOnly, KindTLS, nil},
+		{"service", ssh, KindService, nil},
+		{"silent", silent, KindSilent, nil},
+		{"closed", closed, KindClosed, nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			id := Identify(t.Context(), tc.addr, 500*time.Millisecond)
+			if id.Kind != tc.kind || !slices.Equal(id.Protocols, tc.protocols) {
+				t.Errorf("Identify = %s %v (%q), want %s %v", id.Kind, id.Protocols, id.Banner, tc.kind, tc.protocols)
+			}
+			if len(id.Proxies) != len(tc.protocols) || (len(id.Proxies) > 0 && id.Proxies[0] != string(tc.protocols[0])+"://"+tc.addr) {
+				t.Errorf("Proxies = %v", id.Proxies)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/drsoft-oss/proxybench/internal/tracing"
+)
+
+const (
+	// mtuSegment is about the largest TCP payload that survives common
+	// tunnel and PPPoE overheads; bodies shorter than two of them may
+	// never have needed a full-size segment.
+	mtuSegment = 1400
+	// mtuEnough is how much of the body proves large segments get through.
+	mtuEnough = 64 << 10
+	// mtuStall is the longest the body may go without progress.
+	mtuStall = 5 * time.Second
+)
+
+// probeMTU fetches target, a URL with a large response, through client
+// and reports whether the transfer stalled: the telltale of a path MTU
+// blackhole, where full-size segments are dropped and the ICMP "fragmentation
+// needed" that should shrink them never arrives. Small requests, like the
+// forward check, still pass. The result is nil when the probe proves
+// nothing: the request failed outright or the body was too short.
+func probeMTU(ctx context.Context, client *http.Client, target string, timeout time.Duration) *bool {
+	ctx, span := tracing.Start(ctx, "mtu")
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var stalled atomic.Bool
+	watchdog := time.AfterFunc(timeout, func() {
+		stalled.Store(true)
+		cancel()
+	})
+	defer watchdog.Stop()
+
+	c := *client
+	c.Timeout = 0 // the watchdog bounds the request instead
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		tracing.End(span, err)
+		return nil
+	}
+	resp, err := c.Do(tracing.WithClientTrace(req))
+	if err != nil {
+		tracing.End(span, err)
+		return stallVerdict(stalled.Load())
+	}
+	defer resp.Body.Close()
+
+	stall := min(timeout, mtuStall)
+	buf := make([]byte, 32<<10)
+	n := 0
+	for n < mtuEnough {
+		watchdog.Reset(stall)
+		m, err := resp.Body.Read(buf)
+		n += m
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			tracing.End(span, err)
+			return stallVerdict(stalled.Load())
+		}
+	}
+	tracing.End(span, nil)
+	if n < 2*mtuSegment {
+		return nil
+	}
+	ok := false
+	return &ok
+}
+
+// stallVerdict is the outcome of a failed probe: a blackhole if it was
+// the watchdog that ended it, unknown otherwise.
+func stallVerdict(stalled bool) *bool {
+	if !stalled {
+		return nil
+	}
+	return &stalled
+}
@@ -0,0 +1,63 @@
+package checker
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestCheck_mtuProbe runs the probe through an HTTP proxy that answers
+// everything itself: /big in full, /stall with a few segments and then
+// nothing, /small with a body too short to tell.
+func TestCheck_mtuProbe(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/big":
+			w.Write(bytes.Repeat([]byte("x"), 100<<10)) //nolint:errcheck
+		case "/stall":
+			w.Write(bytes.Repeat([]byte("x"), 3000)) //nolint:errcheck
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		case "/small":
+			w.Write([]byte("ok")) //nolint:errcheck
+		}
+	}))
+	defer srv.Close()
+
+	cases := []struct {
+		path        string
+		want        *bool
+		wantWarning bool
+	}{
+		{"/big", new(bool), false},
+		{"/stall", ptr(true), true},
+		{"/small", nil, false},
+	}
+	for _, c := range cases {
+		opts := Options{Timeout: time.Second, TestURL: "http://example.invalid/", ConnectURL: "http://example.invalid/", MTUURL: "http://example.invalid" + c.path}
+		r := Check(srv.URL, opts)
+		if !r.Alive {
+			t.Fatalf("%s: forward check failed: %s", c.path, r.Error)
+		}
+		switch {
+		case (r.PMTUBlackhole == nil) != (c.want == nil),
+			c.want != nil && *r.PMTUBlackhole != *c.want:
+			t.Errorf("%s: PMTUBlackhole = %v, want %v", c.path, fmtBool(r.PMTUBlackhole), fmtBool(c.want))
+		}
+		if strings.Contains(r.Warning, "MTU") != c.wantWarning {
+			t.Errorf("%s: Warning = %q", c.path, r.Warning)
+		}
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
+
+func fmtBool(b *bool) any {
+	if b == nil {
+		return "nil"
+	}
+	return *b
+}
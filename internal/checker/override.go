@@ -0,0 +1,164 @@
+package checker
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Override changes what a proxy is sent, for proxies that only answer
+// when it matches: SNI is the TLS server name used with an https://
+// proxy, e.g. one reached by IP behind a CDN that routes on it, and Host
+// the Host header of requests sent through the proxy.
+//
+// An address carries its own in the URL fragment,
+// "https://203.0.113.7:443#sni=cdn.example&host=app.example"; see also
+// LoadOverrides.
+type Override struct {
+	SNI  string
+	Host string
+}
+
+// ParseOverride parses "sni=name&host=name", either part optional.
+func ParseOverride(s string) (Override, error) {
+	var o Override
+	for _, kv := range strings.Split(s, "&") {
+		if kv == "" {
+			continue
+		}
+		k, v, _ := strings.Cut(kv, "=")
+		v, err := url.QueryUnescape(v)
+		if err != nil {
+			return Override{}, fmt.Errorf("%s: %w", k, err)
+		}
+		switch strings.ToLower(k) {
+		case "sni":
+			o.SNI = v
+		case "host":
+			o.Host = v
+		default:
+			return Override{}, fmt.Errorf("unknown override %q; want sni=name or host=name", k)
+		}
+		if v == "" {
+			return Override{}, fmt.Errorf("%s: empty value", k)
+		}
+	}
+	return o, nil
+}
+
+// OverrideFor returns the override for the proxy at u: its URL fragment,
+// with the parts it leaves out taken from lookup (which may be nil).
+func OverrideFor(u *url.URL, lookup func(hostPort string) Override) (Override, error) {
+	o, err := ParseOverride(u.Fragment)
+	if err != nil {
+		return Override{}, fmt.Errorf("#%s: %w", u.Fragment, err)
+	}
+	if lookup != nil {
+		from := lookup(u.Host)
+		if o.SNI == "" {
+			o.SNI = from.SNI
+		}
+		if o.Host == "" {
+			o.Host = from.Host
+		}
+	}
+	return o, nil
+}
+
+// Apply sets o on t, the transport for proxyURL, and returns the round
+// tripper to send requests with: t itself when there is no Host to set.
+// The SNI only applies to an https:// proxy.
+func (o Override) Apply(t *http.Transport, proxyURL *url.URL) http.RoundTripper {
+	if o.SNI != "" {
+		ProxyTLS{Config: t.TLSClientConfig, ServerName: o.SNI}.Apply(t, proxyURL)
+	}
+	if o.Host == "" {
+		return t
+	}
+	return hostRoundTripper{t, o.Host}
+}
+
+// hostRoundTripper sends every request with its Host header replaced.
+type hostRoundTripper struct {
+	*http.Transport
+	host string
+}
+
+func (h hostRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Host = h.host
+	return h.Transport.RoundTrip(req)
+}
+
+// LoadOverrides reads a file mapping proxies to overrides and returns its
+// lookup for OverrideFor. The format is the credentials file's, one entry
+// per line with blank lines and #-comments ignored:
+//
+//	203.0.113.7:443   sni=cdn.example&host=app.example
+//	edge.example      sni=front.example   # any port on this host
+//	*                 host=app.example    # everything else
+func LoadOverrides(path string) (func(hostPort string) Override, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	lookup, err := parseOverrides(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return lookup, nil
+}
+
+func parseOverrides(r io.Reader) (func(hostPort string) Override, error) {
+	byHostPort, byHost := map[string]Override{}, map[string]Override{}
+	var fallback Override
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := sc.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: want \"host[:port] sni=name&host=name\"", n)
+		}
+		o, err := ParseOverride(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		target := strings.ToLower(fields[0])
+		if target == "*" {
+			fallback = o
+		} else if _, _, err := net.SplitHostPort(target); err == nil {
+			byHostPort[target] = o
+		} else {
+			byHost[strings.Trim(target, "[]")] = o
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return func(hostPort string) Override {
+		hostPort = strings.ToLower(hostPort)
+		if o, ok := byHostPort[hostPort]; ok {
+			return o
+		}
+		host := hostPort
+		if h, _, err := net.SplitHostPort(hostPort); err == nil {
+			host = h
+		}
+		if o, ok := byHost[strings.Trim(host, "[]")]; ok {
+			return o
+		}
+		return fallback
+	}, nil
+}
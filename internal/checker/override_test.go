@@ -0,0 +1,90 @@
+package checker
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseOverride(t *testing.T) {
+	o, err := ParseOverride("sni=cdn.example&host=app.example%3A8080")
+	if err != nil || o != (Override{SNI: "cdn.example", Host: "app.example:8080"}) {
+		t.Errorf("got %+v, %v", o, err)
+	}
+	if o, err := ParseOverride(""); err != nil || o != (Override{}) {
+		t.Errorf("empty: got %+v, %v", o, err)
+	}
+	for _, bad := range []string{"sni=", "server=x", "host"} {
+		if _, err := ParseOverride(bad); err == nil {
+			t.Errorf("%q: want an error", bad)
+		}
+	}
+}
+
+func TestParseOverrides(t *testing.T) {
+	lookup, err := parseOverrides(strings.NewReader(`
+# comment
+203.0.113.7:443  sni=cdn.example&host=app.example
+Edge.example     sni=front.example   # any port
+*                host=default.example
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for hostPort, want := range map[string]Override{
+		"203.0.113.7:443":   {SNI: "cdn.example", Host: "app.example"},
+		"edge.example:8443": {SNI: "front.example"},
+		"203.0.113.7:8443":  {Host: "default.example"},
+	} {
+		if got := lookup(hostPort); got != want {
+			t.Errorf("%s: got %+v, want %+v", hostPort, got, want)
+		}
+	}
+	if _, err := parseOverrides(strings.NewReader("1.2.3.4 sni=a extra")); err == nil {
+		t.Error("want an error for a malformed line")
+	}
+}
+
+// fakeFrontedProxy is an https forward proxy that only answers 200 to
+// requests with the given SNI and Host, and 421 to anything else.
+func fakeFrontedProxy(t *testing.T, sni, host string) string {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS.ServerName != sni || r.Host != host {
+			w.WriteHeader(http.StatusMisdirectedRequest)
+		}
+	}))
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return "https://" + srv.Listener.Addr().String()
+}
+
+func TestCheck_override(t *testing.T) {
+	proxy := fakeFrontedProxy(t, "cdn.example", "app.example")
+	opts := Options{Timeout: 2 * time.Second, TestURL: "http://example.invalid/", InsecureTLS: true}
+
+	if r := Check(proxy, opts); r.StatusCode != http.StatusMisdirectedRequest {
+		t.Errorf("no override: status = %d, want 421", r.StatusCode)
+	}
+	if r := Check(proxy+"#sni=cdn.example&host=app.example", opts); !r.Alive || r.StatusCode != http.StatusOK {
+		t.Errorf("fragment: alive = %v, status = %d (%s)", r.Alive, r.StatusCode, r.Error)
+	}
+
+	// The fragment wins over the lookup, part by part.
+	opts.Overrides = func(string) Override { return Override{SNI: "wrong.example", Host: "app.example"} }
+	if r := Check(proxy+"#sni=cdn.example", opts); r.StatusCode != http.StatusOK {
+		t.Errorf("fragment and lookup: status = %d (%s)", r.StatusCode, r.Error)
+	}
+	if r := Check(proxy, opts); r.StatusCode != http.StatusMisdirectedRequest {
+		t.Errorf("wrong SNI from lookup: status = %d, want 421", r.StatusCode)
+	}
+
+	if r := Check(proxy+"#server=x", opts); r.Alive || r.Failure == nil || r.Failure.Phase != PhaseParse {
+		t.Errorf("bad fragment: alive = %v, failure = %+v", r.Alive, r.Failure)
+	}
+}
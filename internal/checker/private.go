@@ -0,0 +1,38 @@
+package checker
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+
+	"github.com/drsoft-oss/proxybench/internal/geo"
+)
+
+// privateWarning explains why a proxy whose host is a literal address in
+// a private or reserved range cannot serve clients on other networks. It
+// returns "" for public addresses and hostnames.
+func privateWarning(address string) string {
+	host := address
+	if DetectProtocol(address) != ProtocolUnknown {
+		u, err := url.Parse(address)
+		if err != nil {
+			return ""
+		}
+		host = u.Hostname()
+	} else if h, _, err := net.SplitHostPort(address); err == nil {
+		host = h
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return ""
+	}
+	class, name, ok := geo.Special(ip)
+	if !ok {
+		return ""
+	}
+	if class == geo.ClassLoopback {
+		return fmt.Sprintf("%s address: reachable only from this host", name)
+	}
+	return fmt.Sprintf("%s address: unreachable from other networks", name)
+}
@@ -0,0 +1,77 @@
+package checker
+
+import (
+	"cmp"
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+// ProxyTLSError is a failed TLS handshake with an https:// proxy itself,
+// as opposed to one with a target through it.
+type ProxyTLSError struct {
+	Err error
+}
+
+func (e *ProxyTLSError) Error() string { return "proxy tls: " + e.Err.Error() }
+func (e *ProxyTLSError) Unwrap() error { return e.Err }
+
+// ProxyTLS says how to make the TLS connection to an https:// proxy,
+// which http.Transport would otherwise make with the settings it uses
+// for targets.
+type ProxyTLS struct {
+	// Config is cloned for each connection; nil verifies against the
+	// system roots.
+	Config *tls.Config
+	// ServerName is sent as SNI and verified; "" means the proxy's host
+	// (see Override.SNI).
+	ServerName string
+	// Fingerprint is the ClientHello sent (see ClientHandshake); HelloGo
+	// sends Go's own.
+	Fingerprint string
+	// OnHandshake, if set, is called with each session established.
+	OnHandshake func(tls.ConnectionState)
+}
+
+// Apply makes t connect to its proxy, proxyURL, over TLS as p says, and
+// report failed handshakes as *ProxyTLSError. t's TLSClientConfig still
+// applies to targets. It does nothing unless proxyURL is https://.
+func (p ProxyTLS) Apply(t *http.Transport, proxyURL *url.URL) {
+	if proxyURL.Scheme != "https" {
+		return
+	}
+	dial := t.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	name := cmp.Or(p.ServerName, proxyURL.Hostname())
+	t.DialTLSContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		c := &tls.Config{}
+		if p.Config != nil {
+			c = p.Config.Clone()
+		}
+		c.ServerName = name
+		hctx, cancel := withTimeout(ctx, t.TLSHandshakeTimeout)
+		defer cancel()
+		tc, state, err := ClientHandshake(hctx, conn, c, p.Fingerprint)
+		if err != nil {
+			conn.Close()
+			return nil, &ProxyTLSError{Err: err}
+		}
+		if p.OnHandshake != nil {
+			p.OnHandshake(state)
+		}
+		return tc, nil
+	}
+}
+
+// proxyTarget is the URL a proxy's own certificate is graded against.
+func proxyTarget(proxyURL *url.URL, serverName string) string {
+	return "https://" + net.JoinHostPort(cmp.Or(serverName, proxyURL.Hostname()), "443")
+}
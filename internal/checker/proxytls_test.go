@@ -0,0 +1,53 @@
+package checker
+
+import (
+	"crypto/x509"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheck_proxyTLS(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.StartTLS()
+	defer srv.Close()
+	proxy := "https://" + srv.Listener.Addr().String()
+	opts := Options{Timeout: 2 * time.Second, TestURL: "http://example.invalid/"}
+
+	// httptest's certificate is self-signed: the proxy's handshake fails,
+	// and is reported as such rather than as a forwarding error.
+	r := Check(proxy, opts)
+	if r.Alive || r.Failure == nil || r.Failure.Phase != PhaseHandshake || r.Failure.Kind != ErrTLS || !strings.HasPrefix(r.Error, "proxy tls: ") {
+		t.Fatalf("untrusted proxy: alive = %v, failure = %+v", r.Alive, r.Failure)
+	}
+	if r.ProxyTLS == nil || r.ProxyTLS.Verified || r.TLS != nil {
+		t.Errorf("untrusted proxy: ProxyTLS = %+v, TLS = %+v; want an unverified proxy session only", r.ProxyTLS, r.TLS)
+	}
+
+	opts.ProxyInsecure = true
+	r = Check(proxy, opts)
+	if !r.Alive || r.StatusCode != http.StatusNoContent {
+		t.Fatalf("--proxy-insecure: alive = %v, status = %d (%s)", r.Alive, r.StatusCode, r.Error)
+	}
+	if r.ProxyTLS == nil || r.ProxyTLS.Verified || r.ProxyTLS.Version == "" || !strings.Contains(r.Warning, "proxy certificate not trusted") {
+		t.Errorf("--proxy-insecure: ProxyTLS = %+v, warning = %q", r.ProxyTLS, r.Warning)
+	}
+
+	opts.ProxyInsecure = false
+	opts.RootCAs = x509.NewCertPool()
+	opts.RootCAs.AddCert(srv.Certificate())
+	if r = Check(proxy, opts); !r.Alive || r.ProxyTLS == nil || !r.ProxyTLS.Verified {
+		t.Errorf("trusted proxy: alive = %v (%s), ProxyTLS = %+v", r.Alive, r.Error, r.ProxyTLS)
+	}
+
+	if r = Check("http://"+srv.Listener.Addr().String(), opts); r.ProxyTLS != nil {
+		t.Errorf("http:// proxy: ProxyTLS = %+v, want nil", r.ProxyTLS)
+	}
+}
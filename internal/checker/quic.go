@@ -0,0 +1,216 @@
+package checker
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/drsoft-oss/proxybench/internal/resolver"
+	"github.com/drsoft-oss/proxybench/internal/tracing"
+)
+
+// errAssociate marks H3Transport dials that failed because the proxy
+// would not open a UDP association, rather than in QUIC itself.
+var errAssociate = errors.New("socks5 UDP ASSOCIATE")
+
+// H3Transport returns an HTTP/3 round tripper whose QUIC connections run
+// through UDP associations with the SOCKS5 proxy at hostPort, one each.
+// HTTP/3 is https only, so http:// requests are sent to the same host as
+// https://, on port 443. Unless reuse is set, every request gets its own
+// QUIC connection, closed with the response body, as DisableKeepAlives
+// does for an http.Transport.
+func H3Transport(hostPort string, user *url.Userinfo, tlsConf *tls.Config, reuse bool) http.RoundTripper {
+	newTransport := func() *http3.Transport {
+		return &http3.Transport{
+			TLSClientConfig: tlsConf,
+			Dial: func(ctx context.Context, addr string, tlsCfg *tls.Config, cfg *quic.Config) (*quic.Conn, error) {
+				target, err := quicAddr(ctx, addr)
+				if err != nil {
+					return nil, err
+				}
+				relay, err := AssociateUDP(ctx, hostPort, user)
+				if err != nil {
+					return nil, fmt.Errorf("%w: %w", errAssociate, err)
+				}
+				pc, err := newRelayPacketConn(relay, target)
+				if err != nil {
+					relay.Close()
+					return nil, err
+				}
+				conn, err := quic.Dial(ctx, pc, pc.target, tlsCfg, cfg)
+				if err != nil {
+					relay.Close()
+					return nil, err
+				}
+				context.AfterFunc(conn.Context(), func() { relay.Close() })
+				return conn, nil
+			},
+		}
+	}
+	if reuse {
+		return h3Upgrade{newTransport()}
+	}
+	return h3Upgrade{h3PerRequest(newTransport)}
+}
+
+// h3Upgrade sends http:// requests as https:// ones on the default port.
+type h3Upgrade struct {
+	http.RoundTripper
+}
+
+func (t h3Upgrade) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme == "http" {
+		req = req.Clone(req.Context())
+		req.URL.Scheme, req.URL.Host = "https", req.URL.Hostname()
+		if strings.Contains(req.URL.Host, ":") {
+			req.URL.Host = "[" + req.URL.Host + "]" // IPv6
+		}
+	}
+	return t.RoundTripper.RoundTrip(req)
+}
+
+// CloseIdleConnections closes the QUIC connections of a reused transport.
+func (t h3Upgrade) CloseIdleConnections() {
+	if c, ok := t.RoundTripper.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+}
+
+// h3PerRequest runs every request on a transport of its own.
+type h3PerRequest func() *http3.Transport
+
+func (f h3PerRequest) RoundTrip(req *http.Request) (*http.Response, error) {
+	t := f()
+	resp, err := t.RoundTrip(req)
+	if err != nil {
+		t.Close()
+		return nil, err
+	}
+	resp.Body = closeWith{resp.Body, t}
+	return resp, nil
+}
+
+// closeWith is a response body that closes c after itself.
+type closeWith struct {
+	io.ReadCloser
+	c io.Closer
+}
+
+func (b closeWith) Close() error {
+	err := b.ReadCloser.Close()
+	b.c.Close()
+	return err
+}
+
+// relayPacketConn is a SOCKS5 UDP association as the net.PacketConn
+// quic-go reads and writes: datagrams go to and come from one target,
+// behind the SOCKS5 UDP header.
+type relayPacketConn struct {
+	*UDPRelay
+	target *net.UDPAddr
+	head   []byte
+	buf    []byte
+}
+
+func newRelayPacketConn(relay *UDPRelay, target string) (*relayPacketConn, error) {
+	head, err := udpHeader(target)
+	if err != nil {
+		return nil, err
+	}
+	addr, err := net.ResolveUDPAddr("udp", target)
+	if err != nil {
+		return nil, err
+	}
+	return &relayPacketConn{UDPRelay: relay, target: addr, head: head, buf: make([]byte, 64<<10)}, nil
+}
+
+func (c *relayPacketConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	for {
+		n, err := c.conn.Read(c.buf)
+		if err != nil {
+			return 0, nil, err
+		}
+		// Anything not from target is a stray.
+		if n > len(c.head) && bytes.Equal(c.buf[:len(c.head)], c.head) {
+			return copy(p, c.buf[len(c.head):n]), c.target, nil
+		}
+	}
+}
+
+func (c *relayPacketConn) WriteTo(p []byte, _ net.Addr) (int, error) {
+	if _, err := c.conn.Write(append(c.head[:len(c.head):len(c.head)], p...)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (c *relayPacketConn) LocalAddr() net.Addr                { return c.conn.LocalAddr() }
+func (c *relayPacketConn) SetDeadline(t time.Time) error      { return c.conn.SetDeadline(t) }
+func (c *relayPacketConn) SetReadDeadline(t time.Time) error  { return c.conn.SetReadDeadline(t) }
+func (c *relayPacketConn) SetWriteDeadline(t time.Time) error { return c.conn.SetWriteDeadline(t) }
+func (c *relayPacketConn) SetReadBuffer(bytes int) error      { return c.conn.SetReadBuffer(bytes) }
+func (c *relayPacketConn) SetWriteBuffer(bytes int) error     { return c.conn.SetWriteBuffer(bytes) }
+
+// probeQUIC fetches testURL over HTTP/3 through the SOCKS5 proxy's UDP
+// relay and reports whether a response came back; no response means
+// clients fall back to TCP. It returns nil when the proxy refused UDP
+// ASSOCIATE or could not be asked, since HTTP/3 is then not on offer at
+// all.
+func probeQUIC(ctx context.Context, hostPort string, user *url.Userinfo, testURL string, opts Options) *bool {
+	ctx, span := tracing.Start(ctx, "quic", trace.WithAttributes(attribute.String("http.url", testURL)))
+	defer span.End()
+	ctx, cancel := withTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	req, err := opts.Request.New(ctx, testURL)
+	if err != nil {
+		tracing.Fail(span, err)
+		return nil
+	}
+	resp, err := H3Transport(hostPort, user, ClientTLS(opts.RootCAs, opts.InsecureTLS), false).RoundTrip(req)
+	if errors.Is(err, errAssociate) {
+		tracing.Fail(span, err)
+		return nil
+	}
+	ok := err == nil
+	if ok {
+		io.Copy(io.Discard, resp.Body) //nolint:errcheck
+		resp.Body.Close()
+	}
+	span.SetAttributes(attribute.Bool("quic.ok", ok))
+	return &ok
+}
+
+// quicAddr resolves target ("host:port") to the ip:port a SOCKS5 UDP
+// header needs.
+func quicAddr(ctx context.Context, target string) (string, error) {
+	host, port, err := net.SplitHostPort(target)
+	if err != nil {
+		return "", err
+	}
+	if ip, err := netip.ParseAddr(host); err == nil {
+		return net.JoinHostPort(ip.Unmap().String(), port), nil
+	}
+	addrs, err := resolver.Default().LookupHost(ctx, host)
+	if err != nil {
+		return "", err
+	}
+	if len(addrs) == 0 {
+		return "", errors.New("no addresses for " + host)
+	}
+	return net.JoinHostPort(addrs[0].Unmap().String(), port), nil
+}
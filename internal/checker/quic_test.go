@@ -0,0 +1,60 @@
+package checker
+
+import (
+	"context"
+	"crypto/x509"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/drsoft-oss/proxybench/internal/testproxy"
+)
+
+func TestProbeQUIC(t *testing.T) {
+	ctx := context.Background()
+	addr, cert := testproxy.HTTP3(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Proto) //nolint:errcheck
+	}))
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	opts := Options{Timeout: 2 * time.Second, RootCAs: roots}
+	testURL := "https://" + addr + "/"
+
+	if got := probeQUIC(ctx, (&testproxy.SOCKS5{RelayUDP: true}).Start(t), nil, testURL, opts); got == nil || !*got {
+		t.Errorf("probeQUIC through a relaying proxy = %v, want true", fmtBool(got))
+	}
+	// The fake relay echoes the client's packets back, which no QUIC
+	// server does.
+	if got := probeQUIC(ctx, (&testproxy.SOCKS5{}).Start(t), nil, testURL, opts); got == nil || *got {
+		t.Errorf("probeQUIC through an echoing relay = %v, want false", fmtBool(got))
+	}
+	refuse := func(testproxy.Request) byte { return socks5RepNoCmd }
+	if got := probeQUIC(ctx, (&testproxy.SOCKS5{Reply: refuse}).Start(t), nil, testURL, opts); got != nil {
+		t.Errorf("probeQUIC without UDP ASSOCIATE = %v, want nil", *got)
+	}
+}
+
+func TestH3Transport(t *testing.T) {
+	addr, cert := testproxy.HTTP3(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Proto) //nolint:errcheck
+	}))
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	proxy := (&testproxy.SOCKS5{RelayUDP: true}).Start(t)
+	for _, reuse := range []bool{false, true} {
+		client := &http.Client{Transport: H3Transport(proxy, nil, ClientTLS(roots, false), reuse), Timeout: 2 * time.Second}
+		for range 2 {
+			resp, err := client.Get("https://" + addr + "/")
+			if err != nil {
+				t.Fatalf("reuse %v: %v", reuse, err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if string(body) != "HTTP/3.0" {
+				t.Errorf("reuse %v: served over %q, want HTTP/3.0", reuse, body)
+			}
+		}
+		client.CloseIdleConnections()
+	}
+}
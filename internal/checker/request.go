@@ -0,0 +1,41 @@
+package checker
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+)
+
+// Request describes what is sent to the test URL through the proxy, so the
+// API calls a proxy will really carry can be tested instead of a plain GET.
+// The zero value is a GET without a body.
+type Request struct {
+	Method      string // default GET, or POST when Body is set
+	Body        []byte
+	ContentType string
+}
+
+// New returns the request for testURL. Each call gets its own body reader,
+// so a Request can be used for any number of samples.
+func (rq Request) New(ctx context.Context, testURL string) (*http.Request, error) {
+	method := rq.Method
+	if method == "" {
+		method = http.MethodGet
+		if rq.Body != nil {
+			method = http.MethodPost
+		}
+	}
+	var body io.Reader
+	if rq.Body != nil {
+		body = bytes.NewReader(rq.Body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, testURL, body)
+	if err != nil {
+		return nil, err
+	}
+	if rq.ContentType != "" {
+		req.Header.Set("Content-Type", rq.ContentType)
+	}
+	return req, nil
+}
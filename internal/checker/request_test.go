@@ -0,0 +1,47 @@
+package checker
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestRequest_New(t *testing.T) {
+	ctx := context.Background()
+	cases := []struct {
+		rq         Request
+		wantMethod string
+	}{
+		{Request{}, http.MethodGet},
+		{Request{Body: []byte(`{"q":1}`)}, http.MethodPost},
+		{Request{Method: http.MethodPut, Body: []byte("x")}, http.MethodPut},
+		{Request{Method: http.MethodDelete}, http.MethodDelete},
+	}
+	for _, c := range cases {
+		req, err := c.rq.New(ctx, "http://example.com/api")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if req.Method != c.wantMethod {
+			t.Errorf("%+v: method = %s, want %s", c.rq, req.Method, c.wantMethod)
+		}
+	}
+}
+
+func TestRequest_NewBodyPerCall(t *testing.T) {
+	rq := Request{Body: []byte(`{"q":1}`), ContentType: "application/json"}
+	for i := 0; i < 2; i++ {
+		req, err := rq.New(context.Background(), "http://example.com/api")
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(req.Body)
+		if string(body) != `{"q":1}` || req.ContentLength != 7 {
+			t.Errorf("call %d: body = %q, length %d", i, body, req.ContentLength)
+		}
+		if got := req.Header.Get("Content-Type"); got != "application/json" {
+			t.Errorf("Content-Type = %q", got)
+		}
+	}
+}
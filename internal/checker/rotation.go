@@ -0,0 +1,61 @@
+package checker
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/drsoft-oss/proxybench/internal/tracing"
+)
+
+// Defaults for the exit IP rotation probe.
+const (
+	DefaultRotationSamples  = 5
+	DefaultRotationInterval = 2 * time.Second
+)
+
+// probeRotation asks target, an IP echo service, for the exit IP samples
+// times through client, a new connection each, interval apart. It returns
+// the distinct exit IPs in the order they were first seen and whether
+// there was more than one; nil when fewer than two samples were answered.
+func probeRotation(ctx context.Context, client *http.Client, target string, samples int, interval time.Duration) ([]string, *bool) {
+	ctx, span := tracing.Start(ctx, "exit_ip_rotation")
+	defer span.End()
+	if samples <= 0 {
+		samples = DefaultRotationSamples
+	}
+	if interval <= 0 {
+		interval = DefaultRotationInterval
+	}
+
+	var ips []string
+	answered := 0
+	for i := range samples {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(interval):
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		ip := probeExitIP(ctx, client, target)
+		if ip == "" {
+			continue
+		}
+		answered++
+		if !slices.Contains(ips, ip) {
+			ips = append(ips, ip)
+		}
+	}
+	span.SetAttributes(attribute.Int("exit_ip.distinct", len(ips)), attribute.Int("exit_ip.answered", answered))
+	if answered < 2 {
+		return ips, nil
+	}
+	rotating := len(ips) > 1
+	return ips, &rotating
+}
@@ -0,0 +1,46 @@
+package checker
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCheck_rotation(t *testing.T) {
+	// The echo service sees a new exit IP for every other request.
+	var n atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ip" {
+			fmt.Fprintf(w, "203.0.113.%d\n", (n.Add(1)+1)/2)
+		}
+	}))
+	defer srv.Close()
+
+	opts := Options{
+		Timeout: 2 * time.Second, TestURL: "http://example.invalid/", ConnectURL: "http://example.invalid/",
+		RotationURL: "http://echo.invalid/ip", RotationSamples: 4, RotationInterval: 10 * time.Millisecond,
+	}
+	r := Check(srv.URL, opts)
+	if r.Rotating == nil || !*r.Rotating || !slices.Equal(r.ExitIPs, []string{"203.0.113.1", "203.0.113.2"}) {
+		t.Errorf("Rotating = %v, ExitIPs = %q", r.Rotating, r.ExitIPs)
+	}
+}
+
+func TestProbeRotation_static(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "203.0.113.9")
+	}))
+	defer srv.Close()
+
+	ips, rotating := probeRotation(t.Context(), srv.Client(), srv.URL, 3, time.Millisecond)
+	if rotating == nil || *rotating || !slices.Equal(ips, []string{"203.0.113.9"}) {
+		t.Errorf("probeRotation = %q, %v; want one static IP", ips, rotating)
+	}
+	if _, rotating := probeRotation(t.Context(), srv.Client(), srv.URL, 1, time.Millisecond); rotating != nil {
+		t.Errorf("one sample: rotating = %v, want nil", *rotating)
+	}
+}
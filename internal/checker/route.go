@@ -0,0 +1,57 @@
+package checker
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"strconv"
+
+	"github.com/drsoft-oss/proxybench/internal/resolver"
+	"github.com/drsoft-oss/proxybench/internal/traceroute"
+)
+
+// traceRoute runs a TCP traceroute to the proxy of r, to the address in
+// the family the check connected over. Errors leave the route unset.
+func traceRoute(ctx context.Context, r Result, opts Options) *traceroute.Route {
+	hostPort, ok := proxyHostPort(r.Address, r.Protocol)
+	switch r.Protocol {
+	case ProtocolShadowsocks:
+		cfg, err := ParseShadowsocksURL(r.Address)
+		hostPort, ok = net.JoinHostPort(cfg.Host, cfg.Port), err == nil
+	case ProtocolShadowsocksR:
+		cfg, err := ParseShadowsocksRURL(r.Address)
+		hostPort, ok = net.JoinHostPort(cfg.Host, cfg.Port), err == nil
+	case ProtocolHysteria2:
+		cfg, err := ParseHysteria2URL(r.Address)
+		hostPort, ok = net.JoinHostPort(cfg.Host, cfg.Port), err == nil
+	case ProtocolUnknown:
+		hostPort, ok = r.Address, true
+	}
+	if !ok {
+		return nil
+	}
+	host, portStr, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		return nil
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return nil
+	}
+	addrs, err := resolver.Default().LookupHost(ctx, host)
+	if err != nil || len(addrs) == 0 {
+		return nil
+	}
+	addr := addrs[0]
+	for _, a := range addrs {
+		if a.Is4() == (r.Family == "ipv4") {
+			addr = a
+			break
+		}
+	}
+	rt, err := traceroute.Trace(ctx, netip.AddrPortFrom(addr, uint16(port)), traceroute.Options{Wait: min(opts.Timeout, traceroute.DefaultWait)})
+	if err != nil {
+		return nil
+	}
+	return rt
+}
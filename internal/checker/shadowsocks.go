@@ -1,12 +1,19 @@
 package checker
 
 import (
+	"context"
 	"encoding/base64"
 	"fmt"
 	"net"
 	"net/url"
 	"strings"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/drsoft-oss/proxybench/internal/resolver"
+	"github.com/drsoft-oss/proxybench/internal/tracing"
 )
 
 // ShadowsocksConfig holds parsed Shadowsocks connection parameters.
@@ -91,23 +98,32 @@ func ParseShadowsocksURL(rawURL string) (ShadowsocksConfig, error) {
 // library), but a successful TCP connection indicates the server is reachable.
 // The function also sends a minimal probe to confirm the port is accepting data.
 func CheckShadowsocks(address string, opts Options) Result {
+	return checkShadowsocks(context.Background(), address, opts)
+}
+
+func checkShadowsocks(ctx context.Context, address string, opts Options) Result {
 	result := Result{Address: address, Protocol: ProtocolShadowsocks}
 
 	cfg, err := ParseShadowsocksURL(address)
 	if err != nil {
-		result.Error = fmt.Sprintf("parse: %v", err)
+		result.fail(PhaseParse, "parse", err)
 		return result
 	}
 
 	hostPort := net.JoinHostPort(cfg.Host, cfg.Port)
 	start := time.Now()
 
-	conn, err := net.DialTimeout("tcp", hostPort, opts.Timeout)
+	_, span := tracing.Start(ctx, "tcp_dial", trace.WithAttributes(attribute.String("net.peer", hostPort)))
+	dialCtx, cancel := withTimeout(ctx, opts.connectTimeout())
+	conn, err := resolver.Default().DialContext(dialCtx, "tcp", hostPort)
+	cancel()
+	tracing.End(span, err)
 	if err != nil {
-		result.Error = fmt.Sprintf("tcp: %v", err)
+		result.fail(PhaseConnect, "tcp", err)
 		return result
 	}
 	defer conn.Close()
+	result.Family = resolver.Family(conn.RemoteAddr())
 
 	// Send a few random bytes — a healthy SS server will keep the connection
 	// open waiting for the encrypted handshake rather than immediately closing.
@@ -0,0 +1,254 @@
+package checker
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/drsoft-oss/proxybench/internal/resolver"
+	"github.com/drsoft-oss/proxybench/internal/tracing"
+)
+
+// ShadowsocksRConfig holds parsed ShadowsocksR connection parameters.
+type ShadowsocksRConfig struct {
+	Host     string
+	Port     string
+	Protocol string // e.g. origin, auth_aes128_md5
+	Method   string
+	Obfs     string // e.g. plain, http_simple, tls1.2_ticket_auth
+	Password string
+	// ObfsParam and ProtocolParam are the optional obfs and protocol
+	// settings; Remarks and Group name the server in subscriptions.
+	ObfsParam     string
+	ProtocolParam string
+	Remarks       string
+	Group         string
+}
+
+// ParseShadowsocksRURL parses a ssr:// link:
+//
+//	ssr://BASE64(host:port:protocol:method:obfs:BASE64(password)/?obfsparam=BASE64&protoparam=BASE64&remarks=BASE64&group=BASE64)
+//
+// Both base64 alphabets are accepted, padded or not; an IPv6 host may be
+// written bare, as SSR clients do.
+func ParseShadowsocksRURL(rawURL string) (ShadowsocksRConfig, error) {
+	var cfg ShadowsocksRConfig
+	body, ok := strings.CutPrefix(strings.TrimSpace(rawURL), "ssr://")
+	if !ok {
+		return cfg, fmt.Errorf("not a ssr:// link")
+	}
+	decoded, err := decodeBase64(body)
+	if err != nil {
+		return cfg, fmt.Errorf("base64 decode link: %w", err)
+	}
+	main, query, _ := strings.Cut(string(decoded), "?")
+	main = strings.TrimSuffix(main, "/")
+
+	parts := strings.Split(main, ":")
+	if len(parts) < 6 {
+		return cfg, fmt.Errorf("want host:port:protocol:method:obfs:password, got %d fields", len(parts))
+	}
+	n := len(parts)
+	cfg.Host = strings.Trim(strings.Join(parts[:n-5], ":"), "[]")
+	cfg.Port, cfg.Protocol, cfg.Method, cfg.Obfs = parts[n-5], parts[n-4], parts[n-3], parts[n-2]
+	if cfg.Host == "" {
+		return cfg, fmt.Errorf("missing host")
+	}
+	if p, err := strconv.Atoi(cfg.Port); err != nil || p < 1 || p > 65535 {
+		return cfg, fmt.Errorf("invalid port %q", cfg.Port)
+	}
+	if cfg.Protocol == "" || cfg.Method == "" || cfg.Obfs == "" {
+		return cfg, fmt.Errorf("missing protocol, method or obfs")
+	}
+	password, err := decodeBase64(parts[n-1])
+	if err != nil {
+		return cfg, fmt.Errorf("base64 decode password: %w", err)
+	}
+	cfg.Password = string(password)
+
+	params, err := url.ParseQuery(query)
+	if err != nil {
+		return cfg, fmt.Errorf("parameters: %w", err)
+	}
+	for key, dst := range map[string]*string{"obfsparam": &cfg.ObfsParam, "protoparam": &cfg.ProtocolParam, "remarks": &cfg.Remarks, "group": &cfg.Group} {
+		if v := params.Get(key); v != "" {
+			b, err := decodeBase64(v)
+			if err != nil {
+				return cfg, fmt.Errorf("base64 decode %s: %w", key, err)
+			}
+			*dst = string(b)
+		}
+	}
+	return cfg, nil
+}
+
+// decodeBase64 decodes standard or URL-safe base64, with or without
+// padding, as SSR links mix them.
+func decodeBase64(s string) ([]byte, error) {
+	s = strings.TrimRight(strings.NewReplacer("+", "-", "/", "_").Replace(strings.TrimSpace(s)), "=")
+	return base64.RawURLEncoding.DecodeString(s)
+}
+
+// obfsKind maps an obfs name to the first-packet shape probeSSR sends; the
+// _compatible variants look the same on the wire.
+func obfsKind(obfs string) (string, bool) {
+	switch k := strings.TrimSuffix(obfs, "_compatible"); k {
+	case "plain", "random_head", "http_simple", "http_post", "tls1.2_ticket_auth":
+		return k, true
+	}
+	return "", false
+}
+
+// CheckShadowsocksR checks a ShadowsocksR server. Like CheckShadowsocks it
+// cannot complete a handshake without the server's cipher, but its probe
+// is shaped like the link's obfs, so the server sees what a client's
+// first packet would look like on the wire.
+func CheckShadowsocksR(address string, opts Options) Result {
+	return checkShadowsocksR(context.Background(), address, opts)
+}
+
+func checkShadowsocksR(ctx context.Context, address string, opts Options) Result {
+	result := Result{Address: address, Protocol: ProtocolShadowsocksR}
+
+	cfg, err := ParseShadowsocksRURL(address)
+	if err != nil {
+		result.fail(PhaseParse, "parse", err)
+		return result
+	}
+	kind, ok := obfsKind(cfg.Obfs)
+	if !ok {
+		result.fail(PhaseParse, "parse", fmt.Errorf("unsupported obfs %q", cfg.Obfs))
+		return result
+	}
+
+	hostPort := net.JoinHostPort(cfg.Host, cfg.Port)
+	start := time.Now()
+
+	_, span := tracing.Start(ctx, "tcp_dial", trace.WithAttributes(attribute.String("net.peer", hostPort)))
+	dialCtx, cancel := withTimeout(ctx, opts.connectTimeout())
+	conn, err := resolver.Default().DialContext(dialCtx, "tcp", hostPort)
+	cancel()
+	tracing.End(span, err)
+	if err != nil {
+		result.fail(PhaseConnect, "tcp", err)
+		return result
+	}
+	defer conn.Close()
+	result.Family = resolver.Family(conn.RemoteAddr())
+
+	// An SSR server says nothing until a client's request authenticates,
+	// and keeps the connection open while it waits for more; one that
+	// hangs up at once or answers a forged first packet in the clear is
+	// not one. HTTP obfs servers may pass bad requests on to a web
+	// server, so an HTTP answer only counts against the others.
+	conn.SetDeadline(time.Now().Add(opts.handshakeTimeout()))
+	if _, err := conn.Write(ssrProbe(kind, cfg)); err != nil {
+		result.fail(PhaseHandshake, "obfs probe", err)
+		return result
+	}
+	latency := time.Since(start)
+	reply := make([]byte, 16)
+	n, err := io.ReadAtLeast(conn, reply, 2)
+	if n == 0 && !errors.Is(err, os.ErrDeadlineExceeded) {
+		result.fail(PhaseHandshake, "obfs probe", fmt.Errorf("connection closed on the probe: %w", err))
+		return result
+	}
+	if other := clearReply(reply[:n], kind); other != "" {
+		result.fail(PhaseHandshake, "obfs probe", fmt.Errorf("answered like %s: not a ShadowsocksR server", other))
+		result.Failure.Kind = ErrProtocol
+		return result
+	}
+
+	result.Alive = true
+	result.Latency = latency
+	return result
+}
+
+// clearReply names the protocol a plaintext reply to the probe belongs to,
+// or returns "" if the reply is one an SSR server may give.
+func clearReply(reply []byte, kind string) string {
+	switch {
+	case bytes.HasPrefix(reply, []byte("HTTP/")) && kind != "http_simple" && kind != "http_post":
+		return "HTTP"
+	case len(reply) == 2 && reply[0] == socks5Version:
+		return "SOCKS5"
+	case kind == "tls1.2_ticket_auth" && len(reply) > 0 && reply[0] != 0x16 && reply[0] != 0x15:
+		return "something other than TLS"
+	}
+	return ""
+}
+
+// ssrProbe returns a first packet of the obfs kind carrying random bytes
+// where a client would put its encrypted request.
+func ssrProbe(kind string, cfg ShadowsocksRConfig) []byte {
+	payload := make([]byte, 32)
+	rand.Read(payload) //nolint:errcheck // never fails
+	switch kind {
+	case "http_simple", "http_post":
+		host := cfg.Host
+		if h, _, _ := strings.Cut(cfg.ObfsParam, ","); h != "" {
+			host = h
+		}
+		if cfg.Port != "80" {
+			host = net.JoinHostPort(host, cfg.Port)
+		}
+		var path strings.Builder
+		for _, b := range payload[:16] {
+			fmt.Fprintf(&path, "%%%02x", b)
+		}
+		method, body := "GET", ""
+		if kind == "http_post" {
+			method, body = "POST", string(payload[16:])
+		}
+		return fmt.Appendf(nil, "%s /%s HTTP/1.1\r\nHost: %s\r\nUser-Agent: Mozilla/5.0 (Windows NT 10.0; Win64; x64)\r\nAccept: */*\r\nConnection: keep-alive\r\nContent-Length: %d\r\n\r\n%s",
+			method, path.String(), host, len(body), body)
+	case "tls1.2_ticket_auth":
+		host := cfg.Host
+		if h, _, _ := strings.Cut(cfg.ObfsParam, ","); h != "" {
+			host = h
+		}
+		return clientHello(host, payload)
+	}
+	return payload // plain and random_head: an IV and header, to the eye
+}
+
+// clientHello builds a TLS 1.2 ClientHello record naming host, with random
+// bytes for its random and session ID, as tls1.2_ticket_auth clients send.
+func clientHello(host string, random []byte) []byte {
+	sni := []byte(host)
+	ext := binary.BigEndian.AppendUint16(nil, 0) // server_name
+	ext = binary.BigEndian.AppendUint16(ext, uint16(len(sni)+5))
+	ext = binary.BigEndian.AppendUint16(ext, uint16(len(sni)+3))
+	ext = append(ext, 0)
+	ext = binary.BigEndian.AppendUint16(ext, uint16(len(sni)))
+	ext = append(ext, sni...)
+	ext = append(ext, 0x00, 0x23, 0x00, 0x00) // empty session_ticket
+
+	hello := []byte{0x03, 0x03}
+	hello = append(hello, random...) // 32 bytes
+	hello = append(hello, 32)
+	hello = append(hello, random...) // session ID
+	hello = append(hello, 0x00, 0x04, 0xc0, 0x2f, 0xc0, 0x2b, 0x01, 0x00)
+	hello = binary.BigEndian.AppendUint16(hello, uint16(len(ext)))
+	hello = append(hello, ext...)
+
+	hs := append([]byte{0x01, 0, 0, 0}, hello...)
+	hs[1], hs[2], hs[3] = byte(len(hello)>>16), byte(len(hello)>>8), byte(len(hello))
+	rec := []byte{0x16, 0x03, 0x01}
+	rec = binary.BigEndian.AppendUint16(rec, uint16(len(hs)))
+	return append(rec, hs...)
+}
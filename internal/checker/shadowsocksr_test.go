@@ -0,0 +1,116 @@
+package checker
+
+import (
+	"encoding/base64"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// ssrLink builds a ssr:// link the way SSR clients export them.
+func ssrLink(hostPort, obfs, query string) string {
+	host, port, _ := net.SplitHostPort(hostPort)
+	b64 := base64.RawURLEncoding.EncodeToString
+	body := host + ":" + port + ":auth_aes128_md5:aes-256-cfb:" + obfs + ":" + b64([]byte("secret")) + "/?" + query
+	return "ssr://" + b64([]byte(body))
+}
+
+func TestParseShadowsocksRURL(t *testing.T) {
+	b64 := base64.StdEncoding.EncodeToString
+	link := ssrLink("[2001:db8::1]:8443", "http_simple", "obfsparam="+b64([]byte("cdn.example"))+"&remarks="+b64([]byte("Tokyo 1")))
+	cfg, err := ParseShadowsocksRURL(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ShadowsocksRConfig{
+		Host: "2001:db8::1", Port: "8443", Protocol: "auth_aes128_md5", Method: "aes-256-cfb",
+		Obfs: "http_simple", Password: "secret", ObfsParam: "cdn.example", Remarks: "Tokyo 1",
+	}
+	if cfg != want {
+		t.Errorf("got %+v, want %+v", cfg, want)
+	}
+	if DetectProtocol(link) != ProtocolShadowsocksR {
+		t.Errorf("DetectProtocol = %q, want ssr", DetectProtocol(link))
+	}
+}
+
+func TestParseShadowsocksRURL_invalid(t *testing.T) {
+	b64 := base64.RawURLEncoding.EncodeToString
+	cases := []string{
+		"ssr://",
+		"ssr://!!!",
+		"ss://" + b64([]byte("1.2.3.4:8388:origin:aes-256-cfb:plain:cHc")),
+		"ssr://" + b64([]byte("1.2.3.4:8388:origin:aes-256-cfb:cHc")),
+		"ssr://" + b64([]byte("1.2.3.4:0:origin:aes-256-cfb:plain:cHc")),
+		"ssr://" + b64([]byte("1.2.3.4:8388:origin:aes-256-cfb:plain:!!")),
+	}
+	for _, c := range cases {
+		if _, err := ParseShadowsocksRURL(c); err == nil {
+			t.Errorf("expected error for %q, got nil", c)
+		}
+	}
+}
+
+func TestCheckShadowsocksR(t *testing.T) {
+	// serve reads the probe and then waits for more, as an SSR server
+	// does, or writes answer and hangs up, as something else would.
+	serve := func(answer string, hold bool) string {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { ln.Close() })
+		go func() {
+			for {
+				c, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				c.Read(make([]byte, 1024)) //nolint:errcheck
+				if hold {
+					go func() {
+						io.Copy(io.Discard, c) //nolint:errcheck
+						c.Close()
+					}()
+					continue
+				}
+				c.Write([]byte(answer)) //nolint:errcheck
+				c.Close()
+			}
+		}()
+		return ln.Addr().String()
+	}
+	silent := serve("", true)
+	hangUp := serve("", false)
+	web := serve("HTTP/1.1 400 Bad Request\r\n\r\n", false)
+	opts := DefaultOptions()
+	opts.HandshakeTimeout = 200 * time.Millisecond
+
+	cases := []struct {
+		name, link string
+		alive      bool
+	}{
+		{"plain", ssrLink(silent, "plain", ""), true},
+		{"tls obfs", ssrLink(silent, "tls1.2_ticket_auth", ""), true},
+		{"hangs up", ssrLink(hangUp, "plain", ""), false},
+		{"web server", ssrLink(web, "plain", ""), false},
+		{"web server behind http obfs", ssrLink(web, "http_simple_compatible", ""), true},
+		{"unknown obfs", ssrLink(silent, "made_up", ""), false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := CheckShadowsocksR(tc.link, opts)
+			if r.Alive != tc.alive {
+				t.Fatalf("Alive = %v, want %v (%v)", r.Alive, tc.alive, r.Error)
+			}
+			if r.Alive && r.Latency >= opts.HandshakeTimeout {
+				t.Errorf("Latency = %v, want the connect and write, not the wait for a reply", r.Latency)
+			}
+		})
+	}
+	r := CheckShadowsocksR(ssrLink(web, "random_head", ""), opts)
+	if r.Failure == nil || r.Failure.Kind != ErrProtocol {
+		t.Errorf("web server behind random_head: failure %v, want kind %v", r.Failure, ErrProtocol)
+	}
+}
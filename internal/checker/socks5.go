@@ -1,23 +1,42 @@
 package checker
 
 import (
-	"fmt"
+	"cmp"
+	"context"
+	"crypto/tls"
 	"net"
 	"net/http"
 	"net/url"
+	"sync/atomic"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/net/proxy"
+
+	"github.com/drsoft-oss/proxybench/internal/proxyproto"
+	"github.com/drsoft-oss/proxybench/internal/resolver"
+	"github.com/drsoft-oss/proxybench/internal/tracing"
 )
 
 // CheckSOCKS5 validates a SOCKS5 proxy.
 // It dials through the proxy and performs an HTTP GET to confirm traffic flows.
 func CheckSOCKS5(address string, opts Options) Result {
+	return checkSOCKS5(context.Background(), address, opts)
+}
+
+func checkSOCKS5(ctx context.Context, address string, opts Options) Result {
 	result := Result{Address: address, Protocol: ProtocolSOCKS5}
 
 	proxyURL, err := url.Parse(address)
 	if err != nil {
-		result.Error = fmt.Sprintf("invalid socks5 URL: %v", err)
+		result.fail(PhaseParse, "invalid socks5 URL", err)
+		return result
+	}
+	withCredentials(proxyURL, opts)
+	override, err := OverrideFor(proxyURL, opts.Overrides)
+	if err != nil {
+		result.fail(PhaseParse, "invalid proxy override", err)
 		return result
 	}
 
@@ -28,26 +47,44 @@ func CheckSOCKS5(address string, opts Options) Result {
 		host = host + ":1080"
 	}
 
-	tcpLatency, err := tcpProbe(host, opts.Timeout)
+	tcpLatency, family, err := tcpProbe(ctx, host, opts.connectTimeout())
 	if err != nil {
-		result.Error = fmt.Sprintf("tcp probe: %v", err)
+		result.fail(PhaseConnect, "tcp probe", err)
 		return result
 	}
+	result.Family = family
+
+	if opts.ProbeBind {
+		ok, _, err := probeBind(ctx, host, proxyURL.User, bindTarget(opts.TestURL), opts.Timeout)
+		if err == nil {
+			result.BindSupported = &ok
+		}
+	}
 
 	// Second: route an HTTP request through the SOCKS5 proxy.
-	dialer, err := proxy.FromURL(proxyURL, proxy.Direct)
+	hop := &hopTimer{forward: proxyproto.Dialer{Forward: resolver.Default(), Version: opts.ProxyProtocol}, timeout: opts.connectTimeout()}
+	greeting := &authRecorder{forward: hop}
+	dialer, err := SOCKS5Dialer(proxyURL, greeting)
 	if err != nil {
-		result.Error = fmt.Sprintf("socks5 dialer: %v", err)
+		result.fail(PhaseParse, "socks5 dialer", err)
 		return result
 	}
+	remote := RemoteDNS(address)
 
 	transport := &http.Transport{
-		Dial:              dialer.Dial,
+		DialContext:       tracedDial(HandshakeDialer{Dialer: dialer, Timeout: opts.handshakeTimeout()}),
 		DisableKeepAlives: true,
+		TLSClientConfig:   ClientTLS(opts.RootCAs, opts.InsecureTLS),
 	}
+	var targetSession atomic.Pointer[tls.ConnectionState]
+	hello := ClientHello{
+		Fingerprint: opts.TLSFingerprint,
+		OnHandshake: func(cs tls.ConnectionState) { targetSession.CompareAndSwap(nil, &cs) },
+	}
+	hello.Apply(transport, proxyURL)
 	client := &http.Client{
-		Transport: transport,
-		Timeout:   opts.Timeout,
+		Transport: override.Apply(transport, proxyURL),
+		Timeout:   opts.requestTimeout(),
 		CheckRedirect: func(*http.Request, []*http.Request) error {
 			return http.ErrUseLastResponse
 		},
@@ -58,20 +95,118 @@ func CheckSOCKS5(address string, opts Options) Result {
 		testURL = "http://www.google.com"
 	}
 
+	req, err := opts.Request.New(ctx, testURL)
+	if err != nil {
+		result.fail(PhaseParse, "invalid test URL", err)
+		return result
+	}
+
 	start := time.Now()
-	resp, err := client.Get(testURL)
+	resp, err := client.Do(tracing.WithClientTrace(req))
+	if err != nil && remote && hostnameRefused(err) {
+		// A socks5h proxy that takes no hostnames leaves clients to
+		// resolve them locally; check that they get through that way.
+		transport.DialContext = tracedDial(HandshakeDialer{Dialer: localDNS{dialer}, Timeout: opts.handshakeTimeout()})
+		hello.Apply(transport, proxyURL)
+		remote = false
+		if req, err = opts.Request.New(ctx, testURL); err == nil {
+			start = time.Now()
+			resp, err = client.Do(tracing.WithClientTrace(req))
+		}
+	}
 	elapsed := time.Since(start)
+	result.AuthMethod = greeting.method()
+	if opts.ProbeAuth {
+		// After the timed request, so its connections stay out of the
+		// latency.
+		result.AuthMethods = probeAuthMethods(ctx, host, opts)
+	}
 
 	if err != nil {
 		// Proxy is reachable but won't forward — still partially alive.
 		result.Alive = false
 		result.Latency = tcpLatency
-		result.Error = fmt.Sprintf("forward check: %v", err)
+		result.fail(PhaseRequest, "forward check", err)
+		result.TLS = failedSessionInfo(err, testURL, opts.RootCAs)
 		return result
 	}
 	resp.Body.Close()
+	result.TLS = sessionInfo(cmp.Or(resp.TLS, targetSession.Load()), testURL, opts.RootCAs)
 
 	result.Alive = true
+	result.StatusCode = resp.StatusCode
 	result.Latency = elapsed
+	if net.ParseIP(req.URL.Hostname()) == nil {
+		result.DNSResolution = ResolveLocal
+		if remote {
+			result.DNSResolution = ResolveRemote
+		}
+	}
+	splitLatency(&result, hop.took)
+	if opts.MTUURL != "" {
+		result.PMTUBlackhole = probeMTU(ctx, client, opts.MTUURL, opts.Timeout)
+	}
+	if opts.ExitIPURL != "" {
+		result.ExitIP = probeExitIP(ctx, client, opts.ExitIPURL)
+	}
+	if opts.RotationURL != "" {
+		result.ExitIPs, result.Rotating = probeRotation(ctx, client, opts.RotationURL, opts.RotationSamples, opts.RotationInterval)
+	}
+	if opts.DNSCanaryURL != "" && (remote || !RemoteDNS(address)) {
+		// The canary tests the proxy's resolver, so it sends the proxy
+		// hostnames even when the check resolved them locally; a proxy
+		// that refused them has none to test.
+		canary := client
+		if l, ok := dialer.(localDNS); ok {
+			t := transport.Clone()
+			t.DialContext = tracedDial(HandshakeDialer{Dialer: l.Dialer, Timeout: opts.handshakeTimeout()})
+			hello.Apply(t, proxyURL)
+			canary = &http.Client{Transport: override.Apply(t, proxyURL), Timeout: client.Timeout, CheckRedirect: client.CheckRedirect}
+		}
+		result.DNSCanary = probeDNS(ctx, canary, opts.DNSCanaryURL, opts.DNSCanaryExpect)
+	}
+	if opts.Judges != nil {
+		result.Anonymity = probeAnonymity(ctx, client, opts.Judges)
+	}
+	if opts.ContentRef != nil {
+		result.ContentModified, result.ContentDiff = probeContent(ctx, client, opts.ContentRef)
+	}
+	if opts.QUIC {
+		result.QUIC = probeQUIC(ctx, host, proxyURL.User, testURL, opts)
+	}
 	return result
 }
+
+// bindTarget returns the host:port a BIND request names as the expected
+// peer: the test URL's host, which a well-behaved proxy accepts as-is.
+func bindTarget(testURL string) string {
+	u, err := url.Parse(testURL)
+	if err != nil || u.Hostname() == "" {
+		return "0.0.0.0:0"
+	}
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
+
+// tracedDial wraps a SOCKS5 dialer so the proxy handshake shows up as its own
+// span; httptrace only sees connections made by the transport's own dialer.
+func tracedDial(d proxy.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		ctx, span := tracing.Start(ctx, "socks5.connect", trace.WithAttributes(attribute.String("net.target", addr)))
+		var conn net.Conn
+		var err error
+		if cd, ok := d.(proxy.ContextDialer); ok {
+			conn, err = cd.DialContext(ctx, network, addr)
+		} else {
+			conn, err = d.Dial(network, addr)
+		}
+		tracing.End(span, err)
+		return conn, err
+	}
+}
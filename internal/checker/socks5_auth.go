@@ -0,0 +1,154 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/net/proxy"
+
+	"github.com/drsoft-oss/proxybench/internal/proxyproto"
+	"github.com/drsoft-oss/proxybench/internal/resolver"
+	"github.com/drsoft-oss/proxybench/internal/tracing"
+)
+
+// SOCKS5 authentication methods, as reported in Result.AuthMethod and
+// Result.AuthMethods.
+const (
+	AuthNone         = "none"          // no authentication: an open proxy
+	AuthGSSAPI       = "gssapi"        // GSSAPI (Kerberos), RFC 1961
+	AuthUserPass     = "userpass"      // username/password, RFC 1929
+	AuthNoAcceptable = "no-acceptable" // the proxy refused every method offered
+)
+
+// socks5Methods are the methods probeAuthMethods offers, in report order.
+var socks5Methods = []byte{socks5AuthNone, socks5AuthGSSAPI, socks5AuthUserPwd}
+
+// authMethodName names a method code from a SOCKS5 method reply.
+func authMethodName(code byte) string {
+	switch code {
+	case socks5AuthNone:
+		return AuthNone
+	case socks5AuthGSSAPI:
+		return AuthGSSAPI
+	case socks5AuthUserPwd:
+		return AuthUserPass
+	case socks5AuthNoMatch:
+		return AuthNoAcceptable
+	}
+	return fmt.Sprintf("0x%02x", code)
+}
+
+// authRecorder wraps the dialer a SOCKS5 dialer reaches its proxy with and
+// remembers the method the proxy chose in the first greeting read through
+// it, so the check reports it without a connection of its own.
+type authRecorder struct {
+	forward proxy.ContextDialer
+	mu      sync.Mutex
+	chosen  string
+}
+
+func (a *authRecorder) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	conn, err := a.forward.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	return &greetingConn{Conn: conn, rec: a}, nil
+}
+
+func (a *authRecorder) Dial(network, addr string) (net.Conn, error) {
+	return a.DialContext(context.Background(), network, addr)
+}
+
+// method returns the method chosen in the first greeting; "" if none was
+// read.
+func (a *authRecorder) method() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.chosen
+}
+
+func (a *authRecorder) record(code byte) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.chosen == "" {
+		a.chosen = authMethodName(code)
+	}
+}
+
+// greetingConn passes the method reply, the first two bytes the proxy
+// sends, to its recorder.
+type greetingConn struct {
+	net.Conn
+	rec  *authRecorder
+	seen int
+}
+
+func (c *greetingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if c.seen < 2 && n > 0 {
+		if c.seen+n >= 2 {
+			c.rec.record(p[1-c.seen])
+		}
+		c.seen += n
+	}
+	return n, err
+}
+
+// probeAuthMethods returns the authentication methods the SOCKS5 proxy at
+// hostPort accepts: a server picks one of the methods a client offers, so
+// each is offered alone, one connection after another, each ending at the
+// method reply. A proxy that takes none of them gives AuthNoAcceptable;
+// nil means a greeting failed, as against a server that does not speak
+// SOCKS5.
+func probeAuthMethods(ctx context.Context, hostPort string, opts Options) []string {
+	ctx, span := tracing.Start(ctx, "socks5.auth_methods")
+	ctx, cancel := withTimeout(ctx, opts.handshakeTimeout())
+	defer cancel()
+
+	dialer := proxyproto.Dialer{Forward: resolver.Default(), Version: opts.ProxyProtocol}
+	var methods []string
+	for _, code := range socks5Methods {
+		ok, err := socks5Offer(ctx, dialer, hostPort, code)
+		if err != nil {
+			tracing.End(span, err)
+			return nil
+		}
+		if ok {
+			methods = append(methods, authMethodName(code))
+		}
+	}
+	tracing.End(span, nil)
+	if methods == nil {
+		return []string{AuthNoAcceptable}
+	}
+	return methods
+}
+
+// socks5Offer greets the SOCKS5 proxy at hostPort offering method alone
+// and reports whether the proxy chose it.
+func socks5Offer(ctx context.Context, dialer proxy.ContextDialer, hostPort string, method byte) (bool, error) {
+	conn, err := dialer.DialContext(ctx, "tcp", hostPort)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	conn.SetDeadline(time.Now().Add(time.Minute)) //nolint:errcheck // ctx bounds it sooner
+
+	if _, err := conn.Write([]byte{socks5Version, 1, method}); err != nil {
+		return false, fmt.Errorf("socks5 greeting: %w", err)
+	}
+	var reply [2]byte
+	if _, err := io.ReadFull(conn, reply[:]); err != nil {
+		return false, fmt.Errorf("socks5 greeting: %w", err)
+	}
+	if reply[0] != socks5Version {
+		return false, fmt.Errorf("socks5: unexpected version %d", reply[0])
+	}
+	return reply[1] == method, nil
+}
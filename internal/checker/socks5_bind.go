@@ -0,0 +1,186 @@
+package checker
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/drsoft-oss/proxybench/internal/resolver"
+	"github.com/drsoft-oss/proxybench/internal/tracing"
+)
+
+// SOCKS5 wire constants (RFC 1928 / RFC 1929).
+const (
+	socks5Version     = 0x05
+	socks5CmdBind     = 0x02
+	socks5AuthNone    = 0x00
+	socks5AuthGSSAPI  = 0x01
+	socks5AuthUserPwd = 0x02
+	socks5AuthNoMatch = 0xff
+	socks5RepOK       = 0x00
+	socks5RepNoCmd    = 0x07
+)
+
+var errSOCKS5Auth = errors.New("socks5: no acceptable authentication method")
+
+// probeBind asks the proxy at hostPort to BIND (open a listening port for
+// an inbound connection, as used by active-mode FTP). It reports whether
+// the proxy accepted the command and, if so, the address it bound.
+// The connection is closed straight after the first reply, so no inbound
+// peer is ever expected.
+func probeBind(ctx context.Context, hostPort string, user *url.Userinfo, target string, timeout time.Duration) (bool, string, error) {
+	ctx, span := tracing.Start(ctx, "socks5.bind")
+	ok, bound, err := func() (bool, string, error) {
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		conn, err := resolver.Default().DialContext(ctx, "tcp", hostPort)
+		if err != nil {
+			return false, "", err
+		}
+		defer conn.Close()
+		if dl, ok := ctx.Deadline(); ok {
+			conn.SetDeadline(dl) //nolint:errcheck
+		}
+
+		if err := socks5Auth(conn, user); err != nil {
+			return false, "", err
+		}
+		rep, bound, err := socks5Request(conn, socks5CmdBind, target)
+		if err != nil {
+			return false, "", err
+		}
+		switch rep {
+		case socks5RepOK:
+			return true, bound, nil
+		case socks5RepNoCmd:
+			return false, "", nil
+		default:
+			return false, "", fmt.Errorf("socks5: bind rejected (reply %d)", rep)
+		}
+	}()
+	tracing.End(span, err)
+	return ok, bound, err
+}
+
+// socks5Auth performs method negotiation and, if requested by the server,
+// username/password authentication.
+func socks5Auth(conn net.Conn, user *url.Userinfo) error {
+	methods := []byte{socks5AuthNone}
+	if user != nil {
+		methods = append(methods, socks5AuthUserPwd)
+	}
+	greeting := append([]byte{socks5Version, byte(len(methods))}, methods...)
+	if _, err := conn.Write(greeting); err != nil {
+		return fmt.Errorf("socks5 greeting: %w", err)
+	}
+	var reply [2]byte
+	if _, err := io.ReadFull(conn, reply[:]); err != nil {
+		return fmt.Errorf("socks5 greeting: %w", err)
+	}
+	if reply[0] != socks5Version {
+		return fmt.Errorf("socks5: unexpected version %d", reply[0])
+	}
+
+	switch reply[1] {
+	case socks5AuthNone:
+		return nil
+	case socks5AuthUserPwd:
+		if user == nil {
+			return errSOCKS5Auth
+		}
+		pass, _ := user.Password()
+		name := user.Username()
+		if len(name) > 255 || len(pass) > 255 {
+			return errors.New("socks5: username or password too long")
+		}
+		req := []byte{0x01, byte(len(name))}
+		req = append(req, name...)
+		req = append(req, byte(len(pass)))
+		req = append(req, pass...)
+		if _, err := conn.Write(req); err != nil {
+			return fmt.Errorf("socks5 auth: %w", err)
+		}
+		if _, err := io.ReadFull(conn, reply[:]); err != nil {
+			return fmt.Errorf("socks5 auth: %w", err)
+		}
+		if reply[1] != 0x00 {
+			return errors.New("socks5: authentication failed")
+		}
+		return nil
+	default:
+		return errSOCKS5Auth
+	}
+}
+
+// socks5Request sends cmd for target ("host:port") and returns the reply
+// code and the bound address from the first reply.
+func socks5Request(conn net.Conn, cmd byte, target string) (byte, string, error) {
+	host, portStr, err := net.SplitHostPort(target)
+	if err != nil {
+		return 0, "", err
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return 0, "", fmt.Errorf("socks5: bad port %q", portStr)
+	}
+
+	req := []byte{socks5Version, cmd, 0x00}
+	if ip := net.ParseIP(host); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			req = append(append(req, 0x01), ip4...)
+		} else {
+			req = append(append(req, 0x04), ip.To16()...)
+		}
+	} else {
+		if len(host) > 255 {
+			return 0, "", errors.New("socks5: hostname too long")
+		}
+		req = append(append(req, 0x03, byte(len(host))), host...)
+	}
+	req = binary.BigEndian.AppendUint16(req, uint16(port))
+	if _, err := conn.Write(req); err != nil {
+		return 0, "", fmt.Errorf("socks5 request: %w", err)
+	}
+
+	var head [4]byte
+	if _, err := io.ReadFull(conn, head[:]); err != nil {
+		return 0, "", fmt.Errorf("socks5 reply: %w", err)
+	}
+	if head[0] != socks5Version {
+		return 0, "", fmt.Errorf("socks5: unexpected version %d", head[0])
+	}
+	var addrLen int
+	switch head[3] {
+	case 0x01:
+		addrLen = net.IPv4len
+	case 0x04:
+		addrLen = net.IPv6len
+	case 0x03:
+		var l [1]byte
+		if _, err := io.ReadFull(conn, l[:]); err != nil {
+			return 0, "", fmt.Errorf("socks5 reply: %w", err)
+		}
+		addrLen = int(l[0])
+	default:
+		// Failure replies from some servers carry no usable address.
+		return head[1], "", nil
+	}
+	buf := make([]byte, addrLen+2)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return head[1], "", nil
+	}
+	boundHost := string(buf[:addrLen])
+	if head[3] != 0x03 {
+		boundHost = net.IP(buf[:addrLen]).String()
+	}
+	return head[1], net.JoinHostPort(boundHost, strconv.Itoa(int(binary.BigEndian.Uint16(buf[addrLen:])))), nil
+}
@@ -0,0 +1,78 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/proxy"
+
+	"github.com/drsoft-oss/proxybench/internal/resolver"
+)
+
+// Where the target hostname of a SOCKS5 check was resolved
+// (Result.DNSResolution).
+const (
+	ResolveRemote = "remote" // by the proxy (socks5h://)
+	ResolveLocal  = "local"  // by proxybench, the proxy given an address
+)
+
+// RemoteDNS reports whether address asks its SOCKS5 proxy to resolve
+// target hostnames, as socks5h:// does in curl; socks5:// resolves them
+// locally.
+func RemoteDNS(address string) bool {
+	scheme, _, ok := strings.Cut(address, "://")
+	return ok && strings.EqualFold(scheme, "socks5h")
+}
+
+// SOCKS5Dialer returns a dialer through the SOCKS5 proxy at u, reached
+// through forward. A socks5h:// proxy is sent target hostnames to resolve;
+// for socks5:// they are resolved here first, with the shared resolver.
+func SOCKS5Dialer(u *url.URL, forward proxy.Dialer) (proxy.Dialer, error) {
+	d, err := proxy.FromURL(u, forward)
+	if err != nil || RemoteDNS(u.String()) {
+		return d, err
+	}
+	return localDNS{d}, nil
+}
+
+// localDNS resolves target hostnames before dialing through a proxy, so
+// the proxy only ever sees addresses.
+type localDNS struct {
+	proxy.Dialer
+}
+
+func (l localDNS) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if host, port, err := net.SplitHostPort(addr); err == nil && net.ParseIP(host) == nil {
+		addrs, err := resolver.Default().LookupHost(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		if len(addrs) == 0 {
+			return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+		}
+		addr = net.JoinHostPort(addrs[0].Unmap().String(), port)
+	}
+	if cd, ok := l.Dialer.(proxy.ContextDialer); ok {
+		return cd.DialContext(ctx, network, addr)
+	}
+	return l.Dialer.Dial(network, addr)
+}
+
+func (l localDNS) Dial(network, addr string) (net.Conn, error) {
+	return l.DialContext(context.Background(), network, addr)
+}
+
+// hostnameRefused reports whether a SOCKS5 dial failed because the proxy
+// does not take hostnames: it answered "address type not supported", so
+// clients fall back to resolving them locally.
+func hostnameRefused(err error) bool {
+	for ; err != nil; err = errors.Unwrap(err) {
+		if strings.Contains(err.Error(), "address type not supported") {
+			return true
+		}
+	}
+	return false
+}
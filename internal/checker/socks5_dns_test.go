@@ -0,0 +1,72 @@
+package checker
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/drsoft-oss/proxybench/internal/testproxy"
+)
+
+func TestCheck_socks5DNSResolution(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+
+	cases := []struct {
+		scheme  string
+		refuse  bool
+		testURL string
+		want    string
+		atyp    byte // of the request that got through
+		leak    bool
+	}{
+		{"socks5", false, "http://localhost:" + port, ResolveLocal, 1, false},
+		{"socks5h", false, "http://localhost:" + port, ResolveRemote, 3, false},
+		{"socks5h", true, "http://localhost:" + port, ResolveLocal, 1, true},
+		{"socks5h", false, srv.URL, "", 1, false},
+	}
+	for _, c := range cases {
+		proxy := &testproxy.SOCKS5{Target: srv.Listener.Addr().String()}
+		if c.refuse {
+			// As proxies without remote DNS answer.
+			proxy.Reply = func(r testproxy.Request) byte {
+				if r.AddrType == testproxy.AddrDomain {
+					return 0x08 // address type not supported
+				}
+				return 0
+			}
+		}
+		addr := proxy.Start(t)
+		opts := DefaultOptions()
+		opts.TestURL = c.testURL
+		r := Check(c.scheme+"://"+addr, opts)
+		if !r.Alive {
+			t.Errorf("%s refuse=%v: not alive: %s", c.scheme, c.refuse, r.Error)
+			continue
+		}
+		if r.DNSResolution != c.want {
+			t.Errorf("%s refuse=%v %s: DNSResolution = %q, want %q", c.scheme, c.refuse, c.testURL, r.DNSResolution, c.want)
+		}
+		if reqs := proxy.Requests(); len(reqs) == 0 || reqs[len(reqs)-1].AddrType != c.atyp {
+			t.Errorf("%s refuse=%v: requests = %v, want the last of address type %d", c.scheme, c.refuse, reqs, c.atyp)
+		}
+		if leak := strings.Contains(r.Warning, "DNS leak"); leak != c.leak {
+			t.Errorf("%s refuse=%v: warning = %q", c.scheme, c.refuse, r.Warning)
+		}
+	}
+}
+
+func TestDetectProtocol_socks5h(t *testing.T) {
+	if got := DetectProtocol("socks5h://1.2.3.4:1080"); got != ProtocolSOCKS5 {
+		t.Errorf("DetectProtocol(socks5h) = %q", got)
+	}
+	if got := StripScheme("socks5h://1.2.3.4:1080", ProtocolSOCKS5); got != "1.2.3.4:1080" {
+		t.Errorf("StripScheme(socks5h) = %q", got)
+	}
+	if a, b := Normalize("SOCKS5H://Host:1080"), Normalize("socks5h://host"); a != b {
+		t.Errorf("Normalize: %q != %q", a, b)
+	}
+}
@@ -0,0 +1,128 @@
+package checker
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/drsoft-oss/proxybench/internal/testproxy"
+)
+
+func TestProbeBind(t *testing.T) {
+	cases := []struct {
+		name string
+		user *url.Userinfo
+		srv  string
+		rep  byte
+		want bool
+	}{
+		{"supported", nil, "", socks5RepOK, true},
+		{"not supported", nil, "", socks5RepNoCmd, false},
+		{"with auth", url.UserPassword("bob", "pw"), "bob", socks5RepOK, true},
+	}
+	for _, c := range cases {
+		srv := &testproxy.SOCKS5{Reply: func(testproxy.Request) byte { return c.rep }}
+		if c.srv != "" {
+			srv.Methods, srv.User = []byte{testproxy.MethodUserPass}, c.srv
+		}
+		addr := srv.Start(t)
+		ok, bound, err := probeBind(context.Background(), addr, c.user, "example.com:80", time.Second)
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if ok != c.want {
+			t.Errorf("%s: supported = %v, want %v", c.name, ok, c.want)
+		}
+		if ok && bound != addr {
+			t.Errorf("%s: bound = %q", c.name, bound)
+		}
+	}
+}
+
+func TestProbeBind_authRequired(t *testing.T) {
+	addr := (&testproxy.SOCKS5{Methods: []byte{testproxy.MethodUserPass}, User: "bob"}).Start(t)
+	if _, _, err := probeBind(context.Background(), addr, nil, "example.com:80", time.Second); err == nil {
+		t.Error("expected error when the proxy demands credentials we lack")
+	}
+}
+
+func TestBindTarget(t *testing.T) {
+	cases := map[string]string{
+		"http://www.google.com":     "www.google.com:80",
+		"https://example.com/x":     "example.com:443",
+		"http://1.2.3.4:8080/probe": "1.2.3.4:8080",
+		"":                          "0.0.0.0:0",
+	}
+	for in, want := range cases {
+		if got := bindTarget(in); got != want {
+			t.Errorf("bindTarget(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestProbeAuthMethods(t *testing.T) {
+	cases := []struct {
+		name   string
+		accept []byte
+		want   []string
+	}{
+		{"open", []byte{testproxy.MethodNone}, []string{AuthNone}},
+		{"password", []byte{testproxy.MethodUserPass}, []string{AuthUserPass}},
+		{"all", []byte{testproxy.MethodUserPass, testproxy.MethodGSSAPI, testproxy.MethodNone}, []string{AuthNone, AuthGSSAPI, AuthUserPass}},
+		{"none acceptable", []byte{}, []string{AuthNoAcceptable}},
+	}
+	opts := Options{Timeout: time.Second}
+	for _, c := range cases {
+		got := probeAuthMethods(context.Background(), (&testproxy.SOCKS5{Methods: c.accept}).Start(t), opts)
+		if !slices.Equal(got, c.want) {
+			t.Errorf("%s: methods = %q, want %q", c.name, got, c.want)
+		}
+	}
+
+	// Not a SOCKS5 server: it answers in HTTP.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			io.WriteString(c, "HTTP/1.1 400 Bad Request\r\n\r\n") //nolint:errcheck
+			c.Close()
+		}
+	}()
+	if got := probeAuthMethods(context.Background(), ln.Addr().String(), opts); got != nil {
+		t.Errorf("HTTP server: methods = %q, want nil", got)
+	}
+}
+
+func TestCheckSOCKS5_authMethod(t *testing.T) {
+	cases := []struct {
+		name   string
+		accept []byte
+		probe  bool
+		method string
+		list   []string
+	}{
+		{"open", []byte{testproxy.MethodNone}, false, AuthNone, nil},
+		{"login needed", []byte{testproxy.MethodUserPass}, false, AuthNoAcceptable, nil},
+		{"probed", []byte{testproxy.MethodUserPass, testproxy.MethodNone}, true, AuthNone, []string{AuthNone, AuthUserPass}},
+	}
+	fail := func(testproxy.Request) byte { return 0x01 } // keep the request off the network
+	for _, c := range cases {
+		addr := (&testproxy.SOCKS5{Methods: c.accept, Reply: fail}).Start(t)
+		r := CheckSOCKS5("socks5://"+addr, Options{Timeout: time.Second, TestURL: "http://192.0.2.1", ProbeAuth: c.probe})
+		if r.AuthMethod != c.method || !slices.Equal(r.AuthMethods, c.list) {
+			t.Errorf("%s: auth = %q %q, want %q %q", c.name, r.AuthMethod, r.AuthMethods, c.method, c.list)
+		}
+	}
+}
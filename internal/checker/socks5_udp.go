@@ -0,0 +1,137 @@
+package checker
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/drsoft-oss/proxybench/internal/resolver"
+)
+
+const socks5CmdUDPAssociate = 0x03
+
+// ErrUDPUnsupported is returned by AssociateUDP when the proxy answers
+// UDP ASSOCIATE with "command not supported".
+var ErrUDPUnsupported = errors.New("socks5: UDP ASSOCIATE not supported")
+
+// UDPRelay is a SOCKS5 UDP ASSOCIATE session (RFC 1928 section 7): the
+// control connection that keeps the association open and a UDP socket
+// talking to the relay the proxy allocated.
+type UDPRelay struct {
+	ctrl  net.Conn
+	conn  *net.UDPConn
+	Relay string // host:port of the proxy's UDP relay
+}
+
+// AssociateUDP opens a UDP association with the SOCKS5 proxy at hostPort.
+// ctx bounds the handshake only; Close ends the association.
+func AssociateUDP(ctx context.Context, hostPort string, user *url.Userinfo) (*UDPRelay, error) {
+	ctrl, err := resolver.Default().DialContext(ctx, "tcp", hostPort)
+	if err != nil {
+		return nil, err
+	}
+	if dl, ok := ctx.Deadline(); ok {
+		ctrl.SetDeadline(dl) //nolint:errcheck
+	}
+	fail := func(err error) (*UDPRelay, error) {
+		ctrl.Close()
+		return nil, err
+	}
+	if err := socks5Auth(ctrl, user); err != nil {
+		return fail(err)
+	}
+	// The client does not know which address it will send from, so it
+	// announces 0.0.0.0:0 and lets the relay learn it from the first packet.
+	rep, bound, err := socks5Request(ctrl, socks5CmdUDPAssociate, "0.0.0.0:0")
+	switch {
+	case err != nil:
+		return fail(err)
+	case rep == socks5RepNoCmd:
+		return fail(ErrUDPUnsupported)
+	case rep != socks5RepOK:
+		return fail(fmt.Errorf("socks5: UDP ASSOCIATE rejected (reply %d)", rep))
+	}
+	ctrl.SetDeadline(time.Time{}) //nolint:errcheck
+
+	// A relay bound to the unspecified address is on the proxy's own host.
+	relayHost, relayPort, err := net.SplitHostPort(bound)
+	if err != nil {
+		return fail(fmt.Errorf("socks5: bad relay address %q", bound))
+	}
+	if ip := net.ParseIP(relayHost); ip == nil || ip.IsUnspecified() {
+		remote, _, _ := net.SplitHostPort(ctrl.RemoteAddr().String())
+		relayHost = remote
+	}
+	relay := net.JoinHostPort(relayHost, relayPort)
+	raddr, err := net.ResolveUDPAddr("udp", relay)
+	if err != nil {
+		return fail(err)
+	}
+	conn, err := net.DialUDP("udp", nil, raddr)
+	if err != nil {
+		return fail(err)
+	}
+	return &UDPRelay{ctrl: ctrl, conn: conn, Relay: relay}, nil
+}
+
+// Exchange sends payload to target ("ip:port") through the relay and
+// returns the payload of the first datagram that comes back from target
+// before deadline.
+func (u *UDPRelay) Exchange(target string, payload []byte, deadline time.Time) ([]byte, error) {
+	head, err := udpHeader(target)
+	if err != nil {
+		return nil, err
+	}
+	u.conn.SetDeadline(deadline) //nolint:errcheck
+	if _, err := u.conn.Write(append(head, payload...)); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 64<<10)
+	for {
+		n, err := u.conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		// Skip anything not from target, e.g. a late reply to an earlier
+		// query sent elsewhere.
+		if n > len(head) && bytes.Equal(buf[:len(head)], head) {
+			return append([]byte(nil), buf[len(head):n]...), nil
+		}
+	}
+}
+
+// Close ends the association.
+func (u *UDPRelay) Close() error {
+	u.conn.Close()
+	return u.ctrl.Close()
+}
+
+// udpHeader is the SOCKS5 UDP request header for an IP target: two
+// reserved bytes, fragment 0, then the address as in a TCP request.
+func udpHeader(target string) ([]byte, error) {
+	host, portStr, err := net.SplitHostPort(target)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("socks5: bad port %q", portStr)
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil, fmt.Errorf("socks5: UDP target must be an IP address, got %q", host)
+	}
+	head := []byte{0, 0, 0}
+	if ip4 := ip.To4(); ip4 != nil {
+		head = append(append(head, 0x01), ip4...)
+	} else {
+		head = append(append(head, 0x04), ip.To16()...)
+	}
+	return binary.BigEndian.AppendUint16(head, uint16(port)), nil
+}
@@ -0,0 +1,56 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/drsoft-oss/proxybench/internal/testproxy"
+)
+
+func TestAssociateUDP(t *testing.T) {
+	addr := (&testproxy.SOCKS5{}).Start(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	u, err := AssociateUDP(ctx, addr, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer u.Close()
+	if host, _, _ := net.SplitHostPort(u.Relay); host != "127.0.0.1" {
+		t.Errorf("Relay = %q, want the proxy's own host", u.Relay)
+	}
+	got, err := u.Exchange("9.9.9.9:53", []byte("ping"), time.Now().Add(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "ping" {
+		t.Errorf("Exchange = %q, want %q", got, "ping")
+	}
+}
+
+func TestAssociateUDP_unsupported(t *testing.T) {
+	refuse := func(testproxy.Request) byte { return socks5RepNoCmd }
+	addr := (&testproxy.SOCKS5{Reply: refuse}).Start(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := AssociateUDP(ctx, addr, nil); !errors.Is(err, ErrUDPUnsupported) {
+		t.Errorf("err = %v, want ErrUDPUnsupported", err)
+	}
+}
+
+func TestUDPHeader(t *testing.T) {
+	head, err := udpHeader("1.2.3.4:53")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{0, 0, 0, 1, 1, 2, 3, 4, 0, 53}
+	if string(head) != string(want) {
+		t.Errorf("udpHeader = %v, want %v", head, want)
+	}
+	if _, err := udpHeader("dns.example:53"); err == nil {
+		t.Error("expected error for a hostname target")
+	}
+}
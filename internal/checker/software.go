@@ -0,0 +1,80 @@
+package checker
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+)
+
+// softwareSignature names proxy software found by a case-insensitive
+// substring in a header, or in an error-page body when header is "".
+type softwareSignature struct {
+	software string
+	header   string
+	contains string
+}
+
+// softwareSignatures are tried in order. Via is added by the proxy on every
+// response (CDN markers such as Varnish are left out, as the target may be
+// behind one); Server and the body count only on responses the proxy
+// generated itself (see identifySoftware).
+var softwareSignatures = []softwareSignature{
+	{"Squid", "X-Squid-Error", ""},
+	{"Squid", "Via", "squid"},
+	{"Squid", "Server", "squid"},
+	{"Squid", "", "generated by squid"},
+	{"Squid", "", "(squid/"},
+	{"Tinyproxy", "Via", "tinyproxy"},
+	{"Tinyproxy", "Server", "tinyproxy"},
+	{"Tinyproxy", "", "generated by tinyproxy"},
+	{"3proxy", "Server", "3proxy"},
+	{"3proxy", "", "3proxy"},
+	{"Privoxy", "Proxy-Agent", "privoxy"},
+	{"Privoxy", "Server", "privoxy"},
+	{"Privoxy", "", "privoxy"},
+	{"Polipo", "Server", "polipo"},
+	{"CCProxy", "Server", "ccproxy"},
+	{"CCProxy", "Proxy-Agent", "ccproxy"},
+	{"MikroTik", "Server", "mikrotik"},
+	{"MikroTik", "Proxy-Agent", "mikrotik"},
+	{"Apache Traffic Server", "Via", "apachetrafficserver"},
+	{"Apache Traffic Server", "Server", "ats/"},
+	{"HAProxy", "", "haproxy"},
+	{"nginx", "Server", "nginx"},
+	{"Apache httpd", "Server", "apache"},
+}
+
+// proxyGenerated reports whether a response to a forwarded GET most likely
+// came from the proxy rather than the target: an auth challenge or a
+// gateway error.
+func proxyGenerated(status int) bool {
+	switch status {
+	case http.StatusProxyAuthRequired, http.StatusBadGateway, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// identifySoftware matches h and body against softwareSignatures. Server
+// headers and bodies are only considered when own is set, i.e. the response
+// was the proxy's own (a CONNECT reply or a proxyGenerated status), because
+// otherwise they describe the target. It returns "" when nothing matches.
+func identifySoftware(h http.Header, body []byte, own bool) string {
+	lowBody := bytes.ToLower(body)
+	for _, sig := range softwareSignatures {
+		switch {
+		case sig.header == "":
+			if own && bytes.Contains(lowBody, []byte(sig.contains)) {
+				return sig.software
+			}
+		case sig.header == "Server" && !own:
+		default:
+			for _, v := range h.Values(sig.header) {
+				if strings.Contains(strings.ToLower(v), sig.contains) {
+					return sig.software
+				}
+			}
+		}
+	}
+	return ""
+}
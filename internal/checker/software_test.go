@@ -0,0 +1,55 @@
+package checker
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestIdentifySoftware(t *testing.T) {
+	hdr := func(kv ...string) http.Header {
+		h := http.Header{}
+		for i := 0; i < len(kv); i += 2 {
+			h.Add(kv[i], kv[i+1])
+		}
+		return h
+	}
+	cases := []struct {
+		name string
+		h    http.Header
+		body string
+		own  bool
+		want string
+	}{
+		{"via squid", hdr("Via", "1.1 proxy1 (squid/6.6)"), "", false, "Squid"},
+		{"via tinyproxy", hdr("Via", "1.1 tinyproxy (tinyproxy/1.11.1)"), "", false, "Tinyproxy"},
+		{"squid error header", hdr("X-Squid-Error", "ERR_ACCESS_DENIED 0"), "", false, "Squid"},
+		{"target server ignored", hdr("Server", "nginx/1.25"), "", false, ""},
+		{"proxy server header", hdr("Server", "nginx/1.25"), "", true, "nginx"},
+		{"3proxy error page", hdr(), "<h2>407 Proxy Authentication Required</h2><small>3proxy</small>", true, "3proxy"},
+		{"target body ignored", hdr(), "powered by squid", false, ""},
+		{"privoxy connect", hdr("Proxy-Agent", "Privoxy 3.0.34"), "", true, "Privoxy"},
+		{"nothing", hdr("Content-Type", "text/html"), "", true, ""},
+	}
+	for _, tc := range cases {
+		if got := identifySoftware(tc.h, []byte(tc.body), tc.own); got != tc.want {
+			t.Errorf("%s: identifySoftware = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestCheckHTTP_software(t *testing.T) {
+	// An HTTP server answers absolute-URI requests like a proxy would.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "squid/5.7")
+		w.WriteHeader(http.StatusProxyAuthRequired)
+		w.Write([]byte("Generated Mon, 01 Jan 2024 by proxy (squid/5.7)")) //nolint:errcheck
+	}))
+	defer srv.Close()
+
+	r := CheckHTTP(srv.URL, Options{Timeout: 2 * time.Second, TestURL: "https://example.invalid/"})
+	if r.Software != "Squid" {
+		t.Errorf("Software = %q, want Squid", r.Software)
+	}
+}
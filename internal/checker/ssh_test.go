@@ -0,0 +1,167 @@
+package checker
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// fakeSSH is an SSH server that accepts user/pass or key, or a
+// keyboard-interactive answer "pass" to a password prompt (a one-time
+// code prompt for user "otp"), and forwards direct-tcpip channels. It returns
+// its address and host key.
+func fakeSSH(t *testing.T, key ssh.PublicKey) (string, ssh.PublicKey) {
+	t.Helper()
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	hostKey, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &ssh.ServerConfig{
+		PasswordCallback: func(c ssh.ConnMetadata, pw []byte) (*ssh.Permissions, error) {
+			if c.User() == "user" && string(pw) == "pass" {
+				return nil, nil
+			}
+			return nil, io.EOF
+		},
+		PublicKeyCallback: func(_ ssh.ConnMetadata, k ssh.PublicKey) (*ssh.Permissions, error) {
+			if key != nil && string(k.Marshal()) == string(key.Marshal()) {
+				return nil, nil
+			}
+			return nil, io.EOF
+		},
+		KeyboardInteractiveCallback: func(c ssh.ConnMetadata, challenge ssh.KeyboardInteractiveChallenge) (*ssh.Permissions, error) {
+			prompt := "Password: "
+			if c.User() == "otp" {
+				prompt = "Verification code: "
+			}
+			answers, err := challenge(c.User(), "", []string{prompt}, []bool{false})
+			if err != nil || len(answers) != 1 || answers[0] != "pass" {
+				return nil, io.EOF
+			}
+			return nil, nil
+		},
+	}
+	cfg.AddHostKey(hostKey)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_, chans, reqs, err := ssh.NewServerConn(c, cfg)
+				if err != nil {
+					return
+				}
+				go ssh.DiscardRequests(reqs)
+				for nc := range chans {
+					extra := nc.ExtraData() // host, port, origin host, origin port
+					n := binary.BigEndian.Uint32(extra)
+					host, port := string(extra[4:4+n]), binary.BigEndian.Uint32(extra[4+n:])
+					target, err := net.Dial("tcp", net.JoinHostPort(host, strconv.FormatUint(uint64(port), 10)))
+					if err != nil {
+						nc.Reject(ssh.ConnectionFailed, err.Error()) //nolint:errcheck
+						continue
+					}
+					ch, creqs, _ := nc.Accept()
+					go ssh.DiscardRequests(creqs)
+					go func() { io.Copy(ch, target); ch.Close() }() //nolint:errcheck
+					go func() { io.Copy(target, ch); target.Close() }()
+				}
+			}()
+		}
+	}()
+	return ln.Addr().String(), hostKey.PublicKey()
+}
+
+func TestCheckSSH(t *testing.T) {
+	web := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer web.Close()
+
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	key, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv, hostKey := fakeSSH(t, key.PublicKey())
+	otherKey, _ := ssh.NewSignerFromKey(ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize)))
+
+	for _, tt := range []struct {
+		name, addr string
+		key        ssh.Signer
+		hostKey    ssh.PublicKey
+		alive      bool
+		kind       ErrorKind
+	}{
+		{"password", "ssh://user:pass@" + srv, nil, hostKey, true, ""},
+		{"key", "ssh://user@" + srv, key, hostKey, true, ""},
+		{"password prompt", "ssh://kbd:pass@" + srv, nil, hostKey, true, ""},
+		{"other prompt", "ssh://otp:pass@" + srv, nil, hostKey, false, ErrAuth},
+		{"wrong password", "ssh://user:nope@" + srv, nil, hostKey, false, ErrAuth},
+		{"no credentials", "ssh://user@" + srv, nil, hostKey, false, ErrConfig},
+		{"wrong host key", "ssh://user:pass@" + srv, nil, otherKey.PublicKey(), false, ErrTLS},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultOptions()
+			opts.Timeout = 2 * time.Second
+			opts.TestURL = web.URL
+			opts.SSHKey = tt.key
+			opts.SSHHostKeys = ssh.FixedHostKey(tt.hostKey)
+			r := Check(tt.addr, opts)
+			if r.Protocol != ProtocolSSH || r.Alive != tt.alive {
+				t.Fatalf("Check = %s alive=%v (%s), want ssh alive=%v", r.Protocol, r.Alive, r.Error, tt.alive)
+			}
+			if tt.alive && r.StatusCode != http.StatusNoContent {
+				t.Errorf("StatusCode = %d, want 204", r.StatusCode)
+			}
+			if !tt.alive && r.Failure.Kind != tt.kind {
+				t.Errorf("failure kind = %s (%s), want %s", r.Failure.Kind, r.Error, tt.kind)
+			}
+		})
+	}
+}
+
+func TestKnownHosts(t *testing.T) {
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	signer, _ := ssh.NewSignerFromKey(priv)
+	addr := &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 2222}
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := KnownHosts()("[192.0.2.1]:2222", addr, signer.PublicKey()); err == nil {
+		t.Error("no known_hosts file: want error")
+	}
+
+	os.Mkdir(filepath.Join(home, ".ssh"), 0o700) //nolint:errcheck
+	line := knownhosts.Line([]string{knownhosts.Normalize("192.0.2.1:2222")}, signer.PublicKey())
+	if err := os.WriteFile(filepath.Join(home, ".ssh", "known_hosts"), []byte(line+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := KnownHosts()("192.0.2.1:2222", addr, signer.PublicKey()); err != nil {
+		t.Errorf("known host: %v", err)
+	}
+	if err := KnownHosts()("192.0.2.9:2222", &net.TCPAddr{IP: net.IPv4(192, 0, 2, 9), Port: 2222}, signer.PublicKey()); classify(err).Kind != ErrTLS {
+		t.Errorf("unknown host: %v (%s), want a tls-kind error", err, classify(err).Kind)
+	}
+}
@@ -0,0 +1,141 @@
+package checker
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// TLS policies recorded in Result.TLSPolicy when verification is not the
+// default one.
+const (
+	TLSPolicyCACert   = "ca-cert"  // extra roots were trusted
+	TLSPolicyInsecure = "insecure" // certificates were not verified
+)
+
+// TLSInfo describes the TLS session with an https target reached through
+// the proxy, as the client saw it. A proxy that intercepts TLS shows up
+// with a certificate that does not verify; one that downgrades with an
+// old version.
+type TLSInfo struct {
+	// Version and Cipher are empty when the handshake was aborted over
+	// the certificate.
+	Version string `json:"version,omitempty"` // e.g. "TLS 1.3"
+	Cipher  string `json:"cipher,omitempty"`
+	// Verified reports whether the certificate chain is valid for the
+	// target under the system roots, or Options.RootCAs; VerifyError says
+	// why not.
+	Verified    bool   `json:"verified"`
+	VerifyError string `json:"verify_error,omitempty"`
+	// Issuer is the organisation (or common name) that issued the leaf
+	// certificate, which names the interception product when there is one.
+	Issuer string `json:"issuer,omitempty"`
+}
+
+// Downgraded reports whether the session used a version below TLS 1.2,
+// which no current server picks unless something in between forced it.
+func (t *TLSInfo) Downgraded() bool {
+	switch t.Version {
+	case "SSLv3", "TLS 1.0", "TLS 1.1":
+		return true
+	}
+	return false
+}
+
+// warning describes what is wrong with the session, or "".
+func (t *TLSInfo) warning() string {
+	var w []string
+	if !t.Verified {
+		w = append(w, "TLS to target not trusted (intercepted?): "+t.VerifyError)
+	}
+	if t.Downgraded() {
+		w = append(w, "TLS downgraded to "+t.Version)
+	}
+	return strings.Join(w, "; ")
+}
+
+// LoadCACert returns the system roots plus the PEM certificates in path,
+// such as the CA of a TLS-intercepting corporate proxy.
+func LoadCACert(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("%s: no PEM certificates", path)
+	}
+	return pool, nil
+}
+
+// ClientTLS returns the client TLS configuration for roots (nil for the
+// system roots) and insecure, or nil when both are the defaults.
+func ClientTLS(roots *x509.CertPool, insecure bool) *tls.Config {
+	if roots == nil && !insecure {
+		return nil
+	}
+	return &tls.Config{RootCAs: roots, InsecureSkipVerify: insecure} //nolint:gosec // asked for with --insecure
+}
+
+// TLSPolicy names the relaxation of ClientTLS(roots, insecure) for
+// Result.TLSPolicy, or "" for none.
+func TLSPolicy(roots *x509.CertPool, insecure bool) string {
+	switch {
+	case insecure:
+		return TLSPolicyInsecure
+	case roots != nil:
+		return TLSPolicyCACert
+	}
+	return ""
+}
+
+// sessionInfo describes the session of a response from target, verifying
+// the chain itself against roots (nil for the system roots) since the
+// request may have been made without.
+func sessionInfo(cs *tls.ConnectionState, target string, roots *x509.CertPool) *TLSInfo {
+	if cs == nil || len(cs.PeerCertificates) == 0 {
+		return nil
+	}
+	info := certInfo(cs.PeerCertificates, target, roots)
+	info.Version = tls.VersionName(cs.Version)
+	info.Cipher = tls.CipherSuiteName(cs.CipherSuite)
+	return info
+}
+
+// failedSessionInfo describes a session that err, from a request to
+// target, aborted over the certificate; nil for any other error.
+func failedSessionInfo(err error, target string, roots *x509.CertPool) *TLSInfo {
+	var certErr *tls.CertificateVerificationError
+	if !errors.As(err, &certErr) || len(certErr.UnverifiedCertificates) == 0 {
+		return nil
+	}
+	return certInfo(certErr.UnverifiedCertificates, target, roots)
+}
+
+func certInfo(chain []*x509.Certificate, target string, roots *x509.CertPool) *TLSInfo {
+	host := ""
+	if u, err := url.Parse(target); err == nil {
+		host = u.Hostname()
+	}
+	inter := x509.NewCertPool()
+	for _, c := range chain[1:] {
+		inter.AddCert(c)
+	}
+	leaf := chain[0]
+	_, err := leaf.Verify(x509.VerifyOptions{DNSName: host, Intermediates: inter, Roots: roots})
+	info := &TLSInfo{Verified: err == nil, Issuer: leaf.Issuer.CommonName}
+	if err != nil {
+		info.VerifyError = err.Error()
+	}
+	if len(leaf.Issuer.Organization) > 0 {
+		info.Issuer = leaf.Issuer.Organization[0]
+	}
+	return info
+}
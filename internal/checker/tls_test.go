@@ -0,0 +1,106 @@
+package checker
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckHTTP_tlsInfo(t *testing.T) {
+	target := httptest.NewUnstartedServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	target.Config.ErrorLog = log.New(io.Discard, "", 0) // aborted handshakes
+	target.StartTLS()
+	defer target.Close()
+
+	proxy := fakeHTTPProxy(t, true)
+	opts := Options{Timeout: 2 * time.Second, TestURL: "http://example.invalid/", ConnectURL: target.URL}
+
+	// Untrusted: the test server's certificate stands in for a MITM one.
+	r := Check(proxy, opts)
+	if r.TLS == nil || r.TLS.Verified || r.TLS.Version == "" || r.TLS.Cipher == "" {
+		t.Fatalf("TLS = %+v, want an unverified session", r.TLS)
+	}
+	if !strings.Contains(r.Warning, "not trusted") {
+		t.Errorf("Warning = %q", r.Warning)
+	}
+	if r.ConnectSupported == nil || !*r.ConnectSupported {
+		t.Error("an untrusted certificate must not fail the CONNECT probe")
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(target.Certificate())
+	trusted := opts
+	trusted.RootCAs = roots
+	r = Check(proxy, trusted)
+	if r.TLS == nil || !r.TLS.Verified || r.TLS.Downgraded() {
+		t.Errorf("TLS = %+v, want a verified session", r.TLS)
+	}
+	if r.TLSPolicy != TLSPolicyCACert {
+		t.Errorf("TLSPolicy = %q, want %q", r.TLSPolicy, TLSPolicyCACert)
+	}
+
+	// With an https test URL the forward check enforces verification...
+	opts.TestURL = target.URL
+	if r = Check(proxy, opts); r.Alive || r.TLS == nil || r.TLS.Verified {
+		t.Errorf("alive = %v, TLS = %+v; want a failed check with the session recorded", r.Alive, r.TLS)
+	}
+	if r.TLSPolicy != "" {
+		t.Errorf("TLSPolicy = %q, want none", r.TLSPolicy)
+	}
+	// ...unless the CA is trusted...
+	trusted.TestURL = target.URL
+	if r = Check(proxy, trusted); !r.Alive || r.TLS == nil || !r.TLS.Verified {
+		t.Errorf("with RootCAs: alive = %v, TLS = %+v; want a verified check", r.Alive, r.TLS)
+	}
+	// ...or verification skipped, which still reports the session as
+	// untrusted.
+	opts.InsecureTLS = true
+	if r = Check(proxy, opts); !r.Alive || r.TLS == nil || r.TLS.Verified || r.TLSPolicy != TLSPolicyInsecure {
+		t.Errorf("insecure: alive = %v, TLS = %+v, TLSPolicy = %q", r.Alive, r.TLS, r.TLSPolicy)
+	}
+}
+
+func TestLoadCACert(t *testing.T) {
+	target := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer target.Close()
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	pemData := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: target.Certificate().Raw})
+	if err := os.WriteFile(path, pemData, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	pool, err := LoadCACert(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := target.Certificate().Verify(x509.VerifyOptions{Roots: pool}); err != nil {
+		t.Errorf("loaded pool does not trust the certificate: %v", err)
+	}
+	if err := os.WriteFile(path, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadCACert(path); err == nil {
+		t.Error("LoadCACert accepted a file without certificates")
+	}
+}
+
+func TestCheckHTTP_tlsDowngrade(t *testing.T) {
+	target := httptest.NewUnstartedServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	target.TLS = &tls.Config{MinVersion: tls.VersionTLS10, MaxVersion: tls.VersionTLS11}
+	target.StartTLS()
+	defer target.Close()
+
+	opts := Options{Timeout: 2 * time.Second, TestURL: "http://example.invalid/", ConnectURL: target.URL}
+	r := Check(fakeHTTPProxy(t, true), opts)
+	if r.TLS == nil || !r.TLS.Downgraded() || !strings.Contains(r.Warning, "downgraded to TLS 1.1") {
+		t.Errorf("TLS = %+v, Warning = %q; want a downgrade", r.TLS, r.Warning)
+	}
+}
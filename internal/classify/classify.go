@@ -0,0 +1,139 @@
+// Package classify sorts measurements into named buckets such as
+// fast/medium/slow, so results can be labelled, filtered and exported.
+package classify
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Default bucket specs: latency in milliseconds, speed in bytes/sec.
+const (
+	DefaultLatency = "fast:300,medium:1000,slow"
+	DefaultSpeed   = "fast:1MB,medium:128KB,slow"
+)
+
+// Bucket is one named class. For a latency Scale a value belongs to the
+// first bucket whose Limit it is below; for a speed Scale, the first bucket
+// whose Limit it reaches. The final bucket has no limit and catches the rest.
+type Bucket struct {
+	Name  string
+	Limit float64
+}
+
+// Scale is an ordered set of buckets.
+type Scale struct {
+	Buckets []Bucket
+	// HigherIsBetter flips the comparison: values at or above a limit
+	// match (throughput) instead of values below it (latency).
+	HigherIsBetter bool
+}
+
+// ParseLatency parses a spec like "fast:300,medium:1000,slow" (ms).
+func ParseLatency(spec string) (Scale, error) {
+	return parse(spec, false)
+}
+
+// ParseSpeed parses a spec like "fast:1MB,medium:128KB,slow" (bytes/sec;
+// K, M and G suffixes are powers of 1024, a trailing B is optional).
+func ParseSpeed(spec string) (Scale, error) {
+	return parse(spec, true)
+}
+
+// ParseBytes parses a byte count such as "128KB" or "1.5M" with the
+// suffixes ParseSpeed accepts.
+func ParseBytes(s string) (float64, error) {
+	return parseLimit(strings.TrimSpace(s), true)
+}
+
+func parse(spec string, higherIsBetter bool) (Scale, error) {
+	s := Scale{HigherIsBetter: higherIsBetter}
+	parts := strings.Split(spec, ",")
+	for i, p := range parts {
+		p = strings.TrimSpace(p)
+		name, limit, hasLimit := strings.Cut(p, ":")
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return Scale{}, fmt.Errorf("class spec %q: empty class name", spec)
+		}
+		last := i == len(parts)-1
+		if last != !hasLimit {
+			return Scale{}, fmt.Errorf("class spec %q: every class but the last needs a :limit", spec)
+		}
+		b := Bucket{Name: name}
+		if hasLimit {
+			v, err := parseLimit(strings.TrimSpace(limit), higherIsBetter)
+			if err != nil {
+				return Scale{}, fmt.Errorf("class spec %q: %w", spec, err)
+			}
+			if n := len(s.Buckets); n > 0 {
+				prev := s.Buckets[n-1].Limit
+				if (higherIsBetter && v >= prev) || (!higherIsBetter && v <= prev) {
+					return Scale{}, fmt.Errorf("class spec %q: limits must be %s", spec, order(higherIsBetter))
+				}
+			}
+			b.Limit = v
+		}
+		s.Buckets = append(s.Buckets, b)
+	}
+	return s, nil
+}
+
+func order(higherIsBetter bool) string {
+	if higherIsBetter {
+		return "decreasing"
+	}
+	return "increasing"
+}
+
+func parseLimit(s string, sized bool) (float64, error) {
+	mult := 1.0
+	if sized {
+		u := strings.TrimSuffix(strings.ToUpper(s), "B")
+		for suffix, m := range map[string]float64{"K": 1 << 10, "M": 1 << 20, "G": 1 << 30} {
+			if strings.HasSuffix(u, suffix) {
+				u, mult = strings.TrimSuffix(u, suffix), m
+				break
+			}
+		}
+		s = u
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("bad limit %q", s)
+	}
+	return v * mult, nil
+}
+
+// Classify returns the name of the bucket v falls into.
+func (s Scale) Classify(v float64) string {
+	for _, b := range s.Buckets[:len(s.Buckets)-1] {
+		if (s.HigherIsBetter && v >= b.Limit) || (!s.HigherIsBetter && v < b.Limit) {
+			return b.Name
+		}
+	}
+	return s.Buckets[len(s.Buckets)-1].Name
+}
+
+// Filter matches results by class name; the zero Filter matches everything.
+type Filter map[string]bool
+
+// ParseFilter parses a comma-separated list of class names.
+func ParseFilter(list string) Filter {
+	if strings.TrimSpace(list) == "" {
+		return nil
+	}
+	f := Filter{}
+	for _, n := range strings.Split(list, ",") {
+		if n = strings.TrimSpace(n); n != "" {
+			f[n] = true
+		}
+	}
+	return f
+}
+
+// Match reports whether class passes the filter.
+func (f Filter) Match(class string) bool {
+	return f == nil || f[class]
+}
@@ -0,0 +1,60 @@
+package classify
+
+import "testing"
+
+func TestLatency(t *testing.T) {
+	s, err := ParseLatency(DefaultLatency)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[float64]string{0: "fast", 299: "fast", 300: "medium", 999: "medium", 1000: "slow", 5000: "slow"}
+	for v, want := range cases {
+		if got := s.Classify(v); got != want {
+			t.Errorf("Classify(%v) = %q, want %q", v, got, want)
+		}
+	}
+}
+
+func TestSpeed(t *testing.T) {
+	s, err := ParseSpeed(DefaultSpeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[float64]string{2 << 20: "fast", 1 << 20: "fast", 200 << 10: "medium", 1000: "slow"}
+	for v, want := range cases {
+		if got := s.Classify(v); got != want {
+			t.Errorf("Classify(%v) = %q, want %q", v, got, want)
+		}
+	}
+}
+
+func TestParse_errors(t *testing.T) {
+	for _, spec := range []string{"", "fast:300,slow:1000", "fast,slow", "fast:1000,slow:300,x", "fast:abc,slow"} {
+		if _, err := ParseLatency(spec); err == nil {
+			t.Errorf("ParseLatency(%q) should fail", spec)
+		}
+	}
+	if _, err := ParseSpeed("fast:128KB,medium:1MB,slow"); err == nil {
+		t.Error("speed limits must decrease")
+	}
+}
+
+func TestParseBytes(t *testing.T) {
+	if v, err := ParseBytes("128KB"); err != nil || v != 128<<10 {
+		t.Errorf("ParseBytes(128KB) = %v, %v", v, err)
+	}
+	if _, err := ParseBytes("fast"); err == nil {
+		t.Error("ParseBytes(fast) should fail")
+	}
+}
+
+func TestFilter(t *testing.T) {
+	var all Filter
+	if !all.Match("slow") {
+		t.Error("nil filter should match everything")
+	}
+	f := ParseFilter("fast, medium")
+	if !f.Match("fast") || !f.Match("medium") || f.Match("slow") || f.Match("") {
+		t.Errorf("filter %v matched wrongly", f)
+	}
+}
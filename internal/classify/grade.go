@@ -0,0 +1,112 @@
+package classify
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultThresholds is the spec used by --usable.
+const DefaultThresholds = "loss:0.2,latency:1000"
+
+// Thresholds are the limits a proxy must stay within to be usable. A zero
+// MinSpeedBps (or a proxy whose speed was not measured) skips the speed test.
+type Thresholds struct {
+	MaxLoss      float64 // 0.0 – 1.0
+	MaxLatencyMS float64 // p50
+	MinSpeedBps  float64
+}
+
+// ParseThresholds parses a spec like "loss:0.2,latency:1000,speed:512KB";
+// keys not given keep their DefaultThresholds value, speed is off by default.
+func ParseThresholds(spec string) (Thresholds, error) {
+	t := Thresholds{MaxLoss: 0.2, MaxLatencyMS: 1000}
+	for _, p := range strings.Split(spec, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(p, ":")
+		if !ok {
+			return Thresholds{}, fmt.Errorf("threshold spec %q: %q needs key:value", spec, p)
+		}
+		val = strings.TrimSpace(val)
+		var err error
+		switch strings.TrimSpace(key) {
+		case "loss":
+			t.MaxLoss, err = strconv.ParseFloat(val, 64)
+			if err == nil && (t.MaxLoss < 0 || t.MaxLoss > 1) {
+				err = fmt.Errorf("loss must be between 0 and 1")
+			}
+		case "latency":
+			t.MaxLatencyMS, err = parseLimit(val, false)
+		case "speed":
+			t.MinSpeedBps, err = parseLimit(val, true)
+		default:
+			err = fmt.Errorf("unknown key %q (want loss, latency or speed)", key)
+		}
+		if err != nil {
+			return Thresholds{}, fmt.Errorf("threshold spec %q: %w", spec, err)
+		}
+	}
+	return t, nil
+}
+
+// Grade letters by minimum score; below the last a proxy is graded F.
+var grades = []struct {
+	letter string
+	min    float64
+}{
+	{"A", 0.85},
+	{"B", 0.75},
+	{"C", 0.65},
+	{"D", 0.5},
+}
+
+// Grade combines loss rate, p50 latency and throughput (0 when not
+// measured) into a letter A–F and a usable verdict. Each measure scores 1
+// at its best, 0.5 exactly at its threshold and 0 at twice the threshold
+// (half for speed); the grade comes from their weighted mean, loss and
+// latency counting twice as much as speed. A proxy is usable when every
+// measure is within its threshold, which is exactly when it grades above F.
+func (t Thresholds) Grade(loss, p50MS, speedBps float64) (string, bool) {
+	if loss >= 1 {
+		return "F", false
+	}
+	type part struct{ score, weight float64 }
+	parts := []part{
+		{score(loss, t.MaxLoss), 2},
+		{score(p50MS, t.MaxLatencyMS), 2},
+	}
+	if t.MinSpeedBps > 0 && speedBps > 0 {
+		parts = append(parts, part{score(t.MinSpeedBps, speedBps), 1})
+	}
+
+	var sum, weights float64
+	for _, p := range parts {
+		if p.score < 0.5 {
+			return "F", false
+		}
+		sum += p.score * p.weight
+		weights += p.weight
+	}
+	mean := sum / weights
+	for _, g := range grades {
+		if mean >= g.min {
+			return g.letter, true
+		}
+	}
+	return "F", false
+}
+
+// score maps value against limit (lower is better) onto [0, 1]: 1 at zero,
+// 0.5 at the limit, 0 at twice the limit.
+func score(value, limit float64) float64 {
+	if limit <= 0 {
+		if value <= 0 {
+			return 1
+		}
+		return 0
+	}
+	return max(0, min(1, 1-value/(2*limit)))
+}
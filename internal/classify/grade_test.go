@@ -0,0 +1,49 @@
+package classify
+
+import "testing"
+
+func TestParseThresholds(t *testing.T) {
+	got, err := ParseThresholds("latency:500, speed:1MB")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Thresholds{MaxLoss: 0.2, MaxLatencyMS: 500, MinSpeedBps: 1 << 20}
+	if got != want {
+		t.Errorf("ParseThresholds = %+v, want %+v", got, want)
+	}
+	if def, _ := ParseThresholds(DefaultThresholds); def != (Thresholds{MaxLoss: 0.2, MaxLatencyMS: 1000}) {
+		t.Errorf("default thresholds = %+v", def)
+	}
+	for _, bad := range []string{"loss", "loss:2", "jitter:5", "latency:fast"} {
+		if _, err := ParseThresholds(bad); err == nil {
+			t.Errorf("ParseThresholds(%q) should fail", bad)
+		}
+	}
+}
+
+func TestGrade(t *testing.T) {
+	th := Thresholds{MaxLoss: 0.2, MaxLatencyMS: 1000, MinSpeedBps: 1000}
+	cases := []struct {
+		name           string
+		loss, p50, bps float64
+		grade          string
+		usable         bool
+	}{
+		{"perfect", 0, 0, 0, "A", true},
+		{"good", 0, 200, 10000, "A", true},
+		{"decent", 0.1, 500, 2000, "B", true},
+		{"middling", 0.15, 700, 2000, "C", true},
+		{"at every limit", 0.2, 1000, 1000, "D", true},
+		{"too lossy", 0.3, 100, 10000, "F", false},
+		{"too slow to answer", 0, 1500, 10000, "F", false},
+		{"too little throughput", 0, 100, 900, "F", false},
+		{"speed not measured", 0, 100, 0, "A", true},
+		{"nothing got through", 1, 0, 0, "F", false},
+	}
+	for _, tc := range cases {
+		grade, usable := th.Grade(tc.loss, tc.p50, tc.bps)
+		if grade != tc.grade || usable != tc.usable {
+			t.Errorf("%s: Grade = %s, %v; want %s, %v", tc.name, grade, usable, tc.grade, tc.usable)
+		}
+	}
+}
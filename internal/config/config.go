@@ -0,0 +1,290 @@
+// Package config reads the proxybench configuration file: flag defaults,
+// named profiles, proxy list sources, alert channels, monitor targets and
+// scheduled jobs.
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DefaultPath is $XDG_CONFIG_HOME/proxybench/config.yaml (or the OS
+// equivalent via os.UserConfigDir), or "" if there is no such directory.
+func DefaultPath() string {
+	if dir, err := os.UserConfigDir(); err == nil {
+		return filepath.Join(dir, "proxybench", "config.yaml")
+	}
+	return ""
+}
+
+// Config is a parsed configuration file. The file itself is the base
+// section; each profile is a section layered on top of it.
+//
+//	defaults:              # flags of any command, by long name
+//	  timeout: 5
+//	  concurrency: 50
+//	sources:               # proxy lists read when no proxies are given
+//	  - ~/proxies/vendor.txt
+//	notify:                # monitor alert channels
+//	  slack:
+//	    webhook: https://hooks.slack.com/services/...
+//	    severity: critical
+//	monitor:               # flags of one command
+//	  interval: 300
+//	  targets:
+//	    - socks5://10.0.0.1:1080
+//	jobs:                  # pipelines of the schedule command (see Job)
+//	  refresh:
+//	    cron: "*/30 * * * *"
+//	    check: --timeout 5
+//	profiles:
+//	  scraping:
+//	    timeout: 3         # in a profile, flags go directly in it
+//	    check:
+//	      format: csv
+type Config struct {
+	Base     Section
+	Profiles map[string]Section
+	// Jobs are the pipelines of the schedule command, by name.
+	Jobs []Job
+}
+
+// Section holds the settings of the base file or of one profile.
+type Section struct {
+	// Flags are values for flags of whichever command runs.
+	Flags map[string]string
+	// Commands are flag values for one command, by command name.
+	Commands map[string]map[string]string
+	// Sources are proxy list files, in any format proxylist reads.
+	Sources []string
+	// Notify are the monitor alert flags, e.g. "slack-webhook"; nested
+	// keys are joined with "-".
+	Notify map[string]string
+	// Targets are the proxies monitor watches.
+	Targets []string
+}
+
+// Load reads a configuration file.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	dir := filepath.Dir(path)
+	c.Base.resolve(dir)
+	for name, s := range c.Profiles {
+		s.resolve(dir)
+		c.Profiles[name] = s
+	}
+	for i := range c.Jobs {
+		resolvePaths(c.Jobs[i].Fetch, dir)
+		if e := c.Jobs[i].Export; e != "" {
+			c.Jobs[i].Export = resolvePaths([]string{e}, dir)[0]
+		}
+	}
+	return c, nil
+}
+
+// Parse reads a configuration in the format described on Config.
+func Parse(data []byte) (*Config, error) {
+	root, err := parseYAML(data)
+	if err != nil {
+		return nil, err
+	}
+	c := &Config{Profiles: map[string]Section{}}
+	if root.m == nil {
+		return c, nil
+	}
+	if p := root.m["profiles"]; p != nil {
+		if p.m == nil && (p.value != "" || p.list != nil) {
+			return nil, fmt.Errorf("line %d: profiles must be a mapping", p.line)
+		}
+		for name, n := range p.m {
+			if n.m == nil && (n.value != "" || n.list != nil) {
+				return nil, fmt.Errorf("line %d: profile %s must be a mapping", n.line, name)
+			}
+			s, err := parseSection(n, false)
+			if err != nil {
+				return nil, fmt.Errorf("profile %s: %w", name, err)
+			}
+			c.Profiles[name] = s
+		}
+		delete(root.m, "profiles")
+	}
+	if j := root.m["jobs"]; j != nil {
+		if c.Jobs, err = parseJobs(j); err != nil {
+			return nil, err
+		}
+		delete(root.m, "jobs")
+	}
+	if c.Base, err = parseSection(root, true); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// ProfileNames lists the profiles in name order.
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Profile returns the base section with the named profile layered on top;
+// "" returns the base section alone.
+func (c *Config) Profile(name string) (Section, error) {
+	if name == "" {
+		return c.Base, nil
+	}
+	p, ok := c.Profiles[name]
+	if !ok {
+		if len(c.Profiles) == 0 {
+			return Section{}, fmt.Errorf("unknown profile %q (the config defines none)", name)
+		}
+		return Section{}, fmt.Errorf("unknown profile %q (have %s)", name, strings.Join(c.ProfileNames(), ", "))
+	}
+	return c.Base.merge(p), nil
+}
+
+// For returns the flag values that apply to command, most specific last
+// applied: shared flags, then alert channels, then the command's own.
+func (s Section) For(command string) map[string]string {
+	out := map[string]string{}
+	for _, m := range []map[string]string{s.Flags, s.Notify, s.Commands[command]} {
+		for k, v := range m {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+func parseSection(n *node, root bool) (Section, error) {
+	s := Section{Flags: map[string]string{}, Commands: map[string]map[string]string{}, Notify: map[string]string{}}
+	for key, v := range n.m {
+		switch {
+		case key == "sources":
+			s.Sources = v.strings()
+		case key == "notify":
+			if v.m == nil && v.value != "" {
+				return s, fmt.Errorf("line %d: notify must be a mapping", v.line)
+			}
+			flatten("", v, s.Notify)
+		case key == "defaults" && root:
+			if v.m == nil && v.value != "" {
+				return s, fmt.Errorf("line %d: defaults must be a mapping", v.line)
+			}
+			if err := flags(v, s.Flags); err != nil {
+				return s, err
+			}
+		case v.m != nil:
+			cmd := map[string]string{}
+			if t := v.m["targets"]; t != nil && key == "monitor" {
+				s.Targets = t.strings()
+				delete(v.m, "targets")
+			}
+			if err := flags(v, cmd); err != nil {
+				return s, err
+			}
+			s.Commands[key] = cmd
+		case v.value != "" || v.list != nil:
+			s.Flags[key] = v.scalar()
+		}
+	}
+	return s, nil
+}
+
+// flags copies a mapping of flag values into dst, joining lists with
+// commas the way list flags take them.
+func flags(n *node, dst map[string]string) error {
+	for k, v := range n.m {
+		if v.m != nil {
+			return fmt.Errorf("line %d: %s: a flag value cannot be a mapping", v.line, k)
+		}
+		dst[k] = v.scalar()
+	}
+	return nil
+}
+
+// flatten copies nested keys into dst joined with "-", so that
+// notify: {slack: {webhook: …}} becomes slack-webhook.
+func flatten(prefix string, n *node, dst map[string]string) {
+	for k, v := range n.m {
+		if prefix != "" {
+			k = prefix + "-" + k
+		}
+		if v.m != nil {
+			flatten(k, v, dst)
+		} else {
+			dst[k] = v.scalar()
+		}
+	}
+}
+
+func (s Section) merge(o Section) Section {
+	out := Section{
+		Flags:    mergeMap(s.Flags, o.Flags),
+		Commands: map[string]map[string]string{},
+		Sources:  s.Sources,
+		Notify:   mergeMap(s.Notify, o.Notify),
+		Targets:  s.Targets,
+	}
+	for name, m := range s.Commands {
+		out.Commands[name] = m
+	}
+	for name, m := range o.Commands {
+		out.Commands[name] = mergeMap(out.Commands[name], m)
+	}
+	if o.Sources != nil {
+		out.Sources = o.Sources
+	}
+	if o.Targets != nil {
+		out.Targets = o.Targets
+	}
+	return out
+}
+
+func mergeMap(a, b map[string]string) map[string]string {
+	out := make(map[string]string, len(a)+len(b))
+	for k, v := range a {
+		out[k] = v
+	}
+	for k, v := range b {
+		out[k] = v
+	}
+	return out
+}
+
+// resolve expands a leading ~ in source paths and makes relative ones
+// relative to the config file's directory.
+func (s *Section) resolve(dir string) {
+	resolvePaths(s.Sources, dir)
+}
+
+// resolvePaths resolves paths in place the way resolve does, leaving
+// URLs alone, and returns them.
+func resolvePaths(paths []string, dir string) []string {
+	for i, p := range paths {
+		if strings.Contains(p, "://") {
+			continue
+		}
+		if rest, ok := strings.CutPrefix(p, "~/"); ok || p == "~" {
+			if home, err := os.UserHomeDir(); err == nil {
+				p = filepath.Join(home, rest)
+			}
+		} else if !filepath.IsAbs(p) {
+			p = filepath.Join(dir, p)
+		}
+		paths[i] = p
+	}
+	return paths
+}
@@ -0,0 +1,151 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const sample = `
+# proxybench config
+defaults:
+  timeout: 5
+  concurrency: "50"   # quoted is fine too
+sources:
+  - proxies.txt
+notify:
+  slack:
+    webhook: https://hooks.slack.com/services/T/B/x
+    severity: critical
+  telegram-chat: '-100123'
+monitor:
+  interval: 300
+  targets:
+  - socks5://10.0.0.1:1080
+  - http://10.0.0.2:8080
+profiles:
+  scraping:
+    timeout: 3
+    country: [US, DE]
+    check:
+      format: csv
+  streaming:
+    sources: [/lists/residential.txt]
+    notify:
+      slack-severity: warning
+    monitor:
+      targets: []
+`
+
+func TestParse(t *testing.T) {
+	c, err := Parse([]byte(sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Section{
+		Flags:    map[string]string{"timeout": "5", "concurrency": "50"},
+		Commands: map[string]map[string]string{"monitor": {"interval": "300"}},
+		Sources:  []string{"proxies.txt"},
+		Notify: map[string]string{
+			"slack-webhook":  "https://hooks.slack.com/services/T/B/x",
+			"slack-severity": "critical",
+			"telegram-chat":  "-100123",
+		},
+		Targets: []string{"socks5://10.0.0.1:1080", "http://10.0.0.2:8080"},
+	}
+	if !reflect.DeepEqual(c.Base, want) {
+		t.Errorf("Base = %+v\nwant %+v", c.Base, want)
+	}
+	if got := c.ProfileNames(); !reflect.DeepEqual(got, []string{"scraping", "streaming"}) {
+		t.Errorf("ProfileNames = %v", got)
+	}
+
+	s, err := c.Profile("scraping")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.For("check"); !reflect.DeepEqual(got, map[string]string{
+		"timeout":        "3",
+		"concurrency":    "50",
+		"country":        "US,DE",
+		"format":         "csv",
+		"slack-webhook":  "https://hooks.slack.com/services/T/B/x",
+		"slack-severity": "critical",
+		"telegram-chat":  "-100123",
+	}) {
+		t.Errorf("scraping For(check) = %v", got)
+	}
+	if got := s.For("bench")["format"]; got != "" {
+		t.Errorf("check-only format leaked into bench: %q", got)
+	}
+
+	s, err = c.Profile("streaming")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(s.Sources, []string{"/lists/residential.txt"}) {
+		t.Errorf("streaming Sources = %v", s.Sources)
+	}
+	if s.Targets == nil || len(s.Targets) != 0 {
+		t.Errorf("streaming Targets = %#v, want empty", s.Targets)
+	}
+	if s.Notify["slack-severity"] != "warning" || s.Notify["slack-webhook"] == "" {
+		t.Errorf("streaming Notify = %v", s.Notify)
+	}
+	if s.Commands["monitor"]["interval"] != "300" {
+		t.Errorf("streaming lost the base monitor interval: %v", s.Commands)
+	}
+
+	if _, err := c.Profile("nope"); err == nil || !strings.Contains(err.Error(), "scraping, streaming") {
+		t.Errorf("unknown profile error = %v", err)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	cases := map[string]string{
+		"tabs":          "defaults:\n\ttimeout: 5\n",
+		"no colon":      "defaults\n",
+		"indentation":   "defaults:\n  timeout: 5\n    concurrency: 3\n",
+		"duplicate":     "timeout: 5\ntimeout: 6\n",
+		"open quote":    "defaults:\n  timeout: \"5\n",
+		"nested flag":   "check:\n  format:\n    csv: yes\n",
+		"sequence map":  "sources:\n  - path: a.txt\n",
+		"unterminated":  "country: [US, DE\n",
+		"top sequence":  "- a\n- b\n",
+		"profile value": "profiles:\n  scraping: yes\n",
+	}
+	for name, in := range cases {
+		if _, err := Parse([]byte(in)); err == nil {
+			t.Errorf("%s: Parse succeeded, want an error", name)
+		}
+	}
+}
+
+func TestParseEmpty(t *testing.T) {
+	c, err := Parse([]byte("# nothing yet\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := c.Profile("")
+	if err != nil || len(s.For("check")) != 0 {
+		t.Errorf("Profile(\"\") = %+v, %v", s, err)
+	}
+}
+
+func TestLoadResolvesSources(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, []byte("sources:\n  - lists/a.txt\n  - /abs/b.txt\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	c, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(dir, "lists", "a.txt"), "/abs/b.txt"}
+	if !reflect.DeepEqual(c.Base.Sources, want) {
+		t.Errorf("Sources = %v, want %v", c.Base.Sources, want)
+	}
+}
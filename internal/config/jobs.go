@@ -0,0 +1,123 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Job is a pipeline the schedule command runs on a cron expression:
+// fetch proxy lists, check them, benchmark the alive ones, export the
+// results and notify. Jobs are defined in the base section of the file:
+//
+//	jobs:
+//	  refresh:
+//	    cron: "*/30 * * * *"
+//	    fetch: [https://example.com/proxies.txt, vendor.txt]
+//	    check: --timeout 5 --concurrency 50
+//	    bench: --samples 3
+//	    export: s3://bucket/proxybench/
+//	    notify: failure
+type Job struct {
+	Name string
+	Cron string
+	// Fetch are proxy lists, files or http(s) URLs, in any format
+	// proxylist reads; none means the check reads the config's sources.
+	Fetch []string
+	// Check and Bench are the flags of those stages, nil when the job
+	// skips the stage; an empty, non-nil slice runs it with none.
+	Check, Bench []string
+	// Format is the output format of the last stage: json (default),
+	// ndjson or csv.
+	Format string
+	// Export is a file or upload target (s3://, gs://, az://) the results
+	// are written to.
+	Export string
+	// Notify is when the alert channels hear of a run: "always",
+	// "failure" or "" (never).
+	Notify string
+}
+
+// jobKeys are the settings a job takes.
+var jobKeys = map[string]bool{"cron": true, "fetch": true, "check": true, "bench": true, "format": true, "export": true, "notify": true}
+
+func parseJobs(n *node) ([]Job, error) {
+	if n.m == nil {
+		if n.value != "" || n.list != nil {
+			return nil, fmt.Errorf("line %d: jobs must be a mapping", n.line)
+		}
+		return nil, nil
+	}
+	names := make([]string, 0, len(n.m))
+	for name := range n.m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var jobs []Job
+	for _, name := range names {
+		v := n.m[name]
+		if v.m == nil {
+			return nil, fmt.Errorf("line %d: job %s must be a mapping", v.line, name)
+		}
+		for k, kv := range v.m {
+			if !jobKeys[k] {
+				return nil, fmt.Errorf("line %d: job %s: unknown key %q", kv.line, name, k)
+			}
+			if kv.m != nil {
+				return nil, fmt.Errorf("line %d: job %s: %s cannot be a mapping", kv.line, name, k)
+			}
+		}
+		j := Job{Name: name, Format: "json"}
+		if c := v.m["cron"]; c != nil {
+			j.Cron = c.value
+		}
+		if j.Cron == "" {
+			return nil, fmt.Errorf("line %d: job %s needs a cron expression", v.line, name)
+		}
+		if f := v.m["fetch"]; f != nil {
+			j.Fetch = f.strings()
+		}
+		j.Check, j.Bench = stageArgs(v.m["check"]), stageArgs(v.m["bench"])
+		if j.Check == nil && j.Bench == nil {
+			return nil, fmt.Errorf("line %d: job %s has neither a check nor a bench stage", v.line, name)
+		}
+		if f := v.m["format"]; f != nil {
+			j.Format = f.value
+		}
+		switch j.Format {
+		case "json", "ndjson", "csv":
+		default:
+			return nil, fmt.Errorf("line %d: job %s: format must be json, ndjson or csv", v.m["format"].line, name)
+		}
+		if e := v.m["export"]; e != nil {
+			j.Export = e.value
+		}
+		if nt := v.m["notify"]; nt != nil {
+			j.Notify = nt.value
+		}
+		switch j.Notify {
+		case "", "always", "failure", "never":
+		default:
+			return nil, fmt.Errorf("line %d: job %s: notify must be always, failure or never", v.m["notify"].line, name)
+		}
+		if j.Notify == "never" {
+			j.Notify = ""
+		}
+		jobs = append(jobs, j)
+	}
+	return jobs, nil
+}
+
+// stageArgs returns the flags of a check or bench stage: a string split
+// at spaces, or a list of arguments taken as they are; nil without the
+// key.
+func stageArgs(n *node) []string {
+	switch {
+	case n == nil:
+		return nil
+	case n.list != nil:
+		return n.list
+	default:
+		return append([]string{}, strings.Fields(n.value)...)
+	}
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const jobsSample = `
+notify:
+  slack-webhook: https://hooks.slack.com/services/T/B/x
+schedule:
+  log-dir: /var/log/proxybench
+jobs:
+  refresh:
+    cron: "*/30 * * * *"
+    fetch: [https://example.com/proxies.txt, lists/vendor.txt]
+    check: --timeout 5 --concurrency 50
+    bench: [--samples, "3"]
+    export: out/latest.json
+    notify: failure
+  nightly:
+    cron: "@daily"
+    check:
+    format: csv
+`
+
+func TestLoadJobs(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "sched.yaml")
+	if err := os.WriteFile(path, []byte(jobsSample), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	c, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Job{
+		{Name: "nightly", Cron: "@daily", Check: []string{}, Format: "csv"},
+		{
+			Name:   "refresh",
+			Cron:   "*/30 * * * *",
+			Fetch:  []string{"https://example.com/proxies.txt", filepath.Join(dir, "lists", "vendor.txt")},
+			Check:  []string{"--timeout", "5", "--concurrency", "50"},
+			Bench:  []string{"--samples", "3"},
+			Format: "json",
+			Export: filepath.Join(dir, "out", "latest.json"),
+			Notify: "failure",
+		},
+	}
+	if !reflect.DeepEqual(c.Jobs, want) {
+		t.Errorf("Jobs = %+v\nwant %+v", c.Jobs, want)
+	}
+	if got := c.Base.Commands["schedule"]["log-dir"]; got != "/var/log/proxybench" {
+		t.Errorf("schedule log-dir = %q", got)
+	}
+}
+
+func TestParseJobsErrors(t *testing.T) {
+	cases := map[string]string{
+		"not a mapping": "jobs: daily\n",
+		"job value":     "jobs:\n  a: yes\n",
+		"no cron":       "jobs:\n  a:\n    check:\n",
+		"no stage":      "jobs:\n  a:\n    cron: '@daily'\n",
+		"unknown key":   "jobs:\n  a:\n    cron: '@daily'\n    check:\n    retries: 3\n",
+		"bad format":    "jobs:\n  a:\n    cron: '@daily'\n    check:\n    format: table\n",
+		"bad notify":    "jobs:\n  a:\n    cron: '@daily'\n    check:\n    notify: sometimes\n",
+	}
+	for name, in := range cases {
+		if _, err := Parse([]byte(in)); err == nil {
+			t.Errorf("%s: Parse succeeded, want an error", name)
+		}
+	}
+}
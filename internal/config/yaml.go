@@ -0,0 +1,98 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The config file is YAML, read with yaml.v3 into a tree of nodes.
+// Sequences hold scalars only; nothing in the config format needs more.
+
+// node is a scalar (value), a sequence (list) or a mapping (m).
+type node struct {
+	value string
+	list  []string
+	m     map[string]*node
+	line  int
+}
+
+// scalar is the node's value, with a sequence joined by commas.
+func (n *node) scalar() string {
+	if n.list != nil {
+		return strings.Join(n.list, ",")
+	}
+	return n.value
+}
+
+// strings is the node as a list; a scalar is a list of one.
+func (n *node) strings() []string {
+	if n.list != nil {
+		return n.list
+	}
+	if n.value != "" {
+		return []string{n.value}
+	}
+	return nil
+}
+
+func parseYAML(data []byte) (*node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return &node{}, nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("line %d: the config must be a mapping", root.Line)
+	}
+	return fromYAML(root)
+}
+
+// fromYAML converts a yaml.v3 node; a null scalar becomes an empty node.
+func fromYAML(y *yaml.Node) (*node, error) {
+	if y.Kind == yaml.AliasNode {
+		y = y.Alias
+	}
+	switch y.Kind {
+	case yaml.ScalarNode:
+		if y.Tag == "!!null" {
+			return &node{line: y.Line}, nil
+		}
+		return &node{value: y.Value, line: y.Line}, nil
+	case yaml.SequenceNode:
+		n := &node{list: []string{}, line: y.Line}
+		for _, item := range y.Content {
+			if item.Kind == yaml.AliasNode {
+				item = item.Alias
+			}
+			if item.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("line %d: sequence items must be scalars", item.Line)
+			}
+			n.list = append(n.list, item.Value)
+		}
+		return n, nil
+	case yaml.MappingNode:
+		n := &node{m: map[string]*node{}, line: y.Line}
+		for i := 0; i+1 < len(y.Content); i += 2 {
+			k, v := y.Content[i], y.Content[i+1]
+			if k.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("line %d: keys must be scalars", k.Line)
+			}
+			if _, dup := n.m[k.Value]; dup {
+				return nil, fmt.Errorf("line %d: duplicate key %q", k.Line, k.Value)
+			}
+			child, err := fromYAML(v)
+			if err != nil {
+				return nil, err
+			}
+			child.line = k.Line
+			n.m[k.Value] = child
+		}
+		return n, nil
+	}
+	return nil, fmt.Errorf("line %d: unsupported YAML node", y.Line)
+}
@@ -0,0 +1,160 @@
+// Package creds maps proxy hosts to credentials, so lists distributed
+// without embedded user:pass can be checked as they are.
+package creds
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Map holds credentials by "host:port", by bare host and a default pair.
+//
+// The file format is one entry per line, blank lines and #-comments
+// ignored:
+//
+//	1.2.3.4:8080   alice:s3cret
+//	proxy.example  bob:hunter2      # any port on this host
+//	*              guest:guest      # everything else
+type Map struct {
+	byHostPort map[string]*url.Userinfo
+	byHost     map[string]*url.Userinfo
+	fallback   *url.Userinfo
+}
+
+// Load reads a credentials file.
+func Load(path string) (*Map, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	m, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return m, nil
+}
+
+// Parse reads credentials in the format described on Map.
+func Parse(r io.Reader) (*Map, error) {
+	m := &Map{byHostPort: map[string]*url.Userinfo{}, byHost: map[string]*url.Userinfo{}}
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := sc.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: want \"host[:port] user:pass\"", n)
+		}
+		user, pass, ok := strings.Cut(fields[1], ":")
+		if !ok || user == "" {
+			return nil, fmt.Errorf("line %d: credentials must be user:pass", n)
+		}
+		info := url.UserPassword(user, pass)
+
+		target := strings.ToLower(fields[0])
+		switch {
+		case target == "*":
+			m.fallback = info
+		case hasPort(target):
+			m.byHostPort[target] = info
+		default:
+			m.byHost[strings.Trim(target, "[]")] = info
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Lookup returns the credentials for a proxy at hostPort: an exact
+// host:port entry, else a host entry, else the default pair, else nil.
+func (m *Map) Lookup(hostPort string) *url.Userinfo {
+	if m == nil {
+		return nil
+	}
+	hostPort = strings.ToLower(hostPort)
+	if info, ok := m.byHostPort[hostPort]; ok {
+		return info
+	}
+	host := hostPort
+	if h, _, err := net.SplitHostPort(hostPort); err == nil {
+		host = h
+	}
+	if info, ok := m.byHost[strings.Trim(host, "[]")]; ok {
+		return info
+	}
+	return m.fallback
+}
+
+// Len reports how many entries were loaded, counting the default pair.
+func (m *Map) Len() int {
+	n := len(m.byHostPort) + len(m.byHost)
+	if m.fallback != nil {
+		n++
+	}
+	return n
+}
+
+func hasPort(target string) bool {
+	_, _, err := net.SplitHostPort(target)
+	return err == nil
+}
+
+// LoadList reads a file of credential pairs to try in turn.
+func LoadList(path string) ([]*url.Userinfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	list, err := ParseList(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return list, nil
+}
+
+// ParseList reads one user:pass pair per line, blank lines and
+// #-comments ignored, keeping their order and dropping repeats:
+//
+//	admin:admin
+//	vendor1-user:hunter2   # provider A
+func ParseList(r io.Reader) ([]*url.Userinfo, error) {
+	var list []*url.Userinfo
+	seen := map[string]bool{}
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := sc.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		user, pass, ok := strings.Cut(fields[0], ":")
+		if len(fields) != 1 || !ok || user == "" {
+			return nil, fmt.Errorf("line %d: want user:pass", n)
+		}
+		if !seen[fields[0]] {
+			seen[fields[0]] = true
+			list = append(list, url.UserPassword(user, pass))
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
@@ -0,0 +1,83 @@
+package creds
+
+import (
+	"strings"
+	"testing"
+)
+
+const sample = `
+# proxies from the vendor list
+1.2.3.4:8080   alice:s3cret
+1.2.3.4:9090   carol:p@ss:word
+proxy.example  bob:hunter2   # any port
+[2001:db8::1]:1080 dave:x
+*              guest:guest
+`
+
+func TestLookup(t *testing.T) {
+	m, err := Parse(strings.NewReader(sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Len() != 5 {
+		t.Errorf("Len = %d, want 5", m.Len())
+	}
+	cases := map[string]string{
+		"1.2.3.4:8080":       "alice:s3cret",
+		"1.2.3.4:9090":       "carol:p%40ss%3Aword",
+		"PROXY.example:3128": "bob:hunter2",
+		"[2001:db8::1]:1080": "dave:x",
+		"5.6.7.8:1080":       "guest:guest",
+	}
+	for hostPort, want := range cases {
+		if got := m.Lookup(hostPort).String(); got != want {
+			t.Errorf("Lookup(%q) = %q, want %q", hostPort, got, want)
+		}
+	}
+}
+
+func TestLookup_noDefault(t *testing.T) {
+	m, err := Parse(strings.NewReader("1.2.3.4:8080 alice:x\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info := m.Lookup("9.9.9.9:80"); info != nil {
+		t.Errorf("Lookup without default = %v, want nil", info)
+	}
+	var none *Map
+	if info := none.Lookup("1.2.3.4:8080"); info != nil {
+		t.Errorf("nil Map Lookup = %v, want nil", info)
+	}
+}
+
+func TestParse_errors(t *testing.T) {
+	for _, in := range []string{
+		"1.2.3.4:8080\n",
+		"1.2.3.4:8080 alice\n",
+		"1.2.3.4:8080 :pass\n",
+		"a b c\n",
+	} {
+		if _, err := Parse(strings.NewReader(in)); err == nil {
+			t.Errorf("Parse(%q) should fail", in)
+		}
+	}
+}
+
+func TestParseList(t *testing.T) {
+	list, err := ParseList(strings.NewReader("# defaults\nadmin:admin\n\nvendor:p@ss:word  # provider A\nadmin:admin\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, info := range list {
+		got = append(got, info.String())
+	}
+	if strings.Join(got, " ") != "admin:admin vendor:p%40ss%3Aword" {
+		t.Errorf("ParseList = %v", got)
+	}
+	for _, in := range []string{"admin\n", ":pass\n", "a:b c:d\n"} {
+		if _, err := ParseList(strings.NewReader(in)); err == nil {
+			t.Errorf("ParseList(%q) succeeded", in)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+// Package fdlimit caps how many sockets proxybench holds open at once.
+// Dials beyond the budget wait for a slot instead of failing with "too many
+// open files", so aggressive --concurrency settings degrade to queuing.
+package fdlimit
+
+import (
+	"context"
+	"net"
+	"sync"
+)
+
+// reserve is kept back from RLIMIT_NOFILE for stdio, the geo database,
+// history, DNS and other non-proxy descriptors.
+const reserve = 64
+
+// minBudget is the smallest budget Default will choose, however low the
+// descriptor limit.
+const minBudget = 8
+
+// Budget is a counting semaphore of socket slots.
+type Budget struct {
+	slots chan struct{}
+}
+
+// New returns a Budget allowing n simultaneously open sockets.
+func New(n int) *Budget {
+	if n < 1 {
+		n = 1
+	}
+	return &Budget{slots: make(chan struct{}, n)}
+}
+
+// Cap returns the number of slots.
+func (b *Budget) Cap() int { return cap(b.slots) }
+
+// InUse returns the number of slots currently held.
+func (b *Budget) InUse() int { return len(b.slots) }
+
+// Busy reports whether at least nine in ten slots are held, so callers
+// can hold back new work before dials start to queue.
+func (b *Budget) Busy() bool { return b.InUse()*10 >= b.Cap()*9 }
+
+// Acquire takes a slot, waiting until one is free or ctx is done.
+func (b *Budget) Acquire(ctx context.Context) error {
+	select {
+	case b.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Release returns a slot taken by Acquire.
+func (b *Budget) Release() { <-b.slots }
+
+// Wrap returns conn with its slot released on the first Close.
+func (b *Budget) Wrap(conn net.Conn) net.Conn {
+	return &budgetConn{Conn: conn, release: sync.OnceFunc(b.Release)}
+}
+
+type budgetConn struct {
+	net.Conn
+	release func()
+}
+
+func (c *budgetConn) Close() error {
+	err := c.Conn.Close()
+	c.release()
+	return err
+}
+
+// FromLimit sizes a budget for a process allowed nofile descriptors.
+func FromLimit(nofile uint64) int {
+	if nofile == 0 || nofile > 1<<20 {
+		nofile = 1 << 20
+	}
+	n := int(nofile) - reserve
+	if n < minBudget {
+		n = minBudget
+	}
+	return n
+}
+
+var (
+	defaultOnce sync.Once
+	defaultB    *Budget
+)
+
+// Default returns the process-wide budget, sized from RLIMIT_NOFILE.
+func Default() *Budget {
+	defaultOnce.Do(func() {
+		defaultB = New(FromLimit(openFileLimit()))
+	})
+	return defaultB
+}
@@ -0,0 +1,73 @@
+package fdlimit
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestFromLimit(t *testing.T) {
+	cases := []struct {
+		nofile uint64
+		want   int
+	}{
+		{1024, 1024 - reserve},
+		{10, minBudget},
+		{0, 1<<20 - reserve},
+	}
+	for _, c := range cases {
+		if got := FromLimit(c.nofile); got != c.want {
+			t.Errorf("FromLimit(%d) = %d, want %d", c.nofile, got, c.want)
+		}
+	}
+}
+
+func TestAcquire_waitsForRelease(t *testing.T) {
+	b := New(1)
+	if err := b.Acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := b.Acquire(ctx); err == nil {
+		t.Fatal("second Acquire should block until the context expires")
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		b.Release()
+	}()
+	if err := b.Acquire(context.Background()); err != nil {
+		t.Fatalf("Acquire after Release: %v", err)
+	}
+}
+
+func TestWrap_releasesOnce(t *testing.T) {
+	b := New(2)
+	b.Acquire(context.Background()) //nolint:errcheck
+	c1, c2 := net.Pipe()
+	defer c2.Close()
+
+	conn := b.Wrap(c1)
+	conn.Close()
+	conn.Close()
+	if b.InUse() != 0 {
+		t.Errorf("in use = %d after double Close, want 0", b.InUse())
+	}
+}
+
+func TestBusy(t *testing.T) {
+	b := New(10)
+	for range 8 {
+		b.Acquire(context.Background()) //nolint:errcheck
+	}
+	if b.Busy() {
+		t.Error("8 of 10 slots held: Busy() = true")
+	}
+	b.Acquire(context.Background()) //nolint:errcheck
+	if !b.Busy() {
+		t.Error("9 of 10 slots held: Busy() = false")
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package fdlimit
+
+// openFileLimit has no equivalent here; assume a generous fixed limit.
+func openFileLimit() uint64 {
+	return 8192
+}
@@ -0,0 +1,15 @@
+//go:build unix
+
+package fdlimit
+
+import "syscall"
+
+// openFileLimit returns the soft RLIMIT_NOFILE. The Go runtime has already
+// raised it to the hard limit at startup.
+func openFileLimit() uint64 {
+	var rl syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rl); err != nil {
+		return 1024
+	}
+	return uint64(rl.Cur)
+}
@@ -0,0 +1,14 @@
+package geo
+
+import "testing"
+
+func TestContinent(t *testing.T) {
+	for cc, want := range map[string]string{
+		"DE": Europe, "ru": Europe, "US": NorthAmerica, "PA": NorthAmerica, "BR": SouthAmerica,
+		"JP": Asia, "AU": Oceania, "ZA": Africa, "AQ": Antarctica, "ZZ": "", "": "",
+	} {
+		if got := Continent(cc); got != want {
+			t.Errorf("Continent(%q) = %q, want %q", cc, got, want)
+		}
+	}
+}
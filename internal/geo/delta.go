@@ -0,0 +1,314 @@
+package geo
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Delta files patch one database into the next without downloading it in
+// full. They are produced by Diff and applied by ApplyDelta:
+//
+//	# proxybench geo delta v1
+//	# base-sha256 <Checksum of the database the delta applies to>
+//	# result-sha256 <Checksum of the patched database>
+//	-16777216,16777471,AU,Australia
+//	+16777216,16777471,US,United States
+//
+// Ranges are compared after parsing, so a delta applies to a database in
+// any supported layout. The patched database is written in the numeric
+// layout, with ranges sorted.
+const deltaMagic = "# proxybench geo delta v1"
+
+// Checksum returns the SHA-256 of a database's ranges in canonical form:
+// sorted, numeric, one per line. Two files with the same ranges have the
+// same checksum whatever their layout.
+func Checksum(path string) (string, error) {
+	entries, err := readEntries(path)
+	if err != nil {
+		return "", err
+	}
+	return checksum(entries), nil
+}
+
+// Diff writes the delta from the database at oldPath to the one at
+// newPath and returns how many ranges it removes and adds.
+func Diff(oldPath, newPath string, w io.Writer) (removed, added int, err error) {
+	old, err := readEntries(oldPath)
+	if err != nil {
+		return 0, 0, err
+	}
+	cur, err := readEntries(newPath)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "%s\n# base-sha256 %s\n# result-sha256 %s\n", deltaMagic, checksum(old), checksum(cur))
+	inOld := make(map[Entry]bool, len(old))
+	for _, e := range old {
+		inOld[e] = true
+	}
+	inCur := make(map[Entry]bool, len(cur))
+	for _, e := range cur {
+		inCur[e] = true
+	}
+	for _, e := range old {
+		if !inCur[e] {
+			bw.WriteString("-" + canonicalLine(e)) //nolint:errcheck
+			removed++
+		}
+	}
+	for _, e := range cur {
+		if !inOld[e] {
+			bw.WriteString("+" + canonicalLine(e)) //nolint:errcheck
+			added++
+		}
+	}
+	return removed, added, bw.Flush()
+}
+
+// ApplyDelta patches the database at dbPath with the delta read from src,
+// a local file or an http(s) URL (.gz is decompressed). The database must
+// match the delta's base checksum, and the result is checked against its
+// result checksum before it replaces dbPath; on any mismatch dbPath is
+// left as it was. The binary index, if any, is rebuilt.
+func ApplyDelta(dbPath, src string, timeout time.Duration) (removed, added int, err error) {
+	r, err := openSource(src, timeout)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer r.Close()
+	d, err := parseDelta(r)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	entries, err := readEntries(dbPath)
+	if err != nil {
+		return 0, 0, err
+	}
+	if sum := checksum(entries); sum != d.base {
+		return 0, 0, errors.New("database does not match the delta's base; run a full `db update`")
+	}
+
+	set := make(map[Entry]bool, len(entries))
+	for _, e := range entries {
+		set[e] = true
+	}
+	for _, e := range d.remove {
+		if !set[e] {
+			return 0, 0, fmt.Errorf("delta removes a range not in the database: %s", strings.TrimSpace(canonicalLine(e)))
+		}
+		delete(set, e)
+	}
+	for _, e := range d.add {
+		set[e] = true
+	}
+	patched := make([]Entry, 0, len(set))
+	for e := range set {
+		patched = append(patched, e)
+	}
+	sortEntries(patched)
+	if checksum(patched) != d.result {
+		return 0, 0, errors.New("patched database does not match the delta's result checksum")
+	}
+
+	if err := writeEntries(dbPath, patched); err != nil {
+		return 0, 0, err
+	}
+	if ix := IndexPath(dbPath); fileExists(ix) {
+		if _, err := BuildIndex(dbPath, ix); err != nil {
+			os.Remove(ix) //nolint:errcheck — LoadFile falls back to the CSV
+		}
+	}
+	return len(d.remove), len(d.add), nil
+}
+
+type delta struct {
+	base, result string
+	remove, add  []Entry
+}
+
+func parseDelta(r io.Reader) (*delta, error) {
+	sc := bufio.NewScanner(r)
+	if !sc.Scan() || strings.TrimSpace(sc.Text()) != deltaMagic {
+		return nil, errors.New("not a proxybench geo delta")
+	}
+	d := &delta{}
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "# base-sha256 "):
+			d.base = strings.TrimPrefix(line, "# base-sha256 ")
+		case strings.HasPrefix(line, "# result-sha256 "):
+			d.result = strings.TrimPrefix(line, "# result-sha256 ")
+		case strings.HasPrefix(line, "#"):
+		case line[0] == '-' || line[0] == '+':
+			rec, err := csv.NewReader(strings.NewReader(line[1:])).Read()
+			if err != nil {
+				return nil, fmt.Errorf("delta line %q: %w", line, err)
+			}
+			e, ok := ip2locationEntry(rec)
+			if !ok {
+				return nil, fmt.Errorf("delta line %q: invalid range", line)
+			}
+			if line[0] == '-' {
+				d.remove = append(d.remove, e)
+			} else {
+				d.add = append(d.add, e)
+			}
+		default:
+			return nil, fmt.Errorf("delta line %q: want + or -", line)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read delta: %w", err)
+	}
+	if d.base == "" || d.result == "" {
+		return nil, errors.New("delta has no checksums")
+	}
+	return d, nil
+}
+
+// readEntries parses the CSV database at path into sorted, distinct
+// ranges, dropping rows without a country. Any binary index is ignored:
+// these are operations on the CSV itself.
+func readEntries(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open db: %w", err)
+	}
+	defer f.Close()
+	var entries []Entry
+	err = scanCSV(f, func(e Entry) {
+		if e.CountryCode != "" && e.CountryCode != "-" {
+			entries = append(entries, e)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	sortEntries(entries)
+	return slices.Compact(entries), nil
+}
+
+func sortEntries(entries []Entry) {
+	slices.SortFunc(entries, func(a, b Entry) int {
+		if a.Start != b.Start {
+			return cmpUint32(a.Start, b.Start)
+		}
+		if a.End != b.End {
+			return cmpUint32(a.End, b.End)
+		}
+		return strings.Compare(a.CountryCode+"\x00"+a.CountryName, b.CountryCode+"\x00"+b.CountryName)
+	})
+}
+
+func cmpUint32(a, b uint32) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func checksum(sorted []Entry) string {
+	h := sha256.New()
+	for _, e := range sorted {
+		io.WriteString(h, canonicalLine(e)) //nolint:errcheck
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// canonicalLine is e as a numeric CSV row, newline included.
+func canonicalLine(e Entry) string {
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	w.Write([]string{fmt.Sprint(e.Start), fmt.Sprint(e.End), e.CountryCode, e.CountryName}) //nolint:errcheck
+	w.Flush()
+	return b.String()
+}
+
+// writeEntries replaces path with sorted entries in canonical form.
+func writeEntries(path string, sorted []Entry) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("create temp: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	for _, e := range sorted {
+		w.WriteString(canonicalLine(e)) //nolint:errcheck
+	}
+	err = w.Flush()
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp) //nolint:errcheck
+		return fmt.Errorf("write db: %w", err)
+	}
+	return nil
+}
+
+// openSource opens a local file or downloads an http(s) URL, expanding
+// {YYYY-MM} and decompressing a .gz name.
+func openSource(src string, timeout time.Duration) (io.ReadCloser, error) {
+	var rc io.ReadCloser
+	if strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://") {
+		src = expandURL(src, time.Now().UTC())
+		resp, err := (&http.Client{Timeout: timeout}).Get(src)
+		if err != nil {
+			return nil, fmt.Errorf("download: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("server returned %s", resp.Status)
+		}
+		rc = resp.Body
+	} else {
+		f, err := os.Open(src)
+		if err != nil {
+			return nil, err
+		}
+		rc = f
+	}
+	if !strings.HasSuffix(strings.SplitN(src, "?", 2)[0], ".gz") {
+		return rc, nil
+	}
+	gz, err := gzip.NewReader(rc)
+	if err != nil {
+		rc.Close()
+		return nil, fmt.Errorf("gzip: %w", err)
+	}
+	return readCloser{gz, func() error { gz.Close(); return rc.Close() }}, nil
+}
+
+type readCloser struct {
+	io.Reader
+	close func() error
+}
+
+func (r readCloser) Close() error { return r.close() }
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,111 @@
+package geo
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const nextCSV = `16777216,16777471,US,United States
+16777472,16778239,CN,China
+16778240,16779263,AU,Australia
+134744072,134744072,US,United States
+838860800,838926335,ZZ,"Private, LAN"
+`
+
+func TestDiffApplyDelta(t *testing.T) {
+	dir := t.TempDir()
+	oldPath := writeTempDB(t, sampleCSV)
+	newPath := filepath.Join(dir, "new.csv")
+	os.WriteFile(newPath, []byte(nextCSV), 0o644) //nolint:errcheck
+	deltaPath := filepath.Join(dir, "db.delta")
+
+	var buf bytes.Buffer
+	removed, added, err := Diff(oldPath, newPath, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 1 || added != 2 {
+		t.Errorf("Diff = -%d +%d, want -1 +2", removed, added)
+	}
+	os.WriteFile(deltaPath, buf.Bytes(), 0o644) //nolint:errcheck
+
+	if _, err := BuildIndex(oldPath, IndexPath(oldPath)); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := ApplyDelta(oldPath, deltaPath, 0); err != nil {
+		t.Fatalf("ApplyDelta: %v", err)
+	}
+	want, _ := Checksum(newPath)
+	if got, _ := Checksum(oldPath); got != want {
+		t.Errorf("patched checksum %s, want %s", got, want)
+	}
+	db := &DB{}
+	if err := db.LoadFile(oldPath); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if !db.Indexed() {
+		t.Error("index was not rebuilt")
+	}
+	if cc, cn := db.Lookup("50.0.1.1"); cc != "ZZ" || cn != "Private, LAN" {
+		t.Errorf("patched lookup = %s/%s", cc, cn)
+	}
+
+	// Applying it again fails: the database is no longer the delta's base.
+	before, _ := os.ReadFile(oldPath)
+	if _, _, err := ApplyDelta(oldPath, deltaPath, 0); err == nil || !strings.Contains(err.Error(), "base") {
+		t.Errorf("second ApplyDelta error = %v, want a base mismatch", err)
+	}
+	if after, _ := os.ReadFile(oldPath); !bytes.Equal(before, after) {
+		t.Error("failed ApplyDelta modified the database")
+	}
+}
+
+func TestApplyDelta_badInput(t *testing.T) {
+	path := writeTempDB(t, sampleCSV)
+	for name, delta := range map[string]string{
+		"no magic":     "+1,2,US,United States\n",
+		"no checksums": deltaMagic + "\n+1,2,US,United States\n",
+		"bad line":     deltaMagic + "\n# base-sha256 x\n# result-sha256 y\n*1,2,US\n",
+	} {
+		d := filepath.Join(t.TempDir(), "bad.delta")
+		os.WriteFile(d, []byte(delta), 0o644) //nolint:errcheck
+		if _, _, err := ApplyDelta(path, d, 0); err == nil {
+			t.Errorf("%s: ApplyDelta succeeded", name)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	path := writeTempDB(t, sampleCSV)
+	if _, err := BuildIndex(path, IndexPath(path)); err != nil {
+		t.Fatal(err)
+	}
+	rep, err := Verify(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rep.OK() || rep.Ranges != 4 || !rep.Indexed || rep.Format != FormatIP2Location {
+		t.Errorf("report = %+v", rep)
+	}
+	if sum, _ := Checksum(path); rep.Checksum != sum {
+		t.Errorf("checksum %s, want %s", rep.Checksum, sum)
+	}
+
+	bad := writeTempDB(t, sampleCSV+"16777300,16777400,JP,Japan\n20,10,XX,Inverted\n1677\n")
+	rep, err = Verify(bad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.OK() || rep.Overlaps != 1 || rep.Malformed != 2 {
+		t.Errorf("corrupt database report = %+v", rep)
+	}
+
+	inverted := writeTempDB(t, "1.0.0.9,1.0.0.1,AU\n")
+	if rep, err := Verify(inverted); err != nil || rep.Inverted != 1 || rep.OK() {
+		t.Errorf("inverted range report = %+v, %v", rep, err)
+	}
+}
@@ -0,0 +1,197 @@
+package geo
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Format is a CSV database layout understood by LoadFile and BuildIndex.
+type Format string
+
+const (
+	// FormatDBIP is the DB-IP country lite layout,
+	//
+	//	start_ip,end_ip,country_code[,country_name]
+	//
+	// with dotted addresses. IPv6 rows are skipped.
+	FormatDBIP Format = "db-ip"
+
+	// FormatIP2Location is the ip2location LITE DB1/DB3 layout,
+	//
+	//	"ip_from","ip_to","country_code","country_name"[,"region","city"]
+	//
+	// with addresses as decimal integers, which also covers plain numeric
+	// ip2country files. Unassigned ranges ("-") are skipped; in the IPv6
+	// editions only the IPv4-mapped ranges are kept.
+	FormatIP2Location Format = "ip2location"
+)
+
+// ParseFormat accepts "dbip" (or "db-ip") and "ip2location".
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(s) {
+	case "dbip", "db-ip":
+		return FormatDBIP, nil
+	case "ip2location":
+		return FormatIP2Location, nil
+	}
+	return "", fmt.Errorf("unknown database format %q (want dbip or ip2location)", s)
+}
+
+// ipv4Mapped is ::ffff:0.0.0.0 as an integer, where the IPv4 ranges start
+// in ip2location's IPv6 databases.
+const ipv4Mapped = 0xffff_0000_0000
+
+// DetectFormat reports the layout of the CSV database at path, judged by
+// its first data row.
+func DetectFormat(path string) (Format, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("open db: %w", err)
+	}
+	defer f.Close()
+	var format Format
+	err = scanRecords(f, func(rec []string) bool {
+		format = detectFormat(rec)
+		return format == ""
+	})
+	if err != nil {
+		return "", err
+	}
+	if format == "" {
+		return "", errors.New("no IP ranges in database")
+	}
+	return format, nil
+}
+
+// detectFormat classifies one CSV record, or returns "" for a header,
+// comment or malformed row. ip2location writes every address as an
+// integer; DB-IP writes dotted addresses.
+func detectFormat(rec []string) Format {
+	if len(rec) < 3 {
+		return ""
+	}
+	if _, err := strconv.ParseUint(rec[0], 10, 64); err == nil {
+		return FormatIP2Location
+	}
+	if _, err := parseIP(rec[0]); err == nil {
+		return FormatDBIP
+	}
+	return ""
+}
+
+// scanCSV calls fn for every IPv4 range in a DB-IP or ip2location CSV,
+// detecting the layout from the first data row.
+func scanCSV(r io.Reader, fn func(Entry)) error {
+	var format Format
+	return scanRecords(r, func(rec []string) bool {
+		if format == "" {
+			if format = detectFormat(rec); format == "" {
+				return true
+			}
+		}
+		var e Entry
+		var ok bool
+		if format == FormatIP2Location {
+			e, ok = ip2locationEntry(rec)
+		} else {
+			e, ok = dbipEntry(rec)
+		}
+		if ok {
+			fn(e)
+		}
+		return true
+	})
+}
+
+// scanRecords calls fn for every record of r until it returns false.
+// Quoted fields may contain commas; rows that fail to parse are skipped.
+func scanRecords(r io.Reader, fn func([]string) bool) error {
+	cr := csv.NewReader(r)
+	cr.Comment = '#'
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	cr.TrimLeadingSpace = true
+	cr.ReuseRecord = true
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		var perr *csv.ParseError
+		if errors.As(err, &perr) {
+			continue // skip malformed
+		}
+		if err != nil {
+			return fmt.Errorf("scan: %w", err)
+		}
+		if !fn(rec) {
+			return nil
+		}
+	}
+}
+
+func dbipEntry(rec []string) (Entry, bool) {
+	start, end, ok := dbipRange(rec)
+	if !ok || len(rec) < 3 {
+		return Entry{}, false
+	}
+	e := Entry{Start: start, End: end, CountryCode: strings.TrimSpace(rec[2])}
+	if len(rec) >= 4 {
+		e.CountryName = strings.TrimSpace(rec[3])
+	}
+	return e, true
+}
+
+func ip2locationEntry(rec []string) (Entry, bool) {
+	if len(rec) < 3 {
+		return Entry{}, false
+	}
+	cc := strings.TrimSpace(rec[2])
+	if cc == "-" || cc == "" {
+		return Entry{}, false // unassigned or reserved
+	}
+	start, end, ok := ip2locationRange(rec)
+	if !ok {
+		return Entry{}, false
+	}
+	e := Entry{Start: start, End: end, CountryCode: cc}
+	if len(rec) >= 4 {
+		e.CountryName = strings.TrimSpace(rec[3])
+	}
+	return e, true
+}
+
+// dbipRange parses the dotted (or numeric) start and end of a DB-IP row.
+func dbipRange(rec []string) (start, end uint32, ok bool) {
+	if len(rec) < 2 {
+		return 0, 0, false
+	}
+	start, err1 := parseIP(rec[0])
+	end, err2 := parseIP(rec[1])
+	return start, end, err1 == nil && err2 == nil
+}
+
+// ip2locationRange parses the integer start and end of an ip2location row,
+// mapping the IPv4 block of the IPv6 editions back to IPv4.
+func ip2locationRange(rec []string) (start, end uint32, ok bool) {
+	if len(rec) < 2 {
+		return 0, 0, false
+	}
+	s, err1 := strconv.ParseUint(strings.TrimSpace(rec[0]), 10, 64)
+	e, err2 := strconv.ParseUint(strings.TrimSpace(rec[1]), 10, 64)
+	if err1 != nil || err2 != nil {
+		return 0, 0, false // IPv6 beyond the IPv4-mapped block
+	}
+	if s >= ipv4Mapped {
+		s, e = s-ipv4Mapped, e-ipv4Mapped
+	}
+	if e > 0xffff_ffff || s > e {
+		return 0, 0, false
+	}
+	return uint32(s), uint32(e), true
+}
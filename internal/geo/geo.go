@@ -1,13 +1,15 @@
 // Package geo provides IP-to-country lookups using a local CSV database.
 // The database is loaded lazily from the default data path on first use.
 // Use DB.Load() / DB.LoadFile() explicitly if you need early error handling.
+// Large databases can be compiled into a memory-mapped binary index with
+// BuildIndex, which LoadFile then picks up automatically.
 package geo
 
 import (
-	"bufio"
 	"encoding/binary"
 	"fmt"
 	"net"
+	"net/netip"
 	"os"
 	"path/filepath"
 	"sort"
@@ -28,7 +30,13 @@ type Entry struct {
 type DB struct {
 	mu      sync.RWMutex
 	entries []Entry
+	index   *index // set instead of entries when a binary index is mapped
 	loaded  bool
+
+	overlay overlay // geofeed prefixes, checked before the base table
+
+	namesMu sync.Mutex
+	names   map[string]string // country code → name, built on first use
 }
 
 // DefaultDB is the package-level singleton, loaded lazily.
@@ -61,8 +69,16 @@ func (db *DB) Load() error {
 //
 //	ip_from,ip_to,country_code,country_name
 //
-// Lines starting with # are ignored.
+// or ip2location LITE DB1/DB3 (see Format); the layout is detected from the
+// first data row. Lines starting with # are ignored. If a binary index built from the file
+// (see BuildIndex) sits next to it and is at least as new, the index is
+// memory-mapped instead and the CSV is not read at all.
 func (db *DB) LoadFile(path string) error {
+	if ix, ok := freshIndex(path); ok {
+		db.swap(nil, ix)
+		return nil
+	}
+
 	f, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("open db: %w", err)
@@ -70,42 +86,8 @@ func (db *DB) LoadFile(path string) error {
 	defer f.Close()
 
 	var entries []Entry
-	scanner := bufio.NewScanner(f)
-	lineNum := 0
-	for scanner.Scan() {
-		lineNum++
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
-		}
-		// Strip optional quotes.
-		line = strings.ReplaceAll(line, "\"", "")
-		parts := strings.Split(line, ",")
-		if len(parts) < 3 {
-			continue // skip malformed
-		}
-		start, err := parseIP(parts[0])
-		if err != nil {
-			continue
-		}
-		end, err := parseIP(parts[1])
-		if err != nil {
-			continue
-		}
-		cc := strings.TrimSpace(parts[2])
-		cn := ""
-		if len(parts) >= 4 {
-			cn = strings.TrimSpace(parts[3])
-		}
-		entries = append(entries, Entry{
-			Start:       start,
-			End:         end,
-			CountryCode: cc,
-			CountryName: cn,
-		})
-	}
-	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("scan: %w", err)
+	if err := scanCSV(f, func(e Entry) { entries = append(entries, e) }); err != nil {
+		return err
 	}
 
 	// Sort by start IP for binary search.
@@ -113,14 +95,67 @@ func (db *DB) LoadFile(path string) error {
 		return entries[i].Start < entries[j].Start
 	})
 
+	db.swap(entries, nil)
+	return nil
+}
+
+// freshIndex opens the index for csvPath if it exists and is not older
+// than the CSV.
+func freshIndex(csvPath string) (*index, bool) {
+	idxPath := IndexPath(csvPath)
+	ii, err := os.Stat(idxPath)
+	if err != nil {
+		return nil, false
+	}
+	if ci, err := os.Stat(csvPath); err == nil && ci.ModTime().After(ii.ModTime()) {
+		return nil, false
+	}
+	ix, err := openIndex(idxPath)
+	if err != nil {
+		return nil, false
+	}
+	return ix, true
+}
+
+// swap installs a freshly loaded table, releasing any previous mapping.
+func (db *DB) swap(entries []Entry, ix *index) {
 	db.mu.Lock()
+	old := db.index
 	db.entries = entries
+	db.index = ix
 	db.loaded = true
+	db.namesMu.Lock()
+	db.names = nil
+	db.namesMu.Unlock()
 	db.mu.Unlock()
-	return nil
+	if old != nil {
+		old.close() //nolint:errcheck
+	}
+}
+
+// Close releases a memory-mapped index, if one is in use.
+func (db *DB) Close() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.index == nil {
+		return nil
+	}
+	err := db.index.close()
+	db.index = nil
+	db.loaded = false
+	return err
+}
+
+// Indexed reports whether lookups are served from a memory-mapped index.
+func (db *DB) Indexed() bool {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return db.index != nil
 }
 
 // Lookup returns the country for an IP string. Returns ("--","Unknown") if not found.
+// Special-use addresses (see Special) return their class instead, such as
+// ("PRIVATE", "Private network (RFC 1918)"), whatever the database says.
 func (db *DB) Lookup(ipStr string) (countryCode, countryName string) {
 	db.mu.RLock()
 	if !db.loaded {
@@ -130,6 +165,15 @@ func (db *DB) Lookup(ipStr string) (countryCode, countryName string) {
 	}
 	defer db.mu.RUnlock()
 
+	if addr, err := netip.ParseAddr(ipStr); err == nil {
+		if class, name, ok := Special(addr); ok {
+			return class, name
+		}
+		if code, ok := db.overlay.lookup(addr.Unmap()); ok {
+			return code, db.countryName(code)
+		}
+	}
+
 	ip := net.ParseIP(ipStr)
 	if ip == nil {
 		return "--", "Unknown"
@@ -140,6 +184,13 @@ func (db *DB) Lookup(ipStr string) (countryCode, countryName string) {
 	}
 	n := binary.BigEndian.Uint32(ip4)
 
+	if db.index != nil {
+		if cc, cn, ok := db.index.lookup(n); ok {
+			return cc, cn
+		}
+		return "--", "Unknown"
+	}
+
 	idx := sort.Search(len(db.entries), func(i int) bool {
 		return db.entries[i].End >= n
 	})
@@ -160,6 +211,9 @@ func (db *DB) Loaded() bool {
 func (db *DB) Count() int {
 	db.mu.RLock()
 	defer db.mu.RUnlock()
+	if db.index != nil {
+		return db.index.count
+	}
 	return len(db.entries)
 }
 
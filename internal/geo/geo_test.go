@@ -1,10 +1,15 @@
 package geo
 
 import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 const sampleCSV = `# ip2country sample
@@ -56,7 +61,7 @@ func TestLookup_notFound(t *testing.T) {
 	db := &DB{}
 	_ = db.LoadFile(path)
 
-	cc, _ := db.Lookup("255.255.255.255")
+	cc, _ := db.Lookup("9.9.9.9")
 	if cc != "--" {
 		t.Errorf("out-of-range IP country = %q, want --", cc)
 	}
@@ -121,3 +126,160 @@ func TestLoadFile_dottedDecimal(t *testing.T) {
 		t.Errorf("dotted decimal lookup = %q, want AU", cc)
 	}
 }
+
+func TestBuildIndex_lookupMatchesCSV(t *testing.T) {
+	path := writeTempDB(t, sampleCSV+"\"3232235520\",\"3232301055\",\"ZZ\",\"Private, LAN\"\n")
+	csvDB := &DB{}
+	if err := csvDB.LoadFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := BuildIndex(path, IndexPath(path))
+	if err != nil {
+		t.Fatalf("BuildIndex: %v", err)
+	}
+	if n != csvDB.Count() {
+		t.Errorf("indexed %d ranges, CSV has %d", n, csvDB.Count())
+	}
+
+	db := &DB{}
+	if err := db.LoadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if !db.Indexed() {
+		t.Fatal("LoadFile should use the fresh index")
+	}
+	for _, ip := range []string{"1.0.0.0", "1.0.1.5", "8.8.8.8", "192.168.1.1", "255.255.255.255", "0.0.0.0"} {
+		wc, wn := csvDB.Lookup(ip)
+		gc, gn := db.Lookup(ip)
+		if wc != gc || wn != gn {
+			t.Errorf("Lookup(%s): index = %s/%s, csv = %s/%s", ip, gc, gn, wc, wn)
+		}
+	}
+}
+
+func TestLoadFile_staleIndexIgnored(t *testing.T) {
+	path := writeTempDB(t, sampleCSV)
+	if _, err := BuildIndex(path, IndexPath(path)); err != nil {
+		t.Fatal(err)
+	}
+	// Make the CSV newer than its index.
+	future := time.Now().Add(time.Hour)
+	if err := os.Chtimes(path, future, future); err != nil {
+		t.Fatal(err)
+	}
+	db := &DB{}
+	if err := db.LoadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if db.Indexed() {
+		t.Error("stale index should not be used")
+	}
+}
+
+func TestOpenIndex_corrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.idx")
+	os.WriteFile(path, []byte("PBGEOIX1\xff\xff\xff\xff\x00\x00\x00\x00"), 0o644) //nolint:errcheck
+	if _, err := openIndex(path); err == nil {
+		t.Error("expected error for truncated index")
+	}
+}
+
+func TestLoadFile_ip2location(t *testing.T) {
+	content := `"16777216","16777471","US","United States of America"
+"16777472","16778239","CN","China"
+"16779264","16781311","-","-"
+"838860800","838926335","BQ","Bonaire, Sint Eustatius and Saba"
+`
+	path := writeTempDB(t, content)
+	if f, err := DetectFormat(path); err != nil || f != FormatIP2Location {
+		t.Errorf("DetectFormat = %q, %v; want ip2location", f, err)
+	}
+	db := &DB{}
+	if err := db.LoadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if db.Count() != 3 {
+		t.Errorf("Count() = %d, want 3 (the unassigned range is skipped)", db.Count())
+	}
+	if cc, cn := db.Lookup("1.0.0.1"); cc != "US" || cn != "United States of America" {
+		t.Errorf("1.0.0.1 = %s/%s", cc, cn)
+	}
+	if _, cn := db.Lookup("50.0.0.1"); cn != "Bonaire, Sint Eustatius and Saba" {
+		t.Errorf("quoted name with a comma = %q", cn)
+	}
+	if cc, _ := db.Lookup("1.0.8.1"); cc != "--" {
+		t.Errorf("unassigned range = %q, want --", cc)
+	}
+}
+
+func TestLoadFile_ip2locationDB3IPv6(t *testing.T) {
+	// DB3 adds region and city; the IPv6 edition keeps IPv4 under ::ffff:0:0/96.
+	content := `"0","281470681743359","-","-","-","-"
+"281470698520576","281470698520831","US","United States of America","California","Los Angeles"
+"42540528726795050063891204319802818560","42540528806023212578155541913346768895","JP","Japan","Tokyo","Tokyo"
+`
+	path := writeTempDB(t, content)
+	db := &DB{}
+	if err := db.LoadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if db.Count() != 1 {
+		t.Errorf("Count() = %d, want only the IPv4-mapped range", db.Count())
+	}
+	if cc, _ := db.Lookup("1.0.0.200"); cc != "US" {
+		t.Errorf("1.0.0.200 = %q, want US", cc)
+	}
+}
+
+func TestDetectFormat_dbip(t *testing.T) {
+	path := writeTempDB(t, "1.0.0.0,1.0.0.255,AU\n::,::ffff,ZZ\n")
+	if f, err := DetectFormat(path); err != nil || f != FormatDBIP {
+		t.Errorf("DetectFormat = %q, %v; want db-ip", f, err)
+	}
+	if _, err := DetectFormat(writeTempDB(t, "# nothing\n")); err == nil {
+		t.Error("DetectFormat of an empty database succeeded")
+	}
+}
+
+func TestUpdate_customSource(t *testing.T) {
+	ip2l := "\"16777216\",\"16777471\",\"US\",\"United States of America\"\n"
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte(ip2l)) //nolint:errcheck
+	zw.Close()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(gz.Bytes()) //nolint:errcheck
+	}))
+	defer srv.Close()
+
+	dest := filepath.Join(t.TempDir(), "ip2country.csv")
+	src := &Source{Name: "mirror", URL: srv.URL + "/db.csv.gz", Gzipped: true, Format: FormatIP2Location}
+	if err := Update(UpdateOptions{Source: src, DestPath: dest}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if got, _ := os.ReadFile(dest); string(got) != ip2l {
+		t.Errorf("installed %q", got)
+	}
+
+	// A download in the wrong layout leaves the installed database alone.
+	src.Format = FormatDBIP
+	if err := Update(UpdateOptions{Source: src, DestPath: dest}); err == nil {
+		t.Error("Update accepted an ip2location file as db-ip")
+	}
+	if got, _ := os.ReadFile(dest); string(got) != ip2l {
+		t.Errorf("database replaced after a failed update: %q", got)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for in, want := range map[string]Format{"dbip": FormatDBIP, "db-ip": FormatDBIP, "IP2Location": FormatIP2Location} {
+		if got, err := ParseFormat(in); err != nil || got != want {
+			t.Errorf("ParseFormat(%q) = %q, %v", in, got, err)
+		}
+	}
+	if _, err := ParseFormat("maxmind"); err == nil {
+		t.Error("ParseFormat accepted an unknown format")
+	}
+}
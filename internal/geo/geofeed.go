@@ -0,0 +1,140 @@
+package geo
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// overlay maps geofeed prefixes to country codes, which take precedence
+// over the base database. Prefixes are keyed by their masked form; bits
+// lists the prefix lengths in use, longest first, so the most specific
+// prefix wins.
+type overlay struct {
+	prefixes map[netip.Prefix]string
+	bits     []int
+}
+
+func (o *overlay) add(p netip.Prefix, code string) {
+	if o.prefixes == nil {
+		o.prefixes = make(map[netip.Prefix]string)
+	}
+	p = p.Masked()
+	o.prefixes[p] = code
+	if !slices.Contains(o.bits, p.Bits()) {
+		o.bits = append(o.bits, p.Bits())
+		slices.SortFunc(o.bits, func(a, b int) int { return b - a })
+	}
+}
+
+func (o *overlay) lookup(addr netip.Addr) (string, bool) {
+	for _, bits := range o.bits {
+		if bits > addr.BitLen() {
+			continue
+		}
+		p, err := addr.Prefix(bits)
+		if err != nil {
+			continue
+		}
+		if code, ok := o.prefixes[p]; ok {
+			return code, true
+		}
+	}
+	return "", false
+}
+
+// LoadGeofeed adds the RFC 8805 geofeed at path, a local file or an
+// http(s) URL, as an overlay on the database: addresses in its prefixes
+// get the feed's country, whatever the base database says. Feeds may be
+// loaded before or after the base database, and several can be stacked;
+// for an identical prefix the last feed wins. It returns the number of
+// prefixes read.
+func (db *DB) LoadGeofeed(path string) (int, error) {
+	var r io.ReadCloser
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		client := &http.Client{Timeout: 60 * time.Second}
+		resp, err := client.Get(path)
+		if err != nil {
+			return 0, fmt.Errorf("fetch geofeed: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return 0, fmt.Errorf("fetch geofeed: server returned %s", resp.Status)
+		}
+		r = resp.Body
+	} else {
+		f, err := os.Open(path)
+		if err != nil {
+			return 0, fmt.Errorf("open geofeed: %w", err)
+		}
+		r = f
+	}
+	defer r.Close()
+	return db.AddGeofeed(r)
+}
+
+// AddGeofeed is LoadGeofeed on a reader. Rows are
+//
+//	ip_prefix,alpha2code,region,city,postal_code
+//
+// with IPv4 or IPv6 prefixes. Only the country is used; rows without a
+// valid prefix or country are skipped, as the RFC asks of consumers.
+func (db *DB) AddGeofeed(r io.Reader) (int, error) {
+	var feed overlay
+	n := 0
+	err := scanRecords(r, func(rec []string) bool {
+		if len(rec) < 2 {
+			return true
+		}
+		p, err := netip.ParsePrefix(strings.TrimSpace(rec[0]))
+		if err != nil {
+			return true
+		}
+		code := strings.ToUpper(strings.TrimSpace(rec[1]))
+		if len(code) != 2 {
+			return true
+		}
+		feed.add(p, code)
+		n++
+		return true
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for p, code := range feed.prefixes {
+		db.overlay.add(p, code)
+	}
+	return n, nil
+}
+
+// countryName returns the base database's name for code, or "" if it has
+// none. Names are collected once per loaded table; the caller holds db.mu
+// for reading.
+func (db *DB) countryName(code string) string {
+	db.namesMu.Lock()
+	defer db.namesMu.Unlock()
+	if db.names == nil {
+		db.names = make(map[string]string)
+		if db.index != nil {
+			for id := range uint32(len(db.index.offsets) / 4) {
+				if cc, cn, ok := db.index.label(id); ok && cn != "" {
+					db.names[cc] = cn
+				}
+			}
+		}
+		for _, e := range db.entries {
+			if e.CountryName != "" {
+				db.names[e.CountryCode] = e.CountryName
+			}
+		}
+	}
+	return db.names[code]
+}
@@ -0,0 +1,73 @@
+package geo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const sampleFeed = `# RFC 8805 geofeed
+1.0.0.0/24,CN,CN-BJ,Beijing,
+1.0.0.128/25,us,US-CA,Los Angeles,90001
+2400:cb00::/32,JP,JP-13,Tokyo,
+not-a-prefix,US,,,
+1.0.1.0/24,,,,
+`
+
+func TestGeofeed_overridesBase(t *testing.T) {
+	db := &DB{}
+	if err := db.LoadFile(writeTempDB(t, sampleCSV)); err != nil {
+		t.Fatal(err)
+	}
+	n, err := db.AddGeofeed(strings.NewReader(sampleFeed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("AddGeofeed read %d prefixes, want 3", n)
+	}
+
+	cases := []struct{ ip, code, name string }{
+		{"1.0.0.1", "CN", "China"},           // feed over base AU; name from the base DB
+		{"1.0.0.200", "US", "United States"}, // most specific prefix wins
+		{"::ffff:1.0.0.1", "CN", "China"},
+		{"2400:cb00::1", "JP", ""}, // IPv6, no name in the base DB
+		{"1.0.1.5", "CN", "China"}, // feed row without a country is skipped
+		{"8.8.8.8", "US", "United States"},
+	}
+	for _, c := range cases {
+		if code, name := db.Lookup(c.ip); code != c.code || name != c.name {
+			t.Errorf("Lookup(%s) = %s/%q, want %s/%q", c.ip, code, name, c.code, c.name)
+		}
+	}
+}
+
+func TestGeofeed_lastFeedWins(t *testing.T) {
+	db := &DB{}
+	db.AddGeofeed(strings.NewReader("5.0.0.0/8,DE\n")) //nolint:errcheck
+	db.AddGeofeed(strings.NewReader("5.0.0.0/8,FR\n")) //nolint:errcheck
+	db.swap(nil, nil)                                  // base loaded but empty
+	if code, _ := db.Lookup("5.1.2.3"); code != "FR" {
+		t.Errorf("Lookup = %s, want FR from the later feed", code)
+	}
+}
+
+func TestLoadGeofeed_URL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("45.0.0.0/24,NL,NL-NH,Amsterdam,\n")) //nolint:errcheck
+	}))
+	defer srv.Close()
+
+	db := &DB{}
+	db.swap(nil, nil)
+	if n, err := db.LoadGeofeed(srv.URL + "/geofeed.csv"); err != nil || n != 1 {
+		t.Fatalf("LoadGeofeed = %d, %v", n, err)
+	}
+	if code, _ := db.Lookup("45.0.0.7"); code != "NL" {
+		t.Errorf("Lookup = %s, want NL", code)
+	}
+	if _, err := db.LoadGeofeed("/nonexistent/geofeed.csv"); err == nil {
+		t.Error("LoadGeofeed of a missing file succeeded")
+	}
+}
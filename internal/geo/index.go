@@ -0,0 +1,200 @@
+package geo
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Index file layout (little-endian). The file is memory-mapped, so lookups
+// touch only the pages they binary-search through instead of holding every
+// range on the heap:
+//
+//	magic   [8]byte  "PBGEOIX1"
+//	count   uint32   number of ranges
+//	labels  uint32   number of distinct (code, name) labels
+//	ranges  count × {start, end, label uint32}, sorted by start
+//	offsets labels × uint32, offset of each label within the blob
+//	blob    labels × {len uint8, code, len uint16, name}
+const (
+	indexMagic     = "PBGEOIX1"
+	indexHeaderLen = 16
+	indexRangeLen  = 12
+)
+
+// IndexPath returns where the binary index for csvPath lives: the same
+// path with the extension replaced by ".idx".
+func IndexPath(csvPath string) string {
+	return strings.TrimSuffix(csvPath, filepath.Ext(csvPath)) + ".idx"
+}
+
+// BuildIndex compiles the CSV database at csvPath into a binary index at
+// idxPath (written atomically) and returns the number of ranges.
+func BuildIndex(csvPath, idxPath string) (int, error) {
+	f, err := os.Open(csvPath)
+	if err != nil {
+		return 0, fmt.Errorf("open db: %w", err)
+	}
+	defer f.Close()
+
+	type rng struct{ start, end, label uint32 }
+	var ranges []rng
+	labelIDs := make(map[[2]string]uint32)
+	var labels [][2]string
+	err = scanCSV(f, func(e Entry) {
+		key := [2]string{e.CountryCode, e.CountryName}
+		id, ok := labelIDs[key]
+		if !ok {
+			id = uint32(len(labels))
+			labelIDs[key] = id
+			labels = append(labels, key)
+		}
+		ranges = append(ranges, rng{e.Start, e.End, id})
+	})
+	if err != nil {
+		return 0, err
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].start < ranges[j].start })
+
+	tmp := idxPath + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return 0, fmt.Errorf("create index: %w", err)
+	}
+	w := bufio.NewWriter(out)
+	le := binary.LittleEndian
+	w.WriteString(indexMagic)                                                //nolint:errcheck
+	binary.Write(w, le, [2]uint32{uint32(len(ranges)), uint32(len(labels))}) //nolint:errcheck
+	for _, r := range ranges {
+		binary.Write(w, le, [3]uint32{r.start, r.end, r.label}) //nolint:errcheck
+	}
+	var off uint32
+	for _, l := range labels {
+		binary.Write(w, le, off) //nolint:errcheck
+		off += 1 + uint32(len(l[0])) + 2 + uint32(len(l[1]))
+	}
+	for _, l := range labels {
+		code, name := truncLabel(l[0], 0xff), truncLabel(l[1], 0xffff)
+		w.WriteByte(byte(len(code)))           //nolint:errcheck
+		w.WriteString(code)                    //nolint:errcheck
+		binary.Write(w, le, uint16(len(name))) //nolint:errcheck
+		w.WriteString(name)                    //nolint:errcheck
+	}
+	err = w.Flush()
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, idxPath)
+	}
+	if err != nil {
+		os.Remove(tmp) //nolint:errcheck
+		return 0, fmt.Errorf("write index: %w", err)
+	}
+	return len(ranges), nil
+}
+
+func truncLabel(s string, max int) string {
+	if len(s) > max {
+		return s[:max]
+	}
+	return s
+}
+
+// index is an opened (normally memory-mapped) binary index.
+type index struct {
+	data    []byte
+	count   int
+	ranges  []byte
+	offsets []byte
+	blob    []byte
+	release func() error
+}
+
+var errBadIndex = errors.New("geo index: corrupt or unsupported file")
+
+// openIndex maps the index at path and validates its layout.
+func openIndex(path string) (*index, error) {
+	data, release, err := mapFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("open index: %w", err)
+	}
+	ix, err := parseIndex(data)
+	if err != nil {
+		release() //nolint:errcheck
+		return nil, err
+	}
+	ix.release = release
+	return ix, nil
+}
+
+func parseIndex(data []byte) (*index, error) {
+	if len(data) < indexHeaderLen || string(data[:8]) != indexMagic {
+		return nil, errBadIndex
+	}
+	le := binary.LittleEndian
+	count := int(le.Uint32(data[8:]))
+	labels := int(le.Uint32(data[12:]))
+	rangesEnd := indexHeaderLen + count*indexRangeLen
+	offsetsEnd := rangesEnd + labels*4
+	if offsetsEnd > len(data) {
+		return nil, errBadIndex
+	}
+	return &index{
+		data:    data,
+		count:   count,
+		ranges:  data[indexHeaderLen:rangesEnd],
+		offsets: data[rangesEnd:offsetsEnd],
+		blob:    data[offsetsEnd:],
+	}, nil
+}
+
+func (ix *index) lookup(n uint32) (code, name string, ok bool) {
+	le := binary.LittleEndian
+	i := sort.Search(ix.count, func(i int) bool {
+		return le.Uint32(ix.ranges[i*indexRangeLen+4:]) >= n
+	})
+	if i == ix.count {
+		return "", "", false
+	}
+	r := ix.ranges[i*indexRangeLen:]
+	if le.Uint32(r) > n {
+		return "", "", false
+	}
+	return ix.label(le.Uint32(r[8:]))
+}
+
+func (ix *index) label(id uint32) (code, name string, ok bool) {
+	le := binary.LittleEndian
+	if int(id) >= len(ix.offsets)/4 {
+		return "", "", false
+	}
+	off := int(le.Uint32(ix.offsets[id*4:]))
+	if off >= len(ix.blob) {
+		return "", "", false
+	}
+	b := ix.blob[off:]
+	cl := int(b[0])
+	if len(b) < 1+cl+2 {
+		return "", "", false
+	}
+	code = string(b[1 : 1+cl])
+	b = b[1+cl:]
+	nl := int(le.Uint16(b))
+	if len(b) < 2+nl {
+		return "", "", false
+	}
+	return code, string(b[2 : 2+nl]), true
+}
+
+func (ix *index) close() error {
+	if ix.release == nil {
+		return nil
+	}
+	return ix.release()
+}
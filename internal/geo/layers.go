@@ -0,0 +1,331 @@
+package geo
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Kind is what a database layer provides.
+type Kind string
+
+const (
+	KindCountry Kind = "country"
+	KindASN     Kind = "asn"
+	KindCity    Kind = "city"
+)
+
+// ParseKind accepts "country", "asn" and "city".
+func ParseKind(s string) (Kind, error) {
+	switch k := Kind(strings.ToLower(s)); k {
+	case KindCountry, KindASN, KindCity:
+		return k, nil
+	}
+	return "", fmt.Errorf("unknown database kind %q (want country, asn or city)", s)
+}
+
+// Record is everything the layers of a Layered database know about an
+// address. Empty fields (zero ASN) are unknown.
+type Record struct {
+	CountryCode string `json:"country_code,omitempty"`
+	CountryName string `json:"country_name,omitempty"`
+	ASN         uint32 `json:"asn,omitempty"`
+	ASName      string `json:"as_name,omitempty"`
+	Region      string `json:"region,omitempty"`
+	City        string `json:"city,omitempty"`
+	// ResolvedIP and OtherCountries are set by LookupAddrs for hosts
+	// looked up by name.
+	ResolvedIP     string   `json:"resolved_ip,omitempty"`
+	OtherCountries []string `json:"other_countries,omitempty"`
+	// CountryVotes and GeoDisagreement are set by a Layered with
+	// Consensus: the code each country database gives, "--" where it has
+	// none, and whether those that place the address disagree.
+	CountryVotes    []string `json:"country_votes,omitempty"`
+	GeoDisagreement bool     `json:"geo_disagreement,omitempty"`
+}
+
+// AS returns the ASN as "AS15169 Google LLC", or "" if unknown.
+func (r Record) AS() string {
+	if r.ASN == 0 {
+		return ""
+	}
+	return strings.TrimSpace(fmt.Sprintf("AS%d %s", r.ASN, r.ASName))
+}
+
+// fill copies the fields of o that r lacks.
+func (r *Record) fill(o Record) {
+	if r.CountryCode == "" {
+		r.CountryCode, r.CountryName = o.CountryCode, o.CountryName
+	}
+	if r.ASN == 0 {
+		r.ASN, r.ASName = o.ASN, o.ASName
+	}
+	if r.City == "" {
+		r.Region, r.City = o.Region, o.City
+	}
+}
+
+// Layer names a database file and what it provides.
+type Layer struct {
+	Kind Kind
+	Path string
+}
+
+// Layered answers lookups from several databases at once: a country
+// database (with its binary index and geofeeds) plus any number of
+// ASN, city or further country layers. Each field of a Record comes from
+// the first layer that has it, the base database first.
+type Layered struct {
+	Base *DB
+	// Consensus makes the country the one most country databases (the
+	// base and country layers) agree on, instead of the first's; see
+	// Record.CountryVotes.
+	Consensus bool
+	tables    []table
+}
+
+// table is a non-base layer held in memory, sorted by start address.
+type table struct {
+	layer  Layer
+	ranges []tableRange
+}
+
+type tableRange struct {
+	start, end uint32
+	rec        Record
+}
+
+// Attach loads the database at l.Path as the next layer.
+func (ld *Layered) Attach(l Layer) error {
+	f, err := os.Open(l.Path)
+	if err != nil {
+		return fmt.Errorf("open %s layer: %w", l.Kind, err)
+	}
+	defer f.Close()
+
+	t := table{layer: l}
+	var format Format
+	err = scanRecords(f, func(rec []string) bool {
+		if format == "" {
+			if format = detectFormat(rec); format == "" {
+				return true
+			}
+		}
+		if r, ok := parseLayerRow(l.Kind, format, rec); ok {
+			t.ranges = append(t.ranges, r)
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	if len(t.ranges) == 0 {
+		return fmt.Errorf("%s layer %s: no ranges", l.Kind, l.Path)
+	}
+	sort.Slice(t.ranges, func(i, j int) bool { return t.ranges[i].start < t.ranges[j].start })
+	ld.tables = append(ld.tables, t)
+	return nil
+}
+
+// Layers returns the attached layers in lookup order, the base excluded.
+func (ld *Layered) Layers() []Layer {
+	out := make([]Layer, len(ld.tables))
+	for i, t := range ld.tables {
+		out[i] = t.layer
+	}
+	return out
+}
+
+// Lookup returns what the layers know about ipStr; CountryCode is "" when
+// no layer places it.
+func (ld *Layered) Lookup(ipStr string) Record {
+	var r Record
+	var votes []Record
+	if ld.Base != nil {
+		if cc, cn := ld.Base.Lookup(ipStr); cc != "--" {
+			r.CountryCode, r.CountryName = cc, cn
+		}
+		if ld.Base.Loaded() {
+			votes = append(votes, r)
+		}
+	}
+	ip := net.ParseIP(ipStr).To4()
+	if ip == nil {
+		return ld.vote(r, votes)
+	}
+	n := binary.BigEndian.Uint32(ip)
+	for _, t := range ld.tables {
+		var found Record
+		i := sort.Search(len(t.ranges), func(i int) bool { return t.ranges[i].end >= n })
+		if i < len(t.ranges) && t.ranges[i].start <= n {
+			found = t.ranges[i].rec
+			r.fill(found)
+		}
+		if t.layer.Kind == KindCountry {
+			votes = append(votes, found)
+		}
+	}
+	return ld.vote(r, votes)
+}
+
+// vote settles r's country by the votes of the country databases, in
+// lookup order, when ld.Consensus is set and there are at least two. The
+// most common code wins; a tie goes to the earlier database.
+func (ld *Layered) vote(r Record, votes []Record) Record {
+	if !ld.Consensus || len(votes) < 2 {
+		return r
+	}
+	count := map[string]int{}
+	winner := -1
+	for i, v := range votes {
+		code := cmp.Or(v.CountryCode, "--")
+		r.CountryVotes = append(r.CountryVotes, code)
+		if v.CountryCode == "" {
+			continue
+		}
+		count[code]++
+		if winner < 0 || count[code] > count[votes[winner].CountryCode] {
+			winner = i
+		}
+	}
+	r.GeoDisagreement = len(count) > 1
+	if winner < 0 {
+		return r
+	}
+	r.CountryCode, r.CountryName = votes[winner].CountryCode, ""
+	for _, v := range votes {
+		if v.CountryCode == r.CountryCode && v.CountryName != "" {
+			r.CountryName = v.CountryName
+			break
+		}
+	}
+	if r.CountryName == "" && ld.Base != nil {
+		// db-ip country layers have no names.
+		ld.Base.mu.RLock()
+		r.CountryName = ld.Base.countryName(r.CountryCode)
+		ld.Base.mu.RUnlock()
+	}
+	return r
+}
+
+// CountrySources returns how many country databases Consensus votes
+// among: the base, if loaded, and the country layers.
+func (ld *Layered) CountrySources() int {
+	n := 0
+	if ld.Base != nil && ld.Base.Loaded() {
+		n++
+	}
+	for _, t := range ld.tables {
+		if t.layer.Kind == KindCountry {
+			n++
+		}
+	}
+	return n
+}
+
+// parseLayerRow reads one row of a layer database. Layouts by format:
+//
+//	asn  ip2location: ip_from,ip_to,cidr,asn,as
+//	asn  db-ip:       start_ip,end_ip,asn,as_name
+//	city ip2location: ip_from,ip_to,country_code,country_name,region,city[,...]
+//	city db-ip:       start_ip,end_ip,continent,country,stateprov,city[,...]
+//
+// Country layers use the same layouts as the base database.
+func parseLayerRow(kind Kind, format Format, rec []string) (tableRange, bool) {
+	var r tableRange
+	var ok bool
+	if format == FormatIP2Location {
+		r.start, r.end, ok = ip2locationRange(rec)
+	} else {
+		r.start, r.end, ok = dbipRange(rec)
+	}
+	if !ok {
+		return tableRange{}, false
+	}
+	field := func(i int) string {
+		if i < len(rec) {
+			if v := strings.TrimSpace(rec[i]); v != "-" {
+				return v
+			}
+		}
+		return ""
+	}
+	switch kind {
+	case KindCountry:
+		r.rec.CountryCode, r.rec.CountryName = field(2), field(3)
+		ok = r.rec.CountryCode != ""
+	case KindASN:
+		col := 2
+		if format == FormatIP2Location {
+			col = 3
+		}
+		asn, err := strconv.ParseUint(strings.TrimPrefix(strings.ToUpper(field(col)), "AS"), 10, 32)
+		r.rec.ASN, r.rec.ASName = uint32(asn), field(col+1)
+		ok = err == nil && asn != 0
+	case KindCity:
+		if format == FormatIP2Location {
+			r.rec.CountryCode, r.rec.CountryName = field(2), field(3)
+		} else {
+			r.rec.CountryCode = field(3)
+		}
+		r.rec.Region, r.rec.City = field(4), field(5)
+		ok = r.rec.City != ""
+	}
+	return r, ok
+}
+
+// LayersPath is where `db attach` records the extra layers: next to the
+// default database.
+func LayersPath() string {
+	return filepath.Join(filepath.Dir(DefaultDBPath()), "layers.conf")
+}
+
+// ReadLayers reads a layers file of "kind path" lines; # starts a comment.
+// A missing file means no layers.
+func ReadLayers(path string) ([]Layer, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var layers []Layer
+	sc := bufio.NewScanner(f)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		k, p, _ := strings.Cut(text, " ")
+		kind, err := ParseKind(k)
+		if err != nil || strings.TrimSpace(p) == "" {
+			return nil, fmt.Errorf("%s:%d: want \"kind path\"", path, line)
+		}
+		layers = append(layers, Layer{Kind: kind, Path: strings.TrimSpace(p)})
+	}
+	return layers, sc.Err()
+}
+
+// WriteLayers replaces the layers file at path.
+func WriteLayers(path string, layers []Layer) error {
+	var b strings.Builder
+	b.WriteString("# proxybench geo layers: kind path (see `proxybench db attach`)\n")
+	for _, l := range layers {
+		fmt.Fprintf(&b, "%s %s\n", l.Kind, l.Path)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(b.String()), 0o644)
+}
@@ -0,0 +1,128 @@
+package geo
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLayered_Lookup(t *testing.T) {
+	base := &DB{}
+	if err := base.LoadFile(writeTempDB(t, sampleCSV)); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	asn := filepath.Join(dir, "asn.csv")
+	os.WriteFile(asn, []byte(`"16777216","16777471","1.0.0.0/24","13335","Cloudflare, Inc."
+"134744064","134744319","8.8.8.0/24","15169","Google LLC"
+"134744320","134744575","8.8.9.0/24","-","-"
+`), 0o644) //nolint:errcheck
+	city := filepath.Join(dir, "city.csv")
+	os.WriteFile(city, []byte(`8.8.8.0,8.8.8.255,NA,US,California,Mountain View,37.4,-122.1
+9.9.9.0,9.9.9.255,EU,CH,Zurich,Zurich,47.4,8.5
+`), 0o644) //nolint:errcheck
+
+	ld := &Layered{Base: base}
+	for _, l := range []Layer{{KindASN, asn}, {KindCity, city}} {
+		if err := ld.Attach(l); err != nil {
+			t.Fatalf("Attach %s: %v", l.Kind, err)
+		}
+	}
+	if got := len(ld.Layers()); got != 2 {
+		t.Errorf("Layers() = %d, want 2", got)
+	}
+
+	r := ld.Lookup("8.8.8.8")
+	want := Record{CountryCode: "US", CountryName: "United States", ASN: 15169, ASName: "Google LLC", Region: "California", City: "Mountain View"}
+	if !reflect.DeepEqual(r, want) {
+		t.Errorf("Lookup(8.8.8.8) = %+v, want %+v", r, want)
+	}
+	if r.AS() != "AS15169 Google LLC" {
+		t.Errorf("AS() = %q", r.AS())
+	}
+
+	// Country from the city layer when the base has none.
+	if r := ld.Lookup("9.9.9.9"); r.CountryCode != "CH" || r.City != "Zurich" || r.ASN != 0 {
+		t.Errorf("Lookup(9.9.9.9) = %+v", r)
+	}
+	if r := ld.Lookup("1.0.0.1"); r.ASName != "Cloudflare, Inc." || r.CountryCode != "AU" {
+		t.Errorf("Lookup(1.0.0.1) = %+v", r)
+	}
+	if r := ld.Lookup("8.8.9.1"); r.ASN != 0 {
+		t.Errorf("unassigned ASN row was used: %+v", r)
+	}
+}
+
+func TestLayered_attachEmpty(t *testing.T) {
+	ld := &Layered{}
+	if err := ld.Attach(Layer{KindASN, writeTempDB(t, "# nothing\n")}); err == nil {
+		t.Error("Attach of a layer without ranges succeeded")
+	}
+	if err := ld.Attach(Layer{KindCity, "/nonexistent.csv"}); err == nil {
+		t.Error("Attach of a missing file succeeded")
+	}
+}
+
+func TestReadWriteLayers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "layers.conf")
+	if layers, err := ReadLayers(path); err != nil || layers != nil {
+		t.Errorf("missing file = %v, %v; want no layers", layers, err)
+	}
+	in := []Layer{{KindASN, "/data/asn.csv"}, {KindCity, "/data/my city.csv"}}
+	if err := WriteLayers(path, in); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadLayers(path)
+	if err != nil || len(got) != 2 || got[0] != in[0] || got[1] != in[1] {
+		t.Errorf("ReadLayers = %v, %v; want %v", got, err, in)
+	}
+
+	os.WriteFile(path, []byte("region /data/x.csv\n"), 0o644) //nolint:errcheck
+	if _, err := ReadLayers(path); err == nil {
+		t.Error("ReadLayers accepted an unknown kind")
+	}
+}
+
+func TestLayered_consensus(t *testing.T) {
+	base := &DB{}
+	if err := base.LoadFile(writeTempDB(t, sampleCSV)); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.csv")
+	os.WriteFile(a, []byte("1.0.0.0,1.0.0.255,CN\n8.8.8.8,8.8.8.8,US\n"), 0o644) //nolint:errcheck
+	b := filepath.Join(dir, "b.csv")
+	os.WriteFile(b, []byte("1.0.0.0,1.0.0.255,CN\n8.8.8.0,8.8.8.255,DE\n9.9.9.0,9.9.9.255,CH\n"), 0o644) //nolint:errcheck
+
+	ld := &Layered{Base: base}
+	for _, path := range []string{a, b} {
+		if err := ld.Attach(Layer{KindCountry, path}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := ld.CountrySources(); n != 3 {
+		t.Errorf("CountrySources() = %d, want 3", n)
+	}
+	if r := ld.Lookup("1.0.0.1"); r.CountryCode != "AU" || r.CountryVotes != nil {
+		t.Errorf("without Consensus: %+v, want the base's country alone", r)
+	}
+
+	ld.Consensus = true
+	for _, tc := range []struct {
+		ip, code, name string
+		votes          []string
+		disagree       bool
+	}{
+		{"1.0.0.1", "CN", "China", []string{"AU", "CN", "CN"}, true},
+		{"8.8.8.8", "US", "United States", []string{"US", "US", "DE"}, true},
+		{"9.9.9.9", "CH", "", []string{"--", "--", "CH"}, false},
+		{"1.0.1.1", "CN", "China", []string{"CN", "--", "--"}, false},
+		{"11.0.0.1", "", "", []string{"--", "--", "--"}, false},
+	} {
+		r := ld.Lookup(tc.ip)
+		if r.CountryCode != tc.code || r.CountryName != tc.name || !reflect.DeepEqual(r.CountryVotes, tc.votes) || r.GeoDisagreement != tc.disagree {
+			t.Errorf("Lookup(%s) = %+v, want %s %q votes %v disagreement %v", tc.ip, r, tc.code, tc.name, tc.votes, tc.disagree)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+//go:build unix
+
+package geo
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// mapFile memory-maps path read-only.
+func mapFile(path string) ([]byte, func() error, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	size := fi.Size()
+	if size == 0 {
+		return nil, nil, errors.New("empty file")
+	}
+	if int64(int(size)) != size {
+		return nil, nil, errors.New("file too large to map")
+	}
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, func() error { return syscall.Munmap(data) }, nil
+}
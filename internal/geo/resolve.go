@@ -0,0 +1,33 @@
+package geo
+
+import (
+	"net/netip"
+	"slices"
+)
+
+// LookupAddrs places a host that resolved to addrs (e.g. a proxy given by
+// hostname). The record is that of the first address any layer places,
+// with ResolvedIP set to it; when the other addresses fall in different
+// countries, their codes are listed in OtherCountries, so a round-robin
+// name spanning regions is not reported as a single country.
+func (ld *Layered) LookupAddrs(addrs []netip.Addr) Record {
+	var r Record
+	for _, a := range addrs {
+		rec := ld.Lookup(a.Unmap().String())
+		if rec.CountryCode == "" {
+			continue
+		}
+		if r.CountryCode == "" {
+			r = rec
+			r.ResolvedIP = a.Unmap().String()
+			continue
+		}
+		if rec.CountryCode != r.CountryCode && !slices.Contains(r.OtherCountries, rec.CountryCode) {
+			r.OtherCountries = append(r.OtherCountries, rec.CountryCode)
+		}
+	}
+	if r.CountryCode == "" && len(addrs) > 0 {
+		r.ResolvedIP = addrs[0].Unmap().String()
+	}
+	return r
+}
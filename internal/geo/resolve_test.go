@@ -0,0 +1,41 @@
+package geo
+
+import (
+	"net/netip"
+	"slices"
+	"testing"
+)
+
+func TestLookupAddrs(t *testing.T) {
+	db := &DB{}
+	if err := db.LoadFile(writeTempDB(t, sampleCSV)); err != nil {
+		t.Fatal(err)
+	}
+	ld := &Layered{Base: db}
+	addrs := func(ips ...string) []netip.Addr {
+		var out []netip.Addr
+		for _, ip := range ips {
+			out = append(out, netip.MustParseAddr(ip))
+		}
+		return out
+	}
+
+	r := ld.LookupAddrs(addrs("1.0.0.1", "1.0.0.2"))
+	if r.CountryCode != "AU" || r.ResolvedIP != "1.0.0.1" || len(r.OtherCountries) != 0 {
+		t.Errorf("same-country addresses = %+v", r)
+	}
+
+	// The unplaceable IPv6 address is skipped; the others differ.
+	r = ld.LookupAddrs(addrs("2606:4700::1", "8.8.8.8", "1.0.0.1", "1.0.1.1", "8.8.4.4"))
+	if r.CountryCode != "US" || r.ResolvedIP != "8.8.8.8" {
+		t.Errorf("primary = %s via %s, want US via 8.8.8.8", r.CountryCode, r.ResolvedIP)
+	}
+	if want := []string{"AU", "CN"}; !slices.Equal(r.OtherCountries, want) {
+		t.Errorf("OtherCountries = %v, want %v", r.OtherCountries, want)
+	}
+
+	r = ld.LookupAddrs(addrs("9.9.9.9"))
+	if r.CountryCode != "" || r.ResolvedIP != "9.9.9.9" {
+		t.Errorf("unplaced address = %+v", r)
+	}
+}
@@ -0,0 +1,54 @@
+package geo
+
+import "net/netip"
+
+// Special-use address classes returned by Lookup in place of a country
+// code. None of them can host a proxy reachable from the internet.
+const (
+	ClassPrivate   = "PRIVATE"    // RFC 1918, RFC 4193 unique local
+	ClassCGNAT     = "CGNAT"      // RFC 6598 shared address space
+	ClassLoopback  = "LOOPBACK"   // RFC 1122, RFC 4291
+	ClassLinkLocal = "LINK-LOCAL" // RFC 3927, RFC 4291
+	ClassMulticast = "MULTICAST"  // RFC 5771, RFC 4291
+	ClassReserved  = "RESERVED"   // unspecified, documentation, benchmarking, future use
+)
+
+var specialRanges = []struct {
+	prefix netip.Prefix
+	class  string
+	name   string
+}{
+	{netip.MustParsePrefix("10.0.0.0/8"), ClassPrivate, "Private network (RFC 1918)"},
+	{netip.MustParsePrefix("172.16.0.0/12"), ClassPrivate, "Private network (RFC 1918)"},
+	{netip.MustParsePrefix("192.168.0.0/16"), ClassPrivate, "Private network (RFC 1918)"},
+	{netip.MustParsePrefix("100.64.0.0/10"), ClassCGNAT, "Carrier-grade NAT (RFC 6598)"},
+	{netip.MustParsePrefix("127.0.0.0/8"), ClassLoopback, "Loopback"},
+	{netip.MustParsePrefix("169.254.0.0/16"), ClassLinkLocal, "Link-local"},
+	{netip.MustParsePrefix("224.0.0.0/4"), ClassMulticast, "Multicast"},
+	{netip.MustParsePrefix("0.0.0.0/8"), ClassReserved, "This network (RFC 1122)"},
+	{netip.MustParsePrefix("192.0.0.0/24"), ClassReserved, "IETF protocol assignments (RFC 6890)"},
+	{netip.MustParsePrefix("192.0.2.0/24"), ClassReserved, "Documentation (RFC 5737)"},
+	{netip.MustParsePrefix("198.51.100.0/24"), ClassReserved, "Documentation (RFC 5737)"},
+	{netip.MustParsePrefix("203.0.113.0/24"), ClassReserved, "Documentation (RFC 5737)"},
+	{netip.MustParsePrefix("198.18.0.0/15"), ClassReserved, "Benchmarking (RFC 2544)"},
+	{netip.MustParsePrefix("240.0.0.0/4"), ClassReserved, "Reserved (RFC 1112)"},
+	{netip.MustParsePrefix("::1/128"), ClassLoopback, "Loopback"},
+	{netip.MustParsePrefix("::/128"), ClassReserved, "Unspecified"},
+	{netip.MustParsePrefix("fc00::/7"), ClassPrivate, "Unique local (RFC 4193)"},
+	{netip.MustParsePrefix("fe80::/10"), ClassLinkLocal, "Link-local"},
+	{netip.MustParsePrefix("ff00::/8"), ClassMulticast, "Multicast"},
+	{netip.MustParsePrefix("2001:db8::/32"), ClassReserved, "Documentation (RFC 3849)"},
+}
+
+// Special reports whether ip is in a private, shared, loopback, link-local,
+// multicast or reserved range, returning its class (e.g. ClassPrivate) and
+// a description. IPv4-mapped IPv6 addresses are judged as IPv4.
+func Special(ip netip.Addr) (class, name string, ok bool) {
+	ip = ip.Unmap()
+	for _, r := range specialRanges {
+		if r.prefix.Contains(ip) {
+			return r.class, r.name, true
+		}
+	}
+	return "", "", false
+}
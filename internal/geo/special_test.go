@@ -0,0 +1,52 @@
+package geo
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func TestSpecial(t *testing.T) {
+	cases := []struct{ ip, class string }{
+		{"10.1.2.3", ClassPrivate},
+		{"172.31.255.255", ClassPrivate},
+		{"192.168.0.1", ClassPrivate},
+		{"100.64.0.1", ClassCGNAT},
+		{"127.0.0.1", ClassLoopback},
+		{"169.254.1.1", ClassLinkLocal},
+		{"239.255.255.250", ClassMulticast},
+		{"255.255.255.255", ClassReserved},
+		{"198.51.100.7", ClassReserved},
+		{"::ffff:10.0.0.1", ClassPrivate},
+		{"::1", ClassLoopback},
+		{"fd00::1", ClassPrivate},
+		{"fe80::1", ClassLinkLocal},
+		{"172.32.0.1", ""},
+		{"100.128.0.1", ""},
+		{"8.8.8.8", ""},
+		{"2606:4700::1", ""},
+	}
+	for _, c := range cases {
+		class, _, ok := Special(netip.MustParseAddr(c.ip))
+		if class != c.class || ok != (c.class != "") {
+			t.Errorf("Special(%s) = %q, %v; want %q", c.ip, class, ok, c.class)
+		}
+	}
+}
+
+func TestLookup_special(t *testing.T) {
+	db := &DB{}
+	if err := db.LoadFile(writeTempDB(t, sampleCSV)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.AddGeofeed(strings.NewReader("10.0.0.0/8,DE\n")); err != nil {
+		t.Fatal(err)
+	}
+	code, name := db.Lookup("10.0.0.1")
+	if code != ClassPrivate || name != "Private network (RFC 1918)" {
+		t.Errorf("Lookup(10.0.0.1) = %q %q, want PRIVATE over the geofeed", code, name)
+	}
+	if code, _ := db.Lookup("100.100.0.1"); code != ClassCGNAT {
+		t.Errorf("Lookup(100.100.0.1) = %q, want %s", code, ClassCGNAT)
+	}
+}
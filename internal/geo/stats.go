@@ -0,0 +1,212 @@
+package geo
+
+import (
+	"cmp"
+	"fmt"
+	"net/netip"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Stats summarises what a CSV database covers, for a sanity check after
+// installing one.
+type Stats struct {
+	Format     Format
+	IPv4Ranges int
+	// IPv6Ranges counts rows for IPv6 space, which lookups ignore.
+	IPv6Ranges int
+	// Addresses is the number of IPv4 addresses placed in a country;
+	// Public is the size of the IPv4 space outside special-use ranges.
+	Addresses uint64
+	Public    uint64
+	Countries []CountryStats // most addresses first
+	Gaps      []Gap          // largest unplaced public spans first
+}
+
+// Coverage is the share of public IPv4 space placed in a country.
+func (s Stats) Coverage() float64 {
+	if s.Public == 0 {
+		return 0
+	}
+	return float64(s.Addresses) / float64(s.Public)
+}
+
+// CountryStats is one country's share of a database.
+type CountryStats struct {
+	Code      string
+	Name      string
+	Ranges    int
+	Addresses uint64
+}
+
+// Gap is an inclusive IPv4 span no range covers.
+type Gap struct {
+	Start, End uint32
+}
+
+// Size returns the number of addresses in g.
+func (g Gap) Size() uint64 { return uint64(g.End) - uint64(g.Start) + 1 }
+
+func (g Gap) String() string {
+	return formatIPv4(g.Start) + "-" + formatIPv4(g.End)
+}
+
+func formatIPv4(n uint32) string {
+	return netip.AddrFrom4([4]byte{byte(n >> 24), byte(n >> 16), byte(n >> 8), byte(n)}).String()
+}
+
+// ComputeStats reads the CSV database at path and reports its ranges per
+// country, its IPv4/IPv6 split and up to maxGaps of the largest spans of
+// public IPv4 space it leaves unplaced. Special-use ranges (see Special)
+// are never counted as gaps.
+func ComputeStats(path string, maxGaps int) (Stats, error) {
+	var st Stats
+	format, err := DetectFormat(path)
+	if err != nil {
+		return st, err
+	}
+	st.Format = format
+	if st.IPv6Ranges, err = countIPv6(path, format); err != nil {
+		return st, err
+	}
+	entries, err := readEntries(path)
+	if err != nil {
+		return st, err
+	}
+	st.IPv4Ranges = len(entries)
+
+	byCode := map[string]*CountryStats{}
+	spans := make([]Gap, 0, len(entries)+len(specialRanges))
+	for _, e := range entries {
+		if e.Start > e.End {
+			continue
+		}
+		c := byCode[e.CountryCode]
+		if c == nil {
+			c = &CountryStats{Code: e.CountryCode}
+			byCode[e.CountryCode] = c
+		}
+		if c.Name == "" {
+			c.Name = e.CountryName
+		}
+		c.Ranges++
+		spans = append(spans, Gap{e.Start, e.End})
+	}
+	placed := mergeSpans(spans)
+	for _, e := range entries {
+		if e.Start <= e.End {
+			byCode[e.CountryCode].Addresses += Gap{e.Start, e.End}.Size()
+		}
+	}
+	for _, c := range byCode {
+		st.Countries = append(st.Countries, *c)
+	}
+	slices.SortFunc(st.Countries, func(a, b CountryStats) int {
+		return cmp.Or(cmp.Compare(b.Addresses, a.Addresses), strings.Compare(a.Code, b.Code))
+	})
+
+	var special []Gap
+	for _, r := range specialRanges {
+		if p := r.prefix.Masked(); p.Addr().Is4() {
+			a := p.Addr().As4()
+			start := uint32(a[0])<<24 | uint32(a[1])<<16 | uint32(a[2])<<8 | uint32(a[3])
+			special = append(special, Gap{start, start | (1<<(32-p.Bits()) - 1)})
+		}
+	}
+	special = mergeSpans(special)
+	st.Public = 1 << 32
+	for _, g := range special {
+		st.Public -= g.Size()
+	}
+	for _, g := range placed {
+		st.Addresses += g.Size()
+	}
+	for _, g := range overlapSpans(placed, special) {
+		st.Addresses -= g // placed addresses in special space do not count
+	}
+
+	gaps := complementSpans(mergeSpans(append(placed, special...)))
+	slices.SortStableFunc(gaps, func(a, b Gap) int { return cmp.Compare(b.Size(), a.Size()) })
+	st.Gaps = gaps[:min(len(gaps), maxGaps)]
+	return st, nil
+}
+
+// mergeSpans sorts spans and joins the overlapping and adjacent ones.
+func mergeSpans(spans []Gap) []Gap {
+	slices.SortFunc(spans, func(a, b Gap) int { return cmp.Compare(a.Start, b.Start) })
+	var out []Gap
+	for _, s := range spans {
+		if n := len(out); n > 0 && uint64(s.Start) <= uint64(out[n-1].End)+1 {
+			out[n-1].End = max(out[n-1].End, s.End)
+			continue
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+// complementSpans returns the IPv4 space merged spans leave uncovered.
+func complementSpans(merged []Gap) []Gap {
+	var out []Gap
+	next := uint64(0)
+	for _, s := range merged {
+		if uint64(s.Start) > next {
+			out = append(out, Gap{uint32(next), s.Start - 1})
+		}
+		next = uint64(s.End) + 1
+	}
+	if next <= 0xffff_ffff {
+		out = append(out, Gap{uint32(next), 0xffff_ffff})
+	}
+	return out
+}
+
+// overlapSpans returns the sizes of the intersections of two merged lists.
+func overlapSpans(a, b []Gap) []uint64 {
+	var out []uint64
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		lo, hi := max(a[i].Start, b[j].Start), min(a[i].End, b[j].End)
+		if lo <= hi {
+			out = append(out, Gap{lo, hi}.Size())
+		}
+		if a[i].End < b[j].End {
+			i++
+		} else {
+			j++
+		}
+	}
+	return out
+}
+
+// countIPv6 counts the rows of the database at path that describe IPv6
+// space: colon addresses in DB-IP files, integers past the IPv4-mapped
+// block in ip2location's IPv6 editions.
+func countIPv6(path string, format Format) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("open db: %w", err)
+	}
+	defer f.Close()
+	n := 0
+	err = scanRecords(f, func(rec []string) bool {
+		if len(rec) < 3 {
+			return true
+		}
+		start := strings.TrimSpace(rec[0])
+		if format == FormatIP2Location {
+			if start == "" || strings.Trim(start, "0123456789") != "" {
+				return true // header
+			}
+			v, err := strconv.ParseUint(start, 10, 64)
+			if err != nil || v > 0xffff_ffff && (v < ipv4Mapped || v > ipv4Mapped+0xffff_ffff) {
+				n++ // past 64 bits, or outside the IPv4-mapped block
+			}
+		} else if strings.Contains(start, ":") {
+			n++
+		}
+		return true
+	})
+	return n, err
+}
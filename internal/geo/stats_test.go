@@ -0,0 +1,63 @@
+package geo
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestComputeStats(t *testing.T) {
+	csv := `1.0.0.0,1.0.0.255,AU,Australia
+1.0.1.0,1.0.3.255,CN,China
+8.8.8.0,8.8.8.255,US,United States
+10.0.0.0,10.0.0.255,ZZ,Private
+2001:200::,2001:200:ffff:ffff:ffff:ffff:ffff:ffff,JP,Japan
+`
+	st, err := ComputeStats(writeTempDB(t, csv), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Format != FormatDBIP || st.IPv4Ranges != 4 || st.IPv6Ranges != 1 {
+		t.Errorf("format %s, %d IPv4 / %d IPv6 ranges; want db-ip, 4 / 1", st.Format, st.IPv4Ranges, st.IPv6Ranges)
+	}
+	if st.Addresses != 256+768+256 {
+		t.Errorf("Addresses = %d, want %d (the private range excluded)", st.Addresses, 256+768+256)
+	}
+	if len(st.Countries) != 4 || st.Countries[0].Code != "CN" || st.Countries[0].Ranges != 1 || st.Countries[0].Addresses != 768 {
+		t.Errorf("Countries = %+v, want CN first with 768 addresses", st.Countries)
+	}
+	if len(st.Gaps) != 3 {
+		t.Fatalf("got %d gaps, want 3", len(st.Gaps))
+	}
+	for i, g := range st.Gaps {
+		if i > 0 && g.Size() > st.Gaps[i-1].Size() {
+			t.Errorf("gaps not largest first: %v", st.Gaps)
+		}
+		if _, _, ok := Special(netip.MustParseAddr(formatIPv4(g.Start))); ok {
+			t.Errorf("gap %s starts in special-use space", g)
+		}
+	}
+	if c := st.Coverage(); c <= 0 || c >= 0.001 {
+		t.Errorf("Coverage = %v", c)
+	}
+}
+
+func TestComputeStats_ip2locationIPv6(t *testing.T) {
+	csv := `"0","16777215","-","-"
+"281470698520576","281470698520831","AU","Australia"
+"281470698520832","281470698521599","CN","China"
+"42540528726795050063891204319802818560","42540528806023212578155541913346768895","JP","Japan"
+`
+	st, err := ComputeStats(writeTempDB(t, csv), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.IPv4Ranges != 2 || st.IPv6Ranges != 1 {
+		t.Errorf("%d IPv4 / %d IPv6 ranges, want 2 / 1", st.IPv4Ranges, st.IPv6Ranges)
+	}
+}
+
+func TestGap_String(t *testing.T) {
+	if got := (Gap{Start: 0x01000000, End: 0x01ffffff}).String(); got != "1.0.0.0-1.255.255.255" {
+		t.Errorf("String() = %q", got)
+	}
+}
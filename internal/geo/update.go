@@ -17,6 +17,9 @@ type Source struct {
 	Name    string
 	URL     string
 	Gzipped bool
+	// Format, if set, is the layout the download must have; a file in
+	// another layout is rejected and the installed database kept.
+	Format Format
 }
 
 // BuiltinSources lists the default free IP-country databases.
@@ -25,6 +28,7 @@ var BuiltinSources = []Source{
 		Name:    "db-ip-country-lite",
 		URL:     "https://download.db-ip.com/free/dbip-country-lite-{YYYY-MM}.csv.gz",
 		Gzipped: true,
+		Format:  FormatDBIP,
 	},
 }
 
@@ -109,12 +113,33 @@ func Update(opts UpdateOptions) error {
 		return fmt.Errorf("write: %w", err)
 	}
 
+	if src.Format != "" {
+		got, err := DetectFormat(tmp)
+		if err == nil && got != src.Format {
+			err = fmt.Errorf("download is %s, not %s", got, src.Format)
+		}
+		if err != nil {
+			os.Remove(tmp) //nolint:errcheck
+			return fmt.Errorf("verify: %w", err)
+		}
+	}
+
 	if err := os.Rename(tmp, opts.DestPath); err != nil {
 		os.Remove(tmp) //nolint:errcheck
 		return fmt.Errorf("rename: %w", err)
 	}
 
 	log(fmt.Sprintf("Saved %.1f MB → %s", float64(n)/(1<<20), opts.DestPath))
+
+	// A stale index would shadow the new CSV; rebuild it. Failing here is
+	// not fatal since LoadFile falls back to parsing the CSV.
+	idxPath := IndexPath(opts.DestPath)
+	if count, err := BuildIndex(opts.DestPath, idxPath); err != nil {
+		os.Remove(idxPath) //nolint:errcheck
+		log(fmt.Sprintf("warn: index not built: %v", err))
+	} else {
+		log(fmt.Sprintf("Indexed %d ranges → %s", count, idxPath))
+	}
 	return nil
 }
 
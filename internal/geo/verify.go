@@ -0,0 +1,113 @@
+package geo
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Report is the outcome of Verify.
+type Report struct {
+	Format   Format
+	Ranges   int
+	Checksum string // see Checksum
+
+	Malformed int // data rows that are not a valid range
+	Inverted  int // ranges whose start is after their end
+	Overlaps  int // ranges starting inside the previous one
+
+	// Indexed is set when a fresh binary index sits next to the CSV;
+	// IndexMismatches counts ranges it resolves differently.
+	Indexed         bool
+	IndexMismatches int
+}
+
+// OK reports whether Verify found no problems.
+func (r Report) OK() bool {
+	return r.Ranges > 0 && r.Malformed == 0 && r.Inverted == 0 && r.Overlaps == 0 && r.IndexMismatches == 0
+}
+
+// Verify checks the CSV database at path: that every row parses, that it
+// has ranges, none inverted or overlapping, and that its binary index, if
+// fresh, answers like the CSV at both ends of every range.
+func Verify(path string) (Report, error) {
+	var rep Report
+	format, err := DetectFormat(path)
+	if err != nil {
+		return rep, err
+	}
+	rep.Format = format
+	if rep.Malformed, err = countMalformed(path, format); err != nil {
+		return rep, err
+	}
+	entries, err := readEntries(path)
+	if err != nil {
+		return rep, err
+	}
+	rep.Ranges = len(entries)
+	rep.Checksum = checksum(entries)
+	for i, e := range entries {
+		if e.Start > e.End {
+			rep.Inverted++
+		}
+		if i > 0 && e.Start <= entries[i-1].End {
+			rep.Overlaps++
+		}
+	}
+
+	ix, ok := freshIndex(path)
+	if !ok {
+		return rep, nil // none, or stale and ignored by LoadFile
+	}
+	defer ix.close() //nolint:errcheck
+	rep.Indexed = true
+	csvDB := &DB{}
+	csvDB.swap(entries, nil)
+	for _, e := range entries {
+		for _, n := range []uint32{e.Start, e.End} {
+			ip := fmt.Sprintf("%d.%d.%d.%d", n>>24, n>>16&0xff, n>>8&0xff, n&0xff)
+			wc, wn := csvDB.Lookup(ip)
+			gc, gn, found := ix.lookup(n)
+			if !found {
+				gc, gn = "--", "Unknown"
+			}
+			if wc != gc || wn != gn {
+				rep.IndexMismatches++
+				break
+			}
+		}
+	}
+	return rep, nil
+}
+
+// countMalformed counts rows of the database at path, from the first data
+// row on, that do not parse as a range in format. Unassigned ip2location
+// rows ("-") are valid.
+func countMalformed(path string, format Format) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("open db: %w", err)
+	}
+	defer f.Close()
+	n, started := 0, false
+	err = scanRecords(f, func(rec []string) bool {
+		if !started {
+			// A header row before the data is not an error.
+			if started = detectFormat(rec) != ""; !started {
+				return true
+			}
+		}
+		ok := false
+		if format == FormatIP2Location {
+			_, ok = ip2locationEntry(rec)
+			ok = ok || len(rec) >= 3 && strings.TrimSpace(rec[2]) == "-"
+		} else {
+			_, ok = dbipEntry(rec)
+		}
+		if !ok {
+			n++
+		}
+		return true
+	})
+	return n, err
+}
@@ -0,0 +1,116 @@
+// Package health serves liveness, readiness and build-info endpoints for
+// long-running proxybench modes, suitable for Kubernetes probes.
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// Server tracks daemon state and exposes it over HTTP:
+//
+//	/healthz  200 while the process is serving
+//	/readyz   200 once a round has completed recently, 503 otherwise
+//	/version  JSON build info (version, Go version, uptime, geo DB age)
+type Server struct {
+	Version string
+	DBPath  string // geo database whose age is reported; "" = omit
+	// MaxStaleness bounds how old the last completed round may be before
+	// /readyz fails. Zero disables the staleness check.
+	MaxStaleness time.Duration
+
+	started time.Time
+
+	mu        sync.RWMutex
+	lastRound time.Time
+}
+
+// New returns a Server for the given build version.
+func New(version, dbPath string, maxStaleness time.Duration) *Server {
+	return &Server{Version: version, DBPath: dbPath, MaxStaleness: maxStaleness, started: time.Now()}
+}
+
+// MarkRound records that a check round completed at t.
+func (s *Server) MarkRound(t time.Time) {
+	s.mu.Lock()
+	s.lastRound = t
+	s.mu.Unlock()
+}
+
+// Ready reports whether a round has completed within MaxStaleness of now.
+func (s *Server) Ready(now time.Time) bool {
+	s.mu.RLock()
+	last := s.lastRound
+	s.mu.RUnlock()
+	if last.IsZero() {
+		return false
+	}
+	return s.MaxStaleness <= 0 || now.Sub(last) <= s.MaxStaleness
+}
+
+// Handler returns the HTTP handler with all endpoints mounted.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok\n")) //nolint:errcheck
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if !s.Ready(time.Now()) {
+			http.Error(w, "not ready", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok\n")) //nolint:errcheck
+	})
+	mux.HandleFunc("/version", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.buildInfo(time.Now())) //nolint:errcheck
+	})
+	return mux
+}
+
+// BuildInfo is the /version response body.
+type BuildInfo struct {
+	Version       string    `json:"version"`
+	GoVersion     string    `json:"go_version"`
+	StartedAt     time.Time `json:"started_at"`
+	UptimeSeconds int64     `json:"uptime_seconds"`
+	LastRound     time.Time `json:"last_round,omitzero"`
+	GeoDB         *DBInfo   `json:"geo_db,omitempty"`
+}
+
+// DBInfo describes the geo database file.
+type DBInfo struct {
+	Path       string    `json:"path"`
+	Modified   time.Time `json:"modified,omitzero"`
+	AgeSeconds int64     `json:"age_seconds,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+func (s *Server) buildInfo(now time.Time) BuildInfo {
+	s.mu.RLock()
+	last := s.lastRound
+	s.mu.RUnlock()
+
+	info := BuildInfo{
+		Version:       s.Version,
+		GoVersion:     runtime.Version(),
+		StartedAt:     s.started,
+		UptimeSeconds: int64(now.Sub(s.started).Seconds()),
+		LastRound:     last,
+	}
+	if s.DBPath != "" {
+		db := &DBInfo{Path: s.DBPath}
+		if fi, err := os.Stat(s.DBPath); err != nil {
+			db.Error = err.Error()
+		} else {
+			db.Modified = fi.ModTime()
+			db.AgeSeconds = int64(now.Sub(fi.ModTime()).Seconds())
+		}
+		info.GeoDB = db
+	}
+	return info
+}
@@ -0,0 +1,63 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func get(t *testing.T, h http.Handler, path string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec
+}
+
+func TestHealthz(t *testing.T) {
+	s := New("1.2.3", "", 0)
+	if rec := get(t, s.Handler(), "/healthz"); rec.Code != http.StatusOK {
+		t.Errorf("/healthz = %d, want 200", rec.Code)
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	s := New("1.2.3", "", time.Minute)
+	h := s.Handler()
+	if rec := get(t, h, "/readyz"); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("/readyz before first round = %d, want 503", rec.Code)
+	}
+	s.MarkRound(time.Now())
+	if rec := get(t, h, "/readyz"); rec.Code != http.StatusOK {
+		t.Errorf("/readyz after round = %d, want 200", rec.Code)
+	}
+	s.MarkRound(time.Now().Add(-2 * time.Minute))
+	if rec := get(t, h, "/readyz"); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("/readyz with stale round = %d, want 503", rec.Code)
+	}
+}
+
+func TestVersion(t *testing.T) {
+	db := filepath.Join(t.TempDir(), "ip2country.csv")
+	if err := os.WriteFile(db, []byte("# empty\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-48 * time.Hour)
+	os.Chtimes(db, old, old) //nolint:errcheck
+
+	s := New("1.2.3", db, 0)
+	rec := get(t, s.Handler(), "/version")
+	var info BuildInfo
+	if err := json.Unmarshal(rec.Body.Bytes(), &info); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if info.Version != "1.2.3" {
+		t.Errorf("version = %q", info.Version)
+	}
+	if info.GeoDB == nil || info.GeoDB.AgeSeconds < 47*3600 {
+		t.Errorf("geo db info = %+v, want ~48h age", info.GeoDB)
+	}
+}
@@ -0,0 +1,312 @@
+// Package history persists check and bench runs in a local SQLite database.
+// Each run records a small summary row plus every per-proxy result as JSON,
+// so result fields can grow without schema migrations.
+package history
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	_ "modernc.org/sqlite" // pure-Go driver keeps CGO_ENABLED=0 builds working
+
+	"github.com/drsoft-oss/proxybench/internal/bench"
+	"github.com/drsoft-oss/proxybench/internal/checker"
+)
+
+// Kind identifies which command produced a run.
+type Kind string
+
+const (
+	KindCheck Kind = "check"
+	KindBench Kind = "bench"
+)
+
+// ErrNotFound is returned when a run ID does not exist.
+var ErrNotFound = errors.New("run not found")
+
+// Run is the summary row of a stored run.
+type Run struct {
+	ID        int64         `json:"id"`
+	Kind      Kind          `json:"kind"`
+	StartedAt time.Time     `json:"started_at"`
+	Duration  time.Duration `json:"duration"`
+	Total     int           `json:"total"`
+	OK        int           `json:"ok"` // alive proxies (check) or proxies with ≥1 successful sample (bench)
+}
+
+const schema = `
+CREATE TABLE IF NOT EXISTS runs (
+	id          INTEGER PRIMARY KEY AUTOINCREMENT,
+	kind        TEXT    NOT NULL,
+	started_at  INTEGER NOT NULL,
+	duration_ms INTEGER NOT NULL,
+	total       INTEGER NOT NULL,
+	ok          INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS results (
+	run_id  INTEGER NOT NULL REFERENCES runs(id) ON DELETE CASCADE,
+	seq     INTEGER NOT NULL,
+	address TEXT    NOT NULL,
+	data    TEXT    NOT NULL,
+	PRIMARY KEY (run_id, seq)
+);
+CREATE INDEX IF NOT EXISTS results_address ON results(address);
+`
+
+// Store is an open history database.
+type Store struct {
+	db *sql.DB
+}
+
+// DefaultPath returns the history database location next to the geo DB.
+func DefaultPath() string {
+	if dir, err := os.UserConfigDir(); err == nil {
+		return filepath.Join(dir, "proxybench", "history.db")
+	}
+	return "history.db"
+}
+
+// Open opens (creating if needed) the database at path. "" = DefaultPath().
+func Open(path string) (*Store, error) {
+	if path == "" {
+		path = DefaultPath()
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("mkdir: %w", err)
+	}
+	db, err := sql.Open("sqlite", path+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("open history: %w", err)
+	}
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("init schema: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// SaveCheck stores a check run and returns its ID.
+func (s *Store) SaveCheck(started time.Time, duration time.Duration, results []checker.Result) (int64, error) {
+	ok := 0
+	for _, r := range results {
+		if r.Alive {
+			ok++
+		}
+	}
+	return s.save(KindCheck, started, duration, len(results), ok, len(results), func(i int) (string, any) {
+		return results[i].Address, results[i]
+	})
+}
+
+// SaveBench stores a bench run and returns its ID.
+func (s *Store) SaveBench(started time.Time, duration time.Duration, results []bench.Stats) (int64, error) {
+	ok := 0
+	for _, r := range results {
+		if r.OK() {
+			ok++
+		}
+	}
+	return s.save(KindBench, started, duration, len(results), ok, len(results), func(i int) (string, any) {
+		return results[i].Address, results[i]
+	})
+}
+
+func (s *Store) save(kind Kind, started time.Time, duration time.Duration, total, ok, n int, row func(int) (string, any)) (int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback() //nolint:errcheck — no-op after Commit
+
+	res, err := tx.Exec(`INSERT INTO runs (kind, started_at, duration_ms, total, ok) VALUES (?, ?, ?, ?, ?)`,
+		string(kind), started.UnixMilli(), duration.Milliseconds(), total, ok)
+	if err != nil {
+		return 0, fmt.Errorf("insert run: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	stmt, err := tx.Prepare(`INSERT INTO results (run_id, seq, address, data) VALUES (?, ?, ?, ?)`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+	for i := 0; i < n; i++ {
+		addr, v := row(i)
+		data, err := json.Marshal(v)
+		if err != nil {
+			return 0, fmt.Errorf("encode result: %w", err)
+		}
+		if _, err := stmt.Exec(id, i, addr, string(data)); err != nil {
+			return 0, fmt.Errorf("insert result: %w", err)
+		}
+	}
+	return id, tx.Commit()
+}
+
+// List returns the most recent runs, newest first. limit <= 0 returns all.
+func (s *Store) List(limit int) ([]Run, error) {
+	q := `SELECT id, kind, started_at, duration_ms, total, ok FROM runs ORDER BY id DESC`
+	if limit > 0 {
+		q += fmt.Sprintf(" LIMIT %d", limit)
+	}
+	rows, err := s.db.Query(q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []Run
+	for rows.Next() {
+		r, err := scanRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, r)
+	}
+	return runs, rows.Err()
+}
+
+// Get returns a single run summary.
+func (s *Store) Get(id int64) (Run, error) {
+	row := s.db.QueryRow(`SELECT id, kind, started_at, duration_ms, total, ok FROM runs WHERE id = ?`, id)
+	r, err := scanRun(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return r, ErrNotFound
+	}
+	return r, err
+}
+
+// CheckResults returns the stored results of a check run, in input order.
+func (s *Store) CheckResults(id int64) ([]checker.Result, error) {
+	var out []checker.Result
+	err := s.results(id, func(data []byte) error {
+		var r checker.Result
+		if err := json.Unmarshal(data, &r); err != nil {
+			return err
+		}
+		out = append(out, r)
+		return nil
+	})
+	return out, err
+}
+
+// BenchResults returns the stored results of a bench run, in input order.
+func (s *Store) BenchResults(id int64) ([]bench.Stats, error) {
+	var out []bench.Stats
+	err := s.results(id, func(data []byte) error {
+		var r bench.Stats
+		if err := json.Unmarshal(data, &r); err != nil {
+			return err
+		}
+		out = append(out, r)
+		return nil
+	})
+	return out, err
+}
+
+func (s *Store) results(id int64, decode func([]byte) error) error {
+	rows, err := s.db.Query(`SELECT data FROM results WHERE run_id = ? ORDER BY seq`, id)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return err
+		}
+		if err := decode([]byte(data)); err != nil {
+			return fmt.Errorf("decode result: %w", err)
+		}
+	}
+	return rows.Err()
+}
+
+// Latest is the newest recorded check of an address.
+type Latest struct {
+	At    time.Time // when its run started
+	Alive bool
+}
+
+// LatestChecks returns, by address as recorded, the newest result of each
+// proxy in check runs started at or after since.
+func (s *Store) LatestChecks(since time.Time) (map[string]Latest, error) {
+	rows, err := s.db.Query(`SELECT results.address, results.data, runs.started_at
+		FROM results JOIN runs ON runs.id = results.run_id
+		WHERE runs.kind = ? AND runs.started_at >= ?
+		ORDER BY runs.started_at, runs.id`, string(KindCheck), since.UnixMilli())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string]Latest{}
+	for rows.Next() {
+		var addr, data string
+		var started int64
+		if err := rows.Scan(&addr, &data, &started); err != nil {
+			return nil, err
+		}
+		var r struct {
+			Alive bool `json:"alive"`
+		}
+		if err := json.Unmarshal([]byte(data), &r); err != nil {
+			return nil, fmt.Errorf("decode result: %w", err)
+		}
+		out[addr] = Latest{At: time.UnixMilli(started), Alive: r.Alive}
+	}
+	return out, rows.Err()
+}
+
+// Prune deletes runs started before cutoff (zero = no age limit), and then
+// all but the newest keep runs (keep <= 0 = no count limit). It returns the
+// number of runs removed.
+func (s *Store) Prune(cutoff time.Time, keep int) (int64, error) {
+	var removed int64
+	if !cutoff.IsZero() {
+		res, err := s.db.Exec(`DELETE FROM runs WHERE started_at < ?`, cutoff.UnixMilli())
+		if err != nil {
+			return 0, err
+		}
+		n, _ := res.RowsAffected()
+		removed += n
+	}
+	if keep > 0 {
+		res, err := s.db.Exec(`DELETE FROM runs WHERE id NOT IN (SELECT id FROM runs ORDER BY id DESC LIMIT ?)`, keep)
+		if err != nil {
+			return removed, err
+		}
+		n, _ := res.RowsAffected()
+		removed += n
+	}
+	return removed, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanRun(sc scanner) (Run, error) {
+	var r Run
+	var kind string
+	var started, durMS int64
+	if err := sc.Scan(&r.ID, &kind, &started, &durMS, &r.Total, &r.OK); err != nil {
+		return r, err
+	}
+	r.Kind = Kind(kind)
+	r.StartedAt = time.UnixMilli(started)
+	r.Duration = time.Duration(durMS) * time.Millisecond
+	return r, nil
+}
@@ -0,0 +1,145 @@
+package history
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/drsoft-oss/proxybench/internal/bench"
+	"github.com/drsoft-oss/proxybench/internal/checker"
+)
+
+func openTemp(t *testing.T) *Store {
+	t.Helper()
+	s, err := Open(filepath.Join(t.TempDir(), "history.db"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestSaveAndLoadCheck(t *testing.T) {
+	s := openTemp(t)
+	started := time.Now().Truncate(time.Millisecond)
+	results := []checker.Result{
+		{Address: "http://1.2.3.4:8080", Protocol: checker.ProtocolHTTP, Alive: true, Latency: 120 * time.Millisecond},
+		{Address: "socks5://5.6.7.8:1080", Protocol: checker.ProtocolSOCKS5, Error: "connection refused"},
+	}
+	id, err := s.SaveCheck(started, 3*time.Second, results)
+	if err != nil {
+		t.Fatalf("SaveCheck: %v", err)
+	}
+
+	run, err := s.Get(id)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if run.Kind != KindCheck || run.Total != 2 || run.OK != 1 {
+		t.Errorf("run = %+v, want check 2 total 1 ok", run)
+	}
+	if !run.StartedAt.Equal(started) || run.Duration != 3*time.Second {
+		t.Errorf("timing = %v / %v", run.StartedAt, run.Duration)
+	}
+
+	got, err := s.CheckResults(id)
+	if err != nil {
+		t.Fatalf("CheckResults: %v", err)
+	}
+	if len(got) != 2 || got[0].Latency != 120*time.Millisecond || got[1].Error != "connection refused" {
+		t.Errorf("results = %+v", got)
+	}
+}
+
+func TestSaveAndLoadBench(t *testing.T) {
+	s := openTemp(t)
+	id, err := s.SaveBench(time.Now(), time.Second, []bench.Stats{
+		{Address: "http://1.2.3.4:8080", Samples: 5, Successful: 5, P95MS: 300},
+		{Address: "http://5.6.7.8:8080", Samples: 5, LossRate: 1},
+	})
+	if err != nil {
+		t.Fatalf("SaveBench: %v", err)
+	}
+	run, _ := s.Get(id)
+	if run.Kind != KindBench || run.OK != 1 {
+		t.Errorf("run = %+v", run)
+	}
+	got, err := s.BenchResults(id)
+	if err != nil || len(got) != 2 || got[0].P95MS != 300 {
+		t.Errorf("BenchResults = %+v, %v", got, err)
+	}
+}
+
+func TestGet_notFound(t *testing.T) {
+	s := openTemp(t)
+	if _, err := s.Get(42); !errors.Is(err, ErrNotFound) {
+		t.Errorf("err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestListAndPrune(t *testing.T) {
+	s := openTemp(t)
+	old := time.Now().Add(-48 * time.Hour)
+	for i := 0; i < 3; i++ {
+		if _, err := s.SaveCheck(old, 0, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := s.SaveCheck(time.Now(), 0, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	runs, err := s.List(2)
+	if err != nil || len(runs) != 2 || runs[0].ID != 6 {
+		t.Fatalf("List(2) = %+v, %v", runs, err)
+	}
+
+	n, err := s.Prune(time.Now().Add(-24*time.Hour), 0)
+	if err != nil || n != 3 {
+		t.Errorf("Prune by age removed %d (%v), want 3", n, err)
+	}
+	n, err = s.Prune(time.Time{}, 1)
+	if err != nil || n != 2 {
+		t.Errorf("Prune keep=1 removed %d (%v), want 2", n, err)
+	}
+	runs, _ = s.List(0)
+	if len(runs) != 1 || runs[0].ID != 6 {
+		t.Errorf("remaining runs = %+v", runs)
+	}
+}
+
+func TestLatestChecks(t *testing.T) {
+	s := openTemp(t)
+	day := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	save := func(at time.Time, results ...checker.Result) {
+		if _, err := s.SaveCheck(at, time.Second, results); err != nil {
+			t.Fatal(err)
+		}
+	}
+	save(day.Add(-2*time.Hour), checker.Result{Address: "http://old.example:8080", Alive: true})
+	save(day, checker.Result{Address: "http://a.example:8080", Alive: true}, checker.Result{Address: "http://b.example:8080"})
+	save(day.Add(time.Minute), checker.Result{Address: "http://b.example:8080", Alive: true})
+	if _, err := s.SaveBench(day.Add(2*time.Minute), time.Second, []bench.Stats{{Address: "http://a.example:8080"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.LatestChecks(day.Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]Latest{
+		"http://a.example:8080": {At: day, Alive: true},
+		"http://b.example:8080": {At: day.Add(time.Minute), Alive: true},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("LatestChecks = %+v, want %+v", got, want)
+	}
+	for addr, w := range want {
+		if g := got[addr]; !g.At.Equal(w.At) || g.Alive != w.Alive {
+			t.Errorf("%s = %+v, want %+v", addr, g, w)
+		}
+	}
+}
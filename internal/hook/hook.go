@@ -0,0 +1,131 @@
+// Package hook runs a user command for each result or event, with the
+// value as JSON on stdin, so proxybench can be extended by scripts.
+package hook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Defaults for a Runner.
+const (
+	DefaultTimeout     = 30 * time.Second
+	DefaultConcurrency = 4
+)
+
+// Placeholder in a command is replaced by the value's JSON, quoted for the
+// shell. On Windows it becomes a reference to EnvVar instead, expanded by
+// cmd only after it has parsed the line, since cmd has no quoting that
+// keeps a value's &, | or % from being read as its own syntax.
+const Placeholder = "{json}"
+
+// EnvVar holds the value's JSON in the environment of every run.
+const EnvVar = "PROXYBENCH_JSON"
+
+// Runner runs Command through the shell (sh -c, or cmd /V:ON /C on
+// Windows) for every value passed to Run, at most Concurrency at a time.
+// Each run gets the value as one line of JSON on stdin and in EnvVar, and
+// is killed after Timeout. The command's output goes to Output.
+type Runner struct {
+	Command     string
+	Timeout     time.Duration
+	Concurrency int
+	Output      io.Writer
+
+	once   sync.Once
+	slots  chan struct{}
+	wg     sync.WaitGroup
+	mu     sync.Mutex // serialises Output and first
+	failed atomic.Int64
+	first  error
+}
+
+// Run starts the command for v and returns without waiting for it,
+// unless Concurrency runs are already going.
+func (r *Runner) Run(ctx context.Context, v any) {
+	r.once.Do(func() {
+		r.slots = make(chan struct{}, max(r.Concurrency, 1))
+	})
+	data, err := json.Marshal(v)
+	if err != nil {
+		r.fail(err)
+		return
+	}
+	select {
+	case r.slots <- struct{}{}:
+	case <-ctx.Done():
+		return
+	}
+	r.wg.Go(func() {
+		defer func() { <-r.slots }()
+		if err := r.run(ctx, data); err != nil {
+			r.fail(err)
+		}
+	})
+}
+
+// Wait waits for every run started and returns how many failed and the
+// first failure.
+func (r *Runner) Wait() (int, error) {
+	r.wg.Wait()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return int(r.failed.Load()), r.first
+}
+
+func (r *Runner) fail(err error) {
+	r.failed.Add(1)
+	r.mu.Lock()
+	if r.first == nil {
+		r.first = err
+	}
+	r.mu.Unlock()
+}
+
+func (r *Runner) run(ctx context.Context, data []byte) error {
+	timeout := r.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		// Delayed expansion (/V:ON) substitutes !var! after parsing.
+		command := strings.ReplaceAll(r.Command, Placeholder, "!"+EnvVar+"!")
+		cmd = exec.CommandContext(ctx, "cmd", "/V:ON", "/C", command)
+	} else {
+		command := strings.ReplaceAll(r.Command, Placeholder, quote(string(data)))
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.Env = append(os.Environ(), EnvVar+"="+string(data))
+	cmd.Stdin = bytes.NewReader(append(data, '\n'))
+	cmd.WaitDelay = time.Second // for children of the shell holding its output open
+	var out bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &out
+	err := cmd.Run()
+	if out.Len() > 0 && r.Output != nil {
+		r.mu.Lock()
+		r.Output.Write(out.Bytes()) //nolint:errcheck
+		r.mu.Unlock()
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", r.Command, err)
+	}
+	return nil
+}
+
+// quote quotes s as one argument for sh.
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
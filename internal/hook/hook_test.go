@@ -0,0 +1,59 @@
+package hook
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunner(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	dir := t.TempDir()
+	var out bytes.Buffer
+	r := &Runner{
+		Command:     `cat >> "` + dir + `/stdin"; printf '%s\n' "$PROXYBENCH_JSON" >> "` + dir + `/env"; printf '%s\n' {json} >&2`,
+		Concurrency: 2,
+		Output:      &out,
+	}
+	for _, v := range []map[string]string{{"address": "http://1.2.3.4:8080"}, {"address": "it's quoted"}} {
+		r.Run(context.Background(), v)
+	}
+	if n, err := r.Wait(); n != 0 || err != nil {
+		t.Fatalf("failed = %d, err = %v", n, err)
+	}
+	for _, want := range []string{`{"address":"http://1.2.3.4:8080"}`, `{"address":"it's quoted"}`} {
+		if !strings.Contains(out.String(), want+"\n") {
+			t.Errorf("output %q lacks the {json} argument %s", out.String(), want)
+		}
+	}
+	stdin, _ := os.ReadFile(filepath.Join(dir, "stdin"))
+	if !strings.Contains(string(stdin), `{"address":"it's quoted"}`+"\n") {
+		t.Errorf("stdin = %q", stdin)
+	}
+	env, _ := os.ReadFile(filepath.Join(dir, "env"))
+	if !strings.Contains(string(env), `{"address":"it's quoted"}`+"\n") {
+		t.Errorf("%s = %q", EnvVar, env)
+	}
+}
+
+func TestRunner_failures(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	r := &Runner{Command: "exit 3"}
+	r.Run(context.Background(), 1)
+	slow := &Runner{Command: "sleep 5", Timeout: 50 * time.Millisecond}
+	slow.Run(context.Background(), 1)
+	for _, r := range []*Runner{r, slow} {
+		if n, err := r.Wait(); n != 1 || err == nil {
+			t.Errorf("%s: failed = %d, err = %v", r.Command, n, err)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+// Package memlimit keeps proxybench under a memory ceiling on hosts it
+// shares with other workloads: the Go runtime collects harder as the
+// ceiling nears, and callers pause new work while memory stays close to it.
+package memlimit
+
+import (
+	"context"
+	"runtime"
+	"runtime/debug"
+	"runtime/metrics"
+	"time"
+)
+
+// HighWater is the share of the limit above which Wait holds new work back.
+const HighWater = 0.9
+
+// pollInterval is how often Wait re-reads memory use, and maxPause the
+// longest it waits (vars for tests).
+var (
+	pollInterval = 100 * time.Millisecond
+	maxPause     = time.Second
+)
+
+// Guard holds new work back while the process uses too much memory.
+type Guard struct {
+	limit uint64
+	usage func() uint64
+}
+
+// New returns a Guard for limit bytes and sets it as the Go runtime's soft
+// memory limit.
+func New(limit uint64) *Guard {
+	debug.SetMemoryLimit(int64(limit))
+	return &Guard{limit: limit, usage: Usage}
+}
+
+// Limit returns the limit in bytes.
+func (g *Guard) Limit() uint64 { return g.limit }
+
+// Over reports whether memory use is at or above HighWater of the limit.
+// A nil Guard never is.
+func (g *Guard) Over() bool {
+	return g != nil && float64(g.usage()) >= HighWater*float64(g.limit)
+}
+
+// Wait returns at once when memory use is below HighWater of the limit.
+// Otherwise it forces a collection and waits, re-checking every
+// pollInterval, until use falls below it, a second has passed or ctx is
+// done: a limit below what the process needs at all slows it to one new
+// piece of work a second rather than stalling it. A nil Guard never waits.
+func (g *Guard) Wait(ctx context.Context) error {
+	if !g.Over() {
+		return nil
+	}
+	runtime.GC()
+	tick := time.NewTicker(pollInterval)
+	defer tick.Stop()
+	deadline := time.After(maxPause)
+	for g.Over() {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-deadline:
+			return nil
+		case <-tick.C:
+		}
+	}
+	return nil
+}
+
+// Usage returns the memory the Go runtime holds from the operating
+// system, less what it has returned: close to the resident set, without
+// reading /proc.
+func Usage() uint64 {
+	s := []metrics.Sample{
+		{Name: "/memory/classes/total:bytes"},
+		{Name: "/memory/classes/heap/released:bytes"},
+	}
+	metrics.Read(s)
+	if s[0].Value.Kind() != metrics.KindUint64 || s[1].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return s[0].Value.Uint64() - s[1].Value.Uint64()
+}
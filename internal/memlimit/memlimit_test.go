@@ -0,0 +1,55 @@
+package memlimit
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWait(t *testing.T) {
+	pollInterval = time.Millisecond
+	var used atomic.Uint64
+	g := &Guard{limit: 1000, usage: used.Load}
+
+	used.Store(899)
+	if err := g.Wait(context.Background()); err != nil || g.Over() {
+		t.Fatalf("under the high-water mark: over = %v, err = %v", g.Over(), err)
+	}
+
+	used.Store(950)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := g.Wait(ctx); err == nil {
+		t.Fatal("Wait should block while memory stays high")
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		used.Store(500)
+	}()
+	if err := g.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait after memory fell: %v", err)
+	}
+
+	// A limit that is never met slows work down without stopping it.
+	maxPause = 30 * time.Millisecond
+	used.Store(2000)
+	start := time.Now()
+	if err := g.Wait(context.Background()); err != nil || time.Since(start) < maxPause {
+		t.Errorf("over the limit for good: err = %v after %s", err, time.Since(start))
+	}
+}
+
+func TestWait_nil(t *testing.T) {
+	var g *Guard
+	if err := g.Wait(context.Background()); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	if Usage() == 0 {
+		t.Error("Usage() = 0")
+	}
+}
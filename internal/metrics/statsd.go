@@ -0,0 +1,148 @@
+// Package metrics emits check and benchmark measurements to a StatsD or
+// DogStatsD endpoint over UDP.
+package metrics
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Flavor selects the wire dialect.
+type Flavor string
+
+const (
+	// FlavorDogStatsD appends tags as "|#key:value,...".
+	FlavorDogStatsD Flavor = "dogstatsd"
+	// FlavorStatsD has no tag support; tag values are folded into the metric name.
+	FlavorStatsD Flavor = "statsd"
+)
+
+// Tags are attached to every metric sample.
+type Tags map[string]string
+
+// Client is a fire-and-forget StatsD client. Send errors are counted but never
+// returned, so a missing collector can never slow down or fail a run.
+// A nil *Client is valid and discards everything.
+type Client struct {
+	prefix string
+	flavor Flavor
+
+	mu     sync.Mutex
+	conn   net.Conn
+	errors int
+}
+
+// New dials addr ("host:port") over UDP. prefix is prepended to every metric
+// name, e.g. "proxybench." → "proxybench.check.latency".
+func New(addr, prefix string, flavor Flavor) (*Client, error) {
+	switch flavor {
+	case "":
+		flavor = FlavorDogStatsD
+	case FlavorDogStatsD, FlavorStatsD:
+	default:
+		return nil, fmt.Errorf("unknown statsd flavor %q (want dogstatsd|statsd)", flavor)
+	}
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("dial statsd: %w", err)
+	}
+	return &Client{prefix: prefix, flavor: flavor, conn: conn}, nil
+}
+
+// Timing records a duration in milliseconds.
+func (c *Client) Timing(name string, d time.Duration, tags Tags) {
+	c.send(name, fmt.Sprintf("%d|ms", d.Milliseconds()), tags)
+}
+
+// Count adds delta to a counter.
+func (c *Client) Count(name string, delta int64, tags Tags) {
+	c.send(name, fmt.Sprintf("%d|c", delta), tags)
+}
+
+// Incr increments a counter by one.
+func (c *Client) Incr(name string, tags Tags) {
+	c.Count(name, 1, tags)
+}
+
+// Errors returns how many packets failed to send.
+func (c *Client) Errors() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.errors
+}
+
+// Close releases the UDP socket.
+func (c *Client) Close() error {
+	if c == nil {
+		return nil
+	}
+	return c.conn.Close()
+}
+
+func (c *Client) send(name, value string, tags Tags) {
+	if c == nil {
+		return
+	}
+	line := c.format(name, value, tags)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := c.conn.Write([]byte(line)); err != nil {
+		c.errors++
+	}
+}
+
+// format renders one metric line in the client's flavor.
+func (c *Client) format(name, value string, tags Tags) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	if c.flavor == FlavorStatsD {
+		var b strings.Builder
+		b.WriteString(c.prefix)
+		b.WriteString(name)
+		for _, k := range keys {
+			b.WriteByte('.')
+			b.WriteString(sanitize(tags[k]))
+		}
+		b.WriteByte(':')
+		b.WriteString(value)
+		return b.String()
+	}
+
+	line := c.prefix + name + ":" + value
+	if len(keys) > 0 {
+		pairs := make([]string, len(keys))
+		for i, k := range keys {
+			pairs[i] = k + ":" + tagValue(tags[k])
+		}
+		line += "|#" + strings.Join(pairs, ",")
+	}
+	return line
+}
+
+// sanitize makes a value safe to use as a StatsD name segment.
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
+
+// tagValue strips characters that terminate a DogStatsD tag.
+func tagValue(s string) string {
+	return strings.NewReplacer(",", "_", "|", "_", "#", "_").Replace(s)
+}
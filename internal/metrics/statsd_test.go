@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func listen(t *testing.T) *net.UDPConn {
+	t.Helper()
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func recv(t *testing.T, conn *net.UDPConn) string {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second)) //nolint:errcheck
+	buf := make([]byte, 1500)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	return string(buf[:n])
+}
+
+func TestClient_dogstatsd(t *testing.T) {
+	srv := listen(t)
+	c, err := New(srv.LocalAddr().String(), "proxybench.", FlavorDogStatsD)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer c.Close()
+
+	c.Timing("check.latency", 250*time.Millisecond, Tags{"protocol": "http", "proxy": "http://1.2.3.4:8080"})
+	want := "proxybench.check.latency:250|ms|#protocol:http,proxy:http://1.2.3.4:8080"
+	if got := recv(t, srv); got != want {
+		t.Errorf("packet = %q, want %q", got, want)
+	}
+
+	c.Incr("check.success", nil)
+	if got := recv(t, srv); got != "proxybench.check.success:1|c" {
+		t.Errorf("packet = %q", got)
+	}
+}
+
+func TestClient_statsdFoldsTags(t *testing.T) {
+	c := &Client{prefix: "pb.", flavor: FlavorStatsD}
+	got := c.format("check.failure", "1|c", Tags{"protocol": "socks5", "proxy": "10.0.0.1:1080"})
+	want := "pb.check.failure.socks5.10_0_0_1_1080:1|c"
+	if got != want {
+		t.Errorf("format = %q, want %q", got, want)
+	}
+}
+
+func TestNew_badFlavor(t *testing.T) {
+	if _, err := New("127.0.0.1:8125", "", "graphite"); err == nil {
+		t.Error("expected error for unknown flavor")
+	}
+}
+
+func TestNilClient(t *testing.T) {
+	var c *Client
+	c.Incr("x", nil) // must not panic
+	if c.Errors() != 0 {
+		t.Error("nil client should report zero errors")
+	}
+}
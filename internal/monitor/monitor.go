@@ -0,0 +1,107 @@
+// Package monitor tracks proxy health across repeated check rounds and
+// reports state changes (down, recovered, latency regressions).
+package monitor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/drsoft-oss/proxybench/internal/checker"
+	"github.com/drsoft-oss/proxybench/internal/notify"
+)
+
+// DefaultRegressionFactor flags a regression when latency exceeds 2× baseline.
+const DefaultRegressionFactor = 2.0
+
+// State is the tracked health of a single proxy.
+type State struct {
+	Alive     bool          `json:"alive"`
+	Baseline  time.Duration `json:"baseline"` // smoothed latency of successful checks
+	LastCheck time.Time     `json:"last_check"`
+	LastError string        `json:"last_error,omitempty"`
+}
+
+// Tracker remembers per-proxy state between rounds in a Store. Rounds of a
+// single Tracker are sequential; sharing a RedisStore lets several monitor
+// instances see the same health view.
+type Tracker struct {
+	// RegressionFactor is the latency/baseline ratio that counts as a
+	// regression. Values <= 1 use DefaultRegressionFactor.
+	RegressionFactor float64
+
+	store Store
+}
+
+// NewTracker returns a Tracker backed by store (nil = a new MemoryStore).
+func NewTracker(factor float64, store Store) *Tracker {
+	if store == nil {
+		store = NewMemoryStore()
+	}
+	return &Tracker{RegressionFactor: factor, store: store}
+}
+
+// Observe folds one round of check results into the tracker and returns
+// what changed. On the first observation of a proxy only a failure is
+// reported; recoveries and regressions need a previous state.
+func (t *Tracker) Observe(results []checker.Result, now time.Time) (notify.Summary, error) {
+	factor := t.RegressionFactor
+	if factor <= 1 {
+		factor = DefaultRegressionFactor
+	}
+	sum := notify.Summary{Time: now, Total: len(results)}
+
+	addrs := make([]string, len(results))
+	for i, r := range results {
+		addrs[i] = r.Address
+	}
+	states, err := t.store.Load(addrs)
+	if err != nil {
+		return sum, fmt.Errorf("load state: %w", err)
+	}
+	updated := make(map[string]State, len(results))
+
+	for _, r := range results {
+		prev, seen := states[r.Address]
+		next := State{Alive: r.Alive, Baseline: prev.Baseline, LastCheck: now, LastError: r.Error}
+
+		switch {
+		case !r.Alive:
+			if !seen || prev.Alive {
+				sum.Down = append(sum.Down, notify.Down{Address: r.Address, Error: r.Error})
+			}
+		case seen && !prev.Alive:
+			sum.Recovered = append(sum.Recovered, r.Address)
+		}
+
+		if r.Alive {
+			sum.Alive++
+			if prev.Baseline > 0 && float64(r.Latency) > factor*float64(prev.Baseline) {
+				sum.Regressions = append(sum.Regressions, notify.Regression{
+					Address:  r.Address,
+					Baseline: prev.Baseline,
+					Latency:  r.Latency,
+				})
+			}
+			next.Baseline = smooth(prev.Baseline, r.Latency)
+		}
+		updated[r.Address] = next
+	}
+	if err := t.store.Save(updated); err != nil {
+		return sum, fmt.Errorf("save state: %w", err)
+	}
+	return sum, nil
+}
+
+// States returns the stored state of those of addresses that have one.
+func (t *Tracker) States(addresses []string) (map[string]State, error) {
+	return t.store.Load(addresses)
+}
+
+// smooth updates an exponential moving average (alpha = 0.3) so one slow
+// sample doesn't permanently shift the baseline.
+func smooth(baseline, sample time.Duration) time.Duration {
+	if baseline == 0 {
+		return sample
+	}
+	return time.Duration(0.7*float64(baseline) + 0.3*float64(sample))
+}
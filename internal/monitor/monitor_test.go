@@ -0,0 +1,121 @@
+package monitor
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/drsoft-oss/proxybench/internal/checker"
+)
+
+func result(addr string, alive bool, latency time.Duration) checker.Result {
+	r := checker.Result{Address: addr, Alive: alive, Latency: latency}
+	if !alive {
+		r.Error = "connection refused"
+	}
+	return r
+}
+
+func TestObserve_firstRound(t *testing.T) {
+	tr := NewTracker(0, nil)
+	sum, err := tr.Observe([]checker.Result{
+		result("a", true, 100*time.Millisecond),
+		result("b", false, 0),
+	}, time.Now())
+	if err != nil {
+		t.Fatalf("Observe: %v", err)
+	}
+
+	if sum.Total != 2 || sum.Alive != 1 {
+		t.Errorf("total/alive = %d/%d, want 2/1", sum.Total, sum.Alive)
+	}
+	if len(sum.Down) != 1 || sum.Down[0].Address != "b" {
+		t.Errorf("down = %+v, want [b]", sum.Down)
+	}
+	if len(sum.Recovered) != 0 || len(sum.Regressions) != 0 {
+		t.Error("first round should not report recoveries or regressions")
+	}
+}
+
+func TestObserve_transitions(t *testing.T) {
+	tr := NewTracker(2, nil)
+	now := time.Now()
+	tr.Observe([]checker.Result{
+		result("a", true, 100*time.Millisecond),
+		result("b", false, 0),
+		result("c", true, 100*time.Millisecond),
+	}, now)
+
+	sum, _ := tr.Observe([]checker.Result{
+		result("a", true, 300*time.Millisecond), // regression
+		result("b", true, 100*time.Millisecond), // recovered
+		result("c", false, 0),                   // went down
+	}, now.Add(time.Minute))
+
+	if len(sum.Regressions) != 1 || sum.Regressions[0].Address != "a" {
+		t.Errorf("regressions = %+v, want [a]", sum.Regressions)
+	}
+	if len(sum.Recovered) != 1 || sum.Recovered[0] != "b" {
+		t.Errorf("recovered = %v, want [b]", sum.Recovered)
+	}
+	if len(sum.Down) != 1 || sum.Down[0].Address != "c" {
+		t.Errorf("down = %+v, want [c]", sum.Down)
+	}
+
+	// A proxy that stays down is not re-reported.
+	sum, _ = tr.Observe([]checker.Result{result("c", false, 0)}, now.Add(2*time.Minute))
+	if len(sum.Down) != 0 {
+		t.Errorf("persistent failure re-reported: %+v", sum.Down)
+	}
+}
+
+func TestSmooth(t *testing.T) {
+	if got := smooth(0, 200*time.Millisecond); got != 200*time.Millisecond {
+		t.Errorf("smooth(0, 200ms) = %v", got)
+	}
+	if got := smooth(100*time.Millisecond, 200*time.Millisecond); got != 130*time.Millisecond {
+		t.Errorf("smooth(100ms, 200ms) = %v, want 130ms", got)
+	}
+}
+
+func TestTracker_sharedStore(t *testing.T) {
+	// Two trackers (e.g. two instances) sharing one store see each other's state.
+	store := NewMemoryStore()
+	a := NewTracker(0, store)
+	b := NewTracker(0, store)
+
+	if _, err := a.Observe([]checker.Result{result("p", false, 0)}, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	sum, err := b.Observe([]checker.Result{result("p", false, 0)}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sum.Down) != 0 {
+		t.Errorf("second instance re-reported a known failure: %+v", sum.Down)
+	}
+}
+
+func TestRedisStore(t *testing.T) {
+	url := os.Getenv("PROXYBENCH_TEST_REDIS")
+	if url == "" {
+		t.Skip("set PROXYBENCH_TEST_REDIS=redis://host:6379/15 to run")
+	}
+	store, err := NewRedisStore(url, "proxybench:test:"+t.Name())
+	if err != nil {
+		t.Fatalf("NewRedisStore: %v", err)
+	}
+	defer store.Close()
+
+	want := State{Alive: true, Baseline: 120 * time.Millisecond, LastCheck: time.Now().UTC().Truncate(time.Second)}
+	if err := store.Save(map[string]State{"p": want}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	got, err := store.Load([]string{"p", "missing"})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(got) != 1 || got["p"].Baseline != want.Baseline || !got["p"].LastCheck.Equal(want.LastCheck) {
+		t.Errorf("Load = %+v", got)
+	}
+}
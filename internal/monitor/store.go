@@ -0,0 +1,144 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Store persists per-proxy State between rounds. Implementations must be
+// safe for concurrent use.
+type Store interface {
+	// Load returns the known states for the given addresses; unknown
+	// addresses are simply absent from the map.
+	Load(addresses []string) (map[string]State, error)
+	// Save writes (upserts) the given states.
+	Save(states map[string]State) error
+}
+
+// MemoryStore keeps state in process memory. It is the default store.
+type MemoryStore struct {
+	mu     sync.RWMutex
+	states map[string]State
+}
+
+// NewMemoryStore returns an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{states: make(map[string]State)}
+}
+
+// Load implements Store.
+func (m *MemoryStore) Load(addresses []string) (map[string]State, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make(map[string]State, len(addresses))
+	for _, a := range addresses {
+		if s, ok := m.states[a]; ok {
+			out[a] = s
+		}
+	}
+	return out, nil
+}
+
+// Save implements Store.
+func (m *MemoryStore) Save(states map[string]State) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for a, s := range states {
+		m.states[a] = s
+	}
+	return nil
+}
+
+// DefaultRedisKey is the hash that holds proxy state in Redis.
+const DefaultRedisKey = "proxybench:state"
+
+// RedisStore shares state between proxybench instances through a single
+// Redis hash (field = proxy address, value = JSON State). Concurrent writers
+// follow last-write-wins semantics per proxy.
+type RedisStore struct {
+	client  *redis.Client
+	key     string
+	timeout time.Duration
+}
+
+// NewRedisStore connects to a redis:// or rediss:// URL and verifies the
+// connection. key "" = DefaultRedisKey.
+func NewRedisStore(rawURL, key string) (*RedisStore, error) {
+	opts, err := redis.ParseURL(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse redis URL: %w", err)
+	}
+	if key == "" {
+		key = DefaultRedisKey
+	}
+	s := &RedisStore{client: redis.NewClient(opts), key: key, timeout: 5 * time.Second}
+
+	ctx, cancel := s.ctx()
+	defer cancel()
+	if err := s.client.Ping(ctx).Err(); err != nil {
+		s.client.Close()
+		return nil, fmt.Errorf("redis ping: %w", err)
+	}
+	return s, nil
+}
+
+// Load implements Store.
+func (s *RedisStore) Load(addresses []string) (map[string]State, error) {
+	out := make(map[string]State, len(addresses))
+	if len(addresses) == 0 {
+		return out, nil
+	}
+	ctx, cancel := s.ctx()
+	defer cancel()
+	vals, err := s.client.HMGet(ctx, s.key, addresses...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis hmget: %w", err)
+	}
+	for i, v := range vals {
+		raw, ok := v.(string)
+		if !ok {
+			continue // nil = unknown address
+		}
+		var st State
+		if err := json.Unmarshal([]byte(raw), &st); err != nil {
+			return nil, fmt.Errorf("decode state for %s: %w", addresses[i], err)
+		}
+		out[addresses[i]] = st
+	}
+	return out, nil
+}
+
+// Save implements Store.
+func (s *RedisStore) Save(states map[string]State) error {
+	if len(states) == 0 {
+		return nil
+	}
+	fields := make([]any, 0, 2*len(states))
+	for a, st := range states {
+		b, err := json.Marshal(st)
+		if err != nil {
+			return err
+		}
+		fields = append(fields, a, string(b))
+	}
+	ctx, cancel := s.ctx()
+	defer cancel()
+	if err := s.client.HSet(ctx, s.key, fields...).Err(); err != nil {
+		return fmt.Errorf("redis hset: %w", err)
+	}
+	return nil
+}
+
+// Close closes the Redis connection pool.
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
+
+func (s *RedisStore) ctx() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), s.timeout)
+}
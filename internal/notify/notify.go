@@ -0,0 +1,177 @@
+// Package notify delivers proxy health alerts to chat services (Slack, Telegram).
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Severity ranks how urgent an alert is.
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityCritical
+)
+
+// String returns the lower-case name of the severity.
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityCritical:
+		return "critical"
+	default:
+		return fmt.Sprintf("severity(%d)", int(s))
+	}
+}
+
+// ParseSeverity converts "info", "warning" or "critical" into a Severity.
+func ParseSeverity(s string) (Severity, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "info":
+		return SeverityInfo, nil
+	case "warning", "warn":
+		return SeverityWarning, nil
+	case "critical", "crit":
+		return SeverityCritical, nil
+	default:
+		return SeverityInfo, fmt.Errorf("unknown severity %q (want info|warning|critical)", s)
+	}
+}
+
+// Down describes a proxy that failed its latest check.
+type Down struct {
+	Address string
+	Error   string
+}
+
+// Regression describes a proxy whose latency grew well beyond its baseline.
+type Regression struct {
+	Address  string
+	Baseline time.Duration
+	Latency  time.Duration
+}
+
+// Summary is the data passed to message templates after each monitor round.
+type Summary struct {
+	Time        time.Time
+	Total       int
+	Alive       int
+	Down        []Down
+	Recovered   []string
+	Regressions []Regression
+}
+
+// Severity derives the alert level: critical when proxies went down,
+// warning for latency regressions, info otherwise (e.g. recoveries only).
+func (s Summary) Severity() Severity {
+	switch {
+	case len(s.Down) > 0:
+		return SeverityCritical
+	case len(s.Regressions) > 0:
+		return SeverityWarning
+	default:
+		return SeverityInfo
+	}
+}
+
+// Empty reports whether the summary carries nothing worth alerting about.
+func (s Summary) Empty() bool {
+	return len(s.Down) == 0 && len(s.Recovered) == 0 && len(s.Regressions) == 0
+}
+
+// DefaultTemplate is the plain-text message used when no custom template is set.
+const DefaultTemplate = `[{{.Severity}}] proxybench: {{.Alive}}/{{.Total}} proxies alive
+{{- if .Down}}
+Down ({{len .Down}}):
+{{- range .Down}}
+  • {{.Address}}{{if .Error}} — {{.Error}}{{end}}
+{{- end}}
+{{- end}}
+{{- if .Regressions}}
+Latency regressions ({{len .Regressions}}):
+{{- range .Regressions}}
+  • {{.Address}}: {{ms .Baseline}}ms → {{ms .Latency}}ms
+{{- end}}
+{{- end}}
+{{- if .Recovered}}
+Recovered ({{len .Recovered}}):
+{{- range .Recovered}}
+  • {{.}}
+{{- end}}
+{{- end}}
+`
+
+var funcs = template.FuncMap{
+	"ms": func(d time.Duration) int64 { return d.Milliseconds() },
+}
+
+// ParseTemplate compiles a message template. An empty text selects DefaultTemplate.
+func ParseTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		text = DefaultTemplate
+	}
+	return template.New("message").Funcs(funcs).Parse(text)
+}
+
+// Notifier sends a rendered message to a single destination.
+type Notifier interface {
+	Name() string
+	Send(text string) error
+}
+
+// Channel pairs a Notifier with its minimum severity and message template.
+type Channel struct {
+	Notifier    Notifier
+	MinSeverity Severity
+	Template    *template.Template // nil = DefaultTemplate
+}
+
+// Dispatcher fans a Summary out to every channel whose threshold it meets.
+type Dispatcher struct {
+	Channels []Channel
+}
+
+// Dispatch renders and sends the summary to each eligible channel.
+// Empty summaries are dropped. All channels are attempted; the first error is returned.
+func (d *Dispatcher) Dispatch(s Summary) error {
+	if s.Empty() {
+		return nil
+	}
+	sev := s.Severity()
+	var firstErr error
+	for _, ch := range d.Channels {
+		if sev < ch.MinSeverity {
+			continue
+		}
+		text, err := render(ch.Template, s)
+		if err == nil {
+			err = ch.Notifier.Send(text)
+		}
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("%s: %w", ch.Notifier.Name(), err)
+		}
+	}
+	return firstErr
+}
+
+func render(tmpl *template.Template, s Summary) (string, error) {
+	if tmpl == nil {
+		var err error
+		if tmpl, err = ParseTemplate(""); err != nil {
+			return "", err
+		}
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, s); err != nil {
+		return "", fmt.Errorf("render template: %w", err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
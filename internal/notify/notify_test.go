@@ -0,0 +1,151 @@
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type recorder struct {
+	sent []string
+	err  error
+}
+
+func (r *recorder) Name() string { return "recorder" }
+
+func (r *recorder) Send(text string) error {
+	r.sent = append(r.sent, text)
+	return r.err
+}
+
+func TestParseSeverity(t *testing.T) {
+	cases := []struct {
+		in   string
+		want Severity
+	}{
+		{"info", SeverityInfo},
+		{"WARNING", SeverityWarning},
+		{"crit", SeverityCritical},
+	}
+	for _, c := range cases {
+		got, err := ParseSeverity(c.in)
+		if err != nil || got != c.want {
+			t.Errorf("ParseSeverity(%q) = %v, %v; want %v", c.in, got, err, c.want)
+		}
+	}
+	if _, err := ParseSeverity("loud"); err == nil {
+		t.Error("expected error for unknown severity")
+	}
+}
+
+func TestSummarySeverity(t *testing.T) {
+	s := Summary{Recovered: []string{"a"}}
+	if s.Severity() != SeverityInfo {
+		t.Errorf("recovery-only severity = %v, want info", s.Severity())
+	}
+	s.Regressions = []Regression{{Address: "b"}}
+	if s.Severity() != SeverityWarning {
+		t.Errorf("regression severity = %v, want warning", s.Severity())
+	}
+	s.Down = []Down{{Address: "c"}}
+	if s.Severity() != SeverityCritical {
+		t.Errorf("down severity = %v, want critical", s.Severity())
+	}
+}
+
+func TestDispatch_severityFilter(t *testing.T) {
+	critOnly := &recorder{}
+	all := &recorder{}
+	d := &Dispatcher{Channels: []Channel{
+		{Notifier: critOnly, MinSeverity: SeverityCritical},
+		{Notifier: all, MinSeverity: SeverityInfo},
+	}}
+
+	warn := Summary{Total: 2, Alive: 2, Regressions: []Regression{
+		{Address: "http://1.2.3.4:8080", Baseline: 100 * time.Millisecond, Latency: 450 * time.Millisecond},
+	}}
+	if err := d.Dispatch(warn); err != nil {
+		t.Fatalf("Dispatch: %v", err)
+	}
+	if len(critOnly.sent) != 0 {
+		t.Errorf("critical channel received a warning: %q", critOnly.sent)
+	}
+	if len(all.sent) != 1 {
+		t.Fatalf("info channel got %d messages, want 1", len(all.sent))
+	}
+	if !strings.Contains(all.sent[0], "100ms → 450ms") {
+		t.Errorf("message missing regression detail: %q", all.sent[0])
+	}
+
+	if err := d.Dispatch(Summary{Total: 2, Alive: 2}); err != nil {
+		t.Fatalf("Dispatch empty: %v", err)
+	}
+	if len(all.sent) != 1 {
+		t.Error("empty summary should not be sent")
+	}
+}
+
+func TestDispatch_errorContinues(t *testing.T) {
+	bad := &recorder{err: errors.New("boom")}
+	good := &recorder{}
+	d := &Dispatcher{Channels: []Channel{{Notifier: bad}, {Notifier: good}}}
+	err := d.Dispatch(Summary{Down: []Down{{Address: "x"}}})
+	if err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("err = %v, want boom", err)
+	}
+	if len(good.sent) != 1 {
+		t.Error("second channel should still receive the alert")
+	}
+}
+
+func TestSlackSend(t *testing.T) {
+	var got map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got) //nolint:errcheck
+	}))
+	defer srv.Close()
+
+	s := &Slack{WebhookURL: srv.URL}
+	if err := s.Send("hello"); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if got["text"] != "hello" {
+		t.Errorf("payload text = %q, want hello", got["text"])
+	}
+}
+
+func TestTelegramSend(t *testing.T) {
+	var path string
+	var got map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		json.NewDecoder(r.Body).Decode(&got) //nolint:errcheck
+	}))
+	defer srv.Close()
+
+	tg := &Telegram{Token: "123:ABC", ChatID: "-100", APIBase: srv.URL}
+	if err := tg.Send("hi"); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if path != "/bot123:ABC/sendMessage" {
+		t.Errorf("path = %q", path)
+	}
+	if got["chat_id"] != "-100" || got["text"] != "hi" {
+		t.Errorf("payload = %v", got)
+	}
+}
+
+func TestTelegramSend_hidesToken(t *testing.T) {
+	tg := &Telegram{Token: "secret-token", ChatID: "1", APIBase: "http://127.0.0.1:1"}
+	err := tg.Send("hi")
+	if err == nil {
+		t.Fatal("expected error for unreachable API")
+	}
+	if strings.Contains(err.Error(), "secret-token") {
+		t.Errorf("error leaks token: %v", err)
+	}
+}
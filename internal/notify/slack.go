@@ -0,0 +1,45 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Slack posts messages to a Slack incoming webhook.
+type Slack struct {
+	WebhookURL string
+	Client     *http.Client // nil = 10s timeout client
+}
+
+// Name implements Notifier.
+func (s *Slack) Name() string { return "slack" }
+
+// Send implements Notifier.
+func (s *Slack) Send(text string) error {
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return err
+	}
+	return postJSON(s.Client, s.WebhookURL, body)
+}
+
+// postJSON sends a JSON payload and treats any non-2xx response as an error.
+func postJSON(client *http.Client, url string, body []byte) error {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("server returned %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// TelegramAPI is the default Bot API base URL.
+const TelegramAPI = "https://api.telegram.org"
+
+// Telegram sends messages through a Telegram bot to a single chat.
+type Telegram struct {
+	Token   string
+	ChatID  string
+	APIBase string       // "" = TelegramAPI
+	Client  *http.Client // nil = 10s timeout client
+}
+
+// Name implements Notifier.
+func (t *Telegram) Name() string { return "telegram" }
+
+// Send implements Notifier.
+func (t *Telegram) Send(text string) error {
+	base := t.APIBase
+	if base == "" {
+		base = TelegramAPI
+	}
+	body, err := json.Marshal(map[string]any{
+		"chat_id":                  t.ChatID,
+		"text":                     text,
+		"disable_web_page_preview": true,
+	})
+	if err != nil {
+		return err
+	}
+	url := strings.TrimRight(base, "/") + "/bot" + t.Token + "/sendMessage"
+	if err := postJSON(t.Client, url, body); err != nil {
+		// Transport errors embed the request URL; keep the bot token out of logs.
+		return errors.New(strings.ReplaceAll(err.Error(), t.Token, "***"))
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package output
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/drsoft-oss/proxybench/internal/bench"
+)
+
+// csvColumn is one CSV column: its header and how a row fills it. The
+// header and every record are built from the same table, so a column
+// cannot be added to one and not the other.
+type csvColumn[R any] struct {
+	name string
+	get  func(R) string
+}
+
+func csvHeader[R any](cols []csvColumn[R]) []string {
+	names := make([]string, len(cols))
+	for i, c := range cols {
+		names[i] = c.name
+	}
+	return names
+}
+
+func csvRow[R any](cols []csvColumn[R], r R) []string {
+	fields := make([]string, len(cols))
+	for i, c := range cols {
+		fields[i] = c.get(r)
+	}
+	return fields
+}
+
+// placeColumns are the columns of the Place that place returns from a row.
+func placeColumns[R any](place func(R) Place) []csvColumn[R] {
+	return []csvColumn[R]{
+		{"asn", func(r R) string {
+			if asn := place(r).ASN; asn != 0 {
+				return strconv.FormatUint(uint64(asn), 10)
+			}
+			return ""
+		}},
+		{"as_name", func(r R) string { return place(r).ASName }},
+		{"region", func(r R) string { return place(r).Region }},
+		{"city", func(r R) string { return place(r).City }},
+		{"resolved_ip", func(r R) string { return place(r).ResolvedIP }},
+		{"country_votes", func(r R) string { return strings.Join(place(r).CountryVotes, "; ") }},
+		{"geo_disagreement", func(r R) string { return optTrue(place(r).GeoDisagreement) }},
+	}
+}
+
+// checkColumns are the CSV columns of check results.
+var checkColumns = append([]csvColumn[checkRow]{
+	{"address", func(r checkRow) string { return r.Address }},
+	{"protocol", func(r checkRow) string { return r.Protocol }},
+	{"alive", func(r checkRow) string { return strconv.FormatBool(r.Alive) }},
+	{"latency_ms", func(r checkRow) string { return strconv.FormatInt(r.LatencyMS, 10) }},
+	{"country", func(r checkRow) string { return r.Country }},
+	{"error", func(r checkRow) string { return errMessage(r.Error) }},
+	{"family", func(r checkRow) string { return r.Family }},
+	{"bind_supported", func(r checkRow) string { return optBool(r.Bind) }},
+	{"class", func(r checkRow) string { return r.Class }},
+	{"detected_protocol", func(r checkRow) string { return r.Detected }},
+	{"proxy_protocol", func(r checkRow) string { return r.ProxyHdr }},
+	{"connect_supported", func(r checkRow) string { return optBool(r.Connect) }},
+	{"hop_ms", func(r checkRow) string { return strconv.FormatInt(r.HopMS, 10) }},
+	{"target_ms", func(r checkRow) string { return strconv.FormatInt(r.TargetMS, 10) }},
+	{"banner", func(r checkRow) string { return r.Banner }},
+	{"software", func(r checkRow) string { return r.Software }},
+	{"status_code", func(r checkRow) string { return optInt(r.Status) }},
+	{"warning", func(r checkRow) string { return r.Warning }},
+	{"error_kind", func(r checkRow) string { return errKind(r.Error) }},
+	{"count", func(r checkRow) string { return optInt(r.Count) }},
+	{"trace_hops", func(r checkRow) string { return routeHops(r.Route) }},
+	{"last_mile_ms", func(r checkRow) string { return routeLastMile(r.Route) }},
+	{"pmtu_blackhole", func(r checkRow) string { return optBool(r.PMTU) }},
+	{"tls_version", func(r checkRow) string {
+		if r.TLS == nil {
+			return ""
+		}
+		return r.TLS.Version
+	}},
+	{"tls_cipher", func(r checkRow) string {
+		if r.TLS == nil {
+			return ""
+		}
+		return r.TLS.Cipher
+	}},
+	{"tls_verified", func(r checkRow) string {
+		if r.TLS == nil {
+			return ""
+		}
+		return strconv.FormatBool(r.TLS.Verified)
+	}},
+	{"tls_issuer", func(r checkRow) string {
+		if r.TLS == nil {
+			return ""
+		}
+		return r.TLS.Issuer
+	}},
+	{"exit_ip", func(r checkRow) string { return r.ExitIP }},
+	{"exit_country", func(r checkRow) string { return r.ExitCC }},
+	{"geo_mismatch", func(r checkRow) string { return optTrue(r.Mismatch) }},
+	{"dns_canary", func(r checkRow) string { return r.DNS }},
+	{"quic", func(r checkRow) string { return optBool(r.QUIC) }},
+	{"anonymity", func(r checkRow) string { return r.Anon }},
+	{"credential_user", func(r checkRow) string { return r.CredUser }},
+	{"credential_index", func(r checkRow) string { return optInt(r.CredIdx) }},
+	{"content_modified", func(r checkRow) string { return optBool(r.Content) }},
+	{"content_diff", func(r checkRow) string { return strings.Join(r.Diff, "; ") }},
+	{"tls_policy", func(r checkRow) string { return r.Policy }},
+	{"rotating", func(r checkRow) string { return optBool(r.Rotating) }},
+	{"exit_ips", func(r checkRow) string { return strings.Join(r.ExitIPs, "; ") }},
+	{"dns_resolution", func(r checkRow) string { return r.Resolve }},
+	{"http_only", func(r checkRow) string { return optTrue(r.HTTPOnly) }},
+	{"auth_methods", func(r checkRow) string { return strings.Join(r.Auth, "; ") }},
+	{"auth_method", func(r checkRow) string { return r.AuthUsed }},
+}, placeColumns(func(r checkRow) Place { return r.Place })...)
+
+// benchColumns are the CSV columns of bench results, with fractions
+// written as d has them.
+func (d CSVDialect) benchColumns() []csvColumn[benchRow] {
+	// path reads a --compare-connect column, empty when it was not measured.
+	path := func(get func(*bench.PathStats) string) func(benchRow) string {
+		return func(r benchRow) string {
+			if r.Paths == nil {
+				return ""
+			}
+			return get(r.Paths)
+		}
+	}
+	return append([]csvColumn[benchRow]{
+		{"address", func(r benchRow) string { return r.Address }},
+		{"samples", func(r benchRow) string { return strconv.Itoa(r.Samples) }},
+		{"successful", func(r benchRow) string { return strconv.Itoa(r.Successful) }},
+		{"min_ms", func(r benchRow) string { return strconv.FormatInt(r.MinMS, 10) }},
+		{"max_ms", func(r benchRow) string { return strconv.FormatInt(r.MaxMS, 10) }},
+		{"avg_ms", func(r benchRow) string { return strconv.FormatInt(r.AvgMS, 10) }},
+		{"p50_ms", func(r benchRow) string { return strconv.FormatInt(r.P50MS, 10) }},
+		{"p95_ms", func(r benchRow) string { return strconv.FormatInt(r.P95MS, 10) }},
+		{"loss_rate", func(r benchRow) string { return d.float(r.LossRate, 4) }},
+		{"speed_bps", func(r benchRow) string { return strconv.FormatInt(r.SpeedBps, 10) }},
+		{"country", func(r benchRow) string { return r.Country }},
+		{"cold_ms", func(r benchRow) string { return strconv.FormatInt(r.ColdMS, 10) }},
+		{"warm_ms", func(r benchRow) string { return strconv.FormatInt(r.WarmMS, 10) }},
+		{"latency_class", func(r benchRow) string { return r.LatencyClass }},
+		{"speed_class", func(r benchRow) string { return r.SpeedClass }},
+		{"peak_bps", func(r benchRow) string { return strconv.FormatInt(r.PeakBps, 10) }},
+		{"ramp_up_ms", func(r benchRow) string { return strconv.FormatInt(r.RampUpMS, 10) }},
+		{"speed_series", func(r benchRow) string { return joinInts(r.SpeedSeries, ";") }},
+		{"capacity_bps", func(r benchRow) string { return strconv.FormatInt(r.CapacityBps, 10) }},
+		{"saturation_conns", func(r benchRow) string { return strconv.Itoa(r.SaturationConns) }},
+		{"usable", func(r benchRow) string { return strconv.FormatBool(r.Usable) }},
+		{"grade", func(r benchRow) string { return r.Grade }},
+		{"error", func(r benchRow) string { return r.Error }},
+		{"reconnects", func(r benchRow) string { return strconv.Itoa(r.Reconnects) }},
+		{"percentile_method", func(r benchRow) string { return string(r.PercentileMethod) }},
+		{"status_2xx", func(r benchRow) string { return strconv.Itoa(r.StatusClasses["2xx"]) }},
+		{"status_3xx", func(r benchRow) string { return strconv.Itoa(r.StatusClasses["3xx"]) }},
+		{"status_4xx", func(r benchRow) string { return strconv.Itoa(r.StatusClasses["4xx"]) }},
+		{"status_5xx", func(r benchRow) string { return strconv.Itoa(r.StatusClasses["5xx"]) }},
+		{"status_codes", func(r benchRow) string { return joinCounts(r.StatusCodes) }},
+		{"count", func(r benchRow) string { return optInt(r.Count) }},
+		{"udp_supported", func(r benchRow) string { return optBool(r.UDPSupported) }},
+		{"udp_p50_ms", func(r benchRow) string { return strconv.FormatInt(r.UDPP50MS, 10) }},
+		{"udp_p95_ms", func(r benchRow) string { return strconv.FormatInt(r.UDPP95MS, 10) }},
+		{"udp_loss_rate", func(r benchRow) string { return d.float(r.UDPLossRate, 4) }},
+		{"handshake_rate", func(r benchRow) string { return d.float(r.HandshakeRate, 1) }},
+		{"handshake_conns", func(r benchRow) string { return strconv.Itoa(r.HandshakeConns) }},
+		{"error_onset_conns", func(r benchRow) string { return strconv.Itoa(r.ErrorOnsetConns) }},
+		{"test_url", func(r benchRow) string { return r.TestURL }},
+		{"tls_policy", func(r benchRow) string { return r.TLSPolicy }},
+		{"get_p50_ms", path(func(p *bench.PathStats) string { return strconv.FormatInt(p.GetP50MS, 10) })},
+		{"get_p95_ms", path(func(p *bench.PathStats) string { return strconv.FormatInt(p.GetP95MS, 10) })},
+		{"get_loss_rate", path(func(p *bench.PathStats) string { return d.float(p.GetLossRate, 4) })},
+		{"connect_p50_ms", path(func(p *bench.PathStats) string { return strconv.FormatInt(p.ConnectP50MS, 10) })},
+		{"connect_p95_ms", path(func(p *bench.PathStats) string { return strconv.FormatInt(p.ConnectP95MS, 10) })},
+		{"connect_loss_rate", path(func(p *bench.PathStats) string { return d.float(p.ConnectLossRate, 4) })},
+		{"conn_limit", func(r benchRow) string { return strconv.Itoa(r.ConnLimit) }},
+		{"conn_limit_hit", func(r benchRow) string { return strconv.FormatBool(r.ConnLimitHit) }},
+		{"initial_bps", func(r benchRow) string { return strconv.FormatInt(r.InitialBps, 10) }},
+		{"sustained_bps", func(r benchRow) string { return strconv.FormatInt(r.SustainedBps, 10) }},
+		{"throttled", func(r benchRow) string { return strconv.FormatBool(r.Throttled) }},
+	}, placeColumns(func(r benchRow) Place { return r.Place })...)
+}
@@ -0,0 +1,71 @@
+package output
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// CSVDialect adapts CSV output to spreadsheets that expect something
+// other than RFC 4180 with dotted decimals, such as Excel in locales
+// where ',' is the decimal separator and ';' the list separator. The zero
+// value is plain comma-separated output with LF line endings.
+type CSVDialect struct {
+	Comma        rune // field delimiter; 0 means ','
+	CRLF         bool // end lines with \r\n
+	NoHeader     bool // omit the header row
+	DecimalComma bool // write fractions as 0,25 instead of 0.25
+}
+
+// ParseDelimiter accepts "comma", "semicolon" and "tab", or the
+// character itself.
+func ParseDelimiter(s string) (rune, error) {
+	switch strings.ToLower(s) {
+	case "", "comma", ",":
+		return ',', nil
+	case "semicolon", ";":
+		return ';', nil
+	case "tab", "\t", `\t`:
+		return '\t', nil
+	}
+	return 0, fmt.Errorf("unknown CSV delimiter %q (want comma, semicolon or tab)", s)
+}
+
+// ParseDecimal accepts "." or "point" and "," or "comma" and reports
+// whether the decimal separator is a comma.
+func ParseDecimal(s string) (comma bool, err error) {
+	switch strings.ToLower(s) {
+	case "", ".", "point", "dot":
+		return false, nil
+	case ",", "comma":
+		return true, nil
+	}
+	return false, fmt.Errorf("unknown decimal separator %q (want point or comma)", s)
+}
+
+func (d CSVDialect) writer(w io.Writer) *csv.Writer {
+	cw := csv.NewWriter(w)
+	if d.Comma != 0 {
+		cw.Comma = d.Comma
+	}
+	cw.UseCRLF = d.CRLF
+	return cw
+}
+
+// header writes the header row unless the dialect omits it.
+func (d CSVDialect) header(cw *csv.Writer, fields []string) {
+	if !d.NoHeader {
+		cw.Write(fields) //nolint:errcheck
+	}
+}
+
+// float formats f with prec decimals and the dialect's separator.
+func (d CSVDialect) float(f float64, prec int) string {
+	s := strconv.FormatFloat(f, 'f', prec, 64)
+	if d.DecimalComma {
+		s = strings.Replace(s, ".", ",", 1)
+	}
+	return s
+}
@@ -0,0 +1,59 @@
+package output
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+)
+
+func TestBenchWriter_CSVDialect(t *testing.T) {
+	var buf bytes.Buffer
+	bw := NewBenchWriter(&buf, FormatCSV, false)
+	bw.CSV = CSVDialect{Comma: ';', CRLF: true, NoHeader: true, DecimalComma: true}
+	if err := bw.Write(makeBenchResults()[0], ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := bw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.HasSuffix(out, "\r\n") || strings.Count(out, "\n") != 1 {
+		t.Errorf("want one CRLF-terminated row and no header, got %q", out)
+	}
+	r := csv.NewReader(strings.NewReader(out))
+	r.Comma = ';'
+	rec, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec[0] != "http://1.2.3.4:8080" || rec[8] != "0,2000" {
+		t.Errorf("address %q, loss_rate %q; want 0,2000", rec[0], rec[8])
+	}
+}
+
+func TestCheckWriter_CSVDialectTab(t *testing.T) {
+	var buf bytes.Buffer
+	cw := NewCheckWriter(&buf, FormatCSV)
+	cw.CSV = CSVDialect{Comma: '\t'}
+	if err := cw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "address\tprotocol\talive\t") {
+		t.Errorf("header = %q", buf.String())
+	}
+}
+
+func TestParseDelimiter(t *testing.T) {
+	for in, want := range map[string]rune{"": ',', "comma": ',', "semicolon": ';', ";": ';', "tab": '\t', `\t`: '\t'} {
+		if got, err := ParseDelimiter(in); err != nil || got != want {
+			t.Errorf("ParseDelimiter(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseDelimiter("pipe"); err == nil {
+		t.Error("ParseDelimiter(pipe) succeeded")
+	}
+	if comma, err := ParseDecimal("comma"); err != nil || !comma {
+		t.Errorf("ParseDecimal(comma) = %v, %v", comma, err)
+	}
+}
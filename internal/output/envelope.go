@@ -0,0 +1,83 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+)
+
+// SchemaVersion identifies the layout of JSON output: the envelope and
+// the rows in it. It is bumped when a field is removed or changes
+// meaning; added fields keep the version.
+const SchemaVersion = 2
+
+// Meta describes the run JSON output came from. When set on a writer,
+// JSON output is an object carrying these fields with the rows under
+// "results" (or "countries" for a summary) instead of a bare array.
+type Meta struct {
+	SchemaVersion int        `json:"schema_version"`
+	Version       string     `json:"proxybench_version"`
+	Command       string     `json:"command"`
+	Timestamp     time.Time  `json:"timestamp"`
+	Options       RunOptions `json:"options"`
+	GeoDB         *GeoDBInfo `json:"geo_db,omitempty"`
+	// Args are the flags the run was made with, whether from the command
+	// line or the config file, as arguments that replay it (see
+	// 'proxybench rerun'). Flags left at their defaults are not listed.
+	Args []string `json:"args,omitempty"`
+	// InputSHA256 is the hex SHA-256 of the addresses the run read, one
+	// per line. It is only known once the input is done, so in streamed
+	// JSON it follows the results.
+	InputSHA256 string `json:"input_sha256,omitempty"`
+}
+
+// RunOptions are the settings that shape the numbers in a run's results.
+type RunOptions struct {
+	TestURL     string `json:"test_url,omitempty"`
+	Method      string `json:"method,omitempty"`
+	TimeoutMS   int64  `json:"timeout_ms,omitempty"`
+	Concurrency int    `json:"concurrency,omitempty"`
+	Samples     int    `json:"samples,omitempty"`     // bench
+	PayloadURL  string `json:"payload_url,omitempty"` // bench
+	Percentile  string `json:"percentile,omitempty"`  // bench
+	TLSPolicy   string `json:"tls_policy,omitempty"`
+	Shard       string `json:"shard,omitempty"` // i/N of a split run
+}
+
+// GeoDBInfo identifies the geo database results were located with.
+type GeoDBInfo struct {
+	Path     string    `json:"path"`
+	Modified time.Time `json:"modified"`
+}
+
+// NewMeta returns the metadata of a command run started now.
+func NewMeta(version, command string, opts RunOptions) *Meta {
+	return &Meta{
+		SchemaVersion: SchemaVersion,
+		Version:       version,
+		Command:       command,
+		Timestamp:     time.Now().UTC().Truncate(time.Second),
+		Options:       opts,
+	}
+}
+
+// open returns the start of an envelope holding m, up to and including
+// the opening bracket of its field list, e.g. `{ ...meta..., "results": [`.
+// Fields set later are written by tail.
+func (m *Meta) open(field string) ([]byte, error) {
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	b = bytes.TrimRight(bytes.TrimSuffix(b, []byte("}")), "\n")
+	return append(b, ",\n  \""+field+"\": ["...), nil
+}
+
+// tail returns the fields of m to write after the field list, for those
+// that were still unset when the envelope was opened with head.
+func (m *Meta) tail(head []byte) string {
+	if m.InputSHA256 == "" || bytes.Contains(head, []byte(`"input_sha256"`)) {
+		return ""
+	}
+	return ",\n  \"input_sha256\": \"" + m.InputSHA256 + "\""
+}
@@ -0,0 +1,91 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestCheckWriter_envelope(t *testing.T) {
+	meta := NewMeta("1.2.3", "check", RunOptions{TestURL: "http://example.com", TimeoutMS: 10000})
+	meta.GeoDB = &GeoDBInfo{Path: "/var/lib/ip2country.csv", Modified: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)}
+	var buf bytes.Buffer
+	cw := NewCheckWriter(&buf, FormatJSON)
+	cw.Meta = meta
+	meta.Args = []string{"--timeout=10", "--judge-url=http://a.example/,http://b.example/"}
+	for _, r := range makeCheckResults() {
+		if err := cw.Write(r, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	meta.InputSHA256 = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	if err := cw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var doc struct {
+		Meta
+		Results []checkRow `json:"results"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("envelope is not valid JSON: %v\n%s", err, buf.String())
+	}
+	if doc.SchemaVersion != SchemaVersion || doc.Version != "1.2.3" || doc.Command != "check" {
+		t.Errorf("meta = %+v", doc.Meta)
+	}
+	if doc.Options.TestURL != "http://example.com" || doc.GeoDB == nil || doc.GeoDB.Path != "/var/lib/ip2country.csv" {
+		t.Errorf("options %+v, geo_db %+v", doc.Options, doc.GeoDB)
+	}
+	if len(doc.Results) != 2 || doc.Results[0].Address != "http://1.2.3.4:8080" {
+		t.Errorf("results = %+v", doc.Results)
+	}
+	if len(doc.Args) != 2 || doc.InputSHA256 != meta.InputSHA256 {
+		t.Errorf("args %q, input_sha256 %q", doc.Args, doc.InputSHA256)
+	}
+
+	// The same document matches json.Encoder's indentation.
+	var want bytes.Buffer
+	enc := json.NewEncoder(&want)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(orderedLike(t, buf.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != want.String() {
+		t.Errorf("envelope formatting differs from json.Encoder:\ngot:\n%s\nwant:\n%s", buf.String(), want.String())
+	}
+
+	got, err := ReadLatencies(&buf)
+	if err != nil || got["http://1.2.3.4:8080"] != 200*time.Millisecond {
+		t.Errorf("ReadLatencies(envelope) = %v, %v", got, err)
+	}
+}
+
+// orderedLike re-indents b, keeping its key order.
+func orderedLike(t *testing.T, b []byte) json.RawMessage {
+	t.Helper()
+	var out bytes.Buffer
+	if err := json.Compact(&out, b); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
+
+func TestBenchWriter_emptyEnvelope(t *testing.T) {
+	var buf bytes.Buffer
+	bw := NewBenchWriter(&buf, FormatJSON, false)
+	bw.Meta = NewMeta("dev", "bench", RunOptions{Samples: 5})
+	bw.Meta.InputSHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	if err := bw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Results []json.RawMessage `json:"results"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil || doc.Results == nil || len(doc.Results) != 0 {
+		t.Errorf("empty envelope = %q (%v)", buf.String(), err)
+	}
+	if n := bytes.Count(buf.Bytes(), []byte("input_sha256")); n != 1 {
+		t.Errorf("input_sha256 written %d times:\n%s", n, buf.String())
+	}
+}
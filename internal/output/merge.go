@@ -0,0 +1,149 @@
+package output
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/drsoft-oss/proxybench/internal/bench"
+	"github.com/drsoft-oss/proxybench/internal/checker"
+)
+
+// MergeRule decides which of several results for one proxy Merge keeps.
+type MergeRule string
+
+const (
+	MergeNewest MergeRule = "newest" // from the most recent run
+	MergeBest   MergeRule = "best"   // alive/reachable first, then fastest
+)
+
+// ParseMergeRule parses a --prefer value.
+func ParseMergeRule(s string) (MergeRule, error) {
+	switch r := MergeRule(s); r {
+	case MergeNewest, MergeBest:
+		return r, nil
+	}
+	return "", fmt.Errorf("want newest or best, got %q", s)
+}
+
+// Run is the output of one earlier run and when it was made: the
+// envelope's timestamp for JSON, otherwise the caller's best guess such as
+// the file's modification time.
+type Run struct {
+	Results
+	At time.Time
+}
+
+// Merge combines runs into one row per proxy, compared after
+// checker.Normalize, keeping the row rule prefers; ties go to the later
+// run. Rows keep the order their proxy was first seen in. The Meta of the
+// newest run with one is kept, without a shard if the runs came from
+// different ones. Mixing check and bench runs is an error.
+func Merge(runs []Run, rule MergeRule) (Results, error) {
+	var merged Results
+	var metaAt time.Time
+	shards := map[string]bool{}
+	checks := map[string]int{}
+	checkAt := map[string]time.Time{}
+	benches := map[string]int{}
+	benchAt := map[string]time.Time{}
+	for _, run := range runs {
+		if run.Meta != nil {
+			shards[run.Meta.Options.Shard] = true
+			if merged.Meta == nil || !run.At.Before(metaAt) {
+				m := *run.Meta
+				merged.Meta, metaAt = &m, run.At
+			}
+		}
+		for _, rec := range run.Checks {
+			key := checker.Normalize(rec.Result.Address)
+			i, seen := checks[key]
+			switch {
+			case !seen:
+				checks[key] = len(merged.Checks)
+				merged.Checks = append(merged.Checks, rec)
+			case rule == MergeBest && !betterCheck(rec.Result, merged.Checks[i].Result, run.At, checkAt[key]):
+				continue
+			case rule == MergeNewest && run.At.Before(checkAt[key]):
+				continue
+			default:
+				merged.Checks[i] = rec
+			}
+			checkAt[key] = run.At
+		}
+		for _, rec := range run.Benches {
+			key := checker.Normalize(rec.Stats.Address)
+			i, seen := benches[key]
+			switch {
+			case !seen:
+				benches[key] = len(merged.Benches)
+				merged.Benches = append(merged.Benches, rec)
+			case rule == MergeBest && !betterBench(rec.Stats, merged.Benches[i].Stats, run.At, benchAt[key]):
+				continue
+			case rule == MergeNewest && run.At.Before(benchAt[key]):
+				continue
+			default:
+				merged.Benches[i] = rec
+			}
+			benchAt[key] = run.At
+		}
+		if len(merged.Checks) > 0 && len(merged.Benches) > 0 {
+			return Results{}, errMixedResults
+		}
+	}
+	if merged.Meta != nil && len(shards) > 1 {
+		merged.Meta.Options.Shard = ""
+	}
+	return merged, nil
+}
+
+// betterCheck reports whether check result a, from a run at aAt, beats b:
+// alive before dead, then lower latency, then the newer.
+func betterCheck(a, b checker.Result, aAt, bAt time.Time) bool {
+	switch {
+	case a.Alive != b.Alive:
+		return a.Alive
+	case a.Alive && a.Latency != b.Latency:
+		return a.Latency < b.Latency
+	}
+	return !aAt.Before(bAt)
+}
+
+// betterBench is betterCheck for bench stats: reachable before not, then
+// lower loss, then lower P50, then the newer.
+func betterBench(a, b bench.Stats, aAt, bAt time.Time) bool {
+	switch {
+	case a.OK() != b.OK():
+		return a.OK()
+	case a.LossRate != b.LossRate:
+		return a.LossRate < b.LossRate
+	case a.P50MS != b.P50MS:
+		return a.P50MS < b.P50MS
+	}
+	return !aAt.Before(bAt)
+}
+
+// Best returns the address of the row of rs that MergeBest would keep over
+// all others, or false if no row is alive (bench: reachable).
+func Best(rs *Results) (string, bool) {
+	// Every row is from the same run; a later time for the row kept so far
+	// makes ties keep the earlier row.
+	var at time.Time
+	best := -1
+	for i, rec := range rs.Checks {
+		if rec.Result.Alive && (best < 0 || betterCheck(rec.Result, rs.Checks[best].Result, at, at.Add(1))) {
+			best = i
+		}
+	}
+	if best >= 0 {
+		return rs.Checks[best].Result.Address, true
+	}
+	for i, rec := range rs.Benches {
+		if rec.Stats.OK() && (best < 0 || betterBench(rec.Stats, rs.Benches[best].Stats, at, at.Add(1))) {
+			best = i
+		}
+	}
+	if best >= 0 {
+		return rs.Benches[best].Stats.Address, true
+	}
+	return "", false
+}
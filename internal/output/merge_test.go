@@ -0,0 +1,93 @@
+package output
+
+import (
+	"testing"
+	"time"
+
+	"github.com/drsoft-oss/proxybench/internal/bench"
+	"github.com/drsoft-oss/proxybench/internal/checker"
+)
+
+func TestMerge(t *testing.T) {
+	day1 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+	check := func(address string, alive bool, ms int) CheckRecord {
+		return CheckRecord{Result: checker.Result{Address: address, Alive: alive, Latency: time.Duration(ms) * time.Millisecond}}
+	}
+	// Passed newest first, as the runs' order must not matter.
+	runs := []Run{
+		{At: day2, Results: Results{
+			Checks: []CheckRecord{check("http://b.example:8080", true, 300), check("HTTP://A.example:8080/", false, 0)},
+			Meta:   &Meta{Command: "check", Options: RunOptions{Shard: "2/2"}},
+		}},
+		{At: day1, Results: Results{
+			Checks: []CheckRecord{check("http://a.example:8080", true, 100), check("http://b.example:8080", true, 200)},
+			Meta:   &Meta{Command: "check", Options: RunOptions{Shard: "1/2"}},
+		}},
+	}
+
+	newest, err := Merge(runs, MergeNewest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(newest.Checks) != 2 || newest.Checks[0].Result.Latency != 300*time.Millisecond || newest.Checks[1].Result.Alive {
+		t.Errorf("newest = %+v", newest.Checks)
+	}
+	if newest.Meta == nil || newest.Meta.Options.Shard != "" {
+		t.Errorf("meta = %+v, want the shard dropped", newest.Meta)
+	}
+
+	best, err := Merge(runs, MergeBest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(best.Checks) != 2 || best.Checks[0].Result.Latency != 200*time.Millisecond || !best.Checks[1].Result.Alive {
+		t.Errorf("best = %+v", best.Checks)
+	}
+}
+
+func TestMerge_bench(t *testing.T) {
+	day1 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	stats := func(loss float64, p50 int64) BenchRecord {
+		return BenchRecord{Stats: bench.Stats{Address: "socks5://c.example:1080", Samples: 5, Successful: 4, LossRate: loss, P50MS: p50}}
+	}
+	runs := []Run{
+		{At: day1, Results: Results{Benches: []BenchRecord{stats(0, 400)}}},
+		{At: day1.Add(time.Hour), Results: Results{Benches: []BenchRecord{stats(0.2, 100)}}},
+	}
+	if m, _ := Merge(runs, MergeBest); len(m.Benches) != 1 || m.Benches[0].Stats.P50MS != 400 {
+		t.Errorf("best = %+v, want the run without loss", m.Benches)
+	}
+	if m, _ := Merge(runs, MergeNewest); len(m.Benches) != 1 || m.Benches[0].Stats.P50MS != 100 {
+		t.Errorf("newest = %+v", m.Benches)
+	}
+
+	runs = append(runs, Run{Results: Results{Checks: []CheckRecord{{Result: checker.Result{Address: "http://a.example:8080"}}}}})
+	if _, err := Merge(runs, MergeNewest); err != errMixedResults {
+		t.Errorf("err = %v, want %v", err, errMixedResults)
+	}
+}
+
+func TestBest(t *testing.T) {
+	checks := &Results{Checks: []CheckRecord{
+		{Result: checker.Result{Address: "http://dead.example:8080"}},
+		{Result: checker.Result{Address: "http://slow.example:8080", Alive: true, Latency: 300 * time.Millisecond}},
+		{Result: checker.Result{Address: "http://fast.example:8080", Alive: true, Latency: 100 * time.Millisecond}},
+		{Result: checker.Result{Address: "http://tie.example:8080", Alive: true, Latency: 100 * time.Millisecond}},
+	}}
+	if got, ok := Best(checks); !ok || got != "http://fast.example:8080" {
+		t.Errorf("Best(checks) = %q, %v, want the first fastest", got, ok)
+	}
+
+	benches := &Results{Benches: []BenchRecord{
+		{Stats: bench.Stats{Address: "socks5://lossy.example:1080", Samples: 5, Successful: 4, LossRate: 0.2, P50MS: 50}},
+		{Stats: bench.Stats{Address: "socks5://clean.example:1080", Samples: 5, Successful: 5, P50MS: 200}},
+	}}
+	if got, ok := Best(benches); !ok || got != "socks5://clean.example:1080" {
+		t.Errorf("Best(benches) = %q, %v", got, ok)
+	}
+
+	if _, ok := Best(&Results{Checks: checks.Checks[:1]}); ok {
+		t.Error("Best(all dead): want false")
+	}
+}
@@ -1,4 +1,4 @@
-// Package output formats proxy check and benchmark results as JSON or CSV.
+// Package output formats proxy check and benchmark results as JSON, NDJSON or CSV.
 package output
 
 import (
@@ -6,19 +6,24 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/drsoft-oss/proxybench/internal/bench"
 	"github.com/drsoft-oss/proxybench/internal/checker"
+	"github.com/drsoft-oss/proxybench/internal/geo"
+	"github.com/drsoft-oss/proxybench/internal/traceroute"
 )
 
 // Format selects the output format.
 type Format string
 
 const (
-	FormatJSON  Format = "json"
-	FormatCSV   Format = "csv"
-	FormatTable Format = "table"
+	FormatJSON   Format = "json"
+	FormatNDJSON Format = "ndjson" // one JSON object per line, for streaming
+	FormatCSV    Format = "csv"
+	FormatTable  Format = "table"
 )
 
 // ---- Check results ----------------------------------------------------------
@@ -30,7 +35,69 @@ type checkRow struct {
 	Alive    bool   `json:"alive"`
 	LatencyMS int64 `json:"latency_ms"`
 	Country  string `json:"country,omitempty"`
-	Error    string `json:"error,omitempty"`
+	Error    *checker.Failure `json:"error,omitempty"`
+	Family   string `json:"family,omitempty"`
+	Bind     *bool  `json:"bind_supported,omitempty"`
+	Class    string `json:"class,omitempty"`
+	Detected string `json:"detected_protocol,omitempty"`
+	ProxyHdr string `json:"proxy_protocol,omitempty"`
+	Connect  *bool  `json:"connect_supported,omitempty"`
+	HopMS    int64  `json:"hop_ms,omitempty"`
+	TargetMS int64  `json:"target_ms,omitempty"`
+	Banner   string `json:"banner,omitempty"`
+	Software string `json:"software,omitempty"`
+	Status   int    `json:"status_code,omitempty"`
+	Warning  string `json:"warning,omitempty"`
+	Count    int    `json:"count,omitempty"`
+	Route    *traceroute.Route `json:"route,omitempty"`
+	PMTU     *bool  `json:"pmtu_blackhole,omitempty"`
+	TLS      *checker.TLSInfo `json:"tls,omitempty"`
+	ProxyTLS *checker.TLSInfo `json:"proxy_tls,omitempty"`
+	ExitIP   string `json:"exit_ip,omitempty"`
+	ExitCC   string `json:"exit_country,omitempty"`
+	Mismatch bool   `json:"geo_mismatch,omitempty"`
+	DNS      string `json:"dns_canary,omitempty"`
+	QUIC     *bool  `json:"quic,omitempty"`
+	Anon     string `json:"anonymity,omitempty"`
+	CredUser string `json:"credential_user,omitempty"`
+	CredIdx  int    `json:"credential_index,omitempty"`
+	Content  *bool  `json:"content_modified,omitempty"`
+	Diff     []string `json:"content_diff,omitempty"`
+	Policy   string `json:"tls_policy,omitempty"`
+	ExitIPs  []string `json:"exit_ips,omitempty"`
+	Rotating *bool  `json:"rotating,omitempty"`
+	Resolve  string `json:"dns_resolution,omitempty"`
+	HTTPOnly bool   `json:"http_only,omitempty"`
+	Auth     []string `json:"auth_methods,omitempty"`
+	AuthUsed string `json:"auth_method,omitempty"`
+	Place
+}
+
+// Place is what attached geo layers add to a row beyond the country.
+type Place struct {
+	ASN    uint32 `json:"asn,omitempty"`
+	ASName string `json:"as_name,omitempty"`
+	Region string `json:"region,omitempty"`
+	City   string `json:"city,omitempty"`
+	// ResolvedIP is the address a hostname proxy was located by.
+	ResolvedIP string `json:"resolved_ip,omitempty"`
+	// CountryVotes and GeoDisagreement are set with --geo-consensus.
+	CountryVotes    []string `json:"country_votes,omitempty"`
+	GeoDisagreement bool     `json:"geo_disagreement,omitempty"`
+}
+
+// Locate splits a geo.Record into the "CC Name" country label rows have
+// always carried and the rest. A hostname whose addresses span several
+// countries gets the others appended, e.g. "US United States (+DE,NL)".
+func Locate(rec geo.Record) (country string, place Place) {
+	if rec.CountryCode != "" {
+		country = strings.TrimSpace(rec.CountryCode + " " + rec.CountryName)
+	}
+	if len(rec.OtherCountries) > 0 {
+		country += " (+" + strings.Join(rec.OtherCountries, ",") + ")"
+	}
+	return country, Place{ASN: rec.ASN, ASName: rec.ASName, Region: rec.Region, City: rec.City, ResolvedIP: rec.ResolvedIP,
+		CountryVotes: rec.CountryVotes, GeoDisagreement: rec.GeoDisagreement}
 }
 
 func toCheckRow(r checker.Result, country string) checkRow {
@@ -40,60 +107,239 @@ func toCheckRow(r checker.Result, country string) checkRow {
 		Alive:     r.Alive,
 		LatencyMS: r.LatencyMS(),
 		Country:   country,
-		Error:     r.Error,
+		Error:     failure(r),
+		Family:    r.Family,
+		Bind:      r.BindSupported,
+		Class:     r.Class,
+		Detected:  string(r.DetectedProtocol),
+		ProxyHdr:  r.ProxyProtocol,
+		Connect:   r.ConnectSupported,
+		HopMS:     r.HopLatency.Milliseconds(),
+		TargetMS:  r.TargetLatency.Milliseconds(),
+		Banner:    r.Banner,
+		Software:  r.Software,
+		Status:    r.StatusCode,
+		Warning:   r.Warning,
+		Count:     r.Count,
+		Route:     r.Route,
+		PMTU:      r.PMTUBlackhole,
+		TLS:       r.TLS,
+		ProxyTLS:  r.ProxyTLS,
+		ExitIP:    r.ExitIP,
+		ExitCC:    r.ExitCountry,
+		Mismatch:  r.GeoMismatch,
+		DNS:       r.DNSCanary,
+		QUIC:      r.QUIC,
+		Anon:      r.Anonymity,
+		CredUser:  r.CredentialUser,
+		CredIdx:   r.CredentialIndex,
+		Content:   r.ContentModified,
+		Diff:      r.ContentDiff,
+		Policy:    r.TLSPolicy,
+		ExitIPs:   r.ExitIPs,
+		Rotating:  r.Rotating,
+		Resolve:   r.DNSResolution,
+		HTTPOnly:  r.HTTPOnly,
+		Auth:      r.AuthMethods,
+		AuthUsed:  r.AuthMethod,
+	}
+}
+
+// routeHops and routeLastMile format a traced route for CSV and the
+// table: empty when the proxy was not traced, and "?" for a hop count
+// when the trace did not reach it.
+func routeHops(rt *traceroute.Route) string {
+	switch {
+	case rt == nil:
+		return ""
+	case rt.Hops == 0:
+		return "?"
+	}
+	return strconv.Itoa(rt.Hops)
+}
+
+func routeLastMile(rt *traceroute.Route) string {
+	if rt == nil || rt.Hops == 0 {
+		return ""
+	}
+	return strconv.FormatInt(rt.LastMileMS, 10)
+}
+
+// failure returns the structured error of r. Results from before errors
+// were classified (e.g. in history) carry only the message.
+func failure(r checker.Result) *checker.Failure {
+	switch {
+	case r.Failure != nil:
+		return r.Failure
+	case r.Error != "":
+		return &checker.Failure{Kind: checker.ErrUnknown, Message: r.Error}
+	}
+	return nil
+}
+
+func errMessage(f *checker.Failure) string {
+	if f == nil {
+		return ""
 	}
+	return f.Message
+}
+
+func errKind(f *checker.Failure) string {
+	if f == nil {
+		return ""
+	}
+	return string(f.Kind)
 }
 
 // WriteCheckResults writes check results in the requested format.
 func WriteCheckResults(w io.Writer, results []checker.Result, countries []string, format Format) error {
-	rows := make([]checkRow, len(results))
+	cw := NewCheckWriter(w, format)
 	for i, r := range results {
 		c := ""
 		if i < len(countries) {
 			c = countries[i]
 		}
-		rows[i] = toCheckRow(r, c)
+		if err := cw.Write(r, c); err != nil {
+			return err
+		}
 	}
+	return cw.Close()
+}
+
+// CheckWriter streams check results one row at a time, so output can be
+// produced while later proxies are still being checked. The header (or
+// opening bracket) is written with the first row; Close finishes the
+// document and must be called even when no rows were written.
+type CheckWriter struct {
+	w      io.Writer
+	format Format
+	csv    *csv.Writer
+	json   jsonArray
+	rows   int
+
+	// CSV selects the delimiter, line endings, header and decimal
+	// separator of CSV output.
+	CSV CSVDialect
+
+	// Meta, when set, wraps JSON output in an envelope describing the
+	// run; see Meta.
+	Meta *Meta
+
+	// Chain is the chaining mode written by FormatProxychains.
+	Chain Chain
 
-	switch format {
+	// Route adds HOPS and LAST(ms) columns to the table, for runs with
+	// checker.Options.Trace.
+	Route bool
+}
+
+// NewCheckWriter returns a CheckWriter emitting format to w.
+func NewCheckWriter(w io.Writer, format Format) *CheckWriter {
+	return &CheckWriter{w: w, format: format, json: jsonArray{w: w}}
+}
+
+// Rows reports how many results have been written.
+func (cw *CheckWriter) Rows() int { return cw.rows }
+
+// Write appends one result; country may be empty.
+func (cw *CheckWriter) Write(r checker.Result, country string) error {
+	return cw.WriteGeo(r, geo.Record{CountryCode: country})
+}
+
+// WriteGeo appends one result located by a layered geo lookup.
+func (cw *CheckWriter) WriteGeo(r checker.Result, rec geo.Record) error {
+	if cw.rows == 0 {
+		cw.header()
+	}
+	cw.rows++
+	country, place := Locate(rec)
+	row := toCheckRow(r, country)
+	row.Place = place
+
+	switch cw.format {
 	case FormatJSON:
-		enc := json.NewEncoder(w)
-		enc.SetIndent("", "  ")
-		return enc.Encode(rows)
+		return cw.json.add(row)
+	case FormatNDJSON:
+		return writeLine(cw.w, row)
+	case FormatProxychains:
+		_, err := io.WriteString(cw.w, proxychainsLine(r))
+		return err
 	case FormatCSV:
-		cw := csv.NewWriter(w)
-		cw.Write([]string{"address", "protocol", "alive", "latency_ms", "country", "error"}) //nolint:errcheck
-		for _, row := range rows {
-			cw.Write([]string{
-				row.Address,
-				row.Protocol,
-				strconv.FormatBool(row.Alive),
-				strconv.FormatInt(row.LatencyMS, 10),
-				row.Country,
-				row.Error,
-			}) //nolint:errcheck
-		}
-		cw.Flush()
-		return cw.Error()
+		cw.csv.Write(csvRow(checkColumns, row)) //nolint:errcheck
+		cw.csv.Flush()
+		return cw.csv.Error()
 	default: // table
-		fmt.Fprintf(w, "%-45s %-8s %-6s %8s  %-15s  %s\n",
-			"ADDRESS", "PROTO", "ALIVE", "LAT(ms)", "COUNTRY", "ERROR")
-		fmt.Fprintf(w, "%s\n", repeat('-', 110))
-		for _, row := range rows {
-			alive := "✗"
-			if row.Alive {
-				alive = "✓"
+		alive := "✗"
+		if row.Alive {
+			alive = "✓"
+		}
+		proto := row.Protocol
+		if row.Detected != "" {
+			proto = row.Detected + "*" // fingerprinted, not as declared
+		}
+		errText := errMessage(row.Error)
+		if row.Banner != "" {
+			errText += ": " + row.Banner
+		}
+		if row.Status >= 400 {
+			errText = fmt.Sprintf("HTTP %d", row.Status) // alive, but refusing
+		}
+		if errText == "" {
+			errText = row.Warning
+			if row.CredUser != "" {
+				errText = strings.TrimSuffix(fmt.Sprintf("credentials #%d (%s); %s", row.CredIdx, row.CredUser, errText), "; ")
 			}
-			fmt.Fprintf(w, "%-45s %-8s %-6s %8d  %-15s  %s\n",
-				truncate(row.Address, 45),
-				row.Protocol,
-				alive,
-				row.LatencyMS,
-				row.Country,
-				row.Error,
-			)
 		}
-		return nil
+		route := ""
+		if cw.Route {
+			route = fmt.Sprintf(" %4s %8s", routeHops(row.Route), routeLastMile(row.Route))
+		}
+		_, err := fmt.Fprintf(cw.w, "%-45s %-8s %-6s %8d%s  %-15s  %s\n",
+			counted(row.Address, row.Count, 45),
+			proto,
+			alive,
+			row.LatencyMS,
+			route,
+			row.Country,
+			errText,
+		)
+		return err
+	}
+}
+
+// Close writes the header if no rows were written and terminates the output.
+func (cw *CheckWriter) Close() error {
+	if cw.rows == 0 {
+		cw.header()
+	}
+	switch cw.format {
+	case FormatJSON:
+		return cw.json.close()
+	case FormatCSV:
+		cw.csv.Flush()
+		return cw.csv.Error()
+	}
+	return nil
+}
+
+func (cw *CheckWriter) header() {
+	switch cw.format {
+	case FormatJSON:
+		cw.json.meta = cw.Meta
+	case FormatNDJSON:
+	case FormatProxychains:
+		writeProxychainsHeader(cw.w, cw.Chain)
+	case FormatCSV:
+		cw.csv = cw.CSV.writer(cw.w)
+		cw.CSV.header(cw.csv, csvHeader(checkColumns))
+	default: // table
+		route, width := "", 110
+		if cw.Route {
+			route, width = fmt.Sprintf(" %4s %8s", "HOPS", "LAST(ms)"), width+14
+		}
+		fmt.Fprintf(cw.w, "%-45s %-8s %-6s %8s%s  %-15s  %s\n",
+			"ADDRESS", "PROTO", "ALIVE", "LAT(ms)", route, "COUNTRY", "ERROR")
+		fmt.Fprintf(cw.w, "%s\n", repeat('-', width))
 	}
 }
 
@@ -103,80 +349,302 @@ func WriteCheckResults(w io.Writer, results []checker.Result, countries []string
 type benchRow struct {
 	bench.Stats
 	Country string `json:"country,omitempty"`
+	Place
 }
 
 // WriteBenchResults writes benchmark stats in the requested format.
 // countries is an optional parallel slice of geo strings (may be nil or shorter than results).
 func WriteBenchResults(w io.Writer, results []bench.Stats, countries []string, format Format) error {
-	rows := make([]benchRow, len(results))
+	bw := NewBenchWriter(w, format, len(countries) > 0)
 	for i, r := range results {
-		rows[i] = benchRow{Stats: r}
+		c := ""
 		if i < len(countries) {
-			rows[i].Country = countries[i]
+			c = countries[i]
+		}
+		if err := bw.Write(r, c); err != nil {
+			return err
 		}
 	}
+	return bw.Close()
+}
+
+// BenchWriter streams benchmark stats one row at a time; see CheckWriter.
+type BenchWriter struct {
+	w       io.Writer
+	format  Format
+	withGeo bool // table only: include the COUNTRY column
+	csv     *csv.Writer
+
+	// Warm adds COLD and WARM latency columns to the table, for runs with
+	// bench.Options.ReuseConnections. CSV and JSON always carry them.
+	Warm bool
+
+	// UDP adds UDP P50 and UDP LOSS columns to the table, for runs with
+	// bench.Options.UDPTarget; "-" marks proxies that were not measured.
+	UDP bool
+
+	// Paths adds GET P50 and CONN P50 columns to the table, for runs with
+	// bench.Options.ComparePaths; "-" marks proxies that were not measured.
+	Paths bool
+
+	// CSV and Meta configure CSV and JSON output; see CheckWriter.
+	CSV  CSVDialect
+	Meta *Meta
+
+	json    jsonArray
+	rows    int
+}
+
+// NewBenchWriter returns a BenchWriter emitting format to w.
+func NewBenchWriter(w io.Writer, format Format, withGeo bool) *BenchWriter {
+	return &BenchWriter{w: w, format: format, withGeo: withGeo, json: jsonArray{w: w}}
+}
+
+// Rows reports how many results have been written.
+func (bw *BenchWriter) Rows() int { return bw.rows }
+
+// Write appends one result; country may be empty.
+func (bw *BenchWriter) Write(s bench.Stats, country string) error {
+	return bw.WriteGeo(s, geo.Record{CountryCode: country})
+}
+
+// WriteGeo appends one proxy's stats located by a layered geo lookup.
+func (bw *BenchWriter) WriteGeo(s bench.Stats, rec geo.Record) error {
+	if bw.rows == 0 {
+		bw.header()
+	}
+	bw.rows++
+	country, place := Locate(rec)
+	r := benchRow{Stats: s, Country: country, Place: place}
 
-	switch format {
+	switch bw.format {
 	case FormatJSON:
-		enc := json.NewEncoder(w)
-		enc.SetIndent("", "  ")
-		return enc.Encode(rows)
+		return bw.json.add(r)
+	case FormatNDJSON:
+		return writeLine(bw.w, r)
 	case FormatCSV:
-		cw := csv.NewWriter(w)
-		cw.Write([]string{"address", "samples", "successful", "min_ms", "max_ms", "avg_ms", "p50_ms", "p95_ms", "loss_rate", "speed_bps", "country"}) //nolint:errcheck
-		for _, r := range rows {
-			cw.Write([]string{
-				r.Address,
-				strconv.Itoa(r.Samples),
-				strconv.Itoa(r.Successful),
-				strconv.FormatInt(r.MinMS, 10),
-				strconv.FormatInt(r.MaxMS, 10),
-				strconv.FormatInt(r.AvgMS, 10),
-				strconv.FormatInt(r.P50MS, 10),
-				strconv.FormatInt(r.P95MS, 10),
-				strconv.FormatFloat(r.LossRate, 'f', 4, 64),
-				strconv.FormatInt(r.SpeedBps, 10),
-				r.Country,
-			}) //nolint:errcheck
-		}
-		cw.Flush()
-		return cw.Error()
+		bw.csv.Write(csvRow(bw.CSV.benchColumns(), r)) //nolint:errcheck
+		bw.csv.Flush()
+		return bw.csv.Error()
 	default: // table
-		withGeo := len(countries) > 0
-		if withGeo {
-			fmt.Fprintf(w, "%-45s %4s %4s %7s %7s %7s %7s %7s %8s  %s\n",
-				"ADDRESS", "OK", "ERR", "MIN", "AVG", "P50", "P95", "MAX", "LOSS%", "COUNTRY")
-			fmt.Fprintf(w, "%s\n", repeat('-', 133))
-		} else {
-			fmt.Fprintf(w, "%-45s %4s %4s %7s %7s %7s %7s %7s %8s\n",
-				"ADDRESS", "OK", "ERR", "MIN", "AVG", "P50", "P95", "MAX", "LOSS%")
-			fmt.Fprintf(w, "%s\n", repeat('-', 115))
-		}
-		for _, r := range rows {
-			failed := r.Samples - r.Successful
-			if withGeo {
-				fmt.Fprintf(w, "%-45s %4d %4d %7d %7d %7d %7d %7d %7.1f%%  %s\n",
-					truncate(r.Address, 45),
-					r.Successful, failed,
-					r.MinMS, r.AvgMS, r.P50MS, r.P95MS, r.MaxMS,
-					r.LossRate*100,
-					r.Country,
-				)
+		failed := r.Samples - r.Successful
+		line := fmt.Sprintf("%-45s %4d %4d %7d %7d %7d %7d %7d",
+			counted(r.Address, r.Count, 45),
+			r.Successful, failed,
+			r.MinMS, r.AvgMS, r.P50MS, r.P95MS, r.MaxMS,
+		)
+		if bw.Warm {
+			line += fmt.Sprintf(" %7d %7d", r.ColdMS, r.WarmMS)
+		}
+		if bw.UDP {
+			if r.UDPSupported != nil && *r.UDPSupported {
+				line += fmt.Sprintf(" %7d %7.1f%%", r.UDPP50MS, r.UDPLossRate*100)
 			} else {
-				fmt.Fprintf(w, "%-45s %4d %4d %7d %7d %7d %7d %7d %7.1f%%\n",
-					truncate(r.Address, 45),
-					r.Successful, failed,
-					r.MinMS, r.AvgMS, r.P50MS, r.P95MS, r.MaxMS,
-					r.LossRate*100,
-				)
+				line += fmt.Sprintf(" %7s %8s", "-", "-")
 			}
 		}
-		return nil
+		if bw.Paths {
+			if r.Paths != nil {
+				line += " " + pathCell(r.Paths.GetP50MS, r.Paths.GetLossRate) + " " + pathCell(r.Paths.ConnectP50MS, r.Paths.ConnectLossRate)
+			} else {
+				line += fmt.Sprintf(" %8s %8s", "-", "-")
+			}
+		}
+		status := "-"
+		if code := r.TopStatus(); code != 0 {
+			status = strconv.Itoa(code)
+		}
+		line += fmt.Sprintf(" %7.1f%% %5s %4s", r.LossRate*100, r.Grade, status)
+		if bw.withGeo {
+			line += "  " + r.Country
+		}
+		_, err := fmt.Fprintln(bw.w, line)
+		return err
+	}
+}
+
+// pathCell is a GET P50 or CONN P50 table cell: the median, or "fail"
+// when every request along the path failed.
+func pathCell(p50 int64, loss float64) string {
+	if loss >= 1 {
+		return fmt.Sprintf("%8s", "fail")
+	}
+	return fmt.Sprintf("%8d", p50)
+}
+
+// Close writes the header if no rows were written and terminates the output.
+func (bw *BenchWriter) Close() error {
+	if bw.rows == 0 {
+		bw.header()
+	}
+	switch bw.format {
+	case FormatJSON:
+		return bw.json.close()
+	case FormatCSV:
+		bw.csv.Flush()
+		return bw.csv.Error()
 	}
+	return nil
+}
+
+func (bw *BenchWriter) header() {
+	switch bw.format {
+	case FormatJSON:
+		bw.json.meta = bw.Meta
+	case FormatNDJSON:
+	case FormatCSV:
+		bw.csv = bw.CSV.writer(bw.w)
+		bw.CSV.header(bw.csv, csvHeader(bw.CSV.benchColumns()))
+	default: // table
+		head := fmt.Sprintf("%-45s %4s %4s %7s %7s %7s %7s %7s",
+			"ADDRESS", "OK", "ERR", "MIN", "AVG", "P50", "P95", "MAX")
+		width := 115
+		if bw.Warm {
+			head += fmt.Sprintf(" %7s %7s", "COLD", "WARM")
+			width += 16
+		}
+		if bw.UDP {
+			head += fmt.Sprintf(" %7s %8s", "UDP P50", "UDP LOSS")
+			width += 17
+		}
+		if bw.Paths {
+			head += fmt.Sprintf(" %8s %8s", "GET P50", "CONN P50")
+			width += 18
+		}
+		head += fmt.Sprintf(" %8s %5s %4s", "LOSS%", "GRADE", "HTTP")
+		width += 11
+		if bw.withGeo {
+			head += "  COUNTRY"
+			width += 18
+		}
+		fmt.Fprintln(bw.w, head)
+		fmt.Fprintf(bw.w, "%s\n", repeat('-', width))
+	}
+}
+
+// writeLine writes v as a single compact JSON line.
+func writeLine(w io.Writer, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// jsonArray writes a pretty-printed JSON array element by element, matching
+// json.Encoder with a two-space indent. With meta set, the array is the
+// "results" field of an envelope carrying the metadata.
+type jsonArray struct {
+	w    io.Writer
+	n    int
+	meta *Meta
+	head []byte // the envelope as opened, for Meta.tail
+}
+
+func (a *jsonArray) add(v any) error {
+	indent := a.indent()
+	b, err := json.MarshalIndent(v, indent+"  ", "  ")
+	if err != nil {
+		return err
+	}
+	sep := ",\n" + indent + "  "
+	if a.n == 0 {
+		if err := a.open(); err != nil {
+			return err
+		}
+		sep = "\n" + indent + "  "
+	}
+	a.n++
+	_, err = io.WriteString(a.w, sep+string(b))
+	return err
+}
+
+func (a *jsonArray) close() error {
+	end := "\n" + a.indent() + "]"
+	if a.n == 0 {
+		if err := a.open(); err != nil {
+			return err
+		}
+		end = "]"
+	}
+	if a.meta != nil {
+		end += a.meta.tail(a.head) + "\n}"
+	}
+	_, err := io.WriteString(a.w, end+"\n")
+	return err
+}
+
+// open writes everything up to and including the array's "[".
+func (a *jsonArray) open() error {
+	head := []byte("[")
+	if a.meta != nil {
+		var err error
+		if head, err = a.meta.open("results"); err != nil {
+			return err
+		}
+		a.head = head
+	}
+	_, err := a.w.Write(head)
+	return err
+}
+
+func (a *jsonArray) indent() string {
+	if a.meta != nil {
+		return "  "
+	}
+	return ""
 }
 
 // helpers
 
+// optBool renders an optional flag for CSV: "" when unknown.
+// joinInts renders a series as one CSV cell, e.g. "120;98;40".
+func joinInts(vals []int64, sep string) string {
+	parts := make([]string, len(vals))
+	for i, v := range vals {
+		parts[i] = strconv.FormatInt(v, 10)
+	}
+	return strings.Join(parts, sep)
+}
+
+// joinCounts formats code counts as "200:4;403:1", in code order.
+func joinCounts(counts map[int]int) string {
+	codes := make([]int, 0, len(counts))
+	for code := range counts {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	parts := make([]string, len(codes))
+	for i, code := range codes {
+		parts[i] = fmt.Sprintf("%d:%d", code, counts[code])
+	}
+	return strings.Join(parts, ";")
+}
+
+func optBool(b *bool) string {
+	if b == nil {
+		return ""
+	}
+	return strconv.FormatBool(*b)
+}
+
+// optTrue writes a flag that is only ever set as "true", leaving it empty
+// otherwise.
+func optTrue(b bool) string {
+	if !b {
+		return ""
+	}
+	return "true"
+}
+
+func optInt(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
 func repeat(c byte, n int) string {
 	b := make([]byte, n)
 	for i := range b {
@@ -185,6 +653,16 @@ func repeat(c byte, n int) string {
 	return string(b)
 }
 
+// counted truncates address to max, keeping room for a "×n" suffix when
+// the proxy appeared n > 1 times in the input.
+func counted(address string, n, max int) string {
+	if n <= 1 {
+		return truncate(address, max)
+	}
+	suffix := fmt.Sprintf(" ×%d", n)
+	return truncate(address, max-len([]rune(suffix))) + suffix
+}
+
 func truncate(s string, max int) string {
 	if len(s) <= max {
 		return s
@@ -10,6 +10,7 @@ import (
 
 	"github.com/drsoft-oss/proxybench/internal/bench"
 	"github.com/drsoft-oss/proxybench/internal/checker"
+	"github.com/drsoft-oss/proxybench/internal/geo"
 )
 
 func makeCheckResults() []checker.Result {
@@ -69,8 +70,21 @@ func TestWriteCheckResults_JSON(t *testing.T) {
 	if rows[0].Country != "US United States" {
 		t.Errorf("country = %q, want US United States", rows[0].Country)
 	}
-	if rows[1].Error != "connection refused" {
-		t.Errorf("error field = %q, want 'connection refused'", rows[1].Error)
+	if rows[1].Error == nil || rows[1].Error.Message != "connection refused" || rows[1].Error.Kind != checker.ErrUnknown {
+		t.Errorf("error field = %+v, want unclassified 'connection refused'", rows[1].Error)
+	}
+}
+
+func TestWriteCheckResults_JSONFailure(t *testing.T) {
+	results := makeCheckResults()[1:]
+	results[0].Failure = &checker.Failure{Kind: checker.ErrRefused, Message: "connection refused", Phase: checker.PhaseConnect}
+	var buf bytes.Buffer
+	if err := WriteCheckResults(&buf, results, nil, FormatNDJSON); err != nil {
+		t.Fatal(err)
+	}
+	want := `"error":{"kind":"refused","message":"connection refused","phase":"connect","retriable":false}`
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("row = %s, want %s", buf.String(), want)
 	}
 }
 
@@ -112,6 +126,72 @@ func TestWriteCheckResults_Table(t *testing.T) {
 	}
 }
 
+func TestWriteCheckResults_TableRefusingProxy(t *testing.T) {
+	results := makeCheckResults()[:1]
+	results[0].StatusCode = 403
+	var buf bytes.Buffer
+	if err := WriteCheckResults(&buf, results, nil, FormatTable); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "HTTP 403") {
+		t.Errorf("alive proxy answering 403 should show it:\n%s", buf.String())
+	}
+}
+
+func TestWriteCheckResults_TableWarning(t *testing.T) {
+	results := makeCheckResults()[:1]
+	results[0].Warning = "Private network (RFC 1918) address: unreachable from other networks"
+	var buf bytes.Buffer
+	if err := WriteCheckResults(&buf, results, nil, FormatTable); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "unreachable from other networks") {
+		t.Errorf("warning missing from table:\n%s", buf.String())
+	}
+}
+
+func TestWriteCheckResults_TableCount(t *testing.T) {
+	results := makeCheckResults()[:1]
+	results[0].Count = 3
+	var buf bytes.Buffer
+	if err := WriteCheckResults(&buf, results, nil, FormatTable); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "http://1.2.3.4:8080 ×3") {
+		t.Errorf("count missing from table:\n%s", buf.String())
+	}
+}
+
+func TestLocate_multipleCountries(t *testing.T) {
+	country, place := Locate(geo.Record{CountryCode: "US", CountryName: "United States", ResolvedIP: "8.8.8.8", OtherCountries: []string{"DE", "NL"}})
+	if country != "US United States (+DE,NL)" {
+		t.Errorf("country = %q", country)
+	}
+	if place.ResolvedIP != "8.8.8.8" {
+		t.Errorf("ResolvedIP = %q, want 8.8.8.8", place.ResolvedIP)
+	}
+}
+
+func TestCheckWriter_WriteGeo(t *testing.T) {
+	rec := geo.Record{CountryCode: "US", CountryName: "United States", ASN: 15169, ASName: "Google LLC", City: "Mountain View",
+		CountryVotes: []string{"US", "DE", "US"}, GeoDisagreement: true}
+	var buf bytes.Buffer
+	cw := NewCheckWriter(&buf, FormatNDJSON)
+	if err := cw.WriteGeo(makeCheckResults()[0], rec); err != nil {
+		t.Fatal(err)
+	}
+	var row checkRow
+	if err := json.Unmarshal(buf.Bytes(), &row); err != nil {
+		t.Fatal(err)
+	}
+	if row.Country != "US United States" || row.ASN != 15169 || row.ASName != "Google LLC" || row.City != "Mountain View" {
+		t.Errorf("row = %+v", row)
+	}
+	if len(row.CountryVotes) != 3 || !row.GeoDisagreement {
+		t.Errorf("consensus = %v %v, want the votes and disagreement kept", row.CountryVotes, row.GeoDisagreement)
+	}
+}
+
 // ---- Bench: JSON ------------------------------------------------------------
 
 func TestWriteBenchResults_JSON(t *testing.T) {
@@ -147,6 +227,27 @@ func TestWriteBenchResults_CSV(t *testing.T) {
 	}
 }
 
+func TestWriteBenchResults_CSVStatusCodes(t *testing.T) {
+	stats := makeBenchResults()
+	stats[0].StatusCodes = map[int]int{429: 1, 200: 3}
+	stats[0].StatusClasses = map[string]int{"2xx": 3, "4xx": 1}
+	var buf bytes.Buffer
+	if err := WriteBenchResults(&buf, stats, nil, FormatCSV); err != nil {
+		t.Fatal(err)
+	}
+	records, _ := csv.NewReader(&buf).ReadAll()
+	got := map[string]string{}
+	for i, col := range records[0] {
+		got[col] = records[1][i]
+	}
+	if got["status_2xx"] != "3" || got["status_4xx"] != "1" || got["status_5xx"] != "0" {
+		t.Errorf("status classes = %v", got)
+	}
+	if got["status_codes"] != "200:3;429:1" {
+		t.Errorf("status_codes = %q, want 200:3;429:1", got["status_codes"])
+	}
+}
+
 // ---- Bench: Table -----------------------------------------------------------
 
 func TestWriteBenchResults_Table(t *testing.T) {
@@ -161,6 +262,69 @@ func TestWriteBenchResults_Table(t *testing.T) {
 	}
 }
 
+// ---- Streaming --------------------------------------------------------------
+
+func TestCheckWriter_JSONMatchesEncoder(t *testing.T) {
+	for _, results := range [][]checker.Result{makeCheckResults(), nil} {
+		rows := make([]checkRow, len(results))
+		for i, r := range results {
+			rows[i] = toCheckRow(r, "")
+		}
+		var want bytes.Buffer
+		enc := json.NewEncoder(&want)
+		enc.SetIndent("", "  ")
+		enc.Encode(rows) //nolint:errcheck
+
+		var got bytes.Buffer
+		if err := WriteCheckResults(&got, results, nil, FormatJSON); err != nil {
+			t.Fatal(err)
+		}
+		if got.String() != want.String() {
+			t.Errorf("streamed JSON differs:\ngot:\n%s\nwant:\n%s", got.String(), want.String())
+		}
+	}
+}
+
+func TestWriteCheckResults_NDJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCheckResults(&buf, makeCheckResults(), []string{"US United States"}, FormatNDJSON); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d: %q", len(lines), buf.String())
+	}
+	var row checkRow
+	if err := json.Unmarshal([]byte(lines[0]), &row); err != nil {
+		t.Fatalf("line 1 is not JSON: %v", err)
+	}
+	if row.Country != "US United States" || !row.Alive {
+		t.Errorf("row = %+v", row)
+	}
+}
+
+func TestCheckWriter_emptyCSVHasHeader(t *testing.T) {
+	var buf bytes.Buffer
+	cw := NewCheckWriter(&buf, FormatCSV)
+	if err := cw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "address,protocol,alive,latency_ms,country,error,family,bind_supported,class,detected_protocol,proxy_protocol,connect_supported,hop_ms,target_ms,banner,software,status_code,warning,error_kind,count,trace_hops,last_mile_ms,pmtu_blackhole,tls_version,tls_cipher,tls_verified,tls_issuer,exit_ip,exit_country,geo_mismatch,dns_canary,quic,anonymity,credential_user,credential_index,content_modified,content_diff,tls_policy,rotating,exit_ips,dns_resolution,http_only,auth_methods,auth_method,asn,as_name,region,city,resolved_ip,country_votes,geo_disagreement\n" {
+		t.Errorf("empty CSV = %q", buf.String())
+	}
+}
+
+func TestBenchWriter_emptyCSVHasHeader(t *testing.T) {
+	var buf bytes.Buffer
+	bw := NewBenchWriter(&buf, FormatCSV, false)
+	if err := bw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "address,samples,successful,min_ms,max_ms,avg_ms,p50_ms,p95_ms,loss_rate,speed_bps,country,cold_ms,warm_ms,latency_class,speed_class,peak_bps,ramp_up_ms,speed_series,capacity_bps,saturation_conns,usable,grade,error,reconnects,percentile_method,status_2xx,status_3xx,status_4xx,status_5xx,status_codes,count,udp_supported,udp_p50_ms,udp_p95_ms,udp_loss_rate,handshake_rate,handshake_conns,error_onset_conns,test_url,tls_policy,get_p50_ms,get_p95_ms,get_loss_rate,connect_p50_ms,connect_p95_ms,connect_loss_rate,conn_limit,conn_limit_hit,initial_bps,sustained_bps,throttled,asn,as_name,region,city,resolved_ip,country_votes,geo_disagreement\n" {
+		t.Errorf("empty CSV = %q", buf.String())
+	}
+}
+
 // ---- helpers ----------------------------------------------------------------
 
 func TestTruncate(t *testing.T) {
@@ -0,0 +1,83 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/drsoft-oss/proxybench/internal/checker"
+)
+
+// FormatProxychains writes the alive proxies as a proxychains-ng
+// configuration file. Check results only.
+const FormatProxychains Format = "proxychains"
+
+// Chain is the proxychains-ng chaining mode.
+type Chain string
+
+const (
+	// ChainDynamic skips dead proxies and uses the rest in order.
+	ChainDynamic Chain = "dynamic"
+	// ChainStrict sends every connection through all proxies in order.
+	ChainStrict Chain = "strict"
+)
+
+// ParseChain accepts "dynamic" and "strict".
+func ParseChain(s string) (Chain, error) {
+	switch c := Chain(strings.ToLower(s)); c {
+	case ChainDynamic, ChainStrict:
+		return c, nil
+	}
+	return "", fmt.Errorf("unknown chain type %q (want dynamic or strict)", s)
+}
+
+// writeProxychainsHeader writes the options section and opens the
+// proxy list.
+func writeProxychainsHeader(w io.Writer, chain Chain) {
+	if chain == "" {
+		chain = ChainDynamic
+	}
+	fmt.Fprintf(w, "# proxychains-ng configuration written by proxybench (alive proxies only)\n")
+	fmt.Fprintf(w, "%s_chain\n", chain)
+	fmt.Fprintf(w, "proxy_dns\n")
+	fmt.Fprintf(w, "remote_dns_subnet 224\n")
+	fmt.Fprintf(w, "tcp_read_time_out 15000\n")
+	fmt.Fprintf(w, "tcp_connect_time_out 8000\n")
+	fmt.Fprintf(w, "\n[ProxyList]\n")
+}
+
+// proxychainsLine renders an alive HTTP or SOCKS5 result as a ProxyList
+// entry, "type host port [user pass]". Other results get a comment saying
+// why they were left out, or nothing if they are dead.
+func proxychainsLine(r checker.Result) string {
+	if !r.Alive {
+		return ""
+	}
+	var kind, port string
+	switch r.Protocol {
+	case checker.ProtocolHTTP:
+		kind, port = "http", "80"
+	case checker.ProtocolSOCKS5:
+		kind, port = "socks5", "1080"
+	default:
+		return fmt.Sprintf("# %s: %s proxies are not supported by proxychains\n", checker.Redact(r.Address), r.Protocol)
+	}
+	u, err := url.Parse(string(r.Protocol) + "://" + checker.StripScheme(r.Address, r.Protocol))
+	if err != nil || u.Hostname() == "" {
+		return fmt.Sprintf("# %s: unparseable address\n", checker.Redact(r.Address))
+	}
+	host := u.Hostname()
+	if p := u.Port(); p != "" {
+		port = p
+	}
+	line := kind + " " + host + " " + port
+	if u.User != nil {
+		pass, _ := u.User.Password()
+		if strings.ContainsAny(u.User.Username()+pass, " \t") {
+			return fmt.Sprintf("# %s: credentials with spaces cannot be written\n", checker.Redact(r.Address))
+		}
+		line += " " + u.User.Username() + " " + pass
+	}
+	return line + "\n"
+}
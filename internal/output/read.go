@@ -0,0 +1,111 @@
+package output
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// latencyRow holds the fields ReadLatencies needs from a check or bench row.
+type latencyRow struct {
+	Address   string `json:"address"`
+	Alive     *bool  `json:"alive"`      // check
+	LatencyMS int64  `json:"latency_ms"` // check
+	P95MS     int64  `json:"p95_ms"`     // bench
+}
+
+// ReadLatencies reads earlier check or bench output in JSON (a bare array
+// or an envelope with "results") or NDJSON and
+// returns the observed latency per address: p95 for bench rows, the check
+// latency for alive check rows. Dead or unmeasured proxies are left out.
+func ReadLatencies(r io.Reader) (map[string]time.Duration, error) {
+	br := bufio.NewReader(r)
+	first, err := firstByte(br)
+	if err != nil {
+		return nil, err
+	}
+
+	out := map[string]time.Duration{}
+	add := func(row latencyRow) {
+		switch {
+		case row.P95MS > 0:
+			out[row.Address] = time.Duration(row.P95MS) * time.Millisecond
+		case row.Alive != nil && *row.Alive && row.LatencyMS > 0:
+			out[row.Address] = time.Duration(row.LatencyMS) * time.Millisecond
+		}
+	}
+
+	dec := json.NewDecoder(br)
+	if first == '{' {
+		// An envelope, or the first row of NDJSON.
+		var head struct {
+			latencyRow
+			Results *[]latencyRow `json:"results"`
+		}
+		if err := dec.Decode(&head); err != nil {
+			return nil, fmt.Errorf("parse JSON results: %w", err)
+		}
+		if head.Results != nil {
+			for _, row := range *head.Results {
+				add(row)
+			}
+			return out, nil
+		}
+		add(head.latencyRow)
+	}
+	if first == '[' {
+		var rows []latencyRow
+		if err := dec.Decode(&rows); err != nil {
+			return nil, fmt.Errorf("parse JSON results: %w", err)
+		}
+		for _, row := range rows {
+			add(row)
+		}
+		return out, nil
+	}
+	for {
+		var row latencyRow
+		if err := dec.Decode(&row); err == io.EOF {
+			return out, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("parse NDJSON results: %w", err)
+		}
+		add(row)
+	}
+}
+
+// ReadLatenciesFile is ReadLatencies on a file.
+func ReadLatenciesFile(path string) (map[string]time.Duration, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	m, err := ReadLatencies(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return m, nil
+}
+
+// firstByte returns the first non-whitespace byte without consuming it.
+func firstByte(br *bufio.Reader) (byte, error) {
+	for {
+		b, err := br.ReadByte()
+		if err == io.EOF {
+			return 0, errors.New("no results in input")
+		}
+		if err != nil {
+			return 0, err
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return b, br.UnreadByte()
+	}
+}
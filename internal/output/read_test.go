@@ -0,0 +1,42 @@
+package output
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadLatencies_JSON(t *testing.T) {
+	in := `[
+  {"address":"http://a:1","alive":true,"latency_ms":120},
+  {"address":"http://b:1","alive":false,"latency_ms":0,"error":"refused"}
+]`
+	got, err := ReadLatencies(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got["http://a:1"] != 120*time.Millisecond {
+		t.Errorf("latencies = %v, want only a at 120ms", got)
+	}
+}
+
+func TestReadLatencies_NDJSONBench(t *testing.T) {
+	in := `{"address":"http://a:1","p50_ms":80,"p95_ms":300}
+{"address":"http://b:1","p50_ms":0,"p95_ms":0,"error":"no successful samples"}
+`
+	got, err := ReadLatencies(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got["http://a:1"] != 300*time.Millisecond {
+		t.Errorf("latencies = %v, want only a at its p95 of 300ms", got)
+	}
+}
+
+func TestReadLatencies_invalid(t *testing.T) {
+	for _, in := range []string{"", "  \n", "[{", "not json"} {
+		if _, err := ReadLatencies(strings.NewReader(in)); err == nil {
+			t.Errorf("ReadLatencies(%q) succeeded", in)
+		}
+	}
+}
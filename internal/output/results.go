@@ -0,0 +1,495 @@
+package output
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/drsoft-oss/proxybench/internal/bench"
+	"github.com/drsoft-oss/proxybench/internal/checker"
+	"github.com/drsoft-oss/proxybench/internal/geo"
+	"github.com/drsoft-oss/proxybench/internal/traceroute"
+)
+
+// Results is earlier check or bench output read back by ReadResults. Only
+// one of Checks and Benches is filled.
+type Results struct {
+	Checks  []CheckRecord
+	Benches []BenchRecord
+	// Meta is the envelope of JSON input, nil for other input.
+	Meta *Meta
+}
+
+// CheckRecord is a check result with the location it was written with.
+type CheckRecord struct {
+	Result checker.Result
+	Geo    geo.Record
+}
+
+// BenchRecord is a bench result with the location it was written with.
+type BenchRecord struct {
+	Stats bench.Stats
+	Geo   geo.Record
+}
+
+// Len returns the number of rows.
+func (rs *Results) Len() int { return len(rs.Checks) + len(rs.Benches) }
+
+// ReadResults reads check or bench output written in JSON (a bare array
+// or an envelope), NDJSON or CSV (any delimiter, with its header row), and
+// appends the rows to rs. The kind of each row is told by its fields;
+// mixing check and bench rows is an error.
+func (rs *Results) ReadResults(r io.Reader) error {
+	br := bufio.NewReader(r)
+	first, err := firstByte(br)
+	if err != nil {
+		return err
+	}
+	if first == '{' || first == '[' {
+		return rs.readJSON(br, first)
+	}
+	return rs.readCSV(br)
+}
+
+func (rs *Results) readJSON(br *bufio.Reader, first byte) error {
+	dec := json.NewDecoder(br)
+	if first == '[' {
+		var rows []json.RawMessage
+		if err := dec.Decode(&rows); err != nil {
+			return fmt.Errorf("parse JSON results: %w", err)
+		}
+		return rs.addJSON(rows)
+	}
+	// An envelope, or the first row of NDJSON.
+	var head json.RawMessage
+	if err := dec.Decode(&head); err != nil {
+		return fmt.Errorf("parse JSON results: %w", err)
+	}
+	var env struct {
+		*Meta
+		Results *[]json.RawMessage `json:"results"`
+	}
+	env.Meta = &Meta{}
+	if err := json.Unmarshal(head, &env); err != nil {
+		return fmt.Errorf("parse JSON results: %w", err)
+	}
+	if env.Results != nil {
+		if rs.Meta != nil && rs.Meta.Options.Shard != env.Meta.Options.Shard {
+			// Shards of a split run read back together are no longer one.
+			env.Meta.Options.Shard = ""
+		}
+		rs.Meta = env.Meta
+		return rs.addJSON(*env.Results)
+	}
+	rows := []json.RawMessage{head}
+	for {
+		var row json.RawMessage
+		if err := dec.Decode(&row); err == io.EOF {
+			return rs.addJSON(rows)
+		} else if err != nil {
+			return fmt.Errorf("parse NDJSON results: %w", err)
+		}
+		rows = append(rows, row)
+	}
+}
+
+// jsonCheckRow is checkRow as read back: error is an object since schema
+// version 2 and a plain message before it.
+type jsonCheckRow struct {
+	checkRow
+	Error json.RawMessage `json:"error"`
+}
+
+func (rs *Results) addJSON(rows []json.RawMessage) error {
+	for i, raw := range rows {
+		var keys map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &keys); err != nil {
+			return fmt.Errorf("result %d: %w", i+1, err)
+		}
+		if _, isBench := keys["samples"]; isBench {
+			var row benchRow
+			if err := json.Unmarshal(raw, &row); err != nil {
+				return fmt.Errorf("result %d: %w", i+1, err)
+			}
+			if err := rs.addBench(row.Stats, row.Place.record(row.Country)); err != nil {
+				return err
+			}
+			continue
+		}
+		var row jsonCheckRow
+		if err := json.Unmarshal(raw, &row); err != nil {
+			return fmt.Errorf("result %d: %w", i+1, err)
+		}
+		if len(row.Error) > 0 && row.Error[0] == '"' {
+			var msg string
+			json.Unmarshal(row.Error, &msg) //nolint:errcheck
+			row.checkRow.Error = &checker.Failure{Kind: checker.ErrUnknown, Message: msg}
+		} else if len(row.Error) > 0 && !bytes.Equal(row.Error, []byte("null")) {
+			if err := json.Unmarshal(row.Error, &row.checkRow.Error); err != nil {
+				return fmt.Errorf("result %d: %w", i+1, err)
+			}
+		}
+		if err := rs.addCheck(row.checkRow); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (rs *Results) addCheck(row checkRow) error {
+	if len(rs.Benches) > 0 {
+		return errMixedResults
+	}
+	rs.Checks = append(rs.Checks, CheckRecord{Result: row.result(), Geo: row.Place.record(row.Country)})
+	return nil
+}
+
+func (rs *Results) addBench(s bench.Stats, rec geo.Record) error {
+	if len(rs.Checks) > 0 {
+		return errMixedResults
+	}
+	rs.Benches = append(rs.Benches, BenchRecord{Stats: s, Geo: rec})
+	return nil
+}
+
+var errMixedResults = errors.New("input mixes check and bench results")
+
+// result is the inverse of toCheckRow.
+func (row checkRow) result() checker.Result {
+	return checker.Result{
+		Address:          row.Address,
+		Protocol:         checker.Protocol(row.Protocol),
+		Alive:            row.Alive,
+		Latency:          time.Duration(row.LatencyMS) * time.Millisecond,
+		Failure:          row.Error,
+		Error:            errMessage(row.Error),
+		Family:           row.Family,
+		BindSupported:    row.Bind,
+		Class:            row.Class,
+		DetectedProtocol: checker.Protocol(row.Detected),
+		ProxyProtocol:    row.ProxyHdr,
+		ConnectSupported: row.Connect,
+		HopLatency:       time.Duration(row.HopMS) * time.Millisecond,
+		TargetLatency:    time.Duration(row.TargetMS) * time.Millisecond,
+		Banner:           row.Banner,
+		Software:         row.Software,
+		StatusCode:       row.Status,
+		Warning:          row.Warning,
+		Count:            row.Count,
+		Route:            row.Route,
+		PMTUBlackhole:    row.PMTU,
+		TLS:              row.TLS,
+		ProxyTLS:         row.ProxyTLS,
+		ExitIP:           row.ExitIP,
+		ExitCountry:      row.ExitCC,
+		GeoMismatch:      row.Mismatch,
+		DNSCanary:        row.DNS,
+		QUIC:             row.QUIC,
+		Anonymity:        row.Anon,
+		CredentialUser:   row.CredUser,
+		CredentialIndex:  row.CredIdx,
+		ContentModified:  row.Content,
+		ContentDiff:      row.Diff,
+		TLSPolicy:        row.Policy,
+		ExitIPs:          row.ExitIPs,
+		Rotating:         row.Rotating,
+		DNSResolution:    row.Resolve,
+		HTTPOnly:         row.HTTPOnly,
+		AuthMethods:      row.Auth,
+		AuthMethod:       row.AuthUsed,
+	}
+}
+
+// record is the inverse of Locate: it splits a "CC Name (+DE,NL)" label
+// back into a geo.Record carrying p.
+func (p Place) record(country string) geo.Record {
+	rec := geo.Record{ASN: p.ASN, ASName: p.ASName, Region: p.Region, City: p.City, ResolvedIP: p.ResolvedIP,
+		CountryVotes: p.CountryVotes, GeoDisagreement: p.GeoDisagreement}
+	if i := strings.LastIndex(country, " (+"); i != -1 && strings.HasSuffix(country, ")") {
+		rec.OtherCountries = strings.Split(country[i+3:len(country)-1], ",")
+		country = country[:i]
+	}
+	rec.CountryCode, rec.CountryName, _ = strings.Cut(country, " ")
+	return rec
+}
+
+// ---- CSV --------------------------------------------------------------------
+
+// csvRecord reads one CSV row by column name, keeping the first parse
+// error.
+type csvRecord struct {
+	cols   map[string]int
+	fields []string
+	err    error
+}
+
+func (c *csvRecord) str(name string) string {
+	if i, ok := c.cols[name]; ok && i < len(c.fields) {
+		return c.fields[i]
+	}
+	return ""
+}
+
+func (c *csvRecord) int64(name string) int64 {
+	s := c.str(name)
+	if s == "" {
+		return 0
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil && c.err == nil {
+		c.err = fmt.Errorf("column %s: %w", name, err)
+	}
+	return n
+}
+
+func (c *csvRecord) int(name string) int { return int(c.int64(name)) }
+
+func (c *csvRecord) float(name string) float64 {
+	s := strings.Replace(c.str(name), ",", ".", 1) // --csv-decimal comma
+	if s == "" {
+		return 0
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil && c.err == nil {
+		c.err = fmt.Errorf("column %s: %w", name, err)
+	}
+	return f
+}
+
+func (c *csvRecord) bool(name string) bool {
+	b := c.optBool(name)
+	return b != nil && *b
+}
+
+func (c *csvRecord) optBool(name string) *bool {
+	s := c.str(name)
+	if s == "" {
+		return nil
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil && c.err == nil {
+		c.err = fmt.Errorf("column %s: %w", name, err)
+	}
+	return &b
+}
+
+// list splits a column written as values joined with "; ".
+func (c *csvRecord) list(name string) []string {
+	if s := c.str(name); s != "" {
+		return strings.Split(s, "; ")
+	}
+	return nil
+}
+
+func (c *csvRecord) place() Place {
+	return Place{
+		ASN:             uint32(c.int64("asn")),
+		ASName:          c.str("as_name"),
+		Region:          c.str("region"),
+		City:            c.str("city"),
+		ResolvedIP:      c.str("resolved_ip"),
+		CountryVotes:    c.list("country_votes"),
+		GeoDisagreement: c.bool("geo_disagreement"),
+	}
+}
+
+func (rs *Results) readCSV(br *bufio.Reader) error {
+	line, _ := br.Peek(br.Buffered()) // firstByte filled the buffer
+	if i := bytes.IndexByte(line, '\n'); i != -1 {
+		line = line[:i]
+	}
+	cr := csv.NewReader(br)
+	cr.Comma = sniffDelimiter(line)
+	header, err := cr.Read()
+	if err != nil {
+		return fmt.Errorf("parse CSV results: %w", err)
+	}
+	c := &csvRecord{cols: map[string]int{}}
+	for i, name := range header {
+		c.cols[strings.TrimSpace(name)] = i
+	}
+	if _, ok := c.cols["address"]; !ok {
+		return errors.New("parse CSV results: no address column (files written with --csv-no-header cannot be read back)")
+	}
+	_, isBench := c.cols["samples"]
+	for n := 2; ; n++ {
+		c.fields, err = cr.Read()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("parse CSV results: %w", err)
+		}
+		if isBench {
+			err = rs.addBench(c.stats(), c.place().record(c.str("country")))
+		} else {
+			err = rs.addCheck(c.checkRow())
+		}
+		if c.err != nil {
+			return fmt.Errorf("CSV line %d: %w", n, c.err)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// sniffDelimiter picks the delimiter of a CSV header line among those
+// CSVDialect writes.
+func sniffDelimiter(header []byte) rune {
+	best, most := ',', bytes.Count(header, []byte{','})
+	for _, d := range []rune{';', '\t'} {
+		if n := bytes.Count(header, []byte(string(d))); n > most {
+			best, most = d, n
+		}
+	}
+	return best
+}
+
+func (c *csvRecord) checkRow() checkRow {
+	row := checkRow{
+		Address:   c.str("address"),
+		Protocol:  c.str("protocol"),
+		Alive:     c.bool("alive"),
+		LatencyMS: c.int64("latency_ms"),
+		Country:   c.str("country"),
+		Family:    c.str("family"),
+		Bind:      c.optBool("bind_supported"),
+		Class:     c.str("class"),
+		Detected:  c.str("detected_protocol"),
+		ProxyHdr:  c.str("proxy_protocol"),
+		Connect:   c.optBool("connect_supported"),
+		HopMS:     c.int64("hop_ms"),
+		TargetMS:  c.int64("target_ms"),
+		Banner:    c.str("banner"),
+		Software:  c.str("software"),
+		Status:    c.int("status_code"),
+		Warning:   c.str("warning"),
+		Count:     c.int("count"),
+		PMTU:      c.optBool("pmtu_blackhole"),
+		ExitIP:    c.str("exit_ip"),
+		ExitCC:    c.str("exit_country"),
+		Mismatch:  c.bool("geo_mismatch"),
+		DNS:       c.str("dns_canary"),
+		QUIC:      c.optBool("quic"),
+		Anon:      c.str("anonymity"),
+		CredUser:  c.str("credential_user"),
+		CredIdx:   c.int("credential_index"),
+		Content:   c.optBool("content_modified"),
+		Diff:      c.list("content_diff"),
+		Policy:    c.str("tls_policy"),
+		ExitIPs:   c.list("exit_ips"),
+		Rotating:  c.optBool("rotating"),
+		Resolve:   c.str("dns_resolution"),
+		HTTPOnly:  c.bool("http_only"),
+		Auth:      c.list("auth_methods"),
+		AuthUsed:  c.str("auth_method"),
+		Place:     c.place(),
+	}
+	if verified := c.optBool("tls_verified"); verified != nil {
+		row.TLS = &checker.TLSInfo{Version: c.str("tls_version"), Cipher: c.str("tls_cipher"), Verified: *verified, Issuer: c.str("tls_issuer")}
+	}
+	switch hops := c.str("trace_hops"); hops {
+	case "":
+	case "?":
+		row.Route = &traceroute.Route{}
+	default:
+		row.Route = &traceroute.Route{Hops: c.int("trace_hops"), LastMileMS: c.int64("last_mile_ms")}
+	}
+	if msg, kind := c.str("error"), c.str("error_kind"); msg != "" || kind != "" {
+		row.Error = &checker.Failure{Kind: checker.ErrorKind(kind), Message: msg}
+		if kind == "" {
+			row.Error.Kind = checker.ErrUnknown
+		}
+	}
+	return row
+}
+
+func (c *csvRecord) stats() bench.Stats {
+	s := bench.Stats{
+		Address:          c.str("address"),
+		Samples:          c.int("samples"),
+		Successful:       c.int("successful"),
+		MinMS:            c.int64("min_ms"),
+		MaxMS:            c.int64("max_ms"),
+		AvgMS:            c.int64("avg_ms"),
+		P50MS:            c.int64("p50_ms"),
+		P95MS:            c.int64("p95_ms"),
+		LossRate:         c.float("loss_rate"),
+		SpeedBps:         c.int64("speed_bps"),
+		ColdMS:           c.int64("cold_ms"),
+		WarmMS:           c.int64("warm_ms"),
+		LatencyClass:     c.str("latency_class"),
+		SpeedClass:       c.str("speed_class"),
+		PeakBps:          c.int64("peak_bps"),
+		RampUpMS:         c.int64("ramp_up_ms"),
+		CapacityBps:      c.int64("capacity_bps"),
+		SaturationConns:  c.int("saturation_conns"),
+		Usable:           c.bool("usable"),
+		Grade:            c.str("grade"),
+		Error:            c.str("error"),
+		Reconnects:       c.int("reconnects"),
+		PercentileMethod: bench.PercentileMethod(c.str("percentile_method")),
+		Count:            c.int("count"),
+		UDPSupported:     c.optBool("udp_supported"),
+		UDPP50MS:         c.int64("udp_p50_ms"),
+		UDPP95MS:         c.int64("udp_p95_ms"),
+		UDPLossRate:      c.float("udp_loss_rate"),
+		HandshakeRate:    c.float("handshake_rate"),
+		HandshakeConns:   c.int("handshake_conns"),
+		ErrorOnsetConns:  c.int("error_onset_conns"),
+		TestURL:          c.str("test_url"),
+		TLSPolicy:        c.str("tls_policy"),
+		ConnLimit:        c.int("conn_limit"),
+		ConnLimitHit:     c.bool("conn_limit_hit"),
+		InitialBps:       c.int64("initial_bps"),
+		SustainedBps:     c.int64("sustained_bps"),
+		Throttled:        c.bool("throttled"),
+	}
+	if c.str("get_loss_rate") != "" {
+		s.Paths = &bench.PathStats{
+			GetP50MS:        c.int64("get_p50_ms"),
+			GetP95MS:        c.int64("get_p95_ms"),
+			GetLossRate:     c.float("get_loss_rate"),
+			ConnectP50MS:    c.int64("connect_p50_ms"),
+			ConnectP95MS:    c.int64("connect_p95_ms"),
+			ConnectLossRate: c.float("connect_loss_rate"),
+		}
+	}
+	if series := c.str("speed_series"); series != "" {
+		for _, v := range strings.Split(series, ";") {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil && c.err == nil {
+				c.err = fmt.Errorf("column speed_series: %w", err)
+			}
+			s.SpeedSeries = append(s.SpeedSeries, n)
+		}
+	}
+	for _, class := range []string{"2xx", "3xx", "4xx", "5xx"} {
+		if n := c.int("status_" + class); n > 0 {
+			if s.StatusClasses == nil {
+				s.StatusClasses = map[string]int{}
+			}
+			s.StatusClasses[class] = n
+		}
+	}
+	if codes := c.str("status_codes"); codes != "" {
+		s.StatusCodes = map[int]int{}
+		for _, part := range strings.Split(codes, ";") {
+			code, n, _ := strings.Cut(part, ":")
+			ci, err1 := strconv.Atoi(code)
+			ni, err2 := strconv.Atoi(n)
+			if (err1 != nil || err2 != nil) && c.err == nil {
+				c.err = fmt.Errorf("column status_codes: bad entry %q", part)
+			}
+			s.StatusCodes[ci] = ni
+		}
+	}
+	return s
+}
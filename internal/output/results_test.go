@@ -0,0 +1,180 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/drsoft-oss/proxybench/internal/bench"
+	"github.com/drsoft-oss/proxybench/internal/checker"
+	"github.com/drsoft-oss/proxybench/internal/geo"
+	"github.com/drsoft-oss/proxybench/internal/traceroute"
+)
+
+func TestReadResults_checkRoundTrip(t *testing.T) {
+	in := makeCheckResults()
+	in[1].Failure = &checker.Failure{Kind: checker.ErrRefused, Message: "connection refused", Phase: checker.PhaseConnect}
+	in[0].Route = &traceroute.Route{Hops: 12, RTTMS: 200, LastMileMS: 150}
+	stalls := true
+	in[0].PMTUBlackhole = &stalls
+	in[0].ExitIP = "203.0.113.9"
+	in[0].SetExitCountry("US", "DE")
+	in[0].DNSCanary = checker.DNSNXHijacked
+	in[0].DNSResolution = checker.ResolveRemote
+	in[0].QUIC = new(bool)
+	in[0].Anonymity = checker.AnonymityAnonymous
+	in[0].CredentialUser, in[0].CredentialIndex = "alice", 2
+	modified := true
+	in[0].ContentModified = &modified
+	in[0].TLSPolicy = checker.TLSPolicyCACert
+	rotating := true
+	in[0].ExitIPs, in[0].Rotating = []string{"203.0.113.9", "203.0.113.10"}, &rotating
+	in[0].AuthMethods = []string{checker.AuthNone, checker.AuthUserPass}
+	in[0].AuthMethod = checker.AuthUserPass
+	in[0].ContentDiff = []string{"added script src=http://ads.example/a.js", "removed a href=https://www.iana.org/"}
+	in[0].TLS = &checker.TLSInfo{Version: "TLS 1.0", Cipher: "TLS_RSA_WITH_AES_128_CBC_SHA", Issuer: "Corp Inspection CA"}
+	rec := geo.Record{CountryCode: "US", CountryName: "United States", ASN: 15169, OtherCountries: []string{"DE"}}
+	for _, format := range []Format{FormatJSON, FormatNDJSON, FormatCSV} {
+		var buf bytes.Buffer
+		cw := NewCheckWriter(&buf, format)
+		cw.CSV = CSVDialect{Comma: ';'}
+		cw.Meta = &Meta{SchemaVersion: SchemaVersion, Command: "check"}
+		for _, r := range in {
+			if err := cw.WriteGeo(r, rec); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := cw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		var rs Results
+		if err := rs.ReadResults(&buf); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if len(rs.Checks) != 2 || len(rs.Benches) != 0 {
+			t.Fatalf("%s: got %d check and %d bench rows", format, len(rs.Checks), len(rs.Benches))
+		}
+		got := rs.Checks[1]
+		if got.Result.Address != "socks5://5.6.7.8:1080" || got.Result.Alive || got.Result.Failure.Kind != checker.ErrRefused {
+			t.Errorf("%s: result = %+v", format, got.Result)
+		}
+		if got.Geo.CountryCode != "US" || got.Geo.CountryName != "United States" || got.Geo.ASN != 15169 || len(got.Geo.OtherCountries) != 1 {
+			t.Errorf("%s: geo = %+v", format, got.Geo)
+		}
+		if rt := rs.Checks[0].Result.Route; rt == nil || rt.Hops != 12 || rt.LastMileMS != 150 {
+			t.Errorf("%s: route = %+v", format, rt)
+		}
+		if b := rs.Checks[0].Result.PMTUBlackhole; b == nil || !*b {
+			t.Errorf("%s: pmtu_blackhole = %v", format, b)
+		}
+		if tl := rs.Checks[0].Result.TLS; tl == nil || tl.Verified || !tl.Downgraded() || tl.Issuer != "Corp Inspection CA" {
+			t.Errorf("%s: tls = %+v", format, tl)
+		}
+		if r := rs.Checks[0].Result; r.ExitIP != "203.0.113.9" || r.ExitCountry != "DE" || !r.GeoMismatch || r.DNSCanary != checker.DNSNXHijacked || r.DNSResolution != checker.ResolveRemote {
+			t.Errorf("%s: exit = %q %q %v", format, r.ExitIP, r.ExitCountry, r.GeoMismatch)
+		}
+		if q := rs.Checks[0].Result.QUIC; q == nil || *q {
+			t.Errorf("%s: quic = %v", format, q)
+		}
+		if a := rs.Checks[0].Result.Anonymity; a != checker.AnonymityAnonymous {
+			t.Errorf("%s: anonymity = %q", format, a)
+		}
+		if r := rs.Checks[0].Result; r.CredentialUser != "alice" || r.CredentialIndex != 2 {
+			t.Errorf("%s: credentials = %q #%d", format, r.CredentialUser, r.CredentialIndex)
+		}
+		if r := rs.Checks[0].Result; r.ContentModified == nil || !*r.ContentModified || !slices.Equal(r.ContentDiff, in[0].ContentDiff) {
+			t.Errorf("%s: content = %v %q", format, r.ContentModified, r.ContentDiff)
+		}
+		if r := rs.Checks[0].Result; r.Rotating == nil || !*r.Rotating || !slices.Equal(r.ExitIPs, in[0].ExitIPs) {
+			t.Errorf("%s: rotation = %v %q", format, r.Rotating, r.ExitIPs)
+		}
+		if r := rs.Checks[0].Result; !slices.Equal(r.AuthMethods, in[0].AuthMethods) || r.AuthMethod != checker.AuthUserPass {
+			t.Errorf("%s: auth = %q %q", format, r.AuthMethod, r.AuthMethods)
+		}
+		if p := rs.Checks[0].Result.TLSPolicy; p != checker.TLSPolicyCACert {
+			t.Errorf("%s: tls_policy = %q", format, p)
+		}
+		if rs.Checks[0].Result.LatencyMS() != 200 {
+			t.Errorf("%s: latency = %d", format, rs.Checks[0].Result.LatencyMS())
+		}
+		if (format == FormatJSON) != (rs.Meta != nil) {
+			t.Errorf("%s: meta = %+v", format, rs.Meta)
+		}
+	}
+}
+
+func TestReadResults_benchCSV(t *testing.T) {
+	var buf bytes.Buffer
+	bw := NewBenchWriter(&buf, FormatCSV, true)
+	bw.CSV = CSVDialect{Comma: '\t', DecimalComma: true}
+	s := makeBenchResults()[0]
+	s.StatusCodes = map[int]int{200: 3, 429: 1}
+	s.SpeedSeries = []int64{100, 200}
+	s.TestURL = "http://eu.example.com/"
+	s.TLSPolicy = checker.TLSPolicyInsecure
+	s.Paths = &bench.PathStats{GetP50MS: 40, GetP95MS: 55, ConnectP50MS: 310, ConnectP95MS: 420, ConnectLossRate: 0.5}
+	s.ConnLimit, s.ConnLimitHit = 48, true
+	s.InitialBps, s.SustainedBps, s.Throttled = 4000000, 500000, true
+	if err := bw.Write(s, "DE"); err != nil {
+		t.Fatal(err)
+	}
+	if err := bw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var rs Results
+	if err := rs.ReadResults(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if len(rs.Benches) != 1 {
+		t.Fatalf("got %d bench rows", len(rs.Benches))
+	}
+	got := rs.Benches[0]
+	if got.Stats.LossRate != 0.2 || got.Stats.P95MS != 380 || got.Stats.StatusCodes[429] != 1 || len(got.Stats.SpeedSeries) != 2 || got.Geo.CountryCode != "DE" || got.Stats.TestURL != "http://eu.example.com/" || got.Stats.TLSPolicy != checker.TLSPolicyInsecure {
+		t.Errorf("got %+v", got)
+	}
+	if p := got.Stats.Paths; p == nil || *p != *s.Paths {
+		t.Errorf("paths = %+v, want %+v", p, s.Paths)
+	}
+	if got.Stats.ConnLimit != 48 || !got.Stats.ConnLimitHit {
+		t.Errorf("conn_limit = %d (hit %v)", got.Stats.ConnLimit, got.Stats.ConnLimitHit)
+	}
+	if got.Stats.InitialBps != s.InitialBps || got.Stats.SustainedBps != s.SustainedBps || !got.Stats.Throttled {
+		t.Errorf("throttling = %d, %d, %v", got.Stats.InitialBps, got.Stats.SustainedBps, got.Stats.Throttled)
+	}
+}
+
+func TestReadResults_legacyErrorString(t *testing.T) {
+	var rs Results
+	err := rs.ReadResults(strings.NewReader(`[{"address":"http://1.2.3.4:8080","protocol":"http","alive":false,"latency_ms":0,"error":"timeout"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f := rs.Checks[0].Result.Failure; f == nil || f.Message != "timeout" || f.Kind != checker.ErrUnknown {
+		t.Errorf("failure = %+v", f)
+	}
+}
+
+func TestReadResults_mixed(t *testing.T) {
+	var rs Results
+	if err := rs.ReadResults(strings.NewReader(`{"address":"a","alive":true}` + "\n" + `{"address":"b","samples":5}`)); err != errMixedResults {
+		t.Errorf("err = %v, want %v", err, errMixedResults)
+	}
+}
+
+func TestReadResults_mergedShards(t *testing.T) {
+	var rs Results
+	for i := 1; i <= 2; i++ {
+		env := fmt.Sprintf(`{"schema_version":2,"command":"check","options":{"shard":"%d/2"},"results":[{"address":"http://10.0.0.%d:8080","alive":false}]}`, i, i)
+		if err := rs.ReadResults(strings.NewReader(env)); err != nil {
+			t.Fatal(err)
+		}
+		if i == 1 && rs.Meta.Options.Shard != "1/2" {
+			t.Errorf("one shard: shard = %q, want 1/2", rs.Meta.Options.Shard)
+		}
+	}
+	if rs.Len() != 2 || rs.Meta.Options.Shard != "" {
+		t.Errorf("merged: %d rows, shard %q", rs.Len(), rs.Meta.Options.Shard)
+	}
+}
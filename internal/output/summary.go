@@ -0,0 +1,150 @@
+package output
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+
+	"github.com/drsoft-oss/proxybench/internal/bench"
+	"github.com/drsoft-oss/proxybench/internal/checker"
+	"github.com/drsoft-oss/proxybench/internal/geo"
+)
+
+// CountrySummary aggregates the results of one exit country.
+type CountrySummary struct {
+	Country  string  `json:"country"` // "CC Name", or "--" when not located
+	Proxies  int     `json:"proxies"`
+	Alive    int     `json:"alive"`
+	AlivePct float64 `json:"alive_pct"`
+	// MedianMS is the median latency of the alive proxies (P50 for
+	// bench results); Best is the alive proxy with the lowest.
+	MedianMS int64  `json:"median_latency_ms"`
+	Best     string `json:"best_proxy,omitempty"`
+	BestMS   int64  `json:"best_latency_ms,omitempty"`
+}
+
+// Summary aggregates results by country as they are added.
+type Summary struct {
+	countries map[string]*countryAgg
+}
+
+type countryAgg struct {
+	proxies   int
+	latencies []int64
+	best      string
+	bestMS    int64
+}
+
+// NewSummary returns an empty Summary.
+func NewSummary() *Summary {
+	return &Summary{countries: map[string]*countryAgg{}}
+}
+
+// AddCheck counts one check result located at rec.
+func (s *Summary) AddCheck(r checker.Result, rec geo.Record) {
+	s.add(rec, r.Address, r.Alive, r.LatencyMS())
+}
+
+// AddBench counts one proxy's bench stats located at rec; a proxy is
+// alive when it produced latency stats, and its latency is the P50.
+func (s *Summary) AddBench(st bench.Stats, rec geo.Record) {
+	s.add(rec, st.Address, st.OK(), st.P50MS)
+}
+
+func (s *Summary) add(rec geo.Record, address string, alive bool, ms int64) {
+	country, _ := Locate(geo.Record{CountryCode: rec.CountryCode, CountryName: rec.CountryName})
+	if country == "" {
+		country = "--"
+	}
+	a := s.countries[country]
+	if a == nil {
+		a = &countryAgg{}
+		s.countries[country] = a
+	}
+	a.proxies++
+	if !alive {
+		return
+	}
+	a.latencies = append(a.latencies, ms)
+	if len(a.latencies) == 1 || ms < a.bestMS || ms == a.bestMS && address < a.best {
+		a.best, a.bestMS = address, ms
+	}
+}
+
+// Countries returns one row per country, the most alive proxies first.
+func (s *Summary) Countries() []CountrySummary {
+	out := make([]CountrySummary, 0, len(s.countries))
+	for country, a := range s.countries {
+		c := CountrySummary{Country: country, Proxies: a.proxies, Alive: len(a.latencies)}
+		c.AlivePct = float64(int(1000*float64(c.Alive)/float64(c.Proxies)+0.5)) / 10
+		if c.Alive > 0 {
+			slices.Sort(a.latencies)
+			c.MedianMS = a.latencies[(c.Alive-1)/2]
+			c.Best, c.BestMS = a.best, a.bestMS
+		}
+		out = append(out, c)
+	}
+	slices.SortFunc(out, func(a, b CountrySummary) int {
+		return cmp.Or(cmp.Compare(b.Alive, a.Alive), cmp.Compare(a.MedianMS, b.MedianMS), cmp.Compare(a.Country, b.Country))
+	})
+	return out
+}
+
+// WriteSummary writes the per-country rows in format, CSV in dialect.
+// JSON output is an object with a "countries" section, after the fields
+// of meta if it is set.
+func WriteSummary(w io.Writer, rows []CountrySummary, format Format, dialect CSVDialect, meta *Meta) error {
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(struct {
+			*Meta
+			Countries []CountrySummary `json:"countries"`
+		}{meta, rows})
+	case FormatNDJSON:
+		for _, r := range rows {
+			if err := writeLine(w, r); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatCSV:
+		cw := dialect.writer(w)
+		dialect.header(cw, []string{"country", "proxies", "alive", "alive_pct", "median_latency_ms", "best_proxy", "best_latency_ms"})
+		for _, r := range rows {
+			cw.Write([]string{ //nolint:errcheck
+				r.Country,
+				strconv.Itoa(r.Proxies),
+				strconv.Itoa(r.Alive),
+				dialect.float(r.AlivePct, 1),
+				strconv.FormatInt(r.MedianMS, 10),
+				r.Best,
+				strconv.FormatInt(r.BestMS, 10),
+			})
+		}
+		cw.Flush()
+		return cw.Error()
+	default: // table
+		fmt.Fprintf(w, "%-24s %7s %6s %7s %9s  %s\n", "COUNTRY", "PROXIES", "ALIVE", "ALIVE%", "MEDIAN", "BEST")
+		fmt.Fprintf(w, "%s\n", repeat('-', 100))
+		for _, r := range rows {
+			best := "-"
+			if r.Best != "" {
+				best = fmt.Sprintf("%s (%dms)", r.Best, r.BestMS)
+			}
+			median := "-"
+			if r.Alive > 0 {
+				median = fmt.Sprintf("%dms", r.MedianMS)
+			}
+			if _, err := fmt.Fprintf(w, "%-24s %7d %6d %6.1f%% %9s  %s\n",
+				truncate(r.Country, 24), r.Proxies, r.Alive, r.AlivePct, median, best); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,79 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/drsoft-oss/proxybench/internal/bench"
+	"github.com/drsoft-oss/proxybench/internal/checker"
+	"github.com/drsoft-oss/proxybench/internal/geo"
+)
+
+func TestSummary_Countries(t *testing.T) {
+	us := geo.Record{CountryCode: "US", CountryName: "United States"}
+	de := geo.Record{CountryCode: "DE", CountryName: "Germany"}
+	s := NewSummary()
+	s.AddCheck(checker.Result{Address: "http://a:1", Alive: true, Latency: 300 * time.Millisecond}, us)
+	s.AddCheck(checker.Result{Address: "http://b:1", Alive: true, Latency: 100 * time.Millisecond}, us)
+	s.AddCheck(checker.Result{Address: "http://c:1", Alive: true, Latency: 200 * time.Millisecond}, us)
+	s.AddCheck(checker.Result{Address: "http://d:1"}, us)
+	s.AddCheck(checker.Result{Address: "http://e:1", Alive: true, Latency: 50 * time.Millisecond}, de)
+	s.AddCheck(checker.Result{Address: "http://f:1"}, geo.Record{})
+
+	rows := s.Countries()
+	if len(rows) != 3 {
+		t.Fatalf("got %d countries, want 3", len(rows))
+	}
+	want := CountrySummary{Country: "US United States", Proxies: 4, Alive: 3, AlivePct: 75, MedianMS: 200, Best: "http://b:1", BestMS: 100}
+	if rows[0] != want {
+		t.Errorf("rows[0] = %+v, want %+v", rows[0], want)
+	}
+	if rows[1].Country != "DE Germany" || rows[1].MedianMS != 50 {
+		t.Errorf("rows[1] = %+v", rows[1])
+	}
+	if rows[2].Country != "--" || rows[2].Alive != 0 || rows[2].Best != "" {
+		t.Errorf("unlocated row = %+v", rows[2])
+	}
+}
+
+func TestSummary_AddBench(t *testing.T) {
+	s := NewSummary()
+	s.AddBench(bench.Stats{Address: "http://a:1", Successful: 3, P50MS: 120}, geo.Record{CountryCode: "NL"})
+	s.AddBench(bench.Stats{Address: "http://b:1"}, geo.Record{CountryCode: "NL"})
+	rows := s.Countries()
+	if len(rows) != 1 || rows[0].Alive != 1 || rows[0].MedianMS != 120 || rows[0].AlivePct != 50 {
+		t.Errorf("rows = %+v", rows)
+	}
+}
+
+func TestWriteSummary(t *testing.T) {
+	rows := []CountrySummary{{Country: "US United States", Proxies: 2, Alive: 1, AlivePct: 50, MedianMS: 80, Best: "http://a:1", BestMS: 80}}
+
+	var buf bytes.Buffer
+	if err := WriteSummary(&buf, rows, FormatJSON, CSVDialect{}, nil); err != nil {
+		t.Fatal(err)
+	}
+	var doc struct{ Countries []CountrySummary }
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil || len(doc.Countries) != 1 || doc.Countries[0] != rows[0] {
+		t.Errorf("JSON round trip = %+v, %v", doc, err)
+	}
+
+	buf.Reset()
+	if err := WriteSummary(&buf, rows, FormatCSV, CSVDialect{}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if want := "country,proxies,alive,alive_pct,median_latency_ms,best_proxy,best_latency_ms\nUS United States,2,1,50.0,80,http://a:1,80\n"; buf.String() != want {
+		t.Errorf("CSV = %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := WriteSummary(&buf, rows, FormatTable, CSVDialect{}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "http://a:1 (80ms)") {
+		t.Errorf("table missing best proxy:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,104 @@
+// Package pool runs work over a stream of inputs on a fixed set of workers,
+// delivering results in input order with bounded buffering.
+package pool
+
+import "sync"
+
+// Ordered applies fn to every value received from in using workers
+// goroutines and calls emit with each result in input order. At most
+// 2*workers inputs are in flight (received but not yet emitted), so a slow
+// emit or a slow item at the head of the line stalls reading from in rather
+// than growing memory. emit is called from the caller's goroutine. Ordered
+// returns once in is closed and every result has been emitted.
+func Ordered[In, Out any](in <-chan In, workers int, fn func(In) Out, emit func(Out)) {
+	if workers <= 0 {
+		workers = 1
+	}
+	type job struct {
+		idx int
+		v   In
+	}
+	type result struct {
+		idx int
+		v   Out
+	}
+
+	jobs := make(chan job)
+	results := make(chan result, workers)
+	window := make(chan struct{}, 2*workers)
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Go(func() {
+			for j := range jobs {
+				results <- result{j.idx, fn(j.v)}
+			}
+		})
+	}
+	go func() {
+		idx := 0
+		for v := range in {
+			window <- struct{}{}
+			jobs <- job{idx, v}
+			idx++
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	// Results arrive in completion order; hold early ones until their
+	// predecessors are emitted. The window bounds this map.
+	pending := make(map[int]Out, 2*workers)
+	next := 0
+	for r := range results {
+		pending[r.idx] = r.v
+		for {
+			v, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			emit(v)
+			<-window
+			next++
+		}
+	}
+}
+
+// Unordered is Ordered without the reordering: emit is called with each
+// result as soon as it is ready, so a slow item does not hold back the
+// ones after it. At most workers inputs are in flight.
+func Unordered[In, Out any](in <-chan In, workers int, fn func(In) Out, emit func(Out)) {
+	if workers <= 0 {
+		workers = 1
+	}
+	results := make(chan Out)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Go(func() {
+			for v := range in {
+				results <- fn(v)
+			}
+		})
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	for r := range results {
+		emit(r)
+	}
+}
+
+// FromSlice returns a channel that yields the elements of s and is then closed.
+func FromSlice[T any](s []T) <-chan T {
+	ch := make(chan T)
+	go func() {
+		for _, v := range s {
+			ch <- v
+		}
+		close(ch)
+	}()
+	return ch
+}
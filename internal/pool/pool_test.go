@@ -0,0 +1,79 @@
+package pool
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestOrdered_preservesOrder(t *testing.T) {
+	in := make([]int, 100)
+	for i := range in {
+		in[i] = i
+	}
+	var got []int
+	Ordered(FromSlice(in), 8, func(v int) int {
+		// Later items finish first to force reordering.
+		time.Sleep(time.Duration(100-v) * 10 * time.Microsecond)
+		return v * 2
+	}, func(v int) { got = append(got, v) })
+
+	if len(got) != len(in) {
+		t.Fatalf("got %d results, want %d", len(got), len(in))
+	}
+	for i, v := range got {
+		if v != i*2 {
+			t.Fatalf("result %d = %d, want %d", i, v, i*2)
+		}
+	}
+}
+
+func TestOrdered_boundsInFlight(t *testing.T) {
+	const workers = 4
+	var inFlight, peak atomic.Int64
+	in := make(chan int)
+	go func() {
+		for i := range 200 {
+			in <- i
+			if n := inFlight.Add(1); n > peak.Load() {
+				peak.Store(n)
+			}
+		}
+		close(in)
+	}()
+	Ordered(in, workers, func(v int) int { return v }, func(int) {
+		time.Sleep(50 * time.Microsecond) // slow consumer
+		inFlight.Add(-1)
+	})
+	// One extra item may sit in the feeder while it waits for a window slot.
+	if p := peak.Load(); p > 2*workers+1 {
+		t.Errorf("peak in-flight = %d, want <= %d", p, 2*workers+1)
+	}
+}
+
+func TestOrdered_empty(t *testing.T) {
+	called := false
+	Ordered(FromSlice([]int(nil)), 3, func(v int) int { return v }, func(int) { called = true })
+	if called {
+		t.Error("emit called for empty input")
+	}
+}
+
+func TestUnordered_completionOrder(t *testing.T) {
+	release := make(chan struct{})
+	var got []int
+	Unordered(FromSlice([]int{0, 1, 2}), 3, func(v int) int {
+		if v == 0 {
+			<-release // the first item finishes last
+		}
+		return v
+	}, func(v int) {
+		got = append(got, v)
+		if len(got) == 2 {
+			close(release)
+		}
+	})
+	if len(got) != 3 || got[2] != 0 {
+		t.Errorf("emitted %v, want the slow first item last", got)
+	}
+}
@@ -0,0 +1,287 @@
+package proxylist
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/drsoft-oss/proxybench/internal/checker"
+)
+
+// Clash configs are YAML. Only the top-level proxies: list is read, with
+// its entries in block or flow style and scalar values; nested options
+// (plugin-opts, ws-opts, …) are ignored. That covers what Clash and its
+// forks export without pulling in a YAML library.
+
+// clashStart returns the offset just past a top-level "proxies:" line, or
+// -1 if there is none.
+func clashStart(data []byte) int {
+	off := 0
+	for len(data[off:]) > 0 {
+		line, _, found := bytes.Cut(data[off:], []byte("\n"))
+		next := off + len(line) + 1
+		if !found {
+			next = len(data)
+		}
+		key, rest, ok := bytes.Cut(bytes.TrimRight(line, "\r"), []byte(":"))
+		if ok && string(key) == "proxies" {
+			if rest = bytes.TrimSpace(rest); len(rest) == 0 || rest[0] == '#' || string(rest) == "[]" {
+				return next
+			}
+		}
+		off = next
+	}
+	return -1
+}
+
+func readClash(data []byte) ([]Proxy, []string, error) {
+	start := clashStart(data)
+	if start == -1 {
+		return nil, nil, errors.New("no top-level proxies: list in Clash config")
+	}
+	var items []map[string]string
+	var cur map[string]string
+	itemIndent, keyIndent := -1, -1
+	for _, line := range strings.Split(string(data[start:]), "\n") {
+		line = strings.TrimRight(line, "\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		if indent == 0 && !strings.HasPrefix(trimmed, "-") {
+			break // the next top-level key
+		}
+		if (trimmed == "-" || strings.HasPrefix(trimmed, "- ")) && (itemIndent == -1 || indent == itemIndent) {
+			itemIndent = indent
+			item := strings.TrimSpace(trimmed[1:])
+			cur = map[string]string{}
+			items = append(items, cur)
+			keyIndent = -1
+			if item == "" {
+				continue
+			}
+			keyIndent = indent + len(trimmed) - len(item)
+			if strings.HasPrefix(item, "{") {
+				parseFlow(item, cur)
+				cur = nil
+				continue
+			}
+			k, v := parseKV(item)
+			cur[k] = v
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+		if keyIndent == -1 {
+			keyIndent = indent
+		}
+		if indent == keyIndent {
+			k, v := parseKV(trimmed)
+			cur[k] = v
+		}
+	}
+
+	var proxies []Proxy
+	var skipped []string
+	for _, m := range items {
+		p, err := clashProxy(m)
+		if err != nil {
+			skipped = append(skipped, err.Error())
+			continue
+		}
+		proxies = append(proxies, p)
+	}
+	return proxies, skipped, nil
+}
+
+// clashProxy turns one proxies: entry into a proxybench address.
+func clashProxy(m map[string]string) (Proxy, error) {
+	name := m["name"]
+	if m["server"] == "" || m["port"] == "" {
+		return Proxy{}, fmt.Errorf("%s: no server or port", name)
+	}
+	hostPort := net.JoinHostPort(m["server"], m["port"])
+	var userinfo string
+	if m["username"] != "" {
+		userinfo = url.UserPassword(m["username"], m["password"]).String() + "@"
+	}
+	switch m["type"] {
+	case "http":
+		scheme := "http"
+		if m["tls"] == "true" {
+			scheme = "https"
+		}
+		return Proxy{Address: scheme + "://" + userinfo + hostPort, Name: name}, nil
+	case "socks5":
+		return Proxy{Address: "socks5://" + userinfo + hostPort, Name: name}, nil
+	case "ss":
+		if m["cipher"] == "" {
+			return Proxy{}, fmt.Errorf("%s: ss proxy without cipher", name)
+		}
+		auth := base64.RawURLEncoding.EncodeToString([]byte(m["cipher"] + ":" + m["password"]))
+		return Proxy{Address: "ss://" + auth + "@" + hostPort, Name: name}, nil
+	}
+	return Proxy{}, fmt.Errorf("%s: Clash type %q is not supported", name, m["type"])
+}
+
+// parseKV splits "key: value" and unquotes both.
+func parseKV(s string) (string, string) {
+	k, v, _ := strings.Cut(s, ":")
+	return scalar(strings.TrimSpace(k)), scalar(strings.TrimSpace(v))
+}
+
+// scalar unquotes a YAML scalar or strips a trailing comment from a plain
+// one.
+func scalar(s string) string {
+	switch {
+	case strings.HasPrefix(s, `"`):
+		for i := 1; i < len(s); i++ {
+			if s[i] == '\\' {
+				i++
+			} else if s[i] == '"' {
+				if u, err := strconv.Unquote(s[:i+1]); err == nil {
+					return u
+				}
+				return s[1:i]
+			}
+		}
+	case strings.HasPrefix(s, "'"):
+		var b strings.Builder
+		for i := 1; i < len(s); i++ {
+			if s[i] == '\'' {
+				if i+1 < len(s) && s[i+1] == '\'' {
+					b.WriteByte('\'')
+					i++
+					continue
+				}
+				break
+			}
+			b.WriteByte(s[i])
+		}
+		return b.String()
+	}
+	if i := strings.Index(s, " #"); i != -1 {
+		s = s[:i]
+	}
+	return strings.TrimSpace(s)
+}
+
+// parseFlow reads a flow mapping such as "{name: a, type: http, port: 80}"
+// into m, skipping nested collections.
+func parseFlow(s string, m map[string]string) {
+	s = strings.TrimSpace(s)
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "{"), "}")
+	depth, quote, start := 0, byte(0), 0
+	for i := 0; i <= len(s); i++ {
+		if i < len(s) {
+			c := s[i]
+			switch {
+			case quote != 0:
+				if c == '\\' && quote == '"' {
+					i++
+				} else if c == quote {
+					quote = 0
+				}
+				continue
+			case c == '"' || c == '\'':
+				quote = c
+				continue
+			case c == '{' || c == '[':
+				depth++
+				continue
+			case c == '}' || c == ']':
+				depth--
+				continue
+			case c != ',' || depth > 0:
+				continue
+			}
+		}
+		if part := strings.TrimSpace(s[start:min(i, len(s))]); part != "" {
+			if k, v := parseKV(part); !strings.HasPrefix(v, "{") && !strings.HasPrefix(v, "[") {
+				m[k] = v
+			}
+		}
+		start = i + 1
+	}
+}
+
+// defaultPorts fill in the port of an address written without one.
+var defaultPorts = map[string]string{"http": "80", "https": "443", "socks5": "1080"}
+
+// writeClash writes proxies as a Clash proxies: list. Names must be unique
+// there, so missing ones are made from the address and repeats numbered.
+func writeClash(w io.Writer, proxies []Proxy) ([]string, error) {
+	var b strings.Builder
+	var skipped []string
+	seen := map[string]int{}
+	b.WriteString("proxies:\n")
+	for _, p := range proxies {
+		fields, err := clashFields(p)
+		if err != nil {
+			skipped = append(skipped, err.Error())
+			continue
+		}
+		name := p.Name
+		if name == "" {
+			name = fields[0][1] + " " + net.JoinHostPort(fields[1][1], fields[2][1])
+		}
+		if seen[name]++; seen[name] > 1 {
+			name = fmt.Sprintf("%s #%d", name, seen[name])
+		}
+		fmt.Fprintf(&b, "  - name: %s\n", strconv.Quote(name))
+		for _, f := range fields {
+			value := f[1]
+			if f[0] != "type" && f[0] != "port" && f[0] != "tls" {
+				value = strconv.Quote(value)
+			}
+			fmt.Fprintf(&b, "    %s: %s\n", f[0], value)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return skipped, err
+}
+
+// clashFields returns the type, server and port of p followed by its
+// protocol's options, as key/value pairs in the order they are written.
+func clashFields(p Proxy) ([][2]string, error) {
+	proto := checker.DetectProtocol(p.Address)
+	if proto == checker.ProtocolShadowsocks {
+		cfg, err := checker.ParseShadowsocksURL(p.Address)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", checker.Redact(p.Address), err)
+		}
+		return [][2]string{{"type", "ss"}, {"server", cfg.Host}, {"port", cfg.Port}, {"cipher", cfg.Method}, {"password", cfg.Password}}, nil
+	}
+	if proto == checker.ProtocolUnknown {
+		return nil, fmt.Errorf("%s: no scheme, so no Clash type", checker.Redact(p.Address))
+	}
+	u, err := url.Parse(p.Address)
+	if err != nil || u.Hostname() == "" {
+		return nil, fmt.Errorf("%s: unparseable address", checker.Redact(p.Address))
+	}
+	port := u.Port()
+	if port == "" {
+		port = defaultPorts[u.Scheme]
+	}
+	typ := string(proto)
+	if proto == checker.ProtocolHTTPS {
+		typ = "http"
+	}
+	fields := [][2]string{{"type", typ}, {"server", u.Hostname()}, {"port", port}}
+	if proto == checker.ProtocolHTTPS {
+		fields = append(fields, [2]string{"tls", "true"})
+	}
+	if u.User != nil {
+		pass, _ := u.User.Password()
+		fields = append(fields, [2]string{"username", u.User.Username()}, [2]string{"password", pass})
+	}
+	return fields, nil
+}
@@ -0,0 +1,207 @@
+package proxylist
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/drsoft-oss/proxybench/internal/checker"
+)
+
+// Entry is a cleaned address and the line it was read from.
+type Entry struct {
+	Line    int
+	Address string
+}
+
+// Problem is a list line that is not a usable proxy address.
+type Problem struct {
+	Line   int
+	Text   string // the line as read, credentials redacted
+	Reason string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("line %d: %s: %s", p.Line, p.Text, p.Reason)
+}
+
+// Lint reads a text list, one address per line, and cleans every entry with
+// Clean; blank lines and #-comments are skipped. Lines that cannot be
+// cleaned are returned as problems.
+func Lint(r io.Reader, scheme string) ([]Entry, []Problem, error) {
+	var entries []Entry
+	var problems []Problem
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<20)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if i := strings.Index(line, " #"); i != -1 {
+			line = strings.TrimSpace(line[:i])
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		addr, err := Clean(line, scheme)
+		if err != nil {
+			problems = append(problems, Problem{Line: n, Text: checker.Redact(line), Reason: err.Error()})
+			continue
+		}
+		entries = append(entries, Entry{Line: n, Address: addr})
+	}
+	return entries, problems, sc.Err()
+}
+
+// Clean checks one address and writes it the way proxybench expects:
+// surrounding space and a trailing slash trimmed, the scheme and host
+// lower-cased ("socks" spelled socks5), scheme added to a bare host:port
+// if scheme is not "", and the vendor form host:port:user:pass rewritten
+// as user:pass@host:port. A per-proxy override fragment (see
+// checker.ParseOverride) is kept as written. Unlike checker.Normalize it
+// keeps default ports, so the cleaned address reads as written.
+func Clean(address, scheme string) (string, error) {
+	address = strings.TrimSuffix(strings.TrimSpace(address), "/")
+	if strings.ContainsAny(address, " \t") {
+		return "", errors.New("contains whitespace")
+	}
+	s, rest, hasScheme := strings.Cut(address, "://")
+	if hasScheme {
+		known, ok := schemes[strings.ToLower(s)]
+		if !ok {
+			return "", fmt.Errorf("unsupported scheme %q", s)
+		}
+		s = known
+	} else {
+		s, rest = scheme, address
+	}
+	if s == string(checker.ProtocolShadowsocks) {
+		// The userinfo, or the whole legacy URI, is base64: keep its case.
+		out := s + "://" + rest
+		if _, err := checker.ParseShadowsocksURL(out); err != nil {
+			return "", err
+		}
+		return out, nil
+	}
+	if s == string(checker.ProtocolShadowsocksR) {
+		out := s + "://" + rest // all base64, as for ss
+		if _, err := checker.ParseShadowsocksRURL(out); err != nil {
+			return "", err
+		}
+		return out, nil
+	}
+	if s == string(checker.ProtocolHysteria2) {
+		out := s + "://" + rest // parameters such as obfs-password keep their case
+		if _, err := checker.ParseHysteria2URL(out); err != nil {
+			return "", err
+		}
+		return out, nil
+	}
+
+	fragment := ""
+	if i := strings.LastIndex(rest, "#"); i != -1 {
+		if _, err := checker.ParseOverride(rest[i+1:]); err == nil {
+			rest, fragment = strings.TrimSuffix(rest[:i], "/"), rest[i:]
+		}
+	}
+
+	userinfo, hostPort := "", rest
+	if h, p, u, pw, ok := vendorForm(rest); ok {
+		userinfo = url.UserPassword(u, pw).String()
+		hostPort = net.JoinHostPort(h, p)
+	} else if at := strings.LastIndex(rest, "@"); at != -1 {
+		userinfo, hostPort = rest[:at], rest[at+1:]
+		if userinfo == "" {
+			return "", errors.New("empty credentials before @")
+		}
+	}
+	if strings.ContainsAny(hostPort, "/?#") {
+		return "", errors.New("unexpected path, query or fragment")
+	}
+
+	host, port, err := net.SplitHostPort(hostPort)
+	switch {
+	case err == nil:
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return "", fmt.Errorf("port %q out of range", port)
+		}
+	case strings.Count(hostPort, ":") > 1 && !strings.HasPrefix(hostPort, "["):
+		return "", errors.New("IPv6 addresses need brackets, e.g. [2001:db8::1]:1080")
+	case !hasScheme:
+		return "", errors.New("missing port")
+	default:
+		host = strings.Trim(hostPort, "[]")
+	}
+	if err := checkHost(host); err != nil {
+		return "", err
+	}
+	host = strings.ToLower(host)
+	hostPort = host
+	if strings.Contains(host, ":") {
+		hostPort = "[" + host + "]"
+	}
+	if port != "" {
+		hostPort = net.JoinHostPort(host, port)
+	}
+
+	if userinfo != "" {
+		user, pass, hasPass := strings.Cut(userinfo, ":")
+		u, err1 := url.PathUnescape(user)
+		p, err2 := url.PathUnescape(pass)
+		if err1 != nil || err2 != nil {
+			return "", errors.New("bad percent-encoding in credentials")
+		}
+		if hasPass {
+			userinfo = url.UserPassword(u, p).String() + "@"
+		} else {
+			userinfo = url.User(u).String() + "@"
+		}
+	}
+
+	if s == "" {
+		return userinfo + hostPort + fragment, nil
+	}
+	return s + "://" + userinfo + hostPort + fragment, nil
+}
+
+// vendorForm splits the host:port:user:pass form proxy vendors export;
+// the password may contain any character, the host cannot hold an @.
+func vendorForm(s string) (host, port, user, pass string, ok bool) {
+	parts := strings.SplitN(s, ":", 4)
+	if len(parts) != 4 || parts[2] == "" || strings.Contains(parts[0], "@") {
+		return "", "", "", "", false
+	}
+	if _, err := strconv.Atoi(parts[1]); err != nil {
+		return "", "", "", "", false
+	}
+	return parts[0], parts[1], parts[2], parts[3], true
+}
+
+// checkHost accepts an IP address or an RFC 1123 host name.
+func checkHost(host string) error {
+	if host == "" {
+		return errors.New("missing host")
+	}
+	if net.ParseIP(host) != nil {
+		return nil
+	}
+	if len(host) > 253 {
+		return errors.New("host name too long")
+	}
+	for _, label := range strings.Split(strings.TrimSuffix(host, "."), ".") {
+		if label == "" || len(label) > 63 {
+			return fmt.Errorf("invalid host %q", host)
+		}
+		for i := 0; i < len(label); i++ {
+			c := label[i]
+			isAlnum := c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+			if !isAlnum && (c != '-' && c != '_' || i == 0 || i == len(label)-1) {
+				return fmt.Errorf("invalid host %q", host)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package proxylist
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/drsoft-oss/proxybench/internal/checker"
+)
+
+// pacMarker is the function every proxy auto-config file defines.
+const pacMarker = "FindProxyForURL"
+
+// pacSchemes maps PAC directive keywords to schemes. Browsers take a
+// bare SOCKS to mean SOCKS4; most servers speak both, and only SOCKS5
+// can be checked.
+var pacSchemes = map[string]string{
+	"PROXY":  "http",
+	"HTTP":   "http",
+	"HTTPS":  "https",
+	"SOCKS":  "socks5",
+	"SOCKS5": "socks5",
+}
+
+var (
+	// pacString is a JavaScript string expression: literals and
+	// identifiers joined with +.
+	pacString = regexp.MustCompile(`(?:"(?:[^"\\\n]|\\.)*"|'(?:[^'\\\n]|\\.)*'|[A-Za-z_$][\w$]*)(?:\s*\+\s*(?:"(?:[^"\\\n]|\\.)*"|'(?:[^'\\\n]|\\.)*'|[A-Za-z_$][\w$]*))*`)
+	// pacAssign is a string variable declaration or assignment.
+	pacAssign = regexp.MustCompile(`(?:\b(?:var|let|const)\s+)?\b([A-Za-z_$][\w$]*)\s*=\s*("|')`)
+	// pacComment is a // or /* */ comment.
+	pacComment = regexp.MustCompile(`/\*[\s\S]*?\*/|(?m)(^|[^:"'\\])//.*$`)
+)
+
+// readPAC extracts the proxies a proxy auto-config file can return. It
+// does not run the script: it finds the strings it builds, resolving
+// string variables and + concatenation, and reads the PROXY, HTTPS and
+// SOCKS directives in them. Proxies built at run time from anything else,
+// such as the client's address, are not found. Each proxy is listed once,
+// in order of first mention.
+func readPAC(data []byte) ([]Proxy, []string, error) {
+	if !bytes.Contains(data, []byte(pacMarker)) {
+		return nil, nil, fmt.Errorf("not a PAC file: no %s function", pacMarker)
+	}
+	src := pacComment.ReplaceAllString(string(data), "$1")
+	vars := map[string]string{}
+	for _, m := range pacAssign.FindAllStringSubmatchIndex(src, -1) {
+		expr := pacString.FindString(src[m[4]:])
+		vars[src[m[2]:m[3]]] = evalPAC(expr, vars)
+	}
+
+	var proxies []Proxy
+	var skipped []string
+	seen := map[string]bool{}
+	for _, expr := range pacString.FindAllString(src, -1) {
+		if !strings.ContainsAny(expr, `"'`) {
+			continue // a bare identifier or keyword
+		}
+		for _, directive := range strings.Split(evalPAC(expr, vars), ";") {
+			fields := strings.Fields(directive)
+			if len(fields) != 2 {
+				continue
+			}
+			keyword := strings.ToUpper(fields[0])
+			scheme, known := pacSchemes[keyword]
+			if _, _, err := net.SplitHostPort(fields[1]); err != nil || seen[keyword+" "+fields[1]] {
+				continue
+			}
+			seen[keyword+" "+fields[1]] = true
+			if !known {
+				if keyword == "SOCKS4" {
+					skipped = append(skipped, fmt.Sprintf("SOCKS4 %s: unsupported scheme", fields[1]))
+				}
+				continue
+			}
+			proxies = append(proxies, Proxy{Address: scheme + "://" + fields[1]})
+		}
+	}
+	return proxies, skipped, nil
+}
+
+// evalPAC evaluates a string expression matched by pacString: literals
+// are unquoted, known variables substituted and unknown ones left empty.
+func evalPAC(expr string, vars map[string]string) string {
+	var b strings.Builder
+	for rest := expr; rest != ""; {
+		rest = strings.TrimLeft(rest, " \t\r\n+")
+		if rest == "" {
+			break
+		}
+		switch q := rest[0]; q {
+		case '"', '\'':
+			i := 1
+			for ; i < len(rest) && rest[i] != q; i++ {
+				if rest[i] == '\\' && i+1 < len(rest) {
+					i++
+					b.WriteByte(rest[i])
+					continue
+				}
+				b.WriteByte(rest[i])
+			}
+			rest = rest[min(i+1, len(rest)):]
+		default:
+			end := strings.IndexFunc(rest, func(r rune) bool {
+				return !(r == '_' || r == '$' || r >= '0' && r <= '9' || r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z')
+			})
+			if end == -1 {
+				end = len(rest)
+			}
+			b.WriteString(vars[rest[:end]])
+			rest = rest[end:]
+		}
+	}
+	return b.String()
+}
+
+// writePAC writes a PAC file that sends everything through the proxies
+// in order, falling back to a direct connection. PAC files cannot carry
+// credentials or Shadowsocks, so such entries are left out.
+func writePAC(w io.Writer, proxies []Proxy) ([]string, error) {
+	var directives, skipped []string
+	for _, p := range proxies {
+		u, err := url.Parse(p.Address)
+		if err != nil || u.Host == "" {
+			skipped = append(skipped, fmt.Sprintf("%s: no scheme; PAC directives need one", checker.Redact(p.Address)))
+			continue
+		}
+		if u.User != nil {
+			skipped = append(skipped, fmt.Sprintf("%s: PAC files cannot carry credentials", checker.Redact(p.Address)))
+			continue
+		}
+		switch u.Scheme {
+		case "http":
+			directives = append(directives, "PROXY "+u.Host)
+		case "https":
+			directives = append(directives, "HTTPS "+u.Host)
+		case "socks5":
+			directives = append(directives, "SOCKS5 "+u.Host)
+		default:
+			skipped = append(skipped, fmt.Sprintf("%s: PAC files cannot express %s", checker.Redact(p.Address), u.Scheme))
+		}
+	}
+	directives = append(directives, "DIRECT")
+	_, err := fmt.Fprintf(w, "function %s(url, host) {\n  return %q;\n}\n", pacMarker, strings.Join(directives, "; "))
+	return skipped, err
+}
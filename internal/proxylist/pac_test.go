@@ -0,0 +1,70 @@
+package proxylist
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+const pacFile = `// Corporate proxy configuration
+var eu = "PROXY proxy-eu.corp.example:3128";
+var port = '8080';
+/* Disabled: "PROXY old.corp.example:3128" */
+function FindProxyForURL(url, host) {
+  if (isPlainHostName(host) || dnsDomainIs(host, ".corp.example"))
+    return "DIRECT";
+  if (shExpMatch(url, "http://intranet.example/*")) // "PROXY commented.example:1"
+    return eu;
+  if (dnsDomainIs(host, ".us.example"))
+    return "PROXY proxy-us.corp.example:" + port + "; " + eu + "; DIRECT";
+  if (isInNet(host, "10.0.0.0", "255.0.0.0"))
+    return 'SOCKS5 10.1.2.3:1080; SOCKS4 10.1.2.4:1080; SOCKS 10.1.2.5:1080';
+  return "HTTPS secure.corp.example:443; PROXY [2001:db8::1]:3128; DIRECT";
+}
+`
+
+func TestReadPAC(t *testing.T) {
+	if got := Detect([]byte(pacFile)); got != FormatPAC {
+		t.Fatalf("Detect = %q", got)
+	}
+	out, skipped, err := Read([]byte(pacFile), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Proxy{
+		{Address: "http://proxy-eu.corp.example:3128"},
+		{Address: "http://proxy-us.corp.example:8080"},
+		{Address: "socks5://10.1.2.3:1080"},
+		{Address: "socks5://10.1.2.5:1080"},
+		{Address: "https://secure.corp.example:443"},
+		{Address: "http://[2001:db8::1]:3128"},
+	}
+	if !reflect.DeepEqual(out, want) {
+		t.Errorf("out = %+v", out)
+	}
+	if len(skipped) != 1 {
+		t.Errorf("skipped = %q, want the SOCKS4 proxy", skipped)
+	}
+	if _, _, err := Read([]byte("PROXY a:1"), FormatPAC); err == nil {
+		t.Error("Read of a non-PAC file as pac succeeded")
+	}
+}
+
+func TestPACRoundTrip(t *testing.T) {
+	in := []Proxy{{Address: "http://1.2.3.4:3128"}, {Address: "socks5://5.6.7.8:1080"}, {Address: "https://proxy.example:443"}}
+	var buf bytes.Buffer
+	skipped, err := Write(&buf, append(in, Proxy{Address: "http://alice:x@9.9.9.9:80"}, Proxy{Address: "ss://YWVzOnB3@1.1.1.1:8388"}), FormatPAC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(skipped) != 2 {
+		t.Errorf("skipped = %q", skipped)
+	}
+	out, _, err := Read(buf.Bytes(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("out = %+v\n%s", out, buf.String())
+	}
+}
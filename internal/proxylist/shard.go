@@ -0,0 +1,49 @@
+package proxylist
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+
+	"github.com/drsoft-oss/proxybench/internal/checker"
+)
+
+// Shard is one of Count disjoint parts of a proxy list, numbered from 1,
+// for splitting a run across machines. Which part an address falls in
+// depends on the address alone, so every machine can read the same list
+// in any order and together they cover it exactly once. The zero Shard
+// holds every address.
+type Shard struct {
+	Index, Count int
+}
+
+// ParseShard parses "i/N", e.g. "2/5" for the second of five parts.
+func ParseShard(s string) (Shard, error) {
+	i, n, ok := strings.Cut(s, "/")
+	index, err1 := strconv.Atoi(strings.TrimSpace(i))
+	count, err2 := strconv.Atoi(strings.TrimSpace(n))
+	if !ok || err1 != nil || err2 != nil || count < 1 || index < 1 || index > count {
+		return Shard{}, fmt.Errorf("want i/N with 1 ≤ i ≤ N, e.g. 2/5, got %q", s)
+	}
+	return Shard{Index: index, Count: count}, nil
+}
+
+// Has reports whether address belongs to the shard. Addresses that
+// normalize to the same form (see checker.Normalize) share a shard, so
+// duplicates are still found within one.
+func (s Shard) Has(address string) bool {
+	if s.Count <= 1 {
+		return true
+	}
+	h := fnv.New32a()
+	h.Write([]byte(checker.Normalize(address))) //nolint:errcheck
+	return int(h.Sum32()%uint32(s.Count)) == s.Index-1
+}
+
+func (s Shard) String() string {
+	if s.Count == 0 {
+		return ""
+	}
+	return strconv.Itoa(s.Index) + "/" + strconv.Itoa(s.Count)
+}
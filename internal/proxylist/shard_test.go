@@ -0,0 +1,49 @@
+package proxylist
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParseShard(t *testing.T) {
+	s, err := ParseShard("2/5")
+	if err != nil || s != (Shard{Index: 2, Count: 5}) || s.String() != "2/5" {
+		t.Errorf("ParseShard(2/5) = %+v, %v", s, err)
+	}
+	for _, bad := range []string{"", "2", "0/5", "6/5", "1/0", "a/b"} {
+		if _, err := ParseShard(bad); err == nil {
+			t.Errorf("ParseShard(%q) accepted", bad)
+		}
+	}
+}
+
+func TestShard_Has(t *testing.T) {
+	const n = 4
+	seen := make([]int, n)
+	for a := range 400 {
+		address := fmt.Sprintf("http://10.0.%d.%d:8080", a/256, a%256)
+		owners := 0
+		for i := range n {
+			if (Shard{Index: i + 1, Count: n}).Has(address) {
+				owners++
+				seen[i]++
+			}
+		}
+		if owners != 1 {
+			t.Fatalf("%s is in %d shards", address, owners)
+		}
+	}
+	for i, c := range seen {
+		if c < 50 {
+			t.Errorf("shard %d/%d got only %d of 400 addresses", i+1, n, c)
+		}
+	}
+
+	s := Shard{Index: 1, Count: 3}
+	if s.Has("HTTP://Proxy.example:80/") != s.Has("http://proxy.example") {
+		t.Error("equal addresses in different shards")
+	}
+	if !(Shard{}).Has("http://proxy.example") {
+		t.Error("the zero Shard left an address out")
+	}
+}
@@ -0,0 +1,70 @@
+package proxylist
+
+import (
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/drsoft-oss/proxybench/internal/checker"
+)
+
+// Write writes proxies in format f. Entries the format cannot express,
+// such as a bare host:port in a subscription, are left out and described
+// in skipped. Text output carries addresses only; the other formats keep
+// names.
+func Write(w io.Writer, proxies []Proxy, f Format) (skipped []string, err error) {
+	switch f {
+	case FormatText:
+		var b strings.Builder
+		for _, p := range proxies {
+			b.WriteString(p.Address + "\n")
+		}
+		_, err = io.WriteString(w, b.String())
+		return nil, err
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"address", "protocol", "name"}) //nolint:errcheck
+		for _, p := range proxies {
+			cw.Write([]string{p.Address, p.Protocol(), p.Name}) //nolint:errcheck
+		}
+		cw.Flush()
+		return nil, cw.Error()
+	case FormatJSON:
+		type entry struct {
+			Address  string `json:"address"`
+			Protocol string `json:"protocol,omitempty"`
+			Name     string `json:"name,omitempty"`
+		}
+		entries := make([]entry, len(proxies))
+		for i, p := range proxies {
+			entries[i] = entry{p.Address, p.Protocol(), p.Name}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return nil, enc.Encode(entries)
+	case FormatClash:
+		return writeClash(w, proxies)
+	case FormatPAC:
+		return writePAC(w, proxies)
+	case FormatSubscription:
+		var b strings.Builder
+		for _, p := range proxies {
+			if p.Protocol() == "" {
+				skipped = append(skipped, fmt.Sprintf("%s: no scheme; subscriptions hold URIs", checker.Redact(p.Address)))
+				continue
+			}
+			b.WriteString(p.Address)
+			if p.Name != "" {
+				b.WriteString("#" + url.PathEscape(p.Name))
+			}
+			b.WriteString("\n")
+		}
+		_, err = fmt.Fprintln(w, base64.StdEncoding.EncodeToString([]byte(b.String())))
+		return skipped, err
+	}
+	return nil, fmt.Errorf("unknown list format %q", f)
+}
@@ -0,0 +1,126 @@
+// Package proxyproto writes HAProxy PROXY protocol headers (v1 text and v2
+// binary) so proxies behind a load balancer that requires them can be tested.
+package proxyproto
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+
+	"golang.org/x/net/proxy"
+)
+
+// Version selects the header format; the zero value sends none.
+type Version int
+
+const (
+	None Version = iota
+	V1
+	V2
+)
+
+// String returns "v1" or "v2", or "" for None.
+func (v Version) String() string {
+	switch v {
+	case V1:
+		return "v1"
+	case V2:
+		return "v2"
+	}
+	return ""
+}
+
+// ParseVersion accepts "v1", "v2" (or "1", "2") and "" / "off" for None.
+func ParseVersion(s string) (Version, error) {
+	switch s {
+	case "", "off", "none":
+		return None, nil
+	case "v1", "1":
+		return V1, nil
+	case "v2", "2":
+		return V2, nil
+	}
+	return None, fmt.Errorf("unknown PROXY protocol version %q (want v1 or v2)", s)
+}
+
+// v2Signature opens every v2 header.
+var v2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// Header returns the header announcing a TCP connection from src to dst.
+// Addresses that are not both TCP of the same family are sent as UNKNOWN
+// (v1) or UNSPEC (v2), which receivers accept without address info.
+func Header(v Version, src, dst net.Addr) []byte {
+	s, _ := src.(*net.TCPAddr)
+	d, _ := dst.(*net.TCPAddr)
+	var sIP, dIP net.IP
+	if s != nil && d != nil {
+		s4, d4 := s.IP.To4(), d.IP.To4()
+		switch {
+		case s4 != nil && d4 != nil:
+			sIP, dIP = s4, d4
+		case s4 == nil && d4 == nil:
+			sIP, dIP = s.IP.To16(), d.IP.To16()
+		}
+	}
+
+	switch v {
+	case V1:
+		if sIP == nil {
+			return []byte("PROXY UNKNOWN\r\n")
+		}
+		proto := "TCP4"
+		if len(sIP) == net.IPv6len {
+			proto = "TCP6"
+		}
+		return fmt.Appendf(nil, "PROXY %s %s %s %d %d\r\n", proto, sIP, dIP, s.Port, d.Port)
+	case V2:
+		var b bytes.Buffer
+		b.Write(v2Signature)
+		b.WriteByte(0x21) // version 2, command PROXY
+		switch len(sIP) {
+		case net.IPv4len:
+			b.WriteByte(0x11) // AF_INET, STREAM
+		case net.IPv6len:
+			b.WriteByte(0x21) // AF_INET6, STREAM
+		default:
+			b.WriteByte(0x00)                             // AF_UNSPEC
+			binary.Write(&b, binary.BigEndian, uint16(0)) //nolint:errcheck
+			return b.Bytes()
+		}
+		binary.Write(&b, binary.BigEndian, uint16(2*len(sIP)+4)) //nolint:errcheck
+		b.Write(sIP)
+		b.Write(dIP)
+		binary.Write(&b, binary.BigEndian, uint16(s.Port)) //nolint:errcheck
+		binary.Write(&b, binary.BigEndian, uint16(d.Port)) //nolint:errcheck
+		return b.Bytes()
+	}
+	return nil
+}
+
+// Dialer opens connections with Forward and starts each one with a header
+// describing it, as a load balancer in front of the proxy would.
+type Dialer struct {
+	Forward proxy.ContextDialer
+	Version Version
+}
+
+// DialContext dials addr and writes the header; with Version None it is a
+// plain Forward dial.
+func (d Dialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	conn, err := d.Forward.DialContext(ctx, network, addr)
+	if err != nil || d.Version == None {
+		return conn, err
+	}
+	if _, err := conn.Write(Header(d.Version, conn.LocalAddr(), conn.RemoteAddr())); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("write PROXY %s header: %w", d.Version, err)
+	}
+	return conn, nil
+}
+
+// Dial is DialContext without a context, for proxy.Dialer.
+func (d Dialer) Dial(network, addr string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, addr)
+}
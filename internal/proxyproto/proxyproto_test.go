@@ -0,0 +1,95 @@
+package proxyproto
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net"
+	"testing"
+
+	"golang.org/x/net/proxy"
+)
+
+func tcp(ip string, port int) *net.TCPAddr {
+	return &net.TCPAddr{IP: net.ParseIP(ip), Port: port}
+}
+
+func TestHeaderV1(t *testing.T) {
+	cases := []struct {
+		src, dst net.Addr
+		want     string
+	}{
+		{tcp("10.0.0.1", 5000), tcp("10.0.0.2", 1080), "PROXY TCP4 10.0.0.1 10.0.0.2 5000 1080\r\n"},
+		{tcp("::1", 5000), tcp("2001:db8::2", 8080), "PROXY TCP6 ::1 2001:db8::2 5000 8080\r\n"},
+		{tcp("10.0.0.1", 5000), tcp("::1", 80), "PROXY UNKNOWN\r\n"},
+		{&net.UnixAddr{Name: "/tmp/s"}, tcp("10.0.0.2", 80), "PROXY UNKNOWN\r\n"},
+	}
+	for _, tc := range cases {
+		if got := string(Header(V1, tc.src, tc.dst)); got != tc.want {
+			t.Errorf("Header(V1, %v, %v) = %q, want %q", tc.src, tc.dst, got, tc.want)
+		}
+	}
+}
+
+func TestHeaderV2(t *testing.T) {
+	h := Header(V2, tcp("10.0.0.1", 5000), tcp("10.0.0.2", 1080))
+	want := append(append([]byte{}, v2Signature...),
+		0x21, 0x11, 0, 12,
+		10, 0, 0, 1, 10, 0, 0, 2,
+		0x13, 0x88, 0x04, 0x38)
+	if !bytes.Equal(h, want) {
+		t.Errorf("v2 TCP4 header = % x\nwant            % x", h, want)
+	}
+
+	h = Header(V2, tcp("::1", 1), tcp("::2", 2))
+	if len(h) != 16+36 || h[13] != 0x21 || h[15] != 36 {
+		t.Errorf("v2 TCP6 header = % x", h)
+	}
+
+	h = Header(V2, tcp("10.0.0.1", 1), tcp("::2", 2))
+	if len(h) != 16 || h[13] != 0x00 {
+		t.Errorf("v2 mixed-family header = % x, want UNSPEC", h)
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	for in, want := range map[string]Version{"": None, "off": None, "v1": V1, "2": V2} {
+		if got, err := ParseVersion(in); err != nil || got != want {
+			t.Errorf("ParseVersion(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if _, err := ParseVersion("v3"); err == nil {
+		t.Error("ParseVersion(v3) should fail")
+	}
+}
+
+func TestDialerWritesHeader(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	got := make(chan string, 1)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		line, _ := bufio.NewReader(c).ReadString('\n')
+		got <- line
+	}()
+
+	d := Dialer{Forward: &net.Dialer{}, Version: V1}
+	conn, err := d.DialContext(context.Background(), "tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	want := string(Header(V1, conn.LocalAddr(), conn.RemoteAddr()))
+	if line := <-got; line != want {
+		t.Errorf("server read %q, want %q", line, want)
+	}
+}
+
+var _ proxy.Dialer = Dialer{}
@@ -0,0 +1,92 @@
+package resolver
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// doh queries a DNS-over-HTTPS endpoint using the RFC 8484 wire format.
+type doh struct {
+	url    string
+	client *http.Client
+}
+
+func newDoH(url string) *doh {
+	return &doh{url: url, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// lookup asks for A and AAAA records and returns IPv4 answers first.
+func (d *doh) lookup(ctx context.Context, host string) ([]netip.Addr, error) {
+	var addrs []netip.Addr
+	var firstErr error
+	for _, t := range []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA} {
+		got, err := d.query(ctx, host, t)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		addrs = append(addrs, got...)
+	}
+	if len(addrs) == 0 && firstErr != nil {
+		return nil, firstErr
+	}
+	return addrs, nil
+}
+
+func (d *doh) query(ctx context.Context, host string, qtype dnsmessage.Type) ([]netip.Addr, error) {
+	name, err := dnsmessage.NewName(host + ".")
+	if err != nil {
+		return nil, fmt.Errorf("doh: %w", err)
+	}
+	msg := dnsmessage.Message{
+		Header:    dnsmessage.Header{RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: name, Type: qtype, Class: dnsmessage.ClassINET}},
+	}
+	packed, err := msg.Pack()
+	if err != nil {
+		return nil, fmt.Errorf("doh: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.url, bytes.NewReader(packed))
+	if err != nil {
+		return nil, fmt.Errorf("doh: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("doh: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("doh: %s returned %s", d.url, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return nil, fmt.Errorf("doh: %w", err)
+	}
+
+	var reply dnsmessage.Message
+	if err := reply.Unpack(body); err != nil {
+		return nil, fmt.Errorf("doh: bad response: %w", err)
+	}
+	if reply.RCode != dnsmessage.RCodeSuccess {
+		return nil, fmt.Errorf("doh: %s: %s", host, reply.RCode)
+	}
+	var addrs []netip.Addr
+	for _, a := range reply.Answers {
+		switch rr := a.Body.(type) {
+		case *dnsmessage.AResource:
+			addrs = append(addrs, netip.AddrFrom4(rr.A))
+		case *dnsmessage.AAAAResource:
+			addrs = append(addrs, netip.AddrFrom16(rr.AAAA))
+		}
+	}
+	return addrs, nil
+}
@@ -0,0 +1,372 @@
+// Package resolver provides the process-wide DNS cache shared by every
+// proxybench dialer. Lookups go to the system resolver by default, or to a
+// plain DNS server, DNS-over-TLS or DNS-over-HTTPS endpoint chosen with
+// --resolver.
+package resolver
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/drsoft-oss/proxybench/internal/fdlimit"
+)
+
+// DefaultTTL is how long answers are cached when no TTL is configured.
+const DefaultTTL = 5 * time.Minute
+
+// DefaultFallbackDelay is the RFC 8305 recommended wait before racing the
+// next address while an earlier connection attempt is still pending.
+const DefaultFallbackDelay = 250 * time.Millisecond
+
+// Resolver caches host lookups for TTL and dials through the cache.
+// Concurrent lookups of the same host share a single query.
+type Resolver struct {
+	TTL           time.Duration
+	FallbackDelay time.Duration // zero = DefaultFallbackDelay
+	// Sockets bounds how many dialed connections may be open at once;
+	// dials wait for a free slot. Nil means unbounded.
+	Sockets *fdlimit.Budget
+
+	lookup func(ctx context.Context, host string) ([]netip.Addr, error)
+	dial   func(ctx context.Context, network, addr string) (net.Conn, error)
+	now    func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]entry
+	group singleflight.Group
+}
+
+type entry struct {
+	addrs   []netip.Addr
+	expires time.Time
+}
+
+// New returns a Resolver for spec:
+//
+//	"" or "system"                  the operating system resolver
+//	"8.8.8.8" or "udp://8.8.8.8:53" a plain DNS server over UDP
+//	"tcp://8.8.8.8"                 a plain DNS server over TCP
+//	"tls://1.1.1.1"                 DNS-over-TLS (port 853)
+//	"https://dns.google/dns-query"  DNS-over-HTTPS (RFC 8484)
+//
+// A ttl of zero uses DefaultTTL; a negative ttl disables caching.
+func New(spec string, ttl time.Duration) (*Resolver, error) {
+	lookup, err := parseSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+	if ttl == 0 {
+		ttl = DefaultTTL
+	}
+	var d net.Dialer
+	return &Resolver{
+		TTL:     ttl,
+		Sockets: fdlimit.Default(),
+		lookup:  lookup,
+		dial:    d.DialContext,
+		now:     time.Now,
+		cache:   make(map[string]entry),
+	}, nil
+}
+
+func parseSpec(spec string) (func(context.Context, string) ([]netip.Addr, error), error) {
+	if spec == "" || spec == "system" {
+		return netLookup(net.DefaultResolver), nil
+	}
+	if !strings.Contains(spec, "://") {
+		spec = "udp://" + spec
+	}
+	u, err := url.Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("resolver %q: %w", spec, err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("resolver %q: missing host", spec)
+	}
+
+	switch u.Scheme {
+	case "https":
+		return newDoH(u.String()).lookup, nil
+	case "udp", "tcp":
+		server := withPort(u.Host, "53")
+		network := u.Scheme
+		return netLookup(&net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, server)
+			},
+		}), nil
+	case "tls":
+		server := withPort(u.Host, "853")
+		d := &tls.Dialer{Config: &tls.Config{ServerName: u.Hostname()}}
+		// The Go resolver switches to TCP framing for non-packet conns.
+		return netLookup(&net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return d.DialContext(ctx, "tcp", server)
+			},
+		}), nil
+	default:
+		return nil, fmt.Errorf("resolver %q: unsupported scheme %q (want udp, tcp, tls or https)", spec, u.Scheme)
+	}
+}
+
+func netLookup(r *net.Resolver) func(context.Context, string) ([]netip.Addr, error) {
+	return func(ctx context.Context, host string) ([]netip.Addr, error) {
+		return r.LookupNetIP(ctx, "ip", host)
+	}
+}
+
+func withPort(host, port string) string {
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+	return net.JoinHostPort(strings.Trim(host, "[]"), port)
+}
+
+// LookupHost returns the addresses for host, serving from cache when fresh.
+// IP literals are returned as-is.
+func (r *Resolver) LookupHost(ctx context.Context, host string) ([]netip.Addr, error) {
+	if ip, err := netip.ParseAddr(strings.Trim(host, "[]")); err == nil {
+		return []netip.Addr{ip.Unmap()}, nil
+	}
+	key := strings.ToLower(strings.TrimSuffix(host, "."))
+
+	r.mu.Lock()
+	e, ok := r.cache[key]
+	r.mu.Unlock()
+	if ok && r.now().Before(e.expires) {
+		return e.addrs, nil
+	}
+
+	v, err, _ := r.group.Do(key, func() (any, error) {
+		addrs, err := r.lookup(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		if len(addrs) == 0 {
+			return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+		}
+		for i := range addrs {
+			addrs[i] = addrs[i].Unmap()
+		}
+		if r.TTL > 0 {
+			r.mu.Lock()
+			r.cache[key] = entry{addrs: addrs, expires: r.now().Add(r.TTL)}
+			r.mu.Unlock()
+		}
+		return addrs, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]netip.Addr), nil
+}
+
+// DialContext resolves addr's host through the cache and connects Happy
+// Eyeballs style (RFC 8305): addresses are interleaved by family, IPv6
+// first, and a further attempt starts every FallbackDelay (or as soon as
+// the previous one fails) until one connects. Use Family on the returned
+// conn's RemoteAddr to see which family won.
+func (r *Resolver) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := r.LookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []string
+	for _, ip := range interleave(addrs) {
+		if (strings.HasSuffix(network, "4") && !ip.Is4()) || (strings.HasSuffix(network, "6") && !ip.Is6()) {
+			continue
+		}
+		targets = append(targets, net.JoinHostPort(ip.String(), port))
+	}
+	switch len(targets) {
+	case 0:
+		return nil, &net.AddrError{Err: "no suitable address found", Addr: addr}
+	case 1:
+		return r.connect(ctx, network, targets[0])
+	}
+	return r.race(ctx, network, targets)
+}
+
+// race runs staggered connection attempts and returns the first success.
+// Losing attempts are cancelled, and any that connect anyway are closed.
+func (r *Resolver) race(ctx context.Context, network string, targets []string) (net.Conn, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type attempt struct {
+		conn net.Conn
+		err  error
+	}
+	results := make(chan attempt, len(targets))
+	next, pending := 0, 0
+	launch := func() {
+		target := targets[next]
+		next++
+		pending++
+		go func() {
+			conn, err := r.connect(ctx, network, target)
+			results <- attempt{conn, err}
+		}()
+	}
+	// Close connections from attempts still in flight once we return.
+	drain := func() {
+		go func(n int) {
+			for range n {
+				if a := <-results; a.conn != nil {
+					a.conn.Close()
+				}
+			}
+		}(pending)
+	}
+
+	delay := r.FallbackDelay
+	if delay <= 0 {
+		delay = DefaultFallbackDelay
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	launch()
+	var firstErr error
+	for pending > 0 {
+		select {
+		case a := <-results:
+			pending--
+			if a.err == nil {
+				drain()
+				return a.conn, nil
+			}
+			if firstErr == nil {
+				firstErr = a.err
+			}
+			if next < len(targets) {
+				launch()
+				timer.Reset(delay)
+			}
+		case <-timer.C:
+			if next < len(targets) {
+				launch()
+				timer.Reset(delay)
+			}
+		case <-ctx.Done():
+			drain()
+			if firstErr == nil {
+				firstErr = ctx.Err()
+			}
+			return nil, firstErr
+		}
+	}
+	return nil, firstErr
+}
+
+// connect dials one address, holding a Sockets slot for the conn's lifetime.
+func (r *Resolver) connect(ctx context.Context, network, addr string) (net.Conn, error) {
+	if r.Sockets == nil {
+		return r.dial(ctx, network, addr)
+	}
+	if err := r.Sockets.Acquire(ctx); err != nil {
+		return nil, err
+	}
+	conn, err := r.dial(ctx, network, addr)
+	if err != nil {
+		r.Sockets.Release()
+		return nil, err
+	}
+	return r.Sockets.Wrap(conn), nil
+}
+
+// interleave orders addresses for Happy Eyeballs: alternating families,
+// starting with IPv6, each family keeping the resolver's order.
+func interleave(addrs []netip.Addr) []netip.Addr {
+	var v6, v4 []netip.Addr
+	for _, a := range addrs {
+		if a.Is6() {
+			v6 = append(v6, a)
+		} else {
+			v4 = append(v4, a)
+		}
+	}
+	out := make([]netip.Addr, 0, len(addrs))
+	for i := 0; i < len(v6) || i < len(v4); i++ {
+		if i < len(v6) {
+			out = append(out, v6[i])
+		}
+		if i < len(v4) {
+			out = append(out, v4[i])
+		}
+	}
+	return out
+}
+
+// Family returns "ipv4" or "ipv6" for a connection address, or "" if it
+// is not an IP address.
+func Family(addr net.Addr) string {
+	ap, err := netip.ParseAddrPort(addr.String())
+	if err != nil {
+		return ""
+	}
+	if ap.Addr().Unmap().Is4() {
+		return "ipv4"
+	}
+	return "ipv6"
+}
+
+// Dial implements proxy.Dialer.
+func (r *Resolver) Dial(network, addr string) (net.Conn, error) {
+	return r.DialContext(context.Background(), network, addr)
+}
+
+// DialTimeout is like net.DialTimeout but resolves through the cache.
+func (r *Resolver) DialTimeout(network, addr string, timeout time.Duration) (net.Conn, error) {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return r.DialContext(ctx, network, addr)
+}
+
+var (
+	defaultMu sync.RWMutex
+	defaultR  *Resolver
+)
+
+func init() {
+	defaultR, _ = New("", DefaultTTL)
+}
+
+// Default returns the process-wide resolver used by checker and bench.
+func Default() *Resolver {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultR
+}
+
+// SetDefault replaces the process-wide resolver.
+func SetDefault(r *Resolver) {
+	if r == nil {
+		panic(errors.New("resolver: SetDefault(nil)"))
+	}
+	defaultMu.Lock()
+	defaultR = r
+	defaultMu.Unlock()
+}
@@ -0,0 +1,254 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/drsoft-oss/proxybench/internal/fdlimit"
+)
+
+func fakeResolver(ttl time.Duration, calls *atomic.Int32) *Resolver {
+	r, _ := New("", ttl)
+	r.lookup = func(ctx context.Context, host string) ([]netip.Addr, error) {
+		calls.Add(1)
+		return []netip.Addr{netip.MustParseAddr("127.0.0.1")}, nil
+	}
+	return r
+}
+
+func TestLookupHost_caches(t *testing.T) {
+	var calls atomic.Int32
+	r := fakeResolver(time.Minute, &calls)
+	now := time.Unix(1000, 0)
+	r.now = func() time.Time { return now }
+
+	for range 3 {
+		if _, err := r.LookupHost(context.Background(), "Example.com."); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls.Load() != 1 {
+		t.Errorf("lookups = %d, want 1", calls.Load())
+	}
+
+	now = now.Add(2 * time.Minute)
+	r.LookupHost(context.Background(), "example.com") //nolint:errcheck
+	if calls.Load() != 2 {
+		t.Errorf("lookups after expiry = %d, want 2", calls.Load())
+	}
+}
+
+func TestLookupHost_noCache(t *testing.T) {
+	var calls atomic.Int32
+	r := fakeResolver(-1, &calls)
+	r.LookupHost(context.Background(), "example.com") //nolint:errcheck
+	r.LookupHost(context.Background(), "example.com") //nolint:errcheck
+	if calls.Load() != 2 {
+		t.Errorf("lookups = %d, want 2 with caching disabled", calls.Load())
+	}
+}
+
+func TestLookupHost_ipLiteral(t *testing.T) {
+	var calls atomic.Int32
+	r := fakeResolver(time.Minute, &calls)
+	for _, h := range []string{"10.0.0.1", "[::1]"} {
+		addrs, err := r.LookupHost(context.Background(), h)
+		if err != nil || len(addrs) != 1 {
+			t.Errorf("LookupHost(%q) = %v, %v", h, addrs, err)
+		}
+	}
+	if calls.Load() != 0 {
+		t.Errorf("IP literals should not be looked up")
+	}
+}
+
+func TestDialContext(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		if c, err := ln.Accept(); err == nil {
+			c.Close()
+		}
+	}()
+
+	var calls atomic.Int32
+	r := fakeResolver(time.Minute, &calls)
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	conn, err := r.DialTimeout("tcp", net.JoinHostPort("proxy.test", port), time.Second)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	conn.Close()
+}
+
+func TestNew_specs(t *testing.T) {
+	for _, spec := range []string{"", "system", "8.8.8.8", "udp://8.8.8.8:53", "tcp://[2001:4860:4860::8888]", "tls://1.1.1.1", "https://dns.google/dns-query"} {
+		if _, err := New(spec, 0); err != nil {
+			t.Errorf("New(%q): %v", spec, err)
+		}
+	}
+	for _, spec := range []string{"ftp://1.1.1.1", "udp://"} {
+		if _, err := New(spec, 0); err == nil {
+			t.Errorf("New(%q) should fail", spec)
+		}
+	}
+}
+
+func TestDoH(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		var q dnsmessage.Message
+		if err := q.Unpack(body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		reply := dnsmessage.Message{
+			Header:    dnsmessage.Header{ID: q.ID, Response: true},
+			Questions: q.Questions,
+		}
+		rh := dnsmessage.ResourceHeader{Name: q.Questions[0].Name, Class: dnsmessage.ClassINET, TTL: 60}
+		switch q.Questions[0].Type {
+		case dnsmessage.TypeA:
+			rh.Type = dnsmessage.TypeA
+			reply.Answers = append(reply.Answers, dnsmessage.Resource{Header: rh, Body: &dnsmessage.AResource{A: [4]byte{192, 0, 2, 1}}})
+		case dnsmessage.TypeAAAA:
+			rh.Type = dnsmessage.TypeAAAA
+			reply.Answers = append(reply.Answers, dnsmessage.Resource{Header: rh, Body: &dnsmessage.AAAAResource{AAAA: netip.MustParseAddr("2001:db8::1").As16()}})
+		}
+		b, _ := reply.Pack()
+		w.Header().Set("Content-Type", "application/dns-message")
+		w.Write(b) //nolint:errcheck
+	}))
+	defer srv.Close()
+
+	r, err := New(srv.URL+"/dns-query", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := newDoH(srv.URL + "/dns-query")
+	d.client = srv.Client()
+	r.lookup = d.lookup
+	addrs, err := r.LookupHost(context.Background(), "proxy.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addrs) != 2 || addrs[0].String() != "192.0.2.1" || addrs[1].String() != "2001:db8::1" {
+		t.Errorf("addrs = %v", addrs)
+	}
+}
+
+func TestInterleave(t *testing.T) {
+	var in []netip.Addr
+	for _, s := range []string{"192.0.2.1", "192.0.2.2", "2001:db8::1", "192.0.2.3"} {
+		in = append(in, netip.MustParseAddr(s))
+	}
+	got := interleave(in)
+	want := []string{"2001:db8::1", "192.0.2.1", "192.0.2.2", "192.0.2.3"}
+	for i, a := range got {
+		if a.String() != want[i] {
+			t.Fatalf("interleave = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestDialContext_happyEyeballs(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+
+	r, _ := New("", time.Minute)
+	r.FallbackDelay = 20 * time.Millisecond
+	r.lookup = func(context.Context, string) ([]netip.Addr, error) {
+		return []netip.Addr{netip.MustParseAddr("127.0.0.1"), netip.MustParseAddr("2001:db8::1")}, nil
+	}
+	var d net.Dialer
+	blackholed := make(chan struct{})
+	r.dial = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if addr == net.JoinHostPort("2001:db8::1", port) {
+			<-ctx.Done() // never answers, like a broken IPv6 route
+			close(blackholed)
+			return nil, ctx.Err()
+		}
+		return d.DialContext(ctx, network, addr)
+	}
+
+	start := time.Now()
+	conn, err := r.DialContext(context.Background(), "tcp", net.JoinHostPort("dual.test", port))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	if Family(conn.RemoteAddr()) != "ipv4" {
+		t.Errorf("winner family = %q, want ipv4", Family(conn.RemoteAddr()))
+	}
+	if el := time.Since(start); el < r.FallbackDelay {
+		t.Errorf("IPv4 attempt started after %v, before the fallback delay", el)
+	}
+	select {
+	case <-blackholed:
+	case <-time.After(time.Second):
+		t.Error("losing IPv6 attempt was not cancelled")
+	}
+}
+
+func TestDialContext_allFail(t *testing.T) {
+	r, _ := New("", time.Minute)
+	r.lookup = func(context.Context, string) ([]netip.Addr, error) {
+		return []netip.Addr{netip.MustParseAddr("2001:db8::1"), netip.MustParseAddr("192.0.2.1")}, nil
+	}
+	r.dial = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: errors.New("refused")}
+	}
+	if _, err := r.DialContext(context.Background(), "tcp", "dual.test:80"); err == nil {
+		t.Error("expected error when every address fails")
+	}
+}
+
+func TestDialContext_socketBudget(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer c.Close()
+		}
+	}()
+
+	r, _ := New("", time.Minute)
+	r.Sockets = fdlimit.New(1)
+	first, err := r.DialTimeout("tcp", ln.Addr().String(), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.DialTimeout("tcp", ln.Addr().String(), 50*time.Millisecond); err == nil {
+		t.Fatal("second dial should wait for a free socket slot")
+	}
+	first.Close()
+	second, err := r.DialTimeout("tcp", ln.Addr().String(), time.Second)
+	if err != nil {
+		t.Fatalf("dial after Close: %v", err)
+	}
+	second.Close()
+}
@@ -0,0 +1,232 @@
+// Package rpc serves JSON-RPC 2.0 over a pair of streams, one message per
+// line, so that tools in any language can drive proxybench as a
+// long-lived subprocess through its stdin and stdout.
+//
+// Requests are handled concurrently and answered as they finish, so
+// responses may come out of order; match them by id. The built-in
+// "cancel" method, with params {"id": <id>}, aborts a request in flight,
+// which then fails with CodeCancelled. Handlers may send notifications
+// about a request in progress with Notify.
+package rpc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Error codes: the JSON-RPC 2.0 ones, and CodeCancelled (as in LSP) for
+// requests ended by "cancel".
+const (
+	CodeParse          = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternal       = -32603
+	CodeCancelled      = -32800
+)
+
+// maxLine bounds one request line.
+const maxLine = 16 << 20
+
+// Error is a JSON-RPC error object. A Handler returning one sends it as
+// is; any other error is sent as CodeInternal.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string { return e.Message }
+
+// InvalidParams wraps a problem with a request's params.
+func InvalidParams(err error) *Error {
+	return &Error{Code: CodeInvalidParams, Message: err.Error()}
+}
+
+// Handler answers one method. It should return soon after ctx is done.
+type Handler func(ctx context.Context, params json.RawMessage) (any, error)
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+type notification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params"`
+}
+
+// Server dispatches requests to the handlers registered with Handle.
+type Server struct {
+	methods map[string]Handler
+
+	wmu sync.Mutex
+	enc *json.Encoder
+
+	mu       sync.Mutex
+	inflight map[string]context.CancelFunc
+}
+
+// NewServer returns a Server with no methods but "cancel".
+func NewServer() *Server {
+	return &Server{methods: map[string]Handler{}, inflight: map[string]context.CancelFunc{}}
+}
+
+// Handle registers h for method.
+func (s *Server) Handle(method string, h Handler) {
+	s.methods[method] = h
+}
+
+// Serve reads requests from r until it ends or ctx is done, writing
+// responses and notifications to w. It returns once every request in
+// flight has been answered.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	s.enc = json.NewEncoder(w)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, maxLine)
+	for sc.Scan() {
+		line := sc.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var req request
+		if err := json.Unmarshal(line, &req); err != nil {
+			s.send(response{ID: json.RawMessage("null"), Error: &Error{Code: CodeParse, Message: err.Error()}})
+			continue
+		}
+		if req.JSONRPC != "2.0" || req.Method == "" {
+			s.send(response{ID: idOrNull(req.ID), Error: &Error{Code: CodeInvalidRequest, Message: `want "jsonrpc": "2.0" and a method`}})
+			continue
+		}
+		if req.Method == "cancel" {
+			result, err := s.cancel(req.Params)
+			s.reply(req, result, err)
+			continue
+		}
+		h, ok := s.methods[req.Method]
+		if !ok {
+			s.reply(req, nil, &Error{Code: CodeMethodNotFound, Message: fmt.Sprintf("no method %q", req.Method)})
+			continue
+		}
+		rctx, rcancel := context.WithCancel(context.WithValue(ctx, notifyKey{}, notifier{s, req.ID}))
+		key := string(req.ID)
+		if req.ID != nil {
+			s.mu.Lock()
+			s.inflight[key] = rcancel
+			s.mu.Unlock()
+		}
+		wg.Go(func() {
+			defer rcancel()
+			result, err := h(rctx, req.Params)
+			if req.ID != nil {
+				s.mu.Lock()
+				delete(s.inflight, key)
+				s.mu.Unlock()
+			}
+			if err != nil && rctx.Err() != nil && ctx.Err() == nil {
+				err = &Error{Code: CodeCancelled, Message: "request cancelled"}
+			}
+			s.reply(req, result, err)
+		})
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return sc.Err()
+}
+
+// cancel handles the built-in "cancel" method.
+func (s *Server) cancel(params json.RawMessage) (any, error) {
+	var p struct {
+		ID json.RawMessage `json:"id"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil || p.ID == nil {
+		return nil, &Error{Code: CodeInvalidParams, Message: `want {"id": <id of the request to cancel>}`}
+	}
+	s.mu.Lock()
+	stop, ok := s.inflight[string(p.ID)]
+	s.mu.Unlock()
+	if ok {
+		stop()
+	}
+	return map[string]bool{"cancelled": ok}, nil
+}
+
+// reply answers req, unless it is a notification.
+func (s *Server) reply(req request, result any, err error) {
+	if req.ID == nil {
+		return
+	}
+	resp := response{ID: req.ID, Result: result}
+	if err != nil {
+		var e *Error
+		if !errors.As(err, &e) {
+			e = &Error{Code: CodeInternal, Message: err.Error()}
+		}
+		resp.Result, resp.Error = nil, e
+	} else if result == nil {
+		resp.Result = struct{}{}
+	}
+	s.send(resp)
+}
+
+func (s *Server) send(v any) {
+	switch m := v.(type) {
+	case response:
+		m.JSONRPC = "2.0"
+		v = m
+	case notification:
+		m.JSONRPC = "2.0"
+		v = m
+	}
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+	s.enc.Encode(v) //nolint:errcheck // a closed stdout ends the session on the next read
+}
+
+type notifyKey struct{}
+
+type notifier struct {
+	s  *Server
+	id json.RawMessage
+}
+
+// Notify sends a notification for the request ctx belongs to, with params
+// {"id": <request id>, "result": v}. It does nothing for requests sent as
+// notifications themselves or outside a Handler.
+func Notify(ctx context.Context, method string, v any) {
+	n, ok := ctx.Value(notifyKey{}).(notifier)
+	if !ok || n.id == nil {
+		return
+	}
+	n.s.send(notification{Method: method, Params: struct {
+		ID     json.RawMessage `json:"id"`
+		Result any             `json:"result"`
+	}{n.id, v}})
+}
+
+func idOrNull(id json.RawMessage) json.RawMessage {
+	if id == nil {
+		return json.RawMessage("null")
+	}
+	return id
+}
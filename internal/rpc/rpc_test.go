@@ -0,0 +1,107 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// serve runs s over input and returns the messages it wrote, by id (and
+// notifications under their method).
+func serve(t *testing.T, s *Server, input io.Reader) map[string][]map[string]any {
+	t.Helper()
+	var out bytes.Buffer
+	if err := s.Serve(context.Background(), input, &out); err != nil {
+		t.Fatal(err)
+	}
+	got := map[string][]map[string]any{}
+	dec := json.NewDecoder(&out)
+	for {
+		var m map[string]any
+		if err := dec.Decode(&m); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if m["jsonrpc"] != "2.0" {
+			t.Errorf("message without jsonrpc 2.0: %v", m)
+		}
+		key, _ := json.Marshal(m["id"])
+		if method, ok := m["method"].(string); ok {
+			key = []byte(method)
+		}
+		got[string(key)] = append(got[string(key)], m)
+	}
+	return got
+}
+
+func TestServe(t *testing.T) {
+	s := NewServer()
+	s.Handle("echo", func(ctx context.Context, params json.RawMessage) (any, error) {
+		var p struct{ N int }
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, InvalidParams(err)
+		}
+		for i := range p.N {
+			Notify(ctx, "echo.progress", i)
+		}
+		return p.N, nil
+	})
+	s.Handle("fail", func(context.Context, json.RawMessage) (any, error) {
+		return nil, errors.New("boom")
+	})
+	got := serve(t, s, strings.NewReader(strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"echo","params":{"n":2}}`,
+		`{"jsonrpc":"2.0","id":"b","method":"echo","params":[]}`,
+		`{"jsonrpc":"2.0","id":3,"method":"nope"}`,
+		`{"jsonrpc":"2.0","id":4,"method":"fail"}`,
+		`{"jsonrpc":"2.0","method":"echo","params":{"n":5}}`,
+		`not json`,
+		`{"id":6,"method":"echo"}`,
+	}, "\n")))
+
+	if r := got["1"]; len(r) != 1 || r[0]["result"] != 2.0 {
+		t.Errorf("echo: %v", r)
+	}
+	if n := got["echo.progress"]; len(n) != 2 {
+		t.Errorf("progress notifications = %v; want 2, none for the notification request", n)
+	}
+	for id, code := range map[string]float64{`"b"`: CodeInvalidParams, "3": CodeMethodNotFound, "4": CodeInternal, "6": CodeInvalidRequest, "null": CodeParse} {
+		r := got[id]
+		if len(r) != 1 {
+			t.Errorf("id %s: %d responses", id, len(r))
+			continue
+		}
+		if e, _ := r[0]["error"].(map[string]any); e == nil || e["code"] != code {
+			t.Errorf("id %s: %v, want code %v", id, r[0], code)
+		}
+	}
+}
+
+func TestServe_cancel(t *testing.T) {
+	s := NewServer()
+	started := make(chan struct{})
+	s.Handle("wait", func(ctx context.Context, _ json.RawMessage) (any, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	in, w := io.Pipe()
+	go func() {
+		io.WriteString(w, `{"jsonrpc":"2.0","id":7,"method":"wait"}`+"\n") //nolint:errcheck
+		<-started
+		io.WriteString(w, `{"jsonrpc":"2.0","id":8,"method":"cancel","params":{"id":7}}`+"\n") //nolint:errcheck
+		w.Close()
+	}()
+	got := serve(t, s, in)
+	if e, _ := got["7"][0]["error"].(map[string]any); e == nil || e["code"] != float64(CodeCancelled) {
+		t.Errorf("cancelled request: %v", got["7"])
+	}
+	if r, _ := got["8"][0]["result"].(map[string]any); r["cancelled"] != true {
+		t.Errorf("cancel: %v", got["8"])
+	}
+}
@@ -0,0 +1,177 @@
+// Package scan finds open TCP ports across hosts and networks the user
+// owns, at a bounded rate, so that the listeners can be fingerprinted (see
+// checker.Identify) and checked.
+package scan
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/drsoft-oss/proxybench/internal/pool"
+	"github.com/drsoft-oss/proxybench/internal/resolver"
+)
+
+// Defaults and limits for a scan.
+const (
+	DefaultRate        = 20 // connection attempts per second
+	MaxRate            = 500
+	DefaultConcurrency = 10
+	DefaultMaxHosts    = 1024
+	DefaultTimeout     = 2 * time.Second
+)
+
+// ParsePorts parses a comma-separated list of ports and lo-hi ranges,
+// e.g. "1080,3128,8000-8010", into sorted, distinct port numbers.
+func ParsePorts(s string) ([]int, error) {
+	var ports []int
+	for _, f := range strings.Split(s, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		lo, hi, isRange := strings.Cut(f, "-")
+		from, err := parsePort(lo)
+		if err != nil {
+			return nil, err
+		}
+		to := from
+		if isRange {
+			if to, err = parsePort(hi); err != nil {
+				return nil, err
+			}
+			if to < from {
+				return nil, fmt.Errorf("port range %q ends before it starts", f)
+			}
+		}
+		for p := from; p <= to; p++ {
+			ports = append(ports, p)
+		}
+	}
+	if len(ports) == 0 {
+		return nil, fmt.Errorf("no ports in %q", s)
+	}
+	slices.Sort(ports)
+	return slices.Compact(ports), nil
+}
+
+func parsePort(s string) (int, error) {
+	p, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || p < 1 || p > 65535 {
+		return 0, fmt.Errorf("invalid port %q", s)
+	}
+	return p, nil
+}
+
+// Hosts expands targets, each a host name, an IP address or a CIDR
+// network, into the hosts to scan, refusing more than maxHosts in total so
+// that a mistyped prefix cannot turn into a sweep of someone else's
+// network. The network and broadcast addresses of IPv4 networks larger
+// than /31 are left out.
+func Hosts(targets []string, maxHosts int) ([]string, error) {
+	if maxHosts <= 0 {
+		maxHosts = DefaultMaxHosts
+	}
+	var hosts []string
+	for _, t := range targets {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+		if !strings.Contains(t, "/") {
+			hosts = append(hosts, strings.Trim(t, "[]"))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(t)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network %q: %w", t, err)
+		}
+		prefix = prefix.Masked()
+		hostBits := prefix.Addr().BitLen() - prefix.Bits()
+		if hostBits >= 31 || len(hosts)+1<<hostBits > maxHosts {
+			return nil, fmt.Errorf("%s has more than the %d hosts allowed; raise --max-hosts if you own them all", t, maxHosts)
+		}
+		trim := prefix.Addr().Is4() && hostBits > 1
+		for a := prefix.Addr(); prefix.Contains(a); a = a.Next() {
+			if trim && (a == prefix.Addr() || !prefix.Contains(a.Next())) {
+				continue
+			}
+			hosts = append(hosts, a.String())
+		}
+	}
+	if len(hosts) > maxHosts {
+		return nil, fmt.Errorf("%d hosts exceed the %d allowed; raise --max-hosts if you own them all", len(hosts), maxHosts)
+	}
+	return hosts, nil
+}
+
+// Options configures Open.
+type Options struct {
+	// Rate caps connection attempts per second across the whole scan,
+	// and Concurrency the attempts in flight.
+	Rate        float64
+	Concurrency int
+	Timeout     time.Duration
+}
+
+// Open tries a TCP connection to every port of every host, no faster than
+// opts.Rate, and calls found with each host:port that accepted, as they
+// are found. It returns how many endpoints were tried.
+func Open(ctx context.Context, hosts []string, ports []int, opts Options, found func(hostPort string)) int {
+	if opts.Rate <= 0 {
+		opts.Rate = DefaultRate
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	endpoints := make(chan string)
+	tried := 0
+	go func() {
+		defer close(endpoints)
+		tick := time.NewTicker(time.Duration(float64(time.Second) / opts.Rate))
+		defer tick.Stop()
+		for _, h := range hosts {
+			for _, p := range ports {
+				select {
+				case <-ctx.Done():
+					return
+				case <-tick.C:
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case endpoints <- net.JoinHostPort(h, strconv.Itoa(p)):
+					tried++
+				}
+			}
+		}
+	}()
+	pool.Unordered(endpoints, opts.Concurrency, func(hostPort string) string {
+		if !accepts(ctx, hostPort, opts.Timeout) {
+			return ""
+		}
+		return hostPort
+	}, func(hostPort string) {
+		if hostPort != "" {
+			found(hostPort)
+		}
+	})
+	return tried
+}
+
+// accepts reports whether hostPort accepts a TCP connection within timeout.
+func accepts(ctx context.Context, hostPort string, timeout time.Duration) bool {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	conn, err := resolver.Default().DialContext(ctx, "tcp", hostPort)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
@@ -0,0 +1,82 @@
+package scan
+
+import (
+	"net"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestParsePorts(t *testing.T) {
+	got, err := ParsePorts("8080, 1080,8000-8002,1080")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{1080, 8000, 8001, 8002, 8080}; !slices.Equal(got, want) {
+		t.Errorf("ParsePorts = %v, want %v", got, want)
+	}
+	for _, bad := range []string{"", "0", "65536", "http", "9-8"} {
+		if _, err := ParsePorts(bad); err == nil {
+			t.Errorf("ParsePorts(%q) accepted", bad)
+		}
+	}
+}
+
+func TestHosts(t *testing.T) {
+	got, err := Hosts([]string{"proxy.example.com", "192.0.2.9/30", "2001:db8::/127"}, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"proxy.example.com", "192.0.2.9", "192.0.2.10", "2001:db8::", "2001:db8::1"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Hosts = %q, want %q", got, want)
+	}
+	if _, err := Hosts([]string{"10.0.0.0/8"}, 1024); err == nil {
+		t.Error("a /8 was accepted with --max-hosts 1024")
+	}
+	if _, err := Hosts([]string{"10.0.0.0/24", "10.0.1.0/24"}, 300); err == nil {
+		t.Error("two /24s were accepted with --max-hosts 300")
+	}
+	if _, err := Hosts([]string{"10.0.0.0/33"}, 16); err == nil {
+		t.Error("an invalid prefix was accepted")
+	}
+}
+
+func TestOpen(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	open := ln.Addr().(*net.TCPAddr).Port
+
+	// A port that was just free is most likely still closed.
+	l2, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := l2.Addr().(*net.TCPAddr).Port
+	l2.Close()
+
+	var found []string
+	start := time.Now()
+	tried := Open(t.Context(), []string{"127.0.0.1"}, []int{closed, open}, Options{Rate: 10, Concurrency: 2, Timeout: time.Second}, func(hostPort string) {
+		found = append(found, hostPort)
+	})
+	if tried != 2 || !slices.Equal(found, []string{"127.0.0.1:" + strconv.Itoa(open)}) {
+		t.Errorf("tried %d, found %q", tried, found)
+	}
+	if d := time.Since(start); d < 150*time.Millisecond {
+		t.Errorf("two attempts at 10/s took %s", d)
+	}
+}
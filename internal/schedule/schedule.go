@@ -0,0 +1,118 @@
+package schedule
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Job is a named task run whenever its Cron expression matches.
+type Job struct {
+	Name string
+	Cron *Cron
+	Run  func(ctx context.Context) error
+}
+
+// Event reports a job run starting, finishing or being skipped.
+type Event struct {
+	Job  string
+	Kind EventKind
+	At   time.Time
+	Took time.Duration // EventDone
+	Err  error         // EventDone
+	Next time.Time     // the following run
+}
+
+// EventKind says what an Event reports.
+type EventKind int
+
+const (
+	EventStart EventKind = iota
+	EventDone
+	// EventSkipped is a run not started because the previous run of the
+	// job was still going.
+	EventSkipped
+)
+
+// Scheduler runs Jobs on their cron expressions. A job whose previous
+// run is still going when it comes due is skipped for that time, so runs
+// of one job never overlap; different jobs run independently.
+type Scheduler struct {
+	Jobs []Job
+	// Events, if set, is called for every run started, finished or
+	// skipped. Calls may come from several goroutines at once.
+	Events func(Event)
+	// now and after stand in for the clock in tests.
+	now   func() time.Time
+	after func(time.Duration) <-chan time.Time
+}
+
+// Run runs the jobs until ctx is done, then waits for runs in progress,
+// whose ctx is cancelled too, and returns ctx's error.
+func (s *Scheduler) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for _, j := range s.Jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.loop(ctx, j, &wg)
+		}()
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// loop waits for each time job comes due and starts a run, unless one is
+// still going.
+func (s *Scheduler) loop(ctx context.Context, job Job, wg *sync.WaitGroup) {
+	var running atomic.Bool
+	next := job.Cron.Next(s.clock())
+	for !next.IsZero() {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.wait(next.Sub(s.clock())):
+		}
+		if s.clock().Before(next) {
+			continue // woke early; wait out the rest
+		}
+		at := next
+		// Counted from now, not from at, so times missed while the host
+		// slept are not caught up one after another.
+		next = job.Cron.Next(s.clock())
+		if !running.CompareAndSwap(false, true) {
+			s.emit(Event{Job: job.Name, Kind: EventSkipped, At: at, Next: next})
+			continue
+		}
+		s.emit(Event{Job: job.Name, Kind: EventStart, At: at, Next: next})
+		wg.Add(1)
+		go func(next time.Time) {
+			defer wg.Done()
+			defer running.Store(false)
+			start := s.clock()
+			err := job.Run(ctx)
+			s.emit(Event{Job: job.Name, Kind: EventDone, At: at, Took: s.clock().Sub(start), Err: err, Next: next})
+		}(next)
+	}
+}
+
+func (s *Scheduler) emit(e Event) {
+	if s.Events != nil {
+		s.Events(e)
+	}
+}
+
+func (s *Scheduler) clock() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+	return time.Now()
+}
+
+func (s *Scheduler) wait(d time.Duration) <-chan time.Time {
+	if s.after != nil {
+		return s.after(d)
+	}
+	return time.After(d)
+}
@@ -0,0 +1,110 @@
+package schedule
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock advances by the waited duration whenever the scheduler waits,
+// so minutes pass instantly.
+type fakeClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) after(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	if d > 0 {
+		c.t = c.t.Add(d)
+	}
+	c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	ch <- c.now()
+	return ch
+}
+
+func TestScheduler_skipsOverlappingRuns(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2026, 3, 4, 10, 0, 30, 0, time.UTC)}
+	cron, _ := ParseCron("* * * * *")
+	release := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var mu sync.Mutex
+	var events []Event
+	s := &Scheduler{
+		Jobs: []Job{{Name: "slow", Cron: cron, Run: func(ctx context.Context) error {
+			<-release
+			return nil
+		}}},
+		Events: func(e Event) {
+			mu.Lock()
+			defer mu.Unlock()
+			events = append(events, e)
+			skipped := 0
+			for _, e := range events {
+				if e.Kind == EventSkipped {
+					skipped++
+				}
+			}
+			if skipped == 3 {
+				close(release)
+			}
+			if e.Kind == EventDone {
+				cancel()
+			}
+		},
+		now:   clock.now,
+		after: clock.after,
+	}
+	if err := s.Run(ctx); err != context.Canceled {
+		t.Fatalf("Run = %v, want context.Canceled", err)
+	}
+
+	var kinds []EventKind
+	for _, e := range events {
+		kinds = append(kinds, e.Kind)
+	}
+	want := []EventKind{EventStart, EventSkipped, EventSkipped, EventSkipped}
+	if len(kinds) < len(want)+1 {
+		t.Fatalf("events = %v, want %v then done", kinds, want)
+	}
+	for i, k := range want {
+		if kinds[i] != k {
+			t.Fatalf("events = %v, want %v then done", kinds, want)
+		}
+	}
+	if first := events[0].At; !first.Equal(time.Date(2026, 3, 4, 10, 1, 0, 0, time.UTC)) {
+		t.Errorf("first run at %v", first)
+	}
+	for i, e := range events[:4] {
+		if e.Job != "slow" || e.Next.Sub(e.At) != time.Minute {
+			t.Errorf("event %d = %+v", i, e)
+		}
+	}
+}
+
+func TestScheduler_stopsOnCancel(t *testing.T) {
+	cron, _ := ParseCron("0 0 1 1 *")
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- (&Scheduler{Jobs: []Job{{Name: "yearly", Cron: cron, Run: func(context.Context) error { return nil }}}}).Run(ctx)
+	}()
+	cancel()
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Errorf("Run = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after cancel")
+	}
+}
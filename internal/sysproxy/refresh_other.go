@@ -0,0 +1,7 @@
+//go:build !windows
+
+package sysproxy
+
+// settingsChanged has nothing to notify: networksetup and gsettings
+// announce their changes themselves.
+func settingsChanged() error { return nil }
@@ -0,0 +1,29 @@
+//go:build windows
+
+package sysproxy
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/windows"
+)
+
+var internetSetOption = windows.NewLazySystemDLL("wininet.dll").NewProc("InternetSetOptionW")
+
+// The InternetSetOption options that make WinINET reread its settings.
+const (
+	internetOptionRefresh         = 37
+	internetOptionSettingsChanged = 39
+)
+
+// settingsChanged tells running applications that use WinINET that its
+// registry values changed, as the proxy settings page does, so they
+// pick the proxy up without a restart.
+func settingsChanged() error {
+	for _, opt := range []uintptr{internetOptionSettingsChanged, internetOptionRefresh} {
+		if ok, _, err := internetSetOption.Call(0, opt, 0, 0); ok == 0 {
+			return fmt.Errorf("InternetSetOption: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+// Package sysproxy reads the proxy configuration of the machine: proxy
+// environment variables everywhere, plus the desktop settings of macOS
+// (scutil), Windows (WinINET, in the registry) and GNOME (gsettings).
+package sysproxy
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Entry is one configured proxy, or proxy auto-config URL.
+type Entry struct {
+	// Address is a proxy address with its scheme, or the PAC URL when
+	// PAC is set.
+	Address string
+	PAC     bool
+	// Source says where the setting came from, e.g. "HTTPS_PROXY" or
+	// "WinINET ProxyServer".
+	Source string
+}
+
+// Read returns the proxy settings in effect: environment variables first,
+// then the desktop settings, each address once. Desktop settings that
+// cannot be read (no GNOME, no scutil) are skipped silently.
+func Read(ctx context.Context) []Entry {
+	var out []Entry
+	seen := map[string]bool{}
+	for _, e := range append(fromEnv(os.Getenv), platform(ctx)...) {
+		if !seen[e.Address] {
+			seen[e.Address] = true
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// envVars are the proxy variables curl and most tools honour, lower case
+// taking precedence as it does for curl.
+var envVars = []string{"http_proxy", "HTTP_PROXY", "https_proxy", "HTTPS_PROXY", "all_proxy", "ALL_PROXY"}
+
+func fromEnv(getenv func(string) string) []Entry {
+	var out []Entry
+	for _, name := range envVars {
+		if addr := normalize(getenv(name), "http"); addr != "" {
+			out = append(out, Entry{Address: addr, Source: name})
+		}
+	}
+	return out
+}
+
+// normalize turns a configured proxy into an address proxybench checks:
+// a missing scheme becomes def, socks becomes socks5 (socks5h stays, for
+// its remote DNS), and anything else unsupported (such as socks4) is dropped.
+func normalize(v, def string) string {
+	v = strings.TrimSuffix(strings.TrimSpace(v), "/")
+	if v == "" {
+		return ""
+	}
+	scheme, rest, ok := strings.Cut(v, "://")
+	if !ok {
+		scheme, rest = def, v
+	}
+	switch strings.ToLower(scheme) {
+	case "http":
+		scheme = "http"
+	case "https":
+		scheme = "https"
+	case "socks", "socks5":
+		scheme = "socks5"
+	case "socks5h":
+		scheme = "socks5h"
+	default:
+		return ""
+	}
+	if _, _, err := net.SplitHostPort(rest[strings.LastIndex(rest, "@")+1:]); err != nil {
+		return ""
+	}
+	return scheme + "://" + rest
+}
+
+// hostPort joins a host and port from separate settings, or returns ""
+// if either is missing.
+func hostPort(host, port string) string {
+	host, port = strings.TrimSpace(host), strings.TrimSpace(port)
+	if n, err := strconv.Atoi(port); host == "" || err != nil || n <= 0 || n > 65535 {
+		return ""
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// parseScutil reads the top-level keys of `scutil --proxy` output. The
+// HTTPS proxy of system settings is the HTTP proxy used for https URLs,
+// so it is checked as http://.
+func parseScutil(out string) []Entry {
+	kv := map[string]string{}
+	depth := 0
+	sc := bufio.NewScanner(strings.NewReader(out))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if strings.HasSuffix(line, "}") {
+			depth--
+		}
+		if k, v, ok := strings.Cut(line, " : "); ok && depth == 1 {
+			kv[k] = v
+		}
+		if strings.HasSuffix(line, "{") {
+			depth++
+		}
+	}
+	var entries []Entry
+	for _, kind := range []struct{ key, scheme string }{{"HTTP", "http"}, {"HTTPS", "http"}, {"SOCKS", "socks5"}} {
+		if kv[kind.key+"Enable"] != "1" {
+			continue
+		}
+		if hp := hostPort(kv[kind.key+"Proxy"], kv[kind.key+"Port"]); hp != "" {
+			entries = append(entries, Entry{Address: kind.scheme + "://" + hp, Source: "scutil " + kind.key + "Proxy"})
+		}
+	}
+	if kv["ProxyAutoConfigEnable"] == "1" && kv["ProxyAutoConfigURLString"] != "" {
+		entries = append(entries, Entry{Address: kv["ProxyAutoConfigURLString"], PAC: true, Source: "scutil ProxyAutoConfigURLString"})
+	}
+	return entries
+}
+
+// parseProxyServer reads WinINET's ProxyServer value: one "host:port" for
+// every protocol, or "http=host:port;https=host:port;socks=host:port".
+func parseProxyServer(v string) []Entry {
+	var entries []Entry
+	for _, part := range strings.Split(v, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		proto, addr, ok := strings.Cut(part, "=")
+		if !ok {
+			proto, addr = "http", part
+		}
+		def := "http"
+		switch strings.ToLower(proto) {
+		case "http", "https":
+		case "socks":
+			def = "socks5"
+		default:
+			continue // ftp and others
+		}
+		if a := normalize(addr, def); a != "" {
+			entries = append(entries, Entry{Address: a, Source: "WinINET ProxyServer"})
+		}
+	}
+	return entries
+}
+
+// fromGSettings reads the org.gnome.system.proxy schemas through get,
+// which returns a key's unquoted value or "". GNOME's HTTPS proxy, like
+// macOS's, is the HTTP proxy used for https URLs.
+func fromGSettings(get func(schema, key string) string) []Entry {
+	const schema = "org.gnome.system.proxy"
+	var entries []Entry
+	switch get(schema, "mode") {
+	case "manual":
+		for _, kind := range []struct{ name, scheme string }{{"http", "http"}, {"https", "http"}, {"socks", "socks5"}} {
+			s := schema + "." + kind.name
+			if hp := hostPort(get(s, "host"), get(s, "port")); hp != "" {
+				entries = append(entries, Entry{Address: kind.scheme + "://" + hp, Source: "gsettings " + s})
+			}
+		}
+	case "auto":
+		if pac := get(schema, "autoconfig-url"); pac != "" {
+			entries = append(entries, Entry{Address: pac, PAC: true, Source: "gsettings " + schema + " autoconfig-url"})
+		}
+	}
+	return entries
+}
+
+// gsettingsValue unquotes a value printed by `gsettings get`.
+func gsettingsValue(out string) string {
+	return strings.Trim(strings.TrimSpace(out), "'")
+}
@@ -0,0 +1,18 @@
+//go:build darwin
+
+package sysproxy
+
+import (
+	"context"
+	"os/exec"
+)
+
+// platform reads the settings networksetup manages, for the active
+// network service, as scutil reports them.
+func platform(ctx context.Context) []Entry {
+	out, err := exec.CommandContext(ctx, "scutil", "--proxy").Output()
+	if err != nil {
+		return nil
+	}
+	return parseScutil(string(out))
+}
@@ -0,0 +1,21 @@
+//go:build linux
+
+package sysproxy
+
+import (
+	"context"
+	"os/exec"
+)
+
+// platform reads the GNOME proxy settings, when gsettings is installed.
+// Other desktops configure proxies through the environment.
+func platform(ctx context.Context) []Entry {
+	get := func(schema, key string) string {
+		out, err := exec.CommandContext(ctx, "gsettings", "get", schema, key).Output()
+		if err != nil {
+			return ""
+		}
+		return gsettingsValue(string(out))
+	}
+	return fromGSettings(get)
+}
@@ -0,0 +1,8 @@
+//go:build !darwin && !windows && !linux
+
+package sysproxy
+
+import "context"
+
+// platform has no desktop settings to read: only the environment counts.
+func platform(context.Context) []Entry { return nil }
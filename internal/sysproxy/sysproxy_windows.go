@@ -0,0 +1,30 @@
+//go:build windows
+
+package sysproxy
+
+import (
+	"context"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// platform reads the current user's WinINET settings, which the Windows
+// proxy settings page and Internet Options both write.
+func platform(context.Context) []Entry {
+	k, err := registry.OpenKey(registry.CURRENT_USER,
+		`Software\Microsoft\Windows\CurrentVersion\Internet Settings`, registry.QUERY_VALUE)
+	if err != nil {
+		return nil
+	}
+	defer k.Close()
+	var entries []Entry
+	if enabled, _, err := k.GetIntegerValue("ProxyEnable"); err == nil && enabled != 0 {
+		if server, _, err := k.GetStringValue("ProxyServer"); err == nil {
+			entries = parseProxyServer(server)
+		}
+	}
+	if pac, _, err := k.GetStringValue("AutoConfigURL"); err == nil && pac != "" {
+		entries = append(entries, Entry{Address: pac, PAC: true, Source: "WinINET AutoConfigURL"})
+	}
+	return entries
+}
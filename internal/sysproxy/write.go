@@ -0,0 +1,124 @@
+package sysproxy
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// DefaultService is the macOS network service Commands configures when
+// none is given.
+const DefaultService = "Wi-Fi"
+
+// winINETKey is the registry key the Windows proxy settings page writes.
+const winINETKey = `HKCU\Software\Microsoft\Windows\CurrentVersion\Internet Settings`
+
+// Commands returns the commands that make address the system proxy on
+// goos (a runtime.GOOS value): networksetup for service on macOS, the
+// WinINET registry values on Windows and gsettings on Linux (GNOME). An
+// http:// proxy serves both http and https URLs; a socks5:// proxy is set
+// as the SOCKS proxy alone. Desktop settings cannot hold credentials or
+// proxies reached over TLS, so such addresses are an error. Run tells
+// running applications the WinINET values changed; when the commands
+// are pasted instead, only applications started afterwards see them.
+func Commands(goos, address, service string) ([][]string, error) {
+	normalized := normalize(address, "http")
+	if normalized == "" {
+		return nil, errors.New("want an http:// or socks5:// proxy with a port")
+	}
+	scheme, hostport, _ := strings.Cut(normalized, "://")
+	if strings.Contains(hostport, "@") {
+		return nil, errors.New("system proxy settings cannot hold credentials")
+	}
+	if scheme == "https" {
+		return nil, errors.New("system proxy settings cannot reach a proxy over TLS")
+	}
+	host, port, _ := net.SplitHostPort(hostport)
+	socks := scheme == "socks5" || scheme == "socks5h"
+
+	switch goos {
+	case "darwin":
+		service = cmp.Or(service, DefaultService)
+		if socks {
+			return [][]string{{"networksetup", "-setsocksfirewallproxy", service, host, port}}, nil
+		}
+		return [][]string{
+			{"networksetup", "-setwebproxy", service, host, port},
+			{"networksetup", "-setsecurewebproxy", service, host, port},
+		}, nil
+	case "windows":
+		server := hostport
+		if socks {
+			server = "socks=" + hostport
+		}
+		return [][]string{
+			{"reg", "add", winINETKey, "/v", "ProxyServer", "/t", "REG_SZ", "/d", server, "/f"},
+			{"reg", "add", winINETKey, "/v", "ProxyEnable", "/t", "REG_DWORD", "/d", "1", "/f"},
+		}, nil
+	case "linux":
+		const schema = "org.gnome.system.proxy"
+		kinds := []string{"http", "https"}
+		if socks {
+			kinds = []string{"socks"}
+		}
+		var cmds [][]string
+		for _, k := range kinds {
+			cmds = append(cmds,
+				[]string{"gsettings", "set", schema + "." + k, "host", host},
+				[]string{"gsettings", "set", schema + "." + k, "port", port})
+		}
+		return append(cmds, []string{"gsettings", "set", schema, "mode", "manual"}), nil
+	}
+	return nil, fmt.Errorf("setting the system proxy is not supported on %s; set the proxy environment variables instead", goos)
+}
+
+// OffCommands returns the commands that turn the system proxy off on goos,
+// undoing Commands.
+func OffCommands(goos, service string) ([][]string, error) {
+	switch goos {
+	case "darwin":
+		service = cmp.Or(service, DefaultService)
+		return [][]string{
+			{"networksetup", "-setwebproxystate", service, "off"},
+			{"networksetup", "-setsecurewebproxystate", service, "off"},
+			{"networksetup", "-setsocksfirewallproxystate", service, "off"},
+		}, nil
+	case "windows":
+		return [][]string{{"reg", "add", winINETKey, "/v", "ProxyEnable", "/t", "REG_DWORD", "/d", "0", "/f"}}, nil
+	case "linux":
+		return [][]string{{"gsettings", "set", "org.gnome.system.proxy", "mode", "none"}}, nil
+	}
+	return nil, fmt.Errorf("setting the system proxy is not supported on %s; unset the proxy environment variables instead", goos)
+}
+
+// Run runs cmds in order, stopping at the first that fails, and on
+// Windows then tells running applications the settings changed.
+func Run(ctx context.Context, cmds [][]string) error {
+	for _, argv := range cmds {
+		c := exec.CommandContext(ctx, argv[0], argv[1:]...)
+		c.Stdout, c.Stderr = os.Stderr, os.Stderr
+		if err := c.Run(); err != nil {
+			return fmt.Errorf("%s: %w", Quote(argv), err)
+		}
+	}
+	return settingsChanged()
+}
+
+// Quote renders argv as one line to paste into a POSIX shell, or into
+// PowerShell for the Windows commands, single-quoting the arguments that
+// need it.
+func Quote(argv []string) string {
+	parts := make([]string, len(argv))
+	for i, a := range argv {
+		if a == "" || strings.ContainsAny(a, " \t\n\"'$&|;<>()*?`\\{}[]~#!") {
+			a = "'" + strings.ReplaceAll(a, "'", `'\''`) + "'"
+		}
+		parts[i] = a
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,219 @@
+// Package target serves a self-hosted test target for check and bench, so
+// proxies can be measured against infrastructure the user controls.
+package target
+
+import (
+	"cmp"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultMaxBytes caps /bytes/N responses.
+const DefaultMaxBytes = 1 << 30
+
+// block is repeated to fill /bytes/N responses. It is random so that a
+// proxy compressing responses cannot inflate its throughput.
+var block = func() []byte {
+	b := make([]byte, 64<<10)
+	rand.Read(b) //nolint:errcheck // never fails
+	return b
+}()
+
+// Server answers proxybench's test requests:
+//
+//	/           "ok", for latency samples (--test-url)
+//	/bytes/N    N bytes, with k, M or G suffixes (--payload-url, --mtu-url)
+//	/echo       JSON of the request as received: method, URL, headers, body size, origin
+//	/ip         the client's address as plain text (--exit-ip-url)
+//	/status/N   an empty response with status N
+//
+// Every endpoint accepts ?delay=DURATION (or milliseconds), added to Delay
+// before the response starts.
+type Server struct {
+	Delay    time.Duration
+	MaxBytes int64 // largest /bytes/N; zero means DefaultMaxBytes
+}
+
+// Handler returns the HTTP handler with all endpoints mounted.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/{$}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, "ok\n") //nolint:errcheck
+	})
+	mux.HandleFunc("/bytes/{n}", s.bytes)
+	mux.HandleFunc("/echo", echo)
+	mux.HandleFunc("/ip", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, clientIP(r)+"\n") //nolint:errcheck
+	})
+	mux.HandleFunc("/status/{code}", func(w http.ResponseWriter, r *http.Request) {
+		code, err := strconv.Atoi(r.PathValue("code"))
+		if err != nil || code < 200 || code > 599 {
+			http.Error(w, "status must be 200-599", http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(code)
+	})
+	return s.delayed(mux)
+}
+
+// delayed holds every response back by Delay plus the request's ?delay.
+func (s *Server) delayed(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		delay := s.Delay
+		if v := r.URL.Query().Get("delay"); v != "" {
+			d, err := parseDelay(v)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			delay += d
+		}
+		if delay > 0 {
+			select {
+			case <-time.After(delay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// parseDelay accepts a Go duration ("250ms", "1.5s") or bare milliseconds.
+func parseDelay(v string) (time.Duration, error) {
+	if ms, err := strconv.Atoi(v); err == nil && ms >= 0 {
+		return time.Duration(ms) * time.Millisecond, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("bad delay %q: want a duration like 250ms", v)
+	}
+	return d, nil
+}
+
+func (s *Server) bytes(w http.ResponseWriter, r *http.Request) {
+	n, err := ParseSize(r.PathValue("n"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if limit := cmp.Or(s.MaxBytes, DefaultMaxBytes); n > limit {
+		http.Error(w, fmt.Sprintf("at most %d bytes", limit), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.FormatInt(n, 10))
+	if r.Method == http.MethodHead {
+		return
+	}
+	for n > 0 {
+		chunk := block[:min(n, int64(len(block)))]
+		if _, err := w.Write(chunk); err != nil {
+			return
+		}
+		n -= int64(len(chunk))
+	}
+}
+
+// ParseSize parses a byte count with an optional k, M or G suffix
+// (powers of 1024), such as "512", "64k" or "10M".
+func ParseSize(v string) (int64, error) {
+	mult := int64(1)
+	switch {
+	case strings.HasSuffix(v, "k"), strings.HasSuffix(v, "K"):
+		mult = 1 << 10
+	case strings.HasSuffix(v, "M"):
+		mult = 1 << 20
+	case strings.HasSuffix(v, "G"):
+		mult = 1 << 30
+	}
+	if mult > 1 {
+		v = v[:len(v)-1]
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < 0 || n > (1<<62)/mult {
+		return 0, fmt.Errorf("bad size %q: want a byte count like 512, 64k or 10M", v)
+	}
+	return n * mult, nil
+}
+
+// Echo is the /echo response body.
+type Echo struct {
+	Method   string              `json:"method"`
+	URL      string              `json:"url"`
+	Proto    string              `json:"proto"`
+	Headers  map[string][]string `json:"headers"`
+	BodySize int64               `json:"body_size"`
+	// Origin is the address the request came from: the proxy's exit IP.
+	Origin string `json:"origin"`
+}
+
+func echo(w http.ResponseWriter, r *http.Request) {
+	n, _ := io.Copy(io.Discard, r.Body)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Echo{ //nolint:errcheck
+		Method:   r.Method,
+		URL:      r.URL.String(),
+		Proto:    r.Proto,
+		Headers:  r.Header,
+		BodySize: n,
+		Origin:   clientIP(r),
+	})
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// SelfSigned returns a certificate for hosts (names or IPs) valid for a
+// year, for serving https without a CA-issued certificate. Clients must
+// skip verification or trust it explicitly.
+func SelfSigned(hosts []string) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "proxybench target"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().AddDate(1, 0, 0),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else if h != "" {
+			tmpl.DNSNames = append(tmpl.DNSNames, h)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
@@ -0,0 +1,84 @@
+package target
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func get(t *testing.T, h http.Handler, path string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec
+}
+
+func TestRoot(t *testing.T) {
+	h := (&Server{}).Handler()
+	if rec := get(t, h, "/"); rec.Code != http.StatusOK || rec.Body.String() != "ok\n" {
+		t.Errorf("/ = %d %q, want 200 \"ok\\n\"", rec.Code, rec.Body)
+	}
+	if rec := get(t, h, "/nope"); rec.Code != http.StatusNotFound {
+		t.Errorf("/nope = %d, want 404", rec.Code)
+	}
+}
+
+func TestBytes(t *testing.T) {
+	h := (&Server{MaxBytes: 1 << 20}).Handler()
+	for path, want := range map[string]int{"/bytes/0": 0, "/bytes/100": 100, "/bytes/70k": 70 << 10, "/bytes/1M": 1 << 20} {
+		rec := get(t, h, path)
+		if rec.Code != http.StatusOK || rec.Body.Len() != want {
+			t.Errorf("%s = %d with %d bytes, want 200 with %d", path, rec.Code, rec.Body.Len(), want)
+		}
+	}
+	for _, path := range []string{"/bytes/2M", "/bytes/-1", "/bytes/ten"} {
+		if rec := get(t, h, path); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s = %d, want 400", path, rec.Code)
+		}
+	}
+}
+
+func TestEcho(t *testing.T) {
+	h := (&Server{}).Handler()
+	var e Echo
+	if err := json.Unmarshal(get(t, h, "/echo?x=1").Body.Bytes(), &e); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if e.Method != http.MethodGet || e.URL != "/echo?x=1" || e.Origin != "192.0.2.1" {
+		t.Errorf("echo = %+v", e)
+	}
+	if rec := get(t, h, "/ip"); rec.Body.String() != "192.0.2.1\n" {
+		t.Errorf("/ip = %q, want 192.0.2.1", rec.Body)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	h := (&Server{}).Handler()
+	if rec := get(t, h, "/status/503"); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("/status/503 = %d", rec.Code)
+	}
+	if rec := get(t, h, "/status/42"); rec.Code != http.StatusBadRequest {
+		t.Errorf("/status/42 = %d, want 400", rec.Code)
+	}
+}
+
+func TestDelay(t *testing.T) {
+	h := (&Server{Delay: 20 * time.Millisecond}).Handler()
+	start := time.Now()
+	get(t, h, "/?delay=30ms")
+	if d := time.Since(start); d < 50*time.Millisecond {
+		t.Errorf("response after %s, want at least 50ms", d)
+	}
+	if rec := get(t, h, "/?delay=soon"); rec.Code != http.StatusBadRequest {
+		t.Errorf("bad delay = %d, want 400", rec.Code)
+	}
+}
+
+func TestSelfSigned(t *testing.T) {
+	cert, err := SelfSigned([]string{"localhost", "127.0.0.1"})
+	if err != nil || len(cert.Certificate) != 1 {
+		t.Fatalf("SelfSigned = %v, %v", cert.Certificate, err)
+	}
+}
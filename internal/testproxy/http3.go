@@ -0,0 +1,38 @@
+package testproxy
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/quic-go/quic-go/http3"
+
+	"github.com/drsoft-oss/proxybench/internal/target"
+)
+
+// HTTP3 serves h over HTTP/3 on loopback until the test ends and returns
+// the server's host:port and its self-signed certificate, for 127.0.0.1.
+func HTTP3(t testing.TB, h http.Handler) (string, *x509.Certificate) {
+	t.Helper()
+	cert, err := target.SelfSigned([]string{"127.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	udp, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http3.Server{Handler: h, TLSConfig: http3.ConfigureTLSConfig(&tls.Config{Certificates: []tls.Certificate{cert}})}
+	go srv.Serve(udp) //nolint:errcheck
+	t.Cleanup(func() {
+		srv.Close()
+		udp.Close()
+	})
+	return udp.LocalAddr().String(), leaf
+}
@@ -0,0 +1,248 @@
+package testproxy
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+	"github.com/quic-go/quic-go/quicvarint"
+	"golang.org/x/crypto/blake2b"
+
+	"github.com/drsoft-oss/proxybench/internal/target"
+)
+
+// Hysteria2 configures a test Hysteria2 server; the zero value lets any
+// auth string in and connects wherever it is asked.
+type Hysteria2 struct {
+	// Auth, when set, is the only auth string let in; any other gets a
+	// 404, as from a server masquerading as a web site.
+	Auth string
+	// Obfs, when set, is the Salamander password every datagram is
+	// obfuscated with. Datagrams it cannot deobfuscate go unanswered.
+	Obfs string
+	// Target, when set, is where every TCP stream is relayed, whatever it
+	// names.
+	Target string
+
+	cert *x509.Certificate
+	addr string
+}
+
+// Start listens on loopback until the test ends and returns the server's
+// host:port. Its certificate is self-signed, for 127.0.0.1.
+func (s *Hysteria2) Start(t testing.TB) string {
+	t.Helper()
+	cert, err := target.SelfSigned([]string{"127.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.cert, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+		t.Fatal(err)
+	}
+	udp, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { udp.Close() })
+	var pc net.PacketConn = udp
+	if s.Obfs != "" {
+		pc = &salamanderConn{PacketConn: udp, password: s.Obfs}
+	}
+	ln, err := quic.Listen(pc, &tls.Config{Certificates: []tls.Certificate{cert}, NextProtos: []string{http3.NextProtoH3}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept(t.Context())
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	s.addr = udp.LocalAddr().String()
+	return s.addr
+}
+
+// Certificate returns the server's certificate once it has started.
+func (s *Hysteria2) Certificate() *x509.Certificate {
+	return s.cert
+}
+
+// serve answers the auth request on a connection's first stream and, if it
+// let the client in, relays every stream after it.
+func (s *Hysteria2) serve(conn *quic.Conn) {
+	ctx := conn.Context()
+	var authed bool
+	srv := &http3.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Host != "hysteria" || r.URL.Path != "/auth" ||
+			(s.Auth != "" && r.Header.Get("Hysteria-Auth") != s.Auth) {
+			http.NotFound(w, r)
+			return
+		}
+		authed = true
+		w.Header().Set("Hysteria-UDP", "false")
+		w.Header().Set("Hysteria-CC-RX", "auto")
+		w.WriteHeader(233)
+	})}
+	hc, err := srv.NewRawServerConn(conn)
+	if err != nil {
+		return
+	}
+	go func() {
+		for {
+			str, err := conn.AcceptUniStream(ctx)
+			if err != nil {
+				return
+			}
+			go hc.HandleUnidirectionalStream(str)
+		}
+	}()
+
+	str, err := conn.AcceptStream(ctx)
+	if err != nil {
+		return
+	}
+	hc.HandleRequestStream(str)
+	if !authed {
+		return // the client hangs up
+	}
+	for {
+		str, err := conn.AcceptStream(ctx)
+		if err != nil {
+			return
+		}
+		go s.relay(str)
+	}
+}
+
+// relay reads a TCP request from str, connects and copies both ways.
+func (s *Hysteria2) relay(str *quic.Stream) {
+	defer str.Close()
+	r := quicvarint.NewReader(str)
+	if frame, err := quicvarint.Read(r); err != nil || frame != 0x401 {
+		str.CancelRead(0)
+		return
+	}
+	addr, err := readVarBytes(r)
+	if err != nil {
+		return
+	}
+	if _, err := readVarBytes(r); err != nil { // padding
+		return
+	}
+
+	target := string(addr)
+	if s.Target != "" {
+		target = s.Target
+	}
+	up, err := net.Dial("tcp", target)
+	if err != nil {
+		msg := err.Error()
+		resp := quicvarint.Append([]byte{1}, uint64(len(msg)))
+		str.Write(quicvarint.Append(append(resp, msg...), 0)) //nolint:errcheck
+		return
+	}
+	defer up.Close()
+	if _, err := str.Write([]byte{0, 0, 0}); err != nil { // OK, no message, no padding
+		return
+	}
+	go func() {
+		io.Copy(up, str) //nolint:errcheck
+		up.(*net.TCPConn).CloseWrite()
+	}()
+	io.Copy(str, up) //nolint:errcheck
+}
+
+// readVarBytes reads a varint length and that many bytes.
+func readVarBytes(r quicvarint.Reader) ([]byte, error) {
+	n, err := quicvarint.Read(r)
+	if err != nil {
+		return nil, err
+	}
+	if n > 4096 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	b := make([]byte, n)
+	_, err = io.ReadFull(r, b)
+	return b, err
+}
+
+// salamanderConn obfuscates the datagrams of a Hysteria2 server with
+// Salamander: an 8-byte random salt, then the packet XORed with
+// BLAKE2b-256(password || salt).
+type salamanderConn struct {
+	net.PacketConn
+	password string
+	buf      []byte // quic-go reads from one goroutine
+}
+
+// SetReadBuffer lets quic-go size the socket's buffer without hiding the
+// obfuscation behind the *net.UDPConn fast path.
+func (c *salamanderConn) SetReadBuffer(bytes int) error {
+	return c.PacketConn.(*net.UDPConn).SetReadBuffer(bytes)
+}
+
+func (c *salamanderConn) SetWriteBuffer(bytes int) error {
+	return c.PacketConn.(*net.UDPConn).SetWriteBuffer(bytes)
+}
+
+func (c *salamanderConn) key(salt []byte) [32]byte {
+	return blake2b.Sum256(append([]byte(c.password), salt...))
+}
+
+func (c *salamanderConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	if c.buf == nil {
+		c.buf = make([]byte, 64<<10)
+	}
+	buf := c.buf
+	for {
+		n, from, err := c.PacketConn.ReadFrom(buf)
+		if err != nil {
+			return 0, from, err
+		}
+		if n <= 8 {
+			continue
+		}
+		key := c.key(buf[:8])
+		for i, b := range buf[8:n] {
+			buf[8+i] = b ^ key[i%len(key)]
+		}
+		return copy(p, buf[8:n]), from, nil
+	}
+}
+
+func (c *salamanderConn) WriteTo(p []byte, to net.Addr) (int, error) {
+	out := make([]byte, 8, 8+len(p))
+	rand.Read(out) //nolint:errcheck // never fails
+	key := c.key(out)
+	for i, b := range p {
+		out = append(out, b^key[i%len(key)])
+	}
+	if _, err := c.PacketConn.WriteTo(out, to); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Link returns a hy2:// link to the started server with auth, pinning its
+// certificate and adding the Salamander parameters when Obfs is set.
+func (s *Hysteria2) Link(auth string) string {
+	sum := sha256.Sum256(s.cert.Raw)
+	link := "hy2://" + auth + "@" + s.addr + "/?pinSHA256=" + hex.EncodeToString(sum[:])
+	if s.Obfs != "" {
+		link += "&obfs=salamander&obfs-password=" + s.Obfs
+	}
+	return link
+}
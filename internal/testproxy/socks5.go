@@ -0,0 +1,358 @@
+// Package testproxy runs configurable SOCKS5 and Hysteria2 servers on
+// loopback for the checker and bench tests.
+package testproxy
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// SOCKS5 authentication methods.
+const (
+	MethodNone     = 0x00
+	MethodGSSAPI   = 0x01
+	MethodUserPass = 0x02
+	methodNoMatch  = 0xff
+)
+
+// SOCKS5 commands.
+const (
+	CmdConnect      = 0x01
+	CmdBind         = 0x02
+	CmdUDPAssociate = 0x03
+)
+
+// SOCKS5 address types.
+const (
+	AddrIPv4   = 0x01
+	AddrDomain = 0x03
+	AddrIPv6   = 0x04
+)
+
+// Request is a SOCKS5 request as the server read it.
+type Request struct {
+	Cmd      byte
+	AddrType byte
+	Addr     string // host:port as named, a hostname for AddrDomain
+}
+
+// SOCKS5 configures a test server; the zero value is an open proxy that
+// connects wherever it is asked and echoes UDP.
+type SOCKS5 struct {
+	// Methods are the authentication methods the server accepts: it
+	// picks the first one a client offers that is listed, or answers
+	// "no acceptable methods". Nil accepts MethodNone only.
+	Methods []byte
+	// User, when set, is the only username MethodUserPass lets in.
+	User string
+	// Reply returns the reply code for a request; non-zero codes are
+	// sent and the connection closed. Nil grants every request.
+	Reply func(Request) byte
+	// Target, when set, is where every CONNECT is relayed, whatever it
+	// names.
+	Target string
+	// UDP answers each datagram payload sent to the UDP ASSOCIATE relay;
+	// a nil answer drops it. Nil echoes every payload.
+	UDP func(payload []byte) []byte
+	// RelayUDP, when set, relays datagrams to the address in their
+	// header, as a real server does, instead of answering them with UDP.
+	RelayUDP bool
+
+	ln       net.Listener
+	relay    *net.UDPConn
+	mu       sync.Mutex
+	requests []Request
+	peers    map[string]*net.UDPConn // RelayUDP sockets by client and target
+}
+
+// Start listens on loopback until the test ends and returns the server's
+// host:port. A granted BIND is answered with that address; a granted UDP
+// ASSOCIATE with the relay's port on the unspecified address, as many
+// servers answer.
+func (s *SOCKS5) Start(t testing.TB) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	relay, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { relay.Close() })
+	s.ln, s.relay = ln, relay
+	t.Cleanup(func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for _, peer := range s.peers {
+			peer.Close()
+		}
+	})
+
+	go s.serveUDP()
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(c)
+		}
+	}()
+	return ln.Addr().String()
+}
+
+// Requests returns the requests read so far, in order.
+func (s *SOCKS5) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.requests)
+}
+
+func (s *SOCKS5) serve(c net.Conn) {
+	defer c.Close()
+	if !s.authenticate(c) {
+		return
+	}
+	req, err := readRequest(c)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	s.mu.Unlock()
+
+	if s.Reply != nil {
+		if rep := s.Reply(req); rep != 0 {
+			c.Write(reply(rep, nil)) //nolint:errcheck
+			return
+		}
+	}
+	switch req.Cmd {
+	case CmdConnect:
+		target := req.Addr
+		if s.Target != "" {
+			target = s.Target
+		}
+		up, err := net.Dial("tcp", target)
+		if err != nil {
+			c.Write(reply(0x05, nil)) //nolint:errcheck // connection refused
+			return
+		}
+		defer up.Close()
+		c.Write(reply(0, up.LocalAddr().(*net.TCPAddr))) //nolint:errcheck
+		go io.Copy(up, c)                                //nolint:errcheck
+		io.Copy(c, up)                                   //nolint:errcheck
+	case CmdBind:
+		c.Write(reply(0, s.ln.Addr().(*net.TCPAddr))) //nolint:errcheck
+		io.Copy(io.Discard, c)                        //nolint:errcheck
+	case CmdUDPAssociate:
+		port := s.relay.LocalAddr().(*net.UDPAddr).Port
+		c.Write(reply(0, &net.TCPAddr{IP: net.IPv4zero, Port: port})) //nolint:errcheck
+		io.Copy(io.Discard, c)                                        //nolint:errcheck
+	default:
+		c.Write(reply(0x07, nil)) //nolint:errcheck // command not supported
+	}
+}
+
+// authenticate negotiates the method and, for MethodUserPass, checks the
+// username.
+func (s *SOCKS5) authenticate(c net.Conn) bool {
+	head := make([]byte, 2)
+	if _, err := io.ReadFull(c, head); err != nil || head[0] != 5 {
+		return false
+	}
+	offered := make([]byte, head[1])
+	if _, err := io.ReadFull(c, offered); err != nil {
+		return false
+	}
+	accept := s.Methods
+	if accept == nil {
+		accept = []byte{MethodNone}
+	}
+	chosen := byte(methodNoMatch)
+	for _, m := range offered {
+		if slices.Contains(accept, m) {
+			chosen = m
+			break
+		}
+	}
+	c.Write([]byte{5, chosen}) //nolint:errcheck
+	switch chosen {
+	case MethodNone:
+		return true
+	case MethodUserPass:
+		b := make([]byte, 2)
+		if _, err := io.ReadFull(c, b); err != nil {
+			return false
+		}
+		user := make([]byte, b[1])
+		io.ReadFull(c, user)               //nolint:errcheck
+		io.ReadFull(c, b[:1])              //nolint:errcheck
+		io.ReadFull(c, make([]byte, b[0])) //nolint:errcheck
+		ok := s.User == "" || string(user) == s.User
+		status := byte(1)
+		if ok {
+			status = 0
+		}
+		c.Write([]byte{1, status}) //nolint:errcheck
+		return ok
+	}
+	return false
+}
+
+func readRequest(c net.Conn) (Request, error) {
+	head := make([]byte, 4)
+	if _, err := io.ReadFull(c, head); err != nil {
+		return Request{}, err
+	}
+	req := Request{Cmd: head[1], AddrType: head[3]}
+	var host string
+	switch req.AddrType {
+	case AddrIPv4, AddrIPv6:
+		ip := make(net.IP, 4)
+		if req.AddrType == AddrIPv6 {
+			ip = make(net.IP, 16)
+		}
+		if _, err := io.ReadFull(c, ip); err != nil {
+			return Request{}, err
+		}
+		host = ip.String()
+	case AddrDomain:
+		n := make([]byte, 1)
+		if _, err := io.ReadFull(c, n); err != nil {
+			return Request{}, err
+		}
+		name := make([]byte, n[0])
+		if _, err := io.ReadFull(c, name); err != nil {
+			return Request{}, err
+		}
+		host = string(name)
+	}
+	port := make([]byte, 2)
+	if _, err := io.ReadFull(c, port); err != nil {
+		return Request{}, err
+	}
+	req.Addr = net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port))))
+	return req, nil
+}
+
+// reply encodes a reply with an IPv4 bound address; nil sends 0.0.0.0:0.
+func reply(rep byte, bound *net.TCPAddr) []byte {
+	b := []byte{5, rep, 0, AddrIPv4, 0, 0, 0, 0, 0, 0}
+	if bound != nil {
+		copy(b[4:8], bound.IP.To4())
+		binary.BigEndian.PutUint16(b[8:], uint16(bound.Port))
+	}
+	return b
+}
+
+// serveUDP answers datagrams on the relay, keeping each one's SOCKS5 UDP
+// header on the answer.
+func (s *SOCKS5) serveUDP() {
+	buf := make([]byte, 64<<10)
+	for {
+		n, from, err := s.relay.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		head := udpHeaderLen(buf[:n])
+		if head == 0 {
+			continue
+		}
+		if s.RelayUDP {
+			s.relayUDP(from, slices.Clone(buf[:head]), slices.Clone(buf[head:n]))
+			continue
+		}
+		answer := slices.Clone(buf[head:n])
+		if s.UDP != nil {
+			if answer = s.UDP(answer); answer == nil {
+				continue
+			}
+		}
+		s.relay.WriteToUDP(append(slices.Clone(buf[:head]), answer...), from) //nolint:errcheck
+	}
+}
+
+// relayUDP sends payload to the target named in head from a socket of
+// from's own, whose replies go back to from under head.
+func (s *SOCKS5) relayUDP(from *net.UDPAddr, head, payload []byte) {
+	target := udpHeaderAddr(head)
+	if target == nil {
+		return
+	}
+	key := from.String() + " " + target.String()
+	s.mu.Lock()
+	peer := s.peers[key]
+	if peer == nil {
+		var err error
+		if peer, err = net.DialUDP("udp", nil, target); err != nil {
+			s.mu.Unlock()
+			return
+		}
+		if s.peers == nil {
+			s.peers = map[string]*net.UDPConn{}
+		}
+		s.peers[key] = peer
+		go func() {
+			defer peer.Close()
+			buf := make([]byte, 64<<10)
+			for {
+				n, err := peer.Read(buf)
+				if err != nil {
+					return
+				}
+				if _, err := s.relay.WriteToUDP(append(slices.Clone(head), buf[:n]...), from); err != nil {
+					return
+				}
+			}
+		}()
+	}
+	s.mu.Unlock()
+	peer.Write(payload) //nolint:errcheck
+}
+
+// udpHeaderAddr returns the IP address in a SOCKS5 UDP request header, or
+// nil for a hostname.
+func udpHeaderAddr(head []byte) *net.UDPAddr {
+	var ip net.IP
+	switch head[3] {
+	case AddrIPv4:
+		ip = net.IP(head[4:8])
+	case AddrIPv6:
+		ip = net.IP(head[4:20])
+	default:
+		return nil
+	}
+	return &net.UDPAddr{IP: ip, Port: int(binary.BigEndian.Uint16(head[len(head)-2:]))}
+}
+
+// udpHeaderLen returns the length of the SOCKS5 UDP request header at the
+// start of b, or 0 if it is malformed.
+func udpHeaderLen(b []byte) int {
+	if len(b) < 4 {
+		return 0
+	}
+	n := 0
+	switch b[3] {
+	case AddrIPv4:
+		n = 4 + 4 + 2
+	case AddrIPv6:
+		n = 4 + 16 + 2
+	case AddrDomain:
+		if len(b) < 5 {
+			return 0
+		}
+		n = 4 + 1 + int(b[4]) + 2
+	}
+	if n == 0 || n > len(b) {
+		return 0
+	}
+	return n
+}
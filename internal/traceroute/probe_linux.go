@@ -0,0 +1,107 @@
+//go:build linux
+
+package traceroute
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// probe sends one TCP SYN to target with the given TTL and waits up to
+// wait for the target to answer (SYN-ACK or reset) or for a router's ICMP
+// error, which IP_RECVERR queues on the socket with the router's address.
+func probe(ctx context.Context, target netip.AddrPort, ttl int, wait time.Duration) (reply, error) {
+	family, level, ttlOpt, errOpt := unix.AF_INET, unix.IPPROTO_IP, unix.IP_TTL, unix.IP_RECVERR
+	var sa unix.Sockaddr = &unix.SockaddrInet4{Port: int(target.Port()), Addr: target.Addr().As4()}
+	if !target.Addr().Unmap().Is4() {
+		family, level, ttlOpt, errOpt = unix.AF_INET6, unix.IPPROTO_IPV6, unix.IPV6_UNICAST_HOPS, unix.IPV6_RECVERR
+		sa = &unix.SockaddrInet6{Port: int(target.Port()), Addr: target.Addr().As16()}
+	}
+	fd, err := unix.Socket(family, unix.SOCK_STREAM|unix.SOCK_NONBLOCK|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return reply{}, err
+	}
+	defer unix.Close(fd)
+	if err := unix.SetsockoptInt(fd, level, ttlOpt, ttl); err != nil {
+		return reply{}, err
+	}
+	if err := unix.SetsockoptInt(fd, level, errOpt, 1); err != nil {
+		return reply{}, err
+	}
+
+	start := time.Now()
+	if err := unix.Connect(fd, sa); err != nil && !errors.Is(err, unix.EINPROGRESS) {
+		return reply{}, err
+	}
+	deadline := start.Add(wait)
+	for ctx.Err() == nil {
+		left := time.Until(deadline)
+		if left <= 0 {
+			return reply{}, nil
+		}
+		fds := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLOUT}}
+		n, err := unix.Poll(fds, int(min(left, 100*time.Millisecond)/time.Millisecond)+1)
+		if errors.Is(err, unix.EINTR) || err == nil && n == 0 {
+			continue
+		}
+		if err != nil {
+			return reply{}, err
+		}
+		rtt := time.Since(start)
+		if from, ok := icmpSource(fd, family); ok {
+			return reply{from: from, rtt: rtt}, nil
+		}
+		soErr, err := unix.GetsockoptInt(fd, unix.SOL_SOCKET, unix.SO_ERROR)
+		if err != nil {
+			return reply{}, err
+		}
+		switch unix.Errno(soErr) {
+		case 0, unix.ECONNREFUSED:
+			return reply{from: target.Addr(), rtt: rtt, reached: true}, nil
+		default:
+			// Unreachable without a router to blame, e.g. no route.
+			return reply{}, nil
+		}
+	}
+	return reply{}, nil
+}
+
+// icmpSource reads the socket's error queue and returns the address of
+// the router whose ICMP error it holds.
+func icmpSource(fd, family int) (netip.Addr, bool) {
+	oob := make([]byte, 512)
+	_, oobn, _, _, err := unix.Recvmsg(fd, make([]byte, 1), oob, unix.MSG_ERRQUEUE)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	msgs, err := unix.ParseSocketControlMessage(oob[:oobn])
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	for _, m := range msgs {
+		ok := m.Header.Level == unix.SOL_IP && m.Header.Type == unix.IP_RECVERR ||
+			m.Header.Level == unix.SOL_IPV6 && m.Header.Type == unix.IPV6_RECVERR
+		const eeLen = int(unsafe.Sizeof(unix.SockExtendedErr{}))
+		if !ok || len(m.Data) < eeLen {
+			continue
+		}
+		ee := (*unix.SockExtendedErr)(unsafe.Pointer(&m.Data[0]))
+		if ee.Origin != unix.SO_EE_ORIGIN_ICMP && ee.Origin != unix.SO_EE_ORIGIN_ICMP6 {
+			continue
+		}
+		// The offending router follows as a sockaddr_in or sockaddr_in6.
+		off := m.Data[eeLen:]
+		if family == unix.AF_INET && len(off) >= 8 {
+			return netip.AddrFrom4([4]byte(off[4:8])), true
+		}
+		if family == unix.AF_INET6 && len(off) >= 24 {
+			return netip.AddrFrom16([16]byte(off[8:24])).Unmap(), true
+		}
+	}
+	return netip.Addr{}, false
+}
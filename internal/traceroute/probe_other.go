@@ -0,0 +1,13 @@
+//go:build !linux
+
+package traceroute
+
+import (
+	"context"
+	"net/netip"
+	"time"
+)
+
+func probe(context.Context, netip.AddrPort, int, time.Duration) (reply, error) {
+	return reply{}, ErrUnsupported
+}
@@ -0,0 +1,130 @@
+// Package traceroute maps the network path to a proxy with TCP SYN probes
+// of increasing TTL, the way tcptraceroute does, so high latency can be
+// put down to distance (many hops, a slow path) or to the proxy itself (a
+// short path but a slow last mile). It needs no raw sockets: routers'
+// ICMP "time exceeded" replies are read from each probe socket's error
+// queue.
+package traceroute
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"sync"
+	"time"
+)
+
+// Defaults for Options fields left zero.
+const (
+	DefaultMaxHops = 30
+	DefaultWait    = 2 * time.Second
+
+	// window is how many TTLs are probed at once. Most paths are reached
+	// within two windows, and the target sees at most window handshakes.
+	window = 8
+)
+
+// ErrUnsupported is returned by Trace where probing is not implemented.
+var ErrUnsupported = errors.New("traceroute: not supported on this platform")
+
+// Options configures a trace.
+type Options struct {
+	MaxHops int           // highest TTL tried
+	Wait    time.Duration // how long each probe waits for an answer
+}
+
+// Hop is one TTL of the path.
+type Hop struct {
+	TTL int `json:"ttl"`
+	// Addr is the router that answered, or the target on the last hop;
+	// empty when nothing answered in time.
+	Addr  string `json:"addr,omitempty"`
+	RTTMS int64  `json:"rtt_ms,omitempty"`
+}
+
+// Route is the outcome of a trace.
+type Route struct {
+	// Hops is the TTL the target answered at; 0 if it was not reached
+	// within MaxHops.
+	Hops int `json:"hops"`
+	// RTTMS is the TCP handshake time to the target, and LastMileMS the
+	// part of it after the last router that answered: the target's own
+	// network and the target itself.
+	RTTMS      int64 `json:"rtt_ms,omitempty"`
+	LastMileMS int64 `json:"last_mile_ms,omitempty"`
+	Path       []Hop `json:"path,omitempty"`
+}
+
+// probe result for one TTL.
+type reply struct {
+	from    netip.Addr // zero if nothing answered
+	rtt     time.Duration
+	reached bool // the target itself answered (handshake or reset)
+}
+
+// Trace probes target with TTLs 1 to opts.MaxHops, a window at a time,
+// until the target answers.
+func Trace(ctx context.Context, target netip.AddrPort, opts Options) (*Route, error) {
+	if opts.MaxHops <= 0 {
+		opts.MaxHops = DefaultMaxHops
+	}
+	if opts.Wait <= 0 {
+		opts.Wait = DefaultWait
+	}
+	replies := make([]reply, 0, opts.MaxHops)
+	for first := 1; first <= opts.MaxHops; first += window {
+		last := min(first+window-1, opts.MaxHops)
+		batch := make([]reply, last-first+1)
+		errs := make([]error, len(batch))
+		var wg sync.WaitGroup
+		for i := range batch {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				batch[i], errs[i] = probe(ctx, target, first+i, opts.Wait)
+			}()
+		}
+		wg.Wait()
+		if err := errors.Join(errs...); err != nil {
+			return nil, err
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		replies = append(replies, batch...)
+		for _, r := range batch {
+			if r.reached {
+				return route(replies), nil
+			}
+		}
+	}
+	return route(replies), nil
+}
+
+// route builds a Route from per-TTL replies, cut at the first that
+// reached the target (later TTLs reach it too).
+func route(replies []reply) *Route {
+	rt := &Route{}
+	var lastRouter time.Duration
+	seen := false // a router answered, or there is none (target at TTL 1)
+	for i, r := range replies {
+		hop := Hop{TTL: i + 1}
+		if r.from.IsValid() {
+			hop.Addr = r.from.String()
+			hop.RTTMS = r.rtt.Milliseconds()
+		}
+		rt.Path = append(rt.Path, hop)
+		if r.reached {
+			rt.Hops = hop.TTL
+			rt.RTTMS = r.rtt.Milliseconds()
+			if seen || hop.TTL == 1 {
+				rt.LastMileMS = max(r.rtt-lastRouter, 0).Milliseconds()
+			}
+			break
+		}
+		if r.from.IsValid() {
+			lastRouter, seen = r.rtt, true
+		}
+	}
+	return rt
+}
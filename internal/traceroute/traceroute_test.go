@@ -0,0 +1,60 @@
+package traceroute
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestRoute(t *testing.T) {
+	router := netip.MustParseAddr("10.0.0.1")
+	target := netip.MustParseAddr("192.0.2.7")
+	rt := route([]reply{
+		{from: router, rtt: 5 * time.Millisecond},
+		{}, // silent router
+		{from: target, rtt: 45 * time.Millisecond, reached: true},
+		{from: target, rtt: 46 * time.Millisecond, reached: true},
+	})
+	if rt.Hops != 3 || rt.RTTMS != 45 || rt.LastMileMS != 40 {
+		t.Errorf("route = hops %d, rtt %d, last mile %d; want 3, 45, 40", rt.Hops, rt.RTTMS, rt.LastMileMS)
+	}
+	if len(rt.Path) != 3 || rt.Path[1].Addr != "" || rt.Path[2].Addr != "192.0.2.7" {
+		t.Errorf("path = %+v", rt.Path)
+	}
+
+	if rt := route([]reply{{}, {}}); rt.Hops != 0 || rt.LastMileMS != 0 {
+		t.Errorf("unreached route = %+v", rt)
+	}
+	if rt := route([]reply{{}, {from: target, rtt: 9 * time.Millisecond, reached: true}}); rt.LastMileMS != 0 {
+		t.Errorf("last mile = %d with no router answering, want 0", rt.LastMileMS)
+	}
+}
+
+func TestTrace_local(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("probing is Linux-only")
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed.Close() // a reset answers the probe just as well
+	for _, l := range []net.Listener{ln, closed} {
+		target := netip.MustParseAddrPort(l.Addr().String())
+		rt, err := Trace(context.Background(), target, Options{MaxHops: 4, Wait: time.Second})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rt.Hops != 1 || len(rt.Path) != 1 || rt.Path[0].Addr != "127.0.0.1" {
+			t.Errorf("route = %+v, want the target at hop 1", rt)
+		}
+	}
+}
@@ -0,0 +1,281 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// tracesPath is where OTLP/HTTP collectors take spans.
+const tracesPath = "/v1/traces"
+
+// exporter posts spans to an OTLP/HTTP collector in the protocol's JSON
+// encoding, which collectors accept alongside protobuf. Encoding it here
+// keeps gRPC and protobuf, which the upstream exporter pulls in, out of
+// the build.
+type exporter struct {
+	url     string
+	headers http.Header
+	client  *http.Client
+}
+
+func newExporter(endpoint string, insecure bool) (*exporter, error) {
+	u, err := exportURL(endpoint, insecure)
+	if err != nil {
+		return nil, err
+	}
+	headers, err := exportHeaders()
+	if err != nil {
+		return nil, err
+	}
+	return &exporter{url: u, headers: headers, client: &http.Client{}}, nil
+}
+
+// exportURL returns the URL spans are posted to: endpoint, as Setup takes
+// it, else the standard environment variables, else a local collector.
+func exportURL(endpoint string, insecure bool) (string, error) {
+	scheme := "https"
+	if insecure {
+		scheme = "http"
+	}
+	full := false
+	switch {
+	case strings.Contains(endpoint, "://"):
+	case endpoint != "":
+		endpoint = scheme + "://" + endpoint
+	case os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != "":
+		endpoint, full = os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"), true // used as-is
+	case os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "":
+		endpoint = strings.TrimSuffix(os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"), "/") + tracesPath
+		full = true
+	default:
+		endpoint = scheme + "://localhost:4318"
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return "", fmt.Errorf("%q: want host:port or an http(s) URL", endpoint)
+	}
+	if !full && (u.Path == "" || u.Path == "/") {
+		u.Path = tracesPath
+	}
+	return u.String(), nil
+}
+
+// exportHeaders parses OTEL_EXPORTER_OTLP_HEADERS and, over it,
+// OTEL_EXPORTER_OTLP_TRACES_HEADERS: "key=value,..." with URL-encoded
+// values.
+func exportHeaders() (http.Header, error) {
+	h := http.Header{}
+	for _, env := range []string{"OTEL_EXPORTER_OTLP_HEADERS", "OTEL_EXPORTER_OTLP_TRACES_HEADERS"} {
+		for pair := range strings.SplitSeq(os.Getenv(env), ",") {
+			if strings.TrimSpace(pair) == "" {
+				continue
+			}
+			k, v, ok := strings.Cut(pair, "=")
+			v, err := url.QueryUnescape(strings.TrimSpace(v))
+			if !ok || strings.TrimSpace(k) == "" || err != nil {
+				return nil, fmt.Errorf("%s: bad header %q", env, pair)
+			}
+			h.Set(strings.TrimSpace(k), v)
+		}
+	}
+	return h, nil
+}
+
+// ExportSpans implements sdktrace.SpanExporter.
+func (e *exporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	if len(spans) == 0 {
+		return nil
+	}
+	body, err := json.Marshal(encodeSpans(spans))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range e.headers {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+		return fmt.Errorf("otlp export: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	io.Copy(io.Discard, resp.Body) //nolint:errcheck
+	return nil
+}
+
+// Shutdown implements sdktrace.SpanExporter.
+func (e *exporter) Shutdown(context.Context) error {
+	e.client.CloseIdleConnections()
+	return nil
+}
+
+// The OTLP JSON encoding: IDs in hex, 64-bit integers as strings, enums as
+// numbers.
+type (
+	exportRequest struct {
+		ResourceSpans []resourceSpans `json:"resourceSpans"`
+	}
+	resourceSpans struct {
+		Resource   resourceJSON `json:"resource"`
+		ScopeSpans []scopeSpans `json:"scopeSpans"`
+		SchemaURL  string       `json:"schemaUrl,omitempty"`
+	}
+	resourceJSON struct {
+		Attributes []keyValue `json:"attributes,omitempty"`
+	}
+	scopeSpans struct {
+		Scope scopeJSON  `json:"scope"`
+		Spans []spanJSON `json:"spans"`
+	}
+	scopeJSON struct {
+		Name    string `json:"name"`
+		Version string `json:"version,omitempty"`
+	}
+	spanJSON struct {
+		TraceID      string     `json:"traceId"`
+		SpanID       string     `json:"spanId"`
+		TraceState   string     `json:"traceState,omitempty"`
+		ParentSpanID string     `json:"parentSpanId,omitempty"`
+		Name         string     `json:"name"`
+		Kind         int        `json:"kind"`
+		Start        uint64     `json:"startTimeUnixNano,string"`
+		End          uint64     `json:"endTimeUnixNano,string"`
+		Attributes   []keyValue `json:"attributes,omitempty"`
+		Events       []event    `json:"events,omitempty"`
+		Links        []link     `json:"links,omitempty"`
+		Status       status     `json:"status"`
+	}
+	event struct {
+		Time       uint64     `json:"timeUnixNano,string"`
+		Name       string     `json:"name"`
+		Attributes []keyValue `json:"attributes,omitempty"`
+	}
+	link struct {
+		TraceID    string     `json:"traceId"`
+		SpanID     string     `json:"spanId"`
+		Attributes []keyValue `json:"attributes,omitempty"`
+	}
+	status struct {
+		Code    int    `json:"code,omitempty"`
+		Message string `json:"message,omitempty"`
+	}
+	keyValue struct {
+		Key   string         `json:"key"`
+		Value map[string]any `json:"value"`
+	}
+)
+
+// encodeSpans groups spans, all from one tracer provider and so one
+// resource, by instrumentation scope.
+func encodeSpans(spans []sdktrace.ReadOnlySpan) exportRequest {
+	rs := resourceSpans{}
+	if res := spans[0].Resource(); res != nil {
+		rs.Resource.Attributes = encodeAttrs(res.Attributes())
+		rs.SchemaURL = res.SchemaURL()
+	}
+	index := map[scopeJSON]int{}
+	for _, s := range spans {
+		sc := scopeJSON{Name: s.InstrumentationScope().Name, Version: s.InstrumentationScope().Version}
+		i, ok := index[sc]
+		if !ok {
+			i = len(rs.ScopeSpans)
+			index[sc] = i
+			rs.ScopeSpans = append(rs.ScopeSpans, scopeSpans{Scope: sc})
+		}
+		rs.ScopeSpans[i].Spans = append(rs.ScopeSpans[i].Spans, encodeSpan(s))
+	}
+	return exportRequest{ResourceSpans: []resourceSpans{rs}}
+}
+
+func encodeSpan(s sdktrace.ReadOnlySpan) spanJSON {
+	sc := s.SpanContext()
+	j := spanJSON{
+		TraceID:    sc.TraceID().String(),
+		SpanID:     sc.SpanID().String(),
+		TraceState: sc.TraceState().String(),
+		Name:       s.Name(),
+		Kind:       int(s.SpanKind()), // trace.SpanKind numbers match OTLP's
+		Start:      uint64(s.StartTime().UnixNano()),
+		End:        uint64(s.EndTime().UnixNano()),
+		Attributes: encodeAttrs(s.Attributes()),
+		Status:     status{Message: s.Status().Description},
+	}
+	if p := s.Parent(); p.IsValid() {
+		j.ParentSpanID = p.SpanID().String()
+	}
+	switch s.Status().Code {
+	case codes.Ok:
+		j.Status.Code = 1
+	case codes.Error:
+		j.Status.Code = 2
+	}
+	for _, e := range s.Events() {
+		j.Events = append(j.Events, event{Time: uint64(e.Time.UnixNano()), Name: e.Name, Attributes: encodeAttrs(e.Attributes)})
+	}
+	for _, l := range s.Links() {
+		j.Links = append(j.Links, link{TraceID: l.SpanContext.TraceID().String(), SpanID: l.SpanContext.SpanID().String(), Attributes: encodeAttrs(l.Attributes)})
+	}
+	return j
+}
+
+func encodeAttrs(attrs []attribute.KeyValue) []keyValue {
+	kvs := make([]keyValue, 0, len(attrs))
+	for _, a := range attrs {
+		kvs = append(kvs, keyValue{Key: string(a.Key), Value: encodeValue(a.Value)})
+	}
+	return kvs
+}
+
+func encodeValue(v attribute.Value) map[string]any {
+	array := func(n int, at func(int) map[string]any) map[string]any {
+		values := make([]map[string]any, n)
+		for i := range values {
+			values[i] = at(i)
+		}
+		return map[string]any{"arrayValue": map[string]any{"values": values}}
+	}
+	switch v.Type() {
+	case attribute.BOOL:
+		return map[string]any{"boolValue": v.AsBool()}
+	case attribute.INT64:
+		return map[string]any{"intValue": strconv.FormatInt(v.AsInt64(), 10)}
+	case attribute.FLOAT64:
+		return map[string]any{"doubleValue": v.AsFloat64()}
+	case attribute.BOOLSLICE:
+		s := v.AsBoolSlice()
+		return array(len(s), func(i int) map[string]any { return encodeValue(attribute.BoolValue(s[i])) })
+	case attribute.INT64SLICE:
+		s := v.AsInt64Slice()
+		return array(len(s), func(i int) map[string]any { return encodeValue(attribute.Int64Value(s[i])) })
+	case attribute.FLOAT64SLICE:
+		s := v.AsFloat64Slice()
+		return array(len(s), func(i int) map[string]any { return encodeValue(attribute.Float64Value(s[i])) })
+	case attribute.STRINGSLICE:
+		s := v.AsStringSlice()
+		return array(len(s), func(i int) map[string]any { return encodeValue(attribute.StringValue(s[i])) })
+	}
+	return map[string]any{"stringValue": v.Emit()}
+}
@@ -0,0 +1,101 @@
+package tracing
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSetup_exportsJSON(t *testing.T) {
+	bodies := make(chan []byte, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Content-Type") != "application/json" || r.Header.Get("X-Token") != "a b" {
+			t.Errorf("request %s %s %v", r.Method, r.URL.Path, r.Header)
+		}
+		b, _ := io.ReadAll(r.Body)
+		bodies <- b
+	}))
+	defer srv.Close()
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "X-Token=a%20b")
+
+	prev := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+	shutdown, err := Setup(t.Context(), srv.URL, false, "v1.2.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, parent := Start(t.Context(), "check", trace.WithAttributes(attribute.Int("n", 3), attribute.StringSlice("s", []string{"x"})))
+	_, child := Start(ctx, "tcp_probe")
+	End(child, errors.New("refused"))
+	parent.End()
+	if err := shutdown(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+
+	var req exportRequest
+	if err := json.Unmarshal(<-bodies, &req); err != nil {
+		t.Fatal(err)
+	}
+	if len(req.ResourceSpans) != 1 || len(req.ResourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("got %+v", req)
+	}
+	spans := req.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	c, p := spans[0], spans[1]
+	if c.Name != "tcp_probe" || c.ParentSpanID != p.SpanID || c.TraceID != p.TraceID || len(c.TraceID) != 32 {
+		t.Errorf("child %+v not linked to parent %+v", c, p)
+	}
+	if c.Status.Code != 2 || c.Status.Message != "refused" || len(c.Events) != 1 {
+		t.Errorf("child status = %+v, events %d; want error with its event", c.Status, len(c.Events))
+	}
+	if c.Start == 0 || c.End < c.Start {
+		t.Errorf("times %d..%d", c.Start, c.End)
+	}
+	if len(p.Attributes) != 2 || p.Attributes[0].Value["intValue"] != "3" || p.Attributes[1].Value["arrayValue"] == nil {
+		t.Errorf("parent attributes = %+v", p.Attributes)
+	}
+	var service string
+	for _, a := range req.ResourceSpans[0].Resource.Attributes {
+		if a.Key == "service.name" {
+			service, _ = a.Value["stringValue"].(string)
+		}
+	}
+	if service != "proxybench" {
+		t.Errorf("service.name = %q", service)
+	}
+}
+
+func TestExportURL(t *testing.T) {
+	cases := []struct {
+		endpoint string
+		insecure bool
+		env      map[string]string
+		want     string
+	}{
+		{"collector:4318", false, nil, "https://collector:4318/v1/traces"},
+		{"collector:4318", true, nil, "http://collector:4318/v1/traces"},
+		{"http://collector:4318/custom", false, nil, "http://collector:4318/custom"},
+		{"", false, map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "https://otel.example.com/"}, "https://otel.example.com/v1/traces"},
+		{"", false, map[string]string{"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT": "https://otel.example.com"}, "https://otel.example.com"},
+		{"", true, nil, "http://localhost:4318/v1/traces"},
+	}
+	for _, c := range cases {
+		t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", c.env["OTEL_EXPORTER_OTLP_ENDPOINT"])
+		t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", c.env["OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"])
+		if got, err := exportURL(c.endpoint, c.insecure); err != nil || got != c.want {
+			t.Errorf("exportURL(%q, %v) = %q, %v; want %q", c.endpoint, c.insecure, got, err, c.want)
+		}
+	}
+	if _, err := exportURL("ftp://collector", false); err == nil {
+		t.Error("exportURL(ftp://…): want error")
+	}
+}
@@ -0,0 +1,431 @@
+// Package tui draws the interactive dashboard of 'proxybench top': a
+// sortable table of proxies that are re-checked in rounds, with a detail
+// pane for the selected one. The Dashboard is plain state that renders to
+// lines, so it can be driven and tested without a terminal.
+package tui
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/drsoft-oss/proxybench/internal/bench"
+	"github.com/drsoft-oss/proxybench/internal/checker"
+)
+
+// Row is one proxy on the dashboard.
+type Row struct {
+	Address string
+	Country string
+	// Last is the most recent check; Checked is when it finished, zero
+	// until the first one does.
+	Last    checker.Result
+	Checked time.Time
+	// Checks counts finished checks and Alive those that passed.
+	Checks, Alive int
+	// Checking and Benching are set while a check or bench is running.
+	Checking, Benching bool
+	// Bench is the last on-demand benchmark, nil before one.
+	Bench *bench.Stats
+}
+
+// Uptime is the share of checks that passed, 0–1.
+func (r *Row) Uptime() float64 {
+	if r.Checks == 0 {
+		return 0
+	}
+	return float64(r.Alive) / float64(r.Checks)
+}
+
+func (r *Row) status() string {
+	switch {
+	case r.Checking && r.Checks == 0:
+		return "…"
+	case r.Checks == 0:
+		return "-"
+	case r.Last.Alive:
+		return "alive"
+	}
+	return "dead"
+}
+
+// Column is a sortable table column.
+type Column int
+
+const (
+	ColAddress Column = iota
+	ColProtocol
+	ColStatus
+	ColLatency
+	ColUptime
+	ColCountry
+	ColChecked
+	numColumns
+)
+
+var columnNames = [numColumns]string{"ADDRESS", "PROTO", "STATUS", "LAT(ms)", "UPTIME", "COUNTRY", "CHECKED"}
+
+func (c Column) String() string { return strings.ToLower(strings.TrimSuffix(columnNames[c], "(ms)")) }
+
+// compare orders rows by column c, ascending.
+func (c Column) compare(a, b *Row) int {
+	switch c {
+	case ColProtocol:
+		return strings.Compare(string(a.Last.Protocol), string(b.Last.Protocol))
+	case ColStatus:
+		return strings.Compare(a.status(), b.status())
+	case ColLatency:
+		// Dead and unchecked proxies sort after every measured one.
+		la, lb := a.Last.Latency, b.Last.Latency
+		if !a.Last.Alive {
+			la = 1<<63 - 1
+		}
+		if !b.Last.Alive {
+			lb = 1<<63 - 1
+		}
+		return cmp.Compare(la, lb)
+	case ColUptime:
+		return cmp.Compare(a.Uptime(), b.Uptime())
+	case ColCountry:
+		return strings.Compare(a.Country, b.Country)
+	case ColChecked:
+		return a.Checked.Compare(b.Checked)
+	}
+	return strings.Compare(a.Address, b.Address)
+}
+
+// ActionKind is what a key asks the caller to do.
+type ActionKind int
+
+const (
+	ActionNone ActionKind = iota
+	ActionQuit
+	ActionRecheck    // re-check Action.Address now
+	ActionRecheckAll // start a round now
+	ActionBench      // benchmark Action.Address
+)
+
+// Action is returned by Handle for work outside the dashboard's state.
+type Action struct {
+	Kind    ActionKind
+	Address string
+}
+
+// Dashboard is the state of the screen.
+type Dashboard struct {
+	rows     map[string]*Row
+	sortBy   Column
+	desc     bool
+	selected string // address of the selected row
+	offset   int    // first visible row
+	detail   bool
+	message  string
+
+	// Title heads the screen; NextRound, when set, is shown as a
+	// countdown.
+	Title     string
+	NextRound time.Time
+}
+
+// New returns a dashboard listing addresses, sorted by address.
+func New(addresses []string) *Dashboard {
+	d := &Dashboard{rows: map[string]*Row{}, Title: "proxybench top"}
+	for _, a := range addresses {
+		d.rows[a] = &Row{Address: a}
+	}
+	if v := d.view(); len(v) > 0 {
+		d.selected = v[0].Address
+	}
+	return d
+}
+
+// Addresses returns the proxies still on the dashboard, in display order.
+func (d *Dashboard) Addresses() []string {
+	v := d.view()
+	out := make([]string, len(v))
+	for i, r := range v {
+		out[i] = r.Address
+	}
+	return out
+}
+
+// Row returns the row of address, or nil if it was removed.
+func (d *Dashboard) Row(address string) *Row { return d.rows[address] }
+
+// Checking marks address as being checked.
+func (d *Dashboard) Checking(address string) {
+	if r := d.rows[address]; r != nil {
+		r.Checking = true
+	}
+}
+
+// Update records a finished check of address (r.Address may differ, e.g.
+// with a scheme added to a bare host:port); results for removed proxies
+// are ignored.
+func (d *Dashboard) Update(address string, r checker.Result, now time.Time) {
+	row := d.rows[address]
+	if row == nil {
+		return
+	}
+	row.Last, row.Checked, row.Checking = r, now, false
+	row.Checks++
+	if r.Alive {
+		row.Alive++
+	}
+}
+
+// UpdateBench records a finished benchmark of address.
+func (d *Dashboard) UpdateBench(address string, s bench.Stats) {
+	if row := d.rows[address]; row != nil {
+		row.Bench, row.Benching = &s, false
+	}
+}
+
+// SetCountry sets the country label of address.
+func (d *Dashboard) SetCountry(address, country string) {
+	if row := d.rows[address]; row != nil {
+		row.Country = country
+	}
+}
+
+// Message shows text in the footer until the next key.
+func (d *Dashboard) Message(text string) { d.message = text }
+
+// view returns the rows in display order.
+func (d *Dashboard) view() []*Row {
+	v := make([]*Row, 0, len(d.rows))
+	for _, r := range d.rows {
+		v = append(v, r)
+	}
+	slices.SortFunc(v, func(a, b *Row) int {
+		c := d.sortBy.compare(a, b)
+		if d.desc {
+			c = -c
+		}
+		return cmp.Or(c, strings.Compare(a.Address, b.Address))
+	})
+	return v
+}
+
+func (d *Dashboard) index(v []*Row) int {
+	return max(0, slices.IndexFunc(v, func(r *Row) bool { return r.Address == d.selected }))
+}
+
+// Handle applies a key press and returns the work it asks for.
+func (d *Dashboard) Handle(k Key) Action {
+	d.message = ""
+	v := d.view()
+	i := d.index(v)
+	move := func(to int) {
+		if len(v) > 0 {
+			d.selected = v[min(max(to, 0), len(v)-1)].Address
+		}
+	}
+	var sel *Row
+	if len(v) > 0 {
+		sel = v[i]
+	}
+	switch k {
+	case KeyUp, KeyRune + 'k':
+		move(i - 1)
+	case KeyDown, KeyRune + 'j':
+		move(i + 1)
+	case KeyPageUp:
+		move(i - 10)
+	case KeyPageDown:
+		move(i + 10)
+	case KeyHome, KeyRune + 'g':
+		move(0)
+	case KeyEnd, KeyRune + 'G':
+		move(len(v) - 1)
+	case KeyRight, KeyRune + 's', KeyRune + '>':
+		d.sortBy = (d.sortBy + 1) % numColumns
+	case KeyLeft, KeyRune + '<':
+		d.sortBy = (d.sortBy + numColumns - 1) % numColumns
+	case KeyRune + 'S':
+		d.desc = !d.desc
+	case KeyEnter:
+		d.detail = !d.detail
+	case KeyRune + 'q', KeyCtrlC:
+		return Action{Kind: ActionQuit}
+	case KeyRune + 'R':
+		return Action{Kind: ActionRecheckAll}
+	case KeyRune + 'r':
+		if sel != nil && !sel.Checking {
+			sel.Checking = true
+			return Action{Kind: ActionRecheck, Address: sel.Address}
+		}
+	case KeyRune + 'b':
+		if sel != nil && !sel.Benching {
+			sel.Benching = true
+			d.detail = true
+			return Action{Kind: ActionBench, Address: sel.Address}
+		}
+	case KeyRune + 'd', KeyDelete:
+		if sel != nil {
+			delete(d.rows, sel.Address)
+			d.message = "removed " + checker.Redact(sel.Address)
+			v = d.view()
+			move(i)
+		}
+	}
+	return Action{}
+}
+
+// Render lays the dashboard out for a width × height screen.
+func (d *Dashboard) Render(width, height int, now time.Time) []string {
+	v := d.view()
+	alive := 0
+	for _, r := range v {
+		if r.Checks > 0 && r.Last.Alive {
+			alive++
+		}
+	}
+	head := fmt.Sprintf("%s — %d proxies · %d alive · sorted by %s", d.Title, len(v), alive, d.sortBy)
+	if d.desc {
+		head += " ↓"
+	} else {
+		head += " ↑"
+	}
+	if !d.NextRound.IsZero() {
+		head += fmt.Sprintf(" · next round in %s", max(0, d.NextRound.Sub(now)).Round(time.Second))
+	}
+
+	var detail []string
+	if d.detail && len(v) > 0 {
+		detail = d.detailLines(v[d.index(v)], now)
+	}
+	visible := max(1, height-3-len(detail)) // title, header, footer
+	i := d.index(v)
+	if i < d.offset {
+		d.offset = i
+	} else if i >= d.offset+visible {
+		d.offset = i - visible + 1
+	}
+	d.offset = min(d.offset, max(0, len(v)-visible))
+
+	addrWidth := max(16, width-62) // the other columns take 62 cells
+	lines := []string{
+		clip(head, width),
+		"\x1b[1m" + clip(fmt.Sprintf("  %-*s %-7s %-7s %8s %7s  %-15s %9s",
+			addrWidth, columnNames[0], columnNames[1], columnNames[2], columnNames[3],
+			columnNames[4], columnNames[5], columnNames[6]), width) + "\x1b[0m",
+	}
+	for _, r := range v[d.offset:min(len(v), d.offset+visible)] {
+		lat, up, checked := "-", "-", "-"
+		if r.Last.Alive {
+			lat = fmt.Sprint(r.Last.LatencyMS())
+		}
+		if r.Checks > 0 {
+			up = fmt.Sprintf("%.0f%%", r.Uptime()*100)
+			checked = ago(now.Sub(r.Checked))
+		}
+		marker := "  "
+		if r.Checking {
+			marker = "↻ "
+		}
+		line := fmt.Sprintf("%s%-*s %-7s %-7s %8s %7s  %-15s %9s",
+			marker, addrWidth, truncate(checker.Redact(r.Address), addrWidth),
+			r.Last.Protocol, r.status(), lat, up, truncate(r.Country, 15), checked)
+		line = clip(line, width)
+		if r.Address == d.selected {
+			line = "\x1b[7m" + line + "\x1b[0m"
+		} else if r.Checks > 0 {
+			line = color(r.Last.Alive) + line + "\x1b[0m"
+		}
+		lines = append(lines, line)
+	}
+	for len(lines) < height-1-len(detail) {
+		lines = append(lines, "")
+	}
+	lines = append(lines, detail...)
+	footer := d.message
+	if footer == "" {
+		footer = "↑↓ select  ←→/s sort  S reverse  enter details  r re-check  R round  b bench  d remove  q quit"
+	}
+	return append(lines, "\x1b[2m"+clip(footer, width)+"\x1b[0m")
+}
+
+// detailLines describes the selected row.
+func (d *Dashboard) detailLines(r *Row, now time.Time) []string {
+	lines := []string{strings.Repeat("─", 40), "Proxy:    " + checker.Redact(r.Address)}
+	if r.Checks > 0 {
+		res := r.Last
+		lines = append(lines, fmt.Sprintf("Checks:   %d, %d alive (%.0f%%), last %s ago", r.Checks, r.Alive, r.Uptime()*100, ago(now.Sub(r.Checked))))
+		if res.Alive {
+			lines = append(lines, fmt.Sprintf("Latency:  %d ms (hop %d ms, target %d ms)  status %d  %s",
+				res.LatencyMS(), res.HopLatency.Milliseconds(), res.TargetLatency.Milliseconds(), res.StatusCode, res.Software))
+		}
+		if res.Failure != nil {
+			lines = append(lines, fmt.Sprintf("Error:    %s (%s, %s)", res.Failure.Message, res.Failure.Kind, res.Failure.Phase))
+		} else if res.Error != "" {
+			lines = append(lines, "Error:    "+res.Error)
+		}
+		if res.Warning != "" {
+			lines = append(lines, "Warning:  "+res.Warning)
+		}
+	}
+	switch {
+	case r.Benching:
+		lines = append(lines, "Bench:    running…")
+	case r.Bench != nil:
+		s := r.Bench
+		lines = append(lines, fmt.Sprintf("Bench:    %d/%d ok  p50 %d ms  p95 %d ms  loss %.0f%%", s.Successful, s.Samples, s.P50MS, s.P95MS, s.LossRate*100))
+	default:
+		lines = append(lines, "Bench:    press b to benchmark")
+	}
+	return lines
+}
+
+func color(alive bool) string {
+	if alive {
+		return "\x1b[32m"
+	}
+	return "\x1b[31m"
+}
+
+func ago(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+	return fmt.Sprintf("%dh", int(d.Hours()))
+}
+
+// truncate shortens s to n runes, marking the cut with "…".
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
+
+// clip cuts a line to the screen width.
+func clip(s string, width int) string {
+	r := []rune(s)
+	if len(r) <= width {
+		return s
+	}
+	return string(r[:width])
+}
+
+// frame is the byte stream that repaints the screen with lines: home the
+// cursor, write each line clearing its tail, clear below. Raw mode
+// turns output processing off, so lines end in "\r\n".
+func frame(lines []string) string {
+	var b strings.Builder
+	b.WriteString("\x1b[H")
+	for i, l := range lines {
+		if i > 0 {
+			b.WriteString("\r\n")
+		}
+		b.WriteString(l + "\x1b[K")
+	}
+	b.WriteString("\x1b[J")
+	return b.String()
+}
@@ -0,0 +1,87 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/drsoft-oss/proxybench/internal/checker"
+)
+
+var now = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func newTestDashboard() *Dashboard {
+	d := New([]string{"http://c:80", "http://a:80", "socks5://b:1080"})
+	d.Update("http://a:80", checker.Result{Address: "http://a:80", Protocol: checker.ProtocolHTTP, Alive: true, Latency: 300 * time.Millisecond}, now)
+	d.Update("socks5://b:1080", checker.Result{Address: "socks5://b:1080", Protocol: checker.ProtocolSOCKS5, Alive: true, Latency: 100 * time.Millisecond}, now)
+	d.Update("http://c:80", checker.Result{Address: "http://c:80", Protocol: checker.ProtocolHTTP, Error: "refused"}, now)
+	return d
+}
+
+func TestDashboard_sort(t *testing.T) {
+	d := newTestDashboard()
+	if got := strings.Join(d.Addresses(), " "); got != "http://a:80 http://c:80 socks5://b:1080" {
+		t.Errorf("by address: %s", got)
+	}
+	for d.sortBy != ColLatency {
+		d.Handle(KeyRight)
+	}
+	if got := strings.Join(d.Addresses(), " "); got != "socks5://b:1080 http://a:80 http://c:80" {
+		t.Errorf("by latency: %s", got)
+	}
+	d.Handle(KeyRune + 'S')
+	if got := d.Addresses()[0]; got != "http://c:80" {
+		t.Errorf("by latency, reversed: first = %s", got)
+	}
+}
+
+func TestDashboard_keys(t *testing.T) {
+	d := newTestDashboard()
+	if a := d.Handle(KeyDown); a.Kind != ActionNone || d.selected != "http://c:80" {
+		t.Fatalf("selected %s", d.selected)
+	}
+	if a := d.Handle(KeyRune + 'r'); a.Kind != ActionRecheck || a.Address != "http://c:80" {
+		t.Errorf("r = %+v", a)
+	}
+	if a := d.Handle(KeyRune + 'r'); a.Kind != ActionNone {
+		t.Errorf("r while checking = %+v", a)
+	}
+	d.Handle(KeyRune + 'd')
+	if d.Row("http://c:80") != nil || d.selected != "socks5://b:1080" {
+		t.Errorf("after remove: rows %v, selected %s", d.Addresses(), d.selected)
+	}
+	d.Update("http://c:80", checker.Result{Alive: true}, now) // late result for a removed proxy
+	if len(d.Addresses()) != 2 {
+		t.Errorf("removed proxy came back: %v", d.Addresses())
+	}
+	if a := d.Handle(KeyRune + 'b'); a.Kind != ActionBench || a.Address != "socks5://b:1080" || !d.detail {
+		t.Errorf("b = %+v", a)
+	}
+	if a := d.Handle(KeyRune + 'q'); a.Kind != ActionQuit {
+		t.Errorf("q = %+v", a)
+	}
+}
+
+func TestDashboard_render(t *testing.T) {
+	d := newTestDashboard()
+	d.NextRound = now.Add(20 * time.Second)
+	d.Handle(KeyEnter)
+	lines := d.Render(100, 20, now.Add(5*time.Second))
+	if len(lines) != 20 {
+		t.Fatalf("got %d lines, want 20", len(lines))
+	}
+	screen := strings.Join(lines, "\n")
+	for _, want := range []string{"3 proxies · 2 alive", "next round in 15s", "http://a:80", "300", "Proxy:    http://a:80", "press b to benchmark"} {
+		if !strings.Contains(screen, want) {
+			t.Errorf("screen missing %q:\n%s", want, screen)
+		}
+	}
+
+	// A short screen scrolls to keep the selection visible.
+	d.Handle(KeyEnter)
+	d.Handle(KeyEnd)
+	screen = strings.Join(d.Render(100, 4, now), "\n")
+	if !strings.Contains(screen, "socks5://b:1080") || strings.Contains(screen, "http://a:80") {
+		t.Errorf("scrolled screen:\n%s", screen)
+	}
+}
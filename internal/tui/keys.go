@@ -0,0 +1,75 @@
+package tui
+
+// Key is one decoded key press.
+type Key int
+
+const (
+	KeyNone Key = iota
+	KeyUp
+	KeyDown
+	KeyLeft
+	KeyRight
+	KeyPageUp
+	KeyPageDown
+	KeyHome
+	KeyEnd
+	KeyEnter
+	KeyDelete
+	KeyCtrlC
+	KeyEscape
+	// Printable keys are KeyRune + the character.
+	KeyRune Key = 1 << 16
+)
+
+// Rune returns the character of a printable key, or 0.
+func (k Key) Rune() rune {
+	if k >= KeyRune {
+		return rune(k - KeyRune)
+	}
+	return 0
+}
+
+// escapes are the VT100/xterm sequences for the keys the dashboard uses.
+var escapes = map[string]Key{
+	"[A": KeyUp, "[B": KeyDown, "[C": KeyRight, "[D": KeyLeft,
+	"OA": KeyUp, "OB": KeyDown, "OC": KeyRight, "OD": KeyLeft,
+	"[5~": KeyPageUp, "[6~": KeyPageDown,
+	"[H": KeyHome, "[F": KeyEnd, "[1~": KeyHome, "[4~": KeyEnd,
+	"[3~": KeyDelete,
+}
+
+// ParseKeys decodes the bytes of one terminal read. Unknown escape
+// sequences are dropped.
+func ParseKeys(b []byte) []Key {
+	var keys []Key
+	for i := 0; i < len(b); i++ {
+		switch c := b[i]; {
+		case c == 0x1b:
+			if i+1 == len(b) {
+				keys = append(keys, KeyEscape)
+				continue
+			}
+			// A sequence ends at its first letter or '~'.
+			j := i + 2
+			for j < len(b) && !(b[j] >= 'A' && b[j] <= 'Z' || b[j] >= 'a' && b[j] <= 'z' || b[j] == '~') {
+				j++
+			}
+			if j == len(b) {
+				j--
+			}
+			if k, ok := escapes[string(b[i+1:j+1])]; ok {
+				keys = append(keys, k)
+			}
+			i = j
+		case c == '\r' || c == '\n':
+			keys = append(keys, KeyEnter)
+		case c == 3:
+			keys = append(keys, KeyCtrlC)
+		case c == 0x7f:
+			keys = append(keys, KeyDelete)
+		case c >= 0x20 && c < 0x7f:
+			keys = append(keys, KeyRune+Key(c))
+		}
+	}
+	return keys
+}
@@ -0,0 +1,17 @@
+package tui
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseKeys(t *testing.T) {
+	got := ParseKeys([]byte("j\x1b[A\x1bOB\x1b[3~\r\x03q\x1b[99Z\x1b"))
+	want := []Key{KeyRune + 'j', KeyUp, KeyDown, KeyDelete, KeyEnter, KeyCtrlC, KeyRune + 'q', KeyEscape}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseKeys = %v, want %v", got, want)
+	}
+	if r := (KeyRune + 'q').Rune(); r != 'q' {
+		t.Errorf("Rune = %q", r)
+	}
+}
@@ -0,0 +1,53 @@
+package tui
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// Prompt asks questions on the controlling terminal, so it works while
+// stdin carries a proxy list and stdout the results.
+type Prompt struct {
+	tty tty
+	in  *bufio.Reader
+}
+
+// OpenPrompt opens the controlling terminal, failing if there is none.
+func OpenPrompt() (*Prompt, error) {
+	t, err := openTTY()
+	if err != nil {
+		return nil, fmt.Errorf("open terminal: %w", err)
+	}
+	if !term.IsTerminal(int(t.in.Fd())) {
+		t.Close()
+		return nil, errors.New("not a terminal")
+	}
+	return &Prompt{tty: t, in: bufio.NewReader(t.in)}, nil
+}
+
+// Close releases the terminal.
+func (p *Prompt) Close() error { return p.tty.Close() }
+
+// Say writes a line to the terminal.
+func (p *Prompt) Say(format string, args ...any) {
+	fmt.Fprintf(p.tty.out, format+"\n", args...)
+}
+
+// Line asks for a line of input and returns it without the newline.
+func (p *Prompt) Line(label string) (string, error) {
+	fmt.Fprint(p.tty.out, label)
+	s, err := p.in.ReadString('\n')
+	return strings.TrimRight(s, "\r\n"), err
+}
+
+// Secret is Line with echo turned off, for passwords.
+func (p *Prompt) Secret(label string) (string, error) {
+	fmt.Fprint(p.tty.out, label)
+	pass, err := term.ReadPassword(int(p.tty.in.Fd()))
+	fmt.Fprintln(p.tty.out) // the newline was not echoed
+	return string(pass), err
+}
@@ -0,0 +1,76 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"golang.org/x/term"
+)
+
+// Terminal is the controlling terminal in raw mode, drawn to as a full
+// screen. Keys are read from it rather than stdin, so a proxy list can
+// still be piped in.
+type Terminal struct {
+	tty     tty
+	saved   *term.State
+	resized chan os.Signal
+}
+
+// Open switches the controlling terminal to raw mode and the alternate
+// screen. Close restores it.
+func Open() (*Terminal, error) {
+	t, err := openTTY()
+	if err != nil {
+		return nil, fmt.Errorf("open terminal: %w", err)
+	}
+	if !term.IsTerminal(int(t.in.Fd())) {
+		t.Close()
+		return nil, errors.New("not a terminal")
+	}
+	if err := enableEscapes(t.out); err != nil {
+		t.Close()
+		return nil, fmt.Errorf("escape sequences: %w", err)
+	}
+	saved, err := term.MakeRaw(int(t.in.Fd()))
+	if err != nil {
+		t.Close()
+		return nil, fmt.Errorf("raw mode: %w", err)
+	}
+	tm := &Terminal{tty: t, saved: saved, resized: make(chan os.Signal, 1)}
+	notifyResize(tm.resized)
+	fmt.Fprint(t.out, "\x1b[?1049h\x1b[?25l") // alternate screen, hide cursor
+	return tm, nil
+}
+
+// Close leaves the alternate screen and restores the terminal mode.
+func (t *Terminal) Close() error {
+	signal.Stop(t.resized)
+	fmt.Fprint(t.tty.out, "\x1b[?25h\x1b[?1049l")
+	err := term.Restore(int(t.tty.in.Fd()), t.saved)
+	t.tty.Close()
+	return err
+}
+
+// Size returns the terminal width and height in cells.
+func (t *Terminal) Size() (width, height int) {
+	width, height, err := term.GetSize(int(t.tty.out.Fd()))
+	if err != nil || width == 0 || height == 0 {
+		return 80, 24
+	}
+	return width, height
+}
+
+// Resized receives a value whenever the terminal changes size, where the
+// platform tells; elsewhere the periodic redraw picks the new size up.
+func (t *Terminal) Resized() <-chan os.Signal { return t.resized }
+
+// Read reads raw key bytes.
+func (t *Terminal) Read(p []byte) (int, error) { return t.tty.in.Read(p) }
+
+// Draw repaints the screen with lines, one per row.
+func (t *Terminal) Draw(lines []string) error {
+	_, err := t.tty.out.WriteString(frame(lines))
+	return err
+}
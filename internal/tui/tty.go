@@ -0,0 +1,21 @@
+package tui
+
+import "os"
+
+// tty is the controlling terminal: one file on Unix, and the console's
+// input and output buffers on Windows. Reading and writing it rather
+// than stdin and stdout leaves those to proxy lists and results.
+type tty struct {
+	in, out *os.File
+}
+
+// Close closes the terminal's files.
+func (t tty) Close() error {
+	err := t.in.Close()
+	if t.out != t.in {
+		if cerr := t.out.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
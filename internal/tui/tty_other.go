@@ -0,0 +1,17 @@
+//go:build !unix && !windows
+
+package tui
+
+import (
+	"errors"
+	"os"
+)
+
+// openTTY fails: there is no terminal to open on this platform.
+func openTTY() (tty, error) {
+	return tty{}, errors.New("no terminal on this platform")
+}
+
+func enableEscapes(*os.File) error { return nil }
+
+func notifyResize(chan<- os.Signal) {}
@@ -0,0 +1,24 @@
+//go:build unix
+
+package tui
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// openTTY opens /dev/tty.
+func openTTY() (tty, error) {
+	f, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return tty{}, err
+	}
+	return tty{in: f, out: f}, nil
+}
+
+// enableEscapes is a no-op: Unix terminals interpret escape sequences.
+func enableEscapes(*os.File) error { return nil }
+
+// notifyResize sends SIGWINCH to c.
+func notifyResize(c chan<- os.Signal) { signal.Notify(c, syscall.SIGWINCH) }
@@ -0,0 +1,39 @@
+//go:build windows
+
+package tui
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// openTTY opens the console's input and output buffers, which stay the
+// console's when stdin and stdout are redirected.
+func openTTY() (tty, error) {
+	in, err := os.OpenFile("CONIN$", os.O_RDWR, 0)
+	if err != nil {
+		return tty{}, err
+	}
+	out, err := os.OpenFile("CONOUT$", os.O_RDWR, 0)
+	if err != nil {
+		in.Close()
+		return tty{}, err
+	}
+	return tty{in: in, out: out}, nil
+}
+
+// enableEscapes has the console interpret the escape sequences the
+// dashboard draws with, which older consoles print as is by default.
+func enableEscapes(out *os.File) error {
+	h := windows.Handle(out.Fd())
+	var mode uint32
+	if err := windows.GetConsoleMode(h, &mode); err != nil {
+		return err
+	}
+	return windows.SetConsoleMode(h, mode|windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING)
+}
+
+// notifyResize does nothing: the console signals no resizes, and the
+// dashboard's periodic redraw picks them up.
+func notifyResize(chan<- os.Signal) {}
@@ -0,0 +1,28 @@
+package upload
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+)
+
+// putAzure uploads with DefaultAzureCredential (environment, workload or
+// managed identity, Azure CLI login).
+func putAzure(ctx context.Context, account, container, key, contentType string, data []byte) error {
+	cred, err := azidentity.NewDefaultAzureCredential(nil)
+	if err != nil {
+		return err
+	}
+	serviceURL := fmt.Sprintf("https://%s.blob.core.windows.net/", account)
+	client, err := azblob.NewClient(serviceURL, cred, nil)
+	if err != nil {
+		return err
+	}
+	_, err = client.UploadBuffer(ctx, container, key, data, &azblob.UploadBufferOptions{
+		HTTPHeaders: &blob.HTTPHeaders{BlobContentType: &contentType},
+	})
+	return err
+}
@@ -0,0 +1,25 @@
+package upload
+
+import (
+	"context"
+
+	"cloud.google.com/go/storage"
+)
+
+// putGCS uploads with Application Default Credentials
+// (GOOGLE_APPLICATION_CREDENTIALS, gcloud login, or the metadata server).
+func putGCS(ctx context.Context, bucket, key, contentType string, data []byte) error {
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	w := client.Bucket(bucket).Object(key).NewWriter(ctx)
+	w.ContentType = contentType
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
@@ -0,0 +1,117 @@
+// Package upload ships result files to object storage (Amazon S3, Google
+// Cloud Storage, Azure Blob Storage). Credentials always come from each
+// provider's standard SDK chain (environment, shared config, instance or
+// workload identity), never from proxybench flags.
+package upload
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Target is a parsed upload destination.
+//
+//	s3://bucket/key
+//	gs://bucket/key
+//	az://account/container/key
+//
+// A key that is empty or ends in "/" is treated as a prefix; the file name
+// passed to Upload is appended to it.
+type Target struct {
+	Scheme    string // "s3", "gs" or "az"
+	Account   string // Azure storage account (az only)
+	Bucket    string // S3/GCS bucket or Azure container
+	Key       string
+	RawTarget string
+}
+
+// ParseTarget parses an upload URL.
+func ParseTarget(raw string) (Target, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return Target{}, fmt.Errorf("parse upload URL: %w", err)
+	}
+	t := Target{Scheme: strings.ToLower(u.Scheme), RawTarget: raw}
+	path := strings.TrimPrefix(u.Path, "/")
+
+	switch t.Scheme {
+	case "s3", "gs":
+		t.Bucket = u.Host
+		t.Key = path
+	case "az":
+		t.Account = u.Host
+		container, key, _ := strings.Cut(path, "/")
+		t.Bucket = container
+		t.Key = key
+		if t.Account == "" {
+			return t, fmt.Errorf("az:// URL needs a storage account: az://account/container/path")
+		}
+	default:
+		return t, fmt.Errorf("unsupported upload scheme %q (want s3://, gs:// or az://)", u.Scheme)
+	}
+	if t.Bucket == "" {
+		return t, fmt.Errorf("upload URL %q has no bucket/container", raw)
+	}
+	return t, nil
+}
+
+// ObjectKey resolves the final object key for a file name.
+func (t Target) ObjectKey(name string) string {
+	if t.Key == "" || strings.HasSuffix(t.Key, "/") {
+		return t.Key + name
+	}
+	return t.Key
+}
+
+// Location is the human-readable URL of the uploaded object.
+func (t Target) Location(name string) string {
+	key := t.ObjectKey(name)
+	if t.Scheme == "az" {
+		return fmt.Sprintf("az://%s/%s/%s", t.Account, t.Bucket, key)
+	}
+	return fmt.Sprintf("%s://%s/%s", t.Scheme, t.Bucket, key)
+}
+
+// FileName returns a default object name such as
+// "proxybench-check-20240102T150405Z.json".
+func FileName(command, ext string, t time.Time) string {
+	return fmt.Sprintf("proxybench-%s-%s.%s", command, t.UTC().Format("20060102T150405Z"), ext)
+}
+
+// ContentType maps an output format name to a MIME type.
+func ContentType(format string) string {
+	switch format {
+	case "json":
+		return "application/json"
+	case "ndjson":
+		return "application/x-ndjson"
+	case "csv":
+		return "text/csv"
+	default:
+		return "text/plain; charset=utf-8"
+	}
+}
+
+// Upload writes data to the target under name and returns its location.
+func Upload(ctx context.Context, raw, name, contentType string, data []byte) (string, error) {
+	t, err := ParseTarget(raw)
+	if err != nil {
+		return "", err
+	}
+	key := t.ObjectKey(name)
+	switch t.Scheme {
+	case "s3":
+		err = putS3(ctx, t.Bucket, key, contentType, data)
+	case "gs":
+		err = putGCS(ctx, t.Bucket, key, contentType, data)
+	case "az":
+		err = putAzure(ctx, t.Account, t.Bucket, key, contentType, data)
+	}
+	if err != nil {
+		return "", fmt.Errorf("upload to %s: %w", t.Location(name), err)
+	}
+	return t.Location(name), nil
+}
@@ -0,0 +1,67 @@
+package upload
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseTarget(t *testing.T) {
+	cases := []struct {
+		raw     string
+		scheme  string
+		account string
+		bucket  string
+		key     string
+	}{
+		{"s3://reports/daily/check.json", "s3", "", "reports", "daily/check.json"},
+		{"gs://reports/", "gs", "", "reports", ""},
+		{"az://myacct/results/runs/", "az", "myacct", "results", "runs/"},
+		{"S3://bucket", "s3", "", "bucket", ""},
+	}
+	for _, c := range cases {
+		got, err := ParseTarget(c.raw)
+		if err != nil {
+			t.Errorf("ParseTarget(%q): %v", c.raw, err)
+			continue
+		}
+		if got.Scheme != c.scheme || got.Account != c.account || got.Bucket != c.bucket || got.Key != c.key {
+			t.Errorf("ParseTarget(%q) = %+v", c.raw, got)
+		}
+	}
+}
+
+func TestParseTarget_invalid(t *testing.T) {
+	for _, raw := range []string{"ftp://host/x", "s3:///key", "az://acct", "/local/path"} {
+		if _, err := ParseTarget(raw); err == nil {
+			t.Errorf("expected error for %q", raw)
+		}
+	}
+}
+
+func TestObjectKey(t *testing.T) {
+	prefix, _ := ParseTarget("s3://b/runs/")
+	if got := prefix.ObjectKey("a.json"); got != "runs/a.json" {
+		t.Errorf("prefix key = %q", got)
+	}
+	exact, _ := ParseTarget("s3://b/runs/latest.json")
+	if got := exact.ObjectKey("a.json"); got != "runs/latest.json" {
+		t.Errorf("exact key = %q", got)
+	}
+	if got := exact.Location("a.json"); got != "s3://b/runs/latest.json" {
+		t.Errorf("location = %q", got)
+	}
+}
+
+func TestFileName(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	if got := FileName("check", "json", ts); got != "proxybench-check-20240102T150405Z.json" {
+		t.Errorf("FileName = %q", got)
+	}
+}
+
+func TestUpload_badTarget(t *testing.T) {
+	if _, err := Upload(context.Background(), "ftp://x/y", "a", "text/plain", nil); err == nil {
+		t.Error("expected error for unsupported scheme")
+	}
+}
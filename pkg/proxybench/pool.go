@@ -0,0 +1,327 @@
+// Package proxybench is the library side of the proxybench CLI: a Pool of
+// proxies that are checked and benchmarked with the same code as the
+// 'check' and 'bench' commands, and picked from by health.
+//
+//	p := proxybench.NewPool(proxybench.Options{Timeout: 5 * time.Second})
+//	p.Add("socks5://1.2.3.4:1080", "http://5.6.7.8:8080")
+//	go p.Run(ctx, time.Minute) // re-check (and, with BenchInterval, re-bench) in the background
+//	if px, ok := p.NextHealthy(); ok {
+//		// use px.Address
+//	}
+package proxybench
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/drsoft-oss/proxybench/internal/bench"
+	"github.com/drsoft-oss/proxybench/internal/checker"
+	"github.com/drsoft-oss/proxybench/internal/pool"
+)
+
+// Options configures the checks and benchmarks of a Pool. Zero fields take
+// the defaults of the CLI.
+type Options struct {
+	// TestURL is fetched through each proxy (default http://www.google.com).
+	TestURL string
+	// Timeout bounds one check or benchmark request (default 10s).
+	Timeout time.Duration
+	// Concurrency is the number of proxies checked at once (default 10).
+	Concurrency int
+	// Samples is the number of requests per benchmark (default 5).
+	Samples int
+	// BenchInterval, if set, makes Run benchmark the healthy proxies too,
+	// after the first check and then after the first check at least
+	// BenchInterval after the last benchmark.
+	BenchInterval time.Duration
+}
+
+// Proxy is a proxy in a Pool with its latest known state.
+type Proxy struct {
+	Address string
+	// Protocol is http, https, socks5 or ss; for a bare host:port, the
+	// one its check detected.
+	Protocol string
+	Alive    bool
+	Latency  time.Duration
+	// Error is why the proxy is not alive.
+	Error     string
+	CheckedAt time.Time // zero until checked
+	// Failures counts failed checks and MarkFailed calls since the proxy
+	// was last found alive.
+	Failures int
+	// Bench is the latest benchmark, nil until Bench has run.
+	Bench *BenchResult
+
+	checkedAs string // Address with the detected scheme, for benchmarks
+	key       string // normalized Address
+}
+
+// BenchResult is the summary of a proxy's latest benchmark.
+type BenchResult struct {
+	Samples    int
+	Successful int
+	LossRate   float64
+	P50        time.Duration
+	P95        time.Duration
+	SpeedBps   int64
+	At         time.Time
+}
+
+// Pool holds proxies and their health. It is safe for concurrent use.
+type Pool struct {
+	opts Options
+
+	mu      sync.Mutex
+	proxies []*Proxy
+	byKey   map[string]*Proxy // by normalized address
+	next    int               // NextHealthy cursor
+}
+
+// NewPool returns an empty Pool.
+func NewPool(opts Options) *Pool {
+	return &Pool{opts: opts, byKey: map[string]*Proxy{}}
+}
+
+// Add adds proxies not already in the pool, compared after normalizing
+// their addresses, and returns how many were added. New proxies are
+// unchecked, and so not healthy, until the next Check.
+func (p *Pool) Add(addresses ...string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	added := 0
+	for _, a := range addresses {
+		key := checker.Normalize(a)
+		if a == "" || p.byKey[key] != nil {
+			continue
+		}
+		px := &Proxy{Address: a, Protocol: string(checker.DetectProtocol(a)), key: key}
+		p.proxies = append(p.proxies, px)
+		p.byKey[key] = px
+		added++
+	}
+	return added
+}
+
+// Remove removes a proxy and reports whether it was in the pool.
+func (p *Pool) Remove(address string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	px := p.find(address)
+	if px == nil {
+		return false
+	}
+	delete(p.byKey, px.key)
+	i := slices.Index(p.proxies, px)
+	p.proxies = slices.Delete(p.proxies, i, i+1)
+	if p.next > i {
+		p.next--
+	}
+	return true
+}
+
+// find returns the proxy at address, or nil. The caller holds p.mu.
+func (p *Pool) find(address string) *Proxy {
+	return p.byKey[checker.Normalize(address)]
+}
+
+// Len returns the number of proxies in the pool.
+func (p *Pool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.proxies)
+}
+
+// Proxies returns a snapshot of every proxy, in the order added.
+func (p *Pool) Proxies() []Proxy {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := make([]Proxy, len(p.proxies))
+	for i, px := range p.proxies {
+		out[i] = *px
+	}
+	return out
+}
+
+// Get returns the state of one proxy.
+func (p *Pool) Get(address string) (Proxy, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if px := p.find(address); px != nil {
+		return *px, true
+	}
+	return Proxy{}, false
+}
+
+// MarkFailed records that a caller's request through a proxy failed: it
+// is unhealthy until a check finds it alive again.
+func (p *Pool) MarkFailed(address, reason string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if px := p.find(address); px != nil {
+		px.Alive, px.Error = false, reason
+		px.Failures++
+	}
+}
+
+// Healthy returns the proxies found alive by their latest check.
+func (p *Pool) Healthy() []Proxy {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var out []Proxy
+	for _, px := range p.proxies {
+		if px.Alive {
+			out = append(out, *px)
+		}
+	}
+	return out
+}
+
+// NextHealthy returns the healthy proxies in turn, round-robin, or false
+// if none is healthy.
+func (p *Pool) NextHealthy() (Proxy, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for range p.proxies {
+		if p.next >= len(p.proxies) {
+			p.next = 0
+		}
+		px := p.proxies[p.next]
+		p.next++
+		if px.Alive {
+			return *px, true
+		}
+	}
+	return Proxy{}, false
+}
+
+// Best returns up to n healthy proxies, best first: by benchmark loss and
+// then median latency where both have been benchmarked, otherwise by
+// check latency. n <= 0 returns all of them.
+func (p *Pool) Best(n int) []Proxy {
+	healthy := p.Healthy()
+	slices.SortStableFunc(healthy, func(a, b Proxy) int {
+		if a.Bench != nil && b.Bench != nil {
+			return cmp.Or(cmp.Compare(a.Bench.LossRate, b.Bench.LossRate), cmp.Compare(a.Bench.P50, b.Bench.P50))
+		}
+		return cmp.Compare(a.Latency, b.Latency)
+	})
+	if n > 0 && len(healthy) > n {
+		healthy = healthy[:n]
+	}
+	return healthy
+}
+
+// Check checks every proxy now and updates its state. It returns early,
+// with ctx's error, if ctx is done; proxies not checked by then keep
+// their previous state.
+func (p *Pool) Check(ctx context.Context) error {
+	addrs := p.addresses()
+	opts := checker.DefaultOptions()
+	opts.TestURL = cmp.Or(p.opts.TestURL, opts.TestURL)
+	opts.Timeout = cmp.Or(p.opts.Timeout, opts.Timeout)
+	opts.Concurrency = cmp.Or(p.opts.Concurrency, opts.Concurrency)
+	i := 0
+	checker.CheckStreamContext(ctx, pool.FromSlice(addrs), opts, func(r checker.Result) {
+		addr := addrs[i]
+		i++
+		if ctx.Err() != nil {
+			return
+		}
+		p.update(addr, func(px *Proxy) {
+			px.checkedAs, px.Protocol = r.Address, string(r.Protocol)
+			px.Alive, px.Latency, px.Error = r.Alive, r.Latency, r.Error
+			px.CheckedAt = time.Now()
+			if r.Alive {
+				px.Failures = 0
+			} else {
+				px.Failures++
+			}
+		})
+	})
+	return ctx.Err()
+}
+
+// Bench benchmarks the healthy proxies now and records the results.
+func (p *Pool) Bench(ctx context.Context) error {
+	var addrs, targets []string
+	for _, px := range p.Healthy() {
+		addrs = append(addrs, px.Address)
+		targets = append(targets, cmp.Or(px.checkedAs, px.Address))
+	}
+	opts := bench.DefaultOptions()
+	opts.TestURL = cmp.Or(p.opts.TestURL, opts.TestURL)
+	opts.Timeout = cmp.Or(p.opts.Timeout, opts.Timeout)
+	opts.Samples = cmp.Or(p.opts.Samples, opts.Samples)
+	opts.Concurrency = p.opts.Concurrency
+	opts.Context = ctx
+	i := 0
+	bench.RunStream(pool.FromSlice(targets), opts, func(s bench.Stats) {
+		addr := addrs[i]
+		i++
+		if ctx.Err() != nil {
+			return
+		}
+		p.update(addr, func(px *Proxy) {
+			px.Bench = &BenchResult{
+				Samples:    s.Samples,
+				Successful: s.Successful,
+				LossRate:   s.LossRate,
+				P50:        time.Duration(s.P50MS) * time.Millisecond,
+				P95:        time.Duration(s.P95MS) * time.Millisecond,
+				SpeedBps:   s.SpeedBps,
+				At:         time.Now(),
+			}
+		})
+	})
+	return ctx.Err()
+}
+
+// Run checks the pool every interval, starting at once, until ctx is done,
+// and then returns ctx's error. Proxies added meanwhile are picked up by
+// the next round. With Options.BenchInterval set, rounds also benchmark
+// the healthy proxies once that long has passed since the last benchmark.
+func (p *Pool) Run(ctx context.Context, interval time.Duration) error {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	var benched time.Time
+	for {
+		if err := p.Check(ctx); err != nil {
+			return err
+		}
+		if p.opts.BenchInterval > 0 && time.Since(benched) >= p.opts.BenchInterval {
+			if err := p.Bench(ctx); err != nil {
+				return err
+			}
+			benched = time.Now()
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// addresses returns the addresses of the pool, in order.
+func (p *Pool) addresses() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := make([]string, len(p.proxies))
+	for i, px := range p.proxies {
+		out[i] = px.Address
+	}
+	return out
+}
+
+// update applies fn to a proxy still in the pool.
+func (p *Pool) update(address string, fn func(*Proxy)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if px := p.find(address); px != nil {
+		fn(px)
+	}
+}
@@ -0,0 +1,115 @@
+package proxybench
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// fakeProxy answers every request, absolute-URI ones included, so the
+// checker sees a working HTTP proxy.
+func fakeProxy(t *testing.T) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok")) //nolint:errcheck
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+// closedAddr returns an http:// address nothing listens on.
+func closedAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	return "http://" + addr
+}
+
+func TestPool(t *testing.T) {
+	good, dead := fakeProxy(t), closedAddr(t)
+	p := NewPool(Options{TestURL: "http://example.test/", Timeout: 2 * time.Second, Samples: 2})
+	if n := p.Add(good, dead, good+"/"); n != 2 {
+		t.Errorf("Add = %d, want 2 (duplicate skipped)", n)
+	}
+	if _, ok := p.NextHealthy(); ok {
+		t.Error("NextHealthy before any check returned a proxy")
+	}
+
+	if err := p.Check(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	px, ok := p.Get(good)
+	if !ok || !px.Alive || px.CheckedAt.IsZero() || px.Protocol != "http" {
+		t.Errorf("good = %+v", px)
+	}
+	if px, _ := p.Get(dead); px.Alive || px.Error == "" || px.Failures != 1 {
+		t.Errorf("dead = %+v", px)
+	}
+	for range 3 {
+		if px, ok := p.NextHealthy(); !ok || px.Address != good {
+			t.Errorf("NextHealthy = %+v, %v, want the good proxy every time", px, ok)
+		}
+	}
+
+	if err := p.Bench(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	best := p.Best(5)
+	if len(best) != 1 || best[0].Bench == nil || best[0].Bench.Successful != 2 {
+		t.Errorf("Best = %+v", best)
+	}
+
+	p.MarkFailed(good, "caller timeout")
+	if h := p.Healthy(); len(h) != 0 {
+		t.Errorf("Healthy after MarkFailed = %+v", h)
+	}
+	if !p.Remove(dead) || p.Remove(dead) || p.Len() != 1 {
+		t.Errorf("Remove: len %d", p.Len())
+	}
+}
+
+func TestPool_Best(t *testing.T) {
+	p := NewPool(Options{})
+	p.proxies = []*Proxy{
+		{Address: "http://a:1", Alive: true, Latency: 300 * time.Millisecond},
+		{Address: "http://b:1", Alive: true, Latency: 100 * time.Millisecond},
+		{Address: "http://c:1", Latency: 10 * time.Millisecond},
+		{Address: "http://d:1", Alive: true, Latency: 200 * time.Millisecond},
+	}
+	var got []string
+	for _, px := range p.Best(2) {
+		got = append(got, px.Address)
+	}
+	if len(got) != 2 || got[0] != "http://b:1" || got[1] != "http://d:1" {
+		t.Errorf("Best(2) = %v", got)
+	}
+}
+
+func TestPool_Run(t *testing.T) {
+	p := NewPool(Options{TestURL: "http://example.test/", Timeout: time.Second})
+	p.Add(fakeProxy(t))
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	if err := p.Run(ctx, 50*time.Millisecond); err == nil {
+		t.Error("Run returned nil, want the context's error")
+	}
+	if h := p.Healthy(); len(h) != 1 || h[0].Bench != nil {
+		t.Errorf("Healthy after Run = %+v, want one proxy, not benchmarked", h)
+	}
+
+	p = NewPool(Options{TestURL: "http://example.test/", Timeout: time.Second, Samples: 1, BenchInterval: time.Hour})
+	p.Add(fakeProxy(t))
+	ctx, cancel = context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	p.Run(ctx, 50*time.Millisecond) //nolint:errcheck
+	if h := p.Healthy(); len(h) != 1 || h[0].Bench == nil || h[0].Bench.Successful != 1 {
+		t.Errorf("Healthy after Run with BenchInterval = %+v, want it benchmarked", h)
+	}
+}